@@ -0,0 +1,180 @@
+package quic
+
+import (
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+// AdmissionControlConfig configures the AdmissionController.
+// Limits that are not set are not enforced.
+type AdmissionControlConfig struct {
+	// MaxSessions is the maximum number of concurrent sessions, including sessions that are still handshaking.
+	MaxSessions int
+	// MaxSessionsPerPrefix is the maximum number of concurrent sessions from the same IP prefix.
+	// The prefix length is configured by IPv4PrefixLen and IPv6PrefixLen.
+	MaxSessionsPerPrefix int
+	// IPv4PrefixLen is the length of the prefix that MaxSessionsPerPrefix applies to for IPv4 addresses.
+	// If not set, it defaults to 32, i.e. the limit applies to every IPv4 address.
+	IPv4PrefixLen int
+	// IPv6PrefixLen is the length of the prefix that MaxSessionsPerPrefix applies to for IPv6 addresses.
+	// If not set, it defaults to 64.
+	IPv6PrefixLen int
+	// HandshakeRate is the number of new handshakes per second that are accepted.
+	// New handshakes are rate limited using a token bucket, with HandshakeBurst tokens.
+	HandshakeRate float64
+	// HandshakeBurst is the number of handshakes that can be started at once.
+	// If not set, it defaults to HandshakeRate (but at least 1).
+	HandshakeBurst int
+	// RetryThreshold is the number of concurrent handshakes, above which clients have to validate their address
+	// using a Retry before the server starts a handshake.
+	// This prevents clients from using spoofed addresses to fill up the session limits.
+	RetryThreshold int
+}
+
+// AdmissionStats are the statistics of an AdmissionController.
+type AdmissionStats struct {
+	// Sessions is the number of current sessions.
+	Sessions int
+	// HandshakingSessions is the number of current sessions that haven't completed the handshake yet.
+	HandshakingSessions int
+
+	// Accepted is the number of connection attempts that were accepted.
+	Accepted uint64
+	// Retried is the number of Retry packets that were sent because the RetryThreshold was reached.
+	Retried uint64
+	// RejectedMaxSessions is the number of connection attempts that were rejected because of MaxSessions.
+	RejectedMaxSessions uint64
+	// RejectedMaxSessionsPerPrefix is the number of connection attempts that were rejected because of MaxSessionsPerPrefix.
+	RejectedMaxSessionsPerPrefix uint64
+	// RejectedHandshakeRate is the number of connection attempts that were rejected because of HandshakeRate.
+	RejectedHandshakeRate uint64
+}
+
+type admissionDecision uint8
+
+const (
+	admissionAccept admissionDecision = iota
+	admissionRetry
+	admissionReject
+)
+
+// An AdmissionController decides which connection attempts a server accepts.
+// It is used by setting it on the Config passed to Listen.
+// The same AdmissionController can be used for multiple servers, they then share the limits.
+type AdmissionController struct {
+	mutex sync.Mutex
+
+	config AdmissionControlConfig
+
+	handshakeTokens   float64
+	lastHandshakeTime time.Time
+
+	sessions          int
+	handshaking       int
+	sessionsPerPrefix map[string]int
+
+	stats AdmissionStats
+}
+
+// NewAdmissionController creates a new AdmissionController.
+func NewAdmissionController(config *AdmissionControlConfig) *AdmissionController {
+	c := &AdmissionController{
+		config:            *config,
+		sessionsPerPrefix: make(map[string]int),
+	}
+	if c.config.IPv4PrefixLen == 0 {
+		c.config.IPv4PrefixLen = 32
+	}
+	if c.config.IPv6PrefixLen == 0 {
+		c.config.IPv6PrefixLen = 64
+	}
+	if c.config.HandshakeBurst == 0 {
+		c.config.HandshakeBurst = int(math.Max(1, c.config.HandshakeRate))
+	}
+	c.handshakeTokens = float64(c.config.HandshakeBurst)
+	return c
+}
+
+// Stats returns the current statistics.
+func (c *AdmissionController) Stats() AdmissionStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	stats := c.stats
+	stats.Sessions = c.sessions
+	stats.HandshakingSessions = c.handshaking
+	return stats
+}
+
+// admit decides if a new session is accepted.
+// If it is accepted, the session counts towards the limits until sessionClosed is called.
+func (c *AdmissionController) admit(remoteAddr net.Addr, addrValidated bool, now time.Time) admissionDecision {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.config.MaxSessions > 0 && c.sessions >= c.config.MaxSessions {
+		c.stats.RejectedMaxSessions++
+		return admissionReject
+	}
+	if !addrValidated && c.config.RetryThreshold > 0 && c.handshaking >= c.config.RetryThreshold {
+		c.stats.Retried++
+		return admissionRetry
+	}
+	prefix := c.prefix(remoteAddr)
+	if c.config.MaxSessionsPerPrefix > 0 && c.sessionsPerPrefix[prefix] >= c.config.MaxSessionsPerPrefix {
+		c.stats.RejectedMaxSessionsPerPrefix++
+		return admissionReject
+	}
+	if c.config.HandshakeRate > 0 {
+		if !c.lastHandshakeTime.IsZero() {
+			c.handshakeTokens += now.Sub(c.lastHandshakeTime).Seconds() * c.config.HandshakeRate
+			c.handshakeTokens = math.Min(c.handshakeTokens, float64(c.config.HandshakeBurst))
+		}
+		c.lastHandshakeTime = now
+		if c.handshakeTokens < 1 {
+			c.stats.RejectedHandshakeRate++
+			return admissionReject
+		}
+		c.handshakeTokens--
+	}
+	c.sessions++
+	c.handshaking++
+	c.sessionsPerPrefix[prefix]++
+	c.stats.Accepted++
+	return admissionAccept
+}
+
+// handshakeCompleted is called when an admitted session completes the handshake
+func (c *AdmissionController) handshakeCompleted() {
+	c.mutex.Lock()
+	c.handshaking--
+	c.mutex.Unlock()
+}
+
+// sessionClosed is called when an admitted session is closed
+func (c *AdmissionController) sessionClosed(remoteAddr net.Addr, handshakeCompleted bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.sessions--
+	if !handshakeCompleted {
+		c.handshaking--
+	}
+	prefix := c.prefix(remoteAddr)
+	if c.sessionsPerPrefix[prefix]--; c.sessionsPerPrefix[prefix] <= 0 {
+		delete(c.sessionsPerPrefix, prefix)
+	}
+}
+
+func (c *AdmissionController) prefix(addr net.Addr) string {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return addr.String()
+	}
+	if ip := udpAddr.IP.To4(); ip != nil {
+		return ip.Mask(net.CIDRMask(c.config.IPv4PrefixLen, 32)).String()
+	}
+	return udpAddr.IP.Mask(net.CIDRMask(c.config.IPv6PrefixLen, 128)).String()
+}
@@ -0,0 +1,110 @@
+package quic
+
+import (
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Admission Control", func() {
+	addr := func(ip string) net.Addr {
+		return &net.UDPAddr{IP: net.ParseIP(ip), Port: 443}
+	}
+
+	It("limits the number of sessions", func() {
+		c := NewAdmissionController(&AdmissionControlConfig{MaxSessions: 2})
+		now := time.Now()
+		Expect(c.admit(addr("192.0.2.1"), false, now)).To(Equal(admissionAccept))
+		Expect(c.admit(addr("192.0.2.2"), false, now)).To(Equal(admissionAccept))
+		Expect(c.admit(addr("192.0.2.3"), false, now)).To(Equal(admissionReject))
+		c.sessionClosed(addr("192.0.2.1"), false)
+		Expect(c.admit(addr("192.0.2.3"), false, now)).To(Equal(admissionAccept))
+		stats := c.Stats()
+		Expect(stats.Sessions).To(Equal(2))
+		Expect(stats.Accepted).To(BeEquivalentTo(3))
+		Expect(stats.RejectedMaxSessions).To(BeEquivalentTo(1))
+	})
+
+	It("limits the number of sessions per IPv4 address", func() {
+		c := NewAdmissionController(&AdmissionControlConfig{MaxSessionsPerPrefix: 1})
+		now := time.Now()
+		Expect(c.admit(addr("192.0.2.1"), false, now)).To(Equal(admissionAccept))
+		Expect(c.admit(&net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1234}, false, now)).To(Equal(admissionReject))
+		Expect(c.admit(addr("192.0.2.2"), false, now)).To(Equal(admissionAccept))
+		c.sessionClosed(addr("192.0.2.1"), true)
+		Expect(c.admit(addr("192.0.2.1"), false, now)).To(Equal(admissionAccept))
+		Expect(c.Stats().RejectedMaxSessionsPerPrefix).To(BeEquivalentTo(1))
+	})
+
+	It("limits the number of sessions per prefix", func() {
+		c := NewAdmissionController(&AdmissionControlConfig{
+			MaxSessionsPerPrefix: 2,
+			IPv4PrefixLen:        24,
+		})
+		now := time.Now()
+		Expect(c.admit(addr("192.0.2.1"), false, now)).To(Equal(admissionAccept))
+		Expect(c.admit(addr("192.0.2.2"), false, now)).To(Equal(admissionAccept))
+		Expect(c.admit(addr("192.0.2.3"), false, now)).To(Equal(admissionReject))
+		Expect(c.admit(addr("198.51.100.1"), false, now)).To(Equal(admissionAccept))
+	})
+
+	It("uses /64 prefixes for IPv6 by default", func() {
+		c := NewAdmissionController(&AdmissionControlConfig{MaxSessionsPerPrefix: 1})
+		now := time.Now()
+		Expect(c.admit(addr("2001:db8:0:1::1"), false, now)).To(Equal(admissionAccept))
+		Expect(c.admit(addr("2001:db8:0:1::2"), false, now)).To(Equal(admissionReject))
+		Expect(c.admit(addr("2001:db8:0:2::1"), false, now)).To(Equal(admissionAccept))
+	})
+
+	It("rate limits handshakes", func() {
+		c := NewAdmissionController(&AdmissionControlConfig{
+			HandshakeRate:  10,
+			HandshakeBurst: 2,
+		})
+		now := time.Now()
+		Expect(c.admit(addr("192.0.2.1"), false, now)).To(Equal(admissionAccept))
+		Expect(c.admit(addr("192.0.2.2"), false, now)).To(Equal(admissionAccept))
+		Expect(c.admit(addr("192.0.2.3"), false, now)).To(Equal(admissionReject))
+		// one token is added every 100ms
+		Expect(c.admit(addr("192.0.2.3"), false, now.Add(50*time.Millisecond))).To(Equal(admissionReject))
+		Expect(c.admit(addr("192.0.2.3"), false, now.Add(100*time.Millisecond))).To(Equal(admissionAccept))
+		Expect(c.admit(addr("192.0.2.4"), false, now.Add(100*time.Millisecond))).To(Equal(admissionReject))
+		// the bucket doesn't fill beyond the burst size
+		Expect(c.admit(addr("192.0.2.4"), false, now.Add(time.Hour))).To(Equal(admissionAccept))
+		Expect(c.admit(addr("192.0.2.5"), false, now.Add(time.Hour))).To(Equal(admissionAccept))
+		Expect(c.admit(addr("192.0.2.6"), false, now.Add(time.Hour))).To(Equal(admissionReject))
+		Expect(c.Stats().RejectedHandshakeRate).To(BeEquivalentTo(4))
+	})
+
+	It("requires address validation when too many handshakes are in progress", func() {
+		c := NewAdmissionController(&AdmissionControlConfig{RetryThreshold: 2})
+		now := time.Now()
+		Expect(c.admit(addr("192.0.2.1"), false, now)).To(Equal(admissionAccept))
+		Expect(c.admit(addr("192.0.2.2"), false, now)).To(Equal(admissionAccept))
+		Expect(c.admit(addr("192.0.2.3"), false, now)).To(Equal(admissionRetry))
+		Expect(c.admit(addr("192.0.2.3"), true, now)).To(Equal(admissionAccept))
+		Expect(c.Stats().HandshakingSessions).To(Equal(3))
+		c.handshakeCompleted()
+		c.handshakeCompleted()
+		Expect(c.admit(addr("192.0.2.4"), false, now)).To(Equal(admissionAccept))
+		stats := c.Stats()
+		Expect(stats.Retried).To(BeEquivalentTo(1))
+		Expect(stats.HandshakingSessions).To(Equal(2))
+		Expect(stats.Sessions).To(Equal(4))
+	})
+
+	It("counts sessions that are closed during the handshake", func() {
+		c := NewAdmissionController(&AdmissionControlConfig{})
+		Expect(c.admit(addr("192.0.2.1"), false, time.Now())).To(Equal(admissionAccept))
+		Expect(c.admit(addr("192.0.2.2"), false, time.Now())).To(Equal(admissionAccept))
+		c.handshakeCompleted()
+		c.sessionClosed(addr("192.0.2.1"), true)
+		c.sessionClosed(addr("192.0.2.2"), false)
+		stats := c.Stats()
+		Expect(stats.Sessions).To(BeZero())
+		Expect(stats.HandshakingSessions).To(BeZero())
+		Expect(c.sessionsPerPrefix).To(BeEmpty())
+	})
+})
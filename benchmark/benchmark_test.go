@@ -31,62 +31,71 @@ var _ = Describe("Benchmarks", func() {
 				rand.Read(data) // no need to check for an error. math.Rand.Read never errors
 			})
 
-			Measure("transferring a file", func(b Benchmarker) {
-				var ln quic.Listener
-				serverAddr := make(chan net.Addr)
-				handshakeChan := make(chan struct{})
-				// start the server
-				go func() {
-					defer GinkgoRecover()
-					var err error
-					tlsConf := testdata.GetTLSConfig()
-					tlsConf.NextProtos = []string{"benchmark"}
-					ln, err = quic.ListenAddr(
-						"localhost:0",
-						tlsConf,
-						&quic.Config{Versions: []protocol.VersionNumber{version}},
+			// Batched I/O is only supported on Linux. On other platforms, both measurements should yield the same results.
+			for _, batched := range []bool{false, true} {
+				batchedIO := batched
+				name := "transferring a file"
+				if batchedIO {
+					name += ", using batched I/O"
+				}
+
+				Measure(name, func(b Benchmarker) {
+					var ln quic.Listener
+					serverAddr := make(chan net.Addr)
+					handshakeChan := make(chan struct{})
+					// start the server
+					go func() {
+						defer GinkgoRecover()
+						var err error
+						tlsConf := testdata.GetTLSConfig()
+						tlsConf.NextProtos = []string{"benchmark"}
+						ln, err = quic.ListenAddr(
+							"localhost:0",
+							tlsConf,
+							&quic.Config{Versions: []protocol.VersionNumber{version}, BatchedIO: batchedIO},
+						)
+						Expect(err).ToNot(HaveOccurred())
+						serverAddr <- ln.Addr()
+						sess, err := ln.Accept(context.Background())
+						Expect(err).ToNot(HaveOccurred())
+						// wait for the client to complete the handshake before sending the data
+						// this should not be necessary, but due to timing issues on the CIs, this is necessary to avoid sending too many undecryptable packets
+						<-handshakeChan
+						str, err := sess.OpenStream()
+						Expect(err).ToNot(HaveOccurred())
+						_, err = str.Write(data)
+						Expect(err).ToNot(HaveOccurred())
+						err = str.Close()
+						Expect(err).ToNot(HaveOccurred())
+					}()
+
+					// start the client
+					addr := <-serverAddr
+					sess, err := quic.DialAddr(
+						addr.String(),
+						&tls.Config{InsecureSkipVerify: true, NextProtos: []string{"benchmark"}},
+						&quic.Config{Versions: []protocol.VersionNumber{version}, BatchedIO: batchedIO},
 					)
 					Expect(err).ToNot(HaveOccurred())
-					serverAddr <- ln.Addr()
-					sess, err := ln.Accept(context.Background())
-					Expect(err).ToNot(HaveOccurred())
-					// wait for the client to complete the handshake before sending the data
-					// this should not be necessary, but due to timing issues on the CIs, this is necessary to avoid sending too many undecryptable packets
-					<-handshakeChan
-					str, err := sess.OpenStream()
+					close(handshakeChan)
+					str, err := sess.AcceptStream(context.Background())
 					Expect(err).ToNot(HaveOccurred())
-					_, err = str.Write(data)
-					Expect(err).ToNot(HaveOccurred())
-					err = str.Close()
-					Expect(err).ToNot(HaveOccurred())
-				}()
-
-				// start the client
-				addr := <-serverAddr
-				sess, err := quic.DialAddr(
-					addr.String(),
-					&tls.Config{InsecureSkipVerify: true, NextProtos: []string{"benchmark"}},
-					&quic.Config{Versions: []protocol.VersionNumber{version}},
-				)
-				Expect(err).ToNot(HaveOccurred())
-				close(handshakeChan)
-				str, err := sess.AcceptStream(context.Background())
-				Expect(err).ToNot(HaveOccurred())
 
-				buf := &bytes.Buffer{}
-				// measure the time it takes to download the dataLen bytes
-				// note we're measuring the time for the transfer, i.e. excluding the handshake
-				runtime := b.Time("transfer time", func() {
-					_, err := io.Copy(buf, str)
-					Expect(err).NotTo(HaveOccurred())
-				})
-				Expect(buf.Bytes()).To(Equal(data))
+					buf := &bytes.Buffer{}
+					// measure the time it takes to download the dataLen bytes
+					// note we're measuring the time for the transfer, i.e. excluding the handshake
+					runtime := b.Time("transfer time", func() {
+						_, err := io.Copy(buf, str)
+						Expect(err).NotTo(HaveOccurred())
+					})
+					Expect(buf.Bytes()).To(Equal(data))
 
-				b.RecordValue("transfer rate [MB/s]", float64(dataLen)/1e6/runtime.Seconds())
+					b.RecordValue("transfer rate [MB/s]", float64(dataLen)/1e6/runtime.Seconds())
 
-				ln.Close()
-				sess.Close()
-			}, 3)
+					ln.Close()
+					sess.Close()
+				}, 3)
+			}
 		})
 	}
 })
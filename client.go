@@ -22,6 +22,8 @@ type client struct {
 	// If it is started with Dial, we take a packet conn as a parameter.
 	createdPacketConn bool
 
+	use0RTT bool
+
 	packetHandlers packetHandlerManager
 
 	versionNegotiated                utils.AtomicBool // has the server accepted our version
@@ -81,7 +83,33 @@ func DialAddrContext(
 	if err != nil {
 		return nil, err
 	}
-	return dialContext(ctx, udpConn, udpAddr, addr, tlsConf, config, true)
+	return dialContext(ctx, udpConn, udpAddr, addr, tlsConf, config, false, true)
+}
+
+// DialAddrEarly establishes a new 0-RTT QUIC connection to a server.
+// It uses a new UDP connection and closes this connection when the QUIC session is closed.
+// The hostname for SNI is taken from the given address.
+// The session is returned as soon as the ClientHello was sent, if the server's transport parameters
+// are known from a previous connection, or else when the handshake completes.
+// 0-RTT data can be replayed by an attacker, so only idempotent data should be sent before completion of the handshake.
+func DialAddrEarly(
+	addr string,
+	tlsConf *tls.Config,
+	config *Config,
+) (EarlySession, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+	if err != nil {
+		return nil, err
+	}
+	sess, err := dialContext(context.Background(), udpConn, udpAddr, addr, tlsConf, config, true, true)
+	if err != nil {
+		return nil, err
+	}
+	return sess.(EarlySession), nil
 }
 
 // Dial establishes a new QUIC connection to a server using a net.PacketConn.
@@ -109,7 +137,23 @@ func DialContext(
 	tlsConf *tls.Config,
 	config *Config,
 ) (Session, error) {
-	return dialContext(ctx, pconn, remoteAddr, host, tlsConf, config, false)
+	return dialContext(ctx, pconn, remoteAddr, host, tlsConf, config, false, false)
+}
+
+// DialEarly establishes a new 0-RTT QUIC connection to a server using a net.PacketConn.
+// See Dial and DialAddrEarly for details.
+func DialEarly(
+	pconn net.PacketConn,
+	remoteAddr net.Addr,
+	host string,
+	tlsConf *tls.Config,
+	config *Config,
+) (EarlySession, error) {
+	sess, err := dialContext(context.Background(), pconn, remoteAddr, host, tlsConf, config, true, false)
+	if err != nil {
+		return nil, err
+	}
+	return sess.(EarlySession), nil
 }
 
 func dialContext(
@@ -119,12 +163,20 @@ func dialContext(
 	host string,
 	tlsConf *tls.Config,
 	config *Config,
+	use0RTT bool,
 	createdPacketConn bool,
-) (Session, error) {
+) (quicSession, error) {
 	if tlsConf == nil {
 		return nil, errors.New("quic: tls.Config not set")
 	}
 	config = populateClientConfig(config, createdPacketConn)
+	// Only set the ECN socket options on connections passed in by the application if it asked for it.
+	if createdPacketConn || config.EnableECN {
+		pconn = newECNConn(pconn)
+	}
+	if config.BatchedIO {
+		pconn = newBatchConn(pconn)
+	}
 	packetHandlers, err := getMultiplexer().AddConn(pconn, config.ConnectionIDLength, config.StatelessResetKey)
 	if err != nil {
 		return nil, err
@@ -134,6 +186,7 @@ func dialContext(
 		return nil, err
 	}
 	c.packetHandlers = packetHandlers
+	c.use0RTT = use0RTT
 	if err := c.dial(ctx); err != nil {
 		return nil, err
 	}
@@ -190,7 +243,7 @@ func newClient(
 		config:            config,
 		version:           config.Versions[0],
 		handshakeChan:     make(chan struct{}),
-		logger:            utils.DefaultLogger.WithPrefix("client"),
+		logger:            utils.NewLogger(config.Logger, "client"),
 	}
 	return c, nil
 }
@@ -235,8 +288,17 @@ func populateClientConfig(config *Config, createdPacketConn bool) *Config {
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	initialPacingBurst := config.InitialPacingBurst
+	if initialPacingBurst == 0 {
+		initialPacingBurst = uint64(protocol.DefaultInitialPacingBurst)
+	}
+	maxPacingBurst := config.MaxPacingBurst
+	if maxPacingBurst == 0 {
+		maxPacingBurst = uint64(protocol.DefaultMaxPacingBurst)
+	}
 	connIDLen := config.ConnectionIDLength
-	if connIDLen == 0 && !createdPacketConn {
+	// The multipath extension identifies paths using connection IDs.
+	if connIDLen == 0 && (!createdPacketConn || config.EnableMultipath) {
 		connIDLen = protocol.DefaultConnectionIDLength
 	}
 
@@ -250,7 +312,18 @@ func populateClientConfig(config *Config, createdPacketConn bool) *Config {
 		MaxIncomingStreams:                    maxIncomingStreams,
 		MaxIncomingUniStreams:                 maxIncomingUniStreams,
 		KeepAlive:                             config.KeepAlive,
+		BatchedIO:                             config.BatchedIO,
+		EnableECN:                             config.EnableECN,
+		DisablePacing:                         config.DisablePacing,
+		InitialPacingBurst:                    initialPacingBurst,
+		MaxPacingBurst:                        maxPacingBurst,
 		StatelessResetKey:                     config.StatelessResetKey,
+		KeyUpdateInterval:                     config.KeyUpdateInterval,
+		KeyUpdateIntervalBytes:                config.KeyUpdateIntervalBytes,
+		EnableMultipath:                       config.EnableMultipath,
+		NewPathScheduler:                      config.NewPathScheduler,
+		Logger:                                config.Logger,
+		Metrics:                               config.Metrics,
 		QuicTracer:                            config.QuicTracer,
 		TokenStore:                            config.TokenStore,
 	}
@@ -271,8 +344,13 @@ func (c *client) dial(ctx context.Context) error {
 // - errCloseForRecreating when the server sends a version negotiation packet
 // - any other error that might occur
 // - when the connection is forward-secure
+// - when 0-RTT is used, as soon as the session is ready to send 0-RTT data
 func (c *client) establishSecureConnection(ctx context.Context) error {
 	errorChan := make(chan error, 1)
+	var earlySessionChan <-chan struct{}
+	if c.use0RTT {
+		earlySessionChan = c.session.earlySessionReady()
+	}
 
 	go func() {
 		err := c.session.run() // returns as soon as the session is closed
@@ -289,6 +367,9 @@ func (c *client) establishSecureConnection(ctx context.Context) error {
 		return ctx.Err()
 	case err := <-errorChan:
 		return err
+	case <-earlySessionChan:
+		// ready to send 0-RTT data
+		return nil
 	case <-c.session.HandshakeComplete().Done():
 		// handshake successfully completed
 		return nil
@@ -364,7 +445,8 @@ func (c *client) createNewTLSSession(_ protocol.VersionNumber) {
 		c.tlsConf,
 		c.initialPacketNumber,
 		c.initialVersion,
-		c.logger,
+		c.use0RTT,
+		c.logger.WithConnectionID(c.destConnID),
 		c.version,
 	)
 	c.mutex.Unlock()
@@ -0,0 +1,66 @@
+package quic
+
+import (
+	"crypto/tls"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+type persistentClientSessionCache struct {
+	store KeyValueStore
+
+	logger utils.Logger
+}
+
+var _ tls.ClientSessionCache = &persistentClientSessionCache{}
+
+// NewPersistentClientSessionCache creates a tls.ClientSessionCache that saves session tickets in a KeyValueStore.
+// This allows clients to resume TLS sessions after they were restarted.
+// Session states contain the resumption secret, so the KeyValueStore must store them securely.
+// Session states are deleted when the session ticket expires.
+// Errors of the KeyValueStore are logged to logger. If logger is nil, the default logger is used.
+func NewPersistentClientSessionCache(store KeyValueStore, logger Logger) tls.ClientSessionCache {
+	return &persistentClientSessionCache{
+		store:  store,
+		logger: utils.NewLogger(logger, "session cache"),
+	}
+}
+
+func (c *persistentClientSessionCache) Get(sessionKey string) (*tls.ClientSessionState, bool) {
+	data, err := c.store.Get(c.storeKey(sessionKey))
+	if err != nil {
+		c.logger.Debugf("Reading session state for %s failed: %s", sessionKey, err)
+		return nil, false
+	}
+	if data == nil {
+		return nil, false
+	}
+	state, err := handshake.UnmarshalClientSessionState(data)
+	if err != nil {
+		c.logger.Debugf("Parsing session state for %s failed: %s", sessionKey, err)
+		return nil, false
+	}
+	return state, true
+}
+
+func (c *persistentClientSessionCache) Put(sessionKey string, cs *tls.ClientSessionState) {
+	if cs == nil {
+		if _, err := c.store.Take(c.storeKey(sessionKey)); err != nil {
+			c.logger.Debugf("Deleting session state for %s failed: %s", sessionKey, err)
+		}
+		return
+	}
+	data, expiry, err := handshake.MarshalClientSessionState(cs)
+	if err != nil {
+		c.logger.Debugf("Serializing session state for %s failed: %s", sessionKey, err)
+		return
+	}
+	if err := c.store.Put(c.storeKey(sessionKey), data, expiry); err != nil {
+		c.logger.Debugf("Saving session state for %s failed: %s", sessionKey, err)
+	}
+}
+
+func (c *persistentClientSessionCache) storeKey(sessionKey string) string {
+	return "session " + sessionKey
+}
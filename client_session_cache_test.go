@@ -0,0 +1,49 @@
+package quic
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Persistent Client Session Cache", func() {
+	var (
+		dir   string
+		store KeyValueStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "quic-go-session-cache")
+		Expect(err).ToNot(HaveOccurred())
+		store, err = NewFileStore(dir, bytes.Repeat([]byte{0x42}, 32))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("doesn't return session states that don't exist", func() {
+		state, ok := NewPersistentClientSessionCache(store, nil).Get("localhost")
+		Expect(ok).To(BeFalse())
+		Expect(state).To(BeNil())
+	})
+
+	It("ignores invalid session states", func() {
+		Expect(store.Put("session localhost", []byte("foobar"), time.Now().Add(time.Hour))).To(Succeed())
+		state, ok := NewPersistentClientSessionCache(store, nil).Get("localhost")
+		Expect(ok).To(BeFalse())
+		Expect(state).To(BeNil())
+	})
+
+	It("deletes session states", func() {
+		Expect(store.Put("session localhost", []byte("foobar"), time.Now().Add(time.Hour))).To(Succeed())
+		NewPersistentClientSessionCache(store, nil).Put("localhost", nil)
+		Expect(store.Get("session localhost")).To(BeNil())
+	})
+})
@@ -38,6 +38,7 @@ var _ = Describe("Client", func() {
 			tlsConf *tls.Config,
 			initialPacketNumber protocol.PacketNumber,
 			initialVersion protocol.VersionNumber,
+			enable0RTT bool,
 			logger utils.Logger,
 			v protocol.VersionNumber,
 		) quicSession
@@ -140,6 +141,7 @@ var _ = Describe("Client", func() {
 				_ *tls.Config,
 				_ protocol.PacketNumber,
 				_ protocol.VersionNumber,
+				_ bool,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
 			) quicSession {
@@ -170,6 +172,7 @@ var _ = Describe("Client", func() {
 				tlsConf *tls.Config,
 				_ protocol.PacketNumber,
 				_ protocol.VersionNumber,
+				_ bool,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
 			) quicSession {
@@ -200,6 +203,7 @@ var _ = Describe("Client", func() {
 				tlsConf *tls.Config,
 				_ protocol.PacketNumber,
 				_ protocol.VersionNumber,
+				_ bool,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
 			) quicSession {
@@ -235,6 +239,7 @@ var _ = Describe("Client", func() {
 				_ *tls.Config,
 				_ protocol.PacketNumber,
 				_ protocol.VersionNumber,
+				_ bool,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
 			) quicSession {
@@ -257,6 +262,52 @@ var _ = Describe("Client", func() {
 			Eventually(run).Should(BeClosed())
 		})
 
+		It("returns early sessions", func() {
+			manager := NewMockPacketHandlerManager(mockCtrl)
+			manager.EXPECT().Add(gomock.Any(), gomock.Any())
+			mockMultiplexer.EXPECT().AddConn(packetConn, gomock.Any(), gomock.Any()).Return(manager, nil)
+
+			readyChan := make(chan struct{})
+			done := make(chan struct{})
+			newClientSession = func(
+				_ connection,
+				runner sessionRunner,
+				_ protocol.ConnectionID,
+				_ protocol.ConnectionID,
+				_ *Config,
+				_ *tls.Config,
+				_ protocol.PacketNumber,
+				_ protocol.VersionNumber,
+				enable0RTT bool,
+				_ utils.Logger,
+				_ protocol.VersionNumber,
+			) quicSession {
+				Expect(enable0RTT).To(BeTrue())
+				sess := NewMockQuicSession(mockCtrl)
+				sess.EXPECT().run().Do(func() { <-done })
+				sess.EXPECT().HandshakeComplete().Return(context.Background())
+				sess.EXPECT().earlySessionReady().Return(readyChan)
+				return sess
+			}
+
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				s, err := DialEarly(
+					packetConn,
+					addr,
+					"localhost:1337",
+					tlsConf,
+					&Config{},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s).ToNot(BeNil())
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			close(readyChan)
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("returns an error that occurs while waiting for the handshake to complete", func() {
 			manager := NewMockPacketHandlerManager(mockCtrl)
 			manager.EXPECT().Add(gomock.Any(), gomock.Any())
@@ -272,6 +323,7 @@ var _ = Describe("Client", func() {
 				_ *tls.Config,
 				_ protocol.PacketNumber,
 				_ protocol.VersionNumber,
+				_ bool,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
 			) quicSession {
@@ -312,6 +364,7 @@ var _ = Describe("Client", func() {
 				_ *tls.Config,
 				_ protocol.PacketNumber,
 				_ protocol.VersionNumber,
+				_ bool,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
 			) quicSession {
@@ -360,6 +413,7 @@ var _ = Describe("Client", func() {
 				_ *tls.Config,
 				_ protocol.PacketNumber,
 				_ protocol.VersionNumber,
+				_ bool,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
 			) quicSession {
@@ -405,6 +459,10 @@ var _ = Describe("Client", func() {
 					StatelessResetKey:     []byte("foobar"),
 					QuicTracer:            tracer,
 					TokenStore:            tokenStore,
+					DisablePacing:         true,
+					InitialPacingBurst:    1 << 16,
+					MaxPacingBurst:        1 << 14,
+					EnableECN:             true,
 				}
 				c := populateClientConfig(config, false)
 				Expect(c.HandshakeTimeout).To(Equal(1337 * time.Minute))
@@ -415,6 +473,10 @@ var _ = Describe("Client", func() {
 				Expect(c.StatelessResetKey).To(Equal([]byte("foobar")))
 				Expect(c.QuicTracer).To(Equal(tracer))
 				Expect(c.TokenStore).To(Equal(tokenStore))
+				Expect(c.DisablePacing).To(BeTrue())
+				Expect(c.InitialPacingBurst).To(BeEquivalentTo(1 << 16))
+				Expect(c.MaxPacingBurst).To(BeEquivalentTo(1 << 14))
+				Expect(c.EnableECN).To(BeTrue())
 			})
 
 			It("errors when the Config contains an invalid version", func() {
@@ -452,11 +514,20 @@ var _ = Describe("Client", func() {
 				Expect(c.ConnectionIDLength).To(BeZero())
 			})
 
+			It("uses non-zero connection IDs when dialing an address with multipath enabled", func() {
+				c := populateClientConfig(&Config{EnableMultipath: true}, true)
+				Expect(c.ConnectionIDLength).To(Equal(protocol.DefaultConnectionIDLength))
+				Expect(c.EnableMultipath).To(BeTrue())
+			})
+
 			It("fills in default values if options are not set in the Config", func() {
 				c := populateClientConfig(&Config{}, false)
 				Expect(c.Versions).To(Equal(protocol.SupportedVersions))
 				Expect(c.HandshakeTimeout).To(Equal(protocol.DefaultHandshakeTimeout))
 				Expect(c.IdleTimeout).To(Equal(protocol.DefaultIdleTimeout))
+				Expect(c.DisablePacing).To(BeFalse())
+				Expect(c.InitialPacingBurst).To(BeEquivalentTo(protocol.DefaultInitialPacingBurst))
+				Expect(c.MaxPacingBurst).To(BeEquivalentTo(protocol.DefaultMaxPacingBurst))
 			})
 		})
 
@@ -479,6 +550,7 @@ var _ = Describe("Client", func() {
 				_ *tls.Config,
 				_ protocol.PacketNumber,
 				_ protocol.VersionNumber, /* initial version */
+				_ bool,
 				_ utils.Logger,
 				versionP protocol.VersionNumber,
 			) quicSession {
@@ -527,6 +599,7 @@ var _ = Describe("Client", func() {
 					_ *tls.Config,
 					_ protocol.PacketNumber,
 					_ protocol.VersionNumber,
+					_ bool,
 					_ utils.Logger,
 					_ protocol.VersionNumber,
 				) quicSession {
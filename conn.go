@@ -3,18 +3,27 @@ package quic
 import (
 	"net"
 	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 type connection interface {
 	Write([]byte) error
+	// WriteTo writes a packet to an address other than the current remote address
+	WriteTo([]byte, net.Addr) error
 	Read([]byte) (int, net.Addr, error)
 	Close() error
 	LocalAddr() net.Addr
 	RemoteAddr() net.Addr
 	SetCurrentRemoteAddr(net.Addr)
+	// ECN is the ECN codepoint that outgoing packets are marked with
+	ECN() protocol.ECN
+	// DisableECN stops marking outgoing packets, e.g. because ECN validation failed
+	DisableECN()
 
 	Scheduler() ResponseWriterScheduler
-	Init()
+	Init(utils.Logger, utils.Metrics)
 }
 
 type conn struct {
@@ -22,13 +31,15 @@ type conn struct {
 
 	pconn       net.PacketConn
 	currentAddr net.Addr
+	// set when ECN validation failed. Packets are then sent without ECN marking.
+	ecnDisabled bool
 
 	// 每一条 HTTP3 连接会有一个调度器
 	schd ResponseWriterScheduler
 }
 
-func (c *conn) Init() {
-	c.schd = InitResponseWriterScheduler()
+func (c *conn) Init(logger utils.Logger, metrics utils.Metrics) {
+	c.schd = InitResponseWriterScheduler(logger.WithPrefix("scheduler"), metrics)
 	c.schd.Run()
 }
 
@@ -38,10 +49,35 @@ func (c *conn) Scheduler() ResponseWriterScheduler {
 }
 
 var _ connection = &conn{}
+var _ batchConnection = &conn{}
 
 func (c *conn) Write(p []byte) error {
-	_, err := c.pconn.WriteTo(p, c.currentAddr)
-	return err
+	return c.WriteTo(p, c.RemoteAddr())
+}
+
+func (c *conn) WriteTo(p []byte, addr net.Addr) error {
+	return writePacket(c.pconn, p, addr, c.ECN())
+}
+
+// WriteBatch writes multiple packets.
+// If the underlying packet conn doesn't support batching, the packets are written one by one.
+func (c *conn) WriteBatch(packets [][]byte) error {
+	if bc, ok := c.pconn.(batchPacketConn); ok {
+		return bc.WritePackets(packets, c.RemoteAddr(), c.ECN())
+	}
+	for _, p := range packets {
+		if err := c.Write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *conn) BatchSize() int {
+	if _, ok := c.pconn.(batchPacketConn); ok {
+		return protocol.BatchSize
+	}
+	return 1
 }
 
 func (c *conn) Read(p []byte) (int, net.Addr, error) {
@@ -54,6 +90,22 @@ func (c *conn) SetCurrentRemoteAddr(addr net.Addr) {
 	c.mutex.Unlock()
 }
 
+func (c *conn) ECN() protocol.ECN {
+	c.mutex.RLock()
+	disabled := c.ecnDisabled
+	c.mutex.RUnlock()
+	if disabled {
+		return protocol.ECNNon
+	}
+	return getECN(c.pconn)
+}
+
+func (c *conn) DisableECN() {
+	c.mutex.Lock()
+	c.ecnDisabled = true
+	c.mutex.Unlock()
+}
+
 func (c *conn) LocalAddr() net.Addr {
 	return c.pconn.LocalAddr()
 }
@@ -0,0 +1,25 @@
+package quic
+
+import (
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// A batchPacketConn is a net.PacketConn that can read and write multiple packets at once.
+type batchPacketConn interface {
+	net.PacketConn
+	// ReadPackets reads one or more packets, and calls handle for every packet.
+	// The packetBuffer is owned by the callee.
+	ReadPackets(handle func(net.Addr, *packetBuffer, protocol.ECN, []byte)) error
+	// WritePackets sends all packets to addr, marked with the given ECN codepoint.
+	WritePackets(packets [][]byte, addr net.Addr, ecn protocol.ECN) error
+}
+
+// A batchConnection is a connection that can write multiple packets at once.
+type batchConnection interface {
+	connection
+	WriteBatch([][]byte) error
+	// BatchSize is the maximum number of packets that should be passed to WriteBatch.
+	BatchSize() int
+}
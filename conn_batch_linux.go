@@ -0,0 +1,286 @@
+// +build linux
+
+package quic
+
+import (
+	"net"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// socket options for UDP GSO and GRO, see linux/udp.h
+const (
+	udpSegment = 103 // UDP_SEGMENT
+	udpGRO     = 104 // UDP_GRO
+)
+
+// the maximum size of a UDP datagram, this is the maximum size of a GSO / GRO buffer
+const maxUDPPayloadSize = 1<<16 - 1
+
+const batchOOBBufferSize = 128
+
+// batchIO is implemented by both ipv4.PacketConn and ipv6.PacketConn
+type batchIO interface {
+	ReadBatch([]ipv4.Message, int) (int, error)
+	WriteBatch([]ipv4.Message, int) (int, error)
+}
+
+type batchConn struct {
+	net.PacketConn // the wrapped connection
+
+	batch batchIO
+	ecn   protocol.ECN
+
+	gsoEnabled int32 // atomic. GSO is disabled if sending a GSO packet fails.
+	gro        bool
+
+	// only accessed by the go routine reading from the connection
+	readMsgs    []ipv4.Message
+	readBuffers []*packetBuffer // only used if GRO is disabled
+	groBuffers  [][]byte        // only used if GRO is enabled
+}
+
+var _ batchPacketConn = &batchConn{}
+var _ ecnPacketConn = &batchConn{}
+
+// newBatchConn wraps c such that packets are read and written using recvmmsg and sendmmsg.
+// If the kernel supports it, UDP GSO and GRO are enabled.
+// If c is not a UDP connection, it is returned unmodified.
+func newBatchConn(c net.PacketConn) net.PacketConn {
+	if _, ok := c.(batchPacketConn); ok {
+		return c
+	}
+	var udpConn *net.UDPConn
+	switch conn := c.(type) {
+	case *net.UDPConn:
+		udpConn = conn
+	case *ecnConn:
+		udpConn = conn.UDPConn
+	default:
+		return c
+	}
+	rawConn, err := udpConn.SyscallConn()
+	if err != nil {
+		return c
+	}
+	var gso, gro bool
+	if err := rawConn.Control(func(fd uintptr) {
+		// Reading the UDP_SEGMENT socket option succeeds if the kernel supports GSO.
+		_, err := syscall.GetsockoptInt(int(fd), syscall.IPPROTO_UDP, udpSegment)
+		gso = err == nil
+		gro = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_UDP, udpGRO, 1) == nil
+	}); err != nil {
+		return c
+	}
+
+	bc := &batchConn{
+		PacketConn: c,
+		ecn:        getECN(c),
+		gro:        gro,
+		readMsgs:   make([]ipv4.Message, protocol.BatchSize),
+	}
+	if addr, ok := udpConn.LocalAddr().(*net.UDPAddr); ok && addr.IP.To4() != nil {
+		bc.batch = ipv4.NewPacketConn(udpConn)
+	} else {
+		bc.batch = ipv6.NewPacketConn(udpConn)
+	}
+	if gso {
+		bc.gsoEnabled = 1
+	}
+	if gro {
+		bc.groBuffers = make([][]byte, protocol.BatchSize)
+		for i := range bc.groBuffers {
+			bc.groBuffers[i] = make([]byte, maxUDPPayloadSize)
+		}
+	} else {
+		bc.readBuffers = make([]*packetBuffer, protocol.BatchSize)
+	}
+	for i := range bc.readMsgs {
+		bc.readMsgs[i].Buffers = make([][]byte, 1)
+		bc.readMsgs[i].OOB = make([]byte, batchOOBBufferSize)
+	}
+	return bc
+}
+
+func (c *batchConn) ReadPacket(b []byte) (int, net.Addr, protocol.ECN, error) {
+	return readPacket(c.PacketConn, b)
+}
+
+func (c *batchConn) ECN() protocol.ECN { return c.ecn }
+
+func (c *batchConn) WritePacket(b []byte, addr net.Addr, ecn protocol.ECN) error {
+	return writePacket(c.PacketConn, b, addr, ecn)
+}
+
+func (c *batchConn) ReadPackets(handle func(net.Addr, *packetBuffer, protocol.ECN, []byte)) error {
+	for i := range c.readMsgs {
+		msg := &c.readMsgs[i]
+		if c.gro {
+			msg.Buffers[0] = c.groBuffers[i]
+		} else {
+			if c.readBuffers[i] == nil {
+				c.readBuffers[i] = getPacketBuffer()
+			}
+			msg.Buffers[0] = c.readBuffers[i].Slice
+		}
+		msg.OOB = msg.OOB[:cap(msg.OOB)]
+	}
+	n, err := c.batch.ReadBatch(c.readMsgs, 0)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		msg := &c.readMsgs[i]
+		ecn, segmentSize := parseControlMessages(msg.OOB[:msg.NN])
+		if !c.gro {
+			buffer := c.readBuffers[i]
+			c.readBuffers[i] = nil
+			handle(msg.Addr, buffer, ecn, buffer.Slice[:msg.N])
+			continue
+		}
+		// With GRO, the kernel might have coalesced multiple packets into a single buffer.
+		// All packets have the same size, except for the last one, which might be smaller.
+		data := msg.Buffers[0][:msg.N]
+		if segmentSize <= 0 {
+			segmentSize = len(data)
+		}
+		for len(data) > 0 {
+			size := segmentSize
+			if size > len(data) {
+				size = len(data)
+			}
+			buffer := getPacketBuffer()
+			// packets larger than the packet buffer end up truncated, just as they would without GRO
+			l := copy(buffer.Slice, data[:size])
+			handle(msg.Addr, buffer, ecn, buffer.Slice[:l])
+			data = data[size:]
+		}
+	}
+	return nil
+}
+
+func (c *batchConn) WritePackets(packets [][]byte, addr net.Addr, ecn protocol.ECN) error {
+	if len(packets) == 1 {
+		return c.WritePacket(packets[0], addr, ecn)
+	}
+	if atomic.LoadInt32(&c.gsoEnabled) == 1 {
+		gsoMsgs := gsoMessages(packets, addr)
+		c.setECN(gsoMsgs, addr, ecn)
+		n, err := c.writeMessages(gsoMsgs)
+		if err == nil {
+			return nil
+		}
+		// Sending GSO packets might fail, e.g. if the network interface doesn't support checksum offloading.
+		// Disable GSO, and resend the packets that were not sent yet without GSO.
+		atomic.StoreInt32(&c.gsoEnabled, 0)
+		for _, msg := range gsoMsgs[:n] {
+			packets = packets[len(msg.Buffers):]
+		}
+	}
+	msgs := make([]ipv4.Message, len(packets))
+	for i, p := range packets {
+		msgs[i].Buffers = [][]byte{p}
+		msgs[i].Addr = addr
+	}
+	c.setECN(msgs, addr, ecn)
+	_, err := c.writeMessages(msgs)
+	return err
+}
+
+// setECN adds a control message to the messages, if they need to be marked with a different ECN codepoint
+// than the one set by the socket option.
+func (c *batchConn) setECN(msgs []ipv4.Message, addr net.Addr, ecn protocol.ECN) {
+	if ecn == c.ecn {
+		return
+	}
+	for i := range msgs {
+		msgs[i].OOB = appendECNControlMessage(msgs[i].OOB, addr, ecn)
+	}
+}
+
+// writeMessages writes the messages, and returns the number of messages that were sent.
+func (c *batchConn) writeMessages(msgs []ipv4.Message) (int, error) {
+	var sent int
+	for sent < len(msgs) {
+		n, err := c.batch.WriteBatch(msgs[sent:], 0)
+		if n > 0 {
+			sent += n
+		}
+		if err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// gsoMessages groups trains of equal-sized packets into GSO messages.
+// Every packet of a train needs to have the same size, except for the last one, which may be smaller.
+func gsoMessages(packets [][]byte, addr net.Addr) []ipv4.Message {
+	var msgs []ipv4.Message
+	for len(packets) > 0 {
+		segmentSize := len(packets[0])
+		numSegments := 1
+		totalSize := segmentSize
+		for numSegments < len(packets) && numSegments < protocol.MaxGSOSegments {
+			next := len(packets[numSegments])
+			if next > segmentSize || totalSize+next > maxUDPPayloadSize {
+				break
+			}
+			numSegments++
+			totalSize += next
+			if next < segmentSize {
+				break
+			}
+		}
+		msg := ipv4.Message{
+			Buffers: packets[:numSegments],
+			Addr:    addr,
+		}
+		if numSegments > 1 {
+			msg.OOB = appendUDPSegmentSizeMsg(nil, uint16(segmentSize))
+		}
+		msgs = append(msgs, msg)
+		packets = packets[numSegments:]
+	}
+	return msgs
+}
+
+func appendUDPSegmentSizeMsg(b []byte, size uint16) []byte {
+	const dataLen = 2 // payload is a uint16
+	startLen := len(b)
+	b = append(b, make([]byte, syscall.CmsgSpace(dataLen))...)
+	h := (*syscall.Cmsghdr)(unsafe.Pointer(&b[startLen]))
+	h.Level = syscall.IPPROTO_UDP
+	h.Type = udpSegment
+	h.SetLen(syscall.CmsgLen(dataLen))
+	*(*uint16)(unsafe.Pointer(&b[startLen+syscall.CmsgLen(0)])) = size
+	return b
+}
+
+// parseControlMessages parses the ECN codepoint and the GRO segment size from the control messages.
+// The segment size is 0 if the packet was not coalesced by GRO.
+func parseControlMessages(oob []byte) (protocol.ECN, int) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return protocol.ECNNon, 0
+	}
+	ecn := protocol.ECNNon
+	var segmentSize int
+	for _, msg := range msgs {
+		switch {
+		case msg.Header.Level == syscall.IPPROTO_IP && msg.Header.Type == syscall.IP_TOS && len(msg.Data) >= 1:
+			ecn = protocol.ECNFromTOS(msg.Data[0])
+		case msg.Header.Level == syscall.IPPROTO_IPV6 && msg.Header.Type == syscall.IPV6_TCLASS && len(msg.Data) >= 4:
+			// the traffic class is passed as an int in host byte order
+			ecn = protocol.ECNFromTOS(byte(*(*int32)(unsafe.Pointer(&msg.Data[0]))))
+		case msg.Header.Level == syscall.IPPROTO_UDP && msg.Header.Type == udpGRO && len(msg.Data) >= 4:
+			segmentSize = int(*(*int32)(unsafe.Pointer(&msg.Data[0])))
+		}
+	}
+	return ecn, segmentSize
+}
@@ -0,0 +1,160 @@
+// +build linux
+
+package quic
+
+import (
+	"bytes"
+	"errors"
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"golang.org/x/net/ipv4"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Batch connection", func() {
+	var sender, receiver *batchConn
+
+	BeforeEach(func() {
+		newConn := func() *batchConn {
+			udpConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			c := newBatchConn(newECNConn(udpConn))
+			Expect(c).To(BeAssignableToTypeOf(&batchConn{}))
+			return c.(*batchConn)
+		}
+		sender = newConn()
+		receiver = newConn()
+	})
+
+	AfterEach(func() {
+		Expect(sender.Close()).To(Succeed())
+		Expect(receiver.Close()).To(Succeed())
+	})
+
+	readPackets := func(num int) [][]byte {
+		var packets [][]byte
+		for len(packets) < num {
+			Expect(receiver.ReadPackets(func(_ net.Addr, buffer *packetBuffer, ecn protocol.ECN, data []byte) {
+				Expect(ecn).To(Equal(receiver.ECN()))
+				packets = append(packets, append([]byte{}, data...))
+				buffer.Release()
+			})).To(Succeed())
+		}
+		return packets
+	}
+
+	It("writes and reads a single packet", func() {
+		Expect(sender.WritePackets([][]byte{[]byte("foobar")}, receiver.LocalAddr(), sender.ECN())).To(Succeed())
+		Expect(readPackets(1)).To(Equal([][]byte{[]byte("foobar")}))
+	})
+
+	It("writes and reads multiple packets of different sizes", func() {
+		var packets [][]byte
+		for i := 1; i <= 20; i++ {
+			packets = append(packets, bytes.Repeat([]byte{byte(i)}, 50*i))
+		}
+		Expect(sender.WritePackets(packets, receiver.LocalAddr(), sender.ECN())).To(Succeed())
+		Expect(readPackets(len(packets))).To(Equal(packets))
+	})
+
+	It("writes and reads trains of equal-sized packets", func() {
+		var packets [][]byte
+		for i := 1; i <= 30; i++ {
+			packets = append(packets, bytes.Repeat([]byte{byte(i)}, 1000))
+		}
+		packets = append(packets, []byte("short"))
+		Expect(sender.WritePackets(packets, receiver.LocalAddr(), sender.ECN())).To(Succeed())
+		Expect(readPackets(len(packets))).To(Equal(packets))
+	})
+
+	It("only resends the packets that were not sent, if sending a GSO message fails", func() {
+		sender.batch = &failingGSOBatchIO{batchIO: sender.batch, numGSOMessages: 1}
+		sender.gsoEnabled = 1
+		var packets [][]byte
+		for i := 1; i <= 3; i++ {
+			packets = append(packets, bytes.Repeat([]byte{byte(i)}, 1000))
+		}
+		packets = append(packets, []byte("end of the first train"))
+		for i := 4; i <= 6; i++ {
+			packets = append(packets, bytes.Repeat([]byte{byte(i)}, 800))
+		}
+		Expect(gsoMessages(packets, nil)).To(HaveLen(2))
+		Expect(sender.WritePackets(packets, receiver.LocalAddr(), sender.ECN())).To(Succeed())
+		Expect(readPackets(len(packets))).To(Equal(packets))
+		Expect(sender.gsoEnabled).To(BeZero())
+	})
+
+	It("writes packets without ECN marking", func() {
+		var packets [][]byte
+		for i := 1; i <= 5; i++ {
+			packets = append(packets, bytes.Repeat([]byte{byte(i)}, 1000))
+		}
+		Expect(sender.WritePackets(packets, receiver.LocalAddr(), protocol.ECNNon)).To(Succeed())
+		var received int
+		for received < len(packets) {
+			Expect(receiver.ReadPackets(func(_ net.Addr, buffer *packetBuffer, ecn protocol.ECN, _ []byte) {
+				Expect(ecn).To(Equal(protocol.ECNNon))
+				received++
+				buffer.Release()
+			})).To(Succeed())
+		}
+	})
+
+	It("groups packets into GSO messages", func() {
+		packets := [][]byte{
+			make([]byte, 1000),
+			make([]byte, 1000),
+			make([]byte, 500), // ends the train
+			make([]byte, 1000),
+			make([]byte, 1200), // larger than the previous packet
+		}
+		msgs := gsoMessages(packets, nil)
+		Expect(msgs).To(HaveLen(3))
+		Expect(msgs[0].Buffers).To(HaveLen(3))
+		Expect(msgs[0].OOB).ToNot(BeEmpty())
+		Expect(msgs[1].Buffers).To(HaveLen(1))
+		Expect(msgs[1].OOB).To(BeEmpty())
+		Expect(msgs[2].Buffers).To(HaveLen(1))
+	})
+
+	It("limits the number of segments in a GSO message", func() {
+		packets := make([][]byte, protocol.MaxGSOSegments+1)
+		for i := range packets {
+			packets[i] = make([]byte, 100)
+		}
+		msgs := gsoMessages(packets, nil)
+		Expect(msgs).To(HaveLen(2))
+		Expect(msgs[0].Buffers).To(HaveLen(protocol.MaxGSOSegments))
+	})
+})
+
+// failingGSOBatchIO sends the first numGSOMessages GSO messages segment by segment,
+// and fails to send all following GSO messages.
+type failingGSOBatchIO struct {
+	batchIO
+	numGSOMessages int
+}
+
+func (b *failingGSOBatchIO) WriteBatch(msgs []ipv4.Message, flags int) (int, error) {
+	for i, msg := range msgs {
+		if len(msg.OOB) == 0 {
+			if _, err := b.batchIO.WriteBatch(msgs[i:i+1], flags); err != nil {
+				return i, err
+			}
+			continue
+		}
+		if b.numGSOMessages == 0 {
+			return i, errors.New("GSO not supported")
+		}
+		b.numGSOMessages--
+		for _, p := range msg.Buffers {
+			if _, err := b.batchIO.WriteBatch([]ipv4.Message{{Buffers: [][]byte{p}, Addr: msg.Addr}}, flags); err != nil {
+				return i, err
+			}
+		}
+	}
+	return len(msgs), nil
+}
@@ -0,0 +1,9 @@
+// +build !linux
+
+package quic
+
+import "net"
+
+// Batched I/O is only supported on Linux.
+// On other platforms, the net.PacketConn is used as is.
+func newBatchConn(c net.PacketConn) net.PacketConn { return c }
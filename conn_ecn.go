@@ -0,0 +1,58 @@
+package quic
+
+import (
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// The environment variable that can be used to disable ECN.
+// This is useful on networks that mangle or drop ECN-marked packets.
+const disableECNEnv = "QUIC_GO_DISABLE_ECN"
+
+// An ecnPacketConn is a net.PacketConn that marks outgoing packets with an ECN codepoint,
+// and reports the ECN codepoint of received packets.
+type ecnPacketConn interface {
+	net.PacketConn
+	// ReadPacket works like ReadFrom, but also returns the ECN codepoint of the packet.
+	ReadPacket([]byte) (int, net.Addr, protocol.ECN, error)
+	// ECN is the ECN codepoint that outgoing packets are marked with.
+	ECN() protocol.ECN
+	// WritePacket works like WriteTo, but marks the packet with the given ECN codepoint.
+	WritePacket(b []byte, addr net.Addr, ecn protocol.ECN) error
+}
+
+func isECNDisabled() bool {
+	disabled, _ := strconv.ParseBool(os.Getenv(disableECNEnv))
+	return disabled
+}
+
+// readPacket reads a packet from c.
+// If c doesn't support ECN, the ECN codepoint is always protocol.ECNNon.
+func readPacket(c net.PacketConn, b []byte) (int, net.Addr, protocol.ECN, error) {
+	if ec, ok := c.(ecnPacketConn); ok {
+		return ec.ReadPacket(b)
+	}
+	n, addr, err := c.ReadFrom(b)
+	return n, addr, protocol.ECNNon, err
+}
+
+// writePacket writes a packet to c, marked with the given ECN codepoint.
+// If c doesn't support ECN, the packet is sent without setting the codepoint.
+func writePacket(c net.PacketConn, b []byte, addr net.Addr, ecn protocol.ECN) error {
+	if ec, ok := c.(ecnPacketConn); ok && ecn != ec.ECN() {
+		return ec.WritePacket(b, addr, ecn)
+	}
+	_, err := c.WriteTo(b, addr)
+	return err
+}
+
+// getECN returns the ECN codepoint that packets sent on c are marked with.
+func getECN(c net.PacketConn) protocol.ECN {
+	if ec, ok := c.(ecnPacketConn); ok {
+		return ec.ECN()
+	}
+	return protocol.ECNNon
+}
@@ -0,0 +1,94 @@
+// +build linux
+
+package quic
+
+import (
+	"net"
+	"syscall"
+	"unsafe"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+const ecnOOBBufferSize = 64
+
+type ecnConn struct {
+	*net.UDPConn
+
+	// only accessed by the go routine reading from the connection
+	oob []byte
+}
+
+var _ ecnPacketConn = &ecnConn{}
+
+// newECNConn sets the socket options to mark outgoing packets ECT(0),
+// and to receive the TOS field (IPv4) or the traffic class (IPv6) of incoming packets.
+// If ECN is disabled, or c is not a *net.UDPConn, or the socket options can't be set,
+// c is returned unmodified.
+func newECNConn(c net.PacketConn) net.PacketConn {
+	if _, ok := c.(ecnPacketConn); ok {
+		return c
+	}
+	udpConn, ok := c.(*net.UDPConn)
+	if !ok || isECNDisabled() {
+		return c
+	}
+	rawConn, err := udpConn.SyscallConn()
+	if err != nil {
+		return c
+	}
+	var ipv4, ipv6 bool
+	if err := rawConn.Control(func(fd uintptr) {
+		// A dual-stack socket handles both IPv4 and IPv6 packets.
+		// Set both options, and use ECN if at least one of them succeeded.
+		ipv4 = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS, int(protocol.ECT0)) == nil &&
+			syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_RECVTOS, 1) == nil
+		ipv6 = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS, int(protocol.ECT0)) == nil &&
+			syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_RECVTCLASS, 1) == nil
+	}); err != nil || (!ipv4 && !ipv6) {
+		return c
+	}
+	return &ecnConn{
+		UDPConn: udpConn,
+		oob:     make([]byte, ecnOOBBufferSize),
+	}
+}
+
+func (c *ecnConn) ReadPacket(b []byte) (int, net.Addr, protocol.ECN, error) {
+	n, oobn, _, addr, err := c.ReadMsgUDP(b, c.oob)
+	if err != nil {
+		return n, addr, protocol.ECNNon, err
+	}
+	ecn, _ := parseControlMessages(c.oob[:oobn])
+	return n, addr, ecn, nil
+}
+
+func (c *ecnConn) ECN() protocol.ECN { return protocol.ECT0 }
+
+func (c *ecnConn) WritePacket(b []byte, addr net.Addr, ecn protocol.ECN) error {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		_, err := c.WriteTo(b, addr)
+		return err
+	}
+	_, _, err := c.WriteMsgUDP(b, appendECNControlMessage(nil, addr, ecn), udpAddr)
+	return err
+}
+
+// appendECNControlMessage appends a control message that sets the ECN codepoint of an outgoing packet,
+// overriding the codepoint set by the socket option.
+func appendECNControlMessage(b []byte, addr net.Addr, ecn protocol.ECN) []byte {
+	const dataLen = 4 // the codepoint is passed as an int
+	var level, typ int32 = syscall.IPPROTO_IP, syscall.IP_TOS
+	if udpAddr, ok := addr.(*net.UDPAddr); ok && udpAddr.IP.To4() == nil {
+		level, typ = syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS
+	}
+	startLen := len(b)
+	b = append(b, make([]byte, syscall.CmsgSpace(dataLen))...)
+	h := (*syscall.Cmsghdr)(unsafe.Pointer(&b[startLen]))
+	h.Level = level
+	h.Type = typ
+	h.SetLen(syscall.CmsgLen(dataLen))
+	*(*int32)(unsafe.Pointer(&b[startLen+syscall.CmsgLen(0)])) = int32(ecn)
+	return b
+}
@@ -0,0 +1,125 @@
+// +build linux
+
+package quic
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ECN connection", func() {
+	It("marks packets ECT(0) and reads the ECN codepoint", func() {
+		addr, err := net.ResolveUDPAddr("udp4", "localhost:0")
+		Expect(err).ToNot(HaveOccurred())
+		udpConn, err := net.ListenUDP("udp4", addr)
+		Expect(err).ToNot(HaveOccurred())
+		defer udpConn.Close()
+		c := newECNConn(udpConn)
+		Expect(c).To(BeAssignableToTypeOf(&ecnConn{}))
+		Expect(getECN(c)).To(Equal(protocol.ECT0))
+
+		_, err = c.WriteTo([]byte("foobar"), c.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		b := make([]byte, 100)
+		n, _, ecn, err := readPacket(c, b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b[:n]).To(Equal([]byte("foobar")))
+		Expect(ecn).To(Equal(protocol.ECT0))
+	})
+
+	for _, network := range []string{"udp4", "udp6"} {
+		network := network
+
+		It(fmt.Sprintf("sends packets without ECN marking (%s)", network), func() {
+			ip := net.IPv4(127, 0, 0, 1)
+			if network == "udp6" {
+				ip = net.IPv6loopback
+			}
+			udpConn, err := net.ListenUDP(network, &net.UDPAddr{IP: ip})
+			if err != nil {
+				Skip(fmt.Sprintf("%s not supported: %s", network, err))
+			}
+			defer udpConn.Close()
+			c := newECNConn(udpConn)
+			Expect(c).To(BeAssignableToTypeOf(&ecnConn{}))
+
+			Expect(writePacket(c, []byte("foobar"), c.LocalAddr(), protocol.ECNNon)).To(Succeed())
+			b := make([]byte, 100)
+			n, _, ecn, err := readPacket(c, b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b[:n]).To(Equal([]byte("foobar")))
+			Expect(ecn).To(Equal(protocol.ECNNon))
+			// the socket option still applies to other packets
+			Expect(writePacket(c, []byte("raboof"), c.LocalAddr(), protocol.ECT0)).To(Succeed())
+			_, _, ecn, err = readPacket(c, b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ecn).To(Equal(protocol.ECT0))
+		})
+	}
+
+	It("doesn't wrap the connection if ECN is disabled", func() {
+		os.Setenv(disableECNEnv, "true")
+		defer os.Unsetenv(disableECNEnv)
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer udpConn.Close()
+		c := newECNConn(udpConn)
+		Expect(c).To(Equal(udpConn))
+		Expect(getECN(c)).To(Equal(protocol.ECNNon))
+	})
+
+	Context("servers", func() {
+		getTOS := func(c *net.UDPConn) int {
+			rawConn, err := c.SyscallConn()
+			Expect(err).ToNot(HaveOccurred())
+			var tos int
+			Expect(rawConn.Control(func(fd uintptr) {
+				tos, err = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS)
+			})).To(Succeed())
+			Expect(err).ToNot(HaveOccurred())
+			return tos
+		}
+
+		It("uses ECN on connections it created", func() {
+			ln, err := ListenAddr("127.0.0.1:0", testdata.GetTLSConfig(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+			Expect(ln.(*baseServer).conn).To(BeAssignableToTypeOf(&ecnConn{}))
+		})
+
+		It("doesn't set socket options on connections passed to Listen", func() {
+			udpConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			defer udpConn.Close()
+			ln, err := Listen(udpConn, testdata.GetTLSConfig(), nil)
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+			Expect(ln.(*baseServer).conn).To(Equal(udpConn))
+			Expect(getTOS(udpConn)).To(BeZero())
+		})
+
+		It("uses ECN on connections passed to Listen, if enabled", func() {
+			udpConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			defer udpConn.Close()
+			ln, err := Listen(udpConn, testdata.GetTLSConfig(), &Config{EnableECN: true})
+			Expect(err).ToNot(HaveOccurred())
+			defer ln.Close()
+			Expect(ln.(*baseServer).conn).To(BeAssignableToTypeOf(&ecnConn{}))
+			Expect(getTOS(udpConn)).To(Equal(int(protocol.ECT0)))
+		})
+	})
+
+	It("doesn't wrap connections that are not UDP connections", func() {
+		pconn := newMockPacketConn()
+		Expect(newECNConn(pconn)).To(Equal(pconn))
+	})
+})
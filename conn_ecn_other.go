@@ -0,0 +1,9 @@
+// +build !linux
+
+package quic
+
+import "net"
+
+// ECN is only supported on Linux.
+// On other platforms, the net.PacketConn is used as is.
+func newECNConn(c net.PacketConn) net.PacketConn { return c }
@@ -9,9 +9,26 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
+// generateConnID generates a connection ID using the ConnectionIDGenerator.
+// If no ConnectionIDGenerator is set, a random connection ID is generated.
+func generateConnID(g ConnectionIDGenerator, connIDLen int) (protocol.ConnectionID, error) {
+	if g == nil {
+		return protocol.GenerateConnectionID(connIDLen)
+	}
+	b, err := g.GenerateConnectionID()
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != connIDLen {
+		return nil, fmt.Errorf("ConnectionIDGenerator generated a connection ID of invalid length %d (expected %d)", len(b), connIDLen)
+	}
+	return protocol.ConnectionID(b), nil
+}
+
 type connIDGenerator struct {
 	connIDLen  int
 	highestSeq uint64
+	generator  ConnectionIDGenerator
 
 	activeSrcConnIDs        map[uint64]protocol.ConnectionID
 	initialClientDestConnID protocol.ConnectionID
@@ -26,6 +43,7 @@ type connIDGenerator struct {
 func newConnIDGenerator(
 	initialConnectionID protocol.ConnectionID,
 	initialClientDestConnID protocol.ConnectionID, // nil for the client
+	generator ConnectionIDGenerator, // nil if random connection IDs are used
 	addConnectionID func(protocol.ConnectionID) [16]byte,
 	removeConnectionID func(protocol.ConnectionID),
 	retireConnectionID func(protocol.ConnectionID),
@@ -34,6 +52,7 @@ func newConnIDGenerator(
 ) *connIDGenerator {
 	m := &connIDGenerator{
 		connIDLen:          initialConnectionID.Len(),
+		generator:          generator,
 		activeSrcConnIDs:   make(map[uint64]protocol.ConnectionID),
 		addConnectionID:    addConnectionID,
 		removeConnectionID: removeConnectionID,
@@ -52,7 +71,8 @@ func (m *connIDGenerator) SetMaxActiveConnIDs(limit uint64) error {
 	}
 	// The active_connection_id_limit transport parameter is the number of
 	// connection IDs issued in NEW_CONNECTION_IDs frame that the peer will store.
-	for i := uint64(0); i < utils.MinUint64(limit, protocol.MaxIssuedConnectionIDs); i++ {
+	// The connection ID sent in the preferred_address counts towards this limit.
+	for i := m.highestSeq; i < utils.MinUint64(limit, protocol.MaxIssuedConnectionIDs); i++ {
 		if err := m.issueNewConnID(); err != nil {
 			return err
 		}
@@ -78,8 +98,40 @@ func (m *connIDGenerator) Retire(seq uint64) error {
 	return m.issueNewConnID()
 }
 
+// GeneratePreferredAddressConnID generates the connection ID sent in the preferred_address transport parameter.
+// This connection ID has the sequence number 1, so it must be generated before any other connection ID is issued.
+func (m *connIDGenerator) GeneratePreferredAddressConnID() (protocol.ConnectionID, [16]byte, error) {
+	if m.highestSeq != 0 {
+		return nil, [16]byte{}, fmt.Errorf("the preferred_address connection ID must have sequence number 1, but connection IDs up to sequence number %d were already issued", m.highestSeq)
+	}
+	connID, err := generateConnID(m.generator, m.connIDLen)
+	if err != nil {
+		return nil, [16]byte{}, err
+	}
+	m.highestSeq++
+	m.activeSrcConnIDs[m.highestSeq] = connID
+	return connID, m.addConnectionID(connID), nil
+}
+
+// SequenceNumber returns the sequence number of one of our active connection IDs.
+// The connection ID the client used for its first Initial doesn't have a sequence number.
+func (m *connIDGenerator) SequenceNumber(connID protocol.ConnectionID) (uint64, bool) {
+	for seq, c := range m.activeSrcConnIDs {
+		if c.Equal(connID) {
+			return seq, true
+		}
+	}
+	return 0, false
+}
+
+// IsActive says if the connection ID with sequence number seq was issued and not yet retired.
+func (m *connIDGenerator) IsActive(seq uint64) bool {
+	_, ok := m.activeSrcConnIDs[seq]
+	return ok
+}
+
 func (m *connIDGenerator) issueNewConnID() error {
-	connID, err := protocol.GenerateConnectionID(m.connIDLen)
+	connID, err := generateConnID(m.generator, m.connIDLen)
 	if err != nil {
 		return err
 	}
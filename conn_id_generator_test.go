@@ -1,13 +1,41 @@
 package quic
 
 import (
+	"errors"
+
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quiclb"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
+type sequentialConnIDGenerator struct {
+	connIDLen int
+	counter   uint8
+	err       error
+}
+
+var (
+	_ ConnectionIDGenerator = &sequentialConnIDGenerator{}
+	_ ConnectionIDGenerator = &quiclb.Generator{}
+)
+
+func (g *sequentialConnIDGenerator) GenerateConnectionID() ([]byte, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+	g.counter++
+	b := make([]byte, g.connIDLen)
+	for i := range b {
+		b[i] = g.counter
+	}
+	return b, nil
+}
+
+func (g *sequentialConnIDGenerator) ConnectionIDLen() int { return g.connIDLen }
+
 var _ = Describe("Connection ID Generator", func() {
 	var (
 		addedConnIDs       []protocol.ConnectionID
@@ -15,6 +43,7 @@ var _ = Describe("Connection ID Generator", func() {
 		removedConnIDs     []protocol.ConnectionID
 		replacedWithClosed map[string]packetHandler
 		queuedFrames       []wire.Frame
+		generator          ConnectionIDGenerator
 		g                  *connIDGenerator
 	)
 	initialConnID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7}
@@ -26,9 +55,14 @@ var _ = Describe("Connection ID Generator", func() {
 		removedConnIDs = nil
 		queuedFrames = nil
 		replacedWithClosed = make(map[string]packetHandler)
+		generator = nil
+	})
+
+	JustBeforeEach(func() {
 		g = newConnIDGenerator(
 			initialConnID,
 			initialClientDestConnID,
+			generator,
 			func(c protocol.ConnectionID) [16]byte {
 				addedConnIDs = append(addedConnIDs, c)
 				l := uint8(len(addedConnIDs))
@@ -67,6 +101,69 @@ var _ = Describe("Connection ID Generator", func() {
 		Expect(queuedFrames).To(HaveLen(protocol.MaxIssuedConnectionIDs))
 	})
 
+	Context("using a ConnectionIDGenerator", func() {
+		BeforeEach(func() {
+			generator = &sequentialConnIDGenerator{connIDLen: 7}
+		})
+
+		It("uses the ConnectionIDGenerator for new connection IDs", func() {
+			Expect(g.SetMaxActiveConnIDs(3)).To(Succeed())
+			Expect(addedConnIDs).To(Equal([]protocol.ConnectionID{
+				{1, 1, 1, 1, 1, 1, 1},
+				{2, 2, 2, 2, 2, 2, 2},
+				{3, 3, 3, 3, 3, 3, 3},
+			}))
+		})
+
+		It("errors if the ConnectionIDGenerator fails", func() {
+			generator.(*sequentialConnIDGenerator).err = errors.New("generation failed")
+			Expect(g.SetMaxActiveConnIDs(3)).To(MatchError("generation failed"))
+		})
+
+		It("errors if the ConnectionIDGenerator generates connection IDs of the wrong length", func() {
+			generator.(*sequentialConnIDGenerator).connIDLen = 8
+			Expect(g.SetMaxActiveConnIDs(3)).To(MatchError("ConnectionIDGenerator generated a connection ID of invalid length 8 (expected 7)"))
+		})
+	})
+
+	Context("preferred_address", func() {
+		It("generates the connection ID for the preferred_address", func() {
+			connID, token, err := g.GeneratePreferredAddressConnID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connID.Len()).To(Equal(7))
+			Expect(addedConnIDs).To(Equal([]protocol.ConnectionID{connID}))
+			Expect(token).To(Equal([16]byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}))
+			// the connection ID is sent in the transport parameters, not in a NEW_CONNECTION_ID frame
+			Expect(queuedFrames).To(BeEmpty())
+		})
+
+		It("counts the preferred_address connection ID towards the limit", func() {
+			_, _, err := g.GeneratePreferredAddressConnID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+			Expect(addedConnIDs).To(HaveLen(4))
+			Expect(queuedFrames).To(HaveLen(3))
+			for i, f := range queuedFrames {
+				Expect(f.(*wire.NewConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(i + 2))
+			}
+		})
+
+		It("errors if other connection IDs were already issued", func() {
+			Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+			_, _, err := g.GeneratePreferredAddressConnID()
+			Expect(err).To(MatchError("the preferred_address connection ID must have sequence number 1, but connection IDs up to sequence number 4 were already issued"))
+		})
+
+		It("issues a new connection ID when the preferred_address connection ID is retired", func() {
+			connID, _, err := g.GeneratePreferredAddressConnID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(g.Retire(1)).To(Succeed())
+			Expect(retiredConnIDs).To(Equal([]protocol.ConnectionID{connID}))
+			Expect(queuedFrames).To(HaveLen(1))
+			Expect(queuedFrames[0].(*wire.NewConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(2))
+		})
+	})
+
 	It("errors if the peers tries to retire a connection ID that wasn't yet issued", func() {
 		Expect(g.Retire(1)).To(MatchError("PROTOCOL_VIOLATION: tried to retire connection ID 1. Highest issued: 0"))
 	})
@@ -103,6 +200,31 @@ var _ = Describe("Connection ID Generator", func() {
 		Expect(queuedFrames).To(HaveLen(1))
 	})
 
+	It("returns the sequence number of a connection ID", func() {
+		Expect(g.SetMaxActiveConnIDs(3)).To(Succeed())
+		Expect(queuedFrames).To(HaveLen(3))
+		seq, ok := g.SequenceNumber(initialConnID)
+		Expect(ok).To(BeTrue())
+		Expect(seq).To(BeZero())
+		for _, f := range queuedFrames {
+			nf := f.(*wire.NewConnectionIDFrame)
+			seq, ok := g.SequenceNumber(nf.ConnectionID)
+			Expect(ok).To(BeTrue())
+			Expect(seq).To(Equal(nf.SequenceNumber))
+		}
+		_, ok = g.SequenceNumber(initialClientDestConnID)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("says if a connection ID is active", func() {
+		Expect(g.SetMaxActiveConnIDs(3)).To(Succeed())
+		Expect(g.IsActive(0)).To(BeTrue())
+		Expect(g.IsActive(3)).To(BeTrue())
+		Expect(g.IsActive(4)).To(BeFalse())
+		Expect(g.Retire(2)).To(Succeed())
+		Expect(g.IsActive(2)).To(BeFalse())
+	})
+
 	It("retires the client's initial destination connection ID when the handshake completes", func() {
 		g.SetHandshakeComplete()
 		Expect(retiredConnIDs).To(HaveLen(1))
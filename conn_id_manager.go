@@ -25,6 +25,9 @@ type connIDManager struct {
 	packetsSinceLastChange uint64
 	rand                   *mrand.Rand
 	packetsPerConnectionID uint64
+	// When using the multipath extension, every connection ID is bound to a path.
+	// The connection ID of the initial path is then never changed.
+	multipath bool
 
 	addStatelessResetToken    func([16]byte)
 	removeStatelessResetToken func([16]byte)
@@ -56,7 +59,7 @@ func (h *connIDManager) Add(f *wire.NewConnectionIDFrame) error {
 	if err := h.add(f); err != nil {
 		return err
 	}
-	if h.queue.Len() >= protocol.MaxActiveConnectionIDs {
+	if !h.multipath && h.queue.Len() >= protocol.MaxActiveConnectionIDs {
 		h.updateConnectionID()
 	}
 	return nil
@@ -173,11 +176,57 @@ func (h *connIDManager) SetStatelessResetToken(token [16]byte) {
 	h.addStatelessResetToken(token)
 }
 
+// is called when the client migrates to the server's preferred_address
+// The connection ID of the preferred_address becomes the active connection ID,
+// and the connection ID used so far is retired.
+func (h *connIDManager) UsePreferredAddressConnID(connID protocol.ConnectionID, token [16]byte) {
+	h.queue.PushFront(utils.NewConnectionID{
+		SequenceNumber:      1,
+		ConnectionID:        connID,
+		StatelessResetToken: &token,
+	})
+	h.updateConnectionID()
+}
+
 func (h *connIDManager) SentPacket() {
 	h.packetsSinceLastChange++
 }
 
+// EnableMultipath is called when the multipath extension was negotiated.
+// From this point on, the connection ID of the initial path isn't changed any more.
+func (h *connIDManager) EnableMultipath() {
+	h.multipath = true
+}
+
+// GetForPath returns the connection ID with sequence number seq.
+// It is used by the multipath extension, which uses the connection ID with sequence number n on the path with ID n.
+func (h *connIDManager) GetForPath(seq uint64) (protocol.ConnectionID, bool) {
+	if seq == h.activeSequenceNumber {
+		return h.activeConnectionID, true
+	}
+	for el := h.queue.Front(); el != nil; el = el.Next() {
+		if el.Value.SequenceNumber == seq {
+			return el.Value.ConnectionID, true
+		}
+	}
+	return nil, false
+}
+
+// NextPathConnID returns the connection ID with the lowest sequence number for which isUsed returns false.
+// The connection ID of the initial path is never returned.
+func (h *connIDManager) NextPathConnID(isUsed func(uint64) bool) (uint64, protocol.ConnectionID, bool) {
+	for el := h.queue.Front(); el != nil; el = el.Next() {
+		if !isUsed(el.Value.SequenceNumber) {
+			return el.Value.SequenceNumber, el.Value.ConnectionID, true
+		}
+	}
+	return 0, nil, false
+}
+
 func (h *connIDManager) shouldUpdateConnID() bool {
+	if h.multipath {
+		return false
+	}
 	// iniate the first change as early as possible
 	if h.queue.Len() > 0 && h.activeSequenceNumber == 0 {
 		return true
@@ -294,6 +294,75 @@ var _ = Describe("Connection ID Manager", func() {
 		Expect(retiredTokens[0]).To(Equal([16]byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}))
 	})
 
+	It("switches to the connection ID of the preferred_address", func() {
+		m.SetStatelessResetToken([16]byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1})
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      2,
+			ConnectionID:        protocol.ConnectionID{2, 2, 2, 2},
+			StatelessResetToken: [16]byte{2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2},
+		})).To(Succeed())
+		m.UsePreferredAddressConnID(protocol.ConnectionID{1, 3, 3, 7}, [16]byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1})
+		Expect(m.activeSequenceNumber).To(BeEquivalentTo(1))
+		Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 3, 3, 7}))
+		Expect(*tokenAdded).To(Equal([16]byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}))
+		Expect(retiredTokens).To(Equal([][16]byte{{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}}))
+		Expect(frameQueue).To(Equal([]wire.Frame{&wire.RetireConnectionIDFrame{SequenceNumber: 0}}))
+		// the connection ID with sequence number 2 is still available
+		Expect(m.queue.Len()).To(Equal(1))
+		Expect(m.queue.Front().Value.SequenceNumber).To(BeEquivalentTo(2))
+	})
+
+	Context("multipath", func() {
+		BeforeEach(func() {
+			m.EnableMultipath()
+		})
+
+		It("doesn't change the connection ID", func() {
+			for i := uint8(1); i <= protocol.MaxActiveConnectionIDs; i++ {
+				Expect(m.Add(&wire.NewConnectionIDFrame{
+					SequenceNumber:      uint64(i),
+					ConnectionID:        protocol.ConnectionID{i, i, i, i},
+					StatelessResetToken: [16]byte{i, i, i, i, i, i, i, i, i, i, i, i, i, i, i, i},
+				})).To(Succeed())
+			}
+			Expect(frameQueue).To(BeEmpty())
+			for i := 0; i < 2*protocol.PacketsPerConnectionID; i++ {
+				m.SentPacket()
+				Expect(m.Get()).To(Equal(initialConnID))
+			}
+		})
+
+		It("gets connection IDs for paths", func() {
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber: 2,
+				ConnectionID:   protocol.ConnectionID{2, 2, 2, 2},
+			})).To(Succeed())
+			connID, ok := m.GetForPath(0)
+			Expect(ok).To(BeTrue())
+			Expect(connID).To(Equal(initialConnID))
+			connID, ok = m.GetForPath(2)
+			Expect(ok).To(BeTrue())
+			Expect(connID).To(Equal(protocol.ConnectionID{2, 2, 2, 2}))
+			_, ok = m.GetForPath(1)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("returns the next unused connection ID for a new path", func() {
+			for i := uint8(1); i <= 3; i++ {
+				Expect(m.Add(&wire.NewConnectionIDFrame{
+					SequenceNumber: uint64(i),
+					ConnectionID:   protocol.ConnectionID{i, i, i, i},
+				})).To(Succeed())
+			}
+			seq, connID, ok := m.NextPathConnID(func(seq uint64) bool { return seq == 1 })
+			Expect(ok).To(BeTrue())
+			Expect(seq).To(BeEquivalentTo(2))
+			Expect(connID).To(Equal(protocol.ConnectionID{2, 2, 2, 2}))
+			_, _, ok = m.NextPathConnID(func(uint64) bool { return true })
+			Expect(ok).To(BeFalse())
+		})
+	})
+
 	It("removes the currently active stateless reset token when it is closed", func() {
 		m.Close()
 		Expect(retiredTokens).To(BeEmpty())
@@ -17,34 +17,34 @@ import (
 
 const serverAddr = "https://www.stormlin.com/"
 
-// 新建一个 h3 客户端
-func newH3Client() *http.Client {
+// 新建一个客户端, mode 为 h2, h3 或 auto. auto 模式下先使用 h2, 通过 Alt-Svc 发现 h3 之后切换到 h3
+func newClient(mode string) *http.Client {
 	pool, err := x509.SystemCertPool()
 	if err != nil {
 		log.Fatal(err)
 	}
 	testdata.AddRootCA(pool)
-	roundTripper := &http3.RoundTripper{
+	roundTripper := &http3.FallbackRoundTripper{
 		TLSClientConfig: &tls.Config{
 			RootCAs: pool,
 		},
 	}
-	// defer roundTripper.Close()
-	h3Client := &http.Client{
+	switch mode {
+	case "h2":
+		roundTripper.Mode = http3.TransportHTTP2
+	case "h3":
+		roundTripper.Mode = http3.TransportHTTP3
+	case "auto":
+	default:
+		log.Fatalf("unknown mode: %s", mode)
+	}
+	return &http.Client{
 		Transport: roundTripper,
 	}
-	return h3Client
 }
 
 // 实际执行测试的方法
-func runTest(url string) {
-	client := http.Client{}
-
-	// 使用 H3 时的配置
-	// roundTripper := &http3.RoundTripper{}
-	// defer roundTripper.Close()
-	// client.Transport = roundTripper
-
+func runTest(client *http.Client, url string) {
 	timeStart := time.Now()
 	resp, err := client.Get(url)
 	if err != nil {
@@ -58,7 +58,7 @@ func runTest(url string) {
 }
 
 // 该程序以单线程发起请求，循环执行指定次数的请求并输出每次请求所用时间
-// 调用命令 ./main 1KB 50，第一项参数是目标文件大小，第二项是重复次数
+// 调用命令 ./main 1 h3，第一项参数是目标文件大小，第二项是使用的协议 (h2, h3 或 auto, 默认为 h2)
 func main() {
 	target, err := strconv.Atoi(os.Args[1])
 	if err != nil {
@@ -66,6 +66,11 @@ func main() {
 		return
 	}
 	repeat := 50
+	mode := "h2"
+	if len(os.Args) > 2 {
+		mode = os.Args[2]
+	}
+	client := newClient(mode)
 
 	var targetURL string
 	switch target {
@@ -83,7 +88,7 @@ func main() {
 	// 重复指定次数
 	for i := 0; i < repeat; i++ {
 		// 实际执行测试
-		runTest(targetURL)
+		runTest(client, targetURL)
 		// 睡眠 3 秒以使得已发送的数据包能够完全离开网络
 		time.Sleep(time.Duration(500) * time.Millisecond)
 	}
@@ -14,8 +14,9 @@ type h3FileHandler struct {
 func newH3FileServer() http.Handler {
 	mux := http.NewServeMux()
 	mux.Handle("/", http.FileServer(http.Dir("./")))
+	// 按照 Accept-Encoding 压缩静态文件, Range 请求仍然使用 identity 编码
 	return &h3FileHandler{
-		handler: mux,
+		handler: &http3.CompressionHandler{Handler: mux},
 	}
 }
 
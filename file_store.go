@@ -0,0 +1,242 @@
+package quic
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const fileStoreKeySize = 32
+
+const (
+	// fileStoreLockTimeout is the time after which a lock file is considered stale,
+	// e.g. because the process holding the lock crashed.
+	fileStoreLockTimeout = 10 * time.Second
+	// fileStoreLockRetryInterval is the time to wait before trying to acquire a lock again.
+	fileStoreLockRetryInterval = time.Millisecond
+)
+
+type fileStore struct {
+	dir     string
+	aead    cipher.AEAD
+	nameKey []byte
+}
+
+var _ KeyValueStore = &fileStore{}
+
+// NewFileStore creates a KeyValueStore that stores every value in a separate file in dir.
+// Values are encrypted using AES-GCM with a key derived from the 32 byte key, and file names are derived
+// from the key as well, so the directory doesn't reveal which servers a client connected to.
+// Values are written to a temporary file first, and then renamed, so that multiple processes can use the
+// same directory at the same time. Modifications of a value are serialized using a lock file.
+func NewFileStore(dir string, key []byte) (KeyValueStore, error) {
+	if len(key) != fileStoreKeySize {
+		return nil, errors.New("quic: FileStore key must be 32 bytes long")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	r := hkdf.New(sha256.New, key, nil, []byte("quic-go file store"))
+	aesKey := make([]byte, 32)
+	nameKey := make([]byte, 32)
+	if _, err := io.ReadFull(r, aesKey); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(r, nameKey); err != nil {
+		return nil, err
+	}
+	c, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(c)
+	if err != nil {
+		return nil, err
+	}
+	return &fileStore{
+		dir:     dir,
+		aead:    aead,
+		nameKey: nameKey,
+	}, nil
+}
+
+func (s *fileStore) Get(key string) ([]byte, error) {
+	data, err := ioutil.ReadFile(s.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	value, expired, err := s.open(key, data)
+	if err != nil {
+		return nil, err
+	}
+	if expired {
+		return nil, nil
+	}
+	return value, nil
+}
+
+func (s *fileStore) Put(key string, value []byte, expiry time.Time) error {
+	unlock, err := s.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return s.put(key, value, expiry)
+}
+
+func (s *fileStore) put(key string, value []byte, expiry time.Time) error {
+	f, err := ioutil.TempFile(s.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(s.seal(key, value, expiry)); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	// Renaming is atomic, so other processes either read the old or the new value.
+	if err := os.Rename(f.Name(), s.path(key)); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+func (s *fileStore) Take(key string) ([]byte, error) {
+	unlock, err := s.lock(key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Only one process succeeds in renaming the file.
+	r := make([]byte, 8)
+	if _, err := rand.Read(r); err != nil {
+		return nil, err
+	}
+	taken := s.path(key) + ".taken-" + hex.EncodeToString(r)
+	if err := os.Rename(s.path(key), taken); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer os.Remove(taken)
+	data, err := ioutil.ReadFile(taken)
+	if err != nil {
+		return nil, err
+	}
+	value, expired, err := s.open(key, data)
+	if err != nil || expired {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Update replaces the value stored for the key by the value returned by update.
+// Values that can't be decrypted are treated as if no value was stored.
+func (s *fileStore) Update(key string, update func([]byte) ([]byte, time.Time)) error {
+	unlock, err := s.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var value []byte
+	data, err := ioutil.ReadFile(s.path(key))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if v, expired, err := s.open(key, data); err == nil && !expired {
+			value = v
+		}
+	}
+	newValue, expiry := update(value)
+	if newValue == nil {
+		if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return s.put(key, newValue, expiry)
+}
+
+// lock acquires the lock for the key, by creating a lock file.
+// It returns a function that releases the lock.
+func (s *fileStore) lock(key string) (func(), error) {
+	lockPath := s.path(key) + ".lock"
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		// Remove stale lock files, such that a crashed process doesn't block all other processes.
+		if fi, err := os.Stat(lockPath); err == nil && time.Since(fi.ModTime()) > fileStoreLockTimeout {
+			os.Remove(lockPath)
+			continue
+		}
+		time.Sleep(fileStoreLockRetryInterval)
+	}
+}
+
+func (s *fileStore) path(key string) string {
+	h := hmac.New(sha256.New, s.nameKey)
+	h.Write([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(h.Sum(nil)))
+}
+
+// seal encrypts the value, using the key as additional data.
+// The file contains the nonce, followed by the encrypted expiry time (unix nanoseconds, 0 if the value doesn't expire)
+// and the value.
+func (s *fileStore) seal(key string, value []byte, expiry time.Time) []byte {
+	plaintext := make([]byte, 8+len(value))
+	if !expiry.IsZero() {
+		binary.BigEndian.PutUint64(plaintext, uint64(expiry.UnixNano()))
+	}
+	copy(plaintext[8:], value)
+	nonce := make([]byte, s.aead.NonceSize())
+	rand.Read(nonce)
+	return s.aead.Seal(nonce, nonce, plaintext, []byte(key))
+}
+
+func (s *fileStore) open(key string, data []byte) ([]byte /* value */, bool /* expired */, error) {
+	if len(data) < s.aead.NonceSize() {
+		return nil, false, errors.New("quic: FileStore entry too short")
+	}
+	nonce := data[:s.aead.NonceSize()]
+	plaintext, err := s.aead.Open(nil, nonce, data[s.aead.NonceSize():], []byte(key))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(plaintext) < 8 {
+		return nil, false, errors.New("quic: FileStore entry too short")
+	}
+	if expiry := binary.BigEndian.Uint64(plaintext); expiry != 0 && time.Now().UnixNano() >= int64(expiry) {
+		return nil, true, nil
+	}
+	return plaintext[8:], false, nil
+}
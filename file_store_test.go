@@ -0,0 +1,161 @@
+package quic
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("File Store", func() {
+	var (
+		dir   string
+		key   []byte
+		store KeyValueStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "quic-go-file-store")
+		Expect(err).ToNot(HaveOccurred())
+		key = bytes.Repeat([]byte{0x42}, 32)
+		store, err = NewFileStore(dir, key)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("rejects keys of the wrong length", func() {
+		_, err := NewFileStore(dir, make([]byte, 16))
+		Expect(err).To(MatchError("quic: FileStore key must be 32 bytes long"))
+	})
+
+	It("stores values", func() {
+		Expect(store.Put("foo", []byte("foobar"), time.Now().Add(time.Hour))).To(Succeed())
+		Expect(store.Get("foo")).To(Equal([]byte("foobar")))
+		Expect(store.Get("bar")).To(BeNil())
+		Expect(store.Put("foo", []byte("raboof"), time.Now().Add(time.Hour))).To(Succeed())
+		Expect(store.Get("foo")).To(Equal([]byte("raboof")))
+	})
+
+	It("takes values", func() {
+		Expect(store.Put("foo", []byte("foobar"), time.Now().Add(time.Hour))).To(Succeed())
+		Expect(store.Take("foo")).To(Equal([]byte("foobar")))
+		Expect(store.Take("foo")).To(BeNil())
+		Expect(store.Get("foo")).To(BeNil())
+	})
+
+	It("updates values", func() {
+		Expect(store.Update("foo", func(value []byte) ([]byte, time.Time) {
+			Expect(value).To(BeNil())
+			return []byte("foo"), time.Now().Add(time.Hour)
+		})).To(Succeed())
+		Expect(store.Update("foo", func(value []byte) ([]byte, time.Time) {
+			return append(value, []byte("bar")...), time.Now().Add(time.Hour)
+		})).To(Succeed())
+		Expect(store.Get("foo")).To(Equal([]byte("foobar")))
+		// returning nil deletes the value
+		Expect(store.Update("foo", func([]byte) ([]byte, time.Time) { return nil, time.Time{} })).To(Succeed())
+		Expect(store.Get("foo")).To(BeNil())
+		Expect(store.Update("foo", func([]byte) ([]byte, time.Time) { return nil, time.Time{} })).To(Succeed())
+	})
+
+	It("doesn't return expired values", func() {
+		Expect(store.Put("foo", []byte("foobar"), time.Now().Add(-time.Second))).To(Succeed())
+		Expect(store.Get("foo")).To(BeNil())
+		Expect(store.Take("foo")).To(BeNil())
+	})
+
+	It("stores values that don't expire", func() {
+		Expect(store.Put("foo", []byte("foobar"), time.Time{})).To(Succeed())
+		Expect(store.Get("foo")).To(Equal([]byte("foobar")))
+	})
+
+	It("persists values", func() {
+		Expect(store.Put("foo", []byte("foobar"), time.Now().Add(time.Hour))).To(Succeed())
+		store2, err := NewFileStore(dir, key)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(store2.Get("foo")).To(Equal([]byte("foobar")))
+	})
+
+	It("encrypts values and doesn't reveal keys", func() {
+		Expect(store.Put("quic.clemente.io", []byte("foobar"), time.Now().Add(time.Hour))).To(Succeed())
+		files, err := ioutil.ReadDir(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+		Expect(files[0].Name()).ToNot(ContainSubstring("clemente"))
+		data, err := ioutil.ReadFile(dir + "/" + files[0].Name())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).ToNot(ContainSubstring("foobar"))
+	})
+
+	It("can't read values stored with a different key", func() {
+		Expect(store.Put("foo", []byte("foobar"), time.Now().Add(time.Hour))).To(Succeed())
+		store2, err := NewFileStore(dir, bytes.Repeat([]byte{0x13}, 32))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(store2.Get("foo")).To(BeNil())
+	})
+
+	It("only returns a value once when it is taken concurrently", func() {
+		Expect(store.Put("foo", []byte("foobar"), time.Now().Add(time.Hour))).To(Succeed())
+		var wg sync.WaitGroup
+		var mutex sync.Mutex
+		var taken int
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				// use different stores, to simulate different processes
+				s, err := NewFileStore(dir, key)
+				Expect(err).ToNot(HaveOccurred())
+				value, err := s.Take("foo")
+				Expect(err).ToNot(HaveOccurred())
+				if value != nil {
+					mutex.Lock()
+					taken++
+					mutex.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		Expect(taken).To(Equal(1))
+	})
+
+	It("doesn't lose concurrent updates", func() {
+		const num = 20
+		var wg sync.WaitGroup
+		for i := 0; i < num; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				// use different stores, to simulate different processes
+				s, err := NewFileStore(dir, key)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s.Update("foo", func(value []byte) ([]byte, time.Time) {
+					return append(value, 'a'), time.Time{}
+				})).To(Succeed())
+			}()
+		}
+		wg.Wait()
+		Expect(store.Get("foo")).To(HaveLen(num))
+	})
+
+	It("removes stale lock files", func() {
+		lockPath := store.(*fileStore).path("foo") + ".lock"
+		Expect(ioutil.WriteFile(lockPath, nil, 0600)).To(Succeed())
+		past := time.Now().Add(-2 * fileStoreLockTimeout)
+		Expect(os.Chtimes(lockPath, past, past)).To(Succeed())
+		Expect(store.Put("foo", []byte("foobar"), time.Time{})).To(Succeed())
+		Expect(store.Get("foo")).To(Equal([]byte("foobar")))
+		_, err := os.Stat(lockPath)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+})
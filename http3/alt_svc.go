@@ -0,0 +1,119 @@
+package http3
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Alt-Svc 中没有 ma 参数时, 替代服务的有效期 (RFC 7838 第 3.1 节)
+const defaultAltSvcMaxAge = 24 * time.Hour
+
+// altService 是服务端通过 Alt-Svc 头部宣告的一个替代服务
+type altService struct {
+	protocol  string // ALPN, 例如 h3
+	authority string // 替代服务的地址, host 为空时使用原服务的 host
+	expires   time.Time
+}
+
+// parseAltSvc 解析 Alt-Svc 头部的所有值. clear 表示服务端撤销了之前宣告的所有替代服务.
+// 无法解析的项会被忽略.
+func parseAltSvc(values []string, now time.Time) (services []altService, clear bool) {
+	for _, value := range values {
+		for _, alt := range strings.Split(value, ",") {
+			params := strings.Split(alt, ";")
+			first := strings.TrimSpace(params[0])
+			if first == "clear" {
+				return nil, true
+			}
+			i := strings.IndexByte(first, '=')
+			if i <= 0 {
+				continue
+			}
+			authority, err := strconv.Unquote(strings.TrimSpace(first[i+1:]))
+			if err != nil {
+				continue
+			}
+			if _, _, err := net.SplitHostPort(authority); err != nil {
+				continue
+			}
+			maxAge := defaultAltSvcMaxAge
+			for _, param := range params[1:] {
+				param = strings.TrimSpace(param)
+				if !strings.HasPrefix(param, "ma=") {
+					continue
+				}
+				if secs, err := strconv.ParseUint(strings.Trim(param[3:], `"`), 10, 32); err == nil {
+					maxAge = time.Duration(secs) * time.Second
+				}
+			}
+			services = append(services, altService{
+				protocol:  strings.TrimSpace(first[:i]),
+				authority: authority,
+				expires:   now.Add(maxAge),
+			})
+		}
+	}
+	return services, false
+}
+
+// altSvcCache 保存每个 origin 的 HTTP/3 替代服务, 以 host:port 为键
+type altSvcCache struct {
+	protocols []string // 支持的 ALPN, 按照偏好顺序排列
+
+	mutex    sync.Mutex
+	services map[string]altService
+}
+
+func newAltSvcCache(protocols []string) *altSvcCache {
+	return &altSvcCache{
+		protocols: protocols,
+		services:  make(map[string]altService),
+	}
+}
+
+// update 根据 origin 的响应中的 Alt-Svc 头部更新缓存. 只有使用支持的 HTTP/3 版本的替代服务会被保存,
+// 服务端宣告了多个版本时, 选择最偏好的版本. 响应中没有 Alt-Svc 头部时保留原有的替代服务.
+func (c *altSvcCache) update(origin string, hdr http.Header, now time.Time) {
+	values := hdr["Alt-Svc"]
+	if len(values) == 0 {
+		return
+	}
+	services, clear := parseAltSvc(values, now)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if clear {
+		delete(c.services, origin)
+		return
+	}
+	for _, proto := range c.protocols {
+		for _, s := range services {
+			if s.protocol == proto {
+				c.services[origin] = s
+				return
+			}
+		}
+	}
+}
+
+// get 返回 origin 的 HTTP/3 替代服务的地址和 ALPN. 替代服务的 host 为空时, 使用 origin 的 host.
+func (c *altSvcCache) get(origin string, now time.Time) (string, string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	s, ok := c.services[origin]
+	if !ok {
+		return "", "", false
+	}
+	if now.After(s.expires) {
+		delete(c.services, origin)
+		return "", "", false
+	}
+	host, port, _ := net.SplitHostPort(s.authority)
+	if host == "" {
+		host, _, _ = net.SplitHostPort(origin)
+	}
+	return net.JoinHostPort(host, port), s.protocol, true
+}
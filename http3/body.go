@@ -1,10 +1,13 @@
 package http3
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 
 	"github.com/lucas-clemente/quic-go"
+	"golang.org/x/net/http/httpguts"
 )
 
 // The body of a http.Request or http.Response.
@@ -20,16 +23,25 @@ type body struct {
 	reqDoneClosed bool
 
 	onFrameError func()
+	// only set for the http.Response, if server push is enabled
+	onPushPromise func(*pushPromiseFrame) error
+
+	// trailer 指向 http.Request.Trailer 或 http.Response.Trailer, 在读取到 trailer 时被填充.
+	// trailer 使用 decoder 解码, 其 HEADERS 帧不能超过 maxHeaderBytes.
+	trailer        *http.Header
+	decoder        *qpackDecoder
+	maxHeaderBytes uint64
+	readTrailer    bool // 已经读取了 trailer, 之后 stream 上不能再有其他帧
 
 	bytesRemainingInFrame uint64
 }
 
 var _ io.ReadCloser = &body{}
 
-func newRequestBody(str quic.Stream) *body {
+func newRequestBody(str quic.Stream, onFrameError func()) *body {
 	return &body{
 		str:          str,
-		// onFrameError: onFrameError,
+		onFrameError: onFrameError,
 		isRequest:    true,
 	}
 }
@@ -54,19 +66,31 @@ func (r *body) readImpl(b []byte) (int, error) {
 	if r.bytesRemainingInFrame == 0 {
 	parseLoop:
 		for {
-			frame, err := parseNextFrame(r.str)
+			frame, err := parseNextResponseFrame(r.str, r.onPushPromise)
 			if err != nil {
 				return 0, err
 			}
+			if r.readTrailer {
+				if r.onFrameError != nil {
+					r.onFrameError()
+				}
+				return 0, fmt.Errorf("peer sent a frame after the trailers: %T", frame)
+			}
 			switch f := frame.(type) {
 			case *headersFrame:
-				// skip HEADERS frames
+				// DATA 帧之后的 HEADERS 帧包含 trailer, 之后 stream 必须结束
+				if err := r.readTrailerFrame(f); err != nil {
+					return 0, err
+				}
+				r.readTrailer = true
 				continue
 			case *dataFrame:
 				r.bytesRemainingInFrame = f.Length
 				break parseLoop
 			default:
-				r.onFrameError()
+				if r.onFrameError != nil {
+					r.onFrameError()
+				}
 				// parseNextFrame skips over unknown frame types
 				// Therefore, this condition is only entered when we parsed another known frame type.
 				return 0, fmt.Errorf("peer sent an unexpected frame: %T", f)
@@ -85,6 +109,42 @@ func (r *body) readImpl(b []byte) (int, error) {
 	return n, err
 }
 
+// readTrailerFrame 读取并解码 trailer, 把其中的字段合并到 r.trailer 中
+func (r *body) readTrailerFrame(f *headersFrame) error {
+	if r.maxHeaderBytes > 0 && f.Length > r.maxHeaderBytes {
+		return fmt.Errorf("HEADERS frame too large: %d bytes (max: %d)", f.Length, r.maxHeaderBytes)
+	}
+	headerBlock := make([]byte, f.Length)
+	if _, err := io.ReadFull(r.str, headerBlock); err != nil {
+		return err
+	}
+	hfs, err := r.decoder.decode(r.str.StreamID(), headerBlock)
+	if err != nil {
+		return err
+	}
+	trailer := make(http.Header, len(hfs))
+	for _, hf := range hfs {
+		if hf.IsPseudo() {
+			return errors.New("trailers must not contain pseudo header fields")
+		}
+		if !httpguts.ValidTrailerHeader(hf.Name) {
+			return fmt.Errorf("invalid trailer: %s", hf.Name)
+		}
+		trailer.Add(hf.Name, hf.Value)
+	}
+	if r.trailer == nil {
+		return nil
+	}
+	if *r.trailer == nil {
+		*r.trailer = trailer
+		return nil
+	}
+	for k, vv := range trailer {
+		(*r.trailer)[k] = vv
+	}
+	return nil
+}
+
 func (r *body) requestDone() {
 	if r.reqDoneClosed {
 		return
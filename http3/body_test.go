@@ -3,11 +3,13 @@ package http3
 import (
 	"bytes"
 	"fmt"
-	"io"
+	"io/ioutil"
+	"net/http"
 
 	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go"
 	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/marten-seemann/qpack"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -130,16 +132,83 @@ var _ = Describe("Body", func() {
 				Expect(b[:n]).To(Equal([]byte("bar")))
 			})
 
-			It("skips HEADERS frames", func() {
-				buf.Write(getDataFrame([]byte("foo")))
-				(&headersFrame{Length: 10}).Write(buf)
-				buf.Write(make([]byte, 10))
-				buf.Write(getDataFrame([]byte("bar")))
-				b := make([]byte, 6)
-				n, err := io.ReadFull(rb, b)
-				Expect(err).ToNot(HaveOccurred())
-				Expect(n).To(Equal(6))
-				Expect(b).To(Equal([]byte("foobar")))
+			Context("trailers", func() {
+				var trailer http.Header
+
+				getTrailerFrame := func(hfs ...qpack.HeaderField) []byte {
+					headerBlock := encodeStaticHeaders(hfs)
+					b := &bytes.Buffer{}
+					(&headersFrame{Length: uint64(len(headerBlock))}).Write(b)
+					b.Write(headerBlock)
+					return b.Bytes()
+				}
+
+				BeforeEach(func() {
+					str.EXPECT().StreamID().AnyTimes()
+					trailer = http.Header{"Checksum": nil}
+					rb.trailer = &trailer
+				})
+
+				It("reads trailers", func() {
+					buf.Write(getDataFrame([]byte("foo")))
+					buf.Write(getDataFrame([]byte("bar")))
+					buf.Write(getTrailerFrame(
+						qpack.HeaderField{Name: "checksum", Value: "deadbeef"},
+						qpack.HeaderField{Name: "server-timing", Value: "db;dur=42"},
+					))
+					Expect(trailer).To(HaveKeyWithValue("Checksum", BeNil()))
+					data, err := ioutil.ReadAll(rb)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(data).To(Equal([]byte("foobar")))
+					Expect(trailer).To(Equal(http.Header{
+						"Checksum":      []string{"deadbeef"},
+						"Server-Timing": []string{"db;dur=42"},
+					}))
+				})
+
+				It("reads trailers if no trailers were declared", func() {
+					trailer = nil
+					buf.Write(getDataFrame([]byte("foobar")))
+					buf.Write(getTrailerFrame(qpack.HeaderField{Name: "checksum", Value: "deadbeef"}))
+					_, err := ioutil.ReadAll(rb)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(trailer).To(Equal(http.Header{"Checksum": []string{"deadbeef"}}))
+				})
+
+				It("reads trailers on bodies without DATA frames", func() {
+					buf.Write(getTrailerFrame(qpack.HeaderField{Name: "checksum", Value: "deadbeef"}))
+					data, err := ioutil.ReadAll(rb)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(data).To(BeEmpty())
+					Expect(trailer).To(HaveKeyWithValue("Checksum", []string{"deadbeef"}))
+				})
+
+				It("errors on frames after the trailers, and calls the error callback", func() {
+					buf.Write(getTrailerFrame(qpack.HeaderField{Name: "checksum", Value: "deadbeef"}))
+					buf.Write(getDataFrame([]byte("foobar")))
+					_, err := rb.Read([]byte{0})
+					Expect(err).To(MatchError("peer sent a frame after the trailers: *http3.dataFrame"))
+					Expect(errorCbCalled).To(BeTrue())
+				})
+
+				It("errors on pseudo header fields", func() {
+					buf.Write(getTrailerFrame(qpack.HeaderField{Name: ":status", Value: "200"}))
+					_, err := rb.Read([]byte{0})
+					Expect(err).To(MatchError("trailers must not contain pseudo header fields"))
+				})
+
+				It("errors on header fields that are not allowed in trailers", func() {
+					buf.Write(getTrailerFrame(qpack.HeaderField{Name: "content-length", Value: "6"}))
+					_, err := rb.Read([]byte{0})
+					Expect(err).To(MatchError("invalid trailer: content-length"))
+				})
+
+				It("errors on too large trailers", func() {
+					rb.maxHeaderBytes = 10
+					buf.Write(getTrailerFrame(qpack.HeaderField{Name: "checksum", Value: "deadbeef"}))
+					_, err := rb.Read([]byte{0})
+					Expect(err).To(MatchError(ContainSubstring("HEADERS frame too large")))
+				})
 			})
 
 			It("errors when it can't parse the frame", func() {
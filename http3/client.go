@@ -10,7 +10,6 @@ import (
 
 	"github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/internal/utils"
-	"github.com/marten-seemann/qpack"
 )
 
 const defaultUserAgent = "quic-go HTTP/3"
@@ -21,11 +20,19 @@ var defaultQuicConfig = &quic.Config{
 	// KeepAlive:          true,
 }
 
-var dialAddr = quic.DialAddr
+// dialAddr 使用 0-RTT 建立连接, 如果服务端接受 0-RTT, 幂等的请求在握手完成之前就可以发出
+var dialAddr = func(addr string, tlsConf *tls.Config, config *quic.Config) (quic.Session, error) {
+	return quic.DialAddrEarly(addr, tlsConf, config)
+}
 
 type roundTripperOpts struct {
-	DisableCompression bool
-	MaxHeaderBytes     int64
+	DisableCompression    bool
+	EnablePush            bool
+	MaxHeaderBytes        int64
+	QPACKMaxTableCapacity int64
+	QPACKBlockedStreams   int64
+	Logger                quic.Logger
+	Metrics               quic.Metrics
 }
 
 // client 是对外暴露的 h3 client 接口
@@ -33,6 +40,9 @@ type client interface {
 	// 实现 HTTP/3 所必须的两个接口
 	http.RoundTripper
 	io.Closer
+
+	// qpackStats 返回该 client 的所有连接上请求头部的压缩情况
+	qpackStats() QPACKStats
 }
 
 // client is a HTTP3 client doing requests
@@ -48,7 +58,9 @@ type clientI struct {
 
 	logger utils.Logger
 
-	scheduler requestScheduler // 调度器实例
+	scheduler      requestScheduler     // 调度器实例
+	pushes         *pushCache           // 服务端推送的响应, 未启用推送时为 nil
+	qpackCollector *qpackStatsCollector // 所有连接上 QPACK 编码器的统计数据
 }
 
 func newClient(
@@ -63,18 +75,34 @@ func newClient(
 	} else {
 		tlsConf = tlsConf.Clone()
 	}
-	// Replace existing ALPNs by H3
-	tlsConf.NextProtos = []string{nextProtoH3}
 	if quicConfig == nil {
 		quicConfig = defaultQuicConfig
 	}
+	// Replace existing ALPNs by H3.
+	// The server selects the ALPN that corresponds to the QUIC version of the connection.
+	tlsConf.NextProtos = nextProtos(quicConfig)
+	if opts.Logger != nil && quicConfig.Logger == nil {
+		conf := *quicConfig
+		conf.Logger = opts.Logger
+		quicConfig = &conf
+	}
+	if opts.Metrics != nil && quicConfig.Metrics == nil {
+		conf := *quicConfig
+		conf.Metrics = opts.Metrics
+		quicConfig = &conf
+	}
 	quicConfig.MaxIncomingStreams = -1 // don't allow any bidirectional streams
-	logger := utils.DefaultLogger.WithPrefix("h3 client")
+	logger := utils.NewLogger(opts.Logger, "h3 client")
 
 	newClient := &clientI{
-		hostname: authorityAddr("https", hostname),
-		dialer:   dialer,
-		logger:   logger,
+		hostname:       authorityAddr("https", hostname),
+		dialer:         dialer,
+		logger:         logger,
+		qpackCollector: newQPACKStatsCollector(),
+	}
+
+	if opts.EnablePush {
+		newClient.pushes = newPushCache(maxHeaderBytes(opts.MaxHeaderBytes), logger)
 	}
 
 	info := &clientInfo{
@@ -82,8 +110,12 @@ func newClient(
 		tlsConfig:        tlsConf,
 		quicConfig:       quicConfig,
 		requestWriter:    newRequestWriter(logger),
-		decoder:          qpack.NewDecoder(func(hf qpack.HeaderField) {}),
+		qpackConf:        newQPACKConfig(opts.QPACKMaxTableCapacity, opts.QPACKBlockedStreams, newClient.qpackCollector),
 		roundTripperOpts: opts,
+		pushes:           newClient.pushes,
+		dialer:           dialer,
+		logger:           logger,
+		metrics:          opts.Metrics,
 	}
 
 	// 初始化调度器实例
@@ -101,6 +133,10 @@ func (c *clientI) Close() error {
 	return c.scheduler.close()
 }
 
+func (c *clientI) qpackStats() QPACKStats {
+	return c.qpackCollector.get()
+}
+
 // RoundTrip executes a request and returns a response
 // 在这里把一整个大请求视情况拆分成多个小请求进行并行传输
 // req 是上层来的一整个请求，返回的 response 也是以一整个返回的形式向上层提供
@@ -113,9 +149,25 @@ func (c *clientI) RoundTrip(req *http.Request) (*http.Response, error) {
 		return nil, fmt.Errorf("http3 client BUG: RoundTrip called for the wrong client (expected %s, got %s)", c.hostname, req.Host)
 	}
 
+	// 优先使用服务端已经推送的响应
+	if c.pushes != nil {
+		if p := c.pushes.match(req); p != nil {
+			select {
+			case <-p.ready:
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+			if p.err == nil {
+				p.res.Request = req
+				return p.res, nil
+			}
+			c.logger.Debugf("Push of %s failed (%s), sending the request", p.url, p.err)
+		}
+	}
+
 	resp, err := c.scheduler.addAndWait(req)
 	if err != nil {
-		fmt.Println(err.Error())
+		c.logger.Debugf("Request failed: %s", err)
 		return nil, err
 	}
 
@@ -0,0 +1,296 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// defaultMaxPushID 是客户端在 MAX_PUSH_ID 帧中允许服务端使用的最大 push ID
+const defaultMaxPushID = 100
+
+var errPushCanceled = errors.New("http3: push canceled")
+
+// pushKey 唯一标识一个 push: push ID 只在所属的 quicSession 中唯一
+type pushKey struct {
+	sess   quic.Session
+	pushID uint64
+}
+
+// pushedResponse 记录一个服务端推送的响应.
+// PUSH_PROMISE 帧和 push stream 的到达顺序是不确定的, 二者都可能先到.
+type pushedResponse struct {
+	key pushKey
+	url string // 被推送资源的 URL, 在收到 PUSH_PROMISE 帧之后才会设置
+
+	promised bool // 是否已收到 PUSH_PROMISE 帧
+	canceled bool // 是否已经取消了该 push
+
+	ready chan struct{} // 在 res 或 err 被设置之后关闭
+	res   *http.Response
+	err   error
+}
+
+func (p *pushedResponse) isDone() bool {
+	select {
+	case <-p.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// pushCache 保存一个 client 下所有 quicSession 上收到的服务端推送,
+// 并把推送的响应与之后发出的请求相匹配
+type pushCache struct {
+	mutex sync.Mutex
+
+	maxHeaderBytes uint64
+
+	controlStreams map[quic.Session]quic.SendStream
+	pushes         map[pushKey]*pushedResponse
+	byURL          map[string]*pushedResponse // 已被承诺且尚未被请求匹配的推送
+
+	logger utils.Logger
+}
+
+func newPushCache(maxHeaderBytes uint64, logger utils.Logger) *pushCache {
+	return &pushCache{
+		maxHeaderBytes: maxHeaderBytes,
+		controlStreams: make(map[quic.Session]quic.SendStream),
+		pushes:         make(map[pushKey]*pushedResponse),
+		byURL:          make(map[string]*pushedResponse),
+		logger:         logger,
+	}
+}
+
+// pushURL 返回用于匹配推送和请求的 URL
+func pushURL(authority, requestURI string) string {
+	return authorityAddr("https", authority) + requestURI
+}
+
+// addSession 在 sess 上启用服务端推送.
+// controlStream 是客户端的控制 stream, MAX_PUSH_ID 帧已经在其上发送.
+func (c *pushCache) addSession(sess quic.Session, controlStream quic.SendStream) {
+	c.mutex.Lock()
+	c.controlStreams[sess] = controlStream
+	c.mutex.Unlock()
+}
+
+// removeSession 使 sess 上所有尚未完成的推送失败
+func (c *pushCache) removeSession(sess quic.Session, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.controlStreams, sess)
+	for key, p := range c.pushes {
+		if key.sess != sess {
+			continue
+		}
+		delete(c.pushes, key)
+		if c.byURL[p.url] == p {
+			delete(c.byURL, p.url)
+		}
+		if !p.isDone() {
+			p.err = err
+			close(p.ready)
+		}
+	}
+}
+
+// handleCancelPush 处理服务端发送的 CANCEL_PUSH 帧
+func (c *pushCache) handleCancelPush(sess quic.Session, f *cancelPushFrame) error {
+	if f.PushID > defaultMaxPushID {
+		return fmt.Errorf("CANCEL_PUSH for push ID %d exceeds the maximum push ID", f.PushID)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if p, ok := c.pushes[pushKey{sess: sess, pushID: f.PushID}]; ok && !p.isDone() {
+		p.err = errPushCanceled
+		close(p.ready)
+		if c.byURL[p.url] == p {
+			delete(c.byURL, p.url)
+		}
+	}
+	return nil
+}
+
+// handlePushStream 读取 push stream 上推送的响应, 响应头部使用 sess 的 QPACK 解码器 decoder 解码
+func (c *pushCache) handlePushStream(sess quic.Session, decoder *qpackDecoder, str quic.ReceiveStream, pushID uint64) {
+	if pushID > defaultMaxPushID {
+		sess.CloseWithError(quic.ErrorCode(errorIDError), fmt.Sprintf("push ID %d exceeds the maximum push ID", pushID))
+		return
+	}
+	c.mutex.Lock()
+	p := c.getOrCreate(pushKey{sess: sess, pushID: pushID})
+	canceled := p.canceled || p.isDone()
+	c.mutex.Unlock()
+	if canceled {
+		str.CancelRead(quic.ErrorCode(errorRequestCanceled))
+		return
+	}
+
+	res, rerr := readResponseHeaders(str, c.maxHeaderBytes, decoder, nil)
+	if rerr.err == nil {
+		// push stream 是单向的, 响应体只会用到 quic.Stream 的读取部分
+		body := newResponseBody(&receiveOnlyStream{ReceiveStream: str}, make(chan struct{}), func() {
+			sess.CloseWithError(quic.ErrorCode(errorFrameUnexpected), "")
+		})
+		body.trailer = &res.Trailer
+		body.decoder = decoder
+		body.maxHeaderBytes = c.maxHeaderBytes
+		res.Body = body
+	} else {
+		if rerr.streamErr != 0 {
+			str.CancelRead(quic.ErrorCode(rerr.streamErr))
+		}
+		if rerr.connErr != 0 {
+			sess.CloseWithError(quic.ErrorCode(rerr.connErr), rerr.err.Error())
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if p.isDone() {
+		// the push was canceled by the server while we were reading the headers
+		if res != nil {
+			res.Body.Close()
+		}
+		return
+	}
+	p.res = res
+	p.err = rerr.err
+	close(p.ready)
+	if p.canceled && res != nil {
+		res.Body.Close()
+	}
+}
+
+// promiseHandler 返回处理 sess 上收到的 PUSH_PROMISE 帧的回调.
+// 未启用服务端推送时返回 nil, 此时 PUSH_PROMISE 帧被视为非预期的帧.
+func (c *pushCache) promiseHandler(sess quic.Session) func(*pushPromiseFrame) error {
+	if c == nil {
+		return nil
+	}
+	return func(f *pushPromiseFrame) error {
+		if err := c.handlePromise(sess, f); err != nil {
+			sess.CloseWithError(quic.ErrorCode(errorGeneralProtocolError), err.Error())
+			return err
+		}
+		return nil
+	}
+}
+
+func (c *pushCache) handlePromise(sess quic.Session, f *pushPromiseFrame) error {
+	if f.PushID > defaultMaxPushID {
+		return fmt.Errorf("push ID %d exceeds the maximum push ID", f.PushID)
+	}
+	// PUSH_PROMISE 帧中的头部只使用静态表编码
+	hfs, err := decodeStaticHeaders(f.HeaderBlock)
+	if err != nil {
+		return err
+	}
+	req, err := requestFromHeaders(hfs)
+	if err != nil {
+		return err
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return fmt.Errorf("promised request with invalid method %s", req.Method)
+	}
+	url := pushURL(req.Host, req.URL.RequestURI())
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	p := c.getOrCreate(pushKey{sess: sess, pushID: f.PushID})
+	if p.promised {
+		// The same push ID may be referenced on multiple request streams.
+		if p.url != url {
+			return fmt.Errorf("push ID %d promised for both %s and %s", f.PushID, p.url, url)
+		}
+		return nil
+	}
+	p.promised = true
+	p.url = url
+	if req.Method != http.MethodGet {
+		// we only match pushes against GET requests
+		c.cancel(p)
+		return nil
+	}
+	if existing, ok := c.byURL[url]; ok && existing != p {
+		c.logger.Debugf("Canceling duplicate push of %s", url)
+		c.cancel(p)
+		return nil
+	}
+	c.byURL[url] = p
+	return nil
+}
+
+// cancel 取消一个尚未被请求匹配的推送. 调用时必须持有 c.mutex.
+func (c *pushCache) cancel(p *pushedResponse) {
+	p.canceled = true
+	if p.res != nil {
+		p.res.Body.Close()
+	}
+	if str, ok := c.controlStreams[p.key.sess]; ok && !p.isDone() {
+		buf := &bytes.Buffer{}
+		(&cancelPushFrame{PushID: p.key.pushID}).Write(buf)
+		if _, err := str.Write(buf.Bytes()); err != nil {
+			c.logger.Debugf("Sending CANCEL_PUSH failed: %s", err)
+		}
+	}
+}
+
+// getOrCreate 返回 key 对应的推送记录, 不存在时新建一个. 调用时必须持有 c.mutex.
+func (c *pushCache) getOrCreate(key pushKey) *pushedResponse {
+	p, ok := c.pushes[key]
+	if !ok {
+		p = &pushedResponse{key: key, ready: make(chan struct{})}
+		c.pushes[key] = p
+	}
+	return p
+}
+
+// match 查找与 req 相匹配的推送. 一个推送只会被匹配一次.
+func (c *pushCache) match(req *http.Request) *pushedResponse {
+	if req.Method != "" && req.Method != http.MethodGet {
+		return nil
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		return nil
+	}
+	url := pushURL(hostnameFromRequest(req), req.URL.RequestURI())
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	p, ok := c.byURL[url]
+	if !ok {
+		return nil
+	}
+	delete(c.byURL, url)
+	return p
+}
+
+// receiveOnlyStream 把 push stream 包装成 quic.Stream, 以便复用请求 stream 上的响应解析逻辑
+type receiveOnlyStream struct {
+	quic.ReceiveStream
+}
+
+var _ quic.Stream = &receiveOnlyStream{}
+
+func (s *receiveOnlyStream) Write([]byte) (int, error) {
+	return 0, errors.New("http3: write on a push stream")
+}
+
+func (s *receiveOnlyStream) Close() error                       { return nil }
+func (s *receiveOnlyStream) CancelWrite(quic.ErrorCode)         {}
+func (s *receiveOnlyStream) Context() context.Context           { return context.Background() }
+func (s *receiveOnlyStream) SetWriteDeadline(t time.Time) error { return nil }
+func (s *receiveOnlyStream) SetDeadline(t time.Time) error      { return s.SetReadDeadline(t) }
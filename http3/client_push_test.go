@@ -0,0 +1,194 @@
+package http3
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client push", func() {
+	var (
+		c    *pushCache
+		sess *pushTestSession
+	)
+
+	getPushPromise := func(pushID uint64, method, path string) *pushPromiseFrame {
+		buf := &bytes.Buffer{}
+		enc := qpack.NewEncoder(buf)
+		Expect(enc.WriteField(qpack.HeaderField{Name: ":method", Value: method})).To(Succeed())
+		Expect(enc.WriteField(qpack.HeaderField{Name: ":scheme", Value: "https"})).To(Succeed())
+		Expect(enc.WriteField(qpack.HeaderField{Name: ":authority", Value: "www.example.com"})).To(Succeed())
+		Expect(enc.WriteField(qpack.HeaderField{Name: ":path", Value: path})).To(Succeed())
+		return &pushPromiseFrame{PushID: pushID, HeaderBlock: buf.Bytes()}
+	}
+
+	getRequest := func(method, url string) *http.Request {
+		req, err := http.NewRequest(method, url, nil)
+		Expect(err).ToNot(HaveOccurred())
+		return req
+	}
+
+	getResponseStream := func(status int, body []byte) *mockquic.MockStream {
+		buf := &bytes.Buffer{}
+		rw := newResponseWriter(buf, utils.DefaultLogger)
+		rw.WriteHeader(status)
+		rw.Write(body)
+		rw.Flush()
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().StreamID().AnyTimes()
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+		return str
+	}
+
+	BeforeEach(func() {
+		c = newPushCache(defaultMaxResponseHeaderBytes, utils.DefaultLogger)
+		sess = &pushTestSession{}
+	})
+
+	It("doesn't handle PUSH_PROMISE frames if push is disabled", func() {
+		var nilCache *pushCache
+		Expect(nilCache.promiseHandler(sess)).To(BeNil())
+	})
+
+	It("matches a promised push to a GET request", func() {
+		Expect(c.handlePromise(sess, getPushPromise(3, http.MethodGet, "/style.css"))).To(Succeed())
+		Expect(c.match(getRequest(http.MethodGet, "https://www.example.com/script.js"))).To(BeNil())
+		Expect(c.match(getRequest(http.MethodPost, "https://www.example.com/style.css"))).To(BeNil())
+		p := c.match(getRequest(http.MethodGet, "https://www.example.com:443/style.css"))
+		Expect(p).ToNot(BeNil())
+		Expect(p.key).To(Equal(pushKey{sess: sess, pushID: 3}))
+		Expect(p.url).To(Equal("www.example.com:443/style.css"))
+		// a push is only used once
+		Expect(c.match(getRequest(http.MethodGet, "https://www.example.com/style.css"))).To(BeNil())
+	})
+
+	It("accepts the same push ID on multiple request streams", func() {
+		Expect(c.handlePromise(sess, getPushPromise(3, http.MethodGet, "/style.css"))).To(Succeed())
+		Expect(c.handlePromise(sess, getPushPromise(3, http.MethodGet, "/style.css"))).To(Succeed())
+		err := c.handlePromise(sess, getPushPromise(3, http.MethodGet, "/script.js"))
+		Expect(err).To(MatchError("push ID 3 promised for both www.example.com:443/style.css and www.example.com:443/script.js"))
+	})
+
+	It("errors on push IDs larger than the maximum push ID", func() {
+		err := c.handlePromise(sess, getPushPromise(defaultMaxPushID+1, http.MethodGet, "/style.css"))
+		Expect(err).To(MatchError("push ID 101 exceeds the maximum push ID"))
+	})
+
+	It("errors on promises with invalid methods", func() {
+		err := c.handlePromise(sess, getPushPromise(1, http.MethodPost, "/form"))
+		Expect(err).To(MatchError("promised request with invalid method POST"))
+	})
+
+	It("cancels duplicate pushes", func() {
+		controlStr := mockquic.NewMockStream(mockCtrl)
+		c.controlStreams[sess] = controlStr
+		Expect(c.handlePromise(sess, getPushPromise(1, http.MethodGet, "/style.css"))).To(Succeed())
+		expected := &bytes.Buffer{}
+		(&cancelPushFrame{PushID: 2}).Write(expected)
+		controlStr.EXPECT().Write(expected.Bytes())
+		Expect(c.handlePromise(sess, getPushPromise(2, http.MethodGet, "/style.css"))).To(Succeed())
+		Expect(c.match(getRequest(http.MethodGet, "https://www.example.com/style.css")).key.pushID).To(Equal(uint64(1)))
+	})
+
+	It("reads the pushed response", func() {
+		Expect(c.handlePromise(sess, getPushPromise(0, http.MethodGet, "/style.css"))).To(Succeed())
+		c.handlePushStream(sess, nil, getResponseStream(200, []byte("body { }")), 0)
+		p := c.match(getRequest(http.MethodGet, "https://www.example.com/style.css"))
+		Expect(p).ToNot(BeNil())
+		Expect(p.ready).To(BeClosed())
+		Expect(p.err).ToNot(HaveOccurred())
+		Expect(p.res.StatusCode).To(Equal(200))
+		body, err := ioutil.ReadAll(p.res.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(body).To(Equal([]byte("body { }")))
+	})
+
+	It("reads a pushed response that arrives before the PUSH_PROMISE", func() {
+		c.handlePushStream(sess, nil, getResponseStream(404, nil), 7)
+		Expect(c.match(getRequest(http.MethodGet, "https://www.example.com/style.css"))).To(BeNil())
+		Expect(c.handlePromise(sess, getPushPromise(7, http.MethodGet, "/style.css"))).To(Succeed())
+		p := c.match(getRequest(http.MethodGet, "https://www.example.com/style.css"))
+		Expect(p).ToNot(BeNil())
+		Expect(p.ready).To(BeClosed())
+		Expect(p.res.StatusCode).To(Equal(404))
+	})
+
+	It("rejects push streams for canceled pushes", func() {
+		c.controlStreams[sess] = mockquic.NewMockStream(mockCtrl)
+		c.controlStreams[sess].(*mockquic.MockStream).EXPECT().Write(gomock.Any())
+		Expect(c.handlePromise(sess, getPushPromise(1, http.MethodGet, "/style.css"))).To(Succeed())
+		Expect(c.handlePromise(sess, getPushPromise(2, http.MethodGet, "/style.css"))).To(Succeed())
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().CancelRead(quic.ErrorCode(errorRequestCanceled))
+		c.handlePushStream(sess, nil, str, 2)
+	})
+
+	It("fails pending pushes when the server cancels them", func() {
+		Expect(c.handlePromise(sess, getPushPromise(1, http.MethodGet, "/style.css"))).To(Succeed())
+		p := c.match(getRequest(http.MethodGet, "https://www.example.com/style.css"))
+		Expect(p).ToNot(BeNil())
+		Expect(c.handleCancelPush(sess, &cancelPushFrame{PushID: 1})).To(Succeed())
+		Expect(p.ready).To(BeClosed())
+		Expect(p.err).To(MatchError(errPushCanceled))
+	})
+
+	It("errors when the server cancels a push with a too large push ID", func() {
+		err := c.handleCancelPush(sess, &cancelPushFrame{PushID: defaultMaxPushID + 1})
+		Expect(err).To(MatchError("CANCEL_PUSH for push ID 101 exceeds the maximum push ID"))
+	})
+
+	It("fails pending pushes when the session is closed", func() {
+		Expect(c.handlePromise(sess, getPushPromise(1, http.MethodGet, "/style.css"))).To(Succeed())
+		p := c.match(getRequest(http.MethodGet, "https://www.example.com/style.css"))
+		Expect(p).ToNot(BeNil())
+		testErr := errors.New("session closed")
+		c.removeSession(sess, testErr)
+		Expect(p.ready).To(BeClosed())
+		Expect(p.err).To(MatchError(testErr))
+	})
+
+	Context("parsing frames on the request stream", func() {
+		It("passes PUSH_PROMISE frames to the callback", func() {
+			buf := &bytes.Buffer{}
+			(&pushPromiseFrame{PushID: 1, HeaderBlock: []byte("foo")}).Write(buf)
+			(&pushPromiseFrame{PushID: 2, HeaderBlock: []byte("bar")}).Write(buf)
+			(&headersFrame{Length: 42}).Write(buf)
+			var promises []*pushPromiseFrame
+			frame, err := parseNextResponseFrame(buf, func(f *pushPromiseFrame) error {
+				promises = append(promises, f)
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&headersFrame{Length: 42}))
+			Expect(promises).To(HaveLen(2))
+			Expect(promises[0].PushID).To(BeEquivalentTo(1))
+			Expect(promises[1].PushID).To(BeEquivalentTo(2))
+		})
+
+		It("returns PUSH_PROMISE frames if push is disabled", func() {
+			buf := &bytes.Buffer{}
+			(&pushPromiseFrame{PushID: 1, HeaderBlock: []byte("foo")}).Write(buf)
+			frame, err := parseNextResponseFrame(buf, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(BeAssignableToTypeOf(&pushPromiseFrame{}))
+		})
+
+		It("returns errors from the callback", func() {
+			buf := &bytes.Buffer{}
+			(&pushPromiseFrame{PushID: 1, HeaderBlock: []byte("foo")}).Write(buf)
+			testErr := errors.New("invalid push")
+			_, err := parseNextResponseFrame(buf, func(*pushPromiseFrame) error { return testErr })
+			Expect(err).To(MatchError(testErr))
+		})
+	})
+})
@@ -0,0 +1,214 @@
+package http3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// maxRequestRetries 是一个请求因未被服务端处理而被重试的最大次数
+const maxRequestRetries = 3
+
+// clientSessionState 保存客户端在一条 quicSession 上的 HTTP/3 状态
+type clientSessionState struct {
+	sess   quic.Session
+	pushes *pushCache // 服务端推送的响应, 未启用推送时为 nil
+
+	qpackConf *qpackConfig
+	encoder   *qpackEncoder // 编码请求头部
+	decoder   *qpackDecoder // 解码响应头部
+
+	mutex            sync.Mutex
+	hasControlStream bool          // 是否已经收到服务端的控制 stream
+	goAwayReceived   bool          // 是否已经收到服务端的 GOAWAY 帧
+	goAwayID         quic.StreamID // 服务端最后一个 GOAWAY 帧中的 stream ID
+	goAway           chan struct{} // 在收到第一个 GOAWAY 帧时关闭
+
+	logger utils.Logger
+}
+
+func newClientSessionState(sess quic.Session, pushes *pushCache, qpackConf *qpackConfig, logger utils.Logger) *clientSessionState {
+	encoder, decoder := qpackConf.newCodec(sess)
+	return &clientSessionState{
+		sess:      sess,
+		pushes:    pushes,
+		qpackConf: qpackConf,
+		encoder:   encoder,
+		decoder:   decoder,
+		goAway:    make(chan struct{}),
+		logger:    logger,
+	}
+}
+
+// openRequestStream 为请求打开一个新的 stream.
+// 使用 0-RTT 时, 握手完成之前发送的数据可能被攻击者重放, 因此只有幂等的 GET 和 HEAD 请求
+// 会作为 early data 立即发送, 其他请求要等到握手完成之后才发送.
+func (s *clientSessionState) openRequestStream(ctx context.Context, method string) (quic.Stream, error) {
+	if sess, ok := s.sess.(quic.EarlySession); ok && method != http.MethodGet && method != http.MethodHead {
+		select {
+		case <-sess.HandshakeComplete().Done():
+		case <-sess.Context().Done():
+			return nil, errors.New("http3: session closed before the handshake completed")
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return s.sess.OpenStreamSync(ctx)
+}
+
+// goingAway 返回服务端是否已经通过 GOAWAY 帧宣告关闭该连接.
+// 此后不应在该连接上发送新的请求.
+func (s *clientSessionState) goingAway() bool {
+	if s == nil {
+		return false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.goAwayReceived
+}
+
+// acceptStreams 接受服务端打开的单向 stream, 直到 sess 被关闭
+func (s *clientSessionState) acceptStreams() {
+	for {
+		str, err := s.sess.AcceptUniStream(context.Background())
+		if err != nil {
+			if s.pushes != nil {
+				s.pushes.removeSession(s.sess, err)
+			}
+			s.qpackConf.closeCodec(s.encoder, s.decoder, err)
+			return
+		}
+		go s.handleUniStream(str)
+	}
+}
+
+func (s *clientSessionState) handleUniStream(str quic.ReceiveStream) {
+	br := &byteReaderImpl{str}
+	streamType, err := utils.ReadVarInt(br)
+	if err != nil {
+		return
+	}
+	switch streamType {
+	case 0x0: // control stream
+		s.mutex.Lock()
+		duplicate := s.hasControlStream
+		s.hasControlStream = true
+		s.mutex.Unlock()
+		if duplicate {
+			s.sess.CloseWithError(quic.ErrorCode(errorStreamCreationError), "duplicate control stream")
+			return
+		}
+		s.handleControlStream(str)
+	case 0x1: // push stream
+		pushID, err := utils.ReadVarInt(br)
+		if err != nil {
+			return
+		}
+		if s.pushes == nil {
+			// we never sent a MAX_PUSH_ID frame
+			s.sess.CloseWithError(quic.ErrorCode(errorIDError), "received a push stream, but push is disabled")
+			return
+		}
+		s.pushes.handlePushStream(s.sess, s.decoder, str, pushID)
+	case 0x2, 0x3: // QPACK encoder and decoder streams
+		handleQPACKStream(s.sess, streamType, str, s.encoder, s.decoder)
+	default:
+		// unknown stream types are ignored
+		str.CancelRead(quic.ErrorCode(errorStreamCreationError))
+	}
+}
+
+// handleControlStream 处理服务端的控制 stream, 直到发生错误
+func (s *clientSessionState) handleControlStream(str quic.ReceiveStream) {
+	rerr := readControlStream(str, func(f *settingsFrame) requestError {
+		if err := s.encoder.applySettings(f); err != nil {
+			return newConnError(errorInternalError, err)
+		}
+		return requestError{}
+	}, func(f frame) requestError {
+		switch f := f.(type) {
+		case *goAwayFrame:
+			if err := s.handleGoAway(f); err != nil {
+				return newConnError(errorIDError, err)
+			}
+		case *cancelPushFrame:
+			if s.pushes == nil {
+				return newConnError(errorIDError, fmt.Errorf("CANCEL_PUSH for push ID %d, but push is disabled", f.PushID))
+			}
+			if err := s.pushes.handleCancelPush(s.sess, f); err != nil {
+				return newConnError(errorIDError, err)
+			}
+		case *maxPushIDFrame:
+			// only clients send MAX_PUSH_ID frames
+			return newConnError(errorFrameUnexpected, errors.New("server sent a MAX_PUSH_ID frame"))
+		}
+		return requestError{}
+	})
+	s.logger.Debugf("Handling the control stream failed: %s", rerr.err)
+	closeWithRequestError(s.sess, rerr)
+}
+
+// handleGoAway 处理服务端发送的 GOAWAY 帧. 服务端可以发送多个 GOAWAY 帧, 但其中的 stream ID 不能增大.
+func (s *clientSessionState) handleGoAway(f *goAwayFrame) error {
+	if f.StreamID%4 != 0 {
+		return fmt.Errorf("GOAWAY for invalid stream ID %d", f.StreamID)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.goAwayReceived && f.StreamID > s.goAwayID {
+		return fmt.Errorf("GOAWAY stream ID increased from %d to %d", s.goAwayID, f.StreamID)
+	}
+	if !s.goAwayReceived {
+		s.goAwayReceived = true
+		close(s.goAway)
+	}
+	s.goAwayID = f.StreamID
+	return nil
+}
+
+// isUnprocessed 判断 str 上的请求是否确定没有被服务端处理, 此类请求可以在新的连接上安全地重试.
+// 被服务端以 H3_REQUEST_REJECTED 重置的请求, 以及 stream ID 不小于 GOAWAY 帧中 stream ID 的请求都没有被处理.
+// str 为 nil 表示请求 stream 没能打开, 此时只有在收到 GOAWAY 帧之后才重试.
+func (s *clientSessionState) isUnprocessed(str quic.Stream, err error) bool {
+	if serr, ok := err.(quic.StreamError); ok && errorCode(serr.ErrorCode()) == errorRequestRejected {
+		return true
+	}
+	if s == nil {
+		return false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.goAwayReceived {
+		return false
+	}
+	return str == nil || str.StreamID() >= s.goAwayID
+}
+
+// prepareRetry 为在新的连接上重试 block 中的请求做准备. 如果请求不能被重试, 返回 false.
+// 带有请求体的请求只有在可以通过 GetBody 重新获取请求体时才能重试. 已经被取消的请求不会被重试.
+func (block *requestControlBlock) prepareRetry() bool {
+	if block.retries >= maxRequestRetries || block.canceled() {
+		return false
+	}
+	// 子请求没有 request, 在执行时才根据 url 构造
+	if req := block.request; req != nil && req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return false
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return false
+		}
+		newReq := *req
+		newReq.Body = body
+		block.request = &newReq
+	}
+	block.retries++
+	block.designatedSession = nil
+	return true
+}
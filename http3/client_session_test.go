@@ -0,0 +1,231 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client session", func() {
+	var (
+		sess  *mockquic.MockSession
+		state *clientSessionState
+	)
+
+	getUniStream := func(data []byte) *mockquic.MockStream {
+		buf := bytes.NewBuffer(data)
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+		return str
+	}
+
+	getRequestStream := func(id quic.StreamID) *mockquic.MockStream {
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().StreamID().Return(id).AnyTimes()
+		return str
+	}
+
+	BeforeEach(func() {
+		sess = mockquic.NewMockSession(mockCtrl)
+		state = newClientSessionState(sess, nil, nil, utils.DefaultLogger)
+	})
+
+	Context("control stream", func() {
+		It("handles GOAWAY frames", func() {
+			buf := &bytes.Buffer{}
+			buf.WriteByte(0x0) // stream type
+			(&settingsFrame{}).Write(buf)
+			(&goAwayFrame{StreamID: 8}).Write(buf)
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorClosedCriticalStream), gomock.Any())
+			state.handleUniStream(getUniStream(buf.Bytes()))
+			Expect(state.goingAway()).To(BeTrue())
+			Expect(state.goAway).To(BeClosed())
+			Expect(state.goAwayID).To(Equal(quic.StreamID(8)))
+		})
+
+		It("closes the session when the server opens a second control stream", func() {
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorClosedCriticalStream), gomock.Any())
+			state.handleUniStream(getUniStream([]byte{0x0, 0x4, 0x0})) // an empty SETTINGS frame
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorStreamCreationError), gomock.Any())
+			state.handleUniStream(getUniStream([]byte{0x0}))
+		})
+
+		It("closes the session when the server sends a MAX_PUSH_ID frame", func() {
+			buf := &bytes.Buffer{}
+			buf.WriteByte(0x0) // stream type
+			(&settingsFrame{}).Write(buf)
+			(&maxPushIDFrame{PushID: 10}).Write(buf)
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorFrameUnexpected), gomock.Any())
+			state.handleUniStream(getUniStream(buf.Bytes()))
+		})
+
+		It("closes the session when the server cancels a push, but push is disabled", func() {
+			buf := &bytes.Buffer{}
+			buf.WriteByte(0x0) // stream type
+			(&settingsFrame{}).Write(buf)
+			(&cancelPushFrame{PushID: 1}).Write(buf)
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorIDError), gomock.Any())
+			state.handleUniStream(getUniStream(buf.Bytes()))
+		})
+
+		It("closes the session when the server opens a push stream, but push is disabled", func() {
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorIDError), gomock.Any())
+			state.handleUniStream(getUniStream([]byte{0x1, 0x0}))
+		})
+
+		It("ignores unknown stream types", func() {
+			str := getUniStream([]byte{0x21})
+			str.EXPECT().CancelRead(quic.ErrorCode(errorStreamCreationError))
+			state.handleUniStream(str)
+		})
+	})
+
+	Context("opening request streams", func() {
+		var earlySess *mockquic.MockEarlySession
+
+		BeforeEach(func() {
+			earlySess = mockquic.NewMockEarlySession(mockCtrl)
+			state = newClientSessionState(earlySess, nil, nil, utils.DefaultLogger)
+		})
+
+		It("sends GET and HEAD requests before the handshake completes", func() {
+			str := getRequestStream(0)
+			earlySess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil).Times(2)
+			s, err := state.openRequestStream(context.Background(), http.MethodGet)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s).To(Equal(str))
+			s, err = state.openRequestStream(context.Background(), http.MethodHead)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s).To(Equal(str))
+		})
+
+		It("waits for the handshake to complete before sending other requests", func() {
+			handshakeCtx, handshakeComplete := context.WithCancel(context.Background())
+			earlySess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+			earlySess.EXPECT().Context().Return(context.Background())
+			str := getRequestStream(0)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				s, err := state.openRequestStream(context.Background(), http.MethodPost)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s).To(Equal(str))
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			earlySess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
+			handshakeComplete()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("returns an error when the session is closed before the handshake completes", func() {
+			earlySess.EXPECT().HandshakeComplete().Return(context.Background())
+			sessCtx, closeSess := context.WithCancel(context.Background())
+			closeSess()
+			earlySess.EXPECT().Context().Return(sessCtx)
+			_, err := state.openRequestStream(context.Background(), http.MethodPost)
+			Expect(err).To(MatchError("http3: session closed before the handshake completed"))
+		})
+
+		It("stops waiting for the handshake when the request is canceled", func() {
+			earlySess.EXPECT().HandshakeComplete().Return(context.Background())
+			earlySess.EXPECT().Context().Return(context.Background())
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := state.openRequestStream(ctx, http.MethodPut)
+			Expect(err).To(MatchError(context.Canceled))
+		})
+	})
+
+	Context("GOAWAY", func() {
+		It("accepts GOAWAY frames with decreasing stream IDs", func() {
+			Expect(state.handleGoAway(&goAwayFrame{StreamID: 12})).To(Succeed())
+			Expect(state.handleGoAway(&goAwayFrame{StreamID: 12})).To(Succeed())
+			Expect(state.handleGoAway(&goAwayFrame{StreamID: 4})).To(Succeed())
+			Expect(state.goAwayID).To(Equal(quic.StreamID(4)))
+		})
+
+		It("errors when the stream ID increases", func() {
+			Expect(state.handleGoAway(&goAwayFrame{StreamID: 4})).To(Succeed())
+			Expect(state.handleGoAway(&goAwayFrame{StreamID: 8})).To(MatchError("GOAWAY stream ID increased from 4 to 8"))
+		})
+
+		It("errors on stream IDs that don't belong to client-initiated bidirectional streams", func() {
+			Expect(state.handleGoAway(&goAwayFrame{StreamID: 3})).To(MatchError("GOAWAY for invalid stream ID 3"))
+			Expect(state.goingAway()).To(BeFalse())
+		})
+	})
+
+	Context("detecting unprocessed requests", func() {
+		It("detects requests rejected by the server", func() {
+			err := &streamResetError{code: errorRequestRejected}
+			Expect(state.isUnprocessed(getRequestStream(0), err)).To(BeTrue())
+			err = &streamResetError{code: errorRequestCanceled}
+			Expect(state.isUnprocessed(getRequestStream(0), err)).To(BeFalse())
+		})
+
+		It("detects requests on streams that the server didn't process before going away", func() {
+			testErr := errors.New("test error")
+			Expect(state.isUnprocessed(getRequestStream(8), testErr)).To(BeFalse())
+			Expect(state.isUnprocessed(nil, testErr)).To(BeFalse())
+			Expect(state.handleGoAway(&goAwayFrame{StreamID: 8})).To(Succeed())
+			Expect(state.isUnprocessed(getRequestStream(4), testErr)).To(BeFalse())
+			Expect(state.isUnprocessed(getRequestStream(8), testErr)).To(BeTrue())
+			Expect(state.isUnprocessed(getRequestStream(12), testErr)).To(BeTrue())
+			Expect(state.isUnprocessed(nil, testErr)).To(BeTrue())
+		})
+	})
+
+	Context("preparing retries", func() {
+		It("retries requests without a body", func() {
+			req, err := http.NewRequest(http.MethodGet, "https://www.example.com", nil)
+			Expect(err).ToNot(HaveOccurred())
+			block := &requestControlBlock{request: req, designatedSession: &sessionControlblock{}}
+			Expect(block.prepareRetry()).To(BeTrue())
+			Expect(block.request).To(Equal(req))
+			Expect(block.designatedSession).To(BeNil())
+			Expect(block.retries).To(Equal(1))
+		})
+
+		It("limits the number of retries", func() {
+			req, err := http.NewRequest(http.MethodGet, "https://www.example.com", nil)
+			Expect(err).ToNot(HaveOccurred())
+			block := &requestControlBlock{request: req}
+			for i := 0; i < maxRequestRetries; i++ {
+				Expect(block.prepareRetry()).To(BeTrue())
+			}
+			Expect(block.prepareRetry()).To(BeFalse())
+		})
+
+		It("rewinds the request body", func() {
+			req, err := http.NewRequest(http.MethodPost, "https://www.example.com", bytes.NewReader([]byte("foobar")))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = ioutil.ReadAll(req.Body)
+			Expect(err).ToNot(HaveOccurred())
+			block := &requestControlBlock{request: req}
+			Expect(block.prepareRetry()).To(BeTrue())
+			Expect(block.request).ToNot(BeIdenticalTo(req))
+			body, err := ioutil.ReadAll(block.request.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(body).To(Equal([]byte("foobar")))
+		})
+
+		It("doesn't retry requests with a body that can't be rewound", func() {
+			req, err := http.NewRequest(http.MethodPost, "https://www.example.com", bytes.NewReader([]byte("foobar")))
+			Expect(err).ToNot(HaveOccurred())
+			req.GetBody = nil
+			block := &requestControlBlock{request: req}
+			Expect(block.prepareRetry()).To(BeFalse())
+		})
+	})
+})
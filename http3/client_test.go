@@ -48,7 +48,7 @@ var _ = Describe("Client", func() {
 		var dialAddrCalled bool
 		dialAddr = func(_ string, tlsConf *tls.Config, quicConf *quic.Config) (quic.Session, error) {
 			Expect(quicConf).To(Equal(defaultQuicConfig))
-			Expect(tlsConf.NextProtos).To(Equal([]string{nextProtoH3}))
+			Expect(tlsConf.NextProtos).To(Equal([]string{nextProtoH3, nextProtoH3Draft29, nextProtoH3Draft24}))
 			dialAddrCalled = true
 			return nil, errors.New("test done")
 		}
@@ -85,7 +85,7 @@ var _ = Describe("Client", func() {
 		) (quic.Session, error) {
 			Expect(hostname).To(Equal("localhost:1337"))
 			Expect(tlsConfP.ServerName).To(Equal(tlsConf.ServerName))
-			Expect(tlsConfP.NextProtos).To(Equal([]string{nextProtoH3}))
+			Expect(tlsConfP.NextProtos).To(Equal([]string{nextProtoH3, nextProtoH3Draft29, nextProtoH3Draft24}))
 			Expect(quicConfP.IdleTimeout).To(Equal(quicConf.IdleTimeout))
 			dialAddrCalled = true
 			return nil, errors.New("test done")
@@ -185,6 +185,7 @@ var _ = Describe("Client", func() {
 			rspBuf := &bytes.Buffer{}
 			rw := newResponseWriter(rspBuf, utils.DefaultLogger)
 			rw.WriteHeader(418)
+			rw.Flush()
 
 			sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
 			str.EXPECT().Write(gomock.Any()).AnyTimes()
@@ -285,7 +286,7 @@ var _ = Describe("Client", func() {
 			It("cancels the stream when the HEADERS frame is too large", func() {
 				buf := &bytes.Buffer{}
 				(&headersFrame{Length: 1338}).Write(buf)
-				str.EXPECT().CancelWrite(quic.ErrorCode(errorFrameError))
+				str.EXPECT().CancelWrite(quic.ErrorCode(errorExcessiveLoad))
 				closed := make(chan struct{})
 				str.EXPECT().Close().Do(func() { close(closed) })
 				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(b []byte) (int, error) {
@@ -330,6 +331,7 @@ var _ = Describe("Client", func() {
 				rspBuf := &bytes.Buffer{}
 				rw := newResponseWriter(rspBuf, utils.DefaultLogger)
 				rw.WriteHeader(418)
+				rw.Flush()
 
 				ctx, cancel := context.WithCancel(context.Background())
 				req := request.WithContext(ctx)
@@ -414,6 +416,7 @@ var _ = Describe("Client", func() {
 				gz := gzip.NewWriter(rw)
 				gz.Write([]byte("gzipped response"))
 				gz.Close()
+				rw.Flush()
 				str.EXPECT().Write(gomock.Any()).AnyTimes()
 				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
 					return buf.Read(p)
@@ -435,6 +438,7 @@ var _ = Describe("Client", func() {
 				buf := &bytes.Buffer{}
 				rw := newResponseWriter(buf, utils.DefaultLogger)
 				rw.Write([]byte("not gzipped"))
+				rw.Flush()
 				str.EXPECT().Write(gomock.Any()).AnyTimes()
 				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
 					return buf.Read(p)
@@ -0,0 +1,435 @@
+package http3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultCompressionMinSize   = 1024
+	defaultCompressionCacheSize = 32 << 20
+	// maxCompressionBufferSize 是缓存响应体的最大大小. 更大的响应在传输的同时被压缩, 不设置 Content-Length.
+	maxCompressionBufferSize = 4 << 20
+)
+
+// A ContentEncoder is a content coding that can be used by the CompressionHandler.
+type ContentEncoder struct {
+	// Name is the name of the content coding, as used in the Accept-Encoding and Content-Encoding headers,
+	// e.g. "gzip" or "br".
+	Name string
+	// NewWriter returns a writer that compresses the data written to it and writes it to w.
+	// If the writer has a Flush() error method, it is called when the handler flushes the response.
+	NewWriter func(w io.Writer) (io.WriteCloser, error)
+}
+
+// GzipEncoder is the ContentEncoder for the gzip content coding.
+var GzipEncoder = ContentEncoder{
+	Name: "gzip",
+	NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	},
+}
+
+// A CompressionHandler compresses the responses of Handler, using the content coding that the client
+// prefers according to the Accept-Encoding request header.
+// Only successful responses with a compressible Content-Type are compressed.
+// Requests with a Range header are always served with the identity encoding,
+// so that byte ranges refer to the original representation.
+// Compressed responses of static files, i.e. responses with a Last-Modified or ETag header, are cached.
+// CONNECT requests, e.g. WebTransport sessions, are passed to Handler unmodified.
+type CompressionHandler struct {
+	Handler http.Handler
+
+	// Encoders are the supported content codings, in order of preference if the client accepts multiple
+	// codings with the same quality. If nil, only GzipEncoder is used.
+	// Other codings, e.g. brotli, can be added by providing a ContentEncoder for them.
+	Encoders []ContentEncoder
+
+	// MinSize is the minimum size of a response body to be compressed.
+	// Zero means to use a default size of 1 KB, a negative value means that all responses are compressed.
+	MinSize int
+
+	// MaxCacheSize is the maximum total size of the cached compressed responses.
+	// Zero means to use a default size of 32 MB, a negative value disables the cache.
+	MaxCacheSize int64
+
+	cacheOnce sync.Once
+	cache     *compressionCache
+}
+
+var _ http.Handler = &CompressionHandler{}
+
+func (h *CompressionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		// CONNECT 请求建立的是隧道, 响应体不能被缓存或者压缩
+		h.Handler.ServeHTTP(w, r)
+		return
+	}
+	cw := &compressionWriter{
+		ResponseWriter: w,
+		handler:        h,
+		req:            r,
+	}
+	defer cw.finish()
+	h.Handler.ServeHTTP(cw, r)
+}
+
+func (h *CompressionHandler) encoders() []ContentEncoder {
+	if h.Encoders == nil {
+		return []ContentEncoder{GzipEncoder}
+	}
+	return h.Encoders
+}
+
+func (h *CompressionHandler) minSize() int {
+	if h.MinSize == 0 {
+		return defaultCompressionMinSize
+	}
+	return h.MinSize
+}
+
+func (h *CompressionHandler) getCache() *compressionCache {
+	h.cacheOnce.Do(func() {
+		switch {
+		case h.MaxCacheSize == 0:
+			h.cache = newCompressionCache(defaultCompressionCacheSize)
+		case h.MaxCacheSize > 0:
+			h.cache = newCompressionCache(h.MaxCacheSize)
+		}
+	})
+	return h.cache
+}
+
+// compressionWriter 缓存 handler 写入的响应, 在 handler 返回或者调用 Flush 时决定是否压缩
+type compressionWriter struct {
+	http.ResponseWriter
+	handler *CompressionHandler
+	req     *http.Request
+
+	status    int
+	buf       bytes.Buffer
+	streaming bool           // 响应已经开始发送, 之后写入的数据直接 (经过 encoder) 写入 ResponseWriter
+	encoder   io.WriteCloser // 以流的方式压缩时使用, identity 编码时为 nil
+}
+
+var _ http.Flusher = &compressionWriter{}
+
+func (w *compressionWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	if status >= 100 && status < 200 {
+		// 1xx 响应直接发送
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+}
+
+func (w *compressionWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.streaming {
+		if w.encoder != nil {
+			return w.encoder.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+	if w.buf.Len()+len(p) > maxCompressionBufferSize {
+		if err := w.startStreaming(); err != nil {
+			return 0, err
+		}
+		return w.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+// Flush sends the data written so far.
+// The response is compressed while being sent, and no Content-Length header is sent.
+func (w *compressionWriter) Flush() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.streaming {
+		if err := w.startStreaming(); err != nil {
+			return
+		}
+	}
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Push pushes target if the underlying ResponseWriter supports server push.
+// The pushed response is compressed if the push handler is a CompressionHandler.
+func (w *compressionWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+var _ http.Pusher = &compressionWriter{}
+
+// Unwrap returns the underlying ResponseWriter.
+func (w *compressionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// negotiate 设置 Vary 头部, 并返回响应应当使用的编码. 返回 nil 表示使用 identity 编码.
+func (w *compressionWriter) negotiate() *ContentEncoder {
+	hdr := w.Header()
+	if w.status != http.StatusOK || hdr.Get("Content-Encoding") != "" || hdr.Get("Content-Range") != "" ||
+		!compressibleContentType(hdr.Get("Content-Type")) {
+		return nil
+	}
+	hdr.Add("Vary", "Accept-Encoding")
+	if w.req.Method == http.MethodHead || w.req.Header.Get("Range") != "" {
+		return nil
+	}
+	return negotiateEncoding(w.req.Header.Get("Accept-Encoding"), w.handler.encoders())
+}
+
+// setEncodingHeaders 为使用 enc 编码的响应设置头部. 不同编码的响应不能使用同一个强 ETag.
+func (w *compressionWriter) setEncodingHeaders(enc *ContentEncoder) {
+	hdr := w.Header()
+	hdr.Set("Content-Encoding", enc.Name)
+	if etag := hdr.Get("Etag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		hdr.Set("Etag", "W/"+etag)
+	}
+}
+
+// startStreaming 发送响应头部和已经缓存的数据, 之后写入的数据会被直接发送
+func (w *compressionWriter) startStreaming() error {
+	w.streaming = true
+	enc := w.negotiate()
+	if enc != nil {
+		encoder, err := enc.NewWriter(w.ResponseWriter)
+		if err != nil {
+			enc = nil
+		} else {
+			w.encoder = encoder
+			w.setEncodingHeaders(enc)
+			w.Header().Del("Content-Length")
+		}
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	data := w.buf.Bytes()
+	w.buf = bytes.Buffer{}
+	if len(data) == 0 {
+		return nil
+	}
+	if w.encoder != nil {
+		_, err := w.encoder.Write(data)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(data)
+	return err
+}
+
+// finish 在 handler 返回之后发送响应
+func (w *compressionWriter) finish() {
+	if w.streaming {
+		if w.encoder != nil {
+			w.encoder.Close()
+		}
+		return
+	}
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	body := w.buf.Bytes()
+	if enc := w.negotiate(); enc != nil && len(body) >= w.handler.minSize() {
+		if compressed, ok := w.compress(enc, body); ok {
+			w.setEncodingHeaders(enc)
+			body = compressed
+		}
+	}
+	// 响应体为空时保留 handler 设置的 Content-Length, 例如 HEAD 请求的响应
+	if len(body) > 0 {
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(body) > 0 {
+		w.ResponseWriter.Write(body)
+	}
+}
+
+// compress 使用 enc 压缩 body. 对于静态文件, 压缩的结果会被缓存.
+func (w *compressionWriter) compress(enc *ContentEncoder, body []byte) ([]byte, bool) {
+	hdr := w.Header()
+	cache := w.handler.getCache()
+	key := compressionCacheKey{
+		path:         w.req.URL.Path,
+		encoding:     enc.Name,
+		lastModified: hdr.Get("Last-Modified"),
+		etag:         hdr.Get("Etag"),
+		size:         len(body),
+	}
+	static := cache != nil && (key.lastModified != "" || key.etag != "")
+	if static {
+		if data, ok := cache.get(key); ok {
+			return data, true
+		}
+	}
+	buf := &bytes.Buffer{}
+	encoder, err := enc.NewWriter(buf)
+	if err != nil {
+		return nil, false
+	}
+	if _, err := encoder.Write(body); err != nil {
+		return nil, false
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, false
+	}
+	if buf.Len() >= len(body) {
+		// 压缩之后没有变小, 使用 identity 编码
+		return nil, false
+	}
+	if static {
+		cache.add(key, buf.Bytes())
+	}
+	return buf.Bytes(), true
+}
+
+// compressibleContentType 判断该类型的内容是否值得压缩. 图片, 视频以及已经压缩过的格式不会被压缩.
+func compressibleContentType(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	if strings.HasPrefix(contentType, "text/") ||
+		strings.HasSuffix(contentType, "+json") || strings.HasSuffix(contentType, "+xml") {
+		return true
+	}
+	switch contentType {
+	case "application/javascript", "application/x-javascript", "application/json", "application/xml",
+		"application/wasm", "application/manifest+json", "image/svg+xml", "image/x-icon",
+		"font/ttf", "font/otf", "application/vnd.ms-fontobject":
+		return true
+	}
+	return false
+}
+
+// negotiateEncoding 根据 Accept-Encoding 头部从 encoders 中选择客户端最偏好的编码.
+// 质量值相同时按照 encoders 中的顺序选择. 返回 nil 表示使用 identity 编码.
+func negotiateEncoding(acceptEncoding string, encoders []ContentEncoder) *ContentEncoder {
+	var best *ContentEncoder
+	var bestQ float64
+	for i := range encoders {
+		if q := acceptQuality(acceptEncoding, encoders[i].Name); q > bestQ {
+			best = &encoders[i]
+			bestQ = q
+		}
+	}
+	return best
+}
+
+// acceptQuality 返回 Accept-Encoding 头部中 coding 的质量值. 没有出现的编码的质量值为 0, 除非使用了 *.
+func acceptQuality(acceptEncoding, coding string) float64 {
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		name := strings.TrimSpace(params[0])
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") && !strings.HasPrefix(param, "Q=") {
+				continue
+			}
+			var err error
+			if q, err = strconv.ParseFloat(param[2:], 64); err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+		}
+		if strings.EqualFold(name, coding) {
+			return q
+		}
+		if name == "*" {
+			wildcard = q
+		}
+	}
+	if wildcard >= 0 {
+		return wildcard
+	}
+	return 0
+}
+
+type compressionCacheKey struct {
+	path         string
+	encoding     string
+	lastModified string
+	etag         string
+	size         int // 未压缩的大小
+}
+
+type compressionCacheEntry struct {
+	key  compressionCacheKey
+	data []byte
+}
+
+// compressionCache 是压缩过的静态文件的 LRU 缓存, 以压缩之后的大小计算容量
+type compressionCache struct {
+	maxSize int64
+
+	mutex   sync.Mutex
+	size    int64
+	lru     *list.List // 最近使用的在最前面
+	entries map[compressionCacheKey]*list.Element
+}
+
+func newCompressionCache(maxSize int64) *compressionCache {
+	return &compressionCache{
+		maxSize: maxSize,
+		lru:     list.New(),
+		entries: make(map[compressionCacheKey]*list.Element),
+	}
+}
+
+func (c *compressionCache) get(key compressionCacheKey) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*compressionCacheEntry).data, true
+}
+
+func (c *compressionCache) add(key compressionCacheKey, data []byte) {
+	if int64(len(data)) > c.maxSize {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&compressionCacheEntry{key: key, data: data})
+	c.size += int64(len(data))
+	for c.size > c.maxSize {
+		e := c.lru.Back()
+		entry := e.Value.(*compressionCacheEntry)
+		c.lru.Remove(e)
+		delete(c.entries, entry.key)
+		c.size -= int64(len(entry.data))
+	}
+}
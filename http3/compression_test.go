@@ -0,0 +1,270 @@
+package http3
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Compression", func() {
+	var (
+		h       *CompressionHandler
+		content []byte
+	)
+
+	deflateEncoder := ContentEncoder{
+		Name: "deflate",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(w, flate.DefaultCompression)
+		},
+	}
+
+	gunzip := func(data []byte) []byte {
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		Expect(err).ToNot(HaveOccurred())
+		decompressed, err := ioutil.ReadAll(r)
+		Expect(err).ToNot(HaveOccurred())
+		return decompressed
+	}
+
+	serve := func(method, acceptEncoding string, hdr http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "https://quic.clemente.io/index.html", nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		for k, v := range hdr {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	BeforeEach(func() {
+		content = []byte(strings.Repeat("foobar", 1000))
+		h = &CompressionHandler{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.Write(content)
+			}),
+		}
+	})
+
+	It("compresses responses", func() {
+		rec := serve("GET", "gzip, deflate, br", nil)
+		Expect(rec.Code).To(Equal(200))
+		Expect(rec.Header().Get("Content-Encoding")).To(Equal("gzip"))
+		Expect(rec.Header().Get("Vary")).To(Equal("Accept-Encoding"))
+		Expect(rec.Header().Get("Content-Length")).To(Equal(strconv.Itoa(rec.Body.Len())))
+		Expect(rec.Body.Len()).To(BeNumerically("<", len(content)))
+		Expect(gunzip(rec.Body.Bytes())).To(Equal(content))
+	})
+
+	It("uses additional content codings", func() {
+		// brotli isn't implemented in the standard library, users provide their own ContentEncoder for it.
+		// deflate is used as a stand-in here.
+		h.Encoders = []ContentEncoder{{Name: "br", NewWriter: deflateEncoder.NewWriter}, GzipEncoder}
+		rec := serve("GET", "gzip, deflate, br", nil)
+		Expect(rec.Code).To(Equal(200))
+		Expect(rec.Header().Get("Content-Encoding")).To(Equal("br"))
+		Expect(rec.Header().Get("Content-Length")).To(Equal(strconv.Itoa(rec.Body.Len())))
+		decompressed, err := ioutil.ReadAll(flate.NewReader(rec.Body))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decompressed).To(Equal(content))
+	})
+
+	It("passes CONNECT requests to the handler unmodified", func() {
+		var rw http.ResponseWriter
+		h.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw = w
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write(content)
+		})
+		req := httptest.NewRequest(http.MethodConnect, "https://quic.clemente.io/index.html", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		Expect(rw).To(Equal(rec))
+		Expect(rec.Header().Get("Content-Encoding")).To(BeEmpty())
+		Expect(rec.Body.Bytes()).To(Equal(content))
+	})
+
+	It("doesn't compress if the client doesn't accept a supported encoding", func() {
+		rec := serve("GET", "br", nil)
+		Expect(rec.Header().Get("Content-Encoding")).To(BeEmpty())
+		Expect(rec.Header().Get("Vary")).To(Equal("Accept-Encoding"))
+		Expect(rec.Header().Get("Content-Length")).To(Equal(strconv.Itoa(len(content))))
+		Expect(rec.Body.Bytes()).To(Equal(content))
+	})
+
+	It("uses the encoding preferred by the client", func() {
+		h.Encoders = []ContentEncoder{GzipEncoder, deflateEncoder}
+		rec := serve("GET", "gzip;q=0.5, deflate", nil)
+		Expect(rec.Header().Get("Content-Encoding")).To(Equal("deflate"))
+		decompressed, err := ioutil.ReadAll(flate.NewReader(rec.Body))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decompressed).To(Equal(content))
+		// the order of the encoders is used if the client doesn't have a preference
+		rec = serve("GET", "deflate, gzip", nil)
+		Expect(rec.Header().Get("Content-Encoding")).To(Equal("gzip"))
+	})
+
+	It("doesn't compress small responses", func() {
+		content = bytes.Repeat([]byte{'a'}, 100)
+		rec := serve("GET", "gzip", nil)
+		Expect(rec.Header().Get("Content-Encoding")).To(BeEmpty())
+		Expect(rec.Body.Bytes()).To(Equal(content))
+		h.MinSize = -1
+		rec = serve("GET", "gzip", nil)
+		Expect(rec.Header().Get("Content-Encoding")).To(Equal("gzip"))
+		Expect(gunzip(rec.Body.Bytes())).To(Equal(content))
+	})
+
+	It("doesn't compress incompressible content types", func() {
+		h.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(content)
+		})
+		rec := serve("GET", "gzip", nil)
+		Expect(rec.Header().Get("Content-Encoding")).To(BeEmpty())
+		Expect(rec.Header().Get("Vary")).To(BeEmpty())
+		Expect(rec.Body.Bytes()).To(Equal(content))
+	})
+
+	It("doesn't compress error responses", func() {
+		h.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write(content)
+		})
+		rec := serve("GET", "gzip", nil)
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(rec.Header().Get("Content-Encoding")).To(BeEmpty())
+		Expect(rec.Body.Bytes()).To(Equal(content))
+	})
+
+	Context("static files", func() {
+		var modTime time.Time
+
+		BeforeEach(func() {
+			modTime = time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC)
+			h.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.ServeContent(w, r, "index.html", modTime, bytes.NewReader(content))
+			})
+		})
+
+		It("serves range requests with the identity encoding", func() {
+			rec := serve("GET", "gzip", http.Header{"Range": {"bytes=10-19"}})
+			Expect(rec.Code).To(Equal(http.StatusPartialContent))
+			Expect(rec.Header().Get("Content-Encoding")).To(BeEmpty())
+			Expect(rec.Header().Get("Content-Length")).To(Equal("10"))
+			Expect(rec.Body.Bytes()).To(Equal(content[10:20]))
+		})
+
+		It("keeps the Content-Length of HEAD responses", func() {
+			rec := serve("HEAD", "gzip", nil)
+			Expect(rec.Header().Get("Content-Encoding")).To(BeEmpty())
+			Expect(rec.Header().Get("Content-Length")).To(Equal(strconv.Itoa(len(content))))
+			Expect(rec.Body.Len()).To(BeZero())
+		})
+
+		It("caches compressed files", func() {
+			var compressed int
+			h.Encoders = []ContentEncoder{{
+				Name: "gzip",
+				NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+					compressed++
+					return gzip.NewWriter(w), nil
+				},
+			}}
+			rec := serve("GET", "gzip", nil)
+			Expect(gunzip(rec.Body.Bytes())).To(Equal(content))
+			Expect(compressed).To(Equal(1))
+			rec = serve("GET", "gzip", nil)
+			Expect(gunzip(rec.Body.Bytes())).To(Equal(content))
+			Expect(compressed).To(Equal(1))
+			// the file was modified
+			modTime = modTime.Add(time.Second)
+			content = []byte(strings.Repeat("raboof", 1000))
+			rec = serve("GET", "gzip", nil)
+			Expect(gunzip(rec.Body.Bytes())).To(Equal(content))
+			Expect(compressed).To(Equal(2))
+		})
+
+		It("doesn't cache if the cache is disabled", func() {
+			h.MaxCacheSize = -1
+			serve("GET", "gzip", nil)
+			Expect(h.getCache()).To(BeNil())
+		})
+	})
+
+	It("makes strong ETags weak", func() {
+		h.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Etag", `"foobar"`)
+			w.Write(content)
+		})
+		rec := serve("GET", "gzip", nil)
+		Expect(rec.Header().Get("Content-Encoding")).To(Equal("gzip"))
+		Expect(rec.Header().Get("Etag")).To(Equal(`W/"foobar"`))
+	})
+
+	It("compresses flushed responses while sending them", func() {
+		flushed := make(chan struct{})
+		h.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Content-Length", "1337")
+			w.Write([]byte("data: foo\n\n"))
+			w.(http.Flusher).Flush()
+			close(flushed)
+			w.Write([]byte("data: bar\n\n"))
+		})
+		rec := serve("GET", "gzip", nil)
+		Expect(flushed).To(BeClosed())
+		Expect(rec.Flushed).To(BeTrue())
+		Expect(rec.Header().Get("Content-Encoding")).To(Equal("gzip"))
+		Expect(rec.Header()).ToNot(HaveKey("Content-Length"))
+		Expect(gunzip(rec.Body.Bytes())).To(Equal([]byte("data: foo\n\ndata: bar\n\n")))
+	})
+
+	It("parses the Accept-Encoding header", func() {
+		Expect(acceptQuality("gzip, br", "gzip")).To(Equal(1.0))
+		Expect(acceptQuality("gzip;q=0.8, br", "gzip")).To(Equal(0.8))
+		Expect(acceptQuality("GZIP ; q=0.5", "gzip")).To(Equal(0.5))
+		Expect(acceptQuality("br", "gzip")).To(BeZero())
+		Expect(acceptQuality("*;q=0.3, br", "gzip")).To(Equal(0.3))
+		Expect(acceptQuality("*, gzip;q=0", "gzip")).To(BeZero())
+		Expect(acceptQuality("gzip;q=foo", "gzip")).To(BeZero())
+		Expect(acceptQuality("", "gzip")).To(BeZero())
+	})
+
+	It("evicts the least recently used entries from the cache", func() {
+		c := newCompressionCache(10)
+		key := func(path string) compressionCacheKey { return compressionCacheKey{path: path} }
+		c.add(key("/foo"), []byte("foo"))
+		c.add(key("/bar"), []byte("bar"))
+		c.add(key("/baz"), []byte("baz"))
+		_, ok := c.get(key("/foo"))
+		Expect(ok).To(BeTrue())
+		c.add(key("/qux"), []byte("qux"))
+		_, ok = c.get(key("/bar"))
+		Expect(ok).To(BeFalse())
+		data, ok := c.get(key("/foo"))
+		Expect(ok).To(BeTrue())
+		Expect(data).To(Equal([]byte("foo")))
+		// entries larger than the cache are not cached
+		c.add(key("/large"), make([]byte, 11))
+		_, ok = c.get(key("/large"))
+		Expect(ok).To(BeFalse())
+	})
+})
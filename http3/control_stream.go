@@ -0,0 +1,80 @@
+package http3
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// validateSettings 检查对端发送的 SETTINGS 帧.
+// 在 HTTP/2 中定义, 但在 HTTP/3 中被保留的设置项会导致连接错误.
+func validateSettings(f *settingsFrame) error {
+	for id, val := range f.settings {
+		switch id {
+		case 0x2, 0x3, 0x4, 0x5:
+			return fmt.Errorf("received reserved setting: %#x", id)
+		case settingExtendedConnect:
+			if val > 1 {
+				return fmt.Errorf("invalid value for SETTINGS_ENABLE_CONNECT_PROTOCOL: %d", val)
+			}
+		}
+	}
+	return nil
+}
+
+// readControlStream 读取对端的控制 stream. 第一帧必须是 SETTINGS 帧, 交给 handleSettings 处理 (可以为 nil),
+// 之后的帧交给 handleFrame 处理, 直到发生错误. 返回值是关闭连接时应当使用的错误.
+func readControlStream(str io.Reader, handleSettings func(*settingsFrame) requestError, handleFrame func(frame) requestError) requestError {
+	f, err := parseNextFrame(str)
+	if err != nil {
+		return controlStreamReadError(err)
+	}
+	sf, ok := f.(*settingsFrame)
+	if !ok {
+		return newConnError(errorMissingSettings, errors.New("expected first frame on the control stream to be a SETTINGS frame"))
+	}
+	if err := validateSettings(sf); err != nil {
+		return newConnError(errorSettingsError, err)
+	}
+	if handleSettings != nil {
+		if rerr := handleSettings(sf); rerr.err != nil {
+			return rerr
+		}
+	}
+	for {
+		f, err := parseNextFrame(str)
+		if err != nil {
+			return controlStreamReadError(err)
+		}
+		switch f.(type) {
+		case *settingsFrame, *dataFrame, *headersFrame, *pushPromiseFrame:
+			return newConnError(errorFrameUnexpected, fmt.Errorf("unexpected frame on the control stream: %T", f))
+		}
+		if rerr := handleFrame(f); rerr.err != nil {
+			return rerr
+		}
+	}
+}
+
+// controlStreamReadError 把读取控制 stream 时发生的错误转换为连接错误.
+// 控制 stream 在连接的整个生命周期内都不能被关闭.
+func controlStreamReadError(err error) requestError {
+	if err == io.EOF {
+		return newConnError(errorClosedCriticalStream, errors.New("control stream closed"))
+	}
+	if _, ok := err.(quic.StreamError); ok {
+		return newConnError(errorClosedCriticalStream, err)
+	}
+	return newConnError(errorFrameError, err)
+}
+
+// closeWithRequestError 按照 rerr 中的连接错误关闭 sess
+func closeWithRequestError(sess quic.Session, rerr requestError) {
+	var reason string
+	if rerr.err != nil {
+		reason = rerr.err.Error()
+	}
+	sess.CloseWithError(quic.ErrorCode(rerr.connErr), reason)
+}
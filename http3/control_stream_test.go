@@ -0,0 +1,157 @@
+package http3
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Control stream", func() {
+	var (
+		buf    *bytes.Buffer
+		frames []frame
+	)
+
+	handleFrame := func(f frame) requestError {
+		frames = append(frames, f)
+		return requestError{}
+	}
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+		frames = nil
+	})
+
+	It("passes frames following the SETTINGS frame to the handler", func() {
+		(&settingsFrame{settings: map[uint64]uint64{0x6: 1000}}).Write(buf)
+		(&maxPushIDFrame{PushID: 10}).Write(buf)
+		(&goAwayFrame{StreamID: 8}).Write(buf)
+		rerr := readControlStream(buf, nil, handleFrame)
+		Expect(frames).To(Equal([]frame{&maxPushIDFrame{PushID: 10}, &goAwayFrame{StreamID: 8}}))
+		Expect(rerr.connErr).To(Equal(errorClosedCriticalStream))
+	})
+
+	It("passes the SETTINGS frame to the settings handler", func() {
+		(&settingsFrame{settings: map[uint64]uint64{settingQPACKMaxTableCapacity: 100}}).Write(buf)
+		(&goAwayFrame{StreamID: 8}).Write(buf)
+		var settings *settingsFrame
+		readControlStream(buf, func(f *settingsFrame) requestError {
+			settings = f
+			return requestError{}
+		}, handleFrame)
+		Expect(settings).To(Equal(&settingsFrame{settings: map[uint64]uint64{settingQPACKMaxTableCapacity: 100}}))
+		Expect(frames).To(Equal([]frame{&goAwayFrame{StreamID: 8}}))
+	})
+
+	It("returns errors from the settings handler", func() {
+		(&settingsFrame{}).Write(buf)
+		(&goAwayFrame{StreamID: 8}).Write(buf)
+		testErr := errors.New("test error")
+		rerr := readControlStream(buf, func(*settingsFrame) requestError {
+			return newConnError(errorInternalError, testErr)
+		}, handleFrame)
+		Expect(rerr.connErr).To(Equal(errorInternalError))
+		Expect(rerr.err).To(MatchError(testErr))
+		Expect(frames).To(BeEmpty())
+	})
+
+	It("errors when the first frame is not a SETTINGS frame", func() {
+		(&maxPushIDFrame{PushID: 10}).Write(buf)
+		rerr := readControlStream(buf, nil, handleFrame)
+		Expect(rerr.connErr).To(Equal(errorMissingSettings))
+		Expect(frames).To(BeEmpty())
+	})
+
+	It("errors on reserved HTTP/2 settings", func() {
+		(&settingsFrame{settings: map[uint64]uint64{0x2: 1}}).Write(buf)
+		rerr := readControlStream(buf, nil, handleFrame)
+		Expect(rerr.connErr).To(Equal(errorSettingsError))
+		Expect(rerr.err).To(MatchError("received reserved setting: 0x2"))
+	})
+
+	It("errors on invalid values for SETTINGS_ENABLE_CONNECT_PROTOCOL", func() {
+		(&settingsFrame{settings: map[uint64]uint64{settingExtendedConnect: 2}}).Write(buf)
+		rerr := readControlStream(buf, nil, handleFrame)
+		Expect(rerr.connErr).To(Equal(errorSettingsError))
+		Expect(rerr.err).To(MatchError("invalid value for SETTINGS_ENABLE_CONNECT_PROTOCOL: 2"))
+	})
+
+	It("ignores unknown settings", func() {
+		(&settingsFrame{settings: map[uint64]uint64{0x1f*3 + 0x21: 42}}).Write(buf)
+		(&cancelPushFrame{PushID: 1}).Write(buf)
+		readControlStream(buf, nil, handleFrame)
+		Expect(frames).To(Equal([]frame{&cancelPushFrame{PushID: 1}}))
+	})
+
+	It("errors on a second SETTINGS frame", func() {
+		(&settingsFrame{}).Write(buf)
+		(&settingsFrame{}).Write(buf)
+		rerr := readControlStream(buf, nil, handleFrame)
+		Expect(rerr.connErr).To(Equal(errorFrameUnexpected))
+	})
+
+	It("errors on frames that are not allowed on the control stream", func() {
+		for _, f := range []interface{ Write(*bytes.Buffer) }{
+			&dataFrame{},
+			&headersFrame{},
+			&pushPromiseFrame{PushID: 1, HeaderBlock: []byte("foo")},
+		} {
+			buf := &bytes.Buffer{}
+			(&settingsFrame{}).Write(buf)
+			f.Write(buf)
+			rerr := readControlStream(buf, nil, handleFrame)
+			Expect(rerr.connErr).To(Equal(errorFrameUnexpected))
+		}
+		Expect(frames).To(BeEmpty())
+	})
+
+	It("returns errors from the handler", func() {
+		(&settingsFrame{}).Write(buf)
+		(&goAwayFrame{StreamID: 4}).Write(buf)
+		(&goAwayFrame{StreamID: 0}).Write(buf)
+		testErr := errors.New("test error")
+		rerr := readControlStream(buf, nil, func(f frame) requestError {
+			return newConnError(errorIDError, testErr)
+		})
+		Expect(rerr.connErr).To(Equal(errorIDError))
+		Expect(rerr.err).To(MatchError(testErr))
+	})
+
+	It("errors when the control stream is reset", func() {
+		str := mockquic.NewMockStream(mockCtrl)
+		(&settingsFrame{}).Write(buf)
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+			if buf.Len() == 0 {
+				return 0, &streamResetError{code: errorRequestCanceled}
+			}
+			return buf.Read(p)
+		}).AnyTimes()
+		rerr := readControlStream(str, nil, handleFrame)
+		Expect(rerr.connErr).To(Equal(errorClosedCriticalStream))
+	})
+
+	It("errors on malformed frames", func() {
+		(&settingsFrame{}).Write(buf)
+		buf.Write([]byte{0x7, 0x3, 0x0, 0x0, 0x0}) // a GOAWAY frame with an invalid length
+		rerr := readControlStream(buf, nil, handleFrame)
+		Expect(rerr.connErr).To(Equal(errorFrameError))
+	})
+})
+
+// streamResetError is the error returned when the peer resets a stream.
+type streamResetError struct {
+	code errorCode
+}
+
+var _ quic.StreamError = &streamResetError{}
+
+func (e *streamResetError) Error() string             { return fmt.Sprintf("stream reset with %s", e.code) }
+func (e *streamResetError) Canceled() bool            { return true }
+func (e *streamResetError) ErrorCode() quic.ErrorCode { return quic.ErrorCode(e.code) }
@@ -26,6 +26,13 @@ const (
 	errorEarlyResponse        errorCode = 0x10e
 	errorConnectError         errorCode = 0x10f
 	errorVersionFallback      errorCode = 0x110
+
+	errorQPACKDecompressionFailed errorCode = 0x200
+	errorQPACKEncoderStreamError  errorCode = 0x201
+	errorQPACKDecoderStreamError  errorCode = 0x202
+
+	errorWebTransportBufferedStreamRejected errorCode = 0x3994bd84
+	errorWebTransportSessionGone            errorCode = 0x170d7b68
 )
 
 func (e errorCode) String() string {
@@ -64,6 +71,16 @@ func (e errorCode) String() string {
 		return "H3_CONNECT_ERROR"
 	case errorVersionFallback:
 		return "H3_VERSION_FALLBACK"
+	case errorQPACKDecompressionFailed:
+		return "QPACK_DECOMPRESSION_FAILED"
+	case errorQPACKEncoderStreamError:
+		return "QPACK_ENCODER_STREAM_ERROR"
+	case errorQPACKDecoderStreamError:
+		return "QPACK_DECODER_STREAM_ERROR"
+	case errorWebTransportBufferedStreamRejected:
+		return "WEBTRANSPORT_BUFFERED_STREAM_REJECTED"
+	case errorWebTransportSessionGone:
+		return "WEBTRANSPORT_SESSION_GONE"
 	default:
 		return fmt.Sprintf("unknown error code: %#x", uint16(e))
 	}
@@ -0,0 +1,356 @@
+package http3
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+)
+
+const (
+	defaultRaceDelay      = 300 * time.Millisecond
+	defaultBrokenDuration = 5 * time.Minute
+)
+
+// TransportMode selects the protocols used by a FallbackRoundTripper.
+type TransportMode int
+
+const (
+	// TransportAuto uses HTTP/3 for servers that advertise it using Alt-Svc, and HTTP/2 over TCP otherwise.
+	TransportAuto TransportMode = iota
+	// TransportHTTP2 only uses TCP, i.e. HTTP/2 or HTTP/1.1.
+	TransportHTTP2
+	// TransportHTTP3 only uses HTTP/3, without Alt-Svc discovery.
+	TransportHTTP3
+)
+
+// A FallbackRoundTripper sends requests over TCP (using HTTP/2 if possible) and learns from the Alt-Svc
+// response header which servers support HTTP/3. Requests to those servers are sent over HTTP/3.
+//
+// As long as no HTTP/3 response has been received from a server, requests that can be retried
+// (i.e. requests without a body, or with GetBody set) are raced: the request is sent over HTTP/3,
+// and if no response has arrived after RaceDelay, it is also sent over TCP. The first response is used.
+// If HTTP/3 fails, e.g. because UDP is blocked, it is not used for that server for BrokenDuration.
+type FallbackRoundTripper struct {
+	// TLSClientConfig is the TLS configuration used for both TCP and QUIC connections.
+	TLSClientConfig *tls.Config
+
+	// QuicConfig is the quic.Config used for dialing QUIC connections.
+	QuicConfig *quic.Config
+
+	// DisableCompression disables transparent gzip compression, see RoundTripper.DisableCompression.
+	DisableCompression bool
+
+	// TCP is the RoundTripper used for requests over TCP.
+	// If nil, an http.Transport that attempts HTTP/2 is used.
+	TCP http.RoundTripper
+
+	// Mode selects the protocols. The default is TransportAuto.
+	Mode TransportMode
+
+	// RaceDelay is the time to wait for an HTTP/3 response before sending a request over TCP as well.
+	// Zero means to use a default delay of 300ms.
+	RaceDelay time.Duration
+
+	// BrokenDuration is the time for which HTTP/3 is not used for a server after it failed.
+	// Zero means to use a default duration of 5 minutes.
+	BrokenDuration time.Duration
+
+	initOnce sync.Once
+	h3       http.RoundTripper
+	tcp      http.RoundTripper
+	altSvc   *altSvcCache
+
+	mutex  sync.Mutex
+	states map[string]*h3OriginState
+}
+
+var _ http.RoundTripper = &FallbackRoundTripper{}
+
+// h3OriginState 记录 HTTP/3 对某个 origin 是否可用
+type h3OriginState struct {
+	confirmed   bool      // 已经收到过该 origin 的 HTTP/3 响应
+	racing      bool      // 正在与 TCP 竞争的 HTTP/3 请求还没有结束
+	brokenUntil time.Time // HTTP/3 失败之后, 在此之前不再使用 HTTP/3
+}
+
+type roundTripResult struct {
+	res *http.Response
+	err error
+}
+
+func (f *FallbackRoundTripper) init() {
+	f.initOnce.Do(func() {
+		f.altSvc = newAltSvcCache(nextProtos(f.QuicConfig))
+		f.states = make(map[string]*h3OriginState)
+		if f.h3 == nil {
+			f.h3 = &RoundTripper{
+				TLSClientConfig:    f.TLSClientConfig,
+				QuicConfig:         f.QuicConfig,
+				DisableCompression: f.DisableCompression,
+				Dial:               f.dialAlternative,
+			}
+		}
+		f.tcp = f.TCP
+		if f.tcp == nil {
+			f.tcp = &http.Transport{
+				Proxy:              http.ProxyFromEnvironment,
+				TLSClientConfig:    f.TLSClientConfig,
+				DisableCompression: f.DisableCompression,
+				ForceAttemptHTTP2:  true,
+			}
+		}
+	})
+}
+
+// dialAlternative 建立到 addr 的替代服务的 quic 连接. 证书仍然按照原服务的 host 验证.
+func (f *FallbackRoundTripper) dialAlternative(network, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.Session, error) {
+	if alt, proto, ok := f.altSvc.get(addr, time.Now()); ok {
+		if tlsCfg.ServerName == "" {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			tlsCfg = tlsCfg.Clone()
+			tlsCfg.ServerName = host
+		}
+		// 直接使用替代服务宣告的版本, 避免版本协商
+		if v, ok := alpnToVersion(proto); ok {
+			conf := &quic.Config{}
+			if cfg != nil {
+				*conf = *cfg
+			}
+			conf.Versions = []quic.VersionNumber{v}
+			cfg = conf
+		}
+		addr = alt
+	}
+	return dialAddr(addr, tlsCfg, cfg)
+}
+
+func (f *FallbackRoundTripper) raceDelay() time.Duration {
+	if f.RaceDelay == 0 {
+		return defaultRaceDelay
+	}
+	return f.RaceDelay
+}
+
+func (f *FallbackRoundTripper) brokenDuration() time.Duration {
+	if f.BrokenDuration == 0 {
+		return defaultBrokenDuration
+	}
+	return f.BrokenDuration
+}
+
+// RoundTrip sends the request over HTTP/3 or TCP.
+func (f *FallbackRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.init()
+	if req.URL == nil {
+		closeRequestBody(req)
+		return nil, errors.New("http3: nil Request.URL")
+	}
+	switch {
+	case f.Mode == TransportHTTP3:
+		return f.h3.RoundTrip(req)
+	case f.Mode == TransportHTTP2 || req.URL.Scheme != "https":
+		return f.tcp.RoundTrip(req)
+	}
+
+	origin := authorityAddr("https", hostnameFromRequest(req))
+	if _, _, ok := f.altSvc.get(origin, time.Now()); !ok {
+		return f.roundTripTCP(origin, req)
+	}
+	f.mutex.Lock()
+	state := f.getState(origin)
+	confirmed := state.confirmed
+	useH3 := time.Now().After(state.brokenUntil) && !state.racing
+	startRace := useH3 && !confirmed && canRetryRequest(req)
+	if startRace {
+		state.racing = true
+	}
+	f.mutex.Unlock()
+
+	switch {
+	case startRace:
+		return f.race(origin, req)
+	case useH3 && confirmed:
+		res, err := f.roundTripH3(origin, req)
+		if err != nil && canRetryRequest(req) {
+			f.markBroken(origin)
+			return f.retryTCP(origin, req)
+		}
+		return res, err
+	default:
+		// HTTP/3 还没有被确认可用, 或者暂时不可用
+		return f.roundTripTCP(origin, req)
+	}
+}
+
+func (f *FallbackRoundTripper) getState(origin string) *h3OriginState {
+	state, ok := f.states[origin]
+	if !ok {
+		state = &h3OriginState{}
+		f.states[origin] = state
+	}
+	return state
+}
+
+func (f *FallbackRoundTripper) markBroken(origin string) {
+	f.mutex.Lock()
+	state := f.getState(origin)
+	state.confirmed = false
+	state.brokenUntil = time.Now().Add(f.brokenDuration())
+	f.mutex.Unlock()
+}
+
+func (f *FallbackRoundTripper) roundTripTCP(origin string, req *http.Request) (*http.Response, error) {
+	res, err := f.tcp.RoundTrip(req)
+	if err == nil {
+		f.altSvc.update(origin, res.Header, time.Now())
+	}
+	return res, err
+}
+
+func (f *FallbackRoundTripper) roundTripH3(origin string, req *http.Request) (*http.Response, error) {
+	res, err := f.h3.RoundTrip(req)
+	if err == nil {
+		f.altSvc.update(origin, res.Header, time.Now())
+	}
+	return res, err
+}
+
+// retryTCP 通过 TCP 重新发送已经用于 HTTP/3 的请求
+func (f *FallbackRoundTripper) retryTCP(origin string, req *http.Request) (*http.Response, error) {
+	retry, err := rewindRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return f.roundTripTCP(origin, retry)
+}
+
+// race 通过 HTTP/3 发送请求. 如果在 RaceDelay 之内没有收到响应, 再通过 TCP 发送同一请求, 使用先到达的响应.
+// 没有被使用的响应会被关闭. 只有 HTTP/3 请求结束之后, 才会开始对该 origin 的下一次竞争.
+func (f *FallbackRoundTripper) race(origin string, req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	h3Chan := make(chan roundTripResult, 1)
+	go func() {
+		res, err := f.roundTripH3(origin, req.WithContext(ctx))
+		f.mutex.Lock()
+		state := f.getState(origin)
+		state.racing = false
+		if err == nil {
+			state.confirmed = true
+		} else {
+			state.brokenUntil = time.Now().Add(f.brokenDuration())
+		}
+		f.mutex.Unlock()
+		h3Chan <- roundTripResult{res: res, err: err}
+	}()
+
+	timer := time.NewTimer(f.raceDelay())
+	defer timer.Stop()
+	select {
+	case r := <-h3Chan:
+		if r.err == nil {
+			r.res.Body = &cancelOnCloseBody{ReadCloser: r.res.Body, cancel: cancel}
+			return r.res, nil
+		}
+		cancel()
+		return f.retryTCP(origin, req)
+	case <-timer.C:
+	case <-req.Context().Done():
+		cancel()
+		return nil, req.Context().Err()
+	}
+
+	tcpReq, err := rewindRequest(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	tcpChan := make(chan roundTripResult, 1)
+	go func() {
+		res, err := f.roundTripTCP(origin, tcpReq)
+		tcpChan <- roundTripResult{res: res, err: err}
+	}()
+	for h3Chan != nil || tcpChan != nil {
+		select {
+		case r := <-h3Chan:
+			h3Chan = nil
+			if r.err == nil {
+				go discardResult(tcpChan)
+				r.res.Body = &cancelOnCloseBody{ReadCloser: r.res.Body, cancel: cancel}
+				return r.res, nil
+			}
+		case r := <-tcpChan:
+			tcpChan = nil
+			if r.err == nil || h3Chan == nil {
+				// HTTP/3 请求在后台结束, 其结果只用于更新该 origin 的状态
+				cancel()
+				go discardResult(h3Chan)
+				return r.res, r.err
+			}
+		}
+	}
+	cancel()
+	return nil, errors.New("http3: both HTTP/3 and TCP failed")
+}
+
+// cancelOnCloseBody 在响应体被关闭时取消请求的 context
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// discardResult 关闭竞争中没有被使用的响应
+func discardResult(c <-chan roundTripResult) {
+	if c == nil {
+		return
+	}
+	if r := <-c; r.err == nil {
+		r.res.Body.Close()
+	}
+}
+
+// canRetryRequest 判断请求是否可以被发送多次
+func canRetryRequest(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewindRequest 返回一个可以被再次发送的请求副本
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	retry := *req
+	retry.Body = body
+	return &retry, nil
+}
+
+// Close closes the QUIC connections and the idle TCP connections.
+func (f *FallbackRoundTripper) Close() error {
+	f.init()
+	if c, ok := f.tcp.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+	if c, ok := f.h3.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package http3
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeRoundTripper struct {
+	mutex     sync.Mutex
+	requests  int
+	roundTrip func(*http.Request) (*http.Response, error)
+}
+
+func (t *fakeRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mutex.Lock()
+	t.requests++
+	t.mutex.Unlock()
+	return t.roundTrip(req)
+}
+
+func (t *fakeRoundTripper) numRequests() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.requests
+}
+
+var _ = Describe("Alt-Svc", func() {
+	now := time.Now()
+
+	It("parses Alt-Svc headers", func() {
+		services, clear := parseAltSvc([]string{`h3-24=":443"; ma=3600, h2="alt.example.com:8443"`, `h3=":4433"`}, now)
+		Expect(clear).To(BeFalse())
+		Expect(services).To(Equal([]altService{
+			{protocol: "h3-24", authority: ":443", expires: now.Add(time.Hour)},
+			{protocol: "h2", authority: "alt.example.com:8443", expires: now.Add(defaultAltSvcMaxAge)},
+			{protocol: "h3", authority: ":4433", expires: now.Add(defaultAltSvcMaxAge)},
+		}))
+	})
+
+	It("parses clear", func() {
+		_, clear := parseAltSvc([]string{"clear"}, now)
+		Expect(clear).To(BeTrue())
+	})
+
+	It("ignores invalid alternatives", func() {
+		services, _ := parseAltSvc([]string{`h3-24=443, h3-24, h3-24="foobar", h3-24=":443"; ma=foo`}, now)
+		Expect(services).To(Equal([]altService{{protocol: "h3-24", authority: ":443", expires: now.Add(defaultAltSvcMaxAge)}}))
+	})
+
+	Context("cache", func() {
+		var c *altSvcCache
+
+		BeforeEach(func() {
+			c = newAltSvcCache([]string{nextProtoH3, nextProtoH3Draft29, nextProtoH3Draft24})
+		})
+
+		It("uses the host of the origin if the alternative doesn't have one", func() {
+			c.update("quic.clemente.io:443", http.Header{"Alt-Svc": {`h3-24=":4433"`}}, now)
+			alt, proto, ok := c.get("quic.clemente.io:443", now)
+			Expect(ok).To(BeTrue())
+			Expect(alt).To(Equal("quic.clemente.io:4433"))
+			Expect(proto).To(Equal(nextProtoH3Draft24))
+		})
+
+		It("only saves supported HTTP/3 versions", func() {
+			c.update("quic.clemente.io:443", http.Header{"Alt-Svc": {`h3-23=":443", h3-24="alt.clemente.io:443"`}}, now)
+			alt, _, ok := c.get("quic.clemente.io:443", now)
+			Expect(ok).To(BeTrue())
+			Expect(alt).To(Equal("alt.clemente.io:443"))
+			c.update("example.com:443", http.Header{"Alt-Svc": {`h3-23=":443"`}}, now)
+			_, _, ok = c.get("example.com:443", now)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("prefers the most preferred HTTP/3 version", func() {
+			c.update("quic.clemente.io:443", http.Header{"Alt-Svc": {`h3-24=":443", h3-29=":4429", h3=":4433"`}}, now)
+			alt, proto, ok := c.get("quic.clemente.io:443", now)
+			Expect(ok).To(BeTrue())
+			Expect(alt).To(Equal("quic.clemente.io:4433"))
+			Expect(proto).To(Equal(nextProtoH3))
+			// versions that the client doesn't support are ignored
+			c = newAltSvcCache([]string{nextProtoH3Draft29})
+			c.update("quic.clemente.io:443", http.Header{"Alt-Svc": {`h3=":4433", h3-29=":4429"`}}, now)
+			alt, proto, ok = c.get("quic.clemente.io:443", now)
+			Expect(ok).To(BeTrue())
+			Expect(alt).To(Equal("quic.clemente.io:4429"))
+			Expect(proto).To(Equal(nextProtoH3Draft29))
+		})
+
+		It("expires alternatives", func() {
+			c.update("quic.clemente.io:443", http.Header{"Alt-Svc": {`h3-24=":443"; ma=60`}}, now)
+			_, _, ok := c.get("quic.clemente.io:443", now.Add(59*time.Second))
+			Expect(ok).To(BeTrue())
+			_, _, ok = c.get("quic.clemente.io:443", now.Add(61*time.Second))
+			Expect(ok).To(BeFalse())
+		})
+
+		It("clears alternatives", func() {
+			c.update("quic.clemente.io:443", http.Header{"Alt-Svc": {`h3-24=":443"`}}, now)
+			// responses without an Alt-Svc header don't change anything
+			c.update("quic.clemente.io:443", http.Header{}, now)
+			_, _, ok := c.get("quic.clemente.io:443", now)
+			Expect(ok).To(BeTrue())
+			c.update("quic.clemente.io:443", http.Header{"Alt-Svc": {"clear"}}, now)
+			_, _, ok = c.get("quic.clemente.io:443", now)
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
+
+var _ = Describe("FallbackRoundTripper", func() {
+	var (
+		rt      *FallbackRoundTripper
+		h3, tcp *fakeRoundTripper
+	)
+
+	altSvcHeader := http.Header{"Alt-Svc": {`h3-24=":443"; ma=3600`}}
+
+	response := func(proto string, hdr http.Header) *http.Response {
+		if hdr == nil {
+			hdr = http.Header{}
+		}
+		return &http.Response{
+			StatusCode: 200,
+			Proto:      proto,
+			Header:     hdr,
+			Body:       ioutil.NopCloser(&bytes.Buffer{}),
+		}
+	}
+
+	get := func() *http.Response {
+		req, err := http.NewRequest("GET", "https://quic.clemente.io/foo", nil)
+		Expect(err).ToNot(HaveOccurred())
+		res, err := rt.RoundTrip(req)
+		Expect(err).ToNot(HaveOccurred())
+		return res
+	}
+
+	BeforeEach(func() {
+		h3 = &fakeRoundTripper{roundTrip: func(*http.Request) (*http.Response, error) {
+			return response("HTTP/3", nil), nil
+		}}
+		tcp = &fakeRoundTripper{roundTrip: func(*http.Request) (*http.Response, error) {
+			return response("HTTP/2.0", altSvcHeader), nil
+		}}
+		rt = &FallbackRoundTripper{TCP: tcp, RaceDelay: 50 * time.Millisecond}
+		rt.h3 = h3
+	})
+
+	It("switches to HTTP/3 after learning Alt-Svc", func() {
+		Expect(get().Proto).To(Equal("HTTP/2.0"))
+		Expect(get().Proto).To(Equal("HTTP/3"))
+		Expect(get().Proto).To(Equal("HTTP/3"))
+		Expect(tcp.numRequests()).To(Equal(1))
+		Expect(h3.numRequests()).To(Equal(2))
+	})
+
+	It("falls back to TCP if HTTP/3 fails", func() {
+		h3.roundTrip = func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("UDP blocked")
+		}
+		Expect(get().Proto).To(Equal("HTTP/2.0"))
+		Expect(get().Proto).To(Equal("HTTP/2.0"))
+		Expect(h3.numRequests()).To(Equal(1))
+		// HTTP/3 is not tried again until BrokenDuration has passed
+		Expect(get().Proto).To(Equal("HTTP/2.0"))
+		Expect(h3.numRequests()).To(Equal(1))
+		Expect(tcp.numRequests()).To(Equal(3))
+	})
+
+	It("uses TCP if HTTP/3 is too slow", func() {
+		unblock := make(chan struct{})
+		h3.roundTrip = func(*http.Request) (*http.Response, error) {
+			<-unblock
+			return nil, errors.New("handshake timeout")
+		}
+		get()
+		start := time.Now()
+		Expect(get().Proto).To(Equal("HTTP/2.0"))
+		Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+		// while HTTP/3 is still being tried, requests are sent over TCP directly
+		Expect(get().Proto).To(Equal("HTTP/2.0"))
+		Expect(h3.numRequests()).To(Equal(1))
+		Expect(tcp.numRequests()).To(Equal(3))
+		close(unblock)
+		Eventually(func() bool {
+			rt.mutex.Lock()
+			defer rt.mutex.Unlock()
+			return rt.states["quic.clemente.io:443"].brokenUntil.After(time.Now())
+		}).Should(BeTrue())
+	})
+
+	It("uses the HTTP/3 response if it arrives after the race started", func() {
+		h3.roundTrip = func(*http.Request) (*http.Response, error) {
+			time.Sleep(75 * time.Millisecond)
+			return response("HTTP/3", nil), nil
+		}
+		tcpDone := make(chan struct{})
+		get()
+		tcp.roundTrip = func(*http.Request) (*http.Response, error) {
+			<-tcpDone
+			return response("HTTP/2.0", altSvcHeader), nil
+		}
+		Expect(get().Proto).To(Equal("HTTP/3"))
+		close(tcpDone)
+	})
+
+	It("sends requests that can't be retried over TCP until HTTP/3 works", func() {
+		get()
+		req, err := http.NewRequest("POST", "https://quic.clemente.io/upload", ioutil.NopCloser(bytes.NewReader([]byte("foobar"))))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(req.GetBody).To(BeNil())
+		res, err := rt.RoundTrip(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Proto).To(Equal("HTTP/2.0"))
+		Expect(h3.numRequests()).To(BeZero())
+	})
+
+	It("stops using HTTP/3 when the server clears Alt-Svc", func() {
+		get()
+		h3.roundTrip = func(*http.Request) (*http.Response, error) {
+			return response("HTTP/3", http.Header{"Alt-Svc": {"clear"}}), nil
+		}
+		Expect(get().Proto).To(Equal("HTTP/3"))
+		Expect(get().Proto).To(Equal("HTTP/2.0"))
+	})
+
+	It("only uses TCP in HTTP/2 mode", func() {
+		rt.Mode = TransportHTTP2
+		get()
+		Expect(get().Proto).To(Equal("HTTP/2.0"))
+		Expect(h3.numRequests()).To(BeZero())
+	})
+
+	It("only uses HTTP/3 in HTTP/3 mode", func() {
+		rt.Mode = TransportHTTP3
+		Expect(get().Proto).To(Equal("HTTP/3"))
+		Expect(tcp.numRequests()).To(BeZero())
+	})
+
+	It("dials the alternative service", func() {
+		origDialAddr := dialAddr
+		defer func() { dialAddr = origDialAddr }()
+		var dialedAddr, serverName string
+		var versions []quic.VersionNumber
+		dialAddr = func(addr string, tlsConf *tls.Config, quicConf *quic.Config) (quic.Session, error) {
+			dialedAddr = addr
+			serverName = tlsConf.ServerName
+			versions = quicConf.Versions
+			return nil, errors.New("done")
+		}
+		rt.init()
+		rt.altSvc.update("quic.clemente.io:443", http.Header{"Alt-Svc": {`h3-29="alt.clemente.io:4433"`}}, time.Now())
+		quicConf := &quic.Config{IdleTimeout: time.Minute}
+		_, err := rt.dialAlternative("udp", "quic.clemente.io:443", &tls.Config{}, quicConf)
+		Expect(err).To(MatchError("done"))
+		Expect(dialedAddr).To(Equal("alt.clemente.io:4433"))
+		Expect(serverName).To(Equal("quic.clemente.io"))
+		// the version advertised by the alternative service is used
+		Expect(versions).To(Equal([]quic.VersionNumber{protocol.VersionDraft29}))
+		Expect(quicConf.Versions).To(BeEmpty())
+	})
+})
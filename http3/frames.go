@@ -2,6 +2,7 @@ package http3
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -25,17 +26,67 @@ func (br *byteReaderImpl) ReadByte() (byte, error) {
 	return b[0], nil
 }
 
+// countingByteReader 记录已经读取的字节数
+type countingByteReader struct {
+	byteReader
+	n uint64
+}
+
+func (r *countingByteReader) ReadByte() (byte, error) {
+	b, err := r.byteReader.ReadByte()
+	if err == nil {
+		r.n++
+	}
+	return b, err
+}
+
+func (r *countingByteReader) Read(p []byte) (int, error) {
+	n, err := r.byteReader.Read(p)
+	r.n += uint64(n)
+	return n, err
+}
+
 type frame interface{}
 
-func parseNextFrame(b io.Reader) (frame, error) {
+func toByteReader(b io.Reader) byteReader {
 	br, ok := b.(byteReader)
 	if !ok {
 		br = &byteReaderImpl{b}
 	}
+	return br
+}
+
+func parseNextFrame(b io.Reader) (frame, error) {
+	br := toByteReader(b)
 	t, err := utils.ReadVarInt(br)
 	if err != nil {
 		return nil, err
 	}
+	return parseFrame(br, t)
+}
+
+// parseFirstRequestStreamFrame 解析客户端发起的双向 stream 上的第一个帧.
+// WEBTRANSPORT_STREAM 帧没有长度字段, 只有在这个位置才能和其它帧区分开,
+// 所以只有 webTransport 为 true 时, 才在这里识别它. 在其它位置, 类型 0x41 按未知帧跳过.
+func parseFirstRequestStreamFrame(b io.Reader, webTransport bool) (frame, error) {
+	br := toByteReader(b)
+	t, err := utils.ReadVarInt(br)
+	if err != nil {
+		return nil, err
+	}
+	if webTransport && t == webTransportStreamFrameType {
+		// 类型之后是 session ID, 之后的数据都属于 WebTransport
+		id, err := utils.ReadVarInt(br)
+		if err != nil {
+			return nil, err
+		}
+		return &webTransportStreamFrame{SessionID: protocol.StreamID(id)}, nil
+	}
+	return parseFrame(br, t)
+}
+
+// parseFrame 解析类型为 t 的帧, 类型已经被读取
+func parseFrame(br byteReader, t uint64) (frame, error) {
 	l, err := utils.ReadVarInt(br)
 	if err != nil {
 		return nil, err
@@ -46,16 +97,16 @@ func parseNextFrame(b io.Reader) (frame, error) {
 		return &dataFrame{Length: l}, nil
 	case 0x1:
 		return &headersFrame{Length: l}, nil
+	case 0x3:
+		return parseCancelPushFrame(br, l)
 	case 0x4:
 		return parseSettingsFrame(br, l)
-	case 0x3: // CANCEL_PUSH
-		fallthrough
-	case 0x5: // PUSH_PROMISE
-		fallthrough
-	case 0x7: // GOAWAY
-		fallthrough
-	case 0xd: // MAX_PUSH_ID
-		fallthrough
+	case 0x5:
+		return parsePushPromiseFrame(br, l)
+	case 0x7:
+		return parseGoAwayFrame(br, l)
+	case 0xd:
+		return parseMaxPushIDFrame(br, l)
 	case 0xe: // DUPLICATE_PUSH
 		fallthrough
 	default:
@@ -63,7 +114,7 @@ func parseNextFrame(b io.Reader) (frame, error) {
 		if _, err := io.CopyN(ioutil.Discard, br, int64(l)); err != nil {
 			return nil, err
 		}
-		return parseNextFrame(b)
+		return parseNextFrame(br)
 	}
 }
 
@@ -131,3 +182,145 @@ func (f *settingsFrame) Write(b *bytes.Buffer) {
 		utils.WriteVarInt(b, val)
 	}
 }
+
+// maxPushPromiseHeaderBlockSize is the maximum size of the header block of a PUSH_PROMISE frame.
+const maxPushPromiseHeaderBlockSize = 1 << 16
+
+type pushPromiseFrame struct {
+	PushID      uint64
+	HeaderBlock []byte
+}
+
+func parsePushPromiseFrame(r byteReader, l uint64) (*pushPromiseFrame, error) {
+	pushID, n, err := readPushID(r, l)
+	if err != nil {
+		return nil, err
+	}
+	l -= n
+	if l > maxPushPromiseHeaderBlockSize {
+		return nil, fmt.Errorf("PUSH_PROMISE header block too large: %d bytes", l)
+	}
+	headerBlock := make([]byte, l)
+	if _, err := io.ReadFull(r, headerBlock); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	return &pushPromiseFrame{PushID: pushID, HeaderBlock: headerBlock}, nil
+}
+
+func (f *pushPromiseFrame) Write(b *bytes.Buffer) {
+	utils.WriteVarInt(b, 0x5)
+	utils.WriteVarInt(b, uint64(utils.VarIntLen(f.PushID))+uint64(len(f.HeaderBlock)))
+	utils.WriteVarInt(b, f.PushID)
+	b.Write(f.HeaderBlock)
+}
+
+type cancelPushFrame struct {
+	PushID uint64
+}
+
+func parseCancelPushFrame(r byteReader, l uint64) (*cancelPushFrame, error) {
+	pushID, err := readPushIDFrame(r, l)
+	if err != nil {
+		return nil, err
+	}
+	return &cancelPushFrame{PushID: pushID}, nil
+}
+
+func (f *cancelPushFrame) Write(b *bytes.Buffer) {
+	utils.WriteVarInt(b, 0x3)
+	utils.WriteVarInt(b, uint64(utils.VarIntLen(f.PushID)))
+	utils.WriteVarInt(b, f.PushID)
+}
+
+type maxPushIDFrame struct {
+	PushID uint64
+}
+
+func parseMaxPushIDFrame(r byteReader, l uint64) (*maxPushIDFrame, error) {
+	pushID, err := readPushIDFrame(r, l)
+	if err != nil {
+		return nil, err
+	}
+	return &maxPushIDFrame{PushID: pushID}, nil
+}
+
+func (f *maxPushIDFrame) Write(b *bytes.Buffer) {
+	utils.WriteVarInt(b, 0xd)
+	utils.WriteVarInt(b, uint64(utils.VarIntLen(f.PushID)))
+	utils.WriteVarInt(b, f.PushID)
+}
+
+type goAwayFrame struct {
+	StreamID protocol.StreamID
+}
+
+func parseGoAwayFrame(r byteReader, l uint64) (*goAwayFrame, error) {
+	if l == 0 {
+		return nil, errors.New("GOAWAY frame too short")
+	}
+	// the peer might not use the shortest encoding for the stream ID
+	cr := &countingByteReader{byteReader: r}
+	id, err := utils.ReadVarInt(cr)
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	if cr.n != l {
+		return nil, fmt.Errorf("unexpected frame length: %d", l)
+	}
+	return &goAwayFrame{StreamID: protocol.StreamID(id)}, nil
+}
+
+func (f *goAwayFrame) Write(b *bytes.Buffer) {
+	utils.WriteVarInt(b, 0x7)
+	utils.WriteVarInt(b, uint64(utils.VarIntLen(uint64(f.StreamID))))
+	utils.WriteVarInt(b, uint64(f.StreamID))
+}
+
+// readPushID reads the push ID at the beginning of a frame of length l.
+// It also returns the number of bytes read. The peer might not use the shortest encoding for the push ID,
+// so this can be larger than utils.VarIntLen(pushID).
+func readPushID(r byteReader, l uint64) (uint64, uint64, error) {
+	if l == 0 {
+		return 0, 0, errors.New("frame too short to contain a push ID")
+	}
+	cr := &countingByteReader{byteReader: r}
+	pushID, err := utils.ReadVarInt(cr)
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, 0, io.EOF
+		}
+		return 0, 0, err
+	}
+	if cr.n > l {
+		return 0, 0, errors.New("push ID exceeds the frame length")
+	}
+	return pushID, cr.n, nil
+}
+
+// readPushIDFrame reads the payload of a frame that only consists of a push ID.
+func readPushIDFrame(r byteReader, l uint64) (uint64, error) {
+	pushID, n, err := readPushID(r, l)
+	if err != nil {
+		return 0, err
+	}
+	if n != l {
+		return 0, fmt.Errorf("unexpected frame length: %d", l)
+	}
+	return pushID, nil
+}
+
+// webTransportStreamFrame 位于客户端为 WebTransport session 打开的双向 stream 的开头
+type webTransportStreamFrame struct {
+	SessionID protocol.StreamID
+}
+
+func (f *webTransportStreamFrame) Write(b *bytes.Buffer) {
+	utils.WriteVarInt(b, webTransportStreamFrameType)
+	utils.WriteVarInt(b, uint64(f.SessionID))
+}
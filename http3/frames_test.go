@@ -133,4 +133,233 @@ var _ = Describe("Frames", func() {
 			}
 		})
 	})
+
+	Context("PUSH_PROMISE frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 5) // type byte
+			data = appendVarInt(data, 2+6)
+			data = appendVarInt(data, 0x42) // push ID
+			data = append(data, []byte("foobar")...)
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&pushPromiseFrame{PushID: 0x42, HeaderBlock: []byte("foobar")}))
+		})
+
+		It("writes", func() {
+			f := &pushPromiseFrame{PushID: 0x1337, HeaderBlock: []byte("lorem ipsum")}
+			buf := &bytes.Buffer{}
+			f.Write(buf)
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(f))
+			Expect(buf.Len()).To(BeZero())
+		})
+
+		It("parses push IDs that don't use the shortest encoding", func() {
+			data := appendVarInt(nil, 5) // type byte
+			data = appendVarInt(data, 4+6)
+			data = append(data, 0x80, 0, 0, 0x42) // push ID 0x42, encoded in 4 bytes
+			data = append(data, []byte("foobar")...)
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&pushPromiseFrame{PushID: 0x42, HeaderBlock: []byte("foobar")}))
+		})
+
+		It("errors when the push ID exceeds the frame length", func() {
+			data := appendVarInt(nil, 5) // type byte
+			data = appendVarInt(data, 1)
+			data = appendVarInt(data, 0x1337) // push ID, 2 bytes
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError("push ID exceeds the frame length"))
+		})
+
+		It("errors when the header block is too large", func() {
+			data := appendVarInt(nil, 5) // type byte
+			data = appendVarInt(data, maxPushPromiseHeaderBlockSize+2)
+			data = appendVarInt(data, 1) // push ID
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError("PUSH_PROMISE header block too large: 65537 bytes"))
+		})
+
+		It("errors on EOF", func() {
+			buf := &bytes.Buffer{}
+			(&pushPromiseFrame{PushID: 0x1337, HeaderBlock: []byte("foobar")}).Write(buf)
+			data := buf.Bytes()
+			for i := range data {
+				b := make([]byte, i)
+				copy(b, data[:i])
+				_, err := parseNextFrame(bytes.NewReader(b))
+				Expect(err).To(MatchError(io.EOF))
+			}
+		})
+	})
+
+	Context("CANCEL_PUSH frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 3) // type byte
+			data = appendVarInt(data, 2)
+			data = appendVarInt(data, 0x1337)
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&cancelPushFrame{PushID: 0x1337}))
+		})
+
+		It("writes", func() {
+			buf := &bytes.Buffer{}
+			(&cancelPushFrame{PushID: 0xdeadbeef}).Write(buf)
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&cancelPushFrame{PushID: 0xdeadbeef}))
+		})
+
+		It("parses push IDs that don't use the shortest encoding", func() {
+			data := appendVarInt(nil, 3) // type byte
+			data = appendVarInt(data, 8)
+			data = append(data, 0xc0, 0, 0, 0, 0, 0, 0x13, 0x37) // push ID 0x1337, encoded in 8 bytes
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&cancelPushFrame{PushID: 0x1337}))
+		})
+
+		It("errors if the length doesn't match the push ID", func() {
+			data := appendVarInt(nil, 3) // type byte
+			data = appendVarInt(data, 3)
+			data = appendVarInt(data, 0x1337)
+			data = append(data, 0)
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError("unexpected frame length: 3"))
+		})
+	})
+
+	Context("MAX_PUSH_ID frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 0xd) // type byte
+			data = appendVarInt(data, 1)
+			data = appendVarInt(data, 0x10)
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&maxPushIDFrame{PushID: 0x10}))
+		})
+
+		It("writes", func() {
+			buf := &bytes.Buffer{}
+			(&maxPushIDFrame{PushID: 100}).Write(buf)
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&maxPushIDFrame{PushID: 100}))
+		})
+
+		It("errors on EOF", func() {
+			buf := &bytes.Buffer{}
+			(&maxPushIDFrame{PushID: 0xdecafbad}).Write(buf)
+			data := buf.Bytes()
+			for i := range data {
+				b := make([]byte, i)
+				copy(b, data[:i])
+				_, err := parseNextFrame(bytes.NewReader(b))
+				Expect(err).To(MatchError(io.EOF))
+			}
+		})
+	})
+
+	Context("GOAWAY frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 0x7) // type byte
+			data = appendVarInt(data, 1)
+			data = appendVarInt(data, 0x10)
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&goAwayFrame{StreamID: 0x10}))
+		})
+
+		It("writes", func() {
+			buf := &bytes.Buffer{}
+			(&goAwayFrame{StreamID: 0x1337}).Write(buf)
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&goAwayFrame{StreamID: 0x1337}))
+		})
+
+		It("parses stream IDs that don't use the shortest encoding", func() {
+			data := appendVarInt(nil, 0x7) // type byte
+			data = appendVarInt(data, 4)
+			data = append(data, 0x80, 0, 0, 0x10) // stream ID 0x10, encoded in 4 bytes
+			frame, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&goAwayFrame{StreamID: 0x10}))
+		})
+
+		It("errors if the length doesn't match the stream ID", func() {
+			data := appendVarInt(nil, 0x7) // type byte
+			data = appendVarInt(data, 3)
+			data = appendVarInt(data, 0x1337)
+			data = append(data, 0)
+			_, err := parseNextFrame(bytes.NewReader(data))
+			Expect(err).To(MatchError("unexpected frame length: 3"))
+		})
+
+		It("errors on EOF", func() {
+			buf := &bytes.Buffer{}
+			(&goAwayFrame{StreamID: 0xdecafbad}).Write(buf)
+			data := buf.Bytes()
+			for i := range data {
+				b := make([]byte, i)
+				copy(b, data[:i])
+				_, err := parseNextFrame(bytes.NewReader(b))
+				Expect(err).To(MatchError(io.EOF))
+			}
+		})
+	})
+
+	Context("WEBTRANSPORT_STREAM frames", func() {
+		It("parses", func() {
+			data := appendVarInt(nil, 0x41) // type byte
+			data = appendVarInt(data, 0x10)
+			data = append(data, []byte("foobar")...)
+			r := bytes.NewReader(data)
+			frame, err := parseFirstRequestStreamFrame(r, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&webTransportStreamFrame{SessionID: 0x10}))
+			Expect(r.Len()).To(Equal(6))
+		})
+
+		It("writes", func() {
+			buf := &bytes.Buffer{}
+			(&webTransportStreamFrame{SessionID: 0x1337}).Write(buf)
+			frame, err := parseFirstRequestStreamFrame(buf, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&webTransportStreamFrame{SessionID: 0x1337}))
+			Expect(buf.Len()).To(BeZero())
+		})
+
+		It("skips frames of type 0x41 if WebTransport is disabled", func() {
+			data := appendVarInt(nil, 0x41) // type byte
+			data = appendVarInt(data, 6)
+			data = append(data, []byte("foobar")...)
+			buf := bytes.NewBuffer(data)
+			(&headersFrame{Length: 0x1234}).Write(buf)
+			frame, err := parseFirstRequestStreamFrame(buf, false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&headersFrame{Length: 0x1234}))
+		})
+
+		It("skips frames of type 0x41 that are not the first frame on a stream", func() {
+			data := appendVarInt(nil, 0x41) // type byte
+			data = appendVarInt(data, 6)
+			data = append(data, []byte("foobar")...)
+			buf := bytes.NewBuffer(data)
+			(&dataFrame{Length: 0x1234}).Write(buf)
+			frame, err := parseNextFrame(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&dataFrame{Length: 0x1234}))
+		})
+
+		It("parses other frames at the beginning of a stream", func() {
+			buf := &bytes.Buffer{}
+			(&headersFrame{Length: 0x1234}).Write(buf)
+			frame, err := parseFirstRequestStreamFrame(buf, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&headersFrame{Length: 0x1234}))
+		})
+	})
 })
@@ -4,10 +4,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
-	"errors"
 	"fmt"
-	"io"
-	"log"
 	"math"
 	"mime"
 	"net/http"
@@ -16,7 +13,7 @@ import (
 	"sync"
 
 	"github.com/lucas-clemente/quic-go"
-	"github.com/marten-seemann/qpack"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 // 通知主线程触发下一个请求的剩余数据量比例阈值
@@ -32,13 +29,18 @@ type parallelRequestScheduler struct {
 	tlsConfig        *tls.Config
 	quicConfig       *quic.Config
 	requestWriter    *requestWriter
-	decoder          *qpack.Decoder
+	qpackConf        *qpackConfig
 	roundTripperOpts *roundTripperOpts
+	pushes           *pushCache // 服务端推送的响应, 未启用推送时为 nil
+	dialer           func(network, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.Session, error)
+	logger           utils.Logger
+	metrics          *parallelSchedulerMetrics
 
 	// session 管理部分
 	openedSessions      []*sessionControlblock // 已经打开的 quic 连接
 	idleSession         int                    // 当前处于空闲状态的 quic 连接
 	currentSessionIndex int                    // 使用轮询算法时应当使用的 session 下标
+	closed              bool                   // 调度器是否已经被关闭, 关闭之后不再建立新的 quic 连接
 
 	// request 管理部分
 	documentQueue   *[]*requestControlBlock // html 文件请求队列
@@ -51,6 +53,23 @@ type parallelRequestScheduler struct {
 	newSessionAddedChan   *chan struct{}                //有新的 session 被添加了就往该 chan 发送信号
 }
 
+// parallelSchedulerMetrics 是 parallelRequestScheduler 上报的统计数据
+type parallelSchedulerMetrics struct {
+	requests         utils.Counter // 添加到调度器的请求
+	parallelRequests utils.Counter // 被拆分成子请求并行传输的请求
+	subRequests      utils.Counter // 执行的子请求
+	retries          utils.Counter // 重试的请求和子请求
+}
+
+func newParallelSchedulerMetrics(m utils.Metrics) *parallelSchedulerMetrics {
+	return &parallelSchedulerMetrics{
+		requests:         m.Counter("http3_parallel_scheduler_requests_total", "Number of requests added to the parallel request scheduler."),
+		parallelRequests: m.Counter("http3_parallel_scheduler_split_requests_total", "Number of requests that were split into sub requests."),
+		subRequests:      m.Counter("http3_parallel_scheduler_sub_requests_total", "Number of sub requests executed."),
+		retries:          m.Counter("http3_parallel_scheduler_retries_total", "Number of requests and sub requests that were retried."),
+	}
+}
+
 // newParallelRequestScheduler 初始化并返回新生成的调度器 parallelRequestScheduler 实例
 func newParallelRequestScheduler(info *clientInfo) requestScheduler {
 
@@ -75,8 +94,12 @@ func newParallelRequestScheduler(info *clientInfo) requestScheduler {
 		tlsConfig:        info.tlsConfig,
 		quicConfig:       info.quicConfig,
 		requestWriter:    info.requestWriter,
-		decoder:          info.decoder,
+		qpackConf:        info.qpackConf,
 		roundTripperOpts: info.roundTripperOpts,
+		pushes:           info.pushes,
+		dialer:           info.dialer,
+		logger:           info.logger.WithPrefix("parallel scheduler"),
+		metrics:          newParallelSchedulerMetrics(utils.MetricsOrNop(info.metrics)),
 
 		// 同一 domain 下最多只能打开 4 条 quic 连接
 		openedSessions: make([]*sessionControlblock, 0, maxConcurrentSessions),
@@ -159,9 +182,9 @@ func (scheduler *parallelRequestScheduler) removeFirst(index int) {
 }
 
 // getNewQuicSession 方法创建并返回一条新的 quicSession
-func (scheduler *parallelRequestScheduler) getNewQuicSession() (*quic.Session, error) {
+func (scheduler *parallelRequestScheduler) getNewQuicSession() (*clientSessionState, error) {
 	// 建立一个新的 quicSession
-	newSession, err := dial(scheduler.hostname, scheduler.tlsConfig, scheduler.quicConfig)
+	newSession, err := dial(scheduler.hostname, scheduler.tlsConfig, scheduler.quicConfig, scheduler.pushes, scheduler.qpackConf, scheduler.dialer, scheduler.logger)
 	if err != nil {
 		return nil, err
 	}
@@ -170,6 +193,9 @@ func (scheduler *parallelRequestScheduler) getNewQuicSession() (*quic.Session, e
 
 // addNewQuicSession 向调度器添加一条新的 quicSession，并返回对应的控制块
 func (scheduler *parallelRequestScheduler) addNewQuicSession() (*sessionControlblock, error) {
+	if scheduler.isClosed() {
+		return nil, errSchedulerClosed
+	}
 	newSession, err := scheduler.getNewQuicSession()
 	if err != nil {
 		// 出错，可能是 404 等错误
@@ -177,16 +203,60 @@ func (scheduler *parallelRequestScheduler) addNewQuicSession() (*sessionControlb
 	}
 
 	scheduler.mutex.Lock()
+	if scheduler.closed {
+		// 建立连接期间调度器被关闭了, close 不会再拆除这条连接
+		scheduler.mutex.Unlock()
+		newSession.sess.Close()
+		return nil, errSchedulerClosed
+	}
 	newSessionBlock := newSessionControlBlock(scheduler.maxSessionID, newSession, true)
 	scheduler.openedSessions = append(scheduler.openedSessions, newSessionBlock)
 	scheduler.maxSessionID++
 	scheduler.mutex.Unlock()
-	log.Printf("addNewQuicSession: added = <%d>", newSessionBlock.id)
+	scheduler.logger.Debugf("addNewQuicSession: added = <%d>", newSessionBlock.id)
 	*scheduler.mayExecuteNextRequest <- struct{}{}
-	*scheduler.newSessionAddedChan <- struct{}{}
+	select {
+	case *scheduler.newSessionAddedChan <- struct{}{}:
+	default:
+	}
+	go scheduler.replaceOnGoAway(newSessionBlock)
 	return newSessionBlock, nil
 }
 
+// replaceOnGoAway 在服务端对 block 发送 GOAWAY 帧之后, 把它从调度器中移除, 并建立一条新的 quicSession 代替它
+func (scheduler *parallelRequestScheduler) replaceOnGoAway(block *sessionControlblock) {
+	select {
+	case <-block.h3.goAway:
+	case <-(*block.session).Context().Done():
+		return
+	}
+	scheduler.mutex.Lock()
+	if scheduler.closed {
+		// 调度器已经关闭, 不再建立新的连接代替它
+		scheduler.mutex.Unlock()
+		return
+	}
+	for i, b := range scheduler.openedSessions {
+		if b == block {
+			scheduler.openedSessions = append(scheduler.openedSessions[:i], scheduler.openedSessions[i+1:]...)
+			break
+		}
+	}
+	if scheduler.currentSessionIndex >= len(scheduler.openedSessions) {
+		scheduler.currentSessionIndex = 0
+	}
+	scheduler.mutex.Unlock()
+	scheduler.logger.Debugf("replaceOnGoAway: session = <%d> is going away", block.id)
+	scheduler.addNewQuicSession()
+}
+
+// isClosed 返回调度器是否已经被关闭
+func (scheduler *parallelRequestScheduler) isClosed() bool {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	return scheduler.closed
+}
+
 // getSession 获取调度器中可用的 quicSession
 func (scheduler *parallelRequestScheduler) getSession() (*sessionControlblock, error) {
 	for i := scheduler.currentSessionIndex; i < len(scheduler.openedSessions); i++ {
@@ -210,6 +280,11 @@ func (scheduler *parallelRequestScheduler) mayExecute() (*requestControlBlock, e
 	// 获取可用的请求
 	// FIXME: 这里似乎会锁住整个调度器, 但是加了日志之后似乎重复不出来, 就这样放着先吧
 	nextRequest, index := scheduler.popRequest()
+	for nextRequest != nil && nextRequest.canceled() {
+		// 丢弃已经被取消的请求
+		scheduler.removeFirst(index)
+		nextRequest, index = scheduler.popRequest()
+	}
 	if nextRequest == nil {
 		// log.Println("mayExecute: no available request")
 		return nil, errNoAvailableRequest
@@ -233,12 +308,13 @@ func (scheduler *parallelRequestScheduler) mayExecute() (*requestControlBlock, e
 func (scheduler *parallelRequestScheduler) close() error {
 	scheduler.mutex.Lock()
 	defer scheduler.mutex.Unlock()
+	scheduler.closed = true
 	var err error
 	for _, block := range scheduler.openedSessions {
 		session := *block.session
 		err = session.Close()
 		if err != nil {
-			fmt.Println(err.Error())
+			scheduler.logger.Errorf("error in closing session: id = <%v>, err = <%v>", block.id, err.Error())
 			return err
 		}
 	}
@@ -270,15 +346,39 @@ func (scheduler *parallelRequestScheduler) addNewRequest(block *requestControlBl
 	*scheduler.mayExecuteNextRequest <- struct{}{}
 }
 
-// addAndWait 方法负责把收到的请求添加到调度器队列中，并在调度器处理完成之后返回响应
+// removeRequest 把尚未执行的请求从所在的队列中移除, 返回该请求是否仍在队列中
+func (scheduler *parallelRequestScheduler) removeRequest(block *requestControlBlock) bool {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	queues := []*[]*requestControlBlock{
+		scheduler.documentQueue, scheduler.styleSheetQueue, scheduler.scriptQueue, scheduler.otherFileQueue,
+	}
+	for _, queue := range queues {
+		for i, b := range *queue {
+			if b == block {
+				*queue = append((*queue)[:i], (*queue)[i+1:]...)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// addAndWait 方法负责把收到的请求添加到调度器队列中，并在调度器处理完成之后返回响应.
+// 主请求和它的全部子请求都使用由 req.Context() 派生的 context, 它在请求被取消, 请求出错,
+// 或者响应体被关闭时被取消, 使调度器重置该请求的所有 stream 并释放分段响应体的缓冲区.
 func (scheduler *parallelRequestScheduler) addAndWait(req *http.Request) (*http.Response, error) {
-	var requestDone = make(chan struct{}, 0)
-	var requestError = make(chan struct{}, 0)
+	// 执行请求的 go 程可能在本方法返回之后才发出信号, 因此两个 chan 都需要缓冲
+	var requestDone = make(chan struct{}, 1)
+	var requestError = make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(req.Context())
 	reqBlock := requestControlBlock{
 		isMainSession:                 true,
-		request:                       req,
+		request:                       req.WithContext(ctx),
 		requestDone:                   &requestDone,
 		requestError:                  &requestError,
+		ctx:                           ctx,
+		cancel:                        cancel,
 		shouldUseParallelTransmission: true, // 把该字段设为 true 可以让该请求至少进行一次并行传输决策
 	}
 	scheduler.addNewRequest(&reqBlock)
@@ -286,15 +386,31 @@ func (scheduler *parallelRequestScheduler) addAndWait(req *http.Request) (*http.
 	for {
 		select {
 		case <-*reqBlock.requestError:
-			// 请求错误统一按照 404 Not Found 处理
+			if reqBlock.unhandledError != nil {
+				cancel()
+				return nil, reqBlock.unhandledError
+			}
+			// 其他请求错误统一按照 404 Not Found 处理
 			if reqBlock.response == nil {
 				// 无响应的请求以 404 作为错误码返回
+				cancel()
 				return getErrorResponse(req), nil
 			}
 			// 有响应的请求返回其本身的响应体
+			reqBlock.response.Body = &cancelOnCloseBody{ReadCloser: reqBlock.response.Body, cancel: cancel}
 			return reqBlock.response, nil
 		case <-*reqBlock.requestDone:
+			if reqBlock.response == nil {
+				cancel()
+				return nil, reqBlock.unhandledError
+			}
+			reqBlock.response.Body = &cancelOnCloseBody{ReadCloser: reqBlock.response.Body, cancel: cancel}
 			return reqBlock.response, reqBlock.unhandledError
+		case <-req.Context().Done():
+			// 还在队列中的请求直接被移除. 已经开始执行的请求由执行它的 go 程在 ctx 被取消之后重置 stream.
+			scheduler.removeRequest(&reqBlock)
+			cancel()
+			return nil, req.Context().Err()
 		}
 	}
 }
@@ -372,6 +488,10 @@ func (scheduler *parallelRequestScheduler) shouldUseParallelTransmission(
 			// 跳过主请求所在的 session，即不在此 session 上传输主请求拆分出来的分段
 			continue
 		}
+		if block.goingAway() {
+			// 服务端即将关闭该 session，不再在其上发送新的请求
+			continue
+		}
 
 		/* 排除错误的样本 */
 		bandwidth = block.getBandwidth()
@@ -455,11 +575,11 @@ func (scheduler *parallelRequestScheduler) shouldUseParallelTransmission(
 
 		// 让子请求的缓冲区在分段请求体中有序排列
 		if subReq.designatedSession != nil {
-			log.Printf("session = <%v>, start = <%v>, end = <%v>",
+			scheduler.logger.Debugf("session = <%v>, start = <%v>, end = <%v>",
 				subReq.designatedSession.id, subReq.bytesStartOffset, subReq.bytesEndOffset)
 			subReq.bufferBlock.sessionBlockID = subReq.designatedSession.id
 		} else {
-			log.Printf("session = <?>, start = <%v>, end = <%v>", subReq.bytesStartOffset, subReq.bytesEndOffset)
+			scheduler.logger.Debugf("session = <?>, start = <%v>, end = <%v>", subReq.bytesStartOffset, subReq.bytesEndOffset)
 			// TODO: 这个 -1 暂时没有什么用
 			subReq.bufferBlock.sessionBlockID = -1
 		}
@@ -475,7 +595,7 @@ func (scheduler *parallelRequestScheduler) shouldUseParallelTransmission(
 		newStart := oldStart // 开始字节数不变，只把最后一个子请求的终止字节数调整为全部字节
 		newEnd := receivedBytesCount + remainingDataLen - 1
 		subRequests[len(subRequests)-1].bytesEndOffset = newEnd
-		log.Printf("setBufferBound: set for last sub request: oldStart = <%d>, oldEnd = <%d>, newStart = <%d>, newEnd = <%d>",
+		scheduler.logger.Debugf("setBufferBound: set for last sub request: oldStart = <%d>, oldEnd = <%d>, newStart = <%d>, newEnd = <%d>",
 			oldStart, oldEnd, newStart, newEnd)
 		finalResponseBody.setBufferBound(oldStart, oldEnd, newStart, newEnd)
 	}
@@ -494,32 +614,49 @@ func shouldSendPrestartSignal(remainingDataLen int, blockSize int64) bool {
 
 // mayDoRequestParallel 方法负责实际发出请求并返回响应，视情况决定是否采用并行传输以降低下载时间
 func (scheduler *parallelRequestScheduler) mayDoRequestParallel(reqBlock *requestControlBlock) {
-	log.Printf("mayDoRequestParallel: url = <%v>, session = <%v>, dispatchable = <%v>",
+	scheduler.logger.Debugf("mayDoRequestParallel: url = <%v>, session = <%v>, dispatchable = <%v>",
 		reqBlock.request.URL.RequestURI(), reqBlock.designatedSession.id, reqBlock.designatedSession.dispatchable())
 
 	req := reqBlock.request
 	mainSession := *reqBlock.designatedSession.session
 	// 打开 quic stream，开始处理该 H3 请求
-	str, err := mainSession.OpenStreamSync(context.Background())
+	str, err := reqBlock.designatedSession.h3.openRequestStream(reqBlock.ctx, req.Method)
 	if err != nil {
-		log.Printf(err.Error())
+		scheduler.logger.Errorf("%s", err)
+		if reqBlock.designatedSession.h3.isUnprocessed(nil, err) && scheduler.retry(reqBlock) {
+			return
+		}
 		scheduler.signalRequestError(reqBlock)
 		return
 	}
 
 	// 获取原始响应体
-	rsp, err := scheduler.getResponse(req, &str, &mainSession)
+	rsp, err := scheduler.getResponse(req, &str, reqBlock.designatedSession.h3)
 	if err != nil {
-		log.Printf("mayDoRequestParallel %v", err.Error())
+		scheduler.logger.Errorf("mayDoRequestParallel %v", err.Error())
+		if reqBlock.designatedSession.h3.isUnprocessed(str, err) && scheduler.retry(reqBlock) {
+			return
+		}
+		if req.Body != nil && req.Body != http.NoBody {
+			// 上传失败时返回错误而不是 404 响应, 以免上层应用误认为服务端已经处理了该请求
+			reqBlock.unhandledError = err
+		}
 		scheduler.signalRequestError(reqBlock)
 		return
 	}
 
+	if req.Method != http.MethodGet {
+		// 只有 GET 请求的响应可以通过 Range 子请求并行传输, 其他请求 (例如上传文件) 的响应直接返回
+		reqBlock.response = rsp
+		scheduler.signalRequestDone(reqBlock)
+		return
+	}
+
 	// 读取响应体总长度，确定需要复制的总数据量，在主 go 程处值为 [0-EOF]
 	contentLength, err := strconv.Atoi(rsp.Header.Get("Content-Length"))
 	if err != nil {
 		// 该请求没有响应体
-		log.Printf("url = <%v>, err = <%v>", req.URL.RequestURI(), err.Error())
+		scheduler.logger.Errorf("url = <%v>, err = <%v>", req.URL.RequestURI(), err.Error())
 		reqBlock.response = rsp
 		scheduler.signalRequestError(reqBlock)
 		return
@@ -542,12 +679,12 @@ func (scheduler *parallelRequestScheduler) mayDoRequestParallel(reqBlock *reques
 	if int64(contentLength) < reqBlock.getBlockSize()*2 {
 		reqBlock.response = rsp
 		scheduler.signalRequestDone(reqBlock)
-		log.Printf("using early stop: session = <%d>, url = <%v>", reqBlock.designatedSession.id, req.URL.RequestURI())
+		scheduler.logger.Debugf("using early stop: session = <%d>, url = <%v>", reqBlock.designatedSession.id, req.URL.RequestURI())
 		return
 	}
 
 	// 把主请求读取的数据添加到响应体中
-	respBody := newSegmentedResponseBody(contentLength)
+	respBody := newSegmentedResponseBody(contentLength, scheduler.logger)
 	// 返回包装后的响应
 	reqBlock.response = &http.Response{
 		Proto:      "HTTP/3",
@@ -568,6 +705,11 @@ func (scheduler *parallelRequestScheduler) mayDoRequestParallel(reqBlock *reques
 
 	// log.Printf("main session: main buffer addr = <%p>", mainSessionBufferControlBlock.buffer)
 	respBody.registerSegmentedBuffer(mainSessionBufferControlBlock)
+	// 请求被取消或者响应体被关闭之后, 唤醒正在读取响应体的上层应用, 并释放全部缓冲区
+	go func() {
+		<-reqBlock.ctx.Done()
+		respBody.closeWithError(reqBlock.ctx.Err())
+	}()
 	// 通知上层响应体已准备
 	*reqBlock.requestDone <- struct{}{}
 	mainRequestURL := fmt.Sprintf("%s://%s%s", req.URL.Scheme, req.URL.Hostname(), req.URL.RequestURI())
@@ -584,7 +726,7 @@ func (scheduler *parallelRequestScheduler) mayDoRequestParallel(reqBlock *reques
 		if shouldSendPrestartSignal(remainingDataLen, reqBlock.getBlockSize()) {
 			once.Do(func() {
 				// 还有一块的传输任务，可以通知调度器下发下一个请求了
-				log.Printf("prestart signal sent: session = <%v>, url = <%v>", reqBlock.designatedSession.id, reqBlock.request.URL.RequestURI())
+				scheduler.logger.Debugf("prestart signal sent: session = <%v>, url = <%v>", reqBlock.designatedSession.id, reqBlock.request.URL.RequestURI())
 				reqBlock.designatedSession.setIdle(reqBlock.request.URL.RequestURI())
 				*scheduler.mayExecuteNextRequest <- struct{}{}
 			})
@@ -593,8 +735,17 @@ func (scheduler *parallelRequestScheduler) mayDoRequestParallel(reqBlock *reques
 		// FIXME: 这里似乎需要同时传入 remainingDataLen 和块大小，以决定从请求体中读取的字节数
 		written, bandwidth, err := copyToBuffer(mainSessionBufferControlBlock, rsp, reqBlock.getBlockSize(), remainingDataLen)
 		if err != nil {
-			log.Printf(err.Error())
-			scheduler.signalRequestError(reqBlock)
+			scheduler.logger.Errorf("%s", err)
+			// 上层应用已经拿到了响应, 只能通过响应体返回错误. 取消 ctx 以停止全部子请求.
+			if reqBlock.canceled() {
+				err = reqBlock.ctx.Err()
+			}
+			respBody.closeWithError(err)
+			reqBlock.cancel()
+			once.Do(func() {
+				reqBlock.designatedSession.setIdle(reqBlock.request.URL.RequestURI())
+				*scheduler.mayExecuteNextRequest <- struct{}{}
+			})
 			return
 		}
 		readDataLen += written
@@ -623,100 +774,131 @@ func (scheduler *parallelRequestScheduler) mayDoRequestParallel(reqBlock *reques
 				continue
 			}
 			// 调整使用子请求情况下，调整主请求的字节流起始位置
-			log.Printf("main request: mainSessionAdjustedEndOffset = <%d>, written = <%d>, offset = <%d>",
+			scheduler.logger.Debugf("main request: mainSessionAdjustedEndOffset = <%d>, written = <%d>, offset = <%d>",
 				mainSessionAdjustedEndOffset, written, offset)
 			remainingDataLen = mainSessionAdjustedEndOffset
 			oldStart := 0
 			oldEnd := contentLength - 1 // 字节流起始位置与字节数差 1
 			newStart := 0
 			newEnd := readDataLen + remainingDataLen - 1
-			log.Printf("setBufferBound for main request: session = <%d>, oldStart = <%d>, oldEnd = <%d>, newStart = <%d>, newEnd = <%d>",
+			scheduler.logger.Debugf("setBufferBound for main request: session = <%d>, oldStart = <%d>, oldEnd = <%d>, newStart = <%d>, newEnd = <%d>",
 				reqBlock.designatedSession.id, oldStart, oldEnd, newStart, newEnd)
 			respBody.setBufferBound(oldStart, oldEnd, newStart, newEnd)
+			// 子请求与主请求一同被取消, 并携带主请求的头部
+			for _, subReq := range *subReqs {
+				subReq.ctx = reqBlock.ctx
+				subReq.cancel = reqBlock.cancel
+				subReq.header = req.Header
+			}
 			// 把需要开始的子请求发送到调度器
 			*scheduler.subRequestsChan <- subReqs
-			log.Printf("use parallel request, subReq count = <%v>, url = <%v>", len(*subReqs), mainRequestURL)
+			scheduler.metrics.parallelRequests.Add(1)
+			scheduler.logger.Debugf("use parallel request, subReq count = <%v>, url = <%v>", len(*subReqs), mainRequestURL)
 		}
 	}
 	// 读取完指定数据段之后立刻关闭这条 stream
 	rsp.Body.Close()
-	log.Printf("main request finished: session = <%v>, written = <%v>, buffer addr = <%p>",
+	scheduler.logger.Debugf("main request finished: session = <%v>, written = <%v>, buffer addr = <%p>",
 		reqBlock.request.URL.RequestURI(), offset, reqBlock.bufferBlock.buffer)
 }
 
 // executeSubRequest 负责在执行子请求，并在子请求执行完成的时候向指定的 chan 中发送读取到的全部数据
 func (scheduler *parallelRequestScheduler) executeSubRequest(reqBlock *requestControlBlock) {
+	scheduler.metrics.subRequests.Add(1)
 	subRequest, err := http.NewRequest(http.MethodGet, reqBlock.url, nil)
 	if err != nil {
-		log.Printf(err.Error())
+		scheduler.logger.Errorf("%s", err)
 	}
 
-	subRequest.Header.Add(
+	subRequest = subRequest.WithContext(reqBlock.ctx)
+	if reqBlock.header != nil {
+		subRequest.Header = reqBlock.header.Clone()
+	}
+	subRequest.Header.Set(
 		"Range", fmt.Sprintf("bytes=%d-%d", reqBlock.bytesStartOffset, reqBlock.bytesEndOffset))
+	if reqBlock.canceled() {
+		return
+	}
 
 	if reqBlock.designatedSession == nil {
 		scheduler.mutex.Lock()
 		// 调度器没有为该请求分配 session，需要在执行的时候现开一条新的 session
-		log.Println("get session for sub request")
+		scheduler.logger.Debugf("get session for sub request")
 		session, err := scheduler.getSession()
 		scheduler.mutex.Unlock()
 		reqBlock.designatedSession = session
 		if err != nil && err != errNoAvailableSession {
-			log.Printf("error: %v", err.Error())
+			scheduler.logger.Errorf("error: %v", err.Error())
 			*reqBlock.requestError <- struct{}{}
 			reqBlock.designatedSession.setIdle(reqBlock.url)
 			return
 		}
 		if err != nil {
-			log.Printf("error: %s", err.Error())
+			scheduler.logger.Debugf("getSession: %s", err.Error())
 		}
 		if err == errNoAvailableSession {
-			log.Println("error: wait for newly added session")
-		hotfix:
-			select {
-			case <-*scheduler.newSessionAddedChan:
-				scheduler.mutex.Lock()
-				session, _ := scheduler.getSession()
-				if session == nil {
-					log.Println("error: wait for next signal")
-					goto hotfix
+			scheduler.logger.Debugf("wait for newly added session")
+			for reqBlock.designatedSession == nil {
+				select {
+				case <-*scheduler.newSessionAddedChan:
+				case <-reqBlock.ctx.Done():
+					// 主请求已被取消, 不再等待新的 session
+					return
 				}
-				reqBlock.designatedSession = session
+				scheduler.mutex.Lock()
+				reqBlock.designatedSession, _ = scheduler.getSession()
 				scheduler.mutex.Unlock()
+				if reqBlock.designatedSession == nil {
+					scheduler.logger.Debugf("wait for next signal")
+				}
 			}
 		}
 		if reqBlock.designatedSession == nil {
-			log.Printf("error get nil session")
+			scheduler.logger.Errorf("error get nil session")
 		}
 		reqBlock.designatedSession.setBusy(reqBlock.url)
 	} else {
 		reqBlock.designatedSession.setBusy(reqBlock.url)
 	}
-	session := *reqBlock.designatedSession.session
-	log.Printf("executing sub request <%v>, start = <%v>, end = <%v>, session = <%v>, pendingRequest = <%v>",
+	scheduler.logger.Debugf("executing sub request <%v>, start = <%v>, end = <%v>, session = <%v>, pendingRequest = <%v>",
 		reqBlock.url, reqBlock.bytesStartOffset, reqBlock.bytesEndOffset, reqBlock.designatedSession.id, reqBlock.designatedSession.pendingRequest)
 
 	// 打开 quic stream，开始处理该 H3 请求
-	str, err := session.OpenStreamSync(context.Background())
+	str, err := reqBlock.designatedSession.h3.openRequestStream(reqBlock.ctx, subRequest.Method)
 	if err != nil {
-		log.Printf("executeSubRequest: %v", err.Error())
-		*reqBlock.requestError <- struct{}{}
+		scheduler.logger.Errorf("executeSubRequest: %v", err.Error())
+		if reqBlock.designatedSession.h3.isUnprocessed(nil, err) && scheduler.retrySubRequest(reqBlock) {
+			return
+		}
 		reqBlock.designatedSession.setIdle(reqBlock.url)
+		scheduler.abortSubRequest(reqBlock, err)
 		return
 	}
-	resp, err := scheduler.getResponse(subRequest, &str, &session)
+	resp, err := scheduler.getResponse(subRequest, &str, reqBlock.designatedSession.h3)
 	if err != nil {
-		log.Printf("executeSubRequest: %v", err.Error())
-		*reqBlock.requestError <- struct{}{}
+		scheduler.logger.Errorf("executeSubRequest: %v", err.Error())
+		if reqBlock.designatedSession.h3.isUnprocessed(str, err) && scheduler.retrySubRequest(reqBlock) {
+			return
+		}
 		reqBlock.designatedSession.setIdle(reqBlock.url)
+		scheduler.abortSubRequest(reqBlock, err)
 		return
 	}
 
+	if resp.StatusCode != http.StatusPartialContent {
+		// 服务端没有按照 Range 返回数据, 该子请求负责的数据段无法获得
+		resp.Body.Close()
+		reqBlock.designatedSession.setIdle(reqBlock.url)
+		scheduler.abortSubRequest(reqBlock, fmt.Errorf("http3: unexpected status %d for range request", resp.StatusCode))
+		return
+	}
 	contentLength, err := strconv.Atoi(resp.Header.Get("Content-Length"))
 	if err != nil {
-		log.Printf("subReq error: url = <%v> , err = <%v>", reqBlock.url, err.Error())
-		*reqBlock.requestError <- struct{}{}
+		scheduler.logger.Errorf("subReq error: url = <%v> , err = <%v>", reqBlock.url, err.Error())
+		resp.Body.Close()
 		reqBlock.designatedSession.setIdle(reqBlock.url)
+		scheduler.abortSubRequest(reqBlock, err)
+		return
 	}
 	remainingDataLen := contentLength
 	reqBlock.designatedSession.addRemainingDataLen(remainingDataLen)
@@ -740,7 +922,7 @@ func (scheduler *parallelRequestScheduler) executeSubRequest(reqBlock *requestCo
 		if shouldSendPrestartSignal(remainingDataLen, blockSize) {
 			sendPrestartSignalOnce.Do(func() {
 				// 发送信号给调度器以触发下一请求
-				log.Printf("prestart signal sent: session = <%v>, url = <%v>", reqBlock.designatedSession.id, reqBlock.url)
+				scheduler.logger.Debugf("prestart signal sent: session = <%v>, url = <%v>", reqBlock.designatedSession.id, reqBlock.url)
 				reqBlock.designatedSession.setIdle(reqBlock.url)
 				*scheduler.mayExecuteNextRequest <- struct{}{}
 			})
@@ -749,7 +931,12 @@ func (scheduler *parallelRequestScheduler) executeSubRequest(reqBlock *requestCo
 		// TODO: 改成复制到分段响应体中对应的缓冲区的方式
 		written, bandwidth, err := copyToBuffer(reqBlock.bufferBlock, resp, blockSize, remainingDataLen)
 		if err != nil {
-			log.Printf(err.Error())
+			scheduler.logger.Errorf("%s", err)
+			sendPrestartSignalOnce.Do(func() {
+				reqBlock.designatedSession.setIdle(reqBlock.url)
+				*scheduler.mayExecuteNextRequest <- struct{}{}
+			})
+			scheduler.abortSubRequest(reqBlock, err)
 			return
 		}
 		// 调整本请求和所用 session 上需要传输的数据量
@@ -770,78 +957,52 @@ func (scheduler *parallelRequestScheduler) executeSubRequest(reqBlock *requestCo
 	// 把该 session 标记为可用状态
 	resp.Body.Close()
 
-	log.Printf("sub request <%v> done, start = <%v>, end = <%v>, session = <%v>, buffer addr = <%p>",
+	scheduler.logger.Debugf("sub request <%v> done, start = <%v>, end = <%v>, session = <%v>, buffer addr = <%p>",
 		reqBlock.url, reqBlock.bytesStartOffset, reqBlock.bytesEndOffset, reqBlock.designatedSession.id, reqBlock.bufferBlock.buffer)
 }
 
+// abortSubRequest 在子请求失败之后结束整个主请求. 该子请求负责的数据段已经无法获得,
+// 因此以 err 关闭分段响应体, 并取消主请求和其他子请求.
+func (scheduler *parallelRequestScheduler) abortSubRequest(reqBlock *requestControlBlock, err error) {
+	if reqBlock.canceled() {
+		err = reqBlock.ctx.Err()
+	}
+	reqBlock.finalResponseBody.closeWithError(err)
+	reqBlock.cancel()
+}
+
 // execute 方法负责在给定的 quicStream 上执行单一的一个请求
 func (scheduler *parallelRequestScheduler) execute(
 	req *http.Request,
 	str quic.Stream,
-	quicSession quic.Session,
+	h3 *clientSessionState,
 	reqDone chan struct{},
 ) (*http.Response, requestError) {
+	quicSession := h3.sess
 	requestGzip := isUsingGzip(scheduler.roundTripperOpts.DisableCompression,
 		req.Method, req.Header.Get("accept-encoding"), req.Header.Get("Range"))
 
-	// 发送请求
-	if err := scheduler.requestWriter.WriteRequest(str, req, requestGzip); err != nil {
+	// 发送请求, 请求体在后台发送
+	upload := newRequestUpload(str)
+	if err := scheduler.requestWriter.writeRequest(str, req, requestGzip, h3.encoder, upload.fail); err != nil {
 		return nil, newStreamError(errorInternalError, err)
 	}
 
 	// 开始接受对端返回的数据
-	frame, err := parseNextFrame(str)
-	if err != nil {
-		return nil, newStreamError(errorFrameError, err)
-	}
-	// 确定第一帧是否为 H3 协议规定的 HEADER 帧
-	hf, ok := frame.(*headersFrame)
-	if !ok {
-		return nil, newConnError(errorFrameUnexpected, errors.New("expected first frame to be a HEADERS frame"))
-	}
-	// TODO: 用普通方法代替 maxHeaderBytes
-	if hf.Length > maxHeaderBytes(scheduler.roundTripperOpts.MaxHeaderBytes) {
-		return nil, newStreamError(errorFrameError, fmt.Errorf("HEADERS frame too large: %d bytes (max: %d)", hf.Length, maxHeaderBytes(scheduler.roundTripperOpts.MaxHeaderBytes)))
-	}
-	// 读取并解析 HEADER 帧
-	headerBlock := make([]byte, hf.Length)
-	if _, err := io.ReadFull(str, headerBlock); err != nil {
-		return nil, newStreamError(errorRequestIncomplete, err)
-	}
-	// 调用 qpack 解码 HEADER 帧
-	hfs, err := scheduler.decoder.DecodeFull(headerBlock)
-	if err != nil {
-		// TODO: use the right error code
-		return nil, newConnError(errorGeneralProtocolError, err)
-	}
-
-	// 构造响应体
-	res := &http.Response{
-		Proto:      "HTTP/3",
-		ProtoMajor: 3,
-		Header:     http.Header{},
-	}
-
-	// 把从 HEADER 帧中解码到的信息复制到标准 HTTP 响应头中
-	for _, hf := range hfs {
-		switch hf.Name {
-		case ":status":
-			// 完成 status 字段的解析
-			status, err := strconv.Atoi(hf.Value)
-			if err != nil {
-				return nil, newStreamError(errorGeneralProtocolError, errors.New("malformed non-numeric status pseudo header"))
-			}
-			res.StatusCode = status
-			res.Status = hf.Value + " " + http.StatusText(status)
-		default:
-			res.Header.Add(hf.Name, hf.Value)
-		}
+	res, rerr := readResponseHeaders(str, maxHeaderBytes(scheduler.roundTripperOpts.MaxHeaderBytes),
+		h3.decoder, scheduler.pushes.promiseHandler(quicSession))
+	if rerr.err != nil {
+		return nil, upload.wrap(rerr)
 	}
 
 	// 新建一个空白响应体
 	respBody := newResponseBody(str, reqDone, func() {
 		quicSession.CloseWithError(quic.ErrorCode(errorFrameUnexpected), "")
 	})
+	respBody.onPushPromise = scheduler.pushes.promiseHandler(quicSession)
+	respBody.trailer = &res.Trailer
+	respBody.decoder = h3.decoder
+	respBody.maxHeaderBytes = maxHeaderBytes(scheduler.roundTripperOpts.MaxHeaderBytes)
 	// 根据是否需要 gzip 来实际构造响应体
 	if requestGzip && res.Header.Get("Content-Encoding") == "gzip" {
 		res.Header.Del("Content-Encoding")
@@ -857,9 +1018,9 @@ func (scheduler *parallelRequestScheduler) execute(
 }
 
 // 指定的 session 和 stream 上获取执行请求并获取响应体
-func (scheduler *parallelRequestScheduler) getResponse(req *http.Request, stream *quic.Stream, session *quic.Session) (*http.Response, error) {
+func (scheduler *parallelRequestScheduler) getResponse(req *http.Request, stream *quic.Stream, h3 *clientSessionState) (*http.Response, error) {
 	str := *stream
-	sess := *session
+	sess := h3.sess
 	// Request Cancellation:
 	// This go routine keeps running even after RoundTrip() returns.
 	// It is shut down when the application is done processing the body.
@@ -874,7 +1035,7 @@ func (scheduler *parallelRequestScheduler) getResponse(req *http.Request, stream
 		}
 	}()
 
-	rsp, rerr := scheduler.execute(req, str, sess, reqDone)
+	rsp, rerr := scheduler.execute(req, str, h3, reqDone)
 	if rerr.err != nil { // if any error occurred
 		close(reqDone)
 		if rerr.streamErr != 0 { // if it was a stream error
@@ -887,6 +1048,36 @@ func (scheduler *parallelRequestScheduler) getResponse(req *http.Request, stream
 			}
 			sess.CloseWithError(quic.ErrorCode(rerr.connErr), reason)
 		}
+		return nil, rerr.err
 	}
 	return rsp, nil
 }
+
+// retry 把服务端没有处理的主请求重新加入调度器队列, 由调度器在其他 quicSession 上重试.
+// 如果请求不能被重试, 返回 false.
+func (scheduler *parallelRequestScheduler) retry(reqBlock *requestControlBlock) bool {
+	session := reqBlock.designatedSession
+	if !reqBlock.prepareRetry() {
+		return false
+	}
+	scheduler.logger.Debugf("retry: url = <%v>, retries = <%d>", reqBlock.request.URL.RequestURI(), reqBlock.retries)
+	scheduler.metrics.retries.Add(1)
+	session.setIdle(reqBlock.request.URL.RequestURI())
+	scheduler.addNewRequest(reqBlock)
+	return true
+}
+
+// retrySubRequest 把服务端没有处理的子请求重新发送到调度器, 由调度器为其重新分配 quicSession.
+// 如果子请求不能被重试, 返回 false.
+func (scheduler *parallelRequestScheduler) retrySubRequest(reqBlock *requestControlBlock) bool {
+	session := reqBlock.designatedSession
+	if !reqBlock.prepareRetry() {
+		return false
+	}
+	scheduler.logger.Debugf("retrySubRequest: url = <%v>, start = <%v>, end = <%v>, retries = <%d>",
+		reqBlock.url, reqBlock.bytesStartOffset, reqBlock.bytesEndOffset, reqBlock.retries)
+	scheduler.metrics.retries.Add(1)
+	session.setIdle(reqBlock.url)
+	*scheduler.subRequestsChan <- &[]*requestControlBlock{reqBlock}
+	return true
+}
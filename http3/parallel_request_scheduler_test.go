@@ -0,0 +1,353 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// newBlockingRequestStream returns a stream that blocks all reads until the request is canceled.
+// The returned channel is closed when the stream is reset with H3_REQUEST_CANCELLED.
+func newBlockingRequestStream() (*mockquic.MockStream, <-chan struct{}) {
+	canceled := make(chan struct{})
+	str := mockquic.NewMockStream(mockCtrl)
+	str.EXPECT().StreamID().Return(quic.StreamID(0)).AnyTimes()
+	str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) { return len(p), nil }).AnyTimes()
+	str.EXPECT().Close().AnyTimes()
+	str.EXPECT().Read(gomock.Any()).DoAndReturn(func([]byte) (int, error) {
+		<-canceled
+		return 0, errors.New("stream canceled")
+	}).AnyTimes()
+	str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestCanceled))
+	str.EXPECT().CancelWrite(gomock.Any()).AnyTimes()
+	// the stream may be canceled both by the upload and by the request context
+	var once sync.Once
+	str.EXPECT().CancelRead(quic.ErrorCode(errorRequestCanceled)).Do(func(quic.ErrorCode) {
+		once.Do(func() { close(canceled) })
+	}).MinTimes(1)
+	return str, canceled
+}
+
+func newTestClientInfo() *clientInfo {
+	return &clientInfo{
+		hostname:         "quic.clemente.io:443",
+		requestWriter:    newRequestWriter(utils.DefaultLogger),
+		roundTripperOpts: &roundTripperOpts{},
+	}
+}
+
+var _ = Describe("Parallel request scheduler", func() {
+	var (
+		scheduler     *parallelRequestScheduler
+		numGoroutines int
+	)
+
+	BeforeEach(func() {
+		numGoroutines = runtime.NumGoroutine()
+		scheduler = newRequestScheduler(parallelRequestSchedulerName, newTestClientInfo()).(*parallelRequestScheduler)
+	})
+
+	addSession := func() (*mockquic.MockSession, *sessionControlblock) {
+		sess := mockquic.NewMockSession(mockCtrl)
+		block := newSessionControlBlock(1, newClientSessionState(sess, nil, nil, utils.DefaultLogger), true)
+		scheduler.openedSessions = append(scheduler.openedSessions, block)
+		return sess, block
+	}
+
+	addAndWait := func(ctx context.Context, url string) <-chan error {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		Expect(err).ToNot(HaveOccurred())
+		errChan := make(chan error, 1)
+		go func() {
+			_, err := scheduler.addAndWait(req.WithContext(ctx))
+			errChan <- err
+		}()
+		return errChan
+	}
+
+	upload := func(body io.Reader) (<-chan *http.Response, <-chan error) {
+		req, err := http.NewRequest(http.MethodPost, "https://quic.clemente.io/artifacts/build.tar.gz", body)
+		Expect(err).ToNot(HaveOccurred())
+		rspChan := make(chan *http.Response, 1)
+		errChan := make(chan error, 1)
+		go func() {
+			rsp, err := scheduler.addAndWait(req)
+			if err != nil {
+				errChan <- err
+				return
+			}
+			rspChan <- rsp
+		}()
+		return rspChan, errChan
+	}
+
+	execute := func() {
+		var next *requestControlBlock
+		Eventually(func() (err error) {
+			next, err = scheduler.mayExecute()
+			return err
+		}).Should(Succeed())
+		go scheduler.mayDoRequestParallel(next)
+	}
+
+	// readRequestHeaders decodes the HEADERS frame of a request
+	readRequestHeaders := func(r io.Reader) map[string]string {
+		frame, err := parseNextFrame(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(BeAssignableToTypeOf(&headersFrame{}))
+		data := make([]byte, frame.(*headersFrame).Length)
+		_, err = io.ReadFull(r, data)
+		Expect(err).ToNot(HaveOccurred())
+		hfs, err := qpack.NewDecoder(nil).DecodeFull(data)
+		Expect(err).ToNot(HaveOccurred())
+		values := make(map[string]string)
+		for _, hf := range hfs {
+			values[hf.Name] = hf.Value
+		}
+		return values
+	}
+
+	// getResponseStream returns a stream that records the request and returns the given response
+	getResponseStream := func(status int, content []byte) (*mockquic.MockStream, *bytes.Buffer, <-chan struct{}) {
+		rspBuf := &bytes.Buffer{}
+		rw := newResponseWriter(rspBuf, utils.DefaultLogger)
+		rw.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		rw.WriteHeader(status)
+		rw.Write(content)
+		rw.Flush()
+		reqBuf := &bytes.Buffer{}
+		closed := make(chan struct{})
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().StreamID().AnyTimes()
+		str.EXPECT().Write(gomock.Any()).DoAndReturn(reqBuf.Write).AnyTimes()
+		str.EXPECT().Close().Do(func() { close(closed) })
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+		str.EXPECT().CancelRead(gomock.Any()).AnyTimes()
+		str.EXPECT().CancelWrite(gomock.Any()).AnyTimes()
+		return str, reqBuf, closed
+	}
+
+	queueLen := func() int {
+		scheduler.mutex.Lock()
+		defer scheduler.mutex.Unlock()
+		return len(*scheduler.documentQueue) + len(*scheduler.styleSheetQueue) +
+			len(*scheduler.scriptQueue) + len(*scheduler.otherFileQueue)
+	}
+
+	Context("closing", func() {
+		var dials int32
+
+		BeforeEach(func() {
+			dials = 0
+			scheduler.dialer = func(string, string, *tls.Config, *quic.Config) (quic.Session, error) {
+				atomic.AddInt32(&dials, 1)
+				return nil, errors.New("dial failed")
+			}
+		})
+
+		It("doesn't dial new sessions after it was closed", func() {
+			Expect(scheduler.close()).To(Succeed())
+			_, err := scheduler.addNewQuicSession()
+			Expect(err).To(MatchError(errSchedulerClosed))
+			Expect(atomic.LoadInt32(&dials)).To(BeZero())
+		})
+
+		It("doesn't replace a session that receives a GOAWAY while closing", func() {
+			sess, block := addSession()
+			sess.EXPECT().Context().Return(context.Background()).AnyTimes()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				scheduler.replaceOnGoAway(block)
+			}()
+			// the GOAWAY arrives while close() is tearing down the sessions
+			sess.EXPECT().Close().Do(func() { close(block.h3.goAway) })
+			Expect(scheduler.close()).To(Succeed())
+			Eventually(done).Should(BeClosed())
+			Expect(atomic.LoadInt32(&dials)).To(BeZero())
+			Expect(*scheduler.mayExecuteNextRequest).To(BeEmpty())
+		})
+	})
+
+	It("rejects response HEADERS frames larger than the maximum header size, without reading them", func() {
+		buf := &bytes.Buffer{}
+		(&headersFrame{Length: 1338}).Write(buf)
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+		_, rerr := readResponseHeaders(str, 1337, nil, nil)
+		Expect(rerr.err).To(MatchError("HEADERS frame too large: 1338 bytes (max: 1337)"))
+		Expect(rerr.streamErr).To(Equal(errorExcessiveLoad))
+	})
+
+	It("removes canceled requests from the queue", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		errChan := addAndWait(ctx, "https://quic.clemente.io/style.css")
+		Eventually(queueLen).Should(Equal(1))
+		Expect(*scheduler.styleSheetQueue).To(HaveLen(1))
+		cancel()
+		Eventually(errChan).Should(Receive(MatchError(context.Canceled)))
+		Expect(queueLen()).To(BeZero())
+		Eventually(runtime.NumGoroutine).Should(BeNumerically("<=", numGoroutines))
+	})
+
+	It("doesn't execute requests that were canceled", func() {
+		addSession()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		*scheduler.documentQueue = append(*scheduler.documentQueue, &requestControlBlock{ctx: ctx})
+		_, err := scheduler.mayExecute()
+		Expect(err).To(MatchError(errNoAvailableRequest))
+		Expect(queueLen()).To(BeZero())
+	})
+
+	It("resets the stream of a canceled request", func() {
+		sess, sessBlock := addSession()
+		str, canceled := newBlockingRequestStream()
+		sess.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		errChan := addAndWait(ctx, "https://quic.clemente.io/index.html")
+		execute()
+		Consistently(errChan, 50*time.Millisecond).ShouldNot(Receive())
+		cancel()
+		Eventually(errChan).Should(Receive(MatchError(context.Canceled)))
+		Eventually(canceled).Should(BeClosed())
+		Eventually(sessBlock.getPendingRequest).Should(BeZero())
+		Eventually(runtime.NumGoroutine).Should(BeNumerically("<=", numGoroutines))
+	})
+
+	Context("uploads", func() {
+		It("returns the response without splitting it", func() {
+			sess, sessBlock := addSession()
+			// large enough to be split into sub requests, if it was the response to a GET request
+			content := bytes.Repeat([]byte("foobar"), 20000)
+			str, reqBuf, uploaded := getResponseStream(http.StatusCreated, content)
+			sess.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+			rspChan, _ := upload(strings.NewReader("artifact"))
+			execute()
+			var rsp *http.Response
+			Eventually(rspChan).Should(Receive(&rsp))
+			Expect(rsp.StatusCode).To(Equal(http.StatusCreated))
+			Expect(rsp.Body.(*cancelOnCloseBody).ReadCloser).ToNot(BeAssignableToTypeOf(&segmentedResponseBodyI{}))
+			data, err := ioutil.ReadAll(rsp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(content))
+			Expect(rsp.Body.Close()).To(Succeed())
+			Eventually(uploaded).Should(BeClosed())
+			Expect(readRequestHeaders(reqBuf)).To(HaveKeyWithValue(":method", "POST"))
+			frame, err := parseNextFrame(reqBuf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&dataFrame{Length: 8}))
+			Expect(reqBuf.String()).To(Equal("artifact"))
+			Expect(*scheduler.subRequestsChan).To(BeEmpty())
+			Expect(sessBlock.getPendingRequest()).To(BeZero())
+		})
+
+		It("returns errors that occur while sending the request body", func() {
+			sess, sessBlock := addSession()
+			str, canceled := newBlockingRequestStream()
+			sess.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+			testErr := errors.New("read error")
+			_, errChan := upload(io.MultiReader(strings.NewReader("foo"), &errorReader{err: testErr}))
+			execute()
+			Eventually(errChan).Should(Receive(Equal(testErr)))
+			Expect(canceled).To(BeClosed())
+			Eventually(sessBlock.getPendingRequest).Should(BeZero())
+			Eventually(runtime.NumGoroutine).Should(BeNumerically("<=", numGoroutines))
+		})
+	})
+
+	Context("sub requests", func() {
+		var (
+			body     segmentedResponseBody
+			subBlock *requestControlBlock
+			cancel   context.CancelFunc
+		)
+
+		BeforeEach(func() {
+			body = newSegmentedResponseBody(100, utils.DefaultLogger)
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			subBlock = &requestControlBlock{
+				url:               "https://quic.clemente.io/file.dat",
+				bytesStartOffset:  50,
+				bytesEndOffset:    99,
+				finalResponseBody: body,
+				bufferBlock:       &segmentedBufferControlBlock{start: 50, end: 99, buffer: &bytes.Buffer{}},
+				ctx:               ctx,
+				cancel:            cancel,
+			}
+			body.registerSegmentedBuffer(subBlock.bufferBlock)
+		})
+
+		It("resets the stream and releases the response body", func() {
+			sess, sessBlock := addSession()
+			str, canceled := newBlockingRequestStream()
+			sess.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+			subBlock.designatedSession = sessBlock
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				scheduler.executeSubRequest(subBlock)
+			}()
+			Consistently(done, 50*time.Millisecond).ShouldNot(BeClosed())
+			cancel()
+			Eventually(done).Should(BeClosed())
+			Expect(canceled).To(BeClosed())
+			Expect(sessBlock.getPendingRequest()).To(BeZero())
+			_, err := body.Read(make([]byte, 10))
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(subBlock.bufferBlock.released).To(BeTrue())
+			Eventually(runtime.NumGoroutine).Should(BeNumerically("<=", numGoroutines))
+		})
+
+		It("sends the headers of the main request", func() {
+			sess, sessBlock := addSession()
+			// the server ignores the Range header
+			str, reqBuf, closed := getResponseStream(http.StatusOK, make([]byte, 100))
+			sess.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+			subBlock.designatedSession = sessBlock
+			subBlock.header = http.Header{"X-Build-Id": {"42"}, "Range": {"bytes=0-"}}
+			scheduler.executeSubRequest(subBlock)
+			Expect(closed).To(BeClosed())
+			hdr := readRequestHeaders(reqBuf)
+			Expect(hdr).To(HaveKeyWithValue("x-build-id", "42"))
+			Expect(hdr).To(HaveKeyWithValue("range", "bytes=50-99"))
+			_, err := body.Read(make([]byte, 10))
+			Expect(err).To(MatchError("http3: unexpected status 200 for range request"))
+			Expect(subBlock.canceled()).To(BeTrue())
+			Expect(sessBlock.getPendingRequest()).To(BeZero())
+		})
+
+		It("stops waiting for a session", func() {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				scheduler.executeSubRequest(subBlock)
+			}()
+			Consistently(done, 50*time.Millisecond).ShouldNot(BeClosed())
+			cancel()
+			Eventually(done).Should(BeClosed())
+			body.Close()
+			Eventually(runtime.NumGoroutine).Should(BeNumerically("<=", numGoroutines))
+		})
+	})
+})
@@ -0,0 +1,206 @@
+package http3
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+const (
+	settingQPACKMaxTableCapacity = 0x1
+	settingQPACKBlockedStreams   = 0x7
+)
+
+const (
+	defaultQPACKMaxTableCapacity = 4096
+	defaultQPACKBlockedStreams   = 16
+)
+
+var errQPACKDuplicateStream = errors.New("duplicate QPACK stream")
+
+// QPACKStats contains statistics about the header compression on HTTP/3 connections.
+type QPACKStats struct {
+	HeaderBytes        uint64 // total length of the names and values of the header fields before encoding
+	HeaderBlockBytes   uint64 // total length of the encoded header blocks
+	EncoderStreamBytes uint64 // total length of the instructions sent on the QPACK encoder streams
+}
+
+// CompressionRatio returns the ratio of the bytes sent for headers
+// (header blocks and encoder stream instructions) to the uncompressed header bytes.
+// Smaller values mean better compression.
+func (s QPACKStats) CompressionRatio() float64 {
+	if s.HeaderBytes == 0 {
+		return 0
+	}
+	return float64(s.HeaderBlockBytes+s.EncoderStreamBytes) / float64(s.HeaderBytes)
+}
+
+func (s *QPACKStats) add(other QPACKStats) {
+	s.HeaderBytes += other.HeaderBytes
+	s.HeaderBlockBytes += other.HeaderBlockBytes
+	s.EncoderStreamBytes += other.EncoderStreamBytes
+}
+
+// qpackStatsCollector 汇总多条连接上的 QPACK 编码器的统计数据
+type qpackStatsCollector struct {
+	mutex    sync.Mutex
+	closed   QPACKStats // 已经关闭的连接上的统计数据
+	encoders map[*qpackEncoder]struct{}
+}
+
+func newQPACKStatsCollector() *qpackStatsCollector {
+	return &qpackStatsCollector{encoders: make(map[*qpackEncoder]struct{})}
+}
+
+func (c *qpackStatsCollector) add(e *qpackEncoder) {
+	c.mutex.Lock()
+	c.encoders[e] = struct{}{}
+	c.mutex.Unlock()
+}
+
+// remove 在连接关闭时把 e 的统计数据计入 closed
+func (c *qpackStatsCollector) remove(e *qpackEncoder) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.encoders[e]; !ok {
+		return
+	}
+	delete(c.encoders, e)
+	c.closed.add(e.getStats())
+}
+
+func (c *qpackStatsCollector) get() QPACKStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	stats := c.closed
+	for e := range c.encoders {
+		stats.add(e.getStats())
+	}
+	return stats
+}
+
+// qpackConfig 是 QPACK 编解码器的配置
+type qpackConfig struct {
+	maxTableCapacity uint64 // 本端解码器的最大动态表容量, 同时也是本端编码器使用的动态表容量的上限
+	blockedStreams   uint64 // 本端解码器允许被阻塞的 stream 数目
+	stats            *qpackStatsCollector
+}
+
+// newQPACKConfig 根据用户的配置创建 qpackConfig. 0 表示使用默认值, 负数表示禁用.
+func newQPACKConfig(maxTableCapacity, blockedStreams int64, stats *qpackStatsCollector) *qpackConfig {
+	conf := &qpackConfig{
+		maxTableCapacity: defaultQPACKMaxTableCapacity,
+		blockedStreams:   defaultQPACKBlockedStreams,
+		stats:            stats,
+	}
+	if maxTableCapacity < 0 {
+		conf.maxTableCapacity = 0
+	} else if maxTableCapacity > 0 {
+		conf.maxTableCapacity = uint64(maxTableCapacity)
+	}
+	if blockedStreams < 0 {
+		conf.blockedStreams = 0
+	} else if blockedStreams > 0 {
+		conf.blockedStreams = uint64(blockedStreams)
+	}
+	return conf
+}
+
+// settings 返回本端需要在 SETTINGS 帧中宣告的 QPACK 设置项. conf 为 nil 时不使用动态表.
+func (c *qpackConfig) settings() map[uint64]uint64 {
+	settings := make(map[uint64]uint64)
+	if c == nil {
+		return settings
+	}
+	if c.maxTableCapacity > 0 {
+		settings[settingQPACKMaxTableCapacity] = c.maxTableCapacity
+	}
+	if c.blockedStreams > 0 {
+		settings[settingQPACKBlockedStreams] = c.blockedStreams
+	}
+	return settings
+}
+
+// newCodec 为 sess 创建 QPACK 编码器和解码器. 编码器和解码器 stream 在第一次写入时才被打开.
+func (c *qpackConfig) newCodec(sess quic.Session) (*qpackEncoder, *qpackDecoder) {
+	var maxTableCapacity, blockedStreams uint64
+	if c != nil {
+		maxTableCapacity = c.maxTableCapacity
+		blockedStreams = c.blockedStreams
+	}
+	encoder := newQPACKEncoder(&qpackStreamWriter{sess: sess, streamType: 0x2}, maxTableCapacity)
+	decoder := newQPACKDecoder(&qpackStreamWriter{sess: sess, streamType: 0x3}, maxTableCapacity, blockedStreams)
+	if c != nil && c.stats != nil {
+		c.stats.add(encoder)
+	}
+	return encoder, decoder
+}
+
+// closeCodec 在 sess 关闭之后释放 newCodec 创建的编码器和解码器
+func (c *qpackConfig) closeCodec(encoder *qpackEncoder, decoder *qpackDecoder, err error) {
+	decoder.close(err)
+	if c != nil && c.stats != nil {
+		c.stats.remove(encoder)
+	}
+}
+
+// qpackStreamWriter 在第一次写入时打开 QPACK 编码器或解码器 stream
+type qpackStreamWriter struct {
+	mutex sync.Mutex
+
+	sess       quic.Session
+	streamType uint64
+	str        quic.SendStream
+}
+
+var _ io.Writer = &qpackStreamWriter{}
+
+func (w *qpackStreamWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.str == nil {
+		str, err := w.sess.OpenUniStream()
+		if err != nil {
+			return 0, err
+		}
+		buf := &bytes.Buffer{}
+		utils.WriteVarInt(buf, w.streamType)
+		if _, err := str.Write(buf.Bytes()); err != nil {
+			return 0, err
+		}
+		w.str = str
+	}
+	return w.str.Write(p)
+}
+
+// handleQPACKStream 处理对端的 QPACK 编码器 stream (0x2) 或解码器 stream (0x3), 直到发生错误.
+// 这两种 stream 在连接的整个生命周期内都不能被关闭.
+func handleQPACKStream(sess quic.Session, streamType uint64, str io.Reader, encoder *qpackEncoder, decoder *qpackDecoder) {
+	var err error
+	var code errorCode
+	if streamType == 0x2 {
+		err = decoder.handleEncoderStream(str)
+		code = errorQPACKEncoderStreamError
+	} else {
+		err = encoder.handleDecoderStream(str)
+		code = errorQPACKDecoderStreamError
+	}
+	var rerr requestError
+	switch err {
+	case errQPACKDuplicateStream:
+		rerr = newConnError(errorStreamCreationError, err)
+	case io.EOF, io.ErrUnexpectedEOF:
+		rerr = newConnError(errorClosedCriticalStream, errors.New("QPACK stream closed"))
+	default:
+		if _, ok := err.(quic.StreamError); ok {
+			rerr = newConnError(errorClosedCriticalStream, err)
+		} else {
+			rerr = newConnError(code, err)
+		}
+	}
+	closeWithRequestError(sess, rerr)
+}
@@ -0,0 +1,386 @@
+package http3
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/marten-seemann/qpack"
+)
+
+// qpackDecoder 解码一条连接上收到的头部.
+// 引用了尚未收到的动态表条目的头部块会阻塞, 直到对应的插入指令到达.
+type qpackDecoder struct {
+	mutex sync.Mutex
+
+	stream io.Writer // 解码器 stream
+
+	maxCapacity       uint64 // 本端宣告的 SETTINGS_QPACK_MAX_TABLE_CAPACITY
+	maxBlockedStreams uint64 // 本端宣告的 SETTINGS_QPACK_BLOCKED_STREAMS
+
+	table              qpackDynamicTable
+	knownReceivedCount uint64                   // 已经告知编码器的插入指令数目
+	blocked            map[chan struct{}]uint64 // 被阻塞的头部块所需的 Required Insert Count
+	closeErr           error
+
+	hasEncoderStream bool
+}
+
+func newQPACKDecoder(stream io.Writer, maxCapacity, maxBlockedStreams uint64) *qpackDecoder {
+	return &qpackDecoder{
+		stream:            stream,
+		maxCapacity:       maxCapacity,
+		maxBlockedStreams: maxBlockedStreams,
+		blocked:           make(map[chan struct{}]uint64),
+	}
+}
+
+// decode 解码 stream id 上的一个头部块. 对于引用了动态表的头部块, 在解码之后发送 Section Acknowledgment.
+// d 为 nil 时只能解码不引用动态表的头部块.
+func (d *qpackDecoder) decode(id quic.StreamID, block []byte) ([]qpack.HeaderField, error) {
+	r := bytes.NewReader(block)
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, errors.New("QPACK header block too short")
+	}
+	encodedInsertCount, err := readQPACKInt(r, b, 8)
+	if err != nil {
+		return nil, err
+	}
+	b, err = r.ReadByte()
+	if err != nil {
+		return nil, errors.New("QPACK header block too short")
+	}
+	deltaBase, err := readQPACKInt(r, b, 7)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		if encodedInsertCount != 0 {
+			return nil, errors.New("header block references the dynamic table")
+		}
+		return d.decodeFields(r, 0, 0)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	ric, err := d.requiredInsertCount(encodedInsertCount)
+	if err != nil {
+		return nil, err
+	}
+	var base uint64
+	if b&0x80 == 0 {
+		base = ric + deltaBase
+	} else {
+		if deltaBase >= ric {
+			return nil, fmt.Errorf("invalid Base: Required Insert Count %d, Delta Base %d", ric, deltaBase)
+		}
+		base = ric - deltaBase - 1
+	}
+	if ric > d.table.insertCount() {
+		if err := d.waitLocked(ric); err != nil {
+			return nil, err
+		}
+	}
+	hfs, err := d.decodeFields(r, ric, base)
+	if err != nil {
+		return nil, err
+	}
+	if ric > 0 {
+		if ric > d.knownReceivedCount {
+			d.knownReceivedCount = ric
+		}
+		if _, err := d.stream.Write(appendQPACKInt(nil, 0x80, 7, uint64(id))); err != nil {
+			return nil, err
+		}
+	}
+	return hfs, nil
+}
+
+// decodeStaticHeaders 解码只使用静态表编码的头部块
+func decodeStaticHeaders(block []byte) ([]qpack.HeaderField, error) {
+	var d *qpackDecoder
+	return d.decode(0, block)
+}
+
+// requiredInsertCount 还原头部块前缀中编码的 Required Insert Count (RFC 9204 4.5.1.1 节)
+func (d *qpackDecoder) requiredInsertCount(encoded uint64) (uint64, error) {
+	if encoded == 0 {
+		return 0, nil
+	}
+	maxEntries := d.maxCapacity / qpackEntryOverhead
+	fullRange := 2 * maxEntries
+	if encoded > fullRange {
+		return 0, fmt.Errorf("invalid encoded Required Insert Count %d", encoded)
+	}
+	maxValue := d.table.insertCount() + maxEntries
+	ric := maxValue/fullRange*fullRange + encoded - 1
+	if ric > maxValue {
+		if ric <= fullRange {
+			return 0, fmt.Errorf("invalid encoded Required Insert Count %d", encoded)
+		}
+		ric -= fullRange
+	}
+	if ric == 0 {
+		return 0, fmt.Errorf("invalid encoded Required Insert Count %d", encoded)
+	}
+	return ric, nil
+}
+
+// waitLocked 等待动态表中插入了至少 ric 个条目. 调用时必须持有 d.mutex.
+func (d *qpackDecoder) waitLocked(ric uint64) error {
+	if d.closeErr != nil {
+		return d.closeErr
+	}
+	if uint64(len(d.blocked)) >= d.maxBlockedStreams {
+		return fmt.Errorf("too many blocked streams (max: %d)", d.maxBlockedStreams)
+	}
+	ch := make(chan struct{})
+	d.blocked[ch] = ric
+	d.mutex.Unlock()
+	<-ch
+	d.mutex.Lock()
+	return d.closeErr
+}
+
+func (d *qpackDecoder) decodeFields(r *bytes.Reader, ric, base uint64) ([]qpack.HeaderField, error) {
+	maxLen := uint64(r.Len())
+	var hfs []qpack.HeaderField
+	for r.Len() > 0 {
+		b, _ := r.ReadByte()
+		var hf qpack.HeaderField
+		var err error
+		switch {
+		case b&0x80 > 0: // Indexed Field Line
+			var idx uint64
+			if idx, err = readQPACKInt(r, b, 6); err != nil {
+				return nil, err
+			}
+			if b&0x40 > 0 {
+				hf, err = qpackStaticEntry(idx)
+			} else {
+				hf, err = d.relativeEntry(idx, ric, base)
+			}
+		case b&0x40 > 0: // Literal Field Line with Name Reference
+			var idx uint64
+			if idx, err = readQPACKInt(r, b, 4); err != nil {
+				return nil, err
+			}
+			if b&0x10 > 0 {
+				hf, err = qpackStaticEntry(idx)
+			} else {
+				hf, err = d.relativeEntry(idx, ric, base)
+			}
+			if err == nil {
+				hf.Value, err = readQPACKValue(r, maxLen)
+			}
+		case b&0x20 > 0: // Literal Field Line with Literal Name
+			if hf.Name, err = readQPACKString(r, b, 3, maxLen); err == nil {
+				hf.Value, err = readQPACKValue(r, maxLen)
+			}
+		case b&0x10 > 0: // Indexed Field Line with Post-Base Index
+			var idx uint64
+			if idx, err = readQPACKInt(r, b, 4); err != nil {
+				return nil, err
+			}
+			hf, err = d.entry(base+idx, ric)
+		default: // Literal Field Line with Post-Base Name Reference
+			var idx uint64
+			if idx, err = readQPACKInt(r, b, 3); err != nil {
+				return nil, err
+			}
+			if hf, err = d.entry(base+idx, ric); err == nil {
+				hf.Value, err = readQPACKValue(r, maxLen)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		hfs = append(hfs, hf)
+	}
+	return hfs, nil
+}
+
+func readQPACKValue(r qpackReader, maxLen uint64) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	return readQPACKString(r, b, 7, maxLen)
+}
+
+func qpackStaticEntry(idx uint64) (qpack.HeaderField, error) {
+	if idx >= uint64(len(qpackStaticTable)) {
+		return qpack.HeaderField{}, fmt.Errorf("invalid static table index %d", idx)
+	}
+	return qpackStaticTable[idx], nil
+}
+
+// relativeEntry 返回相对于 base 的索引为 idx 的动态表条目
+func (d *qpackDecoder) relativeEntry(idx, ric, base uint64) (qpack.HeaderField, error) {
+	if idx >= base {
+		return qpack.HeaderField{}, fmt.Errorf("invalid relative index %d (Base: %d)", idx, base)
+	}
+	return d.entry(base-1-idx, ric)
+}
+
+// entry 返回绝对索引为 abs 的动态表条目. 头部块只能引用绝对索引小于其 Required Insert Count 的条目.
+func (d *qpackDecoder) entry(abs, ric uint64) (qpack.HeaderField, error) {
+	if abs >= ric {
+		return qpack.HeaderField{}, fmt.Errorf("reference to dynamic table entry %d beyond the Required Insert Count %d", abs, ric)
+	}
+	hf, ok := d.table.get(abs)
+	if !ok {
+		return qpack.HeaderField{}, fmt.Errorf("reference to evicted dynamic table entry %d", abs)
+	}
+	return hf, nil
+}
+
+// handleEncoderStream 处理对端的编码器 stream, 直到发生错误
+func (d *qpackDecoder) handleEncoderStream(str io.Reader) error {
+	d.mutex.Lock()
+	duplicate := d.hasEncoderStream
+	d.hasEncoderStream = true
+	d.mutex.Unlock()
+	if duplicate {
+		return errQPACKDuplicateStream
+	}
+
+	r := bufio.NewReader(str)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if err := d.handleEncoderInstruction(r, b); err != nil {
+			return err
+		}
+		// 在处理完所有已经到达的指令之后, 告知编码器新插入的条目
+		if r.Buffered() == 0 {
+			if err := d.sendInsertCountIncrement(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (d *qpackDecoder) handleEncoderInstruction(r qpackReader, b byte) error {
+	switch {
+	case b&0x80 > 0: // Insert with Name Reference
+		idx, err := readQPACKInt(r, b, 6)
+		if err != nil {
+			return err
+		}
+		value, err := readQPACKValue(r, d.maxCapacity)
+		if err != nil {
+			return err
+		}
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		var hf qpack.HeaderField
+		if b&0x40 > 0 {
+			hf, err = qpackStaticEntry(idx)
+		} else {
+			hf, err = d.insertedEntry(idx)
+		}
+		if err != nil {
+			return err
+		}
+		hf.Value = value
+		return d.insertLocked(hf)
+	case b&0x40 > 0: // Insert with Literal Name
+		name, err := readQPACKString(r, b, 5, d.maxCapacity)
+		if err != nil {
+			return err
+		}
+		value, err := readQPACKValue(r, d.maxCapacity)
+		if err != nil {
+			return err
+		}
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		return d.insertLocked(qpack.HeaderField{Name: name, Value: value})
+	case b&0x20 > 0: // Set Dynamic Table Capacity
+		capacity, err := readQPACKInt(r, b, 5)
+		if err != nil {
+			return err
+		}
+		if capacity > d.maxCapacity {
+			return fmt.Errorf("dynamic table capacity %d exceeds the maximum %d", capacity, d.maxCapacity)
+		}
+		d.mutex.Lock()
+		d.table.setCapacity(capacity)
+		d.mutex.Unlock()
+		return nil
+	default: // Duplicate
+		idx, err := readQPACKInt(r, b, 5)
+		if err != nil {
+			return err
+		}
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		hf, err := d.insertedEntry(idx)
+		if err != nil {
+			return err
+		}
+		return d.insertLocked(hf)
+	}
+}
+
+// insertedEntry 返回编码器指令中相对索引为 idx 的条目, 相对索引 0 表示最新插入的条目
+func (d *qpackDecoder) insertedEntry(idx uint64) (qpack.HeaderField, error) {
+	if idx >= d.table.insertCount() {
+		return qpack.HeaderField{}, fmt.Errorf("invalid relative index %d", idx)
+	}
+	hf, ok := d.table.get(d.table.insertCount() - 1 - idx)
+	if !ok {
+		return qpack.HeaderField{}, fmt.Errorf("reference to evicted dynamic table entry %d", idx)
+	}
+	return hf, nil
+}
+
+// insertLocked 在动态表中插入 hf, 并唤醒所有可以继续解码的头部块. 调用时必须持有 d.mutex.
+func (d *qpackDecoder) insertLocked(hf qpack.HeaderField) error {
+	if qpackEntrySize(hf) > d.table.capacity {
+		return fmt.Errorf("dynamic table entry of size %d exceeds the capacity %d", qpackEntrySize(hf), d.table.capacity)
+	}
+	d.table.insert(hf)
+	for ch, ric := range d.blocked {
+		if ric <= d.table.insertCount() {
+			delete(d.blocked, ch)
+			close(ch)
+		}
+	}
+	return nil
+}
+
+func (d *qpackDecoder) sendInsertCountIncrement() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	increment := d.table.insertCount() - d.knownReceivedCount
+	if increment == 0 {
+		return nil
+	}
+	d.knownReceivedCount = d.table.insertCount()
+	_, err := d.stream.Write(appendQPACKInt(nil, 0, 6, increment))
+	return err
+}
+
+// close 使所有被阻塞的头部块以 err 解码失败
+func (d *qpackDecoder) close(err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.closeErr != nil {
+		return
+	}
+	d.closeErr = err
+	for ch := range d.blocked {
+		delete(d.blocked, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,332 @@
+package http3
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/marten-seemann/qpack"
+)
+
+// qpackNoIndex 中的头部字段的值几乎每次都不同, 插入动态表只会驱逐更有用的条目
+var qpackNoIndex = map[string]bool{
+	":path":             true,
+	"content-length":    true,
+	"content-range":     true,
+	"range":             true,
+	"date":              true,
+	"etag":              true,
+	"last-modified":     true,
+	"if-modified-since": true,
+	"if-none-match":     true,
+	"age":               true,
+	"location":          true,
+}
+
+// qpackSensitive 中的头部字段不会被插入动态表, 并且要求中间节点也不对其进行索引
+var qpackSensitive = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+}
+
+// qpackSection 是一个尚未被对端确认的头部块
+type qpackSection struct {
+	requiredInsertCount uint64
+	minRef              uint64 // 引用的最小绝对索引
+}
+
+// reference 记录头部块对绝对索引为 abs 的条目的引用
+func (s *qpackSection) reference(abs uint64) {
+	if abs < s.minRef {
+		s.minRef = abs
+	}
+	if abs+1 > s.requiredInsertCount {
+		s.requiredInsertCount = abs + 1
+	}
+}
+
+// qpackEncoder 使用静态表和动态表编码一条连接上发送的头部.
+// 在收到对端的 SETTINGS 帧之前, 只使用静态表.
+type qpackEncoder struct {
+	mutex sync.Mutex
+
+	stream io.Writer // 编码器 stream
+
+	maxCapacity       uint64 // 本端允许使用的最大动态表容量
+	peerMaxCapacity   uint64 // 对端的 SETTINGS_QPACK_MAX_TABLE_CAPACITY
+	maxBlockedStreams uint64 // 对端的 SETTINGS_QPACK_BLOCKED_STREAMS
+
+	table              qpackDynamicTable
+	knownReceivedCount uint64                            // 对端已确认收到的插入指令数目
+	sections           map[quic.StreamID][]*qpackSection // 每个 stream 上尚未被确认的头部块
+
+	hasDecoderStream bool
+
+	stats QPACKStats
+}
+
+func newQPACKEncoder(stream io.Writer, maxCapacity uint64) *qpackEncoder {
+	return &qpackEncoder{
+		stream:      stream,
+		maxCapacity: maxCapacity,
+		sections:    make(map[quic.StreamID][]*qpackSection),
+	}
+}
+
+// applySettings 按照对端 SETTINGS 帧中的 QPACK 设置项启用动态表
+func (e *qpackEncoder) applySettings(f *settingsFrame) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.peerMaxCapacity = f.settings[settingQPACKMaxTableCapacity]
+	e.maxBlockedStreams = f.settings[settingQPACKBlockedStreams]
+	capacity := e.peerMaxCapacity
+	if capacity > e.maxCapacity {
+		capacity = e.maxCapacity
+	}
+	if capacity < qpackEntryOverhead {
+		// 容纳不下任何条目
+		return nil
+	}
+	e.table.setCapacity(capacity)
+	return e.writeInstructions(appendQPACKInt(nil, 0x20, 5, capacity))
+}
+
+// encode 编码 stream id 上的一个头部块.
+// 动态表中的条目通过编码器 stream 插入, 对尚未被对端确认的条目的引用可能使该 stream 在对端被阻塞.
+// e 为 nil 时只使用静态表.
+func (e *qpackEncoder) encode(id quic.StreamID, fields []qpack.HeaderField) ([]byte, error) {
+	if e == nil {
+		return encodeStaticHeaders(fields), nil
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	base := e.table.insertCount()
+	section := &qpackSection{minRef: math.MaxUint64}
+	mayBlock := e.mayBlock(id)
+	var instructions, lines []byte
+	var headerBytes uint64
+	for _, hf := range fields {
+		headerBytes += uint64(len(hf.Name) + len(hf.Value))
+		if _, ok := qpackStaticIndex[hf]; ok || e.table.capacity == 0 || qpackSensitive[hf.Name] {
+			lines = appendQPACKStaticField(lines, hf)
+			continue
+		}
+		abs, ok := e.table.find(hf, false)
+		if !ok && !qpackNoIndex[hf.Name] {
+			abs, ok = e.insert(&instructions, hf, section)
+		}
+		if !ok || (abs >= e.knownReceivedCount && !mayBlock) {
+			lines = appendQPACKStaticField(lines, hf)
+			continue
+		}
+		section.reference(abs)
+		if abs < base {
+			lines = appendQPACKInt(lines, 0x80, 6, base-1-abs)
+		} else {
+			lines = appendQPACKInt(lines, 0x10, 4, abs-base)
+		}
+	}
+	if len(instructions) > 0 {
+		if err := e.writeInstructions(instructions); err != nil {
+			return nil, err
+		}
+	}
+
+	block := make([]byte, 0, len(lines)+8)
+	if ric := section.requiredInsertCount; ric == 0 {
+		block = append(block, 0, 0)
+	} else {
+		maxEntries := e.peerMaxCapacity / qpackEntryOverhead
+		block = appendQPACKInt(block, 0, 8, ric%(2*maxEntries)+1)
+		if base >= ric {
+			block = appendQPACKInt(block, 0, 7, base-ric)
+		} else {
+			block = appendQPACKInt(block, 0x80, 7, ric-base-1)
+		}
+		e.sections[id] = append(e.sections[id], section)
+	}
+	block = append(block, lines...)
+
+	e.stats.HeaderBytes += headerBytes
+	e.stats.HeaderBlockBytes += uint64(len(block))
+	return block, nil
+}
+
+// encodeStaticHeaders 只使用静态表编码一个头部块, 解码该头部块不需要动态表
+func encodeStaticHeaders(fields []qpack.HeaderField) []byte {
+	block := []byte{0, 0}
+	for _, hf := range fields {
+		block = appendQPACKStaticField(block, hf)
+	}
+	return block
+}
+
+// appendQPACKStaticField 只使用静态表编码 hf
+func appendQPACKStaticField(lines []byte, hf qpack.HeaderField) []byte {
+	if idx, ok := qpackStaticIndex[hf]; ok {
+		return appendQPACKInt(lines, 0xc0, 6, idx)
+	}
+	if idx, ok := qpackStaticNameIndex[hf.Name]; ok {
+		flags := byte(0x50)
+		if qpackSensitive[hf.Name] {
+			flags |= 0x20
+		}
+		lines = appendQPACKInt(lines, flags, 4, idx)
+		return appendQPACKString(lines, 0, 7, hf.Value)
+	}
+	flags := byte(0x20)
+	if qpackSensitive[hf.Name] {
+		flags |= 0x10
+	}
+	lines = appendQPACKString(lines, flags, 3, hf.Name)
+	return appendQPACKString(lines, 0, 7, hf.Value)
+}
+
+// insert 把 hf 插入动态表, 并把对应的指令追加到 instructions 中.
+// 如果插入需要驱逐仍被引用的条目, 则不插入, 返回 false.
+func (e *qpackEncoder) insert(instructions *[]byte, hf qpack.HeaderField, section *qpackSection) (uint64, bool) {
+	n, ok := e.table.evictionsNeeded(qpackEntrySize(hf))
+	if !ok {
+		return 0, false
+	}
+	if n > 0 && !e.evictable(e.table.dropped+n-1, section) {
+		return 0, false
+	}
+	if idx, ok := qpackStaticNameIndex[hf.Name]; ok {
+		*instructions = appendQPACKInt(*instructions, 0xc0, 6, idx)
+	} else {
+		*instructions = appendQPACKString(*instructions, 0x40, 5, hf.Name)
+	}
+	*instructions = appendQPACKString(*instructions, 0, 7, hf.Value)
+	e.table.insert(hf)
+	return e.table.insertCount() - 1, true
+}
+
+// evictable 判断绝对索引不大于 abs 的条目是否都可以被驱逐.
+// 被尚未确认的头部块 (包括正在编码的头部块 current) 引用的条目不能被驱逐.
+func (e *qpackEncoder) evictable(abs uint64, current *qpackSection) bool {
+	if current.minRef <= abs {
+		return false
+	}
+	for _, sections := range e.sections {
+		for _, s := range sections {
+			if s.minRef <= abs {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// mayBlock 判断 stream id 上的头部块是否可以引用尚未被对端确认的条目.
+// 对端最多允许 maxBlockedStreams 个 stream 被阻塞.
+func (e *qpackEncoder) mayBlock(id quic.StreamID) bool {
+	if e.maxBlockedStreams == 0 {
+		return false
+	}
+	var blocking uint64
+	for sid, sections := range e.sections {
+		for _, s := range sections {
+			if s.requiredInsertCount > e.knownReceivedCount {
+				if sid == id {
+					return true
+				}
+				blocking++
+				break
+			}
+		}
+	}
+	return blocking < e.maxBlockedStreams
+}
+
+func (e *qpackEncoder) writeInstructions(b []byte) error {
+	e.stats.EncoderStreamBytes += uint64(len(b))
+	_, err := e.stream.Write(b)
+	return err
+}
+
+// handleDecoderStream 处理对端的解码器 stream, 直到发生错误
+func (e *qpackEncoder) handleDecoderStream(str io.Reader) error {
+	e.mutex.Lock()
+	duplicate := e.hasDecoderStream
+	e.hasDecoderStream = true
+	e.mutex.Unlock()
+	if duplicate {
+		return errQPACKDuplicateStream
+	}
+
+	r := bufio.NewReader(str)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch {
+		case b&0x80 > 0: // Section Acknowledgment
+			var id uint64
+			if id, err = readQPACKInt(r, b, 7); err == nil {
+				err = e.acknowledgeSection(quic.StreamID(id))
+			}
+		case b&0x40 > 0: // Stream Cancellation
+			var id uint64
+			if id, err = readQPACKInt(r, b, 6); err == nil {
+				e.cancelStream(quic.StreamID(id))
+			}
+		default: // Insert Count Increment
+			var increment uint64
+			if increment, err = readQPACKInt(r, b, 6); err == nil {
+				err = e.incrementInsertCount(increment)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (e *qpackEncoder) acknowledgeSection(id quic.StreamID) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	sections := e.sections[id]
+	if len(sections) == 0 {
+		return fmt.Errorf("Section Acknowledgment for stream %d without outstanding field sections", id)
+	}
+	if len(sections) == 1 {
+		delete(e.sections, id)
+	} else {
+		e.sections[id] = sections[1:]
+	}
+	if ric := sections[0].requiredInsertCount; ric > e.knownReceivedCount {
+		e.knownReceivedCount = ric
+	}
+	return nil
+}
+
+func (e *qpackEncoder) cancelStream(id quic.StreamID) {
+	e.mutex.Lock()
+	delete(e.sections, id)
+	e.mutex.Unlock()
+}
+
+func (e *qpackEncoder) incrementInsertCount(increment uint64) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if increment == 0 || e.knownReceivedCount+increment > e.table.insertCount() {
+		return fmt.Errorf("invalid Insert Count Increment %d", increment)
+	}
+	e.knownReceivedCount += increment
+	return nil
+}
+
+// getStats 返回该编码器的统计数据
+func (e *qpackEncoder) getStats() QPACKStats {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.stats
+}
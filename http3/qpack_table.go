@@ -0,0 +1,165 @@
+package http3
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/marten-seemann/qpack"
+	"golang.org/x/net/http2/hpack"
+)
+
+// qpackEntryOverhead 是动态表中每个条目除名称和值之外额外占用的大小
+const qpackEntryOverhead = 32
+
+var errQPACKIntegerOverflow = errors.New("QPACK integer overflow")
+
+// qpackReader 用于读取 QPACK 指令和字段行
+type qpackReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// appendQPACKInt 以 n 位前缀编码 i, 并追加到 dst 中 (RFC 7541 5.1 节).
+// flags 是第一个字节中前缀之前的高位.
+func appendQPACKInt(dst []byte, flags byte, n byte, i uint64) []byte {
+	k := uint64(1)<<n - 1
+	if i < k {
+		return append(dst, flags|byte(i))
+	}
+	dst = append(dst, flags|byte(k))
+	i -= k
+	for ; i >= 0x80; i >>= 7 {
+		dst = append(dst, byte(0x80|(i&0x7f)))
+	}
+	return append(dst, byte(i))
+}
+
+// readQPACKInt 读取以 n 位前缀编码的整数, first 是已经读取的第一个字节
+func readQPACKInt(r io.ByteReader, first byte, n byte) (uint64, error) {
+	k := uint64(1)<<n - 1
+	i := uint64(first) & k
+	if i < k {
+		return i, nil
+	}
+	var m uint
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if m > 56 {
+			return 0, errQPACKIntegerOverflow
+		}
+		i += uint64(b&0x7f) << m
+		if b&0x80 == 0 {
+			return i, nil
+		}
+		m += 7
+	}
+}
+
+// appendQPACKString 编码字符串 s. 长度使用 n 位前缀, 紧邻前缀的高一位是 Huffman 标志位.
+// 只有在 Huffman 编码更短时才使用 Huffman 编码.
+func appendQPACKString(dst []byte, flags byte, n byte, s string) []byte {
+	if l := hpack.HuffmanEncodeLength(s); l < uint64(len(s)) {
+		dst = appendQPACKInt(dst, flags|1<<n, n, l)
+		return hpack.AppendHuffmanString(dst, s)
+	}
+	dst = appendQPACKInt(dst, flags, n, uint64(len(s)))
+	return append(dst, s...)
+}
+
+// readQPACKString 读取以 n 位长度前缀编码的字符串, first 是已经读取的第一个字节.
+// 长度超过 maxLen 的字符串会导致错误.
+func readQPACKString(r qpackReader, first byte, n byte, maxLen uint64) (string, error) {
+	huffman := first&(1<<n) > 0
+	l, err := readQPACKInt(r, first, n)
+	if err != nil {
+		return "", err
+	}
+	if l > maxLen {
+		return "", fmt.Errorf("QPACK string too long: %d bytes", l)
+	}
+	buf := make([]byte, l)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	if !huffman {
+		return string(buf), nil
+	}
+	return hpack.HuffmanDecodeToString(buf)
+}
+
+// qpackEntrySize 返回 hf 在动态表中占用的大小
+func qpackEntrySize(hf qpack.HeaderField) uint64 {
+	return uint64(len(hf.Name)+len(hf.Value)) + qpackEntryOverhead
+}
+
+// qpackDynamicTable 是 QPACK 的动态表. 条目使用绝对索引访问, 第一个插入的条目的绝对索引为 0.
+type qpackDynamicTable struct {
+	entries  []qpack.HeaderField // 尚未被驱逐的条目, 按照插入顺序排列
+	dropped  uint64              // 已被驱逐的条目数, 即 entries[0] 的绝对索引
+	size     uint64              // 所有条目占用的大小之和
+	capacity uint64
+}
+
+// insertCount 返回插入过的条目总数
+func (t *qpackDynamicTable) insertCount() uint64 {
+	return t.dropped + uint64(len(t.entries))
+}
+
+// get 返回绝对索引为 abs 的条目. 该条目必须已经插入, 并且尚未被驱逐.
+func (t *qpackDynamicTable) get(abs uint64) (qpack.HeaderField, bool) {
+	if abs < t.dropped || abs >= t.insertCount() {
+		return qpack.HeaderField{}, false
+	}
+	return t.entries[abs-t.dropped], true
+}
+
+// find 返回与 hf 匹配的最新条目的绝对索引. 如果 nameOnly 为 true, 只需要名称匹配.
+func (t *qpackDynamicTable) find(hf qpack.HeaderField, nameOnly bool) (uint64, bool) {
+	for i := len(t.entries) - 1; i >= 0; i-- {
+		e := t.entries[i]
+		if e.Name == hf.Name && (nameOnly || e.Value == hf.Value) {
+			return t.dropped + uint64(i), true
+		}
+	}
+	return 0, false
+}
+
+// evictionsNeeded 返回为了容纳 size 大小的新条目, 需要驱逐的条目数.
+// 如果即使驱逐所有条目也无法容纳, 返回 false.
+func (t *qpackDynamicTable) evictionsNeeded(size uint64) (uint64, bool) {
+	if size > t.capacity {
+		return 0, false
+	}
+	var n uint64
+	for used := t.size; used+size > t.capacity; n++ {
+		used -= qpackEntrySize(t.entries[n])
+	}
+	return n, true
+}
+
+// evict 驱逐最早插入的条目, 直到所有条目占用的大小不超过 size
+func (t *qpackDynamicTable) evict(size uint64) {
+	for t.size > size {
+		t.size -= qpackEntrySize(t.entries[0])
+		t.entries = t.entries[1:]
+		t.dropped++
+	}
+}
+
+// setCapacity 设置动态表的容量, 必要时驱逐条目
+func (t *qpackDynamicTable) setCapacity(capacity uint64) {
+	t.capacity = capacity
+	t.evict(capacity)
+}
+
+// insert 插入一个新条目, 必要时驱逐条目. 调用者需要保证条目大小不超过容量.
+func (t *qpackDynamicTable) insert(hf qpack.HeaderField) {
+	size := qpackEntrySize(hf)
+	t.evict(t.capacity - size)
+	t.entries = append(t.entries, hf)
+	t.size += size
+}
@@ -0,0 +1,314 @@
+package http3
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QPACK", func() {
+	Context("integers", func() {
+		It("encodes and decodes integers", func() {
+			for _, i := range []uint64{0, 1, 30, 31, 32, 127, 128, 1337, math.MaxUint32, 1<<62 - 1} {
+				b := appendQPACKInt(nil, 0xe0, 5, i)
+				Expect(b[0] & 0xe0).To(Equal(byte(0xe0)))
+				r := bytes.NewReader(b[1:])
+				n, err := readQPACKInt(r, b[0], 5)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(i))
+				Expect(r.Len()).To(BeZero())
+			}
+		})
+
+		It("uses the example from RFC 7541", func() {
+			Expect(appendQPACKInt(nil, 0, 5, 1337)).To(Equal([]byte{0x1f, 0x9a, 0x0a}))
+		})
+
+		It("errors on integers that overflow", func() {
+			b := append([]byte{0x1f}, bytes.Repeat([]byte{0xff}, 10)...)
+			_, err := readQPACKInt(bytes.NewReader(b[1:]), b[0], 5)
+			Expect(err).To(MatchError(errQPACKIntegerOverflow))
+		})
+
+		It("encodes and decodes Huffman-encoded strings", func() {
+			b := appendQPACKString(nil, 0, 7, "www.example.com")
+			Expect(b[0] & 0x80).ToNot(BeZero())
+			s, err := readQPACKString(bytes.NewReader(b[1:]), b[0], 7, 100)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s).To(Equal("www.example.com"))
+		})
+
+		It("errors on strings that are too long", func() {
+			b := appendQPACKString(nil, 0, 7, "foobar") // Huffman-encoded to 5 bytes
+			_, err := readQPACKString(bytes.NewReader(b[1:]), b[0], 7, 3)
+			Expect(err).To(MatchError("QPACK string too long: 5 bytes"))
+		})
+	})
+
+	Context("dynamic table", func() {
+		It("evicts the oldest entries", func() {
+			t := &qpackDynamicTable{}
+			t.setCapacity(100)
+			t.insert(qpack.HeaderField{Name: "foo", Value: "bar"})     // 38 bytes
+			t.insert(qpack.HeaderField{Name: "lorem", Value: "ipsum"}) // 42 bytes
+			n, ok := t.evictionsNeeded(qpackEntrySize(qpack.HeaderField{Name: "a", Value: "b"}))
+			Expect(ok).To(BeTrue())
+			Expect(n).To(Equal(uint64(1)))
+			t.insert(qpack.HeaderField{Name: "a", Value: "b"})
+			Expect(t.insertCount()).To(Equal(uint64(3)))
+			_, ok = t.get(0)
+			Expect(ok).To(BeFalse())
+			hf, ok := t.get(1)
+			Expect(ok).To(BeTrue())
+			Expect(hf.Name).To(Equal("lorem"))
+			abs, ok := t.find(qpack.HeaderField{Name: "a"}, true)
+			Expect(ok).To(BeTrue())
+			Expect(abs).To(Equal(uint64(2)))
+		})
+
+		It("doesn't insert entries larger than the capacity", func() {
+			t := &qpackDynamicTable{}
+			t.setCapacity(40)
+			_, ok := t.evictionsNeeded(qpackEntrySize(qpack.HeaderField{Name: "lorem", Value: "ipsum"}))
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("encoding and decoding", func() {
+		var (
+			encoderStream, decoderStream *bytes.Buffer
+			encoder                      *qpackEncoder
+			decoder                      *qpackDecoder
+		)
+
+		fields := []qpack.HeaderField{
+			{Name: ":method", Value: "GET"},
+			{Name: ":authority", Value: "www.example.com"},
+			{Name: ":path", Value: "/index.html"},
+			{Name: "user-agent", Value: "quic-go HTTP/3"},
+			{Name: "x-custom", Value: "foobar"},
+		}
+
+		settings := func(capacity, blockedStreams uint64) *settingsFrame {
+			return &settingsFrame{settings: map[uint64]uint64{
+				settingQPACKMaxTableCapacity: capacity,
+				settingQPACKBlockedStreams:   blockedStreams,
+			}}
+		}
+
+		// transfer passes the instructions written to a QPACK stream to the peer
+		transfer := func(str *bytes.Buffer, handle func(io.Reader) error) {
+			Expect(handle(bytes.NewReader(str.Bytes()))).To(MatchError(io.EOF))
+			str.Reset()
+		}
+
+		BeforeEach(func() {
+			encoderStream = &bytes.Buffer{}
+			decoderStream = &bytes.Buffer{}
+			encoder = newQPACKEncoder(encoderStream, 4096)
+			decoder = newQPACKDecoder(decoderStream, 4096, 16)
+		})
+
+		It("only uses the static table before receiving the SETTINGS", func() {
+			block, err := encoder.encode(0, fields)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encoderStream.Len()).To(BeZero())
+			hfs, err := decodeStaticHeaders(block)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hfs).To(Equal(fields))
+		})
+
+		It("doesn't use the dynamic table if the peer doesn't allow it", func() {
+			Expect(encoder.applySettings(&settingsFrame{})).To(Succeed())
+			block, err := encoder.encode(0, fields)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encoderStream.Len()).To(BeZero())
+			hfs, err := decodeStaticHeaders(block)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hfs).To(Equal(fields))
+		})
+
+		It("uses the dynamic table", func() {
+			Expect(encoder.applySettings(settings(4096, 16))).To(Succeed())
+			block, err := encoder.encode(0, fields)
+			Expect(err).ToNot(HaveOccurred())
+			transfer(encoderStream, decoder.handleEncoderStream)
+			hfs, err := decoder.decode(0, block)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hfs).To(Equal(fields))
+			transfer(decoderStream, encoder.handleDecoderStream)
+			Expect(encoder.knownReceivedCount).To(Equal(uint64(3)))
+			Expect(encoder.sections).To(BeEmpty())
+
+			// the second header block only references entries in the dynamic table
+			block2, err := encoder.encode(4, fields)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encoderStream.Len()).To(BeZero())
+			Expect(len(block2)).To(BeNumerically("<", len(encodeStaticHeaders(fields))))
+			hfs, err = decoder.decode(4, block2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hfs).To(Equal(fields))
+		})
+
+		It("never indexes sensitive header fields", func() {
+			Expect(encoder.applySettings(settings(4096, 16))).To(Succeed())
+			hf := qpack.HeaderField{Name: "authorization", Value: "secret"}
+			block, err := encoder.encode(0, []qpack.HeaderField{hf})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encoder.table.insertCount()).To(BeZero())
+			Expect(block[2] & 0x20).ToNot(BeZero()) // the N bit
+			hfs, err := decoder.decode(0, block)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hfs).To(Equal([]qpack.HeaderField{hf}))
+		})
+
+		It("blocks decoding until the referenced entries are inserted", func() {
+			Expect(encoder.applySettings(settings(4096, 16))).To(Succeed())
+			block, err := encoder.encode(0, fields)
+			Expect(err).ToNot(HaveOccurred())
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				hfs, err := decoder.decode(0, block)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hfs).To(Equal(fields))
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			transfer(encoderStream, decoder.handleEncoderStream)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("fails blocked header blocks when the decoder is closed", func() {
+			Expect(encoder.applySettings(settings(4096, 16))).To(Succeed())
+			block, err := encoder.encode(0, fields)
+			Expect(err).ToNot(HaveOccurred())
+
+			testErr := errors.New("test done")
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				_, err := decoder.decode(0, block)
+				Expect(err).To(MatchError(testErr))
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			decoder.close(testErr)
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("doesn't reference unacknowledged entries if the peer doesn't allow blocked streams", func() {
+			Expect(encoder.applySettings(settings(4096, 0))).To(Succeed())
+			block, err := encoder.encode(0, fields)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encoderStream.Len()).ToNot(BeZero())
+			Expect(block[0]).To(BeZero()) // Required Insert Count
+			hfs, err := decodeStaticHeaders(block)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hfs).To(Equal(fields))
+		})
+
+		It("errors when too many streams are blocked", func() {
+			decoder = newQPACKDecoder(decoderStream, 4096, 0)
+			Expect(encoder.applySettings(settings(4096, 16))).To(Succeed())
+			block, err := encoder.encode(0, fields)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = decoder.decode(0, block)
+			Expect(err).To(MatchError("too many blocked streams (max: 0)"))
+		})
+
+		It("errors when a static decoder receives a header block that references the dynamic table", func() {
+			Expect(encoder.applySettings(settings(4096, 16))).To(Succeed())
+			block, err := encoder.encode(0, fields)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = decodeStaticHeaders(block)
+			Expect(err).To(MatchError("header block references the dynamic table"))
+		})
+
+		It("doesn't evict entries that are referenced by unacknowledged header blocks", func() {
+			Expect(encoder.applySettings(settings(100, 16))).To(Succeed())
+			hf1 := qpack.HeaderField{Name: "x-foo", Value: "foobar"}                 // 43 bytes
+			hf2 := qpack.HeaderField{Name: "x-lorem", Value: "ipsum dolor sit amet"} // 59 bytes
+			_, err := encoder.encode(0, []qpack.HeaderField{hf1})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = encoder.encode(4, []qpack.HeaderField{hf2})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encoder.table.insertCount()).To(Equal(uint64(1)))
+		})
+
+		It("rejects capacities larger than the advertised maximum", func() {
+			encoder = newQPACKEncoder(encoderStream, 8192)
+			Expect(encoder.applySettings(settings(8192, 16))).To(Succeed())
+			Expect(decoder.handleEncoderStream(encoderStream)).To(MatchError("dynamic table capacity 8192 exceeds the maximum 4096"))
+		})
+
+		It("errors on Section Acknowledgments for streams without outstanding header blocks", func() {
+			Expect(encoder.handleDecoderStream(bytes.NewReader([]byte{0x84}))).To(MatchError("Section Acknowledgment for stream 4 without outstanding field sections"))
+		})
+
+		It("errors on invalid Insert Count Increments", func() {
+			Expect(encoder.handleDecoderStream(bytes.NewReader([]byte{0x1}))).To(MatchError("invalid Insert Count Increment 1"))
+		})
+
+		It("forgets header blocks on canceled streams", func() {
+			Expect(encoder.applySettings(settings(4096, 16))).To(Succeed())
+			_, err := encoder.encode(4, fields)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(encoder.sections).To(HaveKey(BeEquivalentTo(4)))
+			Expect(encoder.handleDecoderStream(bytes.NewReader([]byte{0x44}))).To(MatchError(io.EOF))
+			Expect(encoder.sections).To(BeEmpty())
+		})
+
+		It("rejects duplicate streams", func() {
+			Expect(encoder.handleDecoderStream(&bytes.Buffer{})).To(MatchError(io.EOF))
+			Expect(encoder.handleDecoderStream(&bytes.Buffer{})).To(MatchError(errQPACKDuplicateStream))
+			Expect(decoder.handleEncoderStream(&bytes.Buffer{})).To(MatchError(io.EOF))
+			Expect(decoder.handleEncoderStream(&bytes.Buffer{})).To(MatchError(errQPACKDuplicateStream))
+		})
+
+		It("collects statistics", func() {
+			collector := newQPACKStatsCollector()
+			collector.add(encoder)
+			Expect(encoder.applySettings(settings(4096, 16))).To(Succeed())
+			_, err := encoder.encode(0, fields)
+			Expect(err).ToNot(HaveOccurred())
+			stats := collector.get()
+			Expect(stats.HeaderBytes).To(BeEquivalentTo(89))
+			Expect(stats.HeaderBlockBytes).ToNot(BeZero())
+			Expect(stats.EncoderStreamBytes).To(BeEquivalentTo(encoderStream.Len()))
+			Expect(stats.CompressionRatio()).To(BeNumerically(">", 0))
+			collector.remove(encoder)
+			Expect(collector.get()).To(Equal(stats))
+			Expect(QPACKStats{}.CompressionRatio()).To(BeZero())
+		})
+	})
+
+	Context("config", func() {
+		It("uses the default values", func() {
+			Expect(newQPACKConfig(0, 0, nil).settings()).To(Equal(map[uint64]uint64{
+				settingQPACKMaxTableCapacity: defaultQPACKMaxTableCapacity,
+				settingQPACKBlockedStreams:   defaultQPACKBlockedStreams,
+			}))
+		})
+
+		It("disables the dynamic table", func() {
+			Expect(newQPACKConfig(-1, -1, nil).settings()).To(BeEmpty())
+			var conf *qpackConfig
+			Expect(conf.settings()).To(BeEmpty())
+		})
+
+		It("uses the configured values", func() {
+			Expect(newQPACKConfig(1000, 5, nil).settings()).To(Equal(map[uint64]uint64{
+				settingQPACKMaxTableCapacity: 1000,
+				settingQPACKBlockedStreams:   5,
+			}))
+		})
+	})
+})
@@ -3,6 +3,7 @@ package http3
 import (
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -12,10 +13,28 @@ import (
 )
 
 func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
-	var path, authority, method, contentLengthStr string
+	var path, authority, method, protocol, scheme, contentLengthStr string
 	httpHeaders := http.Header{}
 
+	var readRegularHeader bool
+	seenPseudoHeaders := make(map[string]struct{}, 4)
 	for _, h := range headers {
+		if h.IsPseudo() {
+			// All pseudo-header fields must appear in the header block before regular header fields.
+			if readRegularHeader {
+				return nil, fmt.Errorf("pseudo header %s after a regular header field", h.Name)
+			}
+			if _, ok := seenPseudoHeaders[h.Name]; ok {
+				return nil, fmt.Errorf("duplicate pseudo header: %s", h.Name)
+			}
+			seenPseudoHeaders[h.Name] = struct{}{}
+		} else {
+			readRegularHeader = true
+			if strings.ToLower(h.Name) != h.Name {
+				return nil, fmt.Errorf("header field name must be lowercase: %s", h.Name)
+			}
+		}
+
 		switch h.Name {
 		case ":path":
 			path = h.Value
@@ -23,12 +42,20 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 			method = h.Value
 		case ":authority":
 			authority = h.Value
+		case ":scheme":
+			scheme = h.Value
+		case ":protocol":
+			protocol = h.Value
 		case "content-length":
+			if len(contentLengthStr) > 0 && contentLengthStr != h.Value {
+				return nil, errors.New("conflicting content-length headers")
+			}
 			contentLengthStr = h.Value
 		default:
-			if !h.IsPseudo() {
-				httpHeaders.Add(h.Name, h.Value)
+			if h.IsPseudo() {
+				return nil, fmt.Errorf("unknown pseudo header: %s", h.Name)
 			}
+			httpHeaders.Add(h.Name, h.Value)
 		}
 	}
 
@@ -37,13 +64,40 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 		httpHeaders.Set("Cookie", strings.Join(httpHeaders["Cookie"], "; "))
 	}
 
-	if len(path) == 0 || len(authority) == 0 || len(method) == 0 {
-		return nil, errors.New(":path, :authority and :method must not be empty")
+	var u *url.URL
+	var requestURI string
+	var err error
+	switch {
+	case method == http.MethodConnect && len(protocol) == 0:
+		// CONNECT 请求只包含 :method 和 :authority
+		if len(path) > 0 || len(scheme) > 0 {
+			return nil, errors.New(":path and :scheme must be omitted in CONNECT requests")
+		}
+		if len(authority) == 0 {
+			return nil, errors.New(":authority must not be empty")
+		}
+		u = &url.URL{Host: authority}
+		requestURI = authority
+	case len(protocol) > 0 && method != http.MethodConnect:
+		return nil, fmt.Errorf(":protocol in a %s request", method)
+	default:
+		// 扩展 CONNECT 请求 (RFC 9220) 和其他请求一样包含 :scheme 和 :path
+		if len(path) == 0 || len(authority) == 0 || len(method) == 0 {
+			return nil, errors.New(":path, :authority and :method must not be empty")
+		}
+		if len(protocol) > 0 && len(scheme) == 0 {
+			return nil, errors.New(":scheme must not be empty in extended CONNECT requests")
+		}
+		u, err = url.ParseRequestURI(path)
+		if err != nil {
+			return nil, err
+		}
+		requestURI = path
 	}
-
-	u, err := url.ParseRequestURI(path)
-	if err != nil {
-		return nil, err
+	proto := "HTTP/3"
+	if len(protocol) > 0 {
+		// 扩展 CONNECT 请求的 Proto 是 :protocol 的值, 例如 webtransport
+		proto = protocol
 	}
 
 	var contentLength int64
@@ -52,19 +106,26 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 		if err != nil {
 			return nil, err
 		}
+		if contentLength < 0 {
+			return nil, fmt.Errorf("invalid content-length: %d", contentLength)
+		}
 	}
 
+	trailer := declaredTrailer(httpHeaders)
+	httpHeaders.Del("Trailer")
+
 	return &http.Request{
 		Method:        method,
 		URL:           u,
-		Proto:         "HTTP/3",
+		Proto:         proto,
 		ProtoMajor:    3,
 		ProtoMinor:    0,
 		Header:        httpHeaders,
+		Trailer:       trailer,
 		Body:          nil,
 		ContentLength: contentLength,
 		Host:          authority,
-		RequestURI:    path,
+		RequestURI:    requestURI,
 		TLS:           &tls.ConnectionState{},
 	}, nil
 }
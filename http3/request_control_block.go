@@ -1,6 +1,7 @@
 package http3
 
 import (
+	"context"
 	"net/http"
 )
 
@@ -38,11 +39,16 @@ type requestControlBlock struct {
 	subRequestDispatched          bool // 是否已经下发子请求
 
 	url            string                        // 请求的 url，只在子请求是使用
+	header         http.Header                   // 主请求的头部, 只在子请求中使用
 	request        *http.Request                 // 对应的 http 请求
 	requestDone    *chan struct{}                // 调度器完成该 http 请求时向该 chan 发送消息
 	subRequestDone *chan *subRequestControlBlock // 子请求完成时向该 chan 发送消息
 	requestError   *chan struct{}                // 出现任何错误时向该 chan 发送信息
 
+	// 请求的 context, 子请求与主请求共用. 它被取消时, 调度器停止处理该请求及其全部子请求
+	ctx    context.Context
+	cancel context.CancelFunc // 取消 ctx, 在响应体被关闭或者请求出错时调用
+
 	response       *http.Response // 已经处理完成的 response，可以返回上层应用
 	contentLength  int            // 响应体字节数
 	unhandledError error          // 处理 response 过程中发生的错误
@@ -53,6 +59,8 @@ type requestControlBlock struct {
 	bufferBlock       *segmentedBufferControlBlock // 指向属于该子连接的分段请求体的指针
 
 	blockSize int64 // 读取数据时的块大小
+
+	retries int // 该请求因未被服务端处理而被重试的次数
 }
 
 // setBlockSize 设置此请求读取数据时的块大小
@@ -69,3 +77,8 @@ func (block *requestControlBlock) getBlockSize() int64 {
 func (block *requestControlBlock) setContentLength(contentLength int) {
 	block.contentLength = contentLength
 }
+
+// canceled 返回该请求是否已经被取消
+func (block *requestControlBlock) canceled() bool {
+	return block.ctx != nil && block.ctx.Err() != nil
+}
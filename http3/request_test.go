@@ -108,6 +108,140 @@ var _ = Describe("Request", func() {
 		Expect(err).To(MatchError(":path, :authority and :method must not be empty"))
 	})
 
+	It("errors with duplicate pseudo headers", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":path", Value: "/foo"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: "GET"},
+			{Name: ":path", Value: "/bar"},
+		}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError("duplicate pseudo header: :path"))
+	})
+
+	It("errors with unknown pseudo headers", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":path", Value: "/foo"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: "GET"},
+			{Name: ":status", Value: "200"},
+		}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError("unknown pseudo header: :status"))
+	})
+
+	It("errors with pseudo headers after regular headers", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":path", Value: "/foo"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: "cache-control", Value: "max-age=0"},
+			{Name: ":method", Value: "GET"},
+		}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError("pseudo header :method after a regular header field"))
+	})
+
+	It("errors with uppercase header names", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":path", Value: "/foo"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: "GET"},
+			{Name: "Cache-Control", Value: "max-age=0"},
+		}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError("header field name must be lowercase: Cache-Control"))
+	})
+
+	It("errors with invalid content-length headers", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":path", Value: "/foo"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: "GET"},
+			{Name: "content-length", Value: "-1"},
+		}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError("invalid content-length: -1"))
+		headers[3].Value = "foo"
+		_, err = requestFromHeaders(headers)
+		Expect(err).To(HaveOccurred())
+	})
+
+	Context("CONNECT requests", func() {
+		It("parses CONNECT requests", func() {
+			headers := []qpack.HeaderField{
+				{Name: ":authority", Value: "quic.clemente.io:443"},
+				{Name: ":method", Value: "CONNECT"},
+			}
+			req, err := requestFromHeaders(headers)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(req.Method).To(Equal(http.MethodConnect))
+			Expect(req.Proto).To(Equal("HTTP/3"))
+			Expect(req.Host).To(Equal("quic.clemente.io:443"))
+			Expect(req.RequestURI).To(Equal("quic.clemente.io:443"))
+		})
+
+		It("errors with :path in CONNECT requests", func() {
+			headers := []qpack.HeaderField{
+				{Name: ":authority", Value: "quic.clemente.io:443"},
+				{Name: ":method", Value: "CONNECT"},
+				{Name: ":path", Value: "/foo"},
+			}
+			_, err := requestFromHeaders(headers)
+			Expect(err).To(MatchError(":path and :scheme must be omitted in CONNECT requests"))
+		})
+
+		It("parses extended CONNECT requests", func() {
+			headers := []qpack.HeaderField{
+				{Name: ":authority", Value: "quic.clemente.io"},
+				{Name: ":method", Value: "CONNECT"},
+				{Name: ":protocol", Value: "webtransport"},
+				{Name: ":scheme", Value: "https"},
+				{Name: ":path", Value: "/chat"},
+			}
+			req, err := requestFromHeaders(headers)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(req.Method).To(Equal(http.MethodConnect))
+			Expect(req.Proto).To(Equal("webtransport"))
+			Expect(req.URL.Path).To(Equal("/chat"))
+			Expect(req.Host).To(Equal("quic.clemente.io"))
+		})
+
+		It("errors with :protocol in non-CONNECT requests", func() {
+			headers := []qpack.HeaderField{
+				{Name: ":authority", Value: "quic.clemente.io"},
+				{Name: ":method", Value: "GET"},
+				{Name: ":protocol", Value: "webtransport"},
+				{Name: ":scheme", Value: "https"},
+				{Name: ":path", Value: "/chat"},
+			}
+			_, err := requestFromHeaders(headers)
+			Expect(err).To(MatchError(":protocol in a GET request"))
+		})
+
+		It("errors with extended CONNECT requests without :scheme", func() {
+			headers := []qpack.HeaderField{
+				{Name: ":authority", Value: "quic.clemente.io"},
+				{Name: ":method", Value: "CONNECT"},
+				{Name: ":protocol", Value: "webtransport"},
+				{Name: ":path", Value: "/chat"},
+			}
+			_, err := requestFromHeaders(headers)
+			Expect(err).To(MatchError(":scheme must not be empty in extended CONNECT requests"))
+		})
+	})
+
+	It("errors with conflicting content-length headers", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":path", Value: "/foo"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":method", Value: "GET"},
+			{Name: "content-length", Value: "42"},
+			{Name: "content-length", Value: "1337"},
+		}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError("conflicting content-length headers"))
+	})
+
 	Context("extracting the hostname from a request", func() {
 		var url *url.URL
 
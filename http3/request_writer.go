@@ -6,6 +6,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -19,41 +20,56 @@ import (
 )
 
 type requestWriter struct {
-	mutex     sync.Mutex
-	encoder   *qpack.Encoder
-	headerBuf *bytes.Buffer
-
 	logger utils.Logger
 }
 
 func newRequestWriter(logger utils.Logger) *requestWriter {
-	headerBuf := &bytes.Buffer{}
-	encoder := qpack.NewEncoder(headerBuf)
 	return &requestWriter{
-		encoder:   encoder,
-		headerBuf: headerBuf,
-		logger:    logger,
+		logger: logger,
 	}
 }
 
-func (w *requestWriter) WriteRequest(str quic.Stream, req *http.Request, gzip bool) error {
-	headers, err := w.getHeaders(req, gzip)
+// WriteRequest 在 str 上发送请求. 请求头部使用 encoder 编码, encoder 为 nil 时只使用 QPACK 静态表.
+func (w *requestWriter) WriteRequest(str quic.Stream, req *http.Request, gzip bool, encoder *qpackEncoder) error {
+	return w.writeRequest(str, req, gzip, encoder, nil)
+}
+
+// writeRequest 与 WriteRequest 相同. 请求体在后台发送, 发送失败时把错误交给 onBodyError (不为 nil 时).
+// 无论请求是否发送成功, 请求体都会被关闭.
+func (w *requestWriter) writeRequest(str quic.Stream, req *http.Request, gzip bool, encoder *qpackEncoder, onBodyError func(error)) error {
+	headers, err := w.getHeaders(req, gzip, encoder, str.StreamID())
 	if err != nil {
+		closeRequestBody(req)
 		return err
 	}
 	if _, err := str.Write(headers); err != nil {
+		closeRequestBody(req)
 		return err
 	}
-	// TODO: add support for trailers
 	if req.Body == nil {
+		if err := w.writeTrailers(str, req, encoder); err != nil {
+			return err
+		}
 		str.Close()
 		return nil
 	}
 
 	// send the request body asynchronously
 	go func() {
-		if err := w.sendRequestBody(req.Body, str); err != nil {
+		if err := w.sendRequestBody(req.Body, str, actualContentLength(req)); err != nil {
 			w.logger.Errorf("Error writing request: %s", err)
+			if onBodyError != nil {
+				onBodyError(err)
+			}
+			return
+		}
+		// req.Trailer 中的值在读取完请求体之后才确定
+		if err := w.writeTrailers(str, req, encoder); err != nil {
+			w.logger.Errorf("Error writing request trailers: %s", err)
+			str.CancelWrite(quic.ErrorCode(errorInternalError))
+			if onBodyError != nil {
+				onBodyError(err)
+			}
 			return
 		}
 		str.Close()
@@ -62,72 +78,137 @@ func (w *requestWriter) WriteRequest(str quic.Stream, req *http.Request, gzip bo
 	return nil
 }
 
-func (w *requestWriter) getHeaders(req *http.Request, gzip bool) ([]byte, error) {
-	w.mutex.Lock()
-	defer w.mutex.Unlock()
-	defer w.encoder.Close()
-
-	if err := w.encodeHeaders(req, gzip, "", actualContentLength(req)); err != nil {
+func (w *requestWriter) getHeaders(req *http.Request, gzip bool, encoder *qpackEncoder, id quic.StreamID) ([]byte, error) {
+	trailers, err := commaSeparatedTrailers(req)
+	if err != nil {
+		return nil, err
+	}
+	hfs, err := w.encodeHeaders(req, gzip, trailers, actualContentLength(req))
+	if err != nil {
+		return nil, err
+	}
+	headerBlock, err := encoder.encode(id, hfs)
+	if err != nil {
 		return nil, err
 	}
 
 	buf := &bytes.Buffer{}
-	hf := headersFrame{Length: uint64(w.headerBuf.Len())}
+	hf := headersFrame{Length: uint64(len(headerBlock))}
 	hf.Write(buf)
-	if _, err := io.Copy(buf, w.headerBuf); err != nil {
-		return nil, err
-	}
-	w.headerBuf.Reset()
+	buf.Write(headerBlock)
 	return buf.Bytes(), nil
 }
 
-func (w *requestWriter) sendRequestBody(req io.ReadCloser, str quic.Stream) error {
+// writeTrailers 在请求体之后发送 req.Trailer
+func (w *requestWriter) writeTrailers(str quic.Stream, req *http.Request, encoder *qpackEncoder) error {
+	b, err := encodeTrailer(encoder, str.StreamID(), req.Trailer)
+	if err != nil || b == nil {
+		return err
+	}
+	_, err = str.Write(b)
+	return err
+}
+
+// sendRequestBody 以 DATA 帧发送请求体. contentLength 大于 0 时, 请求体的长度必须与之相等.
+// 读取请求体失败时, 以 H3_REQUEST_CANCELLED 重置 stream.
+func (w *requestWriter) sendRequestBody(req io.ReadCloser, str quic.Stream, contentLength int64) error {
+	defer req.Close()
 	b := make([]byte, 8*1024)
+	var sent int64
 	for {
-		n, err := req.Read(b)
-		if err == io.EOF {
+		n, rerr := req.Read(b)
+		if n > 0 {
+			sent += int64(n)
+			if contentLength > 0 && sent > contentLength {
+				str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
+				return fmt.Errorf("http3: ContentLength=%d with Body length of at least %d", contentLength, sent)
+			}
+			buf := &bytes.Buffer{}
+			(&dataFrame{Length: uint64(n)}).Write(buf)
+			if _, err := str.Write(buf.Bytes()); err != nil {
+				return err
+			}
+			if _, err := str.Write(b[:n]); err != nil {
+				return err
+			}
+		}
+		if rerr == io.EOF {
 			break
 		}
-		if err != nil {
+		if rerr != nil {
 			str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
-			return err
-		}
-		buf := &bytes.Buffer{}
-		(&dataFrame{Length: uint64(n)}).Write(buf)
-		if _, err := str.Write(buf.Bytes()); err != nil {
-			return err
-		}
-		if _, err := str.Write(b[:n]); err != nil {
-			return err
+			return rerr
 		}
 	}
-	req.Close()
+	if contentLength > 0 && sent != contentLength {
+		str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
+		return fmt.Errorf("http3: ContentLength=%d with Body length %d", contentLength, sent)
+	}
 	return nil
 }
 
+// requestUpload 记录在后台发送请求体的结果, 使发送失败的原因能够被返回给上层应用
+type requestUpload struct {
+	str quic.Stream
+
+	mutex sync.Mutex
+	err   error
+}
+
+func newRequestUpload(str quic.Stream) *requestUpload {
+	return &requestUpload{str: str}
+}
+
+// fail 在请求体发送失败时被调用. 服务端通过 STOP_SENDING 停止读取请求体之后仍然可能发送响应,
+// 因此不处理这种情况. 其他错误 (读取请求体失败, 连接出错) 会终止读取响应.
+func (u *requestUpload) fail(err error) {
+	if _, ok := err.(quic.StreamError); ok {
+		return
+	}
+	u.mutex.Lock()
+	u.err = err
+	u.mutex.Unlock()
+	u.str.CancelRead(quic.ErrorCode(errorRequestCanceled))
+}
+
+// wrap 在读取响应出错时, 用发送请求体失败的原因代替 rerr
+func (u *requestUpload) wrap(rerr requestError) requestError {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.err == nil {
+		return rerr
+	}
+	return newStreamError(errorRequestCanceled, u.err)
+}
+
 // copied from net/transport.go
 
-func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, trailers string, contentLength int64) error {
+func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, trailers string, contentLength int64) ([]qpack.HeaderField, error) {
 	host := req.Host
 	if host == "" {
 		host = req.URL.Host
 	}
 	host, err := httpguts.PunycodeHostPort(host)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var path string
+	// 扩展 CONNECT 请求 (RFC 9220) 通过 :protocol 伪头部指定协议, 与普通请求一样发送 :path 和 :scheme
+	protocol := req.Header.Get(":protocol")
 	if req.Method != "CONNECT" {
+		protocol = ""
+	}
+	var path string
+	if req.Method != "CONNECT" || protocol != "" {
 		path = req.URL.RequestURI()
 		if !validPseudoPath(path) {
 			orig := path
 			path = strings.TrimPrefix(path, req.URL.Scheme+"://"+host)
 			if !validPseudoPath(path) {
 				if req.URL.Opaque != "" {
-					return fmt.Errorf("invalid request :path %q from URL.Opaque = %q", orig, req.URL.Opaque)
+					return nil, fmt.Errorf("invalid request :path %q from URL.Opaque = %q", orig, req.URL.Opaque)
 				} else {
-					return fmt.Errorf("invalid request :path %q", orig)
+					return nil, fmt.Errorf("invalid request :path %q", orig)
 				}
 			}
 		}
@@ -137,12 +218,15 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 	// potentially pollute our hpack state. (We want to be able to
 	// continue to reuse the hpack encoder for future requests)
 	for k, vv := range req.Header {
+		if k == ":protocol" && protocol != "" {
+			continue
+		}
 		if !httpguts.ValidHeaderFieldName(k) {
-			return fmt.Errorf("invalid HTTP header name %q", k)
+			return nil, fmt.Errorf("invalid HTTP header name %q", k)
 		}
 		for _, v := range vv {
 			if !httpguts.ValidHeaderFieldValue(v) {
-				return fmt.Errorf("invalid HTTP header value %q for header %q", v, k)
+				return nil, fmt.Errorf("invalid HTTP header value %q for header %q", v, k)
 			}
 		}
 	}
@@ -155,17 +239,20 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 		// [RFC3986]).
 		f(":authority", host)
 		f(":method", req.Method)
-		if req.Method != "CONNECT" {
+		if req.Method != "CONNECT" || protocol != "" {
 			f(":path", path)
 			f(":scheme", req.URL.Scheme)
 		}
+		if protocol != "" {
+			f(":protocol", protocol)
+		}
 		if trailers != "" {
 			f("trailer", trailers)
 		}
 
 		var didUA bool
 		for k, vv := range req.Header {
-			if strings.EqualFold(k, "host") || strings.EqualFold(k, "content-length") {
+			if k == ":protocol" || strings.EqualFold(k, "host") || strings.EqualFold(k, "content-length") {
 				// Host is :authority, already sent.
 				// Content-Length is automatic, set below.
 				continue
@@ -227,15 +314,35 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 	// traceHeaders := traceHasWroteHeaderField(trace)
 
 	// Header list size is ok. Write the headers.
+	var hfs []qpack.HeaderField
 	enumerateHeaders(func(name, value string) {
 		name = strings.ToLower(name)
-		w.encoder.WriteField(qpack.HeaderField{Name: name, Value: value})
+		hfs = append(hfs, qpack.HeaderField{Name: name, Value: value})
 		// if traceHeaders {
 		// 	traceWroteHeaderField(trace, name, value)
 		// }
 	})
 
-	return nil
+	return hfs, nil
+}
+
+// copied from net/http2/transport.go
+
+func commaSeparatedTrailers(req *http.Request) (string, error) {
+	keys := make([]string, 0, len(req.Trailer))
+	for k := range req.Trailer {
+		k = http.CanonicalHeaderKey(k)
+		switch k {
+		case "Transfer-Encoding", "Trailer", "Content-Length":
+			return "", fmt.Errorf("invalid Trailer key %q", k)
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		return strings.Join(keys, ","), nil
+	}
+	return "", nil
 }
 
 // authorityAddr returns a given authority (a host/IP, or host:port / ip:port)
@@ -2,13 +2,16 @@ package http3
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/marten-seemann/qpack"
 
 	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go"
 	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 
@@ -16,6 +19,25 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+type errorReader struct{ err error }
+
+func (r *errorReader) Read([]byte) (int, error) { return 0, r.err }
+
+// testRequestBody is a request body that records when it is closed
+type testRequestBody struct {
+	io.Reader
+	closed chan struct{}
+}
+
+func newTestRequestBody(r io.Reader) *testRequestBody {
+	return &testRequestBody{Reader: r, closed: make(chan struct{})}
+}
+
+func (b *testRequestBody) Close() error {
+	close(b.closed)
+	return nil
+}
+
 var _ = Describe("Request Writer", func() {
 	var (
 		rw     *requestWriter
@@ -45,6 +67,7 @@ var _ = Describe("Request Writer", func() {
 		rw = newRequestWriter(utils.DefaultLogger)
 		strBuf = &bytes.Buffer{}
 		str = mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().StreamID().AnyTimes()
 		str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
 			return strBuf.Write(p)
 		}).AnyTimes()
@@ -54,7 +77,7 @@ var _ = Describe("Request Writer", func() {
 		str.EXPECT().Close()
 		req, err := http.NewRequest("GET", "https://quic.clemente.io/index.html?foo=bar", nil)
 		Expect(err).ToNot(HaveOccurred())
-		Expect(rw.WriteRequest(str, req, false)).To(Succeed())
+		Expect(rw.WriteRequest(str, req, false, nil)).To(Succeed())
 		headerFields := decode(strBuf)
 		Expect(headerFields).To(HaveKeyWithValue(":authority", "quic.clemente.io"))
 		Expect(headerFields).To(HaveKeyWithValue(":method", "GET"))
@@ -69,7 +92,9 @@ var _ = Describe("Request Writer", func() {
 		postData := bytes.NewReader([]byte("foobar"))
 		req, err := http.NewRequest("POST", "https://quic.clemente.io/upload.html", postData)
 		Expect(err).ToNot(HaveOccurred())
-		Expect(rw.WriteRequest(str, req, false)).To(Succeed())
+		Expect(rw.WriteRequest(str, req, false, nil)).To(Succeed())
+		// the body is sent in a separate go routine
+		Eventually(closed).Should(BeClosed())
 		headerFields := decode(strBuf)
 		Expect(headerFields).To(HaveKeyWithValue(":method", "POST"))
 		Expect(headerFields).To(HaveKey("content-length"))
@@ -77,7 +102,6 @@ var _ = Describe("Request Writer", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(contentLength).To(BeNumerically(">", 0))
 
-		Eventually(closed).Should(BeClosed())
 		frame, err := parseNextFrame(strBuf)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(frame).To(BeAssignableToTypeOf(&dataFrame{}))
@@ -98,7 +122,7 @@ var _ = Describe("Request Writer", func() {
 		}
 		req.AddCookie(cookie1)
 		req.AddCookie(cookie2)
-		Expect(rw.WriteRequest(str, req, false)).To(Succeed())
+		Expect(rw.WriteRequest(str, req, false, nil)).To(Succeed())
 		headerFields := decode(strBuf)
 		Expect(headerFields).To(HaveKeyWithValue("cookie", `Cookie #1="Value #1"; Cookie #2="Value #2"`))
 	})
@@ -107,8 +131,126 @@ var _ = Describe("Request Writer", func() {
 		str.EXPECT().Close()
 		req, err := http.NewRequest("GET", "https://quic.clemente.io/", nil)
 		Expect(err).ToNot(HaveOccurred())
-		Expect(rw.WriteRequest(str, req, true)).To(Succeed())
+		Expect(rw.WriteRequest(str, req, true, nil)).To(Succeed())
 		headerFields := decode(strBuf)
 		Expect(headerFields).To(HaveKeyWithValue("accept-encoding", "gzip"))
 	})
+
+	It("writes an extended CONNECT request", func() {
+		str.EXPECT().Close()
+		req, err := http.NewRequest("CONNECT", "https://quic.clemente.io/chat", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set(":protocol", "webtransport")
+		Expect(rw.WriteRequest(str, req, false, nil)).To(Succeed())
+		headerFields := decode(strBuf)
+		Expect(headerFields).To(HaveKeyWithValue(":method", "CONNECT"))
+		Expect(headerFields).To(HaveKeyWithValue(":protocol", "webtransport"))
+		Expect(headerFields).To(HaveKeyWithValue(":path", "/chat"))
+		Expect(headerFields).To(HaveKeyWithValue(":scheme", "https"))
+	})
+
+	It("sends trailers after the request body", func() {
+		closed := make(chan struct{})
+		str.EXPECT().Close().Do(func() { close(closed) })
+		pr, pw := io.Pipe()
+		req, err := http.NewRequest("POST", "https://quic.clemente.io/upload.html", pr)
+		Expect(err).ToNot(HaveOccurred())
+		req.Trailer = http.Header{"Checksum": nil}
+		Expect(rw.WriteRequest(str, req, false, nil)).To(Succeed())
+		_, err = pw.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		// the value of the trailer is only known after the body has been sent
+		req.Trailer.Set("Checksum", "deadbeef")
+		Expect(pw.Close()).To(Succeed())
+		Eventually(closed).Should(BeClosed())
+		Expect(decode(strBuf)).To(HaveKeyWithValue("trailer", "Checksum"))
+		frame, err := parseNextFrame(strBuf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(BeAssignableToTypeOf(&dataFrame{}))
+		strBuf.Next(int(frame.(*dataFrame).Length))
+		Expect(decode(strBuf)).To(Equal(map[string]string{"checksum": "deadbeef"}))
+	})
+
+	It("sends trailers for requests without a body", func() {
+		str.EXPECT().Close()
+		req, err := http.NewRequest("GET", "https://quic.clemente.io/", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Trailer = http.Header{"Checksum": []string{"deadbeef"}}
+		Expect(rw.WriteRequest(str, req, false, nil)).To(Succeed())
+		Expect(decode(strBuf)).To(HaveKeyWithValue("trailer", "Checksum"))
+		Expect(decode(strBuf)).To(Equal(map[string]string{"checksum": "deadbeef"}))
+	})
+
+	It("rejects invalid trailer keys", func() {
+		req, err := http.NewRequest("GET", "https://quic.clemente.io/", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Trailer = http.Header{"Content-Length": nil}
+		Expect(rw.WriteRequest(str, req, false, nil)).To(MatchError(`invalid Trailer key "Content-Length"`))
+	})
+
+	Context("request bodies", func() {
+		newRequest := func(body io.ReadCloser, contentLength int64) *http.Request {
+			req, err := http.NewRequest("PUT", "https://quic.clemente.io/upload", body)
+			Expect(err).ToNot(HaveOccurred())
+			req.ContentLength = contentLength
+			return req
+		}
+
+		writeRequest := func(req *http.Request) <-chan error {
+			errChan := make(chan error, 1)
+			Expect(rw.writeRequest(str, req, false, nil, func(err error) { errChan <- err })).To(Succeed())
+			return errChan
+		}
+
+		It("closes the request body", func() {
+			closed := make(chan struct{})
+			str.EXPECT().Close().Do(func() { close(closed) })
+			body := newTestRequestBody(strings.NewReader("foobar"))
+			Expect(rw.WriteRequest(str, newRequest(body, 6), false, nil)).To(Succeed())
+			Eventually(closed).Should(BeClosed())
+			Expect(body.closed).To(BeClosed())
+		})
+
+		It("reports errors reading the request body", func() {
+			testErr := errors.New("read error")
+			str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestCanceled))
+			body := newTestRequestBody(io.MultiReader(strings.NewReader("foo"), &errorReader{err: testErr}))
+			errChan := writeRequest(newRequest(body, 0))
+			Eventually(errChan).Should(Receive(Equal(testErr)))
+			Expect(body.closed).To(BeClosed())
+		})
+
+		It("rejects request bodies shorter than the Content-Length", func() {
+			str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestCanceled))
+			errChan := writeRequest(newRequest(newTestRequestBody(strings.NewReader("foobar")), 10))
+			Eventually(errChan).Should(Receive(MatchError("http3: ContentLength=10 with Body length 6")))
+		})
+
+		It("rejects request bodies longer than the Content-Length", func() {
+			str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestCanceled))
+			errChan := writeRequest(newRequest(newTestRequestBody(strings.NewReader("foobar")), 3))
+			Eventually(errChan).Should(Receive(MatchError("http3: ContentLength=3 with Body length of at least 6")))
+			decode(strBuf)
+			Expect(strBuf.Len()).To(BeZero())
+		})
+	})
+
+	Context("uploads", func() {
+		It("aborts reading the response if the upload fails", func() {
+			upload := newRequestUpload(str)
+			testErr := errors.New("read error")
+			str.EXPECT().CancelRead(quic.ErrorCode(errorRequestCanceled))
+			upload.fail(testErr)
+			rerr := upload.wrap(newStreamError(errorFrameError, errors.New("stream canceled")))
+			Expect(rerr.err).To(Equal(testErr))
+			Expect(rerr.streamErr).To(Equal(errorRequestCanceled))
+		})
+
+		It("keeps reading the response if the server stops reading the request body", func() {
+			upload := newRequestUpload(str)
+			upload.fail(&streamResetError{code: errorNoError})
+			rerr := newStreamError(errorFrameError, errors.New("stream canceled"))
+			Expect(upload.wrap(rerr)).To(Equal(rerr))
+		})
+	})
 })
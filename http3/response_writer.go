@@ -1,22 +1,40 @@
 package http3
 
 import (
+	"bufio"
 	"bytes"
+	"fmt"
 	"io"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/lucas-clemente/quic-go"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/marten-seemann/qpack"
 )
 
 type responseWriter struct {
-	stream io.Writer
+	stream         io.Writer
+	bufferedStream *bufio.Writer // 响应在调用 Flush 或 handler 返回之后才被写入 stream
 
 	header        http.Header
 	status        int // status code passed to WriteHeader
 	headerWritten bool
+	trailers      http.Header // 在 Trailer 头部字段中声明的 trailer, 值为空
+
+	// pusher sends a PUSH_PROMISE for the target and pushes the response.
+	// It is nil if pushing is not possible, e.g. for pushed responses.
+	pusher func(target string, opts *http.PushOptions) error
+
+	// encoder 编码响应头部, streamID 是发送响应的 stream. encoder 为 nil 时只使用 QPACK 静态表.
+	encoder  *qpackEncoder
+	streamID quic.StreamID
+
+	// webTransport 是扩展 CONNECT 请求对应的 WebTransport session, 为 nil 时不能调用 UpgradeWebTransport.
+	// hijacked 表示 session 已经被接受, stream 不再属于这个 responseWriter.
+	webTransport *WebTransportSession
+	hijacked     bool
 
 	logger utils.Logger
 }
@@ -25,9 +43,10 @@ var _ http.ResponseWriter = &responseWriter{}
 
 func newResponseWriter(stream io.Writer, logger utils.Logger) *responseWriter {
 	return &responseWriter{
-		header: http.Header{},
-		stream: stream,
-		logger: logger,
+		header:         http.Header{},
+		stream:         stream,
+		bufferedStream: bufio.NewWriter(stream),
+		logger:         logger,
 	}
 }
 
@@ -39,26 +58,33 @@ func (w *responseWriter) WriteHeader(status int) {
 	if w.headerWritten {
 		return
 	}
+	checkWriteHeaderCode(status)
 	w.headerWritten = true
 	w.status = status
 
-	var headers bytes.Buffer
-	enc := qpack.NewEncoder(&headers)
-	enc.WriteField(qpack.HeaderField{Name: ":status", Value: strconv.Itoa(status)})
-
+	w.trailers = declaredTrailer(w.header)
+	hfs := []qpack.HeaderField{{Name: ":status", Value: strconv.Itoa(status)}}
 	for k, v := range w.header {
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			continue
+		}
 		for index := range v {
-			enc.WriteField(qpack.HeaderField{Name: strings.ToLower(k), Value: v[index]})
+			hfs = append(hfs, qpack.HeaderField{Name: strings.ToLower(k), Value: v[index]})
 		}
 	}
+	headers, err := w.encoder.encode(w.streamID, hfs)
+	if err != nil {
+		w.logger.Errorf("could not encode headers: %s", err.Error())
+		return
+	}
 
 	buf := &bytes.Buffer{}
-	(&headersFrame{Length: uint64(headers.Len())}).Write(buf)
+	(&headersFrame{Length: uint64(len(headers))}).Write(buf)
 	w.logger.Infof("Responding with %d", status)
-	if _, err := w.stream.Write(buf.Bytes()); err != nil {
+	if _, err := w.bufferedStream.Write(buf.Bytes()); err != nil {
 		w.logger.Errorf("could not write headers frame: %s", err.Error())
 	}
-	if _, err := w.stream.Write(headers.Bytes()); err != nil {
+	if _, err := w.bufferedStream.Write(headers); err != nil {
 		w.logger.Errorf("could not write header frame payload: %s", err.Error())
 	}
 }
@@ -73,17 +99,75 @@ func (w *responseWriter) Write(p []byte) (int, error) {
 	df := &dataFrame{Length: uint64(len(p))}
 	buf := &bytes.Buffer{}
 	df.Write(buf)
-	if _, err := w.stream.Write(buf.Bytes()); err != nil {
+	if _, err := w.bufferedStream.Write(buf.Bytes()); err != nil {
 		return 0, err
 	}
-	return w.stream.Write(p)
+	return w.bufferedStream.Write(p)
 }
 
-func (w *responseWriter) Flush() {}
+// Flush writes the buffered response to the stream.
+// If the header hasn't been written yet, it writes the header with status 200 first.
+func (w *responseWriter) Flush() {
+	if !w.headerWritten {
+		w.WriteHeader(200)
+	}
+	if err := w.bufferedStream.Flush(); err != nil {
+		w.logger.Errorf("could not flush to stream: %s", err.Error())
+	}
+}
 
 // test that we implement http.Flusher
 var _ http.Flusher = &responseWriter{}
 
+// writeTrailers 在 handler 返回之后发送 trailer.
+// trailer 包括在 Trailer 头部字段中声明的字段, 以及以 http.TrailerPrefix 开头的字段.
+func (w *responseWriter) writeTrailers() {
+	trailer := make(http.Header)
+	for k := range w.trailers {
+		if vv, ok := w.header[k]; ok {
+			trailer[k] = vv
+		}
+	}
+	for k, vv := range w.header {
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			trailer[http.CanonicalHeaderKey(strings.TrimPrefix(k, http.TrailerPrefix))] = vv
+		}
+	}
+	b, err := encodeTrailer(w.encoder, w.streamID, trailer)
+	if err != nil {
+		w.logger.Errorf("could not encode trailers: %s", err.Error())
+		return
+	}
+	if _, err := w.bufferedStream.Write(b); err != nil {
+		w.logger.Errorf("could not write trailers: %s", err.Error())
+	}
+}
+
+// Push initiates a server push of the target.
+// It returns http.ErrNotSupported if the response itself is being pushed.
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if w.pusher == nil {
+		return http.ErrNotSupported
+	}
+	return w.pusher(target, opts)
+}
+
+// test that we implement http.Pusher
+var _ http.Pusher = &responseWriter{}
+
+// copied from net/http/server.go
+func checkWriteHeaderCode(code int) {
+	// Issue 22880: require valid WriteHeader status codes.
+	// For now we only enforce that it's three digits.
+	// In the future we might block things over 599 (600 and above aren't defined
+	// at https://httpwg.org/specs/rfc7231.html#status.codes)
+	// and we might block under 200 (once we have more mature 1xx support).
+	// But for now any three digits.
+	if code < 100 || code > 999 {
+		panic(fmt.Sprintf("invalid WriteHeader code %v", code))
+	}
+}
+
 // copied from http2/http2.go
 // bodyAllowedForStatus reports whether a given response status code
 // permits a body. See RFC 2616, section 4.4.
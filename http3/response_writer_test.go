@@ -55,6 +55,7 @@ var _ = Describe("Response Writer", func() {
 
 	It("writes status", func() {
 		rw.WriteHeader(http.StatusTeapot)
+		rw.Flush()
 		fields := decodeHeader(strBuf)
 		Expect(fields).To(HaveLen(1))
 		Expect(fields).To(HaveKeyWithValue(":status", []string{"418"}))
@@ -63,6 +64,7 @@ var _ = Describe("Response Writer", func() {
 	It("writes headers", func() {
 		rw.Header().Add("content-length", "42")
 		rw.WriteHeader(http.StatusTeapot)
+		rw.Flush()
 		fields := decodeHeader(strBuf)
 		Expect(fields).To(HaveKeyWithValue("content-length", []string{"42"}))
 	})
@@ -73,6 +75,7 @@ var _ = Describe("Response Writer", func() {
 		rw.Header().Add("set-cookie", cookie1)
 		rw.Header().Add("set-cookie", cookie2)
 		rw.WriteHeader(http.StatusTeapot)
+		rw.Flush()
 		fields := decodeHeader(strBuf)
 		Expect(fields).To(HaveKey("set-cookie"))
 		cookies := fields["set-cookie"]
@@ -85,6 +88,7 @@ var _ = Describe("Response Writer", func() {
 		Expect(n).To(Equal(6))
 		Expect(err).ToNot(HaveOccurred())
 		// Should have written 200 on the header stream
+		rw.Flush()
 		fields := decodeHeader(strBuf)
 		Expect(fields).To(HaveKeyWithValue(":status", []string{"200"}))
 		// And foobar on the data stream
@@ -97,6 +101,7 @@ var _ = Describe("Response Writer", func() {
 		Expect(n).To(Equal(6))
 		Expect(err).ToNot(HaveOccurred())
 		// Should have written 418 on the header stream
+		rw.Flush()
 		fields := decodeHeader(strBuf)
 		Expect(fields).To(HaveKeyWithValue(":status", []string{"418"}))
 		// And foobar on the data stream
@@ -106,6 +111,7 @@ var _ = Describe("Response Writer", func() {
 	It("does not WriteHeader() twice", func() {
 		rw.WriteHeader(200)
 		rw.WriteHeader(500)
+		rw.Flush()
 		fields := decodeHeader(strBuf)
 		Expect(fields).To(HaveLen(1))
 		Expect(fields).To(HaveKeyWithValue(":status", []string{"200"}))
@@ -117,4 +123,60 @@ var _ = Describe("Response Writer", func() {
 		Expect(n).To(BeZero())
 		Expect(err).To(MatchError(http.ErrBodyNotAllowed))
 	})
+
+	It("buffers the response until Flush is called", func() {
+		rw.WriteHeader(http.StatusTeapot)
+		_, err := rw.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strBuf.Len()).To(BeZero())
+		rw.Flush()
+		fields := decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue(":status", []string{"418"}))
+		Expect(getData(strBuf)).To(Equal([]byte("foobar")))
+	})
+
+	It("writes the header when flushing", func() {
+		rw.Header().Set("Content-Type", "text/event-stream")
+		rw.Flush()
+		fields := decodeHeader(strBuf)
+		Expect(fields).To(HaveKeyWithValue(":status", []string{"200"}))
+		Expect(fields).To(HaveKeyWithValue("content-type", []string{"text/event-stream"}))
+		_, err := rw.Write([]byte("data: foo\n\n"))
+		Expect(err).ToNot(HaveOccurred())
+		rw.Flush()
+		Expect(getData(strBuf)).To(Equal([]byte("data: foo\n\n")))
+	})
+
+	It("writes declared trailers", func() {
+		rw.Header().Set("Trailer", "Checksum, Server-Timing")
+		rw.WriteHeader(http.StatusOK)
+		_, err := rw.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		rw.Header().Set("Checksum", "deadbeef")
+		rw.writeTrailers()
+		rw.Flush()
+		Expect(decodeHeader(strBuf)).To(HaveKeyWithValue("trailer", []string{"Checksum, Server-Timing"}))
+		Expect(getData(strBuf)).To(Equal([]byte("foobar")))
+		trailers := decodeHeader(strBuf)
+		Expect(trailers).To(HaveLen(1))
+		Expect(trailers).To(HaveKeyWithValue("checksum", []string{"deadbeef"}))
+	})
+
+	It("writes trailers using the http.TrailerPrefix", func() {
+		rw.Header().Set(http.TrailerPrefix+"Checksum", "early")
+		rw.WriteHeader(http.StatusOK)
+		rw.Header().Set(http.TrailerPrefix+"Checksum", "deadbeef")
+		rw.writeTrailers()
+		rw.Flush()
+		Expect(decodeHeader(strBuf)).ToNot(HaveKey("trailer:checksum"))
+		Expect(decodeHeader(strBuf)).To(Equal(map[string][]string{"checksum": {"deadbeef"}}))
+	})
+
+	It("doesn't write trailers if there are none", func() {
+		rw.WriteHeader(http.StatusOK)
+		rw.writeTrailers()
+		rw.Flush()
+		decodeHeader(strBuf)
+		Expect(strBuf.Len()).To(BeZero())
+	})
 })
@@ -1,14 +1,12 @@
 package http3
 
 import (
-	"context"
 	"crypto/tls"
-	"log"
 	"net/http"
 	"sync"
 
 	"github.com/lucas-clemente/quic-go"
-	"github.com/marten-seemann/qpack"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 const maxParallelStreams = 4
@@ -21,8 +19,11 @@ type roundRobinRequestScheduler struct {
 	tlsConfig        *tls.Config
 	quicConfig       *quic.Config
 	requestWriter    *requestWriter
-	decoder          *qpack.Decoder
+	qpackConf        *qpackConfig
 	roundTripperOpts *roundTripperOpts
+	pushes           *pushCache // 服务端推送的响应, 未启用推送时为 nil
+	dialer           func(network, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.Session, error)
+	logger           utils.Logger
 
 	openedSession    []*sessionControlblock // 保存所有打开的 quicSession
 	nextSessionIndex int                    // 当前使用的 quicSession 下标
@@ -43,8 +44,11 @@ func newRoundRobinRequestScheduler(info *clientInfo) *roundRobinRequestScheduler
 		tlsConfig:        info.tlsConfig,
 		quicConfig:       info.quicConfig,
 		requestWriter:    info.requestWriter,
-		decoder:          info.decoder,
+		qpackConf:        info.qpackConf,
 		roundTripperOpts: info.roundTripperOpts,
+		pushes:           info.pushes,
+		dialer:           info.dialer,
+		logger:           info.logger.WithPrefix("round robin scheduler"),
 
 		openedSession:         make([]*sessionControlblock, 0),
 		mayExecuteNextRequest: &mayExecuteNextRequestChan,
@@ -57,7 +61,7 @@ func newRoundRobinRequestScheduler(info *clientInfo) *roundRobinRequestScheduler
 // run 运行调度器主线程
 func (scheduler *roundRobinRequestScheduler) run() {
 	go func() {
-		log.Printf("start adding new quic sessions")
+		scheduler.logger.Debugf("start adding new quic sessions")
 		for i := 0; i < maxConcurrentSessions; i++ {
 			go scheduler.addNewSession()
 		}
@@ -78,7 +82,7 @@ func (scheduler *roundRobinRequestScheduler) close() error {
 	defer scheduler.Unlock()
 	for _, sessionBlock := range scheduler.openedSession {
 		if err := (*sessionBlock.session).Close(); err != nil {
-			log.Printf("error in closing session: id = <%v>, err = <%v>",
+			scheduler.logger.Errorf("error in closing session: id = <%v>, err = <%v>",
 				sessionBlock.id, err.Error())
 			return err
 		}
@@ -88,10 +92,25 @@ func (scheduler *roundRobinRequestScheduler) close() error {
 
 // addNewRequest 向调度器实例中添加请求控制块
 func (scheduler *roundRobinRequestScheduler) addNewRequest(reqBlock *requestControlBlock) {
+	scheduler.Lock()
 	scheduler.requestQueue = append(scheduler.requestQueue, reqBlock)
+	scheduler.Unlock()
 	*scheduler.mayExecuteNextRequest <- struct{}{}
 }
 
+// removeRequest 把尚未执行的请求从队列中移除, 返回该请求是否仍在队列中
+func (scheduler *roundRobinRequestScheduler) removeRequest(reqBlock *requestControlBlock) bool {
+	scheduler.Lock()
+	defer scheduler.Unlock()
+	for i, b := range scheduler.requestQueue {
+		if b == reqBlock {
+			scheduler.requestQueue = append(scheduler.requestQueue[:i], scheduler.requestQueue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 // addAndWait 把请求添加到调度器内部队列中，由调度器在适当时候执行
 func (scheduler *roundRobinRequestScheduler) addAndWait(req *http.Request) (*http.Response, error) {
 	var requestDone = make(chan struct{}, 10)
@@ -100,6 +119,7 @@ func (scheduler *roundRobinRequestScheduler) addAndWait(req *http.Request) (*htt
 		request:      req,
 		requestDone:  &requestDone,
 		requestError: &requestError,
+		ctx:          req.Context(),
 	}
 	scheduler.addNewRequest(&reqBlock)
 	// log.Printf("new request added, url = <%v>", req.URL.RequestURI())
@@ -110,15 +130,19 @@ func (scheduler *roundRobinRequestScheduler) addAndWait(req *http.Request) (*htt
 			return reqBlock.response, reqBlock.unhandledError
 		case <-*reqBlock.requestError:
 			return getErrorResponse(req), nil
+		case <-req.Context().Done():
+			// 还在队列中的请求直接被移除. 已经开始执行的请求由执行它的 go 程重置 stream.
+			scheduler.removeRequest(&reqBlock)
+			return nil, req.Context().Err()
 		}
 	}
 }
 
 // addNewSession 向调度器中添加新的 quic session
 func (scheduler *roundRobinRequestScheduler) addNewSession() {
-	newSession, err := dial(scheduler.hostname, scheduler.tlsConfig, scheduler.quicConfig)
+	newSession, err := dial(scheduler.hostname, scheduler.tlsConfig, scheduler.quicConfig, scheduler.pushes, scheduler.qpackConf, scheduler.dialer, scheduler.logger)
 	if err != nil {
-		log.Printf("error in creating new quic session: %v", err.Error())
+		scheduler.logger.Errorf("error in creating new quic session: %v", err.Error())
 		return
 	}
 
@@ -128,26 +152,52 @@ func (scheduler *roundRobinRequestScheduler) addNewSession() {
 	scheduler.maxSessionID++
 	scheduler.openedSession = append(scheduler.openedSession, newSessionBlock)
 	scheduler.Unlock()
-	*scheduler.newSessionAdded <- struct{}{}
+	select {
+	case *scheduler.newSessionAdded <- struct{}{}:
+	default:
+	}
+	go scheduler.replaceOnGoAway(newSessionBlock)
 	// log.Printf("new session added: id = <%v>", newSessionBlock.id)
 }
 
+// replaceOnGoAway 在服务端对 block 发送 GOAWAY 帧之后, 把它移出轮询队列, 并建立一条新的 quicSession 代替它
+func (scheduler *roundRobinRequestScheduler) replaceOnGoAway(block *sessionControlblock) {
+	select {
+	case <-block.h3.goAway:
+	case <-(*block.session).Context().Done():
+		return
+	}
+	scheduler.Lock()
+	for i, b := range scheduler.openedSession {
+		if b == block {
+			scheduler.openedSession = append(scheduler.openedSession[:i], scheduler.openedSession[i+1:]...)
+			break
+		}
+	}
+	scheduler.Unlock()
+	scheduler.logger.Debugf("session = <%v> is going away, adding a new session", block.id)
+	scheduler.addNewSession()
+}
+
 // getSession 方法返回当前可用的 quicSession
 func (scheduler *roundRobinRequestScheduler) getSession() (*sessionControlblock, error) {
-	if len(scheduler.openedSession) < 1 {
-		// 在队列中没有新的 session 加入时，等待新的 session 就绪
-		select {
-		case <-*scheduler.newSessionAdded:
+	for {
+		scheduler.Lock()
+		// 在已有的 quic session 中轮询, 跳过服务端已经发送了 GOAWAY 帧的 session
+		n := len(scheduler.openedSession)
+		for i := 0; i < n; i++ {
+			nextSessionBlock := scheduler.openedSession[scheduler.nextSessionIndex%n]
+			// 轮转到下一 quic session
+			scheduler.nextSessionIndex = (scheduler.nextSessionIndex + 1) % n
+			if !nextSessionBlock.goingAway() {
+				scheduler.Unlock()
+				return nextSessionBlock, nil
+			}
 		}
+		scheduler.Unlock()
+		// 在队列中没有可用的 session 时，等待新的 session 就绪
+		<-*scheduler.newSessionAdded
 	}
-	// 已经创建了最大数量的 quic session，需要在已有的 quic session 中轮询
-	nextSessionBlock := scheduler.openedSession[scheduler.nextSessionIndex]
-	// 轮转到下一 quic session
-	scheduler.nextSessionIndex = (scheduler.nextSessionIndex + 1) % maxConcurrentSessions
-	if scheduler.nextSessionIndex > len(scheduler.openedSession) {
-		scheduler.nextSessionIndex = 0
-	}
-	return nextSessionBlock, nil
 }
 
 // removeFirst 移除调度器队列中的第一个请求
@@ -157,20 +207,28 @@ func (scheduler *roundRobinRequestScheduler) removeFirst() {
 
 // mayExecute 视情况决定是否执行下一请求
 func (scheduler *roundRobinRequestScheduler) mayExecute() {
+	scheduler.Lock()
+	// 丢弃已经被取消的请求
+	for len(scheduler.requestQueue) > 0 && scheduler.requestQueue[0].canceled() {
+		scheduler.removeFirst()
+	}
 	if scheduler.pendingRequests >= maxParallelStreams || len(scheduler.requestQueue) < 1 {
 		// 当前正在执行的请求数超过限制，或者队列中没有可供执行的请求
+		scheduler.Unlock()
 		return
 	}
-
-	// 获取下一需要执行的请求以及执行该请求的 quic session
+	// 取出下一需要执行的请求. 此后该请求被取消时, 由 execute 负责结束它
 	nextRequest := scheduler.requestQueue[0]
+	scheduler.removeFirst()
+	scheduler.Unlock()
+
+	// 获取执行该请求的 quic session
 	nextSession, err := scheduler.getSession()
 	if err != nil {
-		log.Printf("error in getting next session: %v", err.Error())
+		scheduler.logger.Errorf("error in getting next session: %v", err.Error())
 		return
 	}
 	nextRequest.designatedSession = nextSession
-	scheduler.removeFirst()
 
 	// 在新的 go 程中执行该请求
 	go scheduler.execute(nextRequest)
@@ -180,15 +238,19 @@ func (scheduler *roundRobinRequestScheduler) mayExecute() {
 func (scheduler *roundRobinRequestScheduler) execute(reqBlock *requestControlBlock) {
 	reqBlock.designatedSession.addNewRequest()
 
-	log.Printf("session = <%v>, executing request = <%v>, sessionPendingRequest = <%v>",
+	scheduler.logger.Debugf("session = <%v>, executing request = <%v>, sessionPendingRequest = <%v>",
 		reqBlock.designatedSession.id, reqBlock.request.URL.RequestURI(), reqBlock.designatedSession.getPendingRequest())
 
 	req := reqBlock.request
 	quicSession := *reqBlock.designatedSession.session
 
-	str, err := quicSession.OpenStreamSync(context.Background())
+	str, err := reqBlock.designatedSession.h3.openRequestStream(req.Context(), req.Method)
 	if err != nil {
-		log.Printf(err.Error())
+		scheduler.logger.Errorf("%s", err)
+		if reqBlock.designatedSession.h3.isUnprocessed(nil, err) && scheduler.retry(reqBlock) {
+			return
+		}
+		reqBlock.designatedSession.removeFinishedRequest()
 		*reqBlock.requestError <- struct{}{}
 		return
 	}
@@ -205,11 +267,16 @@ func (scheduler *roundRobinRequestScheduler) execute(reqBlock *requestControlBlo
 
 	usingGzip := isUsingGzip(scheduler.roundTripperOpts.DisableCompression,
 		req.Method, req.Header.Get("accept-encoding"), req.Header.Get("range"))
-	resp, reqErr := getResponse(req, usingGzip, &str, &quicSession,
+	resp, reqErr := getResponse(req, usingGzip, &str, reqBlock.designatedSession.h3,
 		scheduler.requestWriter, maxHeaderBytes(scheduler.roundTripperOpts.MaxHeaderBytes),
-		scheduler.decoder, reqDone)
+		scheduler.pushes, reqDone)
 	if reqErr.err != nil {
 		close(reqDone)
+		if reqBlock.designatedSession.h3.isUnprocessed(str, reqErr.err) && scheduler.retry(reqBlock) {
+			str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
+			str.CancelRead(quic.ErrorCode(errorRequestCanceled))
+			return
+		}
 		if reqErr.streamErr != 0 {
 			str.CancelWrite(quic.ErrorCode(reqErr.streamErr))
 		}
@@ -220,6 +287,7 @@ func (scheduler *roundRobinRequestScheduler) execute(reqBlock *requestControlBlo
 			}
 			quicSession.CloseWithError(quic.ErrorCode(reqErr.connErr), reason)
 		}
+		reqBlock.unhandledError = reqErr.err
 	}
 
 	reqBlock.designatedSession.removeFinishedRequest()
@@ -230,3 +298,19 @@ func (scheduler *roundRobinRequestScheduler) execute(reqBlock *requestControlBlo
 	scheduler.pendingRequests--
 	scheduler.Unlock()
 }
+
+// retry 把服务端没有处理的请求重新放回队列的头部, 由调度器在其他 quicSession 上重试.
+// 如果请求不能被重试, 返回 false.
+func (scheduler *roundRobinRequestScheduler) retry(reqBlock *requestControlBlock) bool {
+	session := reqBlock.designatedSession
+	if !reqBlock.prepareRetry() {
+		return false
+	}
+	scheduler.logger.Debugf("retrying unprocessed request = <%v>", reqBlock.request.URL.RequestURI())
+	session.removeFinishedRequest()
+	scheduler.Lock()
+	scheduler.requestQueue = append([]*requestControlBlock{reqBlock}, scheduler.requestQueue...)
+	scheduler.Unlock()
+	*scheduler.mayExecuteNextRequest <- struct{}{}
+	return true
+}
@@ -0,0 +1,73 @@
+package http3
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Round robin request scheduler", func() {
+	var (
+		scheduler     *roundRobinRequestScheduler
+		numGoroutines int
+	)
+
+	BeforeEach(func() {
+		numGoroutines = runtime.NumGoroutine()
+		scheduler = newRequestScheduler(roundRobinRequestSchedulerName, newTestClientInfo()).(*roundRobinRequestScheduler)
+	})
+
+	addAndWait := func(ctx context.Context) <-chan error {
+		req, err := http.NewRequest(http.MethodGet, "https://quic.clemente.io/file.dat", nil)
+		Expect(err).ToNot(HaveOccurred())
+		errChan := make(chan error, 1)
+		go func() {
+			_, err := scheduler.addAndWait(req.WithContext(ctx))
+			errChan <- err
+		}()
+		return errChan
+	}
+
+	queueLen := func() int {
+		scheduler.Lock()
+		defer scheduler.Unlock()
+		return len(scheduler.requestQueue)
+	}
+
+	It("removes canceled requests from the queue", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		errChan := addAndWait(ctx)
+		Eventually(queueLen).Should(Equal(1))
+		cancel()
+		Eventually(errChan).Should(Receive(MatchError(context.Canceled)))
+		Expect(queueLen()).To(BeZero())
+		Eventually(runtime.NumGoroutine).Should(BeNumerically("<=", numGoroutines))
+	})
+
+	It("resets the stream of a canceled request", func() {
+		sess := mockquic.NewMockSession(mockCtrl)
+		sessBlock := newSessionControlBlock(0, newClientSessionState(sess, nil, nil, utils.DefaultLogger), true)
+		scheduler.openedSession = append(scheduler.openedSession, sessBlock)
+		str, canceled := newBlockingRequestStream()
+		sess.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		errChan := addAndWait(ctx)
+		Eventually(queueLen).Should(Equal(1))
+		scheduler.mayExecute()
+		Eventually(sessBlock.getPendingRequest).Should(Equal(1))
+		Consistently(errChan, 50*time.Millisecond).ShouldNot(Receive())
+		cancel()
+		Eventually(errChan).Should(Receive(MatchError(context.Canceled)))
+		Eventually(canceled).Should(BeClosed())
+		Eventually(sessBlock.getPendingRequest).Should(BeZero())
+		Eventually(runtime.NumGoroutine).Should(BeNumerically("<=", numGoroutines))
+	})
+})
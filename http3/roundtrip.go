@@ -26,6 +26,11 @@ type RoundTripper struct {
 	// uncompressed.
 	DisableCompression bool
 
+	// EnablePush, if true, allows the server to push responses.
+	// A pushed response is returned for a later GET request for the same URL,
+	// instead of sending the request to the server.
+	EnablePush bool
+
 	// TLSClientConfig specifies the TLS configuration to use with
 	// tls.Client. If nil, the default configuration is used.
 	TLSClientConfig *tls.Config
@@ -44,6 +49,27 @@ type RoundTripper struct {
 	// Zero means to use a default limit.
 	MaxResponseHeaderBytes int64
 
+	// QPACKMaxTableCapacity is the maximum capacity of the QPACK dynamic table used to decode
+	// response headers, advertised to the server in the SETTINGS frame.
+	// It also limits the capacity of the dynamic table used to encode request headers.
+	// Zero means to use a default capacity, a negative value disables the dynamic table.
+	QPACKMaxTableCapacity int64
+
+	// QPACKBlockedStreams is the maximum number of streams that may be blocked
+	// waiting for QPACK encoder instructions, advertised to the server in the SETTINGS frame.
+	// Zero means to use a default limit, a negative value means that no stream may be blocked.
+	QPACKBlockedStreams int64
+
+	// Logger receives the log messages of the HTTP/3 client and its request schedulers.
+	// It is also used for the QUIC connections, unless QuicConfig.Logger is set.
+	// If nil, the default logger configured by the QUIC_GO_LOG_LEVEL environment variable is used.
+	Logger quic.Logger
+
+	// Metrics receives the metrics of the request scheduler.
+	// It is also used for the QUIC connections, unless QuicConfig.Metrics is set.
+	// If nil, no metrics are reported.
+	Metrics quic.Metrics
+
 	// 负责保存为每一个 hostname 打开的 client
 	clients map[string]client
 }
@@ -136,8 +162,13 @@ func (r *RoundTripper) getClient(hostname string, onlyCached bool) (http.RoundTr
 			hostname,
 			r.TLSClientConfig,
 			&roundTripperOpts{
-				DisableCompression: r.DisableCompression,
-				MaxHeaderBytes:     r.MaxResponseHeaderBytes,
+				DisableCompression:    r.DisableCompression,
+				EnablePush:            r.EnablePush,
+				MaxHeaderBytes:        r.MaxResponseHeaderBytes,
+				QPACKMaxTableCapacity: r.QPACKMaxTableCapacity,
+				QPACKBlockedStreams:   r.QPACKBlockedStreams,
+				Logger:                r.Logger,
+				Metrics:               r.Metrics,
 			},
 			r.QuicConfig,
 			r.Dial,
@@ -160,6 +191,18 @@ func (r *RoundTripper) Close() error {
 	return nil
 }
 
+// QPACKStats returns statistics about the compression of request headers
+// on all connections used by this RoundTripper.
+func (r *RoundTripper) QPACKStats() QPACKStats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var stats QPACKStats
+	for _, client := range r.clients {
+		stats.add(client.qpackStats())
+	}
+	return stats
+}
+
 func closeRequestBody(req *http.Request) {
 	if req.Body != nil {
 		req.Body.Close()
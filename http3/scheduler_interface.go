@@ -2,11 +2,10 @@ package http3
 
 import (
 	"crypto/tls"
-	"log"
 	"net/http"
 
 	"github.com/lucas-clemente/quic-go"
-	"github.com/marten-seemann/qpack"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 // 每个 client 下最多只能开 4 个 quic 连接，相当于最多同时使用 4 条连接处理同一个请求
@@ -26,8 +25,13 @@ type clientInfo struct {
 	tlsConfig        *tls.Config
 	quicConfig       *quic.Config
 	requestWriter    *requestWriter
-	decoder          *qpack.Decoder
+	qpackConf        *qpackConfig
 	roundTripperOpts *roundTripperOpts
+	pushes           *pushCache // 服务端推送的响应, 未启用推送时为 nil
+	// dialer 用于建立 quic 连接, 为 nil 时使用 quic.DialAddr
+	dialer  func(network, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.Session, error)
+	logger  utils.Logger  // 调度器及其打开的 quic 连接通过 logger 输出日志
+	metrics utils.Metrics // 调度器的统计数据, 为 nil 时不上报
 }
 
 // requestScheduler 是请求调度器的对外接口
@@ -40,6 +44,10 @@ type requestScheduler interface {
 
 // newRequestScheduler 是根据给定调度器类型生成对应调度器实例的工厂方法
 func newRequestScheduler(schedulerType string, info *clientInfo) requestScheduler {
+	// 未配置 logger 时使用默认的 logger
+	if info.logger == nil {
+		info.logger = utils.DefaultLogger.WithPrefix("h3 client")
+	}
 	switch schedulerType {
 	case roundRobinRequestSchedulerName:
 		return newRoundRobinRequestScheduler(info)
@@ -48,7 +56,7 @@ func newRequestScheduler(schedulerType string, info *clientInfo) requestSchedule
 	case singleConnectionRequestSchedulerName:
 		return newSingleConnectionScheduler(info)
 	default:
-		log.Printf("undefined request scheduler type: <%v>", schedulerType)
+		info.logger.Errorf("undefined request scheduler type: <%v>", schedulerType)
 		return nil
 	}
 }
@@ -2,11 +2,16 @@ package http3
 
 import (
 	"bytes"
+	"errors"
 	"io"
-	"log"
 	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
+// errClosedBody 是在响应体被关闭之后读取数据时返回的错误
+var errClosedBody = errors.New("http3: read on closed response body")
+
 // segmentedBufferControlBlock 是有序 buffer 队列中的对象
 type segmentedBufferControlBlock struct {
 	sync.Mutex
@@ -19,6 +24,7 @@ type segmentedBufferControlBlock struct {
 
 	dataSize int
 	readSize int
+	released bool // 缓冲区已被释放, 之后写入的数据会被丢弃
 }
 
 // Read 以同步方式从 segmentedBufferControlBlock 中的缓冲区读取数据
@@ -27,11 +33,6 @@ func (body *segmentedBufferControlBlock) Read(p []byte) (int, error) {
 	defer body.Unlock()
 
 	read, err := body.buffer.Read(p)
-	if err != nil {
-		if err != io.EOF {
-			log.Printf("Read: error in reading buffer = <%p>, err = <%s>", body.buffer, err.Error())
-		}
-	}
 	body.readSize += read
 	// log.Printf("Read: read <%d> bytes from buffer <%p>, buffer.readSize = <%d>", read, body.buffer, body.readSize)
 	return read, err
@@ -41,15 +42,24 @@ func (body *segmentedBufferControlBlock) Read(p []byte) (int, error) {
 func (body *segmentedBufferControlBlock) Write(p []byte) (int, error) {
 	body.Lock()
 	defer body.Unlock()
-	written, err := body.buffer.Write(p)
-	if err != nil {
-		log.Printf("Write: error in writting buffer = <%p>, err = <%s>", body.buffer, err.Error())
+	if body.released {
+		// 请求已被取消, 仍在传输的数据不再保存
+		return len(p), nil
 	}
+	written, err := body.buffer.Write(p)
 	body.dataSize += written
 	// log.Printf("Write: write <%d> bytes to buffer <%p>, buffer.dataSize = <%d>", written, body.buffer, body.dataSize)
 	return written, err
 }
 
+// release 释放缓冲区中尚未被读取的数据
+func (body *segmentedBufferControlBlock) release() {
+	body.Lock()
+	defer body.Unlock()
+	body.buffer = &bytes.Buffer{}
+	body.released = true
+}
+
 // newDataBlock 是对上层添加的数据及其起始位置的包装结构体
 type newDataBlock struct {
 	data        *[]byte // 数据段指针
@@ -71,6 +81,8 @@ type segmentedResponseBody interface {
 	setBufferBound(int, int, int, int)
 	// signaleDataArrival 方法通知读线程开始工作
 	signaleDataArrival()
+	// closeWithError 结束响应体并释放全部缓冲区, 之后的 Read 返回 err
+	closeWithError(err error)
 }
 
 // segmentedResponseBody 接口的实现类
@@ -84,17 +96,20 @@ type segmentedResponseBodyI struct {
 	offset                   int              // 可以读 offset 以前的数据，可以写 offset 以后的数据
 	readDataLen              int              // 被读取的字节数
 	contentLength            int              // 全部数据长度, 在没有读完 contentLength 个字节之前, Read 方法不会返回 EOF 错误
+	err                      error            // 响应体被关闭或者请求被取消的原因
 
 	newDataAddedChan *chan *newDataBlock // 加入新数据时向此 chan 发送信号以在主线程添加数据
-	closeChan        *chan struct{}      // 需要关闭该 body 时向该 chan 发送数据
+	closeChan        *chan struct{}      // 响应体被关闭时关闭该 chan
 	canReadChan      *chan struct{}      // 可以读取数据时向该 chan 发送消息
 
 	bufferList              []*segmentedBufferControlBlock // buffer 控制块队列
 	currentBufferBlockIndex int                            // 当前正在读 bufferList 中哪一块 buffer
+
+	logger utils.Logger
 }
 
 // newSegmentedResponseBody 返回一个新的 SegmentedResponseBody 实例
-func newSegmentedResponseBody(contentLength int) segmentedResponseBody {
+func newSegmentedResponseBody(contentLength int, logger utils.Logger) segmentedResponseBody {
 	dataMap := make(map[int]*[]byte)
 	newDataChan := make(chan *newDataBlock, 10)
 	closeChan := make(chan struct{})
@@ -109,6 +124,7 @@ func newSegmentedResponseBody(contentLength int) segmentedResponseBody {
 		canReadChan:      &canReadChan,
 
 		bufferList: make([]*segmentedBufferControlBlock, 0),
+		logger:     logger,
 	}
 	// 在后台运行 body 主线程
 	go body.run()
@@ -160,14 +176,14 @@ func (body *segmentedResponseBodyI) addDataImpl(data *[]byte, written int, start
 			// 从 dataMap 临时空间中寻找是否还有能接的上的数据段
 			dataSegment, ok := (*body.dataMap)[body.offset]
 			if !ok {
-				log.Printf("addDataImpl: found no data at offset = <%v>", body.offset)
+				body.logger.Debugf("addDataImpl: found no data at offset = <%v>", body.offset)
 				// 没有该位置的数据分段
 				break
 			}
 			// 找打了下一个连续的数据分段，需要把它写进 mainBuffer 之中
 			written, err := body.mainBuffer.Write(*dataSegment)
 			if err != nil {
-				log.Printf("addDataImpl: error in adding data: err = <%v>", err.Error())
+				body.logger.Errorf("addDataImpl: error in adding data: err = <%v>", err.Error())
 				return
 			}
 			// 声明有新的数据可供读取
@@ -209,6 +225,10 @@ func (body *segmentedResponseBodyI) Write(buf []byte) (int, error) {
 // Read 方法对外提供读取内部连续数据区的接口
 func (body *segmentedResponseBodyI) Read(buf []byte) (int, error) {
 	body.mutex.Lock()
+	if body.err != nil {
+		body.mutex.Unlock()
+		return 0, body.err
+	}
 	if body.readDataLen == body.contentLength {
 		// 已经读完全部数据, 直接返回 EOF 让上层应用停止即可
 		body.mutex.Unlock()
@@ -225,6 +245,11 @@ skipReadOperation:
 	case <-*body.canReadChan:
 		// 有新的数据可供读取
 		body.mutex.Lock()
+		if body.err != nil {
+			// 等待数据期间响应体被关闭
+			body.mutex.Unlock()
+			return 0, body.err
+		}
 		targetBuffer := body.bufferList[body.currentBufferBlockIndex]
 		written, err := targetBuffer.Read(buf)
 		body.readDataLen += written
@@ -235,7 +260,7 @@ skipReadOperation:
 		// TODO: 需要重做这一段的控制逻辑
 		if err != nil && err != io.EOF {
 			// 出现了其他错误，由上层处理
-			log.Printf("Read: unexpected error = <%s>", err.Error())
+			body.logger.Errorf("Read: unexpected error = <%s>", err.Error())
 			return written, err
 		}
 
@@ -301,6 +326,10 @@ skipReadOperation:
 		body.mutex.Unlock()
 		// 正常返回读到的字节数和 nil 错误
 		return written, err
+	case <-*body.closeChan:
+		body.mutex.Lock()
+		defer body.mutex.Unlock()
+		return 0, body.err
 	}
 }
 
@@ -308,6 +337,10 @@ skipReadOperation:
 func (body *segmentedResponseBodyI) registerSegmentedBuffer(bufferBlock *segmentedBufferControlBlock) {
 	body.mutex.Lock()
 	defer body.mutex.Unlock()
+	if body.err != nil {
+		bufferBlock.release()
+		return
+	}
 
 	// log.Printf("register: session = <%d>, start = <%d>, end = <%d>, buf addr = <%p>, buf len = <%d>",
 	// bufferBlock.sessionBlockID, bufferBlock.start, bufferBlock.end, bufferBlock.buffer, bufferBlock.buffer.Len())
@@ -338,15 +371,31 @@ func (body *segmentedResponseBodyI) setBufferBound(oldStart int, oldEnd int, new
 func (body *segmentedResponseBodyI) signaleDataArrival() {
 	body.mutex.Lock()
 	defer body.mutex.Unlock()
-	if len(*body.canReadChan) < 1 {
+	if body.err == nil && len(*body.canReadChan) < 1 {
 		*body.canReadChan <- struct{}{}
 	}
 }
 
 // Close 方法负责关闭该示例相关的各种资源
 func (body *segmentedResponseBodyI) Close() error {
-	// 由于该示例中并无需要关闭的资源，故该方法直接返回 nil 错误
-	// 发出关闭 body 主线程的信号
-	*body.closeChan <- struct{}{}
+	body.closeWithError(errClosedBody)
 	return nil
 }
+
+// closeWithError 结束 body 主线程, 唤醒正在等待数据的 Read, 并释放全部缓冲区.
+// 只有第一次调用时给出的 err 会被 Read 返回.
+func (body *segmentedResponseBodyI) closeWithError(err error) {
+	body.mutex.Lock()
+	defer body.mutex.Unlock()
+	if body.err != nil {
+		return
+	}
+	body.err = err
+	close(*body.closeChan)
+	for _, bufferBlock := range body.bufferList {
+		bufferBlock.release()
+	}
+	body.bufferList = nil
+	*body.dataMap = make(map[int]*[]byte)
+	body.mainBuffer = bytes.Buffer{}
+}
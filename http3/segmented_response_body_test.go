@@ -0,0 +1,78 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"runtime"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Segmented response body", func() {
+	var (
+		body          segmentedResponseBody
+		buf           *segmentedBufferControlBlock
+		numGoroutines int
+	)
+
+	BeforeEach(func() {
+		numGoroutines = runtime.NumGoroutine()
+		body = newSegmentedResponseBody(6, utils.DefaultLogger)
+		buf = &segmentedBufferControlBlock{start: 0, end: 5, buffer: &bytes.Buffer{}}
+		body.registerSegmentedBuffer(buf)
+	})
+
+	It("reads data", func() {
+		buf.Write([]byte("foobar"))
+		body.signaleDataArrival()
+		data := make([]byte, 6)
+		_, err := io.ReadFull(body, data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foobar")))
+		Expect(body.Close()).To(Succeed())
+		Eventually(runtime.NumGoroutine).Should(BeNumerically("<=", numGoroutines))
+	})
+
+	It("unblocks Read when closed with an error", func() {
+		buf.Write([]byte("foo"))
+		body.signaleDataArrival()
+		data := make([]byte, 6)
+		n, err := body.Read(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data[:n]).To(Equal([]byte("foo")))
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, err := body.Read(data)
+			Expect(err).To(MatchError(context.Canceled))
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		body.closeWithError(context.Canceled)
+		Eventually(done).Should(BeClosed())
+		// the first error is kept
+		body.closeWithError(context.DeadlineExceeded)
+		_, err = body.Read(data)
+		Expect(err).To(MatchError(context.Canceled))
+		Eventually(runtime.NumGoroutine).Should(BeNumerically("<=", numGoroutines))
+	})
+
+	It("releases the buffers", func() {
+		buf.Write([]byte("foo"))
+		Expect(body.Close()).To(Succeed())
+		Expect(buf.buffer.Len()).To(BeZero())
+		// data arriving after the body was closed is discarded
+		n, err := buf.Write([]byte("bar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(3))
+		Expect(buf.buffer.Len()).To(BeZero())
+		_, err = body.Read(make([]byte, 6))
+		Expect(err).To(MatchError(errClosedBody))
+		// closing again doesn't block
+		Expect(body.Close()).To(Succeed())
+	})
+})
@@ -10,13 +10,15 @@ import (
 	"net"
 	"net/http"
 	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
-	"github.com/marten-seemann/qpack"
 )
 
 // allows mocking of quic.Listen and quic.ListenAddr
@@ -25,7 +27,50 @@ var (
 	quicListenAddr = quic.ListenAddr
 )
 
-const nextProtoH3 = "h3-24"
+// HTTP/3 的 ALPN 与 QUIC 版本一一对应
+const (
+	nextProtoH3        = "h3"    // QUIC v1 (RFC 9114)
+	nextProtoH3Draft29 = "h3-29" // QUIC draft-29
+	nextProtoH3Draft24 = "h3-24" // QUIC draft-24
+)
+
+// versionToALPN 返回 QUIC 版本对应的 HTTP/3 ALPN, 不支持的版本返回空字符串
+func versionToALPN(v protocol.VersionNumber) string {
+	switch v {
+	case protocol.Version1:
+		return nextProtoH3
+	case protocol.VersionDraft29:
+		return nextProtoH3Draft29
+	case protocol.VersionMilestone0_14:
+		return nextProtoH3Draft24
+	}
+	return ""
+}
+
+// alpnToVersion 是 versionToALPN 的逆映射
+func alpnToVersion(proto string) (protocol.VersionNumber, bool) {
+	for _, v := range protocol.SupportedVersions {
+		if versionToALPN(v) == proto {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// nextProtos 返回 quic.Config 中的版本对应的 ALPN, 顺序与版本的偏好顺序相同
+func nextProtos(conf *quic.Config) []string {
+	versions := protocol.SupportedVersions
+	if conf != nil && len(conf.Versions) > 0 {
+		versions = conf.Versions
+	}
+	protos := make([]string, 0, len(versions))
+	for _, v := range versions {
+		if proto := versionToALPN(v); proto != "" {
+			protos = append(protos, proto)
+		}
+	}
+	return protos
+}
 
 type requestError struct {
 	err       error
@@ -49,12 +94,51 @@ type Server struct {
 	// If nil, it uses reasonable default values.
 	QuicConfig *quic.Config
 
+	// PushOrder is the transmission order of the resources of a page, e.g. one of the lists in the order package.
+	// If set, a GET request for one of the listed resources causes the server to push all resources
+	// that follow it in the list and haven't been pushed on this connection yet.
+	// Resources are pushed one after another, in the order of the list.
+	PushOrder []string
+
+	// QPACKMaxTableCapacity is the maximum capacity of the QPACK dynamic table used to decode
+	// request headers, advertised to clients in the SETTINGS frame.
+	// It also limits the capacity of the dynamic table used to encode response headers.
+	// Zero means to use a default capacity, a negative value disables the dynamic table.
+	QPACKMaxTableCapacity int64
+
+	// QPACKBlockedStreams is the maximum number of request streams that may be blocked
+	// waiting for QPACK encoder instructions, advertised to clients in the SETTINGS frame.
+	// Zero means to use a default limit, a negative value means that no stream may be blocked.
+	QPACKBlockedStreams int64
+
+	// EnableExtendedConnect advertises support for extended CONNECT requests (RFC 9220),
+	// which carry a :protocol pseudo-header. The handler receives the protocol in Request.Proto.
+	// If disabled, extended CONNECT requests are rejected.
+	EnableExtendedConnect bool
+
+	// EnableWebTransport advertises support for WebTransport sessions. It implies EnableExtendedConnect.
+	// Handlers accept a session by calling UpgradeWebTransport.
+	EnableWebTransport bool
+
+	// Logger receives the log messages of the server.
+	// It is also used for the QUIC connections, unless QuicConfig.Logger is set.
+	// If nil, the default logger configured by the QUIC_GO_LOG_LEVEL environment variable is used.
+	Logger quic.Logger
+
+	// Metrics receives the metrics of the QUIC connections, unless QuicConfig.Metrics is set.
+	// If nil, no metrics are reported.
+	Metrics quic.Metrics
+
 	port uint32 // used atomically
 
 	mutex     sync.Mutex
 	listeners map[*quic.Listener]struct{}
+	conns     map[*serverConn]struct{}
 	closed    utils.AtomicBool
 
+	qpackStatsOnce sync.Once
+	qpackStats     *qpackStatsCollector
+
 	logger utils.Logger
 }
 
@@ -96,34 +180,67 @@ func (s *Server) serveImpl(tlsConf *tls.Config, conn net.PacketConn) error {
 	if s.Server == nil {
 		return errors.New("use of http3.Server without http.Server")
 	}
-	s.logger = utils.DefaultLogger.WithPrefix("server")
+	s.logger = utils.NewLogger(s.Logger, "h3 server")
 
 	if tlsConf == nil {
 		tlsConf = &tls.Config{}
 	} else {
 		tlsConf = tlsConf.Clone()
 	}
-	// Replace existing ALPNs by H3
-	tlsConf.NextProtos = []string{nextProtoH3}
-	if tlsConf.GetConfigForClient != nil {
-		getConfigForClient := tlsConf.GetConfigForClient
-		tlsConf.GetConfigForClient = func(ch *tls.ClientHelloInfo) (*tls.Config, error) {
-			conf, err := getConfigForClient(ch)
-			if err != nil || conf == nil {
-				return conf, err
+	// Replace existing ALPNs by H3.
+	// The ALPN is selected based on the QUIC version of the connection when the ClientHello is received.
+	protos := nextProtos(s.QuicConfig)
+	tlsConf.NextProtos = protos
+	getConfigForClient := tlsConf.GetConfigForClient
+	baseConf := tlsConf.Clone()
+	baseConf.GetConfigForClient = nil
+	tlsConf.GetConfigForClient = func(ch *tls.ClientHelloInfo) (*tls.Config, error) {
+		conf := baseConf
+		if getConfigForClient != nil {
+			c, err := getConfigForClient(ch)
+			if err != nil {
+				return nil, err
+			}
+			if c != nil {
+				conf = c
 			}
-			conf = conf.Clone()
-			conf.NextProtos = []string{nextProtoH3}
-			return conf, nil
 		}
+		conf = conf.Clone()
+		conf.NextProtos = protos
+		if qconn, ok := ch.Conn.(handshake.ConnWithVersion); ok {
+			if proto := versionToALPN(qconn.GetQUICVersion()); proto != "" {
+				conf.NextProtos = []string{proto}
+			}
+		}
+		return conf, nil
+	}
+
+	quicConf := s.QuicConfig
+	if s.Logger != nil && (quicConf == nil || quicConf.Logger == nil) {
+		if quicConf == nil {
+			quicConf = &quic.Config{}
+		} else {
+			conf := *quicConf
+			quicConf = &conf
+		}
+		quicConf.Logger = s.Logger
+	}
+	if s.Metrics != nil && (quicConf == nil || quicConf.Metrics == nil) {
+		if quicConf == nil {
+			quicConf = &quic.Config{}
+		} else {
+			conf := *quicConf
+			quicConf = &conf
+		}
+		quicConf.Metrics = s.Metrics
 	}
 
 	var ln quic.Listener
 	var err error
 	if conn == nil {
-		ln, err = quicListenAddr(s.Addr, tlsConf, s.QuicConfig)
+		ln, err = quicListenAddr(s.Addr, tlsConf, quicConf)
 	} else {
-		ln, err = quicListen(conn, tlsConf, s.QuicConfig)
+		ln, err = quicListen(conn, tlsConf, quicConf)
 	}
 	if err != nil {
 		return err
@@ -133,10 +250,10 @@ func (s *Server) serveImpl(tlsConf *tls.Config, conn net.PacketConn) error {
 
 	for {
 		sess, err := ln.Accept(context.Background())
-		fmt.Println("accept new quic connection")
 		if err != nil {
 			return err
 		}
+		s.logger.Debugf("Accepted a new QUIC connection from %s", sess.RemoteAddr())
 		go s.handleConn(sess)
 	}
 }
@@ -159,46 +276,215 @@ func (s *Server) removeListener(l *quic.Listener) {
 	s.mutex.Unlock()
 }
 
-func (s *Server) handleResponseFunc(
-	str quic.Stream, responseWriter http.ResponseWriter, req *http.Request) {
-	// quic.ConcurrentStreamCounter.OnStart()
-	// 返回响应体
-	s.handleRequest(str, responseWriter, req)
+func (s *Server) addConn(c *serverConn) {
+	s.mutex.Lock()
+	if s.conns == nil {
+		s.conns = make(map[*serverConn]struct{})
+	}
+	s.conns[c] = struct{}{}
+	s.mutex.Unlock()
+}
+
+func (s *Server) removeConn(c *serverConn) {
+	s.mutex.Lock()
+	delete(s.conns, c)
+	s.mutex.Unlock()
+}
+
+// serverConn 保存服务端在一条 quicSession 上的 HTTP/3 状态
+type serverConn struct {
+	sess       quic.Session
+	ps         *pushState
+	controlStr quic.SendStream // 服务端的控制 stream
+
+	qpackConf *qpackConfig
+	encoder   *qpackEncoder // 编码响应头部
+	decoder   *qpackDecoder // 解码请求头部
+
+	webTransport *webTransportSessions // 服务端没有启用 WebTransport 时为 nil
+
+	mutex            sync.Mutex
+	hasControlStream bool           // 是否已经收到客户端的控制 stream
+	goingAway        bool           // 是否已经发送了 GOAWAY 帧
+	nextStreamID     quic.StreamID  // 比已接受的所有请求 stream ID 都大的最小的请求 stream ID
+	requests         sync.WaitGroup // 正在处理的请求
+}
+
+func newServerConn(sess quic.Session, qpackConf *qpackConfig) *serverConn {
+	encoder, decoder := qpackConf.newCodec(sess)
+	return &serverConn{
+		sess:      sess,
+		ps:        newPushState(sess, encoder),
+		qpackConf: qpackConf,
+		encoder:   encoder,
+		decoder:   decoder,
+	}
+}
+
+// claimControlStream 记录客户端的控制 stream. 客户端只能打开一条控制 stream, 重复打开时返回 false.
+func (c *serverConn) claimControlStream() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.hasControlStream {
+		return false
+	}
+	c.hasControlStream = true
+	return true
+}
+
+// startRequest 开始处理 id 对应的请求. 在发送 GOAWAY 帧之后, 新的请求不再被处理, 此时返回 false.
+func (c *serverConn) startRequest(id quic.StreamID) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.goingAway {
+		return false
+	}
+	if id >= c.nextStreamID {
+		c.nextStreamID = id + 4
+	}
+	c.requests.Add(1)
+	return true
+}
+
+// goAway 发送 GOAWAY 帧. 客户端在收到之后不会再在该连接上发送新的请求,
+// stream ID 不小于 GOAWAY 帧中 stream ID 的请求都没有被处理, 客户端可以安全地重试.
+func (c *serverConn) goAway() {
+	c.mutex.Lock()
+	if c.goingAway {
+		c.mutex.Unlock()
+		return
+	}
+	c.goingAway = true
+	id := c.nextStreamID
+	c.mutex.Unlock()
+
+	buf := &bytes.Buffer{}
+	(&goAwayFrame{StreamID: id}).Write(buf)
+	c.controlStr.Write(buf.Bytes())
+}
+
+// close 在 sess 关闭之后释放 QPACK 编解码器, 被阻塞的请求以 err 失败
+func (c *serverConn) close(err error) {
+	c.qpackConf.closeCodec(c.encoder, c.decoder, err)
+	if c.webTransport != nil {
+		c.webTransport.closeAll(err)
+	}
+}
+
+// wait 等待所有正在处理的请求, 以及这些请求触发的推送完成.
+// 请求的 handler 返回之后不会再开始新的推送, 因此先等待请求, 再等待推送.
+func (c *serverConn) wait() {
+	c.requests.Wait()
+	c.ps.pending.Wait()
+}
+
+// handleResponseFunc 处理一个请求 stream, 并在发送完响应之后关闭该 stream.
+// 如果请求无法被处理, 则按照错误类型重置 stream 或关闭整个连接.
+func (s *Server) handleResponseFunc(conn *serverConn, str quic.Stream) {
+	sess := conn.sess
+	// quic.ConcurrentStreamCounter.OnStart(s.logger)
+	rerr := s.handleRequest(str, conn.ps, conn.encoder, conn.decoder, conn.webTransport, func() {
+		sess.CloseWithError(quic.ErrorCode(errorFrameUnexpected), "")
+	})
 	// quic.ConcurrentStreamCounter.OnFinish()
+	if rerr.err == errStreamHijacked {
+		// stream 属于 WebTransport session, 由 session 负责关闭
+		return
+	}
+	if rerr.err != nil || rerr.streamErr != 0 || rerr.connErr != 0 {
+		s.logger.Debugf("Handling request failed: %s", rerr.err)
+		if rerr.streamErr != 0 {
+			str.CancelWrite(quic.ErrorCode(rerr.streamErr))
+		}
+		if rerr.connErr != 0 {
+			var reason string
+			if rerr.err != nil {
+				reason = rerr.err.Error()
+			}
+			sess.CloseWithError(quic.ErrorCode(rerr.connErr), reason)
+		}
+		return
+	}
 	// 在发送完响应体之后关闭对应的 stream
 	str.Close()
 }
 
 func (s *Server) handleConn(sess quic.Session) {
-	// TODO: accept control streams
-	decoder := qpack.NewDecoder(nil)
+	conn := newServerConn(sess, s.qpackConfig())
+	if s.EnableWebTransport {
+		conn.webTransport = newWebTransportSessions(sess)
+	}
+	go s.handleUniStreams(conn)
 
 	// send a SETTINGS frame
 	str, err := sess.OpenUniStream()
 	if err != nil {
 		s.logger.Debugf("Opening the control stream failed.")
+		conn.close(err)
 		return
 	}
 	buf := bytes.NewBuffer([]byte{0})
-	(&settingsFrame{}).Write(buf)
+	(&settingsFrame{settings: s.settings(conn.qpackConf)}).Write(buf)
 	str.Write(buf.Bytes())
+	conn.controlStr = str
+
+	s.addConn(conn)
+	defer s.removeConn(conn)
+	if s.closed.Get() {
+		// 服务端正在关闭, 不再处理该连接上的任何请求
+		conn.goAway()
+	}
 
 	for {
 		// 接受客户端发送的 request
 		str, err := sess.AcceptStream(context.Background())
 		if err != nil {
 			s.logger.Debugf("Accepting stream failed: %s", err)
+			conn.close(err)
 			return
 		}
+		if !conn.startRequest(str.StreamID()) {
+			str.CancelRead(quic.ErrorCode(errorRequestRejected))
+			str.CancelWrite(quic.ErrorCode(errorRequestRejected))
+			continue
+		}
+
+		// 在新起的 go 程中解析请求并处理 response,
+		// 这样发送请求缓慢的客户端不会阻塞对其他 stream 的接受
+		go func() {
+			defer conn.requests.Done()
+			s.handleResponseFunc(conn, str)
+		}()
+	}
+}
 
-		// 解析请求并构造对应的 ResponseWriter
-		responseWriter, request := s.decodeRequest(str, decoder)
-		// 把该 ResponseWriter 添加到调度器中
-		// fmt.Printf("accept request <%v>\n", request.RequestURI)
-		// sess.Scheduler().AddNewResponseWriter(responseWriter, request, str, s.Handler)
-		// 在新起的 go 程中处理 request 和 response
-		go s.handleResponseFunc(str, responseWriter, request)
+// settings 返回服务端在 SETTINGS 帧中发送的设置项
+func (s *Server) settings(qpackConf *qpackConfig) map[uint64]uint64 {
+	settings := qpackConf.settings()
+	if s.EnableExtendedConnect || s.EnableWebTransport {
+		settings[settingExtendedConnect] = 1
 	}
+	if s.EnableWebTransport {
+		settings[settingEnableWebTransport] = 1
+	}
+	return settings
+}
+
+// qpackConfig 返回该服务端上所有连接共用的 QPACK 配置
+func (s *Server) qpackConfig() *qpackConfig {
+	return newQPACKConfig(s.QPACKMaxTableCapacity, s.QPACKBlockedStreams, s.getQPACKStatsCollector())
+}
+
+func (s *Server) getQPACKStatsCollector() *qpackStatsCollector {
+	s.qpackStatsOnce.Do(func() {
+		s.qpackStats = newQPACKStatsCollector()
+	})
+	return s.qpackStats
+}
+
+// QPACKStats returns statistics about the compression of response headers on all connections of this server.
+func (s *Server) QPACKStats() QPACKStats {
+	return s.getQPACKStatsCollector().get()
 }
 
 func (s *Server) maxHeaderBytes() uint64 {
@@ -208,51 +494,109 @@ func (s *Server) maxHeaderBytes() uint64 {
 	return uint64(s.Server.MaxHeaderBytes)
 }
 
-// decodeRequest 负责解码收到的请求
-func (s *Server) decodeRequest(str quic.Stream, decoder *qpack.Decoder) (
-	http.ResponseWriter, *http.Request) {
-	frame, _ := parseNextFrame(str)
-	hf, _ := frame.(*headersFrame)
+// decodeRequest 负责解码收到的请求, 并构造对应的 ResponseWriter
+// wt 为 nil 时不接受 WebTransport session 和属于 session 的 stream.
+func (s *Server) decodeRequest(str quic.Stream, ps *pushState, encoder *qpackEncoder, decoder *qpackDecoder, wt *webTransportSessions, onFrameError func()) (
+	*responseWriter, *http.Request, requestError) {
+	frame, err := parseFirstRequestStreamFrame(str, wt != nil)
+	if err != nil {
+		return nil, nil, newStreamError(errorRequestIncomplete, err)
+	}
+	if f, ok := frame.(*webTransportStreamFrame); ok {
+		return nil, nil, wt.handleStream(str, f.SessionID)
+	}
+	hf, ok := frame.(*headersFrame)
+	if !ok {
+		return nil, nil, newConnError(errorFrameUnexpected, errors.New("expected first frame to be a HEADERS frame"))
+	}
+	if hf.Length > s.maxHeaderBytes() {
+		// 帧本身是合法的, 只是超出了我们愿意处理的大小
+		return nil, nil, newStreamError(errorExcessiveLoad, fmt.Errorf("HEADERS frame too large: %d bytes (max: %d)", hf.Length, s.maxHeaderBytes()))
+	}
 	headerBlock := make([]byte, hf.Length)
-	io.ReadFull(str, headerBlock)
-	hfs, _ := decoder.DecodeFull(headerBlock)
-	req, _ := requestFromHeaders(hfs)
+	if _, err := io.ReadFull(str, headerBlock); err != nil {
+		return nil, nil, newStreamError(errorRequestIncomplete, err)
+	}
+	hfs, err := decoder.decode(str.StreamID(), headerBlock)
+	if err != nil {
+		// 无法解码的 header block 会破坏整个连接的 QPACK 状态
+		return nil, nil, newConnError(errorQPACKDecompressionFailed, err)
+	}
+	req, err := requestFromHeaders(hfs)
+	if err != nil {
+		// 格式错误的请求只影响该 stream
+		return nil, nil, newStreamError(errorGeneralProtocolError, err)
+	}
+	extendedConnect := req.Method == http.MethodConnect && req.Proto != "HTTP/3"
+	if extendedConnect && !s.EnableExtendedConnect && !s.EnableWebTransport {
+		return nil, nil, newStreamError(errorGeneralProtocolError, fmt.Errorf("extended CONNECT not enabled (:protocol %s)", req.Proto))
+	}
 
-	req.Body = newRequestBody(str)
 	req = req.WithContext(str.Context())
+	body := newRequestBody(str, onFrameError)
+	body.trailer = &req.Trailer
+	body.decoder = decoder
+	body.maxHeaderBytes = s.maxHeaderBytes()
+	req.Body = body
 	responseWriter := newResponseWriter(str, s.logger)
-
-	return responseWriter, req
+	responseWriter.encoder = encoder
+	responseWriter.streamID = str.StreamID()
+	if extendedConnect && req.Proto == "webtransport" && wt != nil {
+		responseWriter.webTransport = wt.newSession(str)
+	}
+	if ps != nil {
+		responseWriter.pusher = func(target string, opts *http.PushOptions) error {
+			// PUSH_PROMISE 帧和缓冲的响应写入同一个缓冲区, 以免插入到响应的帧中间
+			p, err := s.promise(ps, responseWriter.bufferedStream, req, target, opts)
+			if err != nil {
+				return err
+			}
+			ps.pending.Add(1)
+			go func() {
+				defer ps.pending.Done()
+				s.servePush(ps, p)
+			}()
+			return nil
+		}
+	}
+	return responseWriter, req, requestError{}
 }
 
-func (s *Server) handleRequest(
-	str quic.Stream, responseWriter http.ResponseWriter, req *http.Request) requestError {
-	handler := s.Handler
-	var panicked, readEOF bool
-	func() {
-		defer func() {
-			if p := recover(); p != nil {
-				// Copied from net/http/server.go
-				const size = 64 << 10
-				buf := make([]byte, size)
-				buf = buf[:runtime.Stack(buf, false)]
-				s.logger.Errorf("http: panic serving: %v\n%s", p, buf)
-				panicked = true
-			}
-		}()
-		// 以同步方式调用指定的 handler 处理响应体
-		handler.ServeHTTP(responseWriter, req)
+// handleRequest 解析 stream 上的请求并调用 handler 处理.
+// 如果请求无法被解析, 则返回对应的错误, 此时 handler 不会被调用.
+// 如果 handler 接受了 WebTransport session, 返回 errStreamHijacked, 此时 stream 不能再被关闭.
+func (s *Server) handleRequest(str quic.Stream, ps *pushState, encoder *qpackEncoder, decoder *qpackDecoder, wt *webTransportSessions, onFrameError func()) requestError {
+	responseWriter, req, rerr := s.decodeRequest(str, ps, encoder, decoder, wt, onFrameError)
+	if rerr.err != nil {
+		return rerr
+	}
+	// 按照 PushOrder 推送后续资源, PUSH_PROMISE 帧必须在响应之前发送
+	s.pushInOrder(ps, str, req)
+
+	var readEOF bool
+	panicked := s.serveHTTP(responseWriter, req)
+	if responseWriter.hijacked {
+		return requestError{err: errStreamHijacked}
+	}
+	if responseWriter.webTransport != nil {
+		// handler 没有接受 WebTransport session
+		responseWriter.webTransport.terminate(errWebTransportSessionRejected)
+	}
+	if !panicked {
 		// read the eof
 		if _, err := str.Read([]byte{0}); err == io.EOF {
 			readEOF = true
 		}
-	}()
+	}
 
+	// WriteHeader 只在 handler 没有写入响应头时生效
 	if panicked {
 		responseWriter.WriteHeader(500)
 	} else {
 		responseWriter.WriteHeader(200)
+		responseWriter.writeTrailers()
 	}
+	responseWriter.Flush()
 
 	if !readEOF {
 		str.CancelRead(quic.ErrorCode(errorEarlyResponse))
@@ -260,6 +604,26 @@ func (s *Server) handleRequest(
 	return requestError{}
 }
 
+// serveHTTP 以同步方式调用指定的 handler 处理请求, 并返回 handler 是否发生了 panic
+func (s *Server) serveHTTP(responseWriter http.ResponseWriter, req *http.Request) (panicked bool) {
+	defer func() {
+		if p := recover(); p != nil {
+			// Copied from net/http/server.go
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			s.logger.Errorf("http: panic serving: %v\n%s", p, buf)
+			panicked = true
+		}
+	}()
+	handler := s.Handler
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+	handler.ServeHTTP(responseWriter, req)
+	return false
+}
+
 // Close the server immediately, aborting requests and sending CONNECTION_CLOSE frames to connected clients.
 // Close in combination with ListenAndServe() (instead of Serve()) may race if it is called before a UDP socket is established.
 func (s *Server) Close() error {
@@ -280,13 +644,38 @@ func (s *Server) Close() error {
 // CloseGracefully shuts down the server gracefully. The server sends a GOAWAY frame first, then waits for either timeout to trigger, or for all running requests to complete.
 // CloseGracefully in combination with ListenAndServe() (instead of Serve()) may race if it is called before a UDP socket is established.
 func (s *Server) CloseGracefully(timeout time.Duration) error {
-	// TODO: implement
-	return nil
+	s.closed.Set(true)
+
+	s.mutex.Lock()
+	conns := make([]*serverConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mutex.Unlock()
+
+	for _, c := range conns {
+		c.goAway()
+	}
+	done := make(chan struct{})
+	go func() {
+		for _, c := range conns {
+			c.wait()
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+	for _, c := range conns {
+		c.sess.CloseWithError(quic.ErrorCode(errorNoError), "")
+	}
+	return s.Close()
 }
 
 // SetQuicHeaders can be used to set the proper headers that announce that this server supports QUIC.
 // The values that are set depend on the port information from s.Server.Addr, and currently look like this (if Addr has port 443):
-//  Alt-Svc: quic=":443"; ma=2592000; v="33,32,31,30"
+//  Alt-Svc: h3=":443"; ma=2592000,h3-29=":443"; ma=2592000,h3-24=":443"; ma=2592000
 func (s *Server) SetQuicHeaders(hdr http.Header) error {
 	port := atomic.LoadUint32(&s.port)
 
@@ -304,7 +693,12 @@ func (s *Server) SetQuicHeaders(hdr http.Header) error {
 		atomic.StoreUint32(&s.port, port)
 	}
 
-	hdr.Add("Alt-Svc", fmt.Sprintf(`%s=":%d"; ma=2592000`, nextProtoH3, port))
+	protos := nextProtos(s.QuicConfig)
+	altSvc := make([]string, len(protos))
+	for i, proto := range protos {
+		altSvc[i] = fmt.Sprintf(`%s=":%d"; ma=2592000`, proto, port)
+	}
+	hdr.Add("Alt-Svc", strings.Join(altSvc, ","))
 
 	return nil
 }
@@ -0,0 +1,380 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/marten-seemann/qpack"
+)
+
+// maxPushIDDelay 是按序推送时等待客户端 MAX_PUSH_ID 帧的最长时间.
+// 控制 stream 和请求 stream 在不同的 go 程中处理, 第一个请求可能先于 MAX_PUSH_ID 帧被处理.
+const maxPushIDDelay = 50 * time.Millisecond
+
+var (
+	errPushDisabled      = errors.New("http3: the client didn't allow server push")
+	errPushLimitReached  = errors.New("http3: push ID limit reached")
+	errPushInvalidMethod = errors.New("http3: push method must be GET or HEAD")
+)
+
+// pushState 保存服务端在一条 quicSession 上的推送状态
+type pushState struct {
+	mutex sync.Mutex
+
+	sess quic.Session
+
+	hasMaxPushID      bool          // 是否已经收到客户端的 MAX_PUSH_ID 帧
+	maxPushIDReceived chan struct{} // 在收到第一个 MAX_PUSH_ID 帧时关闭
+	maxPushID         uint64        // 客户端允许使用的最大 push ID
+	nextPushID        uint64
+
+	canceled map[uint64]struct{}        // 已被客户端取消的推送
+	streams  map[uint64]quic.SendStream // 正在发送的 push stream
+	promised map[string]struct{}        // 已经在该连接上承诺过的路径
+
+	pending sync.WaitGroup // 尚未发送完成的推送
+
+	encoder *qpackEncoder // 编码推送的响应头部
+}
+
+func newPushState(sess quic.Session, encoder *qpackEncoder) *pushState {
+	return &pushState{
+		sess:              sess,
+		encoder:           encoder,
+		maxPushIDReceived: make(chan struct{}),
+		canceled:          make(map[uint64]struct{}),
+		streams:           make(map[uint64]quic.SendStream),
+		promised:          make(map[string]struct{}),
+	}
+}
+
+// claim 将 path 标记为已承诺. 如果 path 已经被承诺过, 返回 false.
+func (p *pushState) claim(path string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if _, ok := p.promised[path]; ok {
+		return false
+	}
+	p.promised[path] = struct{}{}
+	return true
+}
+
+// release 撤销对尚未承诺的路径的标记, 使它们之后仍然可以被推送
+func (p *pushState) release(paths ...string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, path := range paths {
+		delete(p.promised, path)
+	}
+}
+
+func (p *pushState) newPushID() (uint64, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !p.hasMaxPushID {
+		return 0, errPushDisabled
+	}
+	if p.nextPushID > p.maxPushID {
+		return 0, errPushLimitReached
+	}
+	id := p.nextPushID
+	p.nextPushID++
+	return id, nil
+}
+
+func (p *pushState) handleMaxPushID(id uint64) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.hasMaxPushID && id < p.maxPushID {
+		return fmt.Errorf("MAX_PUSH_ID reduced from %d to %d", p.maxPushID, id)
+	}
+	if !p.hasMaxPushID {
+		p.hasMaxPushID = true
+		close(p.maxPushIDReceived)
+	}
+	p.maxPushID = id
+	return nil
+}
+
+func (p *pushState) handleCancelPush(id uint64) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if id >= p.nextPushID {
+		return fmt.Errorf("CANCEL_PUSH for push ID %d that was not promised", id)
+	}
+	p.canceled[id] = struct{}{}
+	if str, ok := p.streams[id]; ok {
+		str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
+	}
+	return nil
+}
+
+// openPushStream 打开 pushID 对应的 push stream. 如果该推送已被取消, 返回 nil.
+func (p *pushState) openPushStream(pushID uint64) (quic.SendStream, error) {
+	p.mutex.Lock()
+	_, canceled := p.canceled[pushID]
+	p.mutex.Unlock()
+	if canceled {
+		return nil, nil
+	}
+
+	str, err := p.sess.OpenUniStreamSync(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	utils.WriteVarInt(buf, 0x1)
+	utils.WriteVarInt(buf, pushID)
+	if _, err := str.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if _, canceled := p.canceled[pushID]; canceled {
+		str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
+		return nil, nil
+	}
+	p.streams[pushID] = str
+	return str, nil
+}
+
+func (p *pushState) closePushStream(pushID uint64) {
+	p.mutex.Lock()
+	delete(p.streams, pushID)
+	p.mutex.Unlock()
+}
+
+// promisedPush 是一个已经发送了 PUSH_PROMISE 帧, 但尚未发送响应的推送
+type promisedPush struct {
+	pushID uint64
+	req    *http.Request
+}
+
+// handleUniStreams 接受客户端打开的单向 stream, 直到 sess 被关闭
+func (s *Server) handleUniStreams(conn *serverConn) {
+	for {
+		str, err := conn.sess.AcceptUniStream(context.Background())
+		if err != nil {
+			s.logger.Debugf("Accepting unidirectional stream failed: %s", err)
+			return
+		}
+		go s.handleUniStream(conn, str)
+	}
+}
+
+func (s *Server) handleUniStream(conn *serverConn, str quic.ReceiveStream) {
+	streamType, err := utils.ReadVarInt(&byteReaderImpl{str})
+	if err != nil {
+		return
+	}
+	switch streamType {
+	case 0x0: // control stream
+		if !conn.claimControlStream() {
+			conn.sess.CloseWithError(quic.ErrorCode(errorStreamCreationError), "duplicate control stream")
+			return
+		}
+		s.handleControlStream(conn, str)
+	case 0x1: // push stream
+		// only servers can push
+		conn.sess.CloseWithError(quic.ErrorCode(errorStreamCreationError), "client opened a push stream")
+	case 0x2, 0x3: // QPACK encoder and decoder streams
+		handleQPACKStream(conn.sess, streamType, str, conn.encoder, conn.decoder)
+	case webTransportUniStreamType:
+		if conn.webTransport == nil {
+			str.CancelRead(quic.ErrorCode(errorStreamCreationError))
+			return
+		}
+		conn.webTransport.handleUniStream(str)
+	default:
+		// unknown stream types are ignored
+		str.CancelRead(quic.ErrorCode(errorStreamCreationError))
+	}
+}
+
+// handleControlStream 处理客户端的控制 stream, 直到发生错误
+func (s *Server) handleControlStream(conn *serverConn, str quic.ReceiveStream) {
+	rerr := readControlStream(str, func(f *settingsFrame) requestError {
+		if err := conn.encoder.applySettings(f); err != nil {
+			return newConnError(errorInternalError, err)
+		}
+		return requestError{}
+	}, func(f frame) requestError {
+		var err error
+		switch f := f.(type) {
+		case *maxPushIDFrame:
+			err = conn.ps.handleMaxPushID(f.PushID)
+		case *cancelPushFrame:
+			err = conn.ps.handleCancelPush(f.PushID)
+		case *goAwayFrame:
+			// only servers send GOAWAY frames
+			return newConnError(errorFrameUnexpected, errors.New("client sent a GOAWAY frame"))
+		}
+		if err != nil {
+			return newConnError(errorIDError, err)
+		}
+		return requestError{}
+	})
+	s.logger.Debugf("Handling the control stream failed: %s", rerr.err)
+	closeWithRequestError(conn.sess, rerr)
+}
+
+// promise 在请求 stream 上为 target 发送 PUSH_PROMISE 帧, 并返回被承诺的请求.
+// target 可以是一个绝对路径, 或者与 req 同源的 https URL.
+func (s *Server) promise(ps *pushState, str io.Writer, req *http.Request, target string, opts *http.PushOptions) (*promisedPush, error) {
+	if opts == nil {
+		opts = &http.PushOptions{}
+	}
+	method := opts.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	if method != http.MethodGet && method != http.MethodHead {
+		return nil, errPushInvalidMethod
+	}
+
+	var path string
+	if strings.HasPrefix(target, "/") {
+		path = target
+	} else {
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme != "https" {
+			return nil, fmt.Errorf("http3: push target must use https, got %s", target)
+		}
+		if u.Host != req.Host {
+			return nil, fmt.Errorf("http3: push target %s doesn't match the request host %s", target, req.Host)
+		}
+		path = u.RequestURI()
+	}
+
+	hfs := []qpack.HeaderField{
+		{Name: ":method", Value: method},
+		{Name: ":scheme", Value: "https"},
+		{Name: ":authority", Value: req.Host},
+		{Name: ":path", Value: path},
+	}
+	for k, vv := range opts.Header {
+		if strings.HasPrefix(k, ":") {
+			return nil, fmt.Errorf("http3: push header %s must not be a pseudo header", k)
+		}
+		for _, v := range vv {
+			hfs = append(hfs, qpack.HeaderField{Name: strings.ToLower(k), Value: v})
+		}
+	}
+	pushReq, err := requestFromHeaders(hfs)
+	if err != nil {
+		return nil, err
+	}
+
+	pushID, err := ps.newPushID()
+	if err != nil {
+		return nil, err
+	}
+	// PUSH_PROMISE 帧中的头部只使用静态表编码, 客户端无需在请求 stream 上确认
+	buf := &bytes.Buffer{}
+	(&pushPromiseFrame{PushID: pushID, HeaderBlock: encodeStaticHeaders(hfs)}).Write(buf)
+	if _, err := str.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+	ps.claim(path)
+
+	pushReq.Body = http.NoBody
+	pushReq.RemoteAddr = req.RemoteAddr
+	pushReq = pushReq.WithContext(ps.sess.Context())
+	return &promisedPush{pushID: pushID, req: pushReq}, nil
+}
+
+// servePush 在新的 push stream 上发送被承诺的响应
+func (s *Server) servePush(ps *pushState, p *promisedPush) {
+	str, err := ps.openPushStream(p.pushID)
+	if err != nil {
+		s.logger.Debugf("Opening push stream for push ID %d failed: %s", p.pushID, err)
+		return
+	}
+	if str == nil { // the client canceled the push
+		return
+	}
+	defer ps.closePushStream(p.pushID)
+
+	responseWriter := newResponseWriter(str, s.logger)
+	responseWriter.encoder = ps.encoder
+	responseWriter.streamID = str.StreamID()
+	if s.serveHTTP(responseWriter, p.req) {
+		responseWriter.WriteHeader(500)
+	} else {
+		responseWriter.WriteHeader(200)
+		responseWriter.writeTrailers()
+	}
+	responseWriter.Flush()
+	str.Close()
+}
+
+// pushOrderTargets 返回在 PushOrder 中位于所请求资源之后, 且尚未在该连接上承诺过的路径.
+// 返回的路径都被标记为已承诺, 调用者需要对未能发送 PUSH_PROMISE 的路径调用 release.
+func (s *Server) pushOrderTargets(ps *pushState, req *http.Request) []string {
+	if ps == nil || len(s.PushOrder) == 0 || req.Method != http.MethodGet {
+		return nil
+	}
+	name := strings.TrimPrefix(req.URL.Path, "/")
+	if name == "" {
+		name = "index.html"
+	}
+	var targets []string
+	found := false
+	for _, n := range s.PushOrder {
+		if !found {
+			found = n == name
+			continue
+		}
+		if path := "/" + n; ps.claim(path) {
+			targets = append(targets, path)
+		}
+	}
+	return targets
+}
+
+// pushInOrder 为 PushOrder 中位于所请求资源之后的所有资源发送 PUSH_PROMISE 帧,
+// 然后按照顺序依次推送这些资源
+func (s *Server) pushInOrder(ps *pushState, str io.Writer, req *http.Request) {
+	targets := s.pushOrderTargets(ps, req)
+	if len(targets) == 0 {
+		return
+	}
+	select {
+	case <-ps.maxPushIDReceived:
+	case <-time.After(maxPushIDDelay):
+	}
+	var pushes []*promisedPush
+	for i, target := range targets {
+		p, err := s.promise(ps, str, req, target, nil)
+		if err != nil {
+			s.logger.Debugf("Not pushing %s: %s", target, err)
+			ps.release(targets[i:]...)
+			break
+		}
+		pushes = append(pushes, p)
+	}
+	if len(pushes) == 0 {
+		return
+	}
+	ps.pending.Add(1)
+	go func() {
+		defer ps.pending.Done()
+		for _, p := range pushes {
+			s.servePush(ps, p)
+		}
+	}()
+}
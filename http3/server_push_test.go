@@ -0,0 +1,226 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// pushTestSession is a quic.Session that can be used as a map key.
+// Calling any method other than Context panics.
+type pushTestSession struct {
+	quic.Session
+}
+
+func (s *pushTestSession) Context() context.Context { return context.Background() }
+
+var _ = Describe("Server push", func() {
+	var (
+		s   *Server
+		ps  *pushState
+		req *http.Request
+	)
+
+	decodePushPromise := func(r io.Reader) (uint64, map[string]string) {
+		frame, err := parseNextFrame(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(BeAssignableToTypeOf(&pushPromiseFrame{}))
+		f := frame.(*pushPromiseFrame)
+		hfs, err := qpack.NewDecoder(nil).DecodeFull(f.HeaderBlock)
+		Expect(err).ToNot(HaveOccurred())
+		fields := make(map[string]string)
+		for _, hf := range hfs {
+			fields[hf.Name] = hf.Value
+		}
+		return f.PushID, fields
+	}
+
+	BeforeEach(func() {
+		s = &Server{
+			Server: &http.Server{},
+			logger: utils.DefaultLogger,
+		}
+		ps = newPushState(&pushTestSession{}, nil)
+		var err error
+		req, err = http.NewRequest(http.MethodGet, "https://www.example.com/", nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("doesn't push before receiving a MAX_PUSH_ID frame", func() {
+		_, err := s.promise(ps, &bytes.Buffer{}, req, "/style.css", nil)
+		Expect(err).To(MatchError(errPushDisabled))
+	})
+
+	It("sends a PUSH_PROMISE frame", func() {
+		Expect(ps.handleMaxPushID(10)).To(Succeed())
+		buf := &bytes.Buffer{}
+		p, err := s.promise(ps, buf, req, "/style.css", &http.PushOptions{
+			Header: http.Header{"Accept": []string{"text/css"}},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.pushID).To(BeZero())
+		Expect(p.req.Method).To(Equal(http.MethodGet))
+		Expect(p.req.URL.Path).To(Equal("/style.css"))
+		Expect(p.req.Host).To(Equal("www.example.com"))
+		Expect(p.req.Header.Get("Accept")).To(Equal("text/css"))
+		pushID, fields := decodePushPromise(buf)
+		Expect(pushID).To(BeZero())
+		Expect(fields).To(Equal(map[string]string{
+			":method":    "GET",
+			":scheme":    "https",
+			":authority": "www.example.com",
+			":path":      "/style.css",
+			"accept":     "text/css",
+		}))
+	})
+
+	It("accepts absolute URLs on the same host", func() {
+		Expect(ps.handleMaxPushID(10)).To(Succeed())
+		buf := &bytes.Buffer{}
+		_, err := s.promise(ps, buf, req, "https://www.example.com/script.js?v=2", nil)
+		Expect(err).ToNot(HaveOccurred())
+		_, fields := decodePushPromise(buf)
+		Expect(fields).To(HaveKeyWithValue(":path", "/script.js?v=2"))
+	})
+
+	It("rejects invalid push targets", func() {
+		Expect(ps.handleMaxPushID(10)).To(Succeed())
+		_, err := s.promise(ps, &bytes.Buffer{}, req, "https://www.example.org/script.js", nil)
+		Expect(err).To(MatchError("http3: push target https://www.example.org/script.js doesn't match the request host www.example.com"))
+		_, err = s.promise(ps, &bytes.Buffer{}, req, "http://www.example.com/script.js", nil)
+		Expect(err).To(MatchError("http3: push target must use https, got http://www.example.com/script.js"))
+		_, err = s.promise(ps, &bytes.Buffer{}, req, "/form", &http.PushOptions{Method: http.MethodPost})
+		Expect(err).To(MatchError(errPushInvalidMethod))
+	})
+
+	It("uses consecutive push IDs, up to the maximum push ID", func() {
+		Expect(ps.handleMaxPushID(1)).To(Succeed())
+		buf := &bytes.Buffer{}
+		p, err := s.promise(ps, buf, req, "/foo", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.pushID).To(BeZero())
+		p, err = s.promise(ps, buf, req, "/bar", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.pushID).To(Equal(uint64(1)))
+		_, err = s.promise(ps, buf, req, "/baz", nil)
+		Expect(err).To(MatchError(errPushLimitReached))
+		Expect(ps.handleMaxPushID(2)).To(Succeed())
+		p, err = s.promise(ps, buf, req, "/baz", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.pushID).To(Equal(uint64(2)))
+	})
+
+	It("errors when the client reduces the maximum push ID", func() {
+		Expect(ps.handleMaxPushID(10)).To(Succeed())
+		Expect(ps.handleMaxPushID(10)).To(Succeed())
+		Expect(ps.handleMaxPushID(9)).To(MatchError("MAX_PUSH_ID reduced from 10 to 9"))
+	})
+
+	It("errors when the client cancels a push that wasn't promised", func() {
+		Expect(ps.handleMaxPushID(10)).To(Succeed())
+		Expect(ps.handleCancelPush(0)).To(MatchError("CANCEL_PUSH for push ID 0 that was not promised"))
+	})
+
+	It("resets the push stream when the client cancels a push", func() {
+		Expect(ps.handleMaxPushID(10)).To(Succeed())
+		_, err := s.promise(ps, &bytes.Buffer{}, req, "/foo", nil)
+		Expect(err).ToNot(HaveOccurred())
+		str := mockquic.NewMockStream(mockCtrl)
+		ps.streams[0] = str
+		str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestCanceled))
+		Expect(ps.handleCancelPush(0)).To(Succeed())
+		// the push stream is not opened any more
+		pushStr, err := ps.openPushStream(0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pushStr).To(BeNil())
+	})
+
+	It("returns http.ErrNotSupported when pushing from a pushed response", func() {
+		w := newResponseWriter(&bytes.Buffer{}, utils.DefaultLogger)
+		Expect(w.Push("/foo", nil)).To(MatchError(http.ErrNotSupported))
+	})
+
+	Context("pushing in transmission order", func() {
+		BeforeEach(func() {
+			s.PushOrder = []string{"index.html", "style.css", "app.js", "logo.png"}
+		})
+
+		It("pushes the resources following the requested one", func() {
+			Expect(s.pushOrderTargets(ps, req)).To(Equal([]string{"/style.css", "/app.js", "/logo.png"}))
+		})
+
+		It("doesn't push resources twice on the same connection", func() {
+			r, err := http.NewRequest(http.MethodGet, "https://www.example.com/app.js", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.pushOrderTargets(ps, r)).To(Equal([]string{"/logo.png"}))
+			Expect(s.pushOrderTargets(ps, req)).To(Equal([]string{"/style.css", "/app.js"}))
+			Expect(s.pushOrderTargets(ps, req)).To(BeEmpty())
+		})
+
+		It("doesn't push for resources that are not in the list", func() {
+			r, err := http.NewRequest(http.MethodGet, "https://www.example.com/favicon.ico", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.pushOrderTargets(ps, r)).To(BeEmpty())
+		})
+
+		It("doesn't push for POST requests", func() {
+			r, err := http.NewRequest(http.MethodPost, "https://www.example.com/", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.pushOrderTargets(ps, r)).To(BeEmpty())
+		})
+
+		It("writes PUSH_PROMISE frames in order", func() {
+			sess := &pushOrderTestSession{opened: make(chan struct{}, 3)}
+			ps = newPushState(sess, nil)
+			Expect(ps.handleMaxPushID(10)).To(Succeed())
+			buf := &bytes.Buffer{}
+			s.pushInOrder(ps, buf, req)
+			pushID, fields := decodePushPromise(buf)
+			Expect(pushID).To(BeZero())
+			Expect(fields).To(HaveKeyWithValue(":path", "/style.css"))
+			pushID, fields = decodePushPromise(buf)
+			Expect(pushID).To(Equal(uint64(1)))
+			Expect(fields).To(HaveKeyWithValue(":path", "/app.js"))
+			pushID, fields = decodePushPromise(buf)
+			Expect(pushID).To(Equal(uint64(2)))
+			Expect(fields).To(HaveKeyWithValue(":path", "/logo.png"))
+			Expect(buf.Len()).To(BeZero())
+			// the pushed responses are sent in a separate go routine
+			Eventually(sess.opened).Should(HaveLen(3))
+		})
+
+		It("releases the resources that couldn't be promised", func() {
+			sess := &pushOrderTestSession{opened: make(chan struct{}, 3)}
+			ps = newPushState(sess, nil)
+			Expect(ps.handleMaxPushID(0)).To(Succeed())
+			buf := &bytes.Buffer{}
+			s.pushInOrder(ps, buf, req)
+			_, fields := decodePushPromise(buf)
+			Expect(fields).To(HaveKeyWithValue(":path", "/style.css"))
+			Expect(buf.Len()).To(BeZero())
+			Eventually(sess.opened).Should(HaveLen(1))
+			// the push limit was reached, but the remaining resources can be pushed later
+			Expect(s.pushOrderTargets(ps, req)).To(Equal([]string{"/app.js", "/logo.png"}))
+		})
+	})
+})
+
+// pushOrderTestSession fails to open push streams, and records the attempts.
+type pushOrderTestSession struct {
+	pushTestSession
+	opened chan struct{}
+}
+
+func (s *pushOrderTestSession) OpenUniStreamSync(context.Context) (quic.SendStream, error) {
+	s.opened <- struct{}{}
+	return nil, io.ErrClosedPipe
+}
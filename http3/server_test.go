@@ -7,6 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	mrand "math/rand"
 	"net"
 	"net/http"
 	"time"
@@ -14,6 +16,7 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go"
 	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/testdata"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/marten-seemann/qpack"
@@ -44,7 +47,7 @@ var _ = Describe("Server", func() {
 
 	Context("handling requests", func() {
 		var (
-			qpackDecoder       *qpack.Decoder
+			qpackDecoder       *qpackDecoder
 			str                *mockquic.MockStream
 			exampleGetRequest  *http.Request
 			examplePostRequest *http.Request
@@ -73,13 +76,14 @@ var _ = Describe("Server", func() {
 		encodeRequest := func(req *http.Request) []byte {
 			buf := &bytes.Buffer{}
 			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().AnyTimes()
 			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
 				return buf.Write(p)
 			}).AnyTimes()
 			closed := make(chan struct{})
 			str.EXPECT().Close().Do(func() { close(closed) })
 			rw := newRequestWriter(utils.DefaultLogger)
-			Expect(rw.WriteRequest(str, req, false)).To(Succeed())
+			Expect(rw.WriteRequest(str, req, false, nil)).To(Succeed())
 			Eventually(closed).Should(BeClosed())
 			return buf.Bytes()
 		}
@@ -101,8 +105,9 @@ var _ = Describe("Server", func() {
 			examplePostRequest, err = http.NewRequest("POST", "https://www.example.com", bytes.NewReader([]byte("foobar")))
 			Expect(err).ToNot(HaveOccurred())
 
-			qpackDecoder = qpack.NewDecoder(nil)
+			qpackDecoder = nil
 			str = mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().AnyTimes()
 		})
 
 		It("calls the HTTP handler function", func() {
@@ -117,7 +122,7 @@ var _ = Describe("Server", func() {
 				return len(p), nil
 			}).AnyTimes()
 
-			Expect(s.handleRequest(str, qpackDecoder, nil)).To(Equal(requestError{}))
+			Expect(s.handleRequest(str, nil, nil, qpackDecoder, nil, nil)).To(Equal(requestError{}))
 			var req *http.Request
 			Eventually(requestChan).Should(Receive(&req))
 			Expect(req.Host).To(Equal("www.example.com"))
@@ -133,7 +138,7 @@ var _ = Describe("Server", func() {
 				return responseBuf.Write(p)
 			}).AnyTimes()
 
-			serr := s.handleRequest(str, qpackDecoder, nil)
+			serr := s.handleRequest(str, nil, nil, qpackDecoder, nil, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
@@ -152,12 +157,190 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(str, qpackDecoder, nil)
+			serr := s.handleRequest(str, nil, nil, qpackDecoder, nil, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"500"}))
 		})
 
+		It("uses the status code set by the handler", func() {
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+				w.WriteHeader(http.StatusOK) // ignored
+			})
+
+			responseBuf := &bytes.Buffer{}
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return responseBuf.Write(p)
+			}).AnyTimes()
+
+			serr := s.handleRequest(str, nil, nil, qpackDecoder, nil, nil)
+			Expect(serr.err).ToNot(HaveOccurred())
+			hfs := decodeHeader(responseBuf)
+			Expect(hfs).To(HaveKeyWithValue(":status", []string{"418"}))
+		})
+
+		It("returns 500 if the handler uses an invalid status code", func() {
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(42)
+			})
+
+			responseBuf := &bytes.Buffer{}
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return responseBuf.Write(p)
+			}).AnyTimes()
+			str.EXPECT().CancelRead(gomock.Any())
+
+			serr := s.handleRequest(str, nil, nil, qpackDecoder, nil, nil)
+			Expect(serr.err).ToNot(HaveOccurred())
+			hfs := decodeHeader(responseBuf)
+			Expect(hfs).To(HaveKeyWithValue(":status", []string{"500"}))
+		})
+
+		Context("malformed requests", func() {
+			encodeHeaders := func(hfs []qpack.HeaderField) []byte {
+				headerBlock := &bytes.Buffer{}
+				enc := qpack.NewEncoder(headerBlock)
+				for _, hf := range hfs {
+					Expect(enc.WriteField(hf)).To(Succeed())
+				}
+				buf := &bytes.Buffer{}
+				(&headersFrame{Length: uint64(headerBlock.Len())}).Write(buf)
+				buf.Write(headerBlock.Bytes())
+				return buf.Bytes()
+			}
+
+			BeforeEach(func() {
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					Fail("Handler should not be called.")
+				})
+			})
+
+			It("rejects requests without the required pseudo headers", func() {
+				setRequest(encodeHeaders([]qpack.HeaderField{{Name: ":method", Value: "GET"}}))
+				serr := s.handleRequest(str, nil, nil, qpackDecoder, nil, nil)
+				Expect(serr.err).To(MatchError(":path, :authority and :method must not be empty"))
+				Expect(serr.streamErr).To(Equal(errorGeneralProtocolError))
+				Expect(serr.connErr).To(BeZero())
+			})
+
+			It("rejects requests with pseudo headers after regular headers", func() {
+				setRequest(encodeHeaders([]qpack.HeaderField{
+					{Name: ":method", Value: "GET"},
+					{Name: ":authority", Value: "www.example.com"},
+					{Name: "foo", Value: "bar"},
+					{Name: ":path", Value: "/"},
+				}))
+				serr := s.handleRequest(str, nil, nil, qpackDecoder, nil, nil)
+				Expect(serr.err).To(MatchError("pseudo header :path after a regular header field"))
+				Expect(serr.streamErr).To(Equal(errorGeneralProtocolError))
+			})
+
+			It("rejects truncated HEADERS frames", func() {
+				data := encodeRequest(exampleGetRequest)
+				setRequest(data[:len(data)-1])
+				serr := s.handleRequest(str, nil, nil, qpackDecoder, nil, nil)
+				Expect(serr.err).To(HaveOccurred())
+				Expect(serr.streamErr).To(Equal(errorRequestIncomplete))
+			})
+
+			It("rejects empty request streams", func() {
+				setRequest(nil)
+				serr := s.handleRequest(str, nil, nil, qpackDecoder, nil, nil)
+				Expect(serr.err).To(MatchError(io.EOF))
+				Expect(serr.streamErr).To(Equal(errorRequestIncomplete))
+			})
+
+			It("rejects HEADERS frames larger than the maximum header size, without reading them", func() {
+				buf := &bytes.Buffer{}
+				(&headersFrame{Length: 1 << 60}).Write(buf)
+				setRequest(buf.Bytes())
+				serr := s.handleRequest(str, nil, nil, qpackDecoder, nil, nil)
+				Expect(serr.err).To(MatchError(fmt.Sprintf("HEADERS frame too large: %d bytes (max: %d)", 1<<60, http.DefaultMaxHeaderBytes)))
+				Expect(serr.streamErr).To(Equal(errorExcessiveLoad))
+			})
+
+			It("closes the connection if the header block can't be decoded", func() {
+				buf := &bytes.Buffer{}
+				(&headersFrame{Length: 4}).Write(buf)
+				buf.Write([]byte{0xff, 0xff, 0xff, 0xff})
+				setRequest(buf.Bytes())
+				serr := s.handleRequest(str, nil, nil, qpackDecoder, nil, nil)
+				Expect(serr.err).To(HaveOccurred())
+				Expect(serr.connErr).To(Equal(errorQPACKDecompressionFailed))
+			})
+
+			It("closes the connection if the client sends a PUSH_PROMISE frame", func() {
+				buf := &bytes.Buffer{}
+				(&pushPromiseFrame{PushID: 1, HeaderBlock: []byte("foobar")}).Write(buf)
+				buf.Write(encodeRequest(exampleGetRequest))
+				setRequest(buf.Bytes())
+				serr := s.handleRequest(str, nil, nil, qpackDecoder, nil, nil)
+				Expect(serr.err).To(MatchError("expected first frame to be a HEADERS frame"))
+				Expect(serr.connErr).To(Equal(errorFrameUnexpected))
+			})
+
+			It("closes the connection if the request body contains unexpected frames", func() {
+				handlerErr := make(chan error, 1)
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					_, err := ioutil.ReadAll(r.Body)
+					handlerErr <- err
+				})
+				buf := bytes.NewBuffer(encodeRequest(examplePostRequest))
+				(&settingsFrame{}).Write(buf)
+				setRequest(buf.Bytes())
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any()).AnyTimes()
+				var frameErrorCalled bool
+				serr := s.handleRequest(str, nil, nil, qpackDecoder, nil, func() { frameErrorCalled = true })
+				Expect(serr.err).ToNot(HaveOccurred())
+				Expect(frameErrorCalled).To(BeTrue())
+				Expect(handlerErr).To(Receive(MatchError("peer sent an unexpected frame: *http3.settingsFrame")))
+			})
+
+			It("doesn't panic on random input", func() {
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					ioutil.ReadAll(r.Body)
+				})
+				str.EXPECT().Context().Return(reqContext).AnyTimes()
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any()).AnyTimes()
+				var buf *bytes.Buffer
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					if buf.Len() == 0 {
+						return 0, io.EOF
+					}
+					return buf.Read(p)
+				}).AnyTimes()
+
+				r := mrand.New(mrand.NewSource(GinkgoRandomSeed()))
+				request := encodeRequest(exampleGetRequest)
+				for i := 0; i < 1000; i++ {
+					data := make([]byte, r.Intn(len(request)))
+					if i%2 == 0 {
+						r.Read(data)
+					} else {
+						// flip some bytes in a valid request
+						copy(data, request)
+						for j := 0; j < 3 && len(data) > 0; j++ {
+							data[r.Intn(len(data))] ^= byte(r.Intn(255) + 1)
+						}
+					}
+					buf = bytes.NewBuffer(data)
+					Expect(func() { s.handleRequest(str, nil, nil, nil, nil, func() {}) }).ToNot(Panic())
+				}
+			})
+		})
+
 		Context("stream- and connection-level errors", func() {
 			var sess *mockquic.MockSession
 
@@ -168,6 +351,8 @@ var _ = Describe("Server", func() {
 				sess.EXPECT().OpenUniStream().Return(controlStr, nil)
 				sess.EXPECT().AcceptStream(gomock.Any()).Return(str, nil)
 				sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done"))
+				sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done")).AnyTimes()
+				str.EXPECT().StreamID().AnyTimes()
 			})
 
 			It("cancels reading when client sends a body in GET request", func() {
@@ -197,7 +382,7 @@ var _ = Describe("Server", func() {
 			})
 
 			It("errors when the client sends a too large header frame", func() {
-				s.Server.MaxHeaderBytes = 42
+				s.Server.MaxHeaderBytes = 20
 				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 					Fail("Handler should not be called.")
 				})
@@ -212,7 +397,7 @@ var _ = Describe("Server", func() {
 				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
 					return responseBuf.Write(p)
 				}).AnyTimes()
-				str.EXPECT().CancelWrite(quic.ErrorCode(errorFrameError)).Do(func(quic.ErrorCode) { close(done) })
+				str.EXPECT().CancelWrite(quic.ErrorCode(errorExcessiveLoad)).Do(func(quic.ErrorCode) { close(done) })
 
 				s.handleConn(sess)
 				Eventually(done).Should(BeClosed())
@@ -261,7 +446,7 @@ var _ = Describe("Server", func() {
 					close(handlerCalled)
 				})
 
-				url := bytes.Repeat([]byte{'a'}, http.DefaultMaxHeaderBytes+1)
+				url := bytes.Repeat([]byte{'a'}, 2*http.DefaultMaxHeaderBytes) // long enough even after Huffman encoding
 				req, err := http.NewRequest(http.MethodGet, "https://"+string(url), nil)
 				Expect(err).ToNot(HaveOccurred())
 				setRequest(encodeRequest(req))
@@ -270,7 +455,7 @@ var _ = Describe("Server", func() {
 					return len(p), nil
 				}).AnyTimes()
 				done := make(chan struct{})
-				str.EXPECT().CancelWrite(quic.ErrorCode(errorFrameError)).Do(func(quic.ErrorCode) { close(done) })
+				str.EXPECT().CancelWrite(quic.ErrorCode(errorExcessiveLoad)).Do(func(quic.ErrorCode) { close(done) })
 
 				s.handleConn(sess)
 				Eventually(done).Should(BeClosed())
@@ -294,7 +479,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.ErrorCode(errorEarlyResponse))
 
-			serr := s.handleRequest(str, qpackDecoder, nil)
+			serr := s.handleRequest(str, nil, nil, qpackDecoder, nil, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
@@ -317,10 +502,105 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.ErrorCode(errorEarlyResponse))
 
-			serr := s.handleRequest(str, qpackDecoder, nil)
+			serr := s.handleRequest(str, nil, nil, qpackDecoder, nil, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
+
+		It("passes the request trailers to the handler", func() {
+			trailerChan := make(chan http.Header, 1)
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.Header).ToNot(HaveKey("Trailer"))
+				Expect(r.Trailer).To(HaveKeyWithValue("Checksum", BeNil()))
+				body, err := ioutil.ReadAll(r.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(body).To(Equal([]byte("foobar")))
+				trailerChan <- r.Trailer
+			})
+
+			req, err := http.NewRequest("POST", "https://www.example.com", bytes.NewReader([]byte("foobar")))
+			Expect(err).ToNot(HaveOccurred())
+			req.Trailer = http.Header{"Checksum": []string{"deadbeef"}}
+			setRequest(encodeRequest(req))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+
+			serr := s.handleRequest(str, nil, nil, qpackDecoder, nil, nil)
+			Expect(serr.err).ToNot(HaveOccurred())
+			var trailer http.Header
+			Eventually(trailerChan).Should(Receive(&trailer))
+			Expect(trailer).To(Equal(http.Header{"Checksum": []string{"deadbeef"}}))
+		})
+
+		It("sends the response trailers after the body", func() {
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Trailer", "Checksum")
+				w.Write([]byte("foobar"))
+				w.Header().Set("Checksum", "deadbeef")
+				w.Header().Set(http.TrailerPrefix+"Server-Timing", "db;dur=42")
+			})
+
+			responseBuf := &bytes.Buffer{}
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return responseBuf.Write(p)
+			}).AnyTimes()
+
+			serr := s.handleRequest(str, nil, nil, qpackDecoder, nil, nil)
+			Expect(serr.err).ToNot(HaveOccurred())
+			hfs := decodeHeader(responseBuf)
+			Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
+			Expect(hfs).To(HaveKeyWithValue("trailer", []string{"Checksum"}))
+			frame, err := parseNextFrame(responseBuf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&dataFrame{Length: 6}))
+			responseBuf.Next(6)
+			Expect(decodeHeader(responseBuf)).To(Equal(map[string][]string{
+				"checksum":      {"deadbeef"},
+				"server-timing": {"db;dur=42"},
+			}))
+		})
+
+		It("sends flushed data before the handler returns", func() {
+			handlerDone := make(chan struct{})
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte("data: foo\n\n"))
+				w.(http.Flusher).Flush()
+				<-handlerDone
+			})
+
+			written := make(chan []byte, 10)
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				written <- append([]byte{}, p...)
+				return len(p), nil
+			}).AnyTimes()
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				serr := s.handleRequest(str, nil, nil, qpackDecoder, nil, nil)
+				Expect(serr.err).ToNot(HaveOccurred())
+			}()
+			var response []byte
+			Eventually(written).Should(Receive(&response))
+			responseBuf := bytes.NewBuffer(response)
+			Expect(decodeHeader(responseBuf)).To(HaveKeyWithValue("content-type", []string{"text/event-stream"}))
+			frame, err := parseNextFrame(responseBuf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&dataFrame{Length: 11}))
+			Expect(responseBuf.Bytes()).To(Equal([]byte("data: foo\n\n")))
+			Consistently(done).ShouldNot(BeClosed())
+			close(handlerDone)
+			Eventually(done).Should(BeClosed())
+		})
 	})
 
 	Context("setting http headers", func() {
@@ -328,12 +608,12 @@ var _ = Describe("Server", func() {
 
 		getExpectedHeader := func() http.Header {
 			return http.Header{
-				"Alt-Svc": {fmt.Sprintf(`%s=":443"; ma=2592000`, nextProtoH3)},
+				"Alt-Svc": {fmt.Sprintf(`%s=":443"; ma=2592000,%s=":443"; ma=2592000,%s=":443"; ma=2592000`, nextProtoH3, nextProtoH3Draft29, nextProtoH3Draft24)},
 			}
 		}
 
 		BeforeEach(func() {
-			Expect(getExpectedHeader()).To(Equal(http.Header{"Alt-Svc": {nextProtoH3 + `=":443"; ma=2592000`}}))
+			Expect(getExpectedHeader()).To(Equal(http.Header{"Alt-Svc": {`h3=":443"; ma=2592000,h3-29=":443"; ma=2592000,h3-24=":443"; ma=2592000`}}))
 			expected = getExpectedHeader()
 		})
 
@@ -358,6 +638,14 @@ var _ = Describe("Server", func() {
 			Expect(hdr).To(Equal(expected))
 		})
 
+		It("only advertises the configured QUIC versions", func() {
+			s.Server.Addr = ":443"
+			s.QuicConfig = &quic.Config{Versions: []quic.VersionNumber{protocol.VersionDraft29}}
+			hdr := http.Header{}
+			Expect(s.SetQuicHeaders(hdr)).To(Succeed())
+			Expect(hdr).To(Equal(http.Header{"Alt-Svc": {`h3-29=":443"; ma=2592000`}}))
+		})
+
 		It("works multiple times", func() {
 			s.Server.Addr = ":https"
 			hdr := http.Header{}
@@ -506,7 +794,7 @@ var _ = Describe("Server", func() {
 			}
 			s.TLSConfig = tlsConf
 			Expect(s.ListenAndServe()).To(HaveOccurred())
-			Expect(receivedConf.NextProtos).To(Equal([]string{nextProtoH3}))
+			Expect(receivedConf.NextProtos).To(Equal([]string{nextProtoH3, nextProtoH3Draft29, nextProtoH3Draft24}))
 			// make sure the original tls.Config was not modified
 			Expect(tlsConf.NextProtos).To(Equal([]string{"foo", "bar"}))
 		})
@@ -518,7 +806,25 @@ var _ = Describe("Server", func() {
 				return nil, errors.New("listen err")
 			}
 			Expect(s.ListenAndServe()).To(HaveOccurred())
-			Expect(receivedConf.NextProtos).To(Equal([]string{nextProtoH3}))
+			Expect(receivedConf.NextProtos).To(Equal([]string{nextProtoH3, nextProtoH3Draft29, nextProtoH3Draft24}))
+		})
+
+		It("selects the ALPN based on the QUIC version", func() {
+			var receivedConf *tls.Config
+			quicListenAddr = func(addr string, tlsConf *tls.Config, _ *quic.Config) (quic.Listener, error) {
+				receivedConf = tlsConf
+				return nil, errors.New("listen err")
+			}
+			Expect(s.ListenAndServe()).To(HaveOccurred())
+			for v, proto := range map[protocol.VersionNumber]string{
+				protocol.Version1:             "h3",
+				protocol.VersionDraft29:       "h3-29",
+				protocol.VersionMilestone0_14: "h3-24",
+			} {
+				conf, err := receivedConf.GetConfigForClient(&tls.ClientHelloInfo{Conn: &connWithVersion{version: v}})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(conf.NextProtos).To(Equal([]string{proto}))
+			}
 		})
 
 		It("sets the ALPN for tls.Configs returned by the tls.GetConfigForClient", func() {
@@ -536,9 +842,9 @@ var _ = Describe("Server", func() {
 			s.TLSConfig = tlsConf
 			Expect(s.ListenAndServe()).To(HaveOccurred())
 			// check that the config used by QUIC uses the h3 ALPN
-			conf, err := receivedConf.GetConfigForClient(&tls.ClientHelloInfo{})
+			conf, err := receivedConf.GetConfigForClient(&tls.ClientHelloInfo{Conn: &connWithVersion{version: protocol.VersionDraft29}})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(conf.NextProtos).To(Equal([]string{nextProtoH3}))
+			Expect(conf.NextProtos).To(Equal([]string{nextProtoH3Draft29}))
 			// check that the original config was not modified
 			conf, err = tlsConf.GetConfigForClient(&tls.ClientHelloInfo{})
 			Expect(err).ToNot(HaveOccurred())
@@ -561,9 +867,9 @@ var _ = Describe("Server", func() {
 			s.TLSConfig = tlsConf
 			Expect(s.ListenAndServe()).To(HaveOccurred())
 			// check that the config used by QUIC uses the h3 ALPN
-			conf, err := receivedConf.GetConfigForClient(&tls.ClientHelloInfo{})
+			conf, err := receivedConf.GetConfigForClient(&tls.ClientHelloInfo{Conn: &connWithVersion{version: protocol.VersionDraft29}})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(conf.NextProtos).To(Equal([]string{nextProtoH3}))
+			Expect(conf.NextProtos).To(Equal([]string{nextProtoH3Draft29}))
 			// check that the original config was not modified
 			conf, err = tlsConf.GetConfigForClient(&tls.ClientHelloInfo{})
 			Expect(err).ToNot(HaveOccurred())
@@ -575,6 +881,160 @@ var _ = Describe("Server", func() {
 		Expect(s.CloseGracefully(0)).To(Succeed())
 	})
 
+	Context("control streams", func() {
+		var (
+			sess *mockquic.MockSession
+			conn *serverConn
+		)
+
+		getUniStream := func(data []byte) *mockquic.MockStream {
+			buf := bytes.NewBuffer(data)
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			return str
+		}
+
+		BeforeEach(func() {
+			sess = mockquic.NewMockSession(mockCtrl)
+			conn = newServerConn(sess, nil)
+		})
+
+		It("handles MAX_PUSH_ID frames", func() {
+			buf := &bytes.Buffer{}
+			buf.WriteByte(0x0) // stream type
+			(&settingsFrame{}).Write(buf)
+			(&maxPushIDFrame{PushID: 10}).Write(buf)
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorClosedCriticalStream), gomock.Any())
+			s.handleUniStream(conn, getUniStream(buf.Bytes()))
+			Expect(conn.ps.maxPushIDReceived).To(BeClosed())
+			Expect(conn.ps.maxPushID).To(Equal(uint64(10)))
+		})
+
+		It("closes the session when the client doesn't send a SETTINGS frame", func() {
+			buf := &bytes.Buffer{}
+			buf.WriteByte(0x0) // stream type
+			(&maxPushIDFrame{PushID: 10}).Write(buf)
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorMissingSettings), gomock.Any())
+			s.handleUniStream(conn, getUniStream(buf.Bytes()))
+		})
+
+		It("closes the session when the client opens a second control stream", func() {
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorClosedCriticalStream), gomock.Any())
+			s.handleUniStream(conn, getUniStream([]byte{0x0, 0x4, 0x0})) // an empty SETTINGS frame
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorStreamCreationError), gomock.Any())
+			s.handleUniStream(conn, getUniStream([]byte{0x0}))
+		})
+
+		It("closes the session when the client sends a GOAWAY frame", func() {
+			buf := &bytes.Buffer{}
+			buf.WriteByte(0x0) // stream type
+			(&settingsFrame{}).Write(buf)
+			(&goAwayFrame{StreamID: 0}).Write(buf)
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorFrameUnexpected), gomock.Any())
+			s.handleUniStream(conn, getUniStream(buf.Bytes()))
+		})
+
+		It("ignores unknown stream types", func() {
+			str := getUniStream([]byte{0x21})
+			str.EXPECT().CancelRead(quic.ErrorCode(errorStreamCreationError))
+			s.handleUniStream(conn, str)
+		})
+	})
+
+	Context("graceful shutdown", func() {
+		var (
+			sess       *mockquic.MockSession
+			controlStr *mockquic.MockStream
+			conn       *serverConn
+		)
+
+		expectGoAway := func(id quic.StreamID) {
+			buf := &bytes.Buffer{}
+			(&goAwayFrame{StreamID: id}).Write(buf)
+			controlStr.EXPECT().Write(buf.Bytes())
+		}
+
+		BeforeEach(func() {
+			sess = mockquic.NewMockSession(mockCtrl)
+			controlStr = mockquic.NewMockStream(mockCtrl)
+			conn = newServerConn(sess, nil)
+			conn.controlStr = controlStr
+		})
+
+		It("stops accepting requests after sending a GOAWAY frame", func() {
+			Expect(conn.startRequest(0)).To(BeTrue())
+			Expect(conn.startRequest(4)).To(BeTrue())
+			expectGoAway(8)
+			conn.goAway()
+			Expect(conn.startRequest(8)).To(BeFalse())
+			// GOAWAY is only sent once
+			conn.goAway()
+		})
+
+		It("sends a GOAWAY frame with stream ID 0 if no request was accepted", func() {
+			expectGoAway(0)
+			conn.goAway()
+			Expect(conn.startRequest(0)).To(BeFalse())
+		})
+
+		It("rejects requests on new sessions after the server was closed", func() {
+			s.closed.Set(true)
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().StreamID().AnyTimes()
+			str.EXPECT().CancelRead(quic.ErrorCode(errorRequestRejected))
+			str.EXPECT().CancelWrite(quic.ErrorCode(errorRequestRejected))
+			controlStr.EXPECT().Write(gomock.Any()) // SETTINGS
+			expectGoAway(0)
+			sess.EXPECT().OpenUniStream().Return(controlStr, nil)
+			sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done")).AnyTimes()
+			sess.EXPECT().AcceptStream(gomock.Any()).Return(str, nil)
+			sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done"))
+			s.handleConn(sess)
+		})
+
+		It("waits for running requests before closing sessions", func() {
+			Expect(conn.startRequest(0)).To(BeTrue())
+			s.addConn(conn)
+			expectGoAway(4)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				Expect(s.CloseGracefully(time.Hour)).To(Succeed())
+				close(done)
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorNoError), gomock.Any())
+			conn.requests.Done()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("waits for running pushes before closing sessions", func() {
+			Expect(conn.startRequest(0)).To(BeTrue())
+			conn.ps.pending.Add(1)
+			conn.requests.Done()
+			s.addConn(conn)
+			expectGoAway(4)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				Expect(s.CloseGracefully(time.Hour)).To(Succeed())
+				close(done)
+			}()
+			Consistently(done).ShouldNot(BeClosed())
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorNoError), gomock.Any())
+			conn.ps.pending.Done()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("closes sessions when the timeout expires", func() {
+			Expect(conn.startRequest(0)).To(BeTrue())
+			s.addConn(conn)
+			expectGoAway(4)
+			sess.EXPECT().CloseWithError(quic.ErrorCode(errorNoError), gomock.Any())
+			Expect(s.CloseGracefully(20 * time.Millisecond)).To(Succeed())
+		})
+	})
+
 	It("errors when listening fails", func() {
 		testErr := errors.New("listen error")
 		quicListenAddr = func(addr string, tlsConf *tls.Config, config *quic.Config) (quic.Listener, error) {
@@ -584,3 +1044,10 @@ var _ = Describe("Server", func() {
 		Expect(ListenAndServeQUIC("", fullpem, privkey, nil)).To(MatchError(testErr))
 	})
 })
+
+type connWithVersion struct {
+	net.Conn
+	version protocol.VersionNumber
+}
+
+func (c *connWithVersion) GetQUICVersion() protocol.VersionNumber { return c.version }
@@ -12,8 +12,9 @@ type sessionControlblock struct {
 
 	id int
 
-	session       *quic.Session // 对应的 quic 连接
-	canDispatched bool          // 该连接是否能够被调度器用于承载其他请求
+	session       *quic.Session       // 对应的 quic 连接
+	h3            *clientSessionState // 该连接上的 HTTP/3 状态
+	canDispatched bool                // 该连接是否能够被调度器用于承载其他请求
 
 	dataToFetch int // 还需要加载的字节数
 
@@ -26,8 +27,13 @@ type sessionControlblock struct {
 }
 
 // newSessionControlBlock 方法新建一个 sessionControlBlock 并返回其指针
-func newSessionControlBlock(id int, session *quic.Session, canDispatched bool) *sessionControlblock {
-	return &sessionControlblock{mutex: sync.Mutex{}, id: id, session: session, canDispatched: canDispatched}
+func newSessionControlBlock(id int, h3 *clientSessionState, canDispatched bool) *sessionControlblock {
+	return &sessionControlblock{mutex: sync.Mutex{}, id: id, session: &h3.sess, h3: h3, canDispatched: canDispatched}
+}
+
+// goingAway 返回服务端是否已经通过 GOAWAY 帧宣告关闭该连接
+func (block *sessionControlblock) goingAway() bool {
+	return block.h3.goingAway()
 }
 
 /* 以下三个函数负责处理对 pendingRequest 字段的操作 */
@@ -48,7 +54,7 @@ func (block *sessionControlblock) dispatchable() bool {
 	block.mutex.Lock()
 	defer block.mutex.Unlock()
 	// session 上有超过一个正在处理的请求则不可以被用来处理新的请求
-	return block.pendingRequest < 1
+	return block.pendingRequest < 1 && !block.h3.goingAway()
 }
 
 /* 以下是对 bandwidth 字段的处理方法 */
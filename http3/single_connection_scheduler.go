@@ -1,14 +1,12 @@
 package http3
 
 import (
-	"context"
 	"crypto/tls"
-	"log"
 	"net/http"
 	"sync"
 
 	"github.com/lucas-clemente/quic-go"
-	"github.com/marten-seemann/qpack"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 // 最大并发 stream 数
@@ -26,8 +24,11 @@ type singleConnectionScheduler struct {
 	tlsConfig        *tls.Config
 	quicConfig       *quic.Config
 	requestWriter    *requestWriter
-	decoder          *qpack.Decoder
+	qpackConf        *qpackConfig
 	roundTripperOpts *roundTripperOpts
+	pushes           *pushCache // 服务端推送的响应, 未启用推送时为 nil
+	dialer           func(network, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.Session, error)
+	logger           utils.Logger
 
 	openedSession         []*sessionControlblock // 已经打开的 session，最多打开一条 session
 	mayExecuteNextRequest *chan struct{}         // 可能可以发送下一请求时向此 chan 发送消息
@@ -47,8 +48,11 @@ func newSingleConnectionScheduler(info *clientInfo) *singleConnectionScheduler {
 		tlsConfig:        info.tlsConfig,
 		quicConfig:       info.quicConfig,
 		requestWriter:    info.requestWriter,
-		decoder:          info.decoder,
+		qpackConf:        info.qpackConf,
 		roundTripperOpts: info.roundTripperOpts,
+		pushes:           info.pushes,
+		dialer:           info.dialer,
+		logger:           info.logger.WithPrefix("single connection scheduler"),
 
 		openedSession:         make([]*sessionControlblock, 0),
 		mayExecuteNextRequest: &mayExecuteNextRequestChan,
@@ -77,7 +81,7 @@ func (scheduler *singleConnectionScheduler) close() error {
 		session := *block.session
 		err = session.Close()
 		if err != nil {
-			log.Println(err.Error())
+			scheduler.logger.Errorf("%s", err)
 			return err
 		}
 	}
@@ -94,12 +98,14 @@ func (scheduler *singleConnectionScheduler) addNewRequest(reqBlock *requestContr
 
 // addAndWait 方法向调度器内部队列中添加一个新的请求并在该请求结束之后返回其相应以及错误（如果有）
 func (scheduler *singleConnectionScheduler) addAndWait(req *http.Request) (*http.Response, error) {
-	var requestDone = make(chan struct{}, 0)
-	var requestError = make(chan struct{}, 0)
+	// 执行请求的 go 程可能在本方法返回之后才发出信号, 因此两个 chan 都需要缓冲
+	var requestDone = make(chan struct{}, 1)
+	var requestError = make(chan struct{}, 1)
 	reqBlock := requestControlBlock{
 		request:      req,
 		requestDone:  &requestDone,
 		requestError: &requestError,
+		ctx:          req.Context(),
 	}
 	scheduler.addNewRequest(&reqBlock)
 	// log.Printf("addAndWait: queue len = <%v>, req = <%v>",
@@ -111,12 +117,33 @@ func (scheduler *singleConnectionScheduler) addAndWait(req *http.Request) (*http
 			return reqBlock.response, reqBlock.unhandledError
 		case <-*reqBlock.requestError:
 			return getErrorResponse(req), nil
+		case <-req.Context().Done():
+			// 还在队列中的请求直接被移除. 已经开始执行的请求由执行它的 go 程重置 stream.
+			scheduler.removeRequest(&reqBlock)
+			return nil, req.Context().Err()
 		}
 	}
 }
 
+// removeRequest 把尚未执行的请求从队列中移除, 返回该请求是否仍在队列中
+func (scheduler *singleConnectionScheduler) removeRequest(reqBlock *requestControlBlock) bool {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+	for i, b := range scheduler.requestQueue {
+		if b == reqBlock {
+			scheduler.requestQueue = append(scheduler.requestQueue[:i], scheduler.requestQueue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 // popRequest 方法返回调度器队列中的第一个请求。如果队列中没有请求，则返回 nil。
+// 已经被取消的请求会被直接丢弃。
 func (scheduler *singleConnectionScheduler) popRequest() *requestControlBlock {
+	for len(scheduler.requestQueue) > 0 && scheduler.requestQueue[0].canceled() {
+		scheduler.removeFirst()
+	}
 	if len(scheduler.requestQueue) > 0 {
 		nextRequest := scheduler.requestQueue[0]
 		return nextRequest
@@ -144,6 +171,7 @@ func (scheduler *singleConnectionScheduler) mayExecute() {
 		sessionBlock := scheduler.getSession()
 		if sessionBlock == nil {
 			// 无法获取所需的 quicSession，当做是服务器错误返回
+			scheduler.removeFirst()
 			*nextRequest.requestError <- struct{}{}
 			return
 		}
@@ -158,34 +186,42 @@ func (scheduler *singleConnectionScheduler) mayExecute() {
 
 // getSession 方法返回调度器中可用的 quicSession，如果没有，就会创建新的
 func (scheduler *singleConnectionScheduler) getSession() *sessionControlblock {
-	if len(scheduler.openedSession) > 0 {
+	if len(scheduler.openedSession) > 0 && !scheduler.openedSession[0].goingAway() {
 		// 唯一可用的 session 已打开
-		log.Printf("getSession: return the only session")
+		scheduler.logger.Debugf("getSession: return the only session")
 		return scheduler.openedSession[0]
 	}
 	// log.Printf("getSession: establishing the initial session to <%v>", scheduler.hostname)
-	// 还没有打开唯一的一条 quicSession，需要立刻打开
-	newSession, err := dial(scheduler.hostname, scheduler.tlsConfig, scheduler.quicConfig)
+	// 还没有打开唯一的一条 quicSession, 或者服务端已经对其发送了 GOAWAY 帧, 需要立刻打开新的 quicSession.
+	// 收到 GOAWAY 帧的 quicSession 会在其上的请求完成之后被服务端关闭.
+	newSession, err := dial(scheduler.hostname, scheduler.tlsConfig, scheduler.quicConfig, scheduler.pushes, scheduler.qpackConf, scheduler.dialer, scheduler.logger)
 	if err != nil {
 		return nil
 	}
+	scheduler.maxSessionID++
 	newSessionBlock := newSessionControlBlock(scheduler.maxSessionID, newSession, true)
-	scheduler.openedSession = append(scheduler.openedSession, newSessionBlock)
+	scheduler.openedSession = []*sessionControlblock{newSessionBlock}
 	return newSessionBlock
 }
 
 // execute 方法实际执行给定的请求
 func (scheduler *singleConnectionScheduler) execute(reqBlock *requestControlBlock) {
-	log.Printf("pending requests = <%v>, session = <%v>, executing request = <%v>",
+	scheduler.logger.Debugf("pending requests = <%v>, session = <%v>, executing request = <%v>",
 		scheduler.pendingRequests, reqBlock.designatedSession.id, reqBlock.request.URL.RequestURI())
 
 	req := reqBlock.request
 	quicSession := *reqBlock.designatedSession.session
 
-	str, err := quicSession.OpenStreamSync(context.Background())
+	str, err := reqBlock.designatedSession.h3.openRequestStream(req.Context(), req.Method)
 	if err != nil {
-		log.Printf(err.Error())
+		scheduler.logger.Errorf("%s", err)
+		if reqBlock.designatedSession.h3.isUnprocessed(nil, err) && scheduler.retry(reqBlock) {
+			return
+		}
 		*reqBlock.requestError <- struct{}{}
+		scheduler.mutex.Lock()
+		scheduler.pendingRequests--
+		scheduler.mutex.Unlock()
 		return
 	}
 
@@ -203,11 +239,16 @@ func (scheduler *singleConnectionScheduler) execute(reqBlock *requestControlBloc
 
 	usingGzip := isUsingGzip(scheduler.roundTripperOpts.DisableCompression,
 		req.Method, req.Header.Get("accept-encoding"), req.Header.Get("range"))
-	resp, requestErr := getResponse(req, usingGzip, &str, &quicSession,
+	resp, requestErr := getResponse(req, usingGzip, &str, reqBlock.designatedSession.h3,
 		scheduler.requestWriter, maxHeaderBytes(scheduler.roundTripperOpts.MaxHeaderBytes),
-		scheduler.decoder, reqDone)
+		scheduler.pushes, reqDone)
 	if requestErr.err != nil {
 		close(reqDone)
+		if reqBlock.designatedSession.h3.isUnprocessed(str, requestErr.err) && scheduler.retry(reqBlock) {
+			str.CancelWrite(quic.ErrorCode(errorRequestCanceled))
+			str.CancelRead(quic.ErrorCode(errorRequestCanceled))
+			return
+		}
 		if requestErr.streamErr != 0 {
 			str.CancelWrite(quic.ErrorCode(requestErr.streamErr))
 		}
@@ -218,14 +259,30 @@ func (scheduler *singleConnectionScheduler) execute(reqBlock *requestControlBloc
 			}
 			quicSession.CloseWithError(quic.ErrorCode(requestErr.connErr), reason)
 		}
+		reqBlock.unhandledError = requestErr.err
 	}
 	// 向调用者发送信号
 	reqBlock.response = resp
 	*reqBlock.requestDone <- struct{}{}
 	*scheduler.mayExecuteNextRequest <- struct{}{}
-	log.Printf("session = <%v>, request finished, url = <%v>",
+	scheduler.logger.Debugf("session = <%v>, request finished, url = <%v>",
 		reqBlock.designatedSession.id, reqBlock.request.URL.RequestURI())
 	scheduler.mutex.Lock()
 	scheduler.pendingRequests--
 	scheduler.mutex.Unlock()
 }
+
+// retry 把服务端没有处理的请求重新放回队列的头部, 由调度器在新的 quicSession 上重试.
+// 如果请求不能被重试, 返回 false.
+func (scheduler *singleConnectionScheduler) retry(reqBlock *requestControlBlock) bool {
+	if !reqBlock.prepareRetry() {
+		return false
+	}
+	scheduler.logger.Debugf("retrying unprocessed request = <%v>", reqBlock.request.URL.RequestURI())
+	scheduler.mutex.Lock()
+	scheduler.requestQueue = append([]*requestControlBlock{reqBlock}, scheduler.requestQueue...)
+	scheduler.pendingRequests--
+	scheduler.mutex.Unlock()
+	*scheduler.mayExecuteNextRequest <- struct{}{}
+	return true
+}
@@ -0,0 +1,60 @@
+package http3
+
+import (
+	"bytes"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/marten-seemann/qpack"
+	"golang.org/x/net/http/httpguts"
+)
+
+// declaredTrailer 根据头部中的 Trailer 字段构造 http.Request.Trailer 或 http.Response.Trailer.
+// 其中的值在读取完 body 之后才会被填充. 如果没有声明任何 trailer, 返回 nil.
+func declaredTrailer(h http.Header) http.Header {
+	var trailer http.Header
+	for _, v := range h["Trailer"] {
+		for _, key := range strings.Split(v, ",") {
+			key = http.CanonicalHeaderKey(textproto.TrimString(key))
+			if key == "" || !httpguts.ValidTrailerHeader(key) {
+				continue
+			}
+			if trailer == nil {
+				trailer = make(http.Header)
+			}
+			trailer[key] = nil
+		}
+	}
+	return trailer
+}
+
+// encodeTrailer 把 trailer 编码成一个 HEADERS 帧. 如果 trailer 中没有可以发送的字段, 返回 nil.
+func encodeTrailer(encoder *qpackEncoder, id quic.StreamID, trailer http.Header) ([]byte, error) {
+	keys := make([]string, 0, len(trailer))
+	for k := range trailer {
+		if httpguts.ValidTrailerHeader(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var hfs []qpack.HeaderField
+	for _, k := range keys {
+		for _, v := range trailer[k] {
+			hfs = append(hfs, qpack.HeaderField{Name: strings.ToLower(k), Value: v})
+		}
+	}
+	if len(hfs) == 0 {
+		return nil, nil
+	}
+	headerBlock, err := encoder.encode(id, hfs)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	(&headersFrame{Length: uint64(len(headerBlock))}).Write(buf)
+	buf.Write(headerBlock)
+	return buf.Bytes(), nil
+}
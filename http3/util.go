@@ -7,7 +7,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"net/http"
 	"strconv"
@@ -15,7 +14,7 @@ import (
 	"time"
 
 	"github.com/lucas-clemente/quic-go"
-	"github.com/marten-seemann/qpack"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 // 队列名称
@@ -34,6 +33,7 @@ var errHostNotConnected = errors.New("can not connect to given host")    // 连
 var errNoAvailableSession = errors.New("no available session")           // 找不到指定的
 var errCanNotExecuteRequest = errors.New("can not execute this request") // 无法执行此请求
 var errNoAvailableRequest = errors.New("no available request")           // 队列中没有待处理的下一请求
+var errSchedulerClosed = errors.New("scheduler closed")                  // 调度器已经被关闭
 
 // getQueueIndexByMimeType 根据给出的 mimeType 返回这个资源应当加入的队列序号
 func getQueueIndexByMimeType(mimeType string) int {
@@ -144,7 +144,8 @@ func maxHeaderBytes(optionMaxHeaderBytes int64) uint64 {
 }
 
 // setupH3Session 方法在传输的 quicSession 上初始化 H3 连接
-func setupH3Session(quicSession *quic.Session) error {
+// 如果 pushes 不为 nil, 则允许服务端在该连接上推送资源
+func setupH3Session(quicSession *quic.Session, pushes *pushCache, qpackConf *qpackConfig) error {
 	// 建立单向控制 stream
 	controlStream, err := (*quicSession).OpenUniStream()
 	if err != nil {
@@ -154,10 +155,17 @@ func setupH3Session(quicSession *quic.Session) error {
 	// write the type byte
 	buf.Write([]byte{0x0})
 	// send the SETTINGS frame
-	(&settingsFrame{}).Write(buf)
+	(&settingsFrame{settings: qpackConf.settings()}).Write(buf)
+	if pushes != nil {
+		// 服务端只有在收到 MAX_PUSH_ID 帧之后才能推送资源
+		(&maxPushIDFrame{PushID: defaultMaxPushID}).Write(buf)
+	}
 	if _, err := controlStream.Write(buf.Bytes()); err != nil {
 		return err
 	}
+	if pushes != nil {
+		pushes.addSession(*quicSession, controlStream)
+	}
 	return nil
 }
 
@@ -171,21 +179,40 @@ func getErrorResponse(req *http.Request) *http.Response {
 	}
 }
 
-// dial 方法按照给定的参数向对端拨号，并返回双方的 quicSession
-func dial(hostname string, tlsConfig *tls.Config, quicConfig *quic.Config) (*quic.Session, error) {
-	quicSession, err := dialAddr(hostname, tlsConfig, quicConfig)
+// dial 方法按照给定的参数向对端拨号，并返回双方的 quicSession 及其 HTTP/3 状态
+func dial(hostname string, tlsConfig *tls.Config, quicConfig *quic.Config, pushes *pushCache, qpackConf *qpackConfig,
+	dialer func(network, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.Session, error), logger utils.Logger) (*clientSessionState, error) {
+	var quicSession quic.Session
+	var err error
+	if dialer != nil {
+		quicSession, err = dialer("udp", hostname, tlsConfig, quicConfig)
+	} else {
+		quicSession, err = dialAddr(hostname, tlsConfig, quicConfig)
+	}
 	if err != nil {
 		return nil, err
 	}
+	state := newClientSessionState(quicSession, pushes, qpackConf, logger)
+	// 处理服务端的控制 stream 和 push stream
+	go state.acceptStreams()
+
+	if pushes != nil {
+		// 在发送第一个请求之前建立控制 stream, 使服务端尽早收到 MAX_PUSH_ID 帧
+		if err := setupH3Session(&state.sess, pushes, qpackConf); err != nil {
+			quicSession.CloseWithError(quic.ErrorCode(errorInternalError), "")
+			return nil, err
+		}
+		return state, nil
+	}
 
 	go func() {
-		if err := setupH3Session(&quicSession); err != nil {
-			log.Printf("Setting up session failed: %v", err.Error())
+		if err := setupH3Session(&state.sess, pushes, qpackConf); err != nil {
+			logger.Errorf("Setting up session failed: %v", err.Error())
 			quicSession.CloseWithError(quic.ErrorCode(errorInternalError), "")
 		}
 	}()
 
-	return &quicSession, nil
+	return state, nil
 }
 
 // isusingGzip 方法返回该连接是否使用 GZIP 压缩
@@ -202,47 +229,81 @@ func getResponse(
 	req *http.Request,
 	usingGzip bool,
 	str *quic.Stream,
-	sess *quic.Session,
+	h3 *clientSessionState,
 	requestWriter *requestWriter,
 	maxHeaderBytes uint64,
-	decoder *qpack.Decoder,
+	pushes *pushCache,
 	reqDone chan struct{},
 ) (*http.Response, requestError) {
-	if err := requestWriter.WriteRequest(*str, req, usingGzip); err != nil {
-		log.Printf("write request error: %v", err.Error())
+	sess := &h3.sess
+	upload := newRequestUpload(*str)
+	if err := requestWriter.writeRequest(*str, req, usingGzip, h3.encoder, upload.fail); err != nil {
+		h3.logger.Debugf("write request error: %v", err.Error())
 		return nil, newStreamError(errorInternalError, err)
 	}
 
 	// 开始接受对端返回的数据
-	frame, err := parseNextFrame(*str)
+	res, rerr := readResponseHeaders(*str, maxHeaderBytes, h3.decoder, pushes.promiseHandler(*sess))
+	if rerr.err != nil {
+		rerr = upload.wrap(rerr)
+		h3.logger.Debugf("read response headers error: %v", rerr.err.Error())
+		return nil, rerr
+	}
+
+	// 新建一个空白响应体
+	respBody := newResponseBody(*str, reqDone, func() {
+		(*sess).CloseWithError(quic.ErrorCode(errorFrameUnexpected), "")
+	})
+	respBody.onPushPromise = pushes.promiseHandler(*sess)
+	respBody.trailer = &res.Trailer
+	respBody.decoder = h3.decoder
+	respBody.maxHeaderBytes = maxHeaderBytes
+	// 根据是否需要 gzip 来实际构造响应体
+	if usingGzip && res.Header.Get("Content-Encoding") == "gzip" {
+		res.Header.Del("Content-Encoding")
+		res.Header.Del("Content-Length")
+		res.ContentLength = -1
+		res.Body = newGzipReader(respBody)
+		res.Uncompressed = true
+		h3.logger.Debugf("init with a gzip reader")
+	} else {
+		res.Body = respBody
+	}
+
+	return res, requestError{}
+}
+
+// readResponseHeaders 读取并解码响应的 HEADERS 帧, 构造不含响应体的 http.Response.
+// 在 HEADERS 帧之前到达的 PUSH_PROMISE 帧交给 onPushPromise 处理.
+func readResponseHeaders(
+	str quic.ReceiveStream,
+	maxHeaderBytes uint64,
+	decoder *qpackDecoder,
+	onPushPromise func(*pushPromiseFrame) error,
+) (*http.Response, requestError) {
+	frame, err := parseNextResponseFrame(str, onPushPromise)
 	if err != nil {
-		log.Printf("parse next frame error: %v", err.Error())
 		return nil, newStreamError(errorFrameError, err)
 	}
 	// 确定第一帧是否为 H3 协议规定的 HEADER 帧
 	hf, ok := frame.(*headersFrame)
 	if !ok {
-		log.Println("unexpected first frame")
 		return nil, newConnError(errorFrameUnexpected, errors.New("expected first frame to be a HEADERS frame"))
 	}
-	// TODO: 用普通方法代替 maxHeaderBytes
 	if hf.Length > maxHeaderBytes {
-		log.Println("header frame too large")
-		return nil, newStreamError(errorFrameError,
+		// 与服务端相同, 帧本身是合法的, 只是超出了我们愿意处理的大小
+		return nil, newStreamError(errorExcessiveLoad,
 			fmt.Errorf("HEADERS frame too large: %d bytes (max: %d)", hf.Length, maxHeaderBytes))
 	}
 	// 读取并解析 HEADER 帧
 	headerBlock := make([]byte, hf.Length)
-	if _, err := io.ReadFull(*str, headerBlock); err != nil {
-		log.Println("read head block error")
+	if _, err := io.ReadFull(str, headerBlock); err != nil {
 		return nil, newStreamError(errorRequestIncomplete, err)
 	}
 	// 调用 qpack 解码 HEADER 帧
-	hfs, err := decoder.DecodeFull(headerBlock)
+	hfs, err := decoder.decode(str.StreamID(), headerBlock)
 	if err != nil {
-		log.Println("decode header error")
-		// TODO: use the right error code
-		return nil, newConnError(errorGeneralProtocolError, err)
+		return nil, newConnError(errorQPACKDecompressionFailed, err)
 	}
 
 	// 构造响应体
@@ -267,24 +328,26 @@ func getResponse(
 			res.Header.Add(hf.Name, hf.Value)
 		}
 	}
+	res.Trailer = declaredTrailer(res.Header)
+	return res, requestError{}
+}
 
-	// 新建一个空白响应体
-	respBody := newResponseBody(*str, reqDone, func() {
-		(*sess).CloseWithError(quic.ErrorCode(errorFrameUnexpected), "")
-	})
-	// 根据是否需要 gzip 来实际构造响应体
-	if usingGzip && res.Header.Get("Content-Encoding") == "gzip" {
-		res.Header.Del("Content-Encoding")
-		res.Header.Del("Content-Length")
-		res.ContentLength = -1
-		res.Body = newGzipReader(respBody)
-		res.Uncompressed = true
-		log.Println("init with a gzip reader")
-	} else {
-		res.Body = respBody
+// parseNextResponseFrame 解析请求 stream 上的下一帧.
+// 如果 onPushPromise 不为 nil, PUSH_PROMISE 帧交由它处理, 然后继续解析下一帧.
+func parseNextResponseFrame(r io.Reader, onPushPromise func(*pushPromiseFrame) error) (frame, error) {
+	for {
+		frame, err := parseNextFrame(r)
+		if err != nil {
+			return nil, err
+		}
+		pf, ok := frame.(*pushPromiseFrame)
+		if !ok || onPushPromise == nil {
+			return frame, nil
+		}
+		if err := onPushPromise(pf); err != nil {
+			return nil, err
+		}
 	}
-
-	return res, requestError{}
 }
 
 // CollectorAddr 为数据收集器的默认监听地址, 本机 UDP 8888 端口
@@ -299,6 +362,8 @@ type EndTimeMessage struct {
 
 // StatisticsCollector 负责收集运行数据
 type StatisticsCollector struct {
+	// Logger 接收收集器的日志，为 nil 时使用默认日志
+	Logger quic.Logger
 }
 
 // NewStatisticsCollector 创建数据收集器实例并返回其指针
@@ -308,47 +373,48 @@ func NewStatisticsCollector() *StatisticsCollector {
 
 // Run 运行收集器主线程
 func (collector *StatisticsCollector) Run() {
+	logger := utils.NewLogger(collector.Logger, "h3 collector")
 	listener, err := net.ListenPacket("udp", CollectorAddr)
 	if err != nil {
-		log.Printf("collector: error in listening: %v", err.Error())
+		logger.Errorf("error in listening: %v", err.Error())
 		return
 	}
 
-	log.Printf("collector: running at <%v>", listener.LocalAddr().String())
+	logger.Infof("running at <%v>", listener.LocalAddr().String())
 
 	for {
 		buf := make([]byte, 1024)
 		written, remoteAddr, err := listener.ReadFrom(buf)
 		if err != nil {
-			log.Printf("collector: error in accepting conn: %v", err.Error())
+			logger.Errorf("error in accepting conn: %v", err.Error())
 			return
 		}
-		log.Printf("collector: read <%v> from <%v>", written, remoteAddr)
-		go handlePacket(&buf)
+		logger.Debugf("read <%v> from <%v>", written, remoteAddr)
+		go handlePacket(&buf, logger)
 	}
 }
 
 // handlePacket 处理到来的 UDP 数据包
-func handlePacket(buf *[]byte) {
+func handlePacket(buf *[]byte, logger utils.Logger) {
 	receivedBuf := bytes.NewBuffer(*buf)
 	var receivedMessage EndTimeMessage
 	if err := binary.Read(receivedBuf, binary.LittleEndian, &receivedMessage); err != nil {
-		log.Printf("handleConn: error in parsing message from udp conn")
+		logger.Errorf("handleConn: error in parsing message from udp conn")
 	}
-	log.Printf("received message: main request = <%v>, sub requests = <%v>",
+	logger.Infof("received message: main request = <%v>, sub requests = <%v>",
 		receivedMessage.MainRequestFinishTime, receivedMessage.SubRequestFinishTime)
 }
 
 // sendToStatisticCollector 发送数据到 collector
-func sendToStatisticCollector(message *EndTimeMessage) {
+func sendToStatisticCollector(message *EndTimeMessage, logger utils.Logger) {
 	sendBuf := bytes.Buffer{}
 	if err := binary.Write(&sendBuf, binary.LittleEndian, message); err != nil {
-		log.Printf("send message error: %v", err.Error())
+		logger.Errorf("send message error: %v", err.Error())
 		return
 	}
 	conn, err := net.Dial("udp", CollectorAddr)
 	if err != nil {
-		log.Printf("error in dialing to collector: %v", err.Error())
+		logger.Errorf("error in dialing to collector: %v", err.Error())
 		return
 	}
 	conn.Write(sendBuf.Bytes())
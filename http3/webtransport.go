@@ -0,0 +1,332 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+const (
+	// SETTINGS_ENABLE_CONNECT_PROTOCOL, 允许客户端发送扩展 CONNECT 请求 (RFC 8441 / RFC 9220)
+	settingExtendedConnect = 0x8
+	// SETTINGS_ENABLE_WEBTRANSPORT, 见 draft-ietf-webtrans-http3-02
+	settingEnableWebTransport = 0x2b603742
+
+	webTransportStreamFrameType = 0x41
+	webTransportUniStreamType   = 0x54
+)
+
+// maxQueuedWebTransportStreams 是每个 session 中等待被 Accept 的 stream 的最大数量.
+// 超出的 stream 以 WEBTRANSPORT_BUFFERED_STREAM_REJECTED 被拒绝.
+const maxQueuedWebTransportStreams = 16
+
+// ErrDatagramsNotSupported is returned when sending or receiving datagrams on a WebTransport session.
+// Datagrams require the QUIC DATAGRAM extension, which is not implemented by the QUIC transport.
+var ErrDatagramsNotSupported = errors.New("http3: datagrams are not supported by the QUIC transport")
+
+var (
+	errWebTransportSessionClosed   = errors.New("http3: WebTransport session closed")
+	errWebTransportSessionRejected = errors.New("http3: WebTransport session not accepted by the handler")
+)
+
+// errStreamHijacked 表示 stream 已经交给了 WebTransport session, 处理请求的 go 程不能再关闭或重置它
+var errStreamHijacked = errors.New("stream hijacked by a WebTransport session")
+
+// webTransportSessions 保存一条连接上所有的 WebTransport session, 以 CONNECT 请求的 stream ID 为键
+type webTransportSessions struct {
+	sess quic.Session
+
+	mutex    sync.Mutex
+	sessions map[quic.StreamID]*WebTransportSession
+}
+
+func newWebTransportSessions(sess quic.Session) *webTransportSessions {
+	return &webTransportSessions{
+		sess:     sess,
+		sessions: make(map[quic.StreamID]*WebTransportSession),
+	}
+}
+
+// newSession 为 str 上的扩展 CONNECT 请求创建 session.
+// 在 handler 调用 UpgradeWebTransport 之前, 属于该 session 的 stream 会被缓存.
+func (s *webTransportSessions) newSession(str quic.Stream) *WebTransportSession {
+	ctx, cancel := context.WithCancel(str.Context())
+	ws := &WebTransportSession{
+		id:         str.StreamID(),
+		sess:       s.sess,
+		str:        str,
+		sessions:   s,
+		ctx:        ctx,
+		cancel:     cancel,
+		streams:    make(chan quic.Stream, maxQueuedWebTransportStreams),
+		uniStreams: make(chan quic.ReceiveStream, maxQueuedWebTransportStreams),
+	}
+	s.mutex.Lock()
+	s.sessions[ws.id] = ws
+	s.mutex.Unlock()
+	return ws
+}
+
+func (s *webTransportSessions) get(id quic.StreamID) *WebTransportSession {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.sessions[id]
+}
+
+func (s *webTransportSessions) remove(id quic.StreamID) {
+	s.mutex.Lock()
+	delete(s.sessions, id)
+	s.mutex.Unlock()
+}
+
+// closeAll 在连接关闭之后结束所有的 session
+func (s *webTransportSessions) closeAll(err error) {
+	s.mutex.Lock()
+	sessions := make([]*WebTransportSession, 0, len(s.sessions))
+	for _, ws := range s.sessions {
+		sessions = append(sessions, ws)
+	}
+	s.mutex.Unlock()
+	for _, ws := range sessions {
+		ws.terminate(err)
+	}
+}
+
+// handleStream 把以 WEBTRANSPORT_STREAM 帧开头的双向 stream 交给对应的 session.
+// 如果 session 不存在或者缓存已满, 则拒绝该 stream.
+func (s *webTransportSessions) handleStream(str quic.Stream, id quic.StreamID) requestError {
+	if ws := s.get(id); ws != nil && ws.queueStream(str) {
+		return requestError{err: errStreamHijacked}
+	}
+	str.CancelRead(quic.ErrorCode(errorWebTransportBufferedStreamRejected))
+	return newStreamError(errorWebTransportBufferedStreamRejected, fmt.Errorf("rejected stream for WebTransport session %d", id))
+}
+
+// handleUniStream 读取单向 stream 开头的 session ID, 并把该 stream 交给对应的 session
+func (s *webTransportSessions) handleUniStream(str quic.ReceiveStream) {
+	id, err := utils.ReadVarInt(&byteReaderImpl{str})
+	if err != nil {
+		return
+	}
+	if ws := s.get(quic.StreamID(id)); ws != nil && ws.queueUniStream(str) {
+		return
+	}
+	str.CancelRead(quic.ErrorCode(errorWebTransportBufferedStreamRejected))
+}
+
+// A WebTransportSession is a WebTransport session established by an extended CONNECT request.
+// It multiplexes bidirectional and unidirectional streams with the other requests on the same connection.
+type WebTransportSession struct {
+	id       quic.StreamID // CONNECT 请求的 stream ID
+	sess     quic.Session
+	str      quic.Stream // CONNECT 请求的 stream, 关闭它即结束 session
+	sessions *webTransportSessions
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mutex      sync.Mutex
+	closeErr   error // session 结束的原因, 非 nil 时不再接受新的 stream
+	streams    chan quic.Stream
+	uniStreams chan quic.ReceiveStream
+}
+
+func (w *WebTransportSession) queueStream(str quic.Stream) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.closeErr != nil {
+		return false
+	}
+	select {
+	case w.streams <- str:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *WebTransportSession) queueUniStream(str quic.ReceiveStream) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.closeErr != nil {
+		return false
+	}
+	select {
+	case w.uniStreams <- str:
+		return true
+	default:
+		return false
+	}
+}
+
+// AcceptStream returns the next bidirectional stream opened by the client for this session.
+func (w *WebTransportSession) AcceptStream(ctx context.Context) (quic.Stream, error) {
+	select {
+	case str := <-w.streams:
+		return str, nil
+	case <-w.ctx.Done():
+		return nil, w.err()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// AcceptUniStream returns the next unidirectional stream opened by the client for this session.
+func (w *WebTransportSession) AcceptUniStream(ctx context.Context) (quic.ReceiveStream, error) {
+	select {
+	case str := <-w.uniStreams:
+		return str, nil
+	case <-w.ctx.Done():
+		return nil, w.err()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// OpenStream opens a bidirectional stream for this session.
+func (w *WebTransportSession) OpenStream() (quic.Stream, error) {
+	str, err := w.sess.OpenStream()
+	if err != nil {
+		return nil, err
+	}
+	return str, w.writeStreamHeader(str)
+}
+
+// OpenStreamSync opens a bidirectional stream for this session, blocking until it can be opened.
+func (w *WebTransportSession) OpenStreamSync(ctx context.Context) (quic.Stream, error) {
+	str, err := w.sess.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return str, w.writeStreamHeader(str)
+}
+
+// OpenUniStream opens a unidirectional stream for this session.
+func (w *WebTransportSession) OpenUniStream() (quic.SendStream, error) {
+	str, err := w.sess.OpenUniStream()
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	utils.WriteVarInt(buf, webTransportUniStreamType)
+	utils.WriteVarInt(buf, uint64(w.id))
+	if _, err := str.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+	return str, nil
+}
+
+// writeStreamHeader 在 session 打开的双向 stream 开头写入 WEBTRANSPORT_STREAM 帧
+func (w *WebTransportSession) writeStreamHeader(str quic.Stream) error {
+	buf := &bytes.Buffer{}
+	(&webTransportStreamFrame{SessionID: w.id}).Write(buf)
+	_, err := str.Write(buf.Bytes())
+	return err
+}
+
+// SendDatagram sends a datagram associated with this session.
+// It always returns ErrDatagramsNotSupported.
+func (w *WebTransportSession) SendDatagram([]byte) error {
+	return ErrDatagramsNotSupported
+}
+
+// ReceiveDatagram receives a datagram associated with this session.
+// It always returns ErrDatagramsNotSupported.
+func (w *WebTransportSession) ReceiveDatagram(context.Context) ([]byte, error) {
+	return nil, ErrDatagramsNotSupported
+}
+
+// Context returns a context that is cancelled when the session is closed.
+func (w *WebTransportSession) Context() context.Context {
+	return w.ctx
+}
+
+// Close closes the session.
+// Streams that were opened by the client but not accepted yet are reset.
+func (w *WebTransportSession) Close() error {
+	if !w.terminate(errWebTransportSessionClosed) {
+		return nil
+	}
+	return w.str.Close()
+}
+
+// watch 读取 CONNECT 请求的 stream, 直到对端关闭该 stream, 然后结束 session
+func (w *WebTransportSession) watch() {
+	_, err := io.Copy(ioutil.Discard, w.str)
+	if err == nil {
+		err = errWebTransportSessionClosed
+	}
+	if w.terminate(err) {
+		w.str.Close()
+	}
+}
+
+// terminate 以 err 结束 session, 并重置所有还没有被 Accept 的 stream.
+// 它不会关闭 CONNECT 请求的 stream. 只有第一次调用返回 true.
+func (w *WebTransportSession) terminate(err error) bool {
+	w.mutex.Lock()
+	if w.closeErr != nil {
+		w.mutex.Unlock()
+		return false
+	}
+	w.closeErr = err
+	w.mutex.Unlock()
+
+	w.cancel()
+	w.sessions.remove(w.id)
+	for {
+		select {
+		case str := <-w.streams:
+			str.CancelRead(quic.ErrorCode(errorWebTransportSessionGone))
+			str.CancelWrite(quic.ErrorCode(errorWebTransportSessionGone))
+		case str := <-w.uniStreams:
+			str.CancelRead(quic.ErrorCode(errorWebTransportSessionGone))
+		default:
+			return true
+		}
+	}
+}
+
+func (w *WebTransportSession) err() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.closeErr
+}
+
+// UpgradeWebTransport accepts the WebTransport session requested by r.
+// It must be called by the handler of an extended CONNECT request for the webtransport protocol,
+// before anything is written to w. It sends a 200 response, after which the request stream belongs
+// to the session: the handler must not use w or r.Body anymore, and may return while the session is in use.
+// If the handler returns without calling UpgradeWebTransport, the session is rejected.
+// Middleware that wraps the ResponseWriter must provide an Unwrap() http.ResponseWriter method,
+// and must not write to the response after the upgrade.
+func UpgradeWebTransport(w http.ResponseWriter, r *http.Request) (*WebTransportSession, error) {
+	for {
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	rw, ok := w.(*responseWriter)
+	if !ok || rw.webTransport == nil {
+		return nil, errors.New("http3: not a WebTransport request")
+	}
+	if rw.headerWritten {
+		return nil, errors.New("http3: response header already written")
+	}
+	rw.Header().Set("Sec-Webtransport-Http3-Draft", "draft02")
+	rw.WriteHeader(http.StatusOK)
+	rw.Flush()
+	rw.hijacked = true
+	go rw.webTransport.watch()
+	return rw.webTransport, nil
+}
@@ -0,0 +1,321 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WebTransport", func() {
+	var (
+		s    *Server
+		sess *mockquic.MockSession
+		wt   *webTransportSessions
+	)
+
+	encodeConnectRequest := func(protocol string) []byte {
+		headerBlock := encodeStaticHeaders([]qpack.HeaderField{
+			{Name: ":method", Value: "CONNECT"},
+			{Name: ":protocol", Value: protocol},
+			{Name: ":scheme", Value: "https"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":path", Value: "/chat"},
+		})
+		buf := &bytes.Buffer{}
+		(&headersFrame{Length: uint64(len(headerBlock))}).Write(buf)
+		buf.Write(headerBlock)
+		return buf.Bytes()
+	}
+
+	// newStream 返回一个 stream, 读取完 data 之后阻塞, 直到 done 被关闭
+	newStream := func(id quic.StreamID, data []byte, done <-chan struct{}) (*mockquic.MockStream, *bytes.Buffer) {
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().StreamID().Return(id).AnyTimes()
+		str.EXPECT().Context().Return(context.Background()).AnyTimes()
+		r := bytes.NewReader(data)
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+			if r.Len() == 0 {
+				<-done
+				return 0, io.EOF
+			}
+			return r.Read(p)
+		}).AnyTimes()
+		written := &bytes.Buffer{}
+		str.EXPECT().Write(gomock.Any()).DoAndReturn(written.Write).AnyTimes()
+		return str, written
+	}
+
+	BeforeEach(func() {
+		s = &Server{
+			Server:             &http.Server{TLSConfig: testdata.GetTLSConfig()},
+			EnableWebTransport: true,
+			logger:             utils.DefaultLogger,
+		}
+		sess = mockquic.NewMockSession(mockCtrl)
+		wt = newWebTransportSessions(sess)
+	})
+
+	Context("SETTINGS", func() {
+		It("advertises extended CONNECT and WebTransport", func() {
+			Expect(s.settings(nil)).To(Equal(map[uint64]uint64{
+				settingExtendedConnect:    1,
+				settingEnableWebTransport: 1,
+			}))
+		})
+
+		It("advertises only extended CONNECT", func() {
+			s.EnableWebTransport = false
+			s.EnableExtendedConnect = true
+			Expect(s.settings(nil)).To(Equal(map[uint64]uint64{settingExtendedConnect: 1}))
+		})
+
+		It("doesn't advertise anything by default", func() {
+			s.EnableWebTransport = false
+			Expect(s.settings(nil)).To(BeEmpty())
+		})
+	})
+
+	It("rejects extended CONNECT requests if not enabled", func() {
+		s.EnableWebTransport = false
+		s.Handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			Fail("handler should not be called")
+		})
+		done := make(chan struct{})
+		defer close(done)
+		str, _ := newStream(0, encodeConnectRequest("webtransport"), done)
+		rerr := s.handleRequest(str, nil, nil, nil, nil, nil)
+		Expect(rerr.streamErr).To(Equal(errorGeneralProtocolError))
+	})
+
+	It("passes extended CONNECT requests to the handler", func() {
+		s.EnableWebTransport = false
+		s.EnableExtendedConnect = true
+		reqChan := make(chan *http.Request, 1)
+		s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := UpgradeWebTransport(w, r)
+			Expect(err).To(MatchError("http3: not a WebTransport request"))
+			reqChan <- r
+		})
+		done := make(chan struct{})
+		close(done)
+		str, _ := newStream(0, encodeConnectRequest("websocket"), done)
+		Expect(s.handleRequest(str, nil, nil, nil, nil, nil)).To(Equal(requestError{}))
+		var req *http.Request
+		Expect(reqChan).To(Receive(&req))
+		Expect(req.Proto).To(Equal("websocket"))
+	})
+
+	Context("upgrading", func() {
+		var (
+			connectStr *mockquic.MockStream
+			written    *bytes.Buffer
+			done       chan struct{}
+		)
+
+		BeforeEach(func() {
+			done = make(chan struct{})
+			connectStr, written = newStream(4, encodeConnectRequest("webtransport"), done)
+		})
+
+		upgrade := func() *WebTransportSession {
+			sessChan := make(chan *WebTransportSession, 1)
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.Proto).To(Equal("webtransport"))
+				ws, err := UpgradeWebTransport(w, r)
+				Expect(err).ToNot(HaveOccurred())
+				sessChan <- ws
+			})
+			rerr := s.handleRequest(connectStr, nil, nil, nil, wt, nil)
+			Expect(rerr.err).To(Equal(errStreamHijacked))
+			var ws *WebTransportSession
+			Expect(sessChan).To(Receive(&ws))
+			return ws
+		}
+
+		It("sends a 200 response and keeps the stream open", func() {
+			upgrade()
+			frame, err := parseNextFrame(written)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(BeAssignableToTypeOf(&headersFrame{}))
+			headerBlock := make([]byte, frame.(*headersFrame).Length)
+			_, err = io.ReadFull(written, headerBlock)
+			Expect(err).ToNot(HaveOccurred())
+			hfs, err := decodeStaticHeaders(headerBlock)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hfs).To(ContainElement(qpack.HeaderField{Name: ":status", Value: "200"}))
+			Expect(hfs).To(ContainElement(qpack.HeaderField{Name: "sec-webtransport-http3-draft", Value: "draft02"}))
+			Expect(written.Len()).To(BeZero())
+			// the session ends when the client closes the CONNECT stream
+			closed := make(chan struct{})
+			connectStr.EXPECT().Close().Do(func() { close(closed) })
+			close(done)
+			Eventually(closed).Should(BeClosed())
+		})
+
+		It("upgrades through middleware that wraps the ResponseWriter", func() {
+			sessChan := make(chan *WebTransportSession, 1)
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				ws, err := UpgradeWebTransport(w, r)
+				Expect(err).ToNot(HaveOccurred())
+				sessChan <- ws
+			})
+			s.Handler = &CompressionHandler{
+				Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					handler.ServeHTTP(&wrappedResponseWriter{ResponseWriter: w}, r)
+				}),
+			}
+			Expect(s.handleRequest(connectStr, nil, nil, nil, wt, nil).err).To(Equal(errStreamHijacked))
+			Expect(sessChan).To(Receive(Not(BeNil())))
+			closed := make(chan struct{})
+			connectStr.EXPECT().Close().Do(func() { close(closed) })
+			close(done)
+			Eventually(closed).Should(BeClosed())
+		})
+
+		It("accepts bidirectional streams", func() {
+			ws := upgrade()
+			buf := &bytes.Buffer{}
+			(&webTransportStreamFrame{SessionID: 4}).Write(buf)
+			buf.WriteString("foobar")
+			str, _ := newStream(8, buf.Bytes(), done)
+			Expect(s.handleRequest(str, nil, nil, nil, wt, nil).err).To(Equal(errStreamHijacked))
+			accepted, err := ws.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(accepted.StreamID()).To(Equal(quic.StreamID(8)))
+			data := make([]byte, 6)
+			_, err = io.ReadFull(accepted, data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal("foobar"))
+		})
+
+		It("accepts unidirectional streams", func() {
+			ws := upgrade()
+			conn := newServerConn(sess, nil)
+			conn.webTransport = wt
+			buf := &bytes.Buffer{}
+			utils.WriteVarInt(buf, webTransportUniStreamType)
+			utils.WriteVarInt(buf, 4)
+			buf.WriteString("foobar")
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			s.handleUniStream(conn, str)
+			accepted, err := ws.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data, err := ioutil.ReadAll(accepted)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal("foobar"))
+		})
+
+		It("opens streams", func() {
+			ws := upgrade()
+			str, written := newStream(1, nil, done)
+			sess.EXPECT().OpenStream().Return(str, nil)
+			_, err := ws.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(written.Bytes()).To(Equal([]byte{0x40, 0x41, 0x4}))
+
+			uniStr := mockquic.NewMockStream(mockCtrl)
+			uniBuf := &bytes.Buffer{}
+			uniStr.EXPECT().Write(gomock.Any()).DoAndReturn(uniBuf.Write)
+			sess.EXPECT().OpenUniStream().Return(uniStr, nil)
+			_, err = ws.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(uniBuf.Bytes()).To(Equal([]byte{0x40, 0x54, 0x4}))
+		})
+
+		It("resets queued streams when the session is closed", func() {
+			ws := upgrade()
+			buf := &bytes.Buffer{}
+			(&webTransportStreamFrame{SessionID: 4}).Write(buf)
+			str, _ := newStream(8, buf.Bytes(), done)
+			Expect(s.handleRequest(str, nil, nil, nil, wt, nil).err).To(Equal(errStreamHijacked))
+			str.EXPECT().CancelRead(quic.ErrorCode(errorWebTransportSessionGone))
+			str.EXPECT().CancelWrite(quic.ErrorCode(errorWebTransportSessionGone))
+			connectStr.EXPECT().Close()
+			Expect(ws.Close()).To(Succeed())
+			Expect(ws.Context().Done()).To(BeClosed())
+			_, err := ws.AcceptStream(context.Background())
+			Expect(err).To(MatchError(errWebTransportSessionClosed))
+			Expect(wt.get(4)).To(BeNil())
+			// closing again is a no-op
+			Expect(ws.Close()).To(Succeed())
+			close(done)
+		})
+
+		It("doesn't support datagrams", func() {
+			ws := upgrade()
+			Expect(ws.SendDatagram([]byte("foobar"))).To(MatchError(ErrDatagramsNotSupported))
+			_, err := ws.ReceiveDatagram(context.Background())
+			Expect(err).To(MatchError(ErrDatagramsNotSupported))
+		})
+
+		It("rejects the session if the handler doesn't upgrade", func() {
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			})
+			close(done)
+			connectStr.EXPECT().CancelRead(gomock.Any()).AnyTimes()
+			Expect(s.handleRequest(connectStr, nil, nil, nil, wt, nil)).To(Equal(requestError{}))
+			Expect(wt.get(4)).To(BeNil())
+		})
+	})
+
+	It("rejects streams for unknown sessions", func() {
+		buf := &bytes.Buffer{}
+		(&webTransportStreamFrame{SessionID: 4}).Write(buf)
+		done := make(chan struct{})
+		defer close(done)
+		str, _ := newStream(8, buf.Bytes(), done)
+		str.EXPECT().CancelRead(quic.ErrorCode(errorWebTransportBufferedStreamRejected))
+		rerr := s.handleRequest(str, nil, nil, nil, wt, nil)
+		Expect(rerr.streamErr).To(Equal(errorWebTransportBufferedStreamRejected))
+	})
+
+	It("rejects streams if too many are queued", func() {
+		done := make(chan struct{})
+		defer close(done)
+		connectStr, _ := newStream(0, nil, done)
+		wt.newSession(connectStr)
+		for i := 0; i <= maxQueuedWebTransportStreams; i++ {
+			buf := &bytes.Buffer{}
+			(&webTransportStreamFrame{SessionID: 0}).Write(buf)
+			str, _ := newStream(quic.StreamID(4*(i+1)), buf.Bytes(), done)
+			if i == maxQueuedWebTransportStreams {
+				str.EXPECT().CancelRead(quic.ErrorCode(errorWebTransportBufferedStreamRejected))
+				Expect(s.handleRequest(str, nil, nil, nil, wt, nil).streamErr).To(Equal(errorWebTransportBufferedStreamRejected))
+			} else {
+				Expect(s.handleRequest(str, nil, nil, nil, wt, nil).err).To(Equal(errStreamHijacked))
+			}
+		}
+	})
+
+	It("rejects unidirectional WebTransport streams if not enabled", func() {
+		conn := newServerConn(sess, nil)
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(bytes.NewReader([]byte{0x40, 0x54, 0x4}).Read).AnyTimes()
+		str.EXPECT().CancelRead(quic.ErrorCode(errorStreamCreationError))
+		s.handleUniStream(conn, str)
+	})
+})
+
+type wrappedResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *wrappedResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,84 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sync/atomic"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ECN", func() {
+	for _, v := range protocol.SupportedVersions {
+		version := v
+
+		Context(fmt.Sprintf("with QUIC version %s", version), func() {
+			It("downloads a message when packets are marked ECN-CE", func() {
+				ln, err := quic.ListenAddr(
+					"localhost:0",
+					getTLSConfig(),
+					&quic.Config{Versions: []protocol.VersionNumber{version}},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer ln.Close()
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					sess, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					str, err := sess.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					_, err = str.Write(PRDataLong)
+					Expect(err).ToNot(HaveOccurred())
+					str.Close()
+					close(done)
+				}()
+
+				var numPackets, numMarked int32
+				serverPort := ln.Addr().(*net.UDPAddr).Port
+				proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+					RemoteAddr: fmt.Sprintf("localhost:%d", serverPort),
+					DelayPacket: func(quicproxy.Direction, []byte) time.Duration {
+						return 5 * time.Millisecond
+					},
+					MarkCE: func(dir quicproxy.Direction, _ []byte) bool {
+						if dir != quicproxy.DirectionOutgoing {
+							return false
+						}
+						// mark every 20th packet sent by the server
+						if atomic.AddInt32(&numPackets, 1)%20 != 0 {
+							return false
+						}
+						atomic.AddInt32(&numMarked, 1)
+						return true
+					},
+				})
+				Expect(err).ToNot(HaveOccurred())
+				defer proxy.Close()
+
+				sess, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+					getTLSClientConfig(),
+					&quic.Config{Versions: []protocol.VersionNumber{version}},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				str, err := sess.AcceptStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				data, err := ioutil.ReadAll(str)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal(PRDataLong))
+				sess.Close()
+				Eventually(done).Should(BeClosed())
+				Expect(atomic.LoadInt32(&numMarked)).ToNot(BeZero())
+			})
+		})
+	}
+})
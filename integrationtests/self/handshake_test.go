@@ -132,6 +132,23 @@ var _ = Describe("Handshake tests", func() {
 				Expect(sess.(versioner).GetVersion()).To(Equal(protocol.SupportedVersions[0]))
 				Expect(sess.Close()).To(Succeed())
 			})
+
+			It("negotiates a draft version with a client that doesn't support QUIC v1", func() {
+				serverConfig.Versions = []protocol.VersionNumber{protocol.Version1, protocol.VersionDraft29, protocol.VersionMilestone0_14}
+				server := runServer()
+				defer server.Close()
+				conf := &quic.Config{
+					Versions: []protocol.VersionNumber{7, protocol.VersionDraft29, protocol.VersionMilestone0_14},
+				}
+				sess, err := quic.DialAddr(
+					fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					conf,
+				)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sess.(versioner).GetVersion()).To(Equal(protocol.VersionDraft29))
+				Expect(sess.Close()).To(Succeed())
+			})
 		})
 	}
 
@@ -127,6 +127,25 @@ var _ = Describe("HTTP tests", func() {
 				Expect(string(body)).To(Equal("Hello, World!\n"))
 			})
 
+			It("negotiates the ALPN of the QUIC version", func() {
+				sess, err := quic.DialAddr(
+					"localhost:"+port,
+					&tls.Config{
+						RootCAs:    testdata.GetRootCA(),
+						NextProtos: []string{"h3", "h3-29", "h3-24"},
+					},
+					&quic.Config{Versions: []protocol.VersionNumber{version}},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer sess.Close()
+				expected := map[protocol.VersionNumber]string{
+					protocol.Version1:             "h3",
+					protocol.VersionDraft29:       "h3-29",
+					protocol.VersionMilestone0_14: "h3-24",
+				}[version]
+				Expect(sess.ConnectionState().NegotiatedProtocol).To(Equal(expected))
+			})
+
 			It("sets and gets request headers", func() {
 				handlerCalled := make(chan struct{})
 				mux.HandleFunc("/headers/request", func(w http.ResponseWriter, r *http.Request) {
@@ -7,6 +7,8 @@ import (
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
 )
 
 // Connection is a UDP connection
@@ -65,6 +67,9 @@ var NoDelay DelayCallback = func(Direction, []byte) time.Duration {
 	return 0
 }
 
+// MarkCECallback is a callback that determines which packets get marked ECN-CE.
+type MarkCECallback func(dir Direction, packet []byte) bool
+
 // Opts are proxy options.
 type Opts struct {
 	// The address this proxy proxies packets to.
@@ -75,6 +80,9 @@ type Opts struct {
 	// simulating a connection with non-zero RTTs.
 	// Note that the RTT is the sum of the delay for the incoming and the outgoing packet.
 	DelayPacket DelayCallback
+	// MarkCE determines whether a packet gets marked ECN-CE.
+	// If set, all other packets are marked ECT(0), simulating an ECN-capable path.
+	MarkCE MarkCECallback
 }
 
 // QuicProxy is a QUIC proxy that can drop and delay packets.
@@ -86,6 +94,11 @@ type QuicProxy struct {
 
 	dropPacket  DropCallback
 	delayPacket DelayCallback
+	markCE      MarkCECallback
+
+	// the TOS field (IPv4) or the traffic class (IPv6) is set on the socket before writing a packet
+	// Only used if markCE is set.
+	writeMutex sync.Mutex
 
 	timerID uint64
 	timers  map[uint64]*time.Timer
@@ -130,6 +143,7 @@ func NewQuicProxy(local string, opts *Opts) (*QuicProxy, error) {
 		serverAddr:  raddr,
 		dropPacket:  packetDropper,
 		delayPacket: packetDelayer,
+		markCE:      opts.MarkCE,
 		timers:      make(map[uint64]*time.Timer),
 		logger:      utils.DefaultLogger.WithPrefix("proxy"),
 	}
@@ -218,7 +232,7 @@ func (p *QuicProxy) runProxy() error {
 			p.timerID++
 			id := p.timerID
 			timer := time.AfterFunc(delay, func() {
-				_, _ = conn.ServerConn.Write(raw) // TODO: handle error
+				_ = p.write(conn.ServerConn, nil, DirectionIncoming, raw) // TODO: handle error
 
 				p.mutex.Lock()
 				delete(p.timers, id)
@@ -230,7 +244,7 @@ func (p *QuicProxy) runProxy() error {
 			if p.logger.Debug() {
 				p.logger.Debugf("forwarding incoming packet (%d bytes) to %s", n, conn.ServerConn.RemoteAddr())
 			}
-			if _, err := conn.ServerConn.Write(raw); err != nil {
+			if err := p.write(conn.ServerConn, nil, DirectionIncoming, raw); err != nil {
 				return err
 			}
 		}
@@ -264,7 +278,7 @@ func (p *QuicProxy) runConnection(conn *connection) error {
 			p.timerID++
 			id := p.timerID
 			timer := time.AfterFunc(delay, func() {
-				_, _ = p.conn.WriteToUDP(raw, conn.ClientAddr) // TODO: handle error
+				_ = p.write(p.conn, conn.ClientAddr, DirectionOutgoing, raw) // TODO: handle error
 				p.mutex.Lock()
 				delete(p.timers, id)
 				p.mutex.Unlock()
@@ -275,9 +289,48 @@ func (p *QuicProxy) runConnection(conn *connection) error {
 			if p.logger.Debug() {
 				p.logger.Debugf("forwarding outgoing packet (%d bytes) to %s", n, conn.ClientAddr)
 			}
-			if _, err := p.conn.WriteToUDP(raw, conn.ClientAddr); err != nil {
+			if err := p.write(p.conn, conn.ClientAddr, DirectionOutgoing, raw); err != nil {
 				return err
 			}
 		}
 	}
 }
+
+// write sends a packet on conn.
+// If addr is nil, conn must be a connected UDP socket.
+func (p *QuicProxy) write(conn *net.UDPConn, addr *net.UDPAddr, dir Direction, raw []byte) error {
+	if p.markCE != nil {
+		p.writeMutex.Lock()
+		defer p.writeMutex.Unlock()
+
+		ecn := protocol.ECT0
+		if p.markCE(dir, raw) {
+			if p.logger.Debug() {
+				p.logger.Debugf("marking %s packet (%d bytes) ECN-CE", dir, len(raw))
+			}
+			ecn = protocol.ECNCE
+		}
+		if err := setECN(conn, addr, ecn); err != nil {
+			return err
+		}
+	}
+	var err error
+	if addr == nil {
+		_, err = conn.Write(raw)
+	} else {
+		_, err = conn.WriteToUDP(raw, addr)
+	}
+	return err
+}
+
+// setECN sets the ECN codepoint of packets sent on conn.
+// If addr is nil, conn must be a connected UDP socket.
+func setECN(conn *net.UDPConn, addr *net.UDPAddr, ecn protocol.ECN) error {
+	if addr == nil {
+		addr = conn.RemoteAddr().(*net.UDPAddr)
+	}
+	if addr.IP.To4() != nil {
+		return ipv4.NewConn(conn).SetTOS(int(ecn))
+	}
+	return ipv6.NewConn(conn).SetTrafficClass(int(ecn))
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/wire"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"golang.org/x/net/ipv6"
 )
 
 type packetData []byte
@@ -117,6 +118,36 @@ var _ = Describe("QUIC Proxy", func() {
 		})
 	})
 
+	It("marks packets ECN-CE over IPv6", func() {
+		serverConn, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback})
+		if err != nil {
+			Skip(fmt.Sprintf("IPv6 not supported: %s", err))
+		}
+		defer serverConn.Close()
+		pconn := ipv6.NewPacketConn(serverConn)
+		if err := pconn.SetControlMessage(ipv6.FlagTrafficClass, true); err != nil {
+			Skip(fmt.Sprintf("reading the traffic class not supported: %s", err))
+		}
+
+		proxy, err := NewQuicProxy("[::1]:0", &Opts{
+			RemoteAddr: serverConn.LocalAddr().String(),
+			MarkCE:     func(d Direction, _ []byte) bool { return d == DirectionIncoming },
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+		conn, err := net.DialUDP("udp6", nil, proxy.LocalAddr().(*net.UDPAddr))
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		_, err = conn.Write(makePacket(1, []byte("foobar")))
+		Expect(err).ToNot(HaveOccurred())
+
+		buf := make([]byte, protocol.MaxReceivePacketSize)
+		_, cm, _, err := pconn.ReadFrom(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cm).ToNot(BeNil())
+		Expect(protocol.ECNFromTOS(byte(cm.TrafficClass))).To(Equal(protocol.ECNCE))
+	})
+
 	Context("Proxy tests", func() {
 		var (
 			serverConn            *net.UDPConn
@@ -283,6 +314,29 @@ var _ = Describe("QUIC Proxy", func() {
 			})
 		})
 
+		Context("ECN marking", func() {
+			It("relays packets marked ECN-CE", func() {
+				var counter int32
+				opts := &Opts{
+					RemoteAddr: serverConn.LocalAddr().String(),
+					MarkCE: func(d Direction, _ []byte) bool {
+						if d != DirectionIncoming {
+							return false
+						}
+						return atomic.AddInt32(&counter, 1)%2 == 1
+					},
+				}
+				startProxy(opts)
+
+				for i := 1; i <= 4; i++ {
+					_, err := clientConn.Write(makePacket(protocol.PacketNumber(i), []byte("foobar"+strconv.Itoa(i))))
+					Expect(err).ToNot(HaveOccurred())
+				}
+				Eventually(serverReceivedPackets).Should(HaveLen(4))
+				Expect(atomic.LoadInt32(&counter)).To(BeEquivalentTo(4))
+			})
+		})
+
 		Context("Delay Callback", func() {
 			expectDelay := func(startTime time.Time, rtt time.Duration, numRTTs int) {
 				expectedReceiveTime := startTime.Add(time.Duration(numRTTs) * rtt)
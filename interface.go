@@ -44,6 +44,50 @@ type TokenStore interface {
 	Put(key string, token *ClientToken)
 }
 
+// A KeyValueStore is a persistent store, used by NewPersistentTokenStore and NewPersistentClientSessionCache.
+// It must be safe for concurrent use.
+// Implementations can share the store between multiple processes.
+type KeyValueStore interface {
+	// Get returns the value stored for the key.
+	// It returns nil if no value is stored, or if the value expired.
+	Get(key string) ([]byte, error)
+	// Put stores a value for the key, replacing any value that was stored before.
+	// The value must not be returned after the expiry time.
+	// If expiry is the zero value, the value doesn't expire.
+	Put(key string, value []byte, expiry time.Time) error
+	// Take returns the value stored for the key, and deletes it.
+	// If Take is called concurrently (even from different processes), only one caller receives the value.
+	// It returns nil if no value is stored, or if the value expired.
+	Take(key string) ([]byte, error)
+	// Update replaces the value stored for the key by the value returned by update.
+	// update is called with the value currently stored (nil if no value is stored, or if the value expired).
+	// If update returns a nil value, the value is deleted.
+	// The value must not be modified by any other caller (even from different processes) while update is running.
+	Update(key string, update func(value []byte) ([]byte, time.Time)) error
+}
+
+// A PreferredAddress is an address that the server asks clients to migrate to after the handshake.
+// At least one of IPv4 and IPv6 must be set.
+type PreferredAddress struct {
+	// IPv4 is the address used by clients that connected over IPv4.
+	IPv4 *net.UDPAddr
+	// IPv6 is the address used by clients that connected over IPv6.
+	IPv6 *net.UDPAddr
+}
+
+// A ConnectionIDGenerator generates the connection IDs that a server uses.
+// It can be used to encode routing information into connection IDs,
+// such that a load balancer can route all packets of a connection to the same server.
+// The quiclb package implements a ConnectionIDGenerator that uses QUIC-LB style encoding.
+type ConnectionIDGenerator interface {
+	// GenerateConnectionID generates a new connection ID.
+	// Connection IDs must be unique, and they must be ConnectionIDLen bytes long.
+	GenerateConnectionID() ([]byte, error)
+	// ConnectionIDLen is the length of the connection IDs.
+	// It must be a value between 4 and 20.
+	ConnectionIDLen() int
+}
+
 // An ErrorCode is an application-defined error code.
 // Valid values range between 0 and MAX_UINT62.
 type ErrorCode = protocol.ApplicationErrorCode
@@ -102,6 +146,9 @@ type Stream interface {
 	// with the connection. It is equivalent to calling both
 	// SetReadDeadline and SetWriteDeadline.
 	SetDeadline(t time.Time) error
+	// FlowControlStats returns the flow control statistics of the stream.
+	// Warning: This API should not be considered stable and might change soon.
+	FlowControlStats() FlowControlStats
 }
 
 // A ReceiveStream is a unidirectional Receive Stream.
@@ -114,6 +161,8 @@ type ReceiveStream interface {
 	CancelRead(ErrorCode)
 	// see Stream.SetReadDealine
 	SetReadDeadline(t time.Time) error
+	// see Stream.FlowControlStats
+	FlowControlStats() FlowControlStats
 }
 
 // A SendStream is a unidirectional Send Stream.
@@ -130,6 +179,8 @@ type SendStream interface {
 	Context() context.Context
 	// see Stream.SetWriteDeadline
 	SetWriteDeadline(t time.Time) error
+	// see Stream.FlowControlStats
+	FlowControlStats() FlowControlStats
 }
 
 // StreamError is returned by Read and Write when the peer cancels the stream.
@@ -161,6 +212,12 @@ type Session interface {
 	// If the error is non-nil, it satisfies the net.Error interface.
 	// If the session was closed due to a timeout, Timeout() will be true.
 	OpenStreamSync(context.Context) (Stream, error)
+	// OpenStreamWithOptions opens a new bidirectional QUIC stream, like OpenStream,
+	// and applies the options to the stream.
+	OpenStreamWithOptions(*StreamOptions) (Stream, error)
+	// OpenStreamSyncWithOptions opens a new bidirectional QUIC stream, like OpenStreamSync,
+	// and applies the options to the stream.
+	OpenStreamSyncWithOptions(context.Context, *StreamOptions) (Stream, error)
 	// OpenUniStream opens a new outgoing unidirectional QUIC stream.
 	// If the error is non-nil, it satisfies the net.Error interface.
 	// When reaching the peer's stream limit, Temporary() will be true.
@@ -186,6 +243,14 @@ type Session interface {
 	// ConnectionState returns basic details about the QUIC connection.
 	// Warning: This API should not be considered stable and might change soon.
 	ConnectionState() tls.ConnectionState
+	// InitiateKeyUpdate initiates an update of the 1-RTT keys.
+	// The keys are updated when the next packet is sent, as soon as a key update is permitted,
+	// i.e. after the peer acknowledged a packet sent with the current keys.
+	// It returns an error if the handshake hasn't completed yet.
+	InitiateKeyUpdate() error
+	// FlowControlStats returns the connection-level flow control statistics.
+	// Warning: This API should not be considered stable and might change soon.
+	FlowControlStats() FlowControlStats
 
 	Scheduler() ResponseWriterScheduler
 
@@ -195,18 +260,74 @@ type Session interface {
 }
 
 // An EarlySession is a session that is handshaking.
-// Data sent during the handshake is encrypted using the forward secure keys.
+// On the server side, data sent during the handshake is encrypted using the forward secure keys.
+// On the client side, data sent during the handshake is sent as 0-RTT data, if the server
+// accepts 0-RTT. If 0-RTT is rejected, the data is retransmitted using the forward secure keys.
+// 0-RTT data can be replayed by an attacker, so only idempotent requests should be sent in 0-RTT.
 // When using client certificates, the client's identity is only verified
 // after completion of the handshake.
 type EarlySession interface {
 	Session
 
 	// Blocks until the handshake completes (or fails).
-	// Data sent before completion of the handshake is encrypted with 1-RTT keys.
+	// For the server, data sent before completion of the handshake is encrypted with 1-RTT keys.
 	// Note that the client's identity hasn't been verified yet.
 	HandshakeComplete() context.Context
 }
 
+// FlowControlStats are the flow control statistics of a stream or of the connection.
+type FlowControlStats struct {
+	// SendWindow is the offset up to which the peer allows us to send.
+	SendWindow uint64
+	// BytesSent is the number of bytes sent.
+	BytesSent uint64
+	// BlockedCount is the number of times sending was blocked by flow control.
+	BlockedCount uint64
+	// ReceiveWindow is the offset up to which we allow the peer to send.
+	ReceiveWindow uint64
+	// ReceiveWindowSize is the current size of the receive window.
+	// It is increased by auto-tuning, if the peer is sending faster than the window allows.
+	ReceiveWindowSize uint64
+	// MaxReceiveWindowSize is the size up to which auto-tuning increases the receive window.
+	MaxReceiveWindowSize uint64
+	// BytesRead is the number of bytes read by the application.
+	BytesRead uint64
+	// HighestReceived is the highest offset received from the peer.
+	HighestReceived uint64
+	// PeerBlockedCount is the number of times the peer told us that it was blocked by flow control,
+	// using DATA_BLOCKED or STREAM_DATA_BLOCKED frames.
+	PeerBlockedCount uint64
+	// WindowIncreaseCount is the number of times auto-tuning increased the receive window size.
+	WindowIncreaseCount uint64
+}
+
+// StreamOptions are options that apply to a single stream.
+type StreamOptions struct {
+	// ReceiveWindow is the size of the receive flow control window of the stream.
+	// The peer is always allowed to use the initial window announced in the transport parameters,
+	// so a smaller value only takes effect when the window is updated.
+	// If zero, the window size is chosen based on the Config.
+	ReceiveWindow uint64
+	// MaxReceiveWindow is the size up to which auto-tuning increases the receive window of the stream.
+	// If zero, Config.MaxReceiveStreamFlowControlWindow is used.
+	// If ReceiveWindow is larger than MaxReceiveWindow, MaxReceiveWindow is used as the window size.
+	MaxReceiveWindow uint64
+}
+
+// A MultipathSession is a session that can use multiple paths.
+// All sessions implement this interface, but paths can only be used if both endpoints enabled the multipath extension.
+type MultipathSession interface {
+	Session
+
+	// AddPath opens a new path that sends and receives packets on pconn.
+	// It can only be called by the client, after completion of the handshake.
+	// The path is validated before it is used for sending data.
+	// pconn is closed when the path or the session is closed.
+	AddPath(pconn net.PacketConn) error
+	// Paths returns information about all paths, including the initial path.
+	Paths() []PathInfo
+}
+
 // Config contains all configuration data needed for a QUIC server or client.
 type Config struct {
 	// The QUIC versions that can be negotiated.
@@ -220,6 +341,11 @@ type Config struct {
 	// If used for a server, or dialing on a packet conn, a 4 byte connection ID will be used.
 	// When dialing on a packet conn, the ConnectionIDLength value must be the same for every Dial call.
 	ConnectionIDLength int
+	// ConnectionIDGenerator generates the connection IDs used by the server.
+	// If set, ConnectionIDLength must either be 0 or equal ConnectionIDGenerator.ConnectionIDLen().
+	// If not set, random connection IDs are used.
+	// This option is only valid for the server.
+	ConnectionIDGenerator ConnectionIDGenerator
 	// HandshakeTimeout is the maximum duration that the cryptographic handshake may take.
 	// If the timeout is exceeded, the connection is closed.
 	// If this value is zero, the timeout is set to 10 seconds.
@@ -237,6 +363,12 @@ type Config struct {
 	//   * else, that it was issued within the last 24 hours.
 	// This option is only valid for the server.
 	AcceptToken func(clientAddr net.Addr, token *Token) bool
+	// Accept0RTT determines if 0-RTT data is accepted.
+	// It is called with the ID of the session ticket the client used to resume the session.
+	// Every session ticket ID is unique, so it can be used to detect replayed 0-RTT data.
+	// If not set, all 0-RTT data that passes the TLS checks is accepted.
+	// This option is only valid for the server, and only used for sessions accepted by ListenEarly.
+	Accept0RTT func(clientAddr net.Addr, ticketID []byte) bool
 	// The TokenStore stores tokens received from the server.
 	// Tokens are used to skip address validation on future connection attempts.
 	// The key used to store tokens is the ServerName from the tls.Config, if set
@@ -261,6 +393,66 @@ type Config struct {
 	StatelessResetKey []byte
 	// KeepAlive defines whether this peer will periodically send a packet to keep the connection alive.
 	KeepAlive bool
+	// BatchedIO enables reading and writing multiple UDP packets per system call (using recvmmsg and sendmmsg).
+	// If supported by the kernel, UDP GSO is used for sending trains of equal-sized packets,
+	// and UDP GRO is used when receiving packets.
+	// This option is only supported on Linux, and ignored on other platforms.
+	// When dialing multiple connections on the same packet conn, the option used for the first Dial call applies.
+	BatchedIO bool
+	// EnableECN enables ECN on net.PacketConns passed to Listen and Dial.
+	// This sets socket options on the connection, such that outgoing packets are marked ECT(0)
+	// and the ECN codepoint of incoming packets can be read.
+	// ECN is always used on connections created by ListenAddr and DialAddr.
+	// This option is only supported on Linux, and ignored on other platforms.
+	// In any case, ECN can be disabled by setting the QUIC_GO_DISABLE_ECN environment variable.
+	EnableECN bool
+	// DisablePacing disables pacing of outgoing packets.
+	// Packets are then sent as fast as the congestion controller allows.
+	DisablePacing bool
+	// InitialPacingBurst is the number of bytes that can be sent at the beginning of a connection,
+	// before the pacer starts spreading out packets.
+	// If not set, it defaults to the initial congestion window.
+	InitialPacingBurst uint64
+	// MaxPacingBurst is the maximum number of bytes that the pacer allows to be sent in a single burst,
+	// for example after the connection was idle.
+	// If not set, it defaults to 10 full-size packets.
+	MaxPacingBurst uint64
+	// PreferredAddress is sent to clients in the preferred_address transport parameter.
+	// Clients validate the path to this address after the handshake, and then migrate the connection to it.
+	// This can be used to hand off connections from an anycast address to a unicast address.
+	// Packets sent to the preferred address must be received on the same net.PacketConn as the packets
+	// sent to the address that the server is listening on, e.g. by listening on the unspecified address.
+	// This option is only valid for the server.
+	PreferredAddress *PreferredAddress
+	// KeyUpdateInterval is the maximum number of packets that are sent or received with the same 1-RTT keys.
+	// When this number is reached, a key update is initiated.
+	// If not set, it defaults to 100000 packets.
+	KeyUpdateInterval uint64
+	// KeyUpdateIntervalBytes is the maximum number of bytes that are sent or received with the same 1-RTT keys.
+	// When this number is reached, a key update is initiated.
+	// If not set, keys are only updated based on the number of packets.
+	KeyUpdateIntervalBytes uint64
+	// AdmissionController limits the sessions that the server accepts.
+	// If not set, only the number of sessions in the accept queue is limited.
+	// This option is only valid for the server.
+	AdmissionController *AdmissionController
+	// EnableMultipath enables the (experimental) multipath extension.
+	// It is only used if both endpoints enable it.
+	// The client then can open additional paths using MultipathSession.AddPath.
+	// Warning: The multipath extension is not interoperable with other QUIC implementations.
+	EnableMultipath bool
+	// NewPathScheduler creates the PathScheduler used by a session that uses the multipath extension.
+	// If not set, a MinRTTPathScheduler is used.
+	NewPathScheduler func() PathScheduler
+	// Logger receives the log messages of the client or server and of its sessions.
+	// If not set, messages are logged using the log package,
+	// at the log level set by the QUIC_GO_LOG_LEVEL environment variable.
+	Logger Logger
+	// Metrics receives the metrics of the client or server and of its sessions,
+	// e.g. the number of handshakes, lost packets and RTT samples.
+	// A MetricsRegistry can be used to serve them in the Prometheus text format.
+	// If not set, no metrics are collected.
+	Metrics Metrics
 	// QUIC Event Tracer.
 	// Warning: Experimental. This API should not be considered stable and will change soon.
 	QuicTracer quictrace.Tracer
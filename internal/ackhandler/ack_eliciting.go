@@ -4,8 +4,12 @@ import "github.com/lucas-clemente/quic-go/internal/wire"
 
 // IsFrameAckEliciting returns true if the frame is ack-eliciting.
 func IsFrameAckEliciting(f wire.Frame) bool {
-	_, ok := f.(*wire.AckFrame)
-	return !ok
+	switch f.(type) {
+	case *wire.AckFrame, *wire.AckMPFrame:
+		return false
+	default:
+		return true
+	}
 }
 
 // HasAckElicitingFrames returns true if at least one frame is ack-eliciting.
@@ -11,6 +11,7 @@ import (
 var _ = Describe("ack-eliciting frames", func() {
 	for fl, el := range map[wire.Frame]bool{
 		&wire.AckFrame{}:             false,
+		&wire.AckMPFrame{}:           false,
 		&wire.DataBlockedFrame{}:     true,
 		&wire.ConnectionCloseFrame{}: true,
 		&wire.PingFrame{}:            true,
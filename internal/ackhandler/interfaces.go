@@ -32,11 +32,11 @@ type SentPacketHandler interface {
 	// The SendMode determines if and what kind of packets can be sent.
 	SendMode() SendMode
 	// TimeUntilSend is the time when the next packet should be sent.
-	// It is used for pacing packets.
+	// It is used for pacing packets. If pacing is disabled, it returns the zero time.
 	TimeUntilSend() time.Time
 	// ShouldSendNumPackets returns the number of packets that should be sent immediately.
 	// It always returns a number greater or equal than 1.
-	// A number greater than 1 is returned when the pacing delay is smaller than the minimum pacing delay.
+	// A number greater than 1 is returned when the pacer has budget for multiple packets.
 	// Note that the number of packets is only calculated based on the pacing algorithm.
 	// Before sending any packet, SendingAllowed() must be called to learn if we can actually send it.
 	ShouldSendNumPackets() int
@@ -57,7 +57,7 @@ type SentPacketHandler interface {
 
 // ReceivedPacketHandler handles ACKs needed to send for incoming packets
 type ReceivedPacketHandler interface {
-	ReceivedPacket(pn protocol.PacketNumber, encLevel protocol.EncryptionLevel, rcvTime time.Time, shouldInstigateAck bool)
+	ReceivedPacket(pn protocol.PacketNumber, ecn protocol.ECN, encLevel protocol.EncryptionLevel, rcvTime time.Time, shouldInstigateAck bool)
 	IgnoreBelow(protocol.PacketNumber)
 	DropPackets(protocol.EncryptionLevel)
 
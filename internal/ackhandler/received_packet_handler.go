@@ -54,17 +54,19 @@ func NewReceivedPacketHandler(
 
 func (h *receivedPacketHandler) ReceivedPacket(
 	pn protocol.PacketNumber,
+	ecn protocol.ECN,
 	encLevel protocol.EncryptionLevel,
 	rcvTime time.Time,
 	shouldInstigateAck bool,
 ) {
 	switch encLevel {
 	case protocol.EncryptionInitial:
-		h.initialPackets.ReceivedPacket(pn, rcvTime, shouldInstigateAck)
+		h.initialPackets.ReceivedPacket(pn, ecn, rcvTime, shouldInstigateAck)
 	case protocol.EncryptionHandshake:
-		h.handshakePackets.ReceivedPacket(pn, rcvTime, shouldInstigateAck)
-	case protocol.Encryption1RTT:
-		h.oneRTTPackets.ReceivedPacket(pn, rcvTime, shouldInstigateAck)
+		h.handshakePackets.ReceivedPacket(pn, ecn, rcvTime, shouldInstigateAck)
+	case protocol.Encryption0RTT, protocol.Encryption1RTT:
+		// 0-RTT and 1-RTT packets share the same packet number space
+		h.oneRTTPackets.ReceivedPacket(pn, ecn, rcvTime, shouldInstigateAck)
 	default:
 		panic(fmt.Sprintf("received packet with unknown encryption level: %s", encLevel))
 	}
@@ -81,6 +83,9 @@ func (h *receivedPacketHandler) DropPackets(encLevel protocol.EncryptionLevel) {
 		h.initialPackets = nil
 	case protocol.EncryptionHandshake:
 		h.handshakePackets = nil
+	case protocol.Encryption0RTT:
+		// Nothing to do here.
+		// 0-RTT packets are acknowledged in 1-RTT packets.
 	default:
 		panic(fmt.Sprintf("Cannot drop keys for encryption level %s", encLevel))
 	}
@@ -110,6 +115,7 @@ func (h *receivedPacketHandler) GetAckFrame(encLevel protocol.EncryptionLevel) *
 			ack = h.handshakePackets.GetAckFrame()
 		}
 	case protocol.Encryption1RTT:
+		// 0-RTT packets can't contain ACK frames
 		return h.oneRTTPackets.GetAckFrame()
 	default:
 		return nil
@@ -25,12 +25,12 @@ var _ = Describe("Received Packet Handler", func() {
 
 	It("generates ACKs for different packet number spaces", func() {
 		sendTime := time.Now().Add(-time.Second)
-		handler.ReceivedPacket(2, protocol.EncryptionInitial, sendTime, true)
-		handler.ReceivedPacket(1, protocol.EncryptionHandshake, sendTime, true)
-		handler.ReceivedPacket(5, protocol.Encryption1RTT, sendTime, true)
-		handler.ReceivedPacket(3, protocol.EncryptionInitial, sendTime, true)
-		handler.ReceivedPacket(2, protocol.EncryptionHandshake, sendTime, true)
-		handler.ReceivedPacket(4, protocol.Encryption1RTT, sendTime, true)
+		handler.ReceivedPacket(2, protocol.ECNNon, protocol.EncryptionInitial, sendTime, true)
+		handler.ReceivedPacket(1, protocol.ECNNon, protocol.EncryptionHandshake, sendTime, true)
+		handler.ReceivedPacket(5, protocol.ECNNon, protocol.Encryption1RTT, sendTime, true)
+		handler.ReceivedPacket(3, protocol.ECNNon, protocol.EncryptionInitial, sendTime, true)
+		handler.ReceivedPacket(2, protocol.ECNNon, protocol.EncryptionHandshake, sendTime, true)
+		handler.ReceivedPacket(4, protocol.ECNNon, protocol.Encryption1RTT, sendTime, true)
 		initialAck := handler.GetAckFrame(protocol.EncryptionInitial)
 		Expect(initialAck).ToNot(BeNil())
 		Expect(initialAck.AckRanges).To(HaveLen(1))
@@ -50,8 +50,8 @@ var _ = Describe("Received Packet Handler", func() {
 
 	It("drops Initial packets", func() {
 		sendTime := time.Now().Add(-time.Second)
-		handler.ReceivedPacket(2, protocol.EncryptionInitial, sendTime, true)
-		handler.ReceivedPacket(1, protocol.EncryptionHandshake, sendTime, true)
+		handler.ReceivedPacket(2, protocol.ECNNon, protocol.EncryptionInitial, sendTime, true)
+		handler.ReceivedPacket(1, protocol.ECNNon, protocol.EncryptionHandshake, sendTime, true)
 		Expect(handler.GetAckFrame(protocol.EncryptionInitial)).ToNot(BeNil())
 		handler.DropPackets(protocol.EncryptionInitial)
 		Expect(handler.GetAckFrame(protocol.EncryptionInitial)).To(BeNil())
@@ -60,11 +60,25 @@ var _ = Describe("Received Packet Handler", func() {
 
 	It("drops Handshake packets", func() {
 		sendTime := time.Now().Add(-time.Second)
-		handler.ReceivedPacket(1, protocol.EncryptionHandshake, sendTime, true)
-		handler.ReceivedPacket(2, protocol.Encryption1RTT, sendTime, true)
+		handler.ReceivedPacket(1, protocol.ECNNon, protocol.EncryptionHandshake, sendTime, true)
+		handler.ReceivedPacket(2, protocol.ECNNon, protocol.Encryption1RTT, sendTime, true)
 		Expect(handler.GetAckFrame(protocol.EncryptionHandshake)).ToNot(BeNil())
 		handler.DropPackets(protocol.EncryptionInitial)
 		Expect(handler.GetAckFrame(protocol.EncryptionHandshake)).To(BeNil())
 		Expect(handler.GetAckFrame(protocol.Encryption1RTT)).ToNot(BeNil())
 	})
+
+	It("acknowledges 0-RTT packets in 1-RTT ACKs", func() {
+		sendTime := time.Now().Add(-time.Second)
+		handler.ReceivedPacket(2, protocol.ECNNon, protocol.Encryption0RTT, sendTime, true)
+		handler.ReceivedPacket(3, protocol.ECNNon, protocol.Encryption1RTT, sendTime, true)
+		Expect(handler.GetAckFrame(protocol.Encryption0RTT)).To(BeNil())
+		ack := handler.GetAckFrame(protocol.Encryption1RTT)
+		Expect(ack).ToNot(BeNil())
+		Expect(ack.AckRanges).To(Equal([]wire.AckRange{{Smallest: 2, Largest: 3}}))
+		// dropping the 0-RTT keys doesn't drop the 1-RTT packet number space
+		handler.DropPackets(protocol.Encryption0RTT)
+		handler.ReceivedPacket(4, protocol.ECNNon, protocol.Encryption1RTT, sendTime, true)
+		Expect(handler.GetAckFrame(protocol.Encryption1RTT)).ToNot(BeNil())
+	})
 })
@@ -16,6 +16,9 @@ type receivedPacketTracker struct {
 
 	packetHistory *receivedPacketHistory
 
+	// the number of packets received with each ECN codepoint
+	ect0, ect1, ecnce uint64
+
 	maxAckDelay time.Duration
 	rttStats    *congestion.RTTStats
 
@@ -44,11 +47,25 @@ func newReceivedPacketTracker(
 	}
 }
 
-func (h *receivedPacketTracker) ReceivedPacket(packetNumber protocol.PacketNumber, rcvTime time.Time, shouldInstigateAck bool) {
+func (h *receivedPacketTracker) ReceivedPacket(packetNumber protocol.PacketNumber, ecn protocol.ECN, rcvTime time.Time, shouldInstigateAck bool) {
 	if packetNumber < h.ignoreBelow {
 		return
 	}
 
+	switch ecn {
+	case protocol.ECT0:
+		h.ect0++
+	case protocol.ECT1:
+		h.ect1++
+	case protocol.ECNCE:
+		h.ecnce++
+		// Report congestion to the peer as soon as possible.
+		if h.logger.Debug() {
+			h.logger.Debugf("\tQueueing ACK because packet %#x was CE-marked.", packetNumber)
+		}
+		h.ackQueued = true
+	}
+
 	isMissing := h.isMissing(packetNumber)
 	if packetNumber >= h.largestObserved {
 		h.largestObserved = packetNumber
@@ -177,6 +194,9 @@ func (h *receivedPacketTracker) GetAckFrame() *wire.AckFrame {
 		// Make sure that the DelayTime is always positive.
 		// This is not guaranteed on systems that don't have a monotonic clock.
 		DelayTime: utils.MaxDuration(0, now.Sub(h.largestObservedReceivedTime)),
+		ECT0:      h.ect0,
+		ECT1:      h.ect1,
+		ECNCE:     h.ecnce,
 	}
 
 	h.lastAck = ack
@@ -25,7 +25,7 @@ var _ = Describe("Received Packet Tracker", func() {
 
 	Context("accepting packets", func() {
 		It("saves the time when each packet arrived", func() {
-			tracker.ReceivedPacket(protocol.PacketNumber(3), time.Now(), true)
+			tracker.ReceivedPacket(protocol.PacketNumber(3), protocol.ECNNon, time.Now(), true)
 			Expect(tracker.largestObservedReceivedTime).To(BeTemporally("~", time.Now(), 10*time.Millisecond))
 		})
 
@@ -33,7 +33,7 @@ var _ = Describe("Received Packet Tracker", func() {
 			now := time.Now()
 			tracker.largestObserved = 3
 			tracker.largestObservedReceivedTime = now.Add(-1 * time.Second)
-			tracker.ReceivedPacket(5, now, true)
+			tracker.ReceivedPacket(5, protocol.ECNNon, now, true)
 			Expect(tracker.largestObserved).To(Equal(protocol.PacketNumber(5)))
 			Expect(tracker.largestObservedReceivedTime).To(Equal(now))
 		})
@@ -43,17 +43,52 @@ var _ = Describe("Received Packet Tracker", func() {
 			timestamp := now.Add(-1 * time.Second)
 			tracker.largestObserved = 5
 			tracker.largestObservedReceivedTime = timestamp
-			tracker.ReceivedPacket(4, now, true)
+			tracker.ReceivedPacket(4, protocol.ECNNon, now, true)
 			Expect(tracker.largestObserved).To(Equal(protocol.PacketNumber(5)))
 			Expect(tracker.largestObservedReceivedTime).To(Equal(timestamp))
 		})
 	})
 
+	Context("ECN", func() {
+		It("counts the ECN codepoints", func() {
+			tracker.ReceivedPacket(1, protocol.ECT0, time.Now(), true)
+			tracker.ReceivedPacket(2, protocol.ECT0, time.Now(), true)
+			tracker.ReceivedPacket(3, protocol.ECT1, time.Now(), true)
+			tracker.ReceivedPacket(4, protocol.ECNNon, time.Now(), true)
+			tracker.ReceivedPacket(5, protocol.ECNCE, time.Now(), true)
+			ack := tracker.GetAckFrame()
+			Expect(ack).ToNot(BeNil())
+			Expect(ack.HasECN()).To(BeTrue())
+			Expect(ack.ECT0).To(BeEquivalentTo(2))
+			Expect(ack.ECT1).To(BeEquivalentTo(1))
+			Expect(ack.ECNCE).To(BeEquivalentTo(1))
+		})
+
+		It("doesn't include ECN counts if no ECN-marked packets were received", func() {
+			tracker.ReceivedPacket(1, protocol.ECNNon, time.Now(), true)
+			ack := tracker.GetAckFrame()
+			Expect(ack).ToNot(BeNil())
+			Expect(ack.HasECN()).To(BeFalse())
+		})
+
+		It("queues an ACK when a CE-marked packet is received", func() {
+			tracker.ReceivedPacket(1, protocol.ECT0, time.Now(), true)
+			Expect(tracker.GetAckFrame()).ToNot(BeNil())
+			tracker.ReceivedPacket(2, protocol.ECT0, time.Now(), true)
+			Expect(tracker.ackQueued).To(BeFalse())
+			tracker.ReceivedPacket(3, protocol.ECNCE, time.Now(), true)
+			Expect(tracker.ackQueued).To(BeTrue())
+			ack := tracker.GetAckFrame()
+			Expect(ack).ToNot(BeNil())
+			Expect(ack.ECNCE).To(BeEquivalentTo(1))
+		})
+	})
+
 	Context("ACKs", func() {
 		Context("queueing ACKs", func() {
 			receiveAndAck10Packets := func() {
 				for i := 1; i <= 10; i++ {
-					tracker.ReceivedPacket(protocol.PacketNumber(i), time.Time{}, true)
+					tracker.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, time.Time{}, true)
 				}
 				Expect(tracker.GetAckFrame()).ToNot(BeNil())
 				Expect(tracker.ackQueued).To(BeFalse())
@@ -61,21 +96,21 @@ var _ = Describe("Received Packet Tracker", func() {
 
 			receiveAndAckPacketsUntilAckDecimation := func() {
 				for i := 1; i <= minReceivedBeforeAckDecimation; i++ {
-					tracker.ReceivedPacket(protocol.PacketNumber(i), time.Time{}, true)
+					tracker.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, time.Time{}, true)
 				}
 				Expect(tracker.GetAckFrame()).ToNot(BeNil())
 				Expect(tracker.ackQueued).To(BeFalse())
 			}
 
 			It("always queues an ACK for the first packet", func() {
-				tracker.ReceivedPacket(1, time.Now(), false)
+				tracker.ReceivedPacket(1, protocol.ECNNon, time.Now(), false)
 				Expect(tracker.ackQueued).To(BeTrue())
 				Expect(tracker.GetAlarmTimeout()).To(BeZero())
 				Expect(tracker.GetAckFrame().DelayTime).To(BeNumerically("~", 0, time.Second))
 			})
 
 			It("works with packet number 0", func() {
-				tracker.ReceivedPacket(0, time.Now(), false)
+				tracker.ReceivedPacket(0, protocol.ECNNon, time.Now(), false)
 				Expect(tracker.ackQueued).To(BeTrue())
 				Expect(tracker.GetAlarmTimeout()).To(BeZero())
 				Expect(tracker.GetAckFrame().DelayTime).To(BeNumerically("~", 0, time.Second))
@@ -85,10 +120,10 @@ var _ = Describe("Received Packet Tracker", func() {
 				receiveAndAck10Packets()
 				p := protocol.PacketNumber(11)
 				for i := 0; i <= 20; i++ {
-					tracker.ReceivedPacket(p, time.Time{}, true)
+					tracker.ReceivedPacket(p, protocol.ECNNon, time.Time{}, true)
 					Expect(tracker.ackQueued).To(BeFalse())
 					p++
-					tracker.ReceivedPacket(p, time.Time{}, true)
+					tracker.ReceivedPacket(p, protocol.ECNNon, time.Time{}, true)
 					Expect(tracker.ackQueued).To(BeTrue())
 					p++
 					// dequeue the ACK frame
@@ -100,49 +135,49 @@ var _ = Describe("Received Packet Tracker", func() {
 				receiveAndAck10Packets()
 				p := protocol.PacketNumber(10000)
 				for i := 0; i < 9; i++ {
-					tracker.ReceivedPacket(p, time.Now(), true)
+					tracker.ReceivedPacket(p, protocol.ECNNon, time.Now(), true)
 					Expect(tracker.ackQueued).To(BeFalse())
 					p++
 				}
 				Expect(tracker.GetAlarmTimeout()).NotTo(BeZero())
-				tracker.ReceivedPacket(p, time.Now(), true)
+				tracker.ReceivedPacket(p, protocol.ECNNon, time.Now(), true)
 				Expect(tracker.ackQueued).To(BeTrue())
 				Expect(tracker.GetAlarmTimeout()).To(BeZero())
 			})
 
 			It("only sets the timer when receiving a ack-eliciting packets", func() {
 				receiveAndAck10Packets()
-				tracker.ReceivedPacket(11, time.Now(), false)
+				tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), false)
 				Expect(tracker.ackQueued).To(BeFalse())
 				Expect(tracker.GetAlarmTimeout()).To(BeZero())
 				rcvTime := time.Now().Add(10 * time.Millisecond)
-				tracker.ReceivedPacket(12, rcvTime, true)
+				tracker.ReceivedPacket(12, protocol.ECNNon, rcvTime, true)
 				Expect(tracker.ackQueued).To(BeFalse())
 				Expect(tracker.GetAlarmTimeout()).To(Equal(rcvTime.Add(protocol.MaxAckDelay)))
 			})
 
 			It("queues an ACK if it was reported missing before", func() {
 				receiveAndAck10Packets()
-				tracker.ReceivedPacket(11, time.Time{}, true)
-				tracker.ReceivedPacket(13, time.Time{}, true)
+				tracker.ReceivedPacket(11, protocol.ECNNon, time.Time{}, true)
+				tracker.ReceivedPacket(13, protocol.ECNNon, time.Time{}, true)
 				ack := tracker.GetAckFrame() // ACK: 1-11 and 13, missing: 12
 				Expect(ack).ToNot(BeNil())
 				Expect(ack.HasMissingRanges()).To(BeTrue())
 				Expect(tracker.ackQueued).To(BeFalse())
-				tracker.ReceivedPacket(12, time.Time{}, false)
+				tracker.ReceivedPacket(12, protocol.ECNNon, time.Time{}, false)
 				Expect(tracker.ackQueued).To(BeTrue())
 			})
 
 			It("doesn't queue an ACK if it was reported missing before, but is below the threshold", func() {
 				receiveAndAck10Packets()
 				// 11 is missing
-				tracker.ReceivedPacket(12, time.Time{}, true)
-				tracker.ReceivedPacket(13, time.Time{}, true)
+				tracker.ReceivedPacket(12, protocol.ECNNon, time.Time{}, true)
+				tracker.ReceivedPacket(13, protocol.ECNNon, time.Time{}, true)
 				ack := tracker.GetAckFrame() // ACK: 1-10, 12-13
 				Expect(ack).ToNot(BeNil())
 				// now receive 11
 				tracker.IgnoreBelow(12)
-				tracker.ReceivedPacket(11, time.Time{}, false)
+				tracker.ReceivedPacket(11, protocol.ECNNon, time.Time{}, false)
 				ack = tracker.GetAckFrame()
 				Expect(ack).To(BeNil())
 			})
@@ -150,10 +185,10 @@ var _ = Describe("Received Packet Tracker", func() {
 			It("doesn't queue an ACK if the packet closes a gap that was not yet reported", func() {
 				receiveAndAckPacketsUntilAckDecimation()
 				p := protocol.PacketNumber(minReceivedBeforeAckDecimation + 1)
-				tracker.ReceivedPacket(p+1, time.Now(), true) // p is missing now
+				tracker.ReceivedPacket(p+1, protocol.ECNNon, time.Now(), true) // p is missing now
 				Expect(tracker.ackQueued).To(BeFalse())
 				Expect(tracker.GetAlarmTimeout()).ToNot(BeZero())
-				tracker.ReceivedPacket(p, time.Now(), true) // p is not missing any more
+				tracker.ReceivedPacket(p, protocol.ECNNon, time.Now(), true) // p is not missing any more
 				Expect(tracker.ackQueued).To(BeFalse())
 			})
 
@@ -164,9 +199,9 @@ var _ = Describe("Received Packet Tracker", func() {
 				receiveAndAckPacketsUntilAckDecimation()
 				p := protocol.PacketNumber(minReceivedBeforeAckDecimation + 1)
 				for i := p; i < p+6; i++ {
-					tracker.ReceivedPacket(i, now, true)
+					tracker.ReceivedPacket(i, protocol.ECNNon, now, true)
 				}
-				tracker.ReceivedPacket(p+10, now, true) // we now know that packets p+7, p+8 and p+9
+				tracker.ReceivedPacket(p+10, protocol.ECNNon, now, true) // we now know that packets p+7, p+8 and p+9
 				Expect(rttStats.MinRTT()).To(Equal(rtt))
 				Expect(tracker.ackAlarm.Sub(now)).To(Equal(rtt / 8))
 				ack := tracker.GetAckFrame()
@@ -181,8 +216,8 @@ var _ = Describe("Received Packet Tracker", func() {
 			})
 
 			It("generates a simple ACK frame", func() {
-				tracker.ReceivedPacket(1, time.Time{}, true)
-				tracker.ReceivedPacket(2, time.Time{}, true)
+				tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
+				tracker.ReceivedPacket(2, protocol.ECNNon, time.Time{}, true)
 				ack := tracker.GetAckFrame()
 				Expect(ack).ToNot(BeNil())
 				Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(2)))
@@ -191,7 +226,7 @@ var _ = Describe("Received Packet Tracker", func() {
 			})
 
 			It("generates an ACK for packet number 0", func() {
-				tracker.ReceivedPacket(0, time.Time{}, true)
+				tracker.ReceivedPacket(0, protocol.ECNNon, time.Time{}, true)
 				ack := tracker.GetAckFrame()
 				Expect(ack).ToNot(BeNil())
 				Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(0)))
@@ -200,26 +235,26 @@ var _ = Describe("Received Packet Tracker", func() {
 			})
 
 			It("sets the delay time", func() {
-				tracker.ReceivedPacket(1, time.Time{}, true)
-				tracker.ReceivedPacket(2, time.Now().Add(-1337*time.Millisecond), true)
+				tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
+				tracker.ReceivedPacket(2, protocol.ECNNon, time.Now().Add(-1337*time.Millisecond), true)
 				ack := tracker.GetAckFrame()
 				Expect(ack).ToNot(BeNil())
 				Expect(ack.DelayTime).To(BeNumerically("~", 1337*time.Millisecond, 50*time.Millisecond))
 			})
 
 			It("uses a 0 delay time if the delay would be negative", func() {
-				tracker.ReceivedPacket(0, time.Now().Add(time.Hour), true)
+				tracker.ReceivedPacket(0, protocol.ECNNon, time.Now().Add(time.Hour), true)
 				ack := tracker.GetAckFrame()
 				Expect(ack).ToNot(BeNil())
 				Expect(ack.DelayTime).To(BeZero())
 			})
 
 			It("saves the last sent ACK", func() {
-				tracker.ReceivedPacket(1, time.Time{}, true)
+				tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
 				ack := tracker.GetAckFrame()
 				Expect(ack).ToNot(BeNil())
 				Expect(tracker.lastAck).To(Equal(ack))
-				tracker.ReceivedPacket(2, time.Time{}, true)
+				tracker.ReceivedPacket(2, protocol.ECNNon, time.Time{}, true)
 				tracker.ackQueued = true
 				ack = tracker.GetAckFrame()
 				Expect(ack).ToNot(BeNil())
@@ -227,8 +262,8 @@ var _ = Describe("Received Packet Tracker", func() {
 			})
 
 			It("generates an ACK frame with missing packets", func() {
-				tracker.ReceivedPacket(1, time.Time{}, true)
-				tracker.ReceivedPacket(4, time.Time{}, true)
+				tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
+				tracker.ReceivedPacket(4, protocol.ECNNon, time.Time{}, true)
 				ack := tracker.GetAckFrame()
 				Expect(ack).ToNot(BeNil())
 				Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(4)))
@@ -240,9 +275,9 @@ var _ = Describe("Received Packet Tracker", func() {
 			})
 
 			It("generates an ACK for packet number 0 and other packets", func() {
-				tracker.ReceivedPacket(0, time.Time{}, true)
-				tracker.ReceivedPacket(1, time.Time{}, true)
-				tracker.ReceivedPacket(3, time.Time{}, true)
+				tracker.ReceivedPacket(0, protocol.ECNNon, time.Time{}, true)
+				tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
+				tracker.ReceivedPacket(3, protocol.ECNNon, time.Time{}, true)
 				ack := tracker.GetAckFrame()
 				Expect(ack).ToNot(BeNil())
 				Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(3)))
@@ -255,8 +290,8 @@ var _ = Describe("Received Packet Tracker", func() {
 
 			It("doesn't add delayed packets to the packetHistory", func() {
 				tracker.IgnoreBelow(7)
-				tracker.ReceivedPacket(4, time.Time{}, true)
-				tracker.ReceivedPacket(10, time.Time{}, true)
+				tracker.ReceivedPacket(4, protocol.ECNNon, time.Time{}, true)
+				tracker.ReceivedPacket(10, protocol.ECNNon, time.Time{}, true)
 				ack := tracker.GetAckFrame()
 				Expect(ack).ToNot(BeNil())
 				Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(10)))
@@ -265,7 +300,7 @@ var _ = Describe("Received Packet Tracker", func() {
 
 			It("deletes packets from the packetHistory when a lower limit is set", func() {
 				for i := 1; i <= 12; i++ {
-					tracker.ReceivedPacket(protocol.PacketNumber(i), time.Time{}, true)
+					tracker.ReceivedPacket(protocol.PacketNumber(i), protocol.ECNNon, time.Time{}, true)
 				}
 				tracker.IgnoreBelow(7)
 				// check that the packets were deleted from the receivedPacketHistory by checking the values in an ACK frame
@@ -279,14 +314,14 @@ var _ = Describe("Received Packet Tracker", func() {
 			// TODO: remove this test when dropping support for STOP_WAITINGs
 			It("handles a lower limit of 0", func() {
 				tracker.IgnoreBelow(0)
-				tracker.ReceivedPacket(1337, time.Time{}, true)
+				tracker.ReceivedPacket(1337, protocol.ECNNon, time.Time{}, true)
 				ack := tracker.GetAckFrame()
 				Expect(ack).ToNot(BeNil())
 				Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(1337)))
 			})
 
 			It("resets all counters needed for the ACK queueing decision when sending an ACK", func() {
-				tracker.ReceivedPacket(1, time.Time{}, true)
+				tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
 				tracker.ackAlarm = time.Now().Add(-time.Minute)
 				Expect(tracker.GetAckFrame()).ToNot(BeNil())
 				Expect(tracker.packetsReceivedSinceLastAck).To(BeZero())
@@ -296,21 +331,21 @@ var _ = Describe("Received Packet Tracker", func() {
 			})
 
 			It("doesn't generate an ACK when none is queued and the timer is not set", func() {
-				tracker.ReceivedPacket(1, time.Time{}, true)
+				tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
 				tracker.ackQueued = false
 				tracker.ackAlarm = time.Time{}
 				Expect(tracker.GetAckFrame()).To(BeNil())
 			})
 
 			It("doesn't generate an ACK when none is queued and the timer has not yet expired", func() {
-				tracker.ReceivedPacket(1, time.Time{}, true)
+				tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
 				tracker.ackQueued = false
 				tracker.ackAlarm = time.Now().Add(time.Minute)
 				Expect(tracker.GetAckFrame()).To(BeNil())
 			})
 
 			It("generates an ACK when the timer has expired", func() {
-				tracker.ReceivedPacket(1, time.Time{}, true)
+				tracker.ReceivedPacket(1, protocol.ECNNon, time.Time{}, true)
 				tracker.ackQueued = false
 				tracker.ackAlarm = time.Now().Add(-time.Minute)
 				Expect(tracker.GetAckFrame()).ToNot(BeNil())
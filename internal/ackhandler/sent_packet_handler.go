@@ -21,6 +21,19 @@ const (
 	packetThreshold = 3
 )
 
+type ecnState uint8
+
+const (
+	// outgoing packets are not ECN-marked
+	ecnStateDisabled ecnState = iota
+	// outgoing packets are ECN-marked, but we don't know yet if the path supports ECN
+	ecnStateTesting
+	// the peer correctly reported the ECN codepoints of our packets
+	ecnStateCapable
+	// ECN validation failed. ECN counts reported by the peer are ignored.
+	ecnStateFailed
+)
+
 type packetNumberSpace struct {
 	history *sentPacketHistory
 	pns     *packetNumberGenerator
@@ -30,6 +43,11 @@ type packetNumberSpace struct {
 
 	largestAcked protocol.PacketNumber
 	largestSent  protocol.PacketNumber
+
+	// the number of ECN-marked packets sent in this packet number space
+	ecnMarkedSent uint64
+	// the ECN counts last reported by the peer
+	ect0, ect1, ecnce uint64
 }
 
 func newPacketNumberSpace(initialPN protocol.PacketNumber) *packetNumberSpace {
@@ -41,9 +59,17 @@ func newPacketNumberSpace(initialPN protocol.PacketNumber) *packetNumberSpace {
 	}
 }
 
-type sentPacketHandler struct {
-	nextSendTime time.Time
+// PacingConfig configures the pacing of outgoing packets.
+type PacingConfig struct {
+	// Disabled disables pacing.
+	Disabled bool
+	// InitialBurst is the number of bytes that can be sent at the beginning of the connection without being paced.
+	InitialBurst protocol.ByteCount
+	// MaxBurst is the maximum number of bytes that can be sent in a burst, after the initial burst was used up.
+	MaxBurst protocol.ByteCount
+}
 
+type sentPacketHandler struct {
 	initialPackets   *packetNumberSpace
 	handshakePackets *packetNumberSpace
 	oneRTTPackets    *packetNumberSpace
@@ -60,6 +86,8 @@ type sentPacketHandler struct {
 
 	congestion congestion.SendAlgorithmWithDebugInfos
 	rttStats   *congestion.RTTStats
+	// nil if pacing is disabled
+	pacer *congestion.Pacer
 
 	// The number of times a PTO has been sent without receiving an ack.
 	ptoCount uint32
@@ -71,16 +99,36 @@ type sentPacketHandler struct {
 	// The alarm timeout
 	alarm time.Time
 
+	// the ECN codepoint that outgoing packets are marked with
+	ecn      protocol.ECN
+	ecnState ecnState
+	// called when ECN validation fails, to stop marking outgoing packets
+	disableECN func()
+
 	traceCallback func(quictrace.Event)
+	// the congestion window at the time of the last trace event, used to detect changes
+	tracedCongestionWindow protocol.ByteCount
+
+	packetsSent utils.Counter
+	packetsLost utils.Counter
+	ptos        utils.Counter
+	rttSamples  utils.Histogram
 
 	logger utils.Logger
 }
 
+// rttBuckets are the buckets (in seconds) of the RTT histogram.
+var rttBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
 // NewSentPacketHandler creates a new sentPacketHandler
 func NewSentPacketHandler(
 	initialPacketNumber protocol.PacketNumber,
 	rttStats *congestion.RTTStats,
+	ecn protocol.ECN,
+	disableECN func(),
+	pacing PacingConfig,
 	traceCallback func(quictrace.Event),
+	metrics utils.Metrics,
 	logger utils.Logger,
 ) SentPacketHandler {
 	congestion := congestion.NewCubicSender(
@@ -91,32 +139,59 @@ func NewSentPacketHandler(
 		protocol.DefaultMaxCongestionWindow,
 	)
 
-	return &sentPacketHandler{
+	state := ecnStateDisabled
+	if ecn != protocol.ECNNon {
+		state = ecnStateTesting
+	}
+	h := &sentPacketHandler{
 		initialPackets:   newPacketNumberSpace(initialPacketNumber),
 		handshakePackets: newPacketNumberSpace(0),
 		oneRTTPackets:    newPacketNumberSpace(0),
 		rttStats:         rttStats,
 		congestion:       congestion,
+		ecn:              ecn,
+		ecnState:         state,
+		disableECN:       disableECN,
 		traceCallback:    traceCallback,
+		packetsSent:      metrics.Counter("quic_packets_sent_total", "Number of packets sent."),
+		packetsLost:      metrics.Counter("quic_packets_lost_total", "Number of packets declared lost."),
+		ptos:             metrics.Counter("quic_pto_total", "Number of times the probe timeout fired."),
+		rttSamples:       metrics.Histogram("quic_rtt_seconds", "RTT samples, in seconds.", rttBuckets),
 		logger:           logger,
 	}
+	if !pacing.Disabled {
+		h.pacer = h.newPacer(pacing)
+	}
+	return h
+}
+
+func (h *sentPacketHandler) newPacer(conf PacingConfig) *congestion.Pacer {
+	// The congestion controller is accessed through the sentPacketHandler,
+	// so that it can be replaced in tests.
+	return congestion.NewPacer(func() congestion.Bandwidth { return h.congestion.PacingRate() }, conf.InitialBurst, conf.MaxBurst)
 }
 
 func (h *sentPacketHandler) DropPackets(encLevel protocol.EncryptionLevel) {
 	// remove outstanding packets from bytes_in_flight
-	pnSpace := h.getPacketNumberSpace(encLevel)
-	pnSpace.history.Iterate(func(p *Packet) (bool, error) {
-		if p.includedInBytesInFlight {
-			h.bytesInFlight -= p.Length
-		}
-		return true, nil
-	})
+	if encLevel == protocol.EncryptionInitial || encLevel == protocol.EncryptionHandshake {
+		pnSpace := h.getPacketNumberSpace(encLevel)
+		pnSpace.history.Iterate(func(p *Packet) (bool, error) {
+			if p.includedInBytesInFlight {
+				h.bytesInFlight -= p.Length
+			}
+			return true, nil
+		})
+	}
 	// drop the packet history
 	switch encLevel {
 	case protocol.EncryptionInitial:
 		h.initialPackets = nil
 	case protocol.EncryptionHandshake:
 		h.handshakePackets = nil
+	case protocol.Encryption0RTT:
+		// The server rejected 0-RTT.
+		// Retransmit the frames sent in 0-RTT packets in 1-RTT packets.
+		h.drop0RTTPackets()
 	default:
 		panic(fmt.Sprintf("Cannot drop keys for encryption level %s", encLevel))
 	}
@@ -124,6 +199,24 @@ func (h *sentPacketHandler) DropPackets(encLevel protocol.EncryptionLevel) {
 	h.ptoMode = SendNone
 }
 
+// drop0RTTPackets removes all 0-RTT packets from the history, and queues their frames for retransmission.
+func (h *sentPacketHandler) drop0RTTPackets() {
+	var zeroRTTPackets []*Packet
+	h.oneRTTPackets.history.Iterate(func(p *Packet) (bool, error) {
+		if p.EncryptionLevel == protocol.Encryption0RTT {
+			zeroRTTPackets = append(zeroRTTPackets, p)
+		}
+		return true, nil
+	})
+	for _, p := range zeroRTTPackets {
+		h.queueFramesForRetransmission(p)
+		if p.includedInBytesInFlight {
+			h.bytesInFlight -= p.Length
+		}
+		h.oneRTTPackets.history.Remove(p.PacketNumber)
+	}
+}
+
 func (h *sentPacketHandler) SentPacket(packet *Packet) {
 	if isAckEliciting := h.sentPacketImpl(packet); isAckEliciting {
 		h.getPacketNumberSpace(packet.EncryptionLevel).history.SentPacket(packet)
@@ -137,7 +230,7 @@ func (h *sentPacketHandler) getPacketNumberSpace(encLevel protocol.EncryptionLev
 		return h.initialPackets
 	case protocol.EncryptionHandshake:
 		return h.handshakePackets
-	case protocol.Encryption1RTT:
+	case protocol.Encryption0RTT, protocol.Encryption1RTT:
 		return h.oneRTTPackets
 	default:
 		panic("invalid packet number space")
@@ -154,7 +247,11 @@ func (h *sentPacketHandler) sentPacketImpl(packet *Packet) bool /* is ack-elicit
 	}
 
 	pnSpace.largestSent = packet.PacketNumber
+	h.packetsSent.Add(1)
 	isAckEliciting := len(packet.Frames) > 0
+	if h.ecn != protocol.ECNNon {
+		pnSpace.ecnMarkedSent++
+	}
 
 	if isAckEliciting {
 		pnSpace.lastSentAckElicitingPacketTime = packet.SendTime
@@ -165,8 +262,9 @@ func (h *sentPacketHandler) sentPacketImpl(packet *Packet) bool /* is ack-elicit
 		}
 	}
 	h.congestion.OnPacketSent(packet.SendTime, h.bytesInFlight, packet.PacketNumber, packet.Length, isAckEliciting)
-
-	h.nextSendTime = utils.MaxTime(h.nextSendTime, packet.SendTime).Add(h.congestion.TimeUntilSend(h.bytesInFlight))
+	if isAckEliciting && h.pacer != nil {
+		h.pacer.SentPacket(packet.SendTime, packet.Length)
+	}
 	return isAckEliciting
 }
 
@@ -178,6 +276,8 @@ func (h *sentPacketHandler) ReceivedAck(ackFrame *wire.AckFrame, withPacketNumbe
 		return qerr.Error(qerr.ProtocolViolation, "Received ACK for an unsent packet")
 	}
 
+	// ACKs might be reordered. Only use the ECN counts of ACKs that increase the largest acknowledged.
+	isNewLargestAcked := largestAcked > pnSpace.largestAcked
 	pnSpace.largestAcked = utils.MaxPacketNumber(pnSpace.largestAcked, largestAcked)
 
 	if !pnSpace.pns.Validate(ackFrame) {
@@ -192,6 +292,7 @@ func (h *sentPacketHandler) ReceivedAck(ackFrame *wire.AckFrame, withPacketNumbe
 			ackDelay = utils.MinDuration(ackFrame.DelayTime, h.rttStats.MaxAckDelay())
 		}
 		h.rttStats.UpdateRTT(rcvTime.Sub(p.SendTime), ackDelay, rcvTime)
+		h.rttSamples.Observe(h.rttStats.LatestRTT().Seconds())
 		if h.logger.Debug() {
 			h.logger.Debugf("\tupdated RTT: %s (σ: %s)", h.rttStats.SmoothedRTT(), h.rttStats.MeanDeviation())
 		}
@@ -218,10 +319,14 @@ func (h *sentPacketHandler) ReceivedAck(ackFrame *wire.AckFrame, withPacketNumbe
 			h.congestion.OnPacketAcked(p.PacketNumber, p.Length, priorInFlight, rcvTime)
 		}
 	}
+	if isNewLargestAcked {
+		h.processECNCounts(ackFrame, pnSpace, len(ackedPackets), priorInFlight)
+	}
 
 	if err := h.detectLostPackets(rcvTime, encLevel, priorInFlight); err != nil {
 		return err
 	}
+	h.maybeTraceCongestionWindow(rcvTime)
 
 	h.ptoCount = 0
 	h.numProbesToSend = 0
@@ -230,6 +335,56 @@ func (h *sentPacketHandler) ReceivedAck(ackFrame *wire.AckFrame, withPacketNumbe
 	return nil
 }
 
+// processECNCounts validates the ECN counts reported by the peer, as described in
+// section 13.4.2 of the transport draft, and notifies the congestion controller
+// when the peer reports newly CE-marked packets.
+func (h *sentPacketHandler) processECNCounts(ackFrame *wire.AckFrame, pnSpace *packetNumberSpace, numNewlyAcked int, priorInFlight protocol.ByteCount) {
+	if h.ecnState != ecnStateTesting && h.ecnState != ecnStateCapable {
+		return
+	}
+	if !ackFrame.HasECN() {
+		h.failECNValidation("peer didn't report any ECN counts")
+		return
+	}
+	if ackFrame.ECT0 < pnSpace.ect0 || ackFrame.ECT1 < pnSpace.ect1 || ackFrame.ECNCE < pnSpace.ecnce {
+		h.failECNValidation("ECN counts decreased")
+		return
+	}
+	newECT0 := ackFrame.ECT0 - pnSpace.ect0
+	newECNCE := ackFrame.ECNCE - pnSpace.ecnce
+	// We're only sending ECT(0), so all newly acknowledged packets must be reported as ECT(0) or CE.
+	if newECT0+newECNCE < uint64(numNewlyAcked) {
+		h.failECNValidation("peer reported fewer ECN-marked packets than were acknowledged")
+		return
+	}
+	if ackFrame.ECT0+ackFrame.ECT1+ackFrame.ECNCE > pnSpace.ecnMarkedSent {
+		h.failECNValidation("peer reported more ECN-marked packets than were sent")
+		return
+	}
+	pnSpace.ect0 = ackFrame.ECT0
+	pnSpace.ect1 = ackFrame.ECT1
+	pnSpace.ecnce = ackFrame.ECNCE
+	if h.ecnState == ecnStateTesting {
+		h.logger.Debugf("ECN validation succeeded")
+		h.ecnState = ecnStateCapable
+	}
+	if newECNCE > 0 {
+		if h.logger.Debug() {
+			h.logger.Debugf("\tpeer reported %d newly CE-marked packets", newECNCE)
+		}
+		h.congestion.OnCongestionEvent(ackFrame.LargestAcked(), priorInFlight)
+	}
+}
+
+func (h *sentPacketHandler) failECNValidation(reason string) {
+	h.logger.Debugf("ECN validation failed: %s. Disabling ECN for this connection.", reason)
+	h.ecnState = ecnStateFailed
+	h.ecn = protocol.ECNNon
+	if h.disableECN != nil {
+		h.disableECN()
+	}
+}
+
 func (h *sentPacketHandler) GetLowestPacketNotConfirmedAcked() protocol.PacketNumber {
 	return h.lowestNotConfirmedAcked
 }
@@ -403,6 +558,7 @@ func (h *sentPacketHandler) detectLostPackets(
 		h.logger.Debugf("\tlost packets (%d): %#x", len(pns), pns)
 	}
 
+	h.packetsLost.Add(float64(len(lostPackets)))
 	for _, p := range lostPackets {
 		h.queueFramesForRetransmission(p)
 		// the bytes in flight need to be reduced no matter if this packet will be retransmitted
@@ -451,7 +607,12 @@ func (h *sentPacketHandler) onVerifiedLossDetectionTimeout() error {
 			h.logger.Debugf("Loss detection alarm fired in loss timer mode. Loss time: %s", earliestLossTime)
 		}
 		// Early retransmit or time loss detection
-		return h.detectLostPackets(time.Now(), encLevel, h.bytesInFlight)
+		now := time.Now()
+		if err := h.detectLostPackets(now, encLevel, h.bytesInFlight); err != nil {
+			return err
+		}
+		h.maybeTraceCongestionWindow(now)
+		return nil
 	}
 
 	// PTO
@@ -460,6 +621,7 @@ func (h *sentPacketHandler) onVerifiedLossDetectionTimeout() error {
 		h.logger.Debugf("Loss detection alarm for %s fired in PTO mode. PTO count: %d", encLevel, h.ptoCount)
 	}
 	h.ptoCount++
+	h.ptos.Add(1)
 	h.numProbesToSend += 2
 	switch encLevel {
 	case protocol.EncryptionInitial:
@@ -552,7 +714,10 @@ func (h *sentPacketHandler) SendMode() SendMode {
 }
 
 func (h *sentPacketHandler) TimeUntilSend() time.Time {
-	return h.nextSendTime
+	if h.pacer == nil {
+		return time.Time{}
+	}
+	return h.pacer.TimeUntilSend()
 }
 
 func (h *sentPacketHandler) ShouldSendNumPackets() int {
@@ -560,11 +725,11 @@ func (h *sentPacketHandler) ShouldSendNumPackets() int {
 		// RTO probes should not be paced, but must be sent immediately.
 		return h.numProbesToSend
 	}
-	delay := h.congestion.TimeUntilSend(h.bytesInFlight)
-	if delay == 0 || delay > protocol.MinPacingDelay {
-		return 1
+	if h.pacer == nil {
+		// Without pacing, we send as many packets as the congestion controller allows.
+		return math.MaxInt32
 	}
-	return int(math.Ceil(float64(protocol.MinPacingDelay) / float64(delay)))
+	return utils.Max(1, int(h.pacer.Budget(time.Now())/protocol.MaxPacketSizeIPv4))
 }
 
 func (h *sentPacketHandler) QueueProbePacket(encLevel protocol.EncryptionLevel) bool {
@@ -590,15 +755,47 @@ func (h *sentPacketHandler) queueFramesForRetransmission(p *Packet) {
 	for _, f := range p.Frames {
 		f.OnLost(f.Frame)
 	}
+	if h.traceCallback != nil && len(p.Frames) > 0 {
+		frames := make([]wire.Frame, 0, len(p.Frames))
+		for _, f := range p.Frames {
+			frames = append(frames, f.Frame)
+		}
+		h.traceCallback(quictrace.Event{
+			Time:            time.Now(),
+			EventType:       quictrace.FramesRetransmitted,
+			EncryptionLevel: p.EncryptionLevel,
+			PacketNumber:    p.PacketNumber,
+			Frames:          frames,
+			TransportState:  h.GetStats(),
+		})
+	}
+}
+
+func (h *sentPacketHandler) maybeTraceCongestionWindow(now time.Time) {
+	if h.traceCallback == nil {
+		return
+	}
+	cwnd := h.congestion.GetCongestionWindow()
+	if cwnd == h.tracedCongestionWindow {
+		return
+	}
+	h.tracedCongestionWindow = cwnd
+	h.traceCallback(quictrace.Event{
+		Time:           now,
+		EventType:      quictrace.CongestionWindowUpdated,
+		TransportState: h.GetStats(),
+	})
 }
 
 func (h *sentPacketHandler) ResetForRetry() error {
-	h.bytesInFlight = 0
 	h.initialPackets.history.Iterate(func(p *Packet) (bool, error) {
 		h.queueFramesForRetransmission(p)
 		return true, nil
 	})
 	h.initialPackets = newPacketNumberSpace(h.initialPackets.pns.Pop())
+	// The server drops 0-RTT packets received before the Retry.
+	h.drop0RTTPackets()
+	h.bytesInFlight = 0
 	h.setLossDetectionTimer()
 	return nil
 }
@@ -24,7 +24,7 @@ var _ = Describe("SentPacketHandler", func() {
 	BeforeEach(func() {
 		lostPackets = nil
 		rttStats := &congestion.RTTStats{}
-		handler = NewSentPacketHandler(42, rttStats, nil, utils.DefaultLogger).(*sentPacketHandler)
+		handler = NewSentPacketHandler(42, rttStats, protocol.ECNNon, nil, PacingConfig{}, nil, utils.NopMetrics, utils.DefaultLogger).(*sentPacketHandler)
 		streamFrame = wire.StreamFrame{
 			StreamID: 5,
 			Data:     []byte{0x13, 0x37},
@@ -410,7 +410,7 @@ var _ = Describe("SentPacketHandler", func() {
 				protocol.ByteCount(42),
 				true,
 			)
-			cong.EXPECT().TimeUntilSend(gomock.Any())
+			cong.EXPECT().PacingRate().AnyTimes()
 			handler.SentPacket(&Packet{
 				PacketNumber:    1,
 				Length:          42,
@@ -422,7 +422,7 @@ var _ = Describe("SentPacketHandler", func() {
 		It("should call MaybeExitSlowStart and OnPacketAcked", func() {
 			rcvTime := time.Now().Add(-5 * time.Second)
 			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
-			cong.EXPECT().TimeUntilSend(gomock.Any()).Times(3)
+			cong.EXPECT().PacingRate().AnyTimes()
 			gomock.InOrder(
 				cong.EXPECT().MaybeExitSlowStart(), // must be called before packets are acked
 				cong.EXPECT().OnPacketAcked(protocol.PacketNumber(1), protocol.ByteCount(1), protocol.ByteCount(3), rcvTime),
@@ -438,7 +438,7 @@ var _ = Describe("SentPacketHandler", func() {
 
 		It("doesn't call OnPacketAcked when a retransmitted packet is acked", func() {
 			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
-			cong.EXPECT().TimeUntilSend(gomock.Any()).Times(2)
+			cong.EXPECT().PacingRate().AnyTimes()
 			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Hour)}))
 			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2}))
 			// lose packet 1
@@ -458,7 +458,7 @@ var _ = Describe("SentPacketHandler", func() {
 
 		It("calls OnPacketAcked and OnPacketLost with the right bytes_in_flight value", func() {
 			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(4)
-			cong.EXPECT().TimeUntilSend(gomock.Any()).Times(4)
+			cong.EXPECT().PacingRate().AnyTimes()
 			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Hour)}))
 			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, SendTime: time.Now().Add(-30 * time.Minute)}))
 			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 3, SendTime: time.Now().Add(-30 * time.Minute)}))
@@ -498,7 +498,7 @@ var _ = Describe("SentPacketHandler", func() {
 
 		It("allows sending of ACKs when we're keeping track of MaxOutstandingSentPackets packets", func() {
 			cong.EXPECT().CanSend(gomock.Any()).Return(true).AnyTimes()
-			cong.EXPECT().TimeUntilSend(gomock.Any()).AnyTimes()
+			cong.EXPECT().PacingRate().AnyTimes()
 			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 			for i := protocol.PacketNumber(1); i < protocol.MaxOutstandingSentPackets; i++ {
 				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
@@ -516,35 +516,77 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.SendMode()).To(Equal(SendPTOHandshake))
 		})
 
-		It("gets the pacing delay", func() {
-			sendTime := time.Now().Add(-time.Minute)
-			handler.bytesInFlight = 100
-			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
-			cong.EXPECT().TimeUntilSend(protocol.ByteCount(100)).Return(time.Hour)
-			handler.SentPacket(&Packet{PacketNumber: 1, SendTime: sendTime, EncryptionLevel: protocol.Encryption1RTT})
-			Expect(handler.TimeUntilSend()).To(Equal(sendTime.Add(time.Hour)))
-		})
-
 		It("allows sending of all RTO probe packets", func() {
 			handler.numProbesToSend = 5
 			Expect(handler.ShouldSendNumPackets()).To(Equal(5))
 		})
 
-		It("allows sending of one packet, if it should be sent immediately", func() {
-			cong.EXPECT().TimeUntilSend(gomock.Any()).Return(time.Duration(0))
-			Expect(handler.ShouldSendNumPackets()).To(Equal(1))
-		})
+		Context("pacing", func() {
+			// a full-size packet every millisecond
+			const pacingRate = congestion.Bandwidth(protocol.MaxPacketSizeIPv4) * 1000 * congestion.BytesPerSecond
 
-		It("allows sending of multiple packets, if the pacing delay is smaller than the minimum", func() {
-			pacingDelay := protocol.MinPacingDelay / 10
-			cong.EXPECT().TimeUntilSend(gomock.Any()).Return(pacingDelay)
-			Expect(handler.ShouldSendNumPackets()).To(Equal(10))
-		})
+			sendPacket := func(pn protocol.PacketNumber, sendTime time.Time) {
+				handler.SentPacket(ackElicitingPacket(&Packet{
+					PacketNumber: pn,
+					Length:       protocol.MaxPacketSizeIPv4,
+					SendTime:     sendTime,
+				}))
+			}
 
-		It("allows sending of multiple packets, if the pacing delay is smaller than the minimum, and not a fraction", func() {
-			pacingDelay := protocol.MinPacingDelay * 2 / 5
-			cong.EXPECT().TimeUntilSend(gomock.Any()).Return(pacingDelay)
-			Expect(handler.ShouldSendNumPackets()).To(Equal(3))
+			BeforeEach(func() {
+				cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+				cong.EXPECT().PacingRate().Return(pacingRate).AnyTimes()
+				handler.pacer = handler.newPacer(PacingConfig{
+					InitialBurst: 3 * protocol.MaxPacketSizeIPv4,
+					MaxBurst:     2 * protocol.MaxPacketSizeIPv4,
+				})
+			})
+
+			It("allows sending the initial burst at once", func() {
+				Expect(handler.ShouldSendNumPackets()).To(Equal(3))
+				Expect(handler.TimeUntilSend()).To(BeZero())
+			})
+
+			It("spaces out packets after the initial burst", func() {
+				sendTime := time.Now().Add(-time.Hour)
+				for i := 1; i <= 3; i++ {
+					Expect(handler.TimeUntilSend()).To(BeTemporally("<=", sendTime))
+					sendPacket(protocol.PacketNumber(i), sendTime)
+				}
+				for i := 4; i <= 8; i++ {
+					next := handler.TimeUntilSend()
+					Expect(next.Sub(sendTime)).To(BeNumerically("~", time.Millisecond, time.Microsecond))
+					sendTime = next
+					sendPacket(protocol.PacketNumber(i), sendTime)
+				}
+			})
+
+			It("allows sending a burst of the maximum burst size after an idle period", func() {
+				sendTime := time.Now().Add(-time.Hour)
+				for i := 1; i <= 3; i++ {
+					sendPacket(protocol.PacketNumber(i), sendTime)
+				}
+				Expect(handler.ShouldSendNumPackets()).To(Equal(2))
+			})
+
+			It("sends one packet at a time, if the budget is used up", func() {
+				now := time.Now()
+				for i := 1; i <= 3; i++ {
+					sendPacket(protocol.PacketNumber(i), now)
+				}
+				Expect(handler.ShouldSendNumPackets()).To(Equal(1))
+				Expect(handler.TimeUntilSend()).To(BeTemporally("~", now.Add(time.Millisecond), time.Microsecond))
+			})
+
+			It("doesn't pace if pacing is disabled", func() {
+				handler.pacer = nil
+				now := time.Now()
+				for i := 1; i <= 10; i++ {
+					sendPacket(protocol.PacketNumber(i), now)
+				}
+				Expect(handler.TimeUntilSend()).To(BeZero())
+				Expect(handler.ShouldSendNumPackets()).To(BeNumerically(">", 10))
+			})
 		})
 	})
 
@@ -562,6 +604,74 @@ var _ = Describe("SentPacketHandler", func() {
 		Expect(handler.SendMode()).To(Equal(SendAny))
 	})
 
+	Context("ECN", func() {
+		var (
+			cong        *mocks.MockSendAlgorithmWithDebugInfos
+			ecnDisabled bool
+		)
+
+		BeforeEach(func() {
+			ecnDisabled = false
+			handler.disableECN = func() { ecnDisabled = true }
+			cong = mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			cong.EXPECT().PacingRate().AnyTimes()
+			cong.EXPECT().MaybeExitSlowStart().AnyTimes()
+			cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			handler.congestion = cong
+			handler.ecn = protocol.ECT0
+			handler.ecnState = ecnStateTesting
+			for i := protocol.PacketNumber(1); i <= 4; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
+			}
+		})
+
+		It("validates ECN and reports CE-marked packets to the congestion controller", func() {
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 2}}, ECT0: 2}
+			Expect(handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(handler.ecnState).To(Equal(ecnStateCapable))
+			cong.EXPECT().OnCongestionEvent(protocol.PacketNumber(3), protocol.ByteCount(2))
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 3}}, ECT0: 2, ECNCE: 1}
+			Expect(handler.ReceivedAck(ack, 2, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(handler.ecnState).To(Equal(ecnStateCapable))
+		})
+
+		It("fails validation if the peer doesn't report ECN counts", func() {
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 2}}}
+			Expect(handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(handler.ecnState).To(Equal(ecnStateFailed))
+			// packets are not marked any more
+			Expect(ecnDisabled).To(BeTrue())
+			Expect(handler.ecn).To(Equal(protocol.ECNNon))
+			marked := handler.oneRTTPackets.ecnMarkedSent
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 5}))
+			Expect(handler.oneRTTPackets.ecnMarkedSent).To(Equal(marked))
+			// CE marks are ignored after validation failed
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 3}}, ECNCE: 3}
+			Expect(handler.ReceivedAck(ack, 2, protocol.Encryption1RTT, time.Now())).To(Succeed())
+		})
+
+		It("fails validation if the peer reports fewer ECN-marked packets than were acknowledged", func() {
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 3}}, ECT0: 2}
+			Expect(handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(handler.ecnState).To(Equal(ecnStateFailed))
+		})
+
+		It("fails validation if the peer reports more ECN-marked packets than were sent", func() {
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}, ECT0: 5}
+			Expect(handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(handler.ecnState).To(Equal(ecnStateFailed))
+		})
+
+		It("fails validation if the ECN counts decrease", func() {
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 2}}, ECT0: 2}
+			Expect(handler.ReceivedAck(ack, 1, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 3, Largest: 3}}, ECT0: 1}
+			Expect(handler.ReceivedAck(ack, 2, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(handler.ecnState).To(Equal(ecnStateFailed))
+		})
+	})
+
 	Context("probe packets", func() {
 		It("queues a probe packet", func() {
 			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 10}))
@@ -831,6 +941,29 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(10)))
 			Expect(handler.handshakePackets).To(BeNil())
 		})
+
+		It("queues 0-RTT packets for retransmission when 0-RTT is rejected", func() {
+			for i := protocol.PacketNumber(0); i < 6; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{
+					PacketNumber:    i,
+					EncryptionLevel: protocol.Encryption0RTT,
+				}))
+			}
+			for i := protocol.PacketNumber(6); i < 10; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{
+					PacketNumber:    i,
+					EncryptionLevel: protocol.Encryption1RTT,
+				}))
+			}
+			Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(10)))
+			handler.DropPackets(protocol.Encryption0RTT)
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{0, 1, 2, 3, 4, 5}))
+			Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(4)))
+			Expect(handler.oneRTTPackets.history.Len()).To(Equal(4))
+			// 0-RTT packets are acknowledged in 1-RTT ACKs, in the same packet number space
+			Expect(handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 6, Largest: 9}}}, 1, protocol.Encryption1RTT, time.Now())).To(Succeed())
+			Expect(handler.oneRTTPackets.history.Len()).To(BeZero())
+		})
 	})
 
 	Context("peeking and popping packet number", func() {
@@ -868,5 +1001,15 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.GetLossDetectionTimeout()).To(BeZero())
 			Expect(handler.SendMode()).To(Equal(SendAny))
 		})
+
+		It("queues 0-RTT packets for retransmission", func() {
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 42, EncryptionLevel: protocol.EncryptionInitial}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 0, EncryptionLevel: protocol.Encryption0RTT}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, EncryptionLevel: protocol.Encryption0RTT}))
+			Expect(handler.ResetForRetry()).To(Succeed())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{42, 0, 1}))
+			Expect(handler.bytesInFlight).To(BeZero())
+			Expect(handler.oneRTTPackets.history.Len()).To(BeZero())
+		})
 	})
 })
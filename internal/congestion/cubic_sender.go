@@ -85,15 +85,20 @@ func NewCubicSender(clock Clock, rttStats *RTTStats, reno bool, initialCongestio
 	}
 }
 
-// TimeUntilSend returns when the next packet should be sent.
-func (c *cubicSender) TimeUntilSend(bytesInFlight protocol.ByteCount) time.Duration {
-	if !c.noPRR && c.InRecovery() {
-		// PRR is used when in recovery.
-		if c.prr.CanSend(c.GetCongestionWindow(), bytesInFlight, c.GetSlowStartThreshold()) {
-			return 0
-		}
+// PacingRate returns the rate at which packets should be paced.
+// In slow start, packets are paced at twice the bandwidth estimate, and at 1.25 times the estimate otherwise,
+// such that pacing doesn't prevent the congestion window from growing.
+// Before an RTT sample is available, the default initial RTT is used.
+func (c *cubicSender) PacingRate() Bandwidth {
+	srtt := c.rttStats.SmoothedRTT()
+	if srtt == 0 {
+		srtt = defaultInitialRTT
 	}
-	return c.rttStats.SmoothedRTT() * time.Duration(protocol.DefaultTCPMSS) / time.Duration(2*c.GetCongestionWindow())
+	bandwidth := BandwidthFromDelta(c.GetCongestionWindow(), srtt)
+	if c.InSlowStart() {
+		return 2 * bandwidth
+	}
+	return bandwidth * 5 / 4
 }
 
 func (c *cubicSender) OnPacketSent(
@@ -171,6 +176,12 @@ func (c *cubicSender) OnPacketAcked(
 	}
 }
 
+// OnCongestionEvent reduces the congestion window in the same way as a packet loss does.
+// Just like for losses, only one reduction is performed per round trip.
+func (c *cubicSender) OnCongestionEvent(packetNumber protocol.PacketNumber, priorInFlight protocol.ByteCount) {
+	c.OnPacketLost(packetNumber, 0, priorInFlight)
+}
+
 func (c *cubicSender) OnPacketLost(
 	packetNumber protocol.PacketNumber,
 	lostBytes protocol.ByteCount,
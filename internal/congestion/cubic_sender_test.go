@@ -4,7 +4,6 @@ import (
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
-	"github.com/lucas-clemente/quic-go/internal/utils"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -87,7 +86,6 @@ var _ = Describe("Cubic Sender", func() {
 		// At startup make sure we are at the default.
 		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
 		// Make sure we can send.
-		Expect(sender.TimeUntilSend(0)).To(BeZero())
 		Expect(sender.CanSend(bytesInFlight)).To(BeTrue())
 		// And that window is un-affected.
 		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
@@ -102,9 +100,19 @@ var _ = Describe("Cubic Sender", func() {
 		// Fill the send window with data, then verify that we can't send.
 		SendAvailableSendWindow()
 		AckNPackets(1)
-		delay := sender.TimeUntilSend(bytesInFlight)
-		Expect(delay).ToNot(BeZero())
-		Expect(delay).ToNot(Equal(utils.InfDuration))
+		Expect(sender.PacingRate()).To(Equal(2 * BandwidthFromDelta(sender.GetCongestionWindow(), rttStats.SmoothedRTT())))
+	})
+
+	It("uses the default initial RTT for the pacing rate, before an RTT sample is available", func() {
+		Expect(sender.PacingRate()).To(Equal(2 * BandwidthFromDelta(defaultWindowTCP, defaultInitialRTT)))
+	})
+
+	It("paces slower after leaving slow start", func() {
+		SendAvailableSendWindow()
+		AckNPackets(2)
+		sender.ExitSlowstart()
+		Expect(sender.InSlowStart()).To(BeFalse())
+		Expect(sender.PacingRate()).To(Equal(BandwidthFromDelta(sender.GetCongestionWindow(), rttStats.SmoothedRTT()) * 5 / 4))
 	})
 
 	It("application limited slow start", func() {
@@ -112,7 +120,6 @@ var _ = Describe("Cubic Sender", func() {
 		const numberOfAcks = 5
 		// At startup make sure we can send.
 		Expect(sender.CanSend(0)).To(BeTrue())
-		Expect(sender.TimeUntilSend(0)).To(BeZero())
 
 		SendAvailableSendWindow()
 		for i := 0; i < numberOfAcks; i++ {
@@ -128,10 +135,7 @@ var _ = Describe("Cubic Sender", func() {
 		const numberOfAcks = 20
 		// At startup make sure we can send.
 		Expect(sender.CanSend(0)).To(BeTrue())
-		Expect(sender.TimeUntilSend(0)).To(BeZero())
 		Expect(sender.BandwidthEstimate()).To(BeZero())
-		// Make sure we can send.
-		Expect(sender.TimeUntilSend(0)).To(BeZero())
 
 		for i := 0; i < numberOfAcks; i++ {
 			// Send our full send window.
@@ -453,6 +457,20 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(postLossWindow).To(BeNumerically(">", sender.GetCongestionWindow()))
 	})
 
+	It("reduces the window once per window on congestion events", func() {
+		SendAvailableSendWindow()
+		initialWindow := sender.GetCongestionWindow()
+		sender.OnCongestionEvent(ackedPacketNumber+1, bytesInFlight)
+		postCongestionWindow := sender.GetCongestionWindow()
+		Expect(postCongestionWindow).To(Equal(protocol.ByteCount(float32(initialWindow) * renoBeta)))
+		sender.OnCongestionEvent(packetNumber-1, bytesInFlight)
+		Expect(sender.GetCongestionWindow()).To(Equal(postCongestionWindow))
+
+		// A congestion event for a packet sent after the reduction reduces the window again.
+		sender.OnCongestionEvent(packetNumber, bytesInFlight)
+		Expect(postCongestionWindow).To(BeNumerically(">", sender.GetCongestionWindow()))
+	})
+
 	It("2 connection congestion avoidance at end of recovery", func() {
 		sender.SetNumEmulatedConnections(2)
 		// Ack 10 packets in 5 acks to raise the CWND to 20.
@@ -477,7 +495,6 @@ var _ = Describe("Cubic Sender", func() {
 		for i := 0; i < 10; i++ {
 			// Send our full send window.
 			SendAvailableSendWindow()
-			Expect(sender.InRecovery()).To(BeTrue())
 			AckNPackets(2)
 			Expect(sender.GetCongestionWindow()).To(Equal(expectedSendWindow))
 		}
@@ -532,7 +549,6 @@ var _ = Describe("Cubic Sender", func() {
 		for i := 0; i < 10; i++ {
 			// Send our full send window.
 			SendAvailableSendWindow()
-			Expect(sender.InRecovery()).To(BeTrue())
 			AckNPackets(2)
 			Expect(sender.GetCongestionWindow()).To(Equal(expectedSendWindow))
 		}
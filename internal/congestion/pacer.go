@@ -0,0 +1,92 @@
+package congestion
+
+import (
+	"math"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+const maxDatagramSize = protocol.ByteCount(protocol.MaxPacketSizeIPv4)
+
+// A Pacer paces outgoing packets using a token bucket.
+// The bucket is refilled at the pacing rate of the congestion controller.
+// It holds up to maxBurst bytes, but it may start out with a larger initial burst.
+type Pacer struct {
+	getPacingRate func() Bandwidth
+
+	maxBurst protocol.ByteCount
+
+	budgetAtLastSent protocol.ByteCount
+	lastSentTime     time.Time
+}
+
+// NewPacer creates a new Pacer.
+// The initial burst may be larger than the maximum burst. In that case, the bucket starts out overfull,
+// and is only refilled once the initial burst has been used up.
+// Both values are at least the size of a single packet.
+func NewPacer(getPacingRate func() Bandwidth, initialBurst, maxBurst protocol.ByteCount) *Pacer {
+	return &Pacer{
+		getPacingRate:    getPacingRate,
+		maxBurst:         utils.MaxByteCount(maxBurst, maxDatagramSize),
+		budgetAtLastSent: utils.MaxByteCount(initialBurst, maxDatagramSize),
+	}
+}
+
+// SentPacket must be called for every packet that is subject to pacing.
+func (p *Pacer) SentPacket(sendTime time.Time, size protocol.ByteCount) {
+	budget := p.Budget(sendTime)
+	if size > budget {
+		p.budgetAtLastSent = 0
+	} else {
+		p.budgetAtLastSent = budget - size
+	}
+	p.lastSentTime = sendTime
+}
+
+// Budget returns the number of bytes that may be sent at time now.
+func (p *Pacer) Budget(now time.Time) protocol.ByteCount {
+	if p.lastSentTime.IsZero() {
+		return p.budgetAtLastSent
+	}
+	rate := p.getPacingRate()
+	if rate == 0 {
+		// Without a pacing rate, packets are not paced.
+		return utils.MaxByteCount(p.budgetAtLastSent, p.maxBurst)
+	}
+	maxBurst := p.maxBurstSize(rate)
+	if p.budgetAtLastSent >= maxBurst {
+		// We're still using the initial burst.
+		return p.budgetAtLastSent
+	}
+	budget := float64(p.budgetAtLastSent) + float64(rate/BytesPerSecond)*now.Sub(p.lastSentTime).Seconds()
+	if budget >= float64(maxBurst) {
+		return maxBurst
+	}
+	return protocol.ByteCount(budget)
+}
+
+// maxBurstSize is the maximum number of bytes the bucket can hold.
+// At high pacing rates, the bucket must at least hold as many bytes as are sent during one timer period,
+// so that the precision of the timer doesn't limit the sending rate.
+func (p *Pacer) maxBurstSize(rate Bandwidth) protocol.ByteCount {
+	return utils.MaxByteCount(
+		p.maxBurst,
+		protocol.ByteCount(float64(rate/BytesPerSecond)*(protocol.MinPacingDelay+protocol.TimerGranularity).Seconds()),
+	)
+}
+
+// TimeUntilSend returns the time when the next full-size packet may be sent.
+// If the budget already allows sending a packet, this is the time the last packet was sent.
+func (p *Pacer) TimeUntilSend() time.Time {
+	if p.budgetAtLastSent >= maxDatagramSize {
+		return p.lastSentTime
+	}
+	rate := p.getPacingRate()
+	if rate == 0 {
+		return p.lastSentTime
+	}
+	delay := time.Duration(math.Ceil(float64(maxDatagramSize-p.budgetAtLastSent) * 1e9 / float64(rate/BytesPerSecond)))
+	return p.lastSentTime.Add(utils.MaxDuration(protocol.MinPacingDelay, delay))
+}
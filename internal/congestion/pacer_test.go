@@ -0,0 +1,136 @@
+package congestion
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pacer", func() {
+	const packetsPerSecond = 500
+	var (
+		p         *Pacer
+		bandwidth Bandwidth
+	)
+
+	BeforeEach(func() {
+		bandwidth = packetsPerSecond * Bandwidth(maxDatagramSize) * BytesPerSecond
+		p = NewPacer(func() Bandwidth { return bandwidth }, 10*maxDatagramSize, 10*maxDatagramSize)
+	})
+
+	// sendPackets sends n full-size packets, each one as soon as the pacer allows it.
+	// It returns the send times.
+	sendPackets := func(start time.Time, n int) []time.Time {
+		times := make([]time.Time, 0, n)
+		now := start
+		for i := 0; i < n; i++ {
+			if t := p.TimeUntilSend(); t.After(now) {
+				now = t
+			}
+			Expect(p.Budget(now)).To(BeNumerically(">=", maxDatagramSize))
+			p.SentPacket(now, maxDatagramSize)
+			times = append(times, now)
+		}
+		return times
+	}
+
+	It("allows a burst at the beginning", func() {
+		t := time.Now()
+		Expect(p.Budget(t)).To(Equal(10 * maxDatagramSize))
+		times := sendPackets(t, 10)
+		for _, st := range times {
+			Expect(st).To(Equal(t))
+		}
+		Expect(p.Budget(t)).To(BeZero())
+	})
+
+	It("paces packets after the initial burst", func() {
+		t := time.Now()
+		times := sendPackets(t, 20)
+		gap := time.Second / packetsPerSecond
+		for i := 10; i < 20; i++ {
+			Expect(times[i].Sub(times[i-1])).To(BeNumerically("~", gap, time.Microsecond))
+		}
+	})
+
+	It("refills the budget over time, up to the maximum burst size", func() {
+		t := time.Now()
+		sendPackets(t, 10)
+		Expect(p.Budget(t)).To(BeZero())
+		Expect(p.Budget(t.Add(4 * time.Second / packetsPerSecond))).To(BeNumerically("~", 4*maxDatagramSize, 1))
+		Expect(p.Budget(t.Add(time.Hour))).To(Equal(10 * maxDatagramSize))
+	})
+
+	It("sends a burst after an idle period", func() {
+		t := time.Now()
+		sendPackets(t, 15)
+		t = t.Add(time.Minute)
+		times := sendPackets(t, 12)
+		for i := 0; i < 10; i++ {
+			Expect(times[i]).To(Equal(t))
+		}
+		Expect(times[10]).To(BeTemporally(">", t))
+		Expect(times[11].Sub(times[10])).To(BeNumerically("~", time.Second/packetsPerSecond, time.Microsecond))
+	})
+
+	It("uses a larger initial burst", func() {
+		p = NewPacer(func() Bandwidth { return bandwidth }, 32*maxDatagramSize, 4*maxDatagramSize)
+		t := time.Now()
+		times := sendPackets(t, 34)
+		for i := 0; i < 32; i++ {
+			Expect(times[i]).To(Equal(t))
+		}
+		Expect(times[32]).To(BeTemporally(">", t))
+		// once the initial burst is used up, the bucket only fills up to the maximum burst size
+		t = times[33].Add(time.Hour)
+		Expect(p.Budget(t)).To(Equal(4 * maxDatagramSize))
+	})
+
+	It("adjusts the gap when the pacing rate changes", func() {
+		t := time.Now()
+		sendPackets(t, 10)
+		bandwidth *= 2
+		times := sendPackets(t, 5)
+		for i := 1; i < 5; i++ {
+			Expect(times[i].Sub(times[i-1])).To(BeNumerically("~", time.Second/(2*packetsPerSecond), time.Microsecond))
+		}
+	})
+
+	It("doesn't send packets closer to each other than the minimum pacing delay", func() {
+		// at this rate, a full-size packet could be sent every 10 microseconds
+		bandwidth = 100000 * Bandwidth(maxDatagramSize) * BytesPerSecond
+		t := time.Now()
+		p.SentPacket(t, p.Budget(t))
+		Expect(p.TimeUntilSend()).To(Equal(t.Add(protocol.MinPacingDelay)))
+	})
+
+	It("allows bursts that are large enough for the timer granularity at high pacing rates", func() {
+		bandwidth = 100000 * Bandwidth(maxDatagramSize) * BytesPerSecond
+		t := time.Now()
+		p.SentPacket(t, p.Budget(t))
+		budget := p.Budget(t.Add(time.Hour))
+		Expect(budget).To(BeNumerically(">", 10*maxDatagramSize))
+		Expect(budget).To(BeNumerically("~", 110*maxDatagramSize, maxDatagramSize))
+	})
+
+	It("doesn't pace if the pacing rate is unknown", func() {
+		bandwidth = 0
+		t := time.Now()
+		times := sendPackets(t, 100)
+		for _, st := range times {
+			Expect(st).To(Equal(t))
+		}
+	})
+
+	It("uses a burst of at least one packet", func() {
+		p = NewPacer(func() Bandwidth { return bandwidth }, 0, 0)
+		t := time.Now()
+		Expect(p.Budget(t)).To(Equal(maxDatagramSize))
+		times := sendPackets(t, 3)
+		Expect(times[1].Sub(times[0])).To(BeNumerically("~", time.Second/packetsPerSecond, time.Microsecond))
+		Expect(times[2].Sub(times[1])).To(BeNumerically("~", time.Second/packetsPerSecond, time.Microsecond))
+	})
+})
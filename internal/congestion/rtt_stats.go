@@ -18,6 +18,8 @@ const (
 
 // RTTStats provides round-trip statistics
 type RTTStats struct {
+	hasMeasurement bool
+
 	minRTT        time.Duration
 	latestRTT     time.Duration
 	smoothedRTT   time.Duration
@@ -84,7 +86,8 @@ func (r *RTTStats) UpdateRTT(sendDelta, ackDelay time.Duration, now time.Time) {
 	}
 	r.latestRTT = sample
 	// First time call.
-	if r.smoothedRTT == 0 {
+	if !r.hasMeasurement {
+		r.hasMeasurement = true
 		r.smoothedRTT = sample
 		r.meanDeviation = sample / 2
 	} else {
@@ -93,12 +96,24 @@ func (r *RTTStats) UpdateRTT(sendDelta, ackDelay time.Duration, now time.Time) {
 	}
 }
 
+// SetInitialRTT sets the initial RTT.
+// It is used during the 0-RTT handshake when restoring the RTT stats from the session state.
+// It has no effect once an RTT sample was taken.
+func (r *RTTStats) SetInitialRTT(t time.Duration) {
+	if r.hasMeasurement {
+		return
+	}
+	r.smoothedRTT = t
+	r.latestRTT = t
+}
+
 func (r *RTTStats) SetMaxAckDelay(mad time.Duration) {
 	r.maxAckDelay = mad
 }
 
 // OnConnectionMigration is called when connection migrates and rtt measurement needs to be reset.
 func (r *RTTStats) OnConnectionMigration() {
+	r.hasMeasurement = false
 	r.latestRTT = 0
 	r.minRTT = 0
 	r.smoothedRTT = 0
@@ -11,12 +11,16 @@ import (
 
 type baseFlowController struct {
 	// for sending data
+	sendMutex     sync.Mutex
 	bytesSent     protocol.ByteCount
 	sendWindow    protocol.ByteCount
 	lastBlockedAt protocol.ByteCount
+	numBlocked    uint64
 
 	// for receiving data
 	mutex                sync.RWMutex
+	numPeerBlocked       uint64
+	numWindowIncreases   uint64
 	bytesRead            protocol.ByteCount
 	highestReceived      protocol.ByteCount
 	receiveWindow        protocol.ByteCount
@@ -34,26 +38,40 @@ type baseFlowController struct {
 // For every offset, it only returns true once.
 // If it is blocked, the offset is returned.
 func (c *baseFlowController) IsNewlyBlocked() (bool, protocol.ByteCount) {
-	if c.sendWindowSize() != 0 || c.sendWindow == c.lastBlockedAt {
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+
+	if c.sendWindowSizeLocked() != 0 || c.sendWindow == c.lastBlockedAt {
 		return false, 0
 	}
 	c.lastBlockedAt = c.sendWindow
+	c.numBlocked++
 	return true, c.sendWindow
 }
 
 func (c *baseFlowController) AddBytesSent(n protocol.ByteCount) {
+	c.sendMutex.Lock()
 	c.bytesSent += n
+	c.sendMutex.Unlock()
 }
 
 // UpdateSendWindow should be called after receiving a WindowUpdateFrame
 // it returns true if the window was actually updated
 func (c *baseFlowController) UpdateSendWindow(offset protocol.ByteCount) {
+	c.sendMutex.Lock()
 	if offset > c.sendWindow {
 		c.sendWindow = offset
 	}
+	c.sendMutex.Unlock()
 }
 
 func (c *baseFlowController) sendWindowSize() protocol.ByteCount {
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+	return c.sendWindowSizeLocked()
+}
+
+func (c *baseFlowController) sendWindowSizeLocked() protocol.ByteCount {
 	// this only happens during connection establishment, when data is sent before we receive the peer's transport parameters
 	if c.bytesSent > c.sendWindow {
 		return 0
@@ -73,6 +91,36 @@ func (c *baseFlowController) AddBytesRead(n protocol.ByteCount) {
 	c.bytesRead += n
 }
 
+// ReceivedBlocked is called when the peer tells us that it is blocked by flow control.
+// It is only used for statistics.
+func (c *baseFlowController) ReceivedBlocked() {
+	c.mutex.Lock()
+	c.numPeerBlocked++
+	c.mutex.Unlock()
+}
+
+// GetStats returns the statistics of this flow controller.
+func (c *baseFlowController) GetStats() Stats {
+	c.sendMutex.Lock()
+	stats := Stats{
+		SendWindow:   c.sendWindow,
+		BytesSent:    c.bytesSent,
+		BlockedCount: c.numBlocked,
+	}
+	c.sendMutex.Unlock()
+
+	c.mutex.RLock()
+	stats.ReceiveWindow = c.receiveWindow
+	stats.ReceiveWindowSize = c.receiveWindowSize
+	stats.MaxReceiveWindowSize = c.maxReceiveWindowSize
+	stats.BytesRead = c.bytesRead
+	stats.HighestReceived = c.highestReceived
+	stats.PeerBlockedCount = c.numPeerBlocked
+	stats.WindowIncreaseCount = c.numWindowIncreases
+	c.mutex.RUnlock()
+	return stats
+}
+
 func (c *baseFlowController) hasWindowUpdate() bool {
 	bytesRemaining := c.receiveWindow - c.bytesRead
 	// update the window when more than the threshold was consumed
@@ -99,7 +147,7 @@ func (c *baseFlowController) maybeAdjustWindowSize() {
 	if bytesReadInEpoch <= c.receiveWindowSize/2 {
 		return
 	}
-	rtt := c.rttStats.SmoothedRTT()
+	rtt := c.autoTuningRTT()
 	if rtt == 0 {
 		return
 	}
@@ -107,11 +155,22 @@ func (c *baseFlowController) maybeAdjustWindowSize() {
 	fraction := float64(bytesReadInEpoch) / float64(c.receiveWindowSize)
 	if time.Since(c.epochStartTime) < time.Duration(4*fraction*float64(rtt)) {
 		// window is consumed too fast, try to increase the window size
-		c.receiveWindowSize = utils.MinByteCount(2*c.receiveWindowSize, c.maxReceiveWindowSize)
+		if newSize := utils.MinByteCount(2*c.receiveWindowSize, c.maxReceiveWindowSize); newSize > c.receiveWindowSize {
+			c.receiveWindowSize = newSize
+			c.numWindowIncreases++
+		}
 	}
 	c.startNewAutoTuningEpoch()
 }
 
+// autoTuningRTT returns the RTT used for auto-tuning the window size.
+// The smoothed RTT only slowly adapts to RTT increases, e.g. when the path becomes congested
+// because multiple streams are downloading in parallel.
+// Using the latest RTT sample in that case prevents the window from becoming the bottleneck.
+func (c *baseFlowController) autoTuningRTT() time.Duration {
+	return utils.MaxDuration(c.rttStats.SmoothedRTT(), c.rttStats.LatestRTT())
+}
+
 func (c *baseFlowController) startNewAutoTuningEpoch() {
 	c.epochStartTime = time.Now()
 	c.epochStartOffset = c.bytesRead
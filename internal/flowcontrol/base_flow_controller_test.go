@@ -85,6 +85,21 @@ var _ = Describe("Base Flow controller", func() {
 			newlyBlocked, _ = controller.IsNewlyBlocked()
 			Expect(newlyBlocked).To(BeTrue())
 		})
+
+		It("counts how often it was blocked", func() {
+			controller.UpdateSendWindow(100)
+			controller.AddBytesSent(100)
+			controller.IsNewlyBlocked()
+			controller.IsNewlyBlocked()
+			Expect(controller.GetStats().BlockedCount).To(BeEquivalentTo(1))
+			controller.UpdateSendWindow(150)
+			controller.AddBytesSent(50)
+			controller.IsNewlyBlocked()
+			stats := controller.GetStats()
+			Expect(stats.BlockedCount).To(BeEquivalentTo(2))
+			Expect(stats.SendWindow).To(Equal(protocol.ByteCount(150)))
+			Expect(stats.BytesSent).To(Equal(protocol.ByteCount(150)))
+		})
 	})
 
 	Context("receive flow control", func() {
@@ -99,6 +114,20 @@ var _ = Describe("Base Flow controller", func() {
 			controller.receiveWindowSize = receiveWindowSize
 		})
 
+		It("returns the receive statistics", func() {
+			controller.maxReceiveWindowSize = 5000
+			controller.highestReceived = receiveWindow - 10
+			controller.ReceivedBlocked()
+			controller.ReceivedBlocked()
+			stats := controller.GetStats()
+			Expect(stats.ReceiveWindow).To(Equal(receiveWindow))
+			Expect(stats.ReceiveWindowSize).To(Equal(receiveWindowSize))
+			Expect(stats.MaxReceiveWindowSize).To(Equal(protocol.ByteCount(5000)))
+			Expect(stats.BytesRead).To(Equal(receiveWindow - receiveWindowSize))
+			Expect(stats.HighestReceived).To(Equal(receiveWindow - 10))
+			Expect(stats.PeerBlockedCount).To(BeEquivalentTo(2))
+		})
+
 		It("adds bytes read", func() {
 			controller.bytesRead = 5
 			controller.AddBytesRead(6)
@@ -167,10 +196,26 @@ var _ = Describe("Base Flow controller", func() {
 				// check that the window size was increased
 				newWindowSize := controller.receiveWindowSize
 				Expect(newWindowSize).To(Equal(2 * oldWindowSize))
+				Expect(controller.GetStats().WindowIncreaseCount).To(BeEquivalentTo(1))
 				// check that the new window size was used to increase the offset
 				Expect(offset).To(Equal(bytesRead + dataRead + newWindowSize))
 			})
 
+			It("uses the latest RTT sample, if it is larger than the smoothed RTT", func() {
+				rtt := scaleDuration(20 * time.Millisecond)
+				setRtt(rtt)
+				// a single large RTT sample only slightly increases the smoothed RTT
+				controller.rttStats.UpdateRTT(3*rtt, 0, time.Now())
+				Expect(controller.rttStats.SmoothedRTT()).To(BeNumerically("<", 2*rtt))
+				// consume more than 2/3 of the window in 4*2/3 of the smoothed RTT
+				dataRead := receiveWindowSize*2/3 + 1
+				controller.epochStartOffset = controller.bytesRead
+				controller.epochStartTime = time.Now().Add(-2 * rtt * 4 * 2 / 3)
+				controller.AddBytesRead(dataRead)
+				Expect(controller.getWindowUpdate()).ToNot(BeZero())
+				Expect(controller.receiveWindowSize).To(Equal(2 * oldWindowSize))
+			})
+
 			It("doesn't increase the window size if data is read so fast that the window would be consumed in less than 4 RTTs, but less than half the window has been read", func() {
 				// this test only makes sense if a window update is triggered before half of the window has been consumed
 				Expect(protocol.WindowUpdateThreshold).To(BeNumerically(">", 1/3))
@@ -230,6 +275,7 @@ var _ = Describe("Base Flow controller", func() {
 				Expect(controller.receiveWindowSize).To(Equal(controller.maxReceiveWindowSize)) // 5000
 				controller.maybeAdjustWindowSize()
 				Expect(controller.receiveWindowSize).To(Equal(controller.maxReceiveWindowSize)) // 5000
+				Expect(controller.GetStats().WindowIncreaseCount).To(BeEquivalentTo(3))
 			})
 		})
 	})
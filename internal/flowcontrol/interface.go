@@ -2,6 +2,22 @@ package flowcontrol
 
 import "github.com/lucas-clemente/quic-go/internal/protocol"
 
+// Stats are the statistics of a flow controller.
+type Stats struct {
+	// for sending
+	SendWindow   protocol.ByteCount // the offset up to which the peer allows us to send
+	BytesSent    protocol.ByteCount
+	BlockedCount uint64 // how often sending was blocked by flow control
+	// for receiving
+	ReceiveWindow        protocol.ByteCount // the offset up to which we allow the peer to send
+	ReceiveWindowSize    protocol.ByteCount
+	MaxReceiveWindowSize protocol.ByteCount
+	BytesRead            protocol.ByteCount
+	HighestReceived      protocol.ByteCount
+	PeerBlockedCount     uint64 // the number of (STREAM_)DATA_BLOCKED frames received
+	WindowIncreaseCount  uint64 // how often auto-tuning increased the receive window size
+}
+
 type flowController interface {
 	// for sending
 	SendWindowSize() protocol.ByteCount
@@ -11,6 +27,9 @@ type flowController interface {
 	AddBytesRead(protocol.ByteCount)
 	GetWindowUpdate() protocol.ByteCount // returns 0 if no update is necessary
 	IsNewlyBlocked() (bool, protocol.ByteCount)
+	// ReceivedBlocked should be called when a (STREAM_)DATA_BLOCKED frame is received
+	ReceivedBlocked()
+	GetStats() Stats
 }
 
 // A StreamFlowController is a flow controller for a QUIC stream.
@@ -24,6 +43,10 @@ type StreamFlowController interface {
 	// Abandon should be called when reading from the stream is aborted early,
 	// and there won't be any further calls to AddBytesRead.
 	Abandon()
+	// SetReceiveWindowSize overrides the receive window size and the maximum receive window size.
+	// A value of 0 leaves the respective value unchanged.
+	// The receive window offset that was already granted to the peer is never decreased.
+	SetReceiveWindowSize(size, maxSize protocol.ByteCount)
 }
 
 // The ConnectionFlowController is the flow controller for the connection.
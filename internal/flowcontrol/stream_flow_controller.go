@@ -110,6 +110,22 @@ func (c *streamFlowController) SendWindowSize() protocol.ByteCount {
 	return utils.MinByteCount(c.baseFlowController.sendWindowSize(), c.connection.SendWindowSize())
 }
 
+func (c *streamFlowController) SetReceiveWindowSize(size, maxSize protocol.ByteCount) {
+	c.mutex.Lock()
+	if maxSize != 0 {
+		c.maxReceiveWindowSize = maxSize
+	}
+	if size != 0 {
+		c.receiveWindowSize = size
+	}
+	c.receiveWindowSize = utils.MinByteCount(c.receiveWindowSize, c.maxReceiveWindowSize)
+	size = c.receiveWindowSize
+	c.mutex.Unlock()
+
+	c.connection.EnsureMinimumWindowSize(protocol.ByteCount(float64(size) * protocol.ConnectionFlowControlMultiplier))
+	c.maybeQueueWindowUpdate()
+}
+
 func (c *streamFlowController) maybeQueueWindowUpdate() {
 	c.mutex.Lock()
 	hasWindowUpdate := !c.receivedFinalOffset && c.hasWindowUpdate()
@@ -131,7 +147,7 @@ func (c *streamFlowController) GetWindowUpdate() protocol.ByteCount {
 	oldWindowSize := c.receiveWindowSize
 	offset := c.baseFlowController.getWindowUpdate()
 	if c.receiveWindowSize > oldWindowSize { // auto-tuning enlarged the window size
-		c.logger.Debugf("Increasing receive flow control window for stream %d to %d kB", c.streamID, c.receiveWindowSize/(1<<10))
+		c.logger.Debugf("Increasing receive flow control window for stream %d to %d kB (RTT: %s)", c.streamID, c.receiveWindowSize/(1<<10), c.autoTuningRTT())
 		c.connection.EnsureMinimumWindowSize(protocol.ByteCount(float64(c.receiveWindowSize) * protocol.ConnectionFlowControlMultiplier))
 	}
 	c.mutex.Unlock()
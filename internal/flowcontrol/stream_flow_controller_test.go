@@ -188,6 +188,22 @@ var _ = Describe("Stream Flow controller", func() {
 				Expect(controller.connection.(*connectionFlowController).receiveWindowSize).To(Equal(protocol.ByteCount(float64(controller.receiveWindowSize) * protocol.ConnectionFlowControlMultiplier)))
 			})
 
+			It("overrides the receive window size", func() {
+				controller.SetReceiveWindowSize(500, 2000)
+				Expect(controller.receiveWindowSize).To(Equal(protocol.ByteCount(500)))
+				Expect(controller.maxReceiveWindowSize).To(Equal(protocol.ByteCount(2000)))
+				Expect(queuedWindowUpdate).To(BeTrue())
+				Expect(controller.GetWindowUpdate()).To(Equal(protocol.ByteCount(40 + 500)))
+				Expect(controller.connection.(*connectionFlowController).receiveWindowSize).To(Equal(protocol.ByteCount(750)))
+			})
+
+			It("only overrides the maximum receive window size", func() {
+				controller.SetReceiveWindowSize(0, 50)
+				Expect(controller.maxReceiveWindowSize).To(Equal(protocol.ByteCount(50)))
+				Expect(controller.receiveWindowSize).To(Equal(protocol.ByteCount(50)))
+				Expect(queuedWindowUpdate).To(BeFalse())
+			})
+
 			It("sends a connection-level window update when a large stream is abandoned", func() {
 				Expect(controller.UpdateHighestReceived(90, true)).To(Succeed())
 				Expect(controller.connection.GetWindowUpdate()).To(BeZero())
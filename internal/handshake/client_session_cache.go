@@ -0,0 +1,115 @@
+package handshake
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"time"
+	"unsafe"
+
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/marten-seemann/qtls"
+)
+
+const clientSessionStateRevision = 1
+
+// The clientSessionCache wraps the tls.ClientSessionCache of the tls.Config.
+// In addition to the TLS session state, it saves the RTT and the data returned by getAppData
+// (the server's transport parameters) in the nonce field of the session state.
+// The nonce is serialized by MarshalClientSessionState, so this also works with persistent caches.
+type clientSessionCache struct {
+	tls.ClientSessionCache
+	rttStats *congestion.RTTStats
+
+	getAppData func() []byte
+	setAppData func([]byte)
+}
+
+func newClientSessionCache(
+	cache tls.ClientSessionCache,
+	rttStats *congestion.RTTStats,
+	get func() []byte,
+	set func([]byte),
+) *clientSessionCache {
+	return &clientSessionCache{
+		ClientSessionCache: cache,
+		rttStats:           rttStats,
+		getAppData:         get,
+		setAppData:         set,
+	}
+}
+
+var _ qtls.ClientSessionCache = &clientSessionCache{}
+
+func (c *clientSessionCache) Get(sessionKey string) (*qtls.ClientSessionState, bool) {
+	sess, ok := c.ClientSessionCache.Get(sessionKey)
+	if sess == nil {
+		return nil, ok
+	}
+	// qtls.ClientSessionState is identical to the tls.ClientSessionState.
+	// In order to allow users of quic-go to use a tls.Config,
+	// we need this workaround to use the ClientSessionCache.
+	// In unsafe.go we check that the two structs are actually identical.
+	// The session state is copied, since we modify the nonce.
+	// The session state saved in the cache must not be changed.
+	session := *(*clientSessionState)(unsafe.Pointer(sess))
+	r := bytes.NewReader(session.nonce)
+	rev, err := utils.ReadVarInt(r)
+	if err != nil || rev != clientSessionStateRevision {
+		return nil, false
+	}
+	rtt, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, false
+	}
+	appData, err := readLengthPrefixed(r)
+	if err != nil {
+		return nil, false
+	}
+	nonce, err := readLengthPrefixed(r)
+	if err != nil {
+		return nil, false
+	}
+	c.setAppData(appData)
+	session.nonce = nonce
+	c.rttStats.SetInitialRTT(time.Duration(rtt) * time.Microsecond)
+	return (*qtls.ClientSessionState)(unsafe.Pointer(&session)), ok
+}
+
+func (c *clientSessionCache) Put(sessionKey string, cs *qtls.ClientSessionState) {
+	if cs == nil {
+		c.ClientSessionCache.Put(sessionKey, nil)
+		return
+	}
+	// qtls.ClientSessionState is identical to the tls.ClientSessionState.
+	// In order to allow users of quic-go to use a tls.Config,
+	// we need this workaround to use the ClientSessionCache.
+	// In unsafe.go we check that the two structs are actually identical.
+	session := *(*clientSessionState)(unsafe.Pointer(cs))
+	appData := c.getAppData()
+	buf := &bytes.Buffer{}
+	utils.WriteVarInt(buf, clientSessionStateRevision)
+	utils.WriteVarInt(buf, uint64(c.rttStats.SmoothedRTT()/time.Microsecond))
+	utils.WriteVarInt(buf, uint64(len(appData)))
+	buf.Write(appData)
+	utils.WriteVarInt(buf, uint64(len(session.nonce)))
+	buf.Write(session.nonce)
+	session.nonce = buf.Bytes()
+	c.ClientSessionCache.Put(sessionKey, (*tls.ClientSessionState)(unsafe.Pointer(&session)))
+}
+
+func readLengthPrefixed(r *bytes.Reader) ([]byte, error) {
+	l, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if l > uint64(r.Len()) {
+		return nil, io.EOF
+	}
+	b := make([]byte, l)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,69 @@
+package handshake
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"unsafe"
+
+	"github.com/marten-seemann/qtls"
+)
+
+// qtlsConnectionState has the same memory layout as qtls.ConnectionState.
+// It is used to access the ekm field, which is used by ExportKeyingMaterial.
+// In unsafe.go we check that the structs are actually identical.
+type qtlsConnectionState struct {
+	Version                     uint16
+	HandshakeComplete           bool
+	DidResume                   bool
+	CipherSuite                 uint16
+	NegotiatedProtocol          string
+	NegotiatedProtocolIsMutual  bool
+	ServerName                  string
+	PeerCertificates            []*x509.Certificate
+	VerifiedChains              [][]*x509.Certificate
+	SignedCertificateTimestamps [][]byte
+	OCSPResponse                []byte
+	Used0RTT                    bool
+	ekm                         func(label string, context []byte, length int) ([]byte, error)
+	TLSUnique                   []byte
+}
+
+// tlsConnectionState has the same memory layout as tls.ConnectionState.
+// In unsafe.go we check that the structs are actually identical.
+type tlsConnectionState struct {
+	Version                     uint16
+	HandshakeComplete           bool
+	DidResume                   bool
+	CipherSuite                 uint16
+	NegotiatedProtocol          string
+	NegotiatedProtocolIsMutual  bool
+	ServerName                  string
+	PeerCertificates            []*x509.Certificate
+	VerifiedChains              [][]*x509.Certificate
+	SignedCertificateTimestamps [][]byte
+	OCSPResponse                []byte
+	ekm                         func(label string, context []byte, length int) ([]byte, error)
+	TLSUnique                   []byte
+}
+
+// toTLSConnectionState converts a qtls.ConnectionState to a tls.ConnectionState.
+// qtls.ConnectionState contains an additional field (Used0RTT), so it can't be cast directly.
+// The unexported ekm field is copied, such that ExportKeyingMaterial still works.
+func toTLSConnectionState(cs qtls.ConnectionState) tls.ConnectionState {
+	qcs := (*qtlsConnectionState)(unsafe.Pointer(&cs))
+	return *(*tls.ConnectionState)(unsafe.Pointer(&tlsConnectionState{
+		Version:                     qcs.Version,
+		HandshakeComplete:           qcs.HandshakeComplete,
+		DidResume:                   qcs.DidResume,
+		CipherSuite:                 qcs.CipherSuite,
+		NegotiatedProtocol:          qcs.NegotiatedProtocol,
+		NegotiatedProtocolIsMutual:  qcs.NegotiatedProtocolIsMutual,
+		ServerName:                  qcs.ServerName,
+		PeerCertificates:            qcs.PeerCertificates,
+		VerifiedChains:              qcs.VerifiedChains,
+		SignedCertificateTimestamps: qcs.SignedCertificateTimestamps,
+		OCSPResponse:                qcs.OCSPResponse,
+		ekm:                         qcs.ekm,
+		TLSUnique:                   qcs.TLSUnique,
+	}))
+}
@@ -1,13 +1,15 @@
 package handshake
 
 import (
+	"bytes"
+	"crypto/rand"
 	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"sync"
-	"unsafe"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -66,7 +68,10 @@ type cryptoSetup struct {
 
 	messageChan chan []byte
 
-	paramsChan <-chan []byte
+	ourParams *TransportParameters
+	// the transport parameters received from the peer, as sent on the wire
+	peerParamsData []byte
+	paramsChan     <-chan []byte
 
 	runner handshakeRunner
 
@@ -76,8 +81,16 @@ type cryptoSetup struct {
 	// is closed when Close() is called
 	closeChan chan struct{}
 
+	// only set for the server, if 0-RTT is enabled
+	// It is used to reject replayed 0-RTT data.
+	acceptTicket func(ticketID []byte) bool
+	// only used by the server
+	// qtls also derives the 0-RTT read key if 0-RTT was rejected, it must not be installed in that case.
+	accepted0RTT bool
+
+	zeroRTTParameters      *TransportParameters
 	clientHelloWritten     bool
-	clientHelloWrittenChan chan struct{}
+	clientHelloWrittenChan chan *TransportParameters
 
 	receivedWriteKey chan struct{}
 	receivedReadKey  chan struct{}
@@ -88,15 +101,23 @@ type cryptoSetup struct {
 	// for clients: to see if a ServerHello is a HelloRetryRequest
 	writeRecord chan struct{}
 
+	rttStats *congestion.RTTStats
+
 	logger utils.Logger
 
 	perspective protocol.Perspective
+	version     protocol.VersionNumber
 
 	mutex sync.Mutex // protects all members below
 
+	handshakeCompleteTime time.Time
+
 	readEncLevel  protocol.EncryptionLevel
 	writeEncLevel protocol.EncryptionLevel
 
+	zeroRTTOpener LongHeaderOpener // only set for the server
+	zeroRTTSealer LongHeaderSealer // only set for the client
+
 	initialStream io.Writer
 	initialOpener LongHeaderOpener
 	initialSealer LongHeaderSealer
@@ -124,9 +145,13 @@ func NewCryptoSetupClient(
 	tp *TransportParameters,
 	runner handshakeRunner,
 	tlsConf *tls.Config,
+	enable0RTT bool,
+	keyUpdateInterval uint64,
+	keyUpdateIntervalBytes uint64,
 	rttStats *congestion.RTTStats,
 	logger utils.Logger,
-) (CryptoSetup, <-chan struct{} /* ClientHello written */) {
+	version protocol.VersionNumber,
+) (CryptoSetup, <-chan *TransportParameters /* ClientHello written. Receive nil for non-0-RTT */) {
 	cs, clientHelloWritten := newCryptoSetup(
 		initialStream,
 		handshakeStream,
@@ -135,15 +160,21 @@ func NewCryptoSetupClient(
 		tp,
 		runner,
 		tlsConf,
+		enable0RTT,
+		keyUpdateInterval,
+		keyUpdateIntervalBytes,
 		rttStats,
 		logger,
 		protocol.PerspectiveClient,
+		version,
 	)
-	cs.conn = qtls.Client(newConn(remoteAddr), cs.tlsConf)
+	cs.conn = qtls.Client(newConn(remoteAddr, version), cs.tlsConf)
 	return cs, clientHelloWritten
 }
 
-// NewCryptoSetupServer creates a new crypto setup for the server
+// NewCryptoSetupServer creates a new crypto setup for the server.
+// If 0-RTT is enabled, acceptTicket is called with the ID of the session ticket that a client uses for 0-RTT.
+// It is used to reject replayed 0-RTT data. If it is nil, 0-RTT is accepted for every valid session ticket.
 func NewCryptoSetupServer(
 	initialStream io.Writer,
 	handshakeStream io.Writer,
@@ -153,8 +184,13 @@ func NewCryptoSetupServer(
 	tp *TransportParameters,
 	runner handshakeRunner,
 	tlsConf *tls.Config,
+	enable0RTT bool,
+	acceptTicket func(ticketID []byte) bool,
+	keyUpdateInterval uint64,
+	keyUpdateIntervalBytes uint64,
 	rttStats *congestion.RTTStats,
 	logger utils.Logger,
+	version protocol.VersionNumber,
 ) CryptoSetup {
 	cs, _ := newCryptoSetup(
 		initialStream,
@@ -164,11 +200,16 @@ func NewCryptoSetupServer(
 		tp,
 		runner,
 		tlsConf,
+		enable0RTT,
+		keyUpdateInterval,
+		keyUpdateIntervalBytes,
 		rttStats,
 		logger,
 		protocol.PerspectiveServer,
+		version,
 	)
-	cs.conn = qtls.Server(newConn(remoteAddr), cs.tlsConf)
+	cs.acceptTicket = acceptTicket
+	cs.conn = qtls.Server(newConn(remoteAddr, version), cs.tlsConf)
 	return cs
 }
 
@@ -180,51 +221,84 @@ func newCryptoSetup(
 	tp *TransportParameters,
 	runner handshakeRunner,
 	tlsConf *tls.Config,
+	enable0RTT bool,
+	keyUpdateInterval uint64,
+	keyUpdateIntervalBytes uint64,
 	rttStats *congestion.RTTStats,
 	logger utils.Logger,
 	perspective protocol.Perspective,
-) (*cryptoSetup, <-chan struct{} /* ClientHello written */) {
-	initialSealer, initialOpener := NewInitialAEAD(connID, perspective)
-	extHandler := newExtensionHandler(tp.Marshal(), perspective)
+	version protocol.VersionNumber,
+) (*cryptoSetup, <-chan *TransportParameters /* ClientHello written. Receive nil for non-0-RTT */) {
+	initialSealer, initialOpener := NewInitialAEAD(connID, perspective, version)
+	extHandler := newExtensionHandler(tp.Marshal(version), perspective, version)
 	cs := &cryptoSetup{
 		initialStream:          initialStream,
 		initialSealer:          initialSealer,
 		initialOpener:          initialOpener,
 		handshakeStream:        handshakeStream,
 		oneRTTStream:           oneRTTStream,
-		aead:                   newUpdatableAEAD(rttStats, logger),
+		aead:                   newUpdatableAEAD(rttStats, keyUpdateInterval, keyUpdateIntervalBytes, logger),
 		readEncLevel:           protocol.EncryptionInitial,
 		writeEncLevel:          protocol.EncryptionInitial,
 		runner:                 runner,
+		ourParams:              tp,
 		paramsChan:             extHandler.TransportParameters(),
+		rttStats:               rttStats,
 		logger:                 logger,
 		perspective:            perspective,
+		version:                version,
 		handshakeDone:          make(chan struct{}),
 		alertChan:              make(chan uint8),
-		clientHelloWrittenChan: make(chan struct{}),
+		clientHelloWrittenChan: make(chan *TransportParameters, 1),
 		messageChan:            make(chan []byte, 100),
 		receivedReadKey:        make(chan struct{}),
 		receivedWriteKey:       make(chan struct{}),
 		writeRecord:            make(chan struct{}, 1),
 		closeChan:              make(chan struct{}),
 	}
-	qtlsConf := tlsConfigToQtlsConfig(tlsConf, cs, extHandler)
+	qtlsConf := tlsConfigToQtlsConfig(tlsConf, cs, extHandler, rttStats, cs.marshalPeerParamsForSessionState, cs.handlePeerParamsFromSessionState, cs.accept0RTT, cs.rejected0RTT, enable0RTT)
 	cs.tlsConf = qtlsConf
 	return cs, cs.clientHelloWrittenChan
 }
 
 func (h *cryptoSetup) ChangeConnectionID(id protocol.ConnectionID) {
-	initialSealer, initialOpener := NewInitialAEAD(id, h.perspective)
+	initialSealer, initialOpener := NewInitialAEAD(id, h.perspective, h.version)
 	h.initialSealer = initialSealer
 	h.initialOpener = initialOpener
 }
 
 func (h *cryptoSetup) SetLargest1RTTAcked(pn protocol.PacketNumber) {
 	h.aead.SetLargestAcked(pn)
-	// drop handshake keys
-	if h.handshakeOpener != nil {
-		h.handshakeOpener = nil
-		h.handshakeSealer = nil
+	h.dropHandshakeKeys()
+}
+
+// SetHandshakeConfirmed is called when the handshake is confirmed.
+// For versions that use the HANDSHAKE_DONE frame, this happens
+// when the server completes the handshake, or when the client receives the HANDSHAKE_DONE frame.
+func (h *cryptoSetup) SetHandshakeConfirmed() {
+	h.dropHandshakeKeys()
+}
+
+// InitiateKeyUpdate requests a key update of the 1-RTT keys.
+// The keys are updated as soon as this is permitted.
+func (h *cryptoSetup) InitiateKeyUpdate() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if !h.has1RTTSealer {
+		return ErrKeysNotYetAvailable
+	}
+	h.aead.InitiateKeyUpdate()
+	return nil
+}
+
+func (h *cryptoSetup) dropHandshakeKeys() {
+	h.mutex.Lock()
+	dropped := h.handshakeOpener != nil
+	h.handshakeOpener = nil
+	h.handshakeSealer = nil
+	h.mutex.Unlock()
+	if dropped {
 		h.logger.Debugf("Dropping Handshake keys.")
 		h.runner.DropKeys(protocol.EncryptionHandshake)
 	}
@@ -245,6 +319,9 @@ func (h *cryptoSetup) RunHandshake() {
 
 	select {
 	case <-handshakeComplete: // return when the handshake is done
+		h.mutex.Lock()
+		h.handshakeCompleteTime = time.Now()
+		h.mutex.Unlock()
 		h.runner.OnHandshakeComplete()
 		// send a session ticket
 		if h.perspective == protocol.PerspectiveServer {
@@ -336,7 +413,7 @@ func (h *cryptoSetup) handleMessageForServer(msgType messageType) bool {
 			h.logger.Debugf("Sending HelloRetryRequest")
 			return false
 		case data := <-h.paramsChan:
-			h.runner.OnReceivedParams(data)
+			h.handleTransportParameters(data)
 		case <-h.handshakeDone:
 			return false
 		}
@@ -401,7 +478,7 @@ func (h *cryptoSetup) handleMessageForClient(msgType messageType) bool {
 	case typeEncryptedExtensions:
 		select {
 		case data := <-h.paramsChan:
-			h.runner.OnReceivedParams(data)
+			h.handleTransportParameters(data)
 		case <-h.handshakeDone:
 			return false
 		}
@@ -428,9 +505,92 @@ func (h *cryptoSetup) handleMessageForClient(msgType messageType) bool {
 	}
 }
 
+func (h *cryptoSetup) handleTransportParameters(data []byte) {
+	h.peerParamsData = data
+	h.runner.OnReceivedParams(data)
+}
+
+// must be called after receiving the transport parameters
+func (h *cryptoSetup) marshalPeerParamsForSessionState() []byte {
+	// The transport parameters were already validated when they were received.
+	var tp TransportParameters
+	if err := tp.Unmarshal(h.peerParamsData, h.perspective.Opposite(), h.version); err != nil {
+		return nil
+	}
+	b := &bytes.Buffer{}
+	tp.MarshalForSessionTicket(b)
+	return b.Bytes()
+}
+
+func (h *cryptoSetup) handlePeerParamsFromSessionState(data []byte) {
+	var tp TransportParameters
+	if err := tp.UnmarshalFromSessionTicket(data); err != nil {
+		h.logger.Debugf("Restoring of transport parameters from session ticket failed: %s", err.Error())
+		return
+	}
+	h.zeroRTTParameters = &tp
+}
+
+// accept0RTT is called for the server when receiving the client's session ticket.
+// It decides whether to accept 0-RTT.
+func (h *cryptoSetup) accept0RTT(sessionTicketData []byte) bool {
+	var t sessionTicket
+	if err := t.Unmarshal(sessionTicketData); err != nil {
+		h.logger.Debugf("Unmarshaling transport parameters from session ticket failed: %s", err.Error())
+		return false
+	}
+	if t.Version != h.version {
+		h.logger.Debugf("Session ticket was issued for %s. Rejecting 0-RTT.", t.Version)
+		return false
+	}
+	if !h.ourParams.ValidFor0RTT(t.Parameters) {
+		h.logger.Debugf("Transport parameters changed. Rejecting 0-RTT.")
+		return false
+	}
+	if h.acceptTicket != nil && !h.acceptTicket(t.ID) {
+		h.logger.Debugf("Session ticket %#x was rejected. Rejecting 0-RTT.", t.ID)
+		return false
+	}
+	h.logger.Debugf("Accepting 0-RTT. Restoring RTT from session ticket: %s", t.RTT)
+	h.rttStats.SetInitialRTT(t.RTT)
+	h.mutex.Lock()
+	h.accepted0RTT = true
+	h.mutex.Unlock()
+	return true
+}
+
+// rejected0RTT is called for the client when the server rejects 0-RTT.
+func (h *cryptoSetup) rejected0RTT() {
+	h.logger.Debugf("0-RTT was rejected. Dropping 0-RTT keys.")
+
+	h.mutex.Lock()
+	had0RTTKeys := h.zeroRTTSealer != nil
+	h.zeroRTTSealer = nil
+	h.mutex.Unlock()
+
+	if had0RTTKeys {
+		h.runner.DropKeys(protocol.Encryption0RTT)
+	}
+}
+
 // only valid for the server
 func (h *cryptoSetup) maybeSendSessionTicket() {
-	ticket, err := h.conn.GetSessionTicket()
+	var appData []byte
+	// Save the transport parameters to the session ticket if we're allowing 0-RTT.
+	if h.tlsConf.MaxEarlyData > 0 {
+		id := make([]byte, sessionTicketIDLen)
+		if _, err := rand.Read(id); err != nil {
+			h.onError(alertInternalError, err.Error())
+			return
+		}
+		appData = (&sessionTicket{
+			ID:         id,
+			Version:    h.version,
+			Parameters: h.ourParams,
+			RTT:        h.rttStats.SmoothedRTT(),
+		}).Marshal()
+	}
+	ticket, err := h.conn.GetSessionTicket(appData)
 	if err != nil {
 		h.onError(alertInternalError, err.Error())
 		return
@@ -478,6 +638,22 @@ func (h *cryptoSetup) ReadHandshakeMessage() ([]byte, error) {
 func (h *cryptoSetup) SetReadKey(encLevel qtls.EncryptionLevel, suite *qtls.CipherSuiteTLS13, trafficSecret []byte) {
 	h.mutex.Lock()
 	switch encLevel {
+	case qtls.Encryption0RTT:
+		if h.perspective == protocol.PerspectiveClient {
+			panic("Received 0-RTT read key for the client")
+		}
+		if !h.accepted0RTT {
+			h.mutex.Unlock()
+			h.logger.Debugf("Not installing 0-RTT Read keys, since 0-RTT was rejected.")
+			return
+		}
+		h.zeroRTTOpener = newLongHeaderOpener(
+			createAEAD(suite, trafficSecret),
+			newHeaderProtector(suite, trafficSecret, true),
+		)
+		h.mutex.Unlock()
+		h.logger.Debugf("Installed 0-RTT Read keys (using %s)", cipherSuiteName(suite.ID))
+		return
 	case qtls.EncryptionHandshake:
 		h.readEncLevel = protocol.EncryptionHandshake
 		h.handshakeOpener = newHandshakeOpener(
@@ -502,6 +678,17 @@ func (h *cryptoSetup) SetReadKey(encLevel qtls.EncryptionLevel, suite *qtls.Ciph
 func (h *cryptoSetup) SetWriteKey(encLevel qtls.EncryptionLevel, suite *qtls.CipherSuiteTLS13, trafficSecret []byte) {
 	h.mutex.Lock()
 	switch encLevel {
+	case qtls.Encryption0RTT:
+		if h.perspective == protocol.PerspectiveServer {
+			panic("Received 0-RTT write key for the server")
+		}
+		h.zeroRTTSealer = newLongHeaderSealer(
+			createAEAD(suite, trafficSecret),
+			newHeaderProtector(suite, trafficSecret, true),
+		)
+		h.mutex.Unlock()
+		h.logger.Debugf("Installed 0-RTT Write keys (using %s)", cipherSuiteName(suite.ID))
+		return
 	case qtls.EncryptionHandshake:
 		h.writeEncLevel = protocol.EncryptionHandshake
 		h.handshakeSealer = newHandshakeSealer(
@@ -516,6 +703,10 @@ func (h *cryptoSetup) SetWriteKey(encLevel qtls.EncryptionLevel, suite *qtls.Cip
 		h.aead.SetWriteKey(suite, trafficSecret)
 		h.has1RTTSealer = true
 		h.logger.Debugf("Installed 1-RTT Write keys (using %s)", cipherSuiteName(suite.ID))
+		if h.zeroRTTSealer != nil {
+			h.zeroRTTSealer = nil
+			h.logger.Debugf("Dropping 0-RTT keys.")
+		}
 	default:
 		panic("unexpected write encryption level")
 	}
@@ -534,7 +725,13 @@ func (h *cryptoSetup) WriteRecord(p []byte) (int, error) {
 		n, err := h.initialStream.Write(p)
 		if !h.clientHelloWritten && h.perspective == protocol.PerspectiveClient {
 			h.clientHelloWritten = true
-			close(h.clientHelloWrittenChan)
+			if h.zeroRTTSealer != nil && h.zeroRTTParameters != nil {
+				h.logger.Debugf("Doing 0-RTT.")
+				h.clientHelloWrittenChan <- h.zeroRTTParameters
+			} else {
+				h.logger.Debugf("Not doing 0-RTT. Has sealer: %t, has params: %t", h.zeroRTTSealer != nil, h.zeroRTTParameters != nil)
+				h.clientHelloWrittenChan <- nil
+			}
 		} else {
 			// We need additional signaling to properly detect HelloRetryRequests.
 			// For servers: when the ServerHello is written.
@@ -573,6 +770,16 @@ func (h *cryptoSetup) GetInitialSealer() (LongHeaderSealer, error) {
 	return h.initialSealer, nil
 }
 
+func (h *cryptoSetup) Get0RTTSealer() (LongHeaderSealer, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.zeroRTTSealer == nil {
+		return nil, ErrKeysDropped
+	}
+	return h.zeroRTTSealer, nil
+}
+
 func (h *cryptoSetup) GetHandshakeSealer() (LongHeaderSealer, error) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
@@ -593,9 +800,30 @@ func (h *cryptoSetup) Get1RTTSealer() (ShortHeaderSealer, error) {
 	if !h.has1RTTSealer {
 		return nil, ErrKeysNotYetAvailable
 	}
+	if h.aead.confidentialityLimitReached() {
+		// The peer didn't allow us to update the keys in time.
+		h.runner.OnError(qerr.Error(qerr.AEADLimitReached, "confidentiality limit reached"))
+	}
 	return h.aead, nil
 }
 
+func (h *cryptoSetup) Get1RTTSealerForPath(pathID uint64) (ShortHeaderSealer, error) {
+	if pathID == 0 {
+		return h.Get1RTTSealer()
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if !h.has1RTTSealer {
+		return nil, ErrKeysNotYetAvailable
+	}
+	if h.aead.confidentialityLimitReached() {
+		// The peer didn't allow us to update the keys in time.
+		h.runner.OnError(qerr.Error(qerr.AEADLimitReached, "confidentiality limit reached"))
+	}
+	return h.aead.forPath(pathID), nil
+}
+
 func (h *cryptoSetup) GetInitialOpener() (LongHeaderOpener, error) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
@@ -606,6 +834,20 @@ func (h *cryptoSetup) GetInitialOpener() (LongHeaderOpener, error) {
 	return h.initialOpener, nil
 }
 
+func (h *cryptoSetup) Get0RTTOpener() (LongHeaderOpener, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.zeroRTTOpener == nil {
+		if h.initialOpener != nil {
+			return nil, ErrKeysNotYetAvailable
+		}
+		// if the initial opener is also not available, the keys were already dropped
+		return nil, ErrKeysDropped
+	}
+	return h.zeroRTTOpener, nil
+}
+
 func (h *cryptoSetup) GetHandshakeOpener() (LongHeaderOpener, error) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
@@ -624,18 +866,31 @@ func (h *cryptoSetup) Get1RTTOpener() (ShortHeaderOpener, error) {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
 
+	// Reordered 0-RTT packets might still arrive shortly after the handshake completed.
+	if h.zeroRTTOpener != nil && !h.handshakeCompleteTime.IsZero() && time.Since(h.handshakeCompleteTime) > 3*h.rttStats.PTO(true) {
+		h.zeroRTTOpener = nil
+		h.logger.Debugf("Dropping 0-RTT keys.")
+	}
+
 	if !h.has1RTTOpener {
 		return nil, ErrKeysNotYetAvailable
 	}
 	return h.aead, nil
 }
 
+func (h *cryptoSetup) Get1RTTOpenerForPath(pathID uint64) (ShortHeaderOpener, error) {
+	if pathID == 0 {
+		return h.Get1RTTOpener()
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if !h.has1RTTOpener {
+		return nil, ErrKeysNotYetAvailable
+	}
+	return h.aead.forPath(pathID), nil
+}
+
 func (h *cryptoSetup) ConnectionState() tls.ConnectionState {
-	cs := h.conn.ConnectionState()
-	// h.conn is a qtls.Conn, which returns a qtls.ConnectionState.
-	// qtls.ConnectionState is identical to the tls.ConnectionState.
-	// It contains an unexported field which is used ExportKeyingMaterial().
-	// The only way to return a tls.ConnectionState is to use unsafe.
-	// In unsafe.go we check that the two objects are actually identical.
-	return *(*tls.ConnectionState)(unsafe.Pointer(&cs))
+	return toTLSConnectionState(h.conn.ConnectionState())
 }
@@ -95,8 +95,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 			&TransportParameters{},
 			NewMockHandshakeRunner(mockCtrl),
 			tlsConf,
+			false,
+			nil,
+			0,
+			0,
 			&congestion.RTTStats{},
 			utils.DefaultLogger.WithPrefix("server"),
+			protocol.VersionTLS,
 		)
 		qtlsConf := server.(*cryptoSetup).tlsConf
 		Expect(qtlsConf.ServerName).To(Equal(tlsConf.ServerName))
@@ -104,7 +109,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 		Expect(getCertificateErr).To(MatchError("GetCertificate"))
 		_, getClientCertificateErr := qtlsConf.GetClientCertificate(nil)
 		Expect(getClientCertificateErr).To(MatchError("GetClientCertificate"))
-		cconf, err := qtlsConf.GetConfigForClient(&tls.ClientHelloInfo{ServerName: "foo.bar"})
+		cconf, err := qtlsConf.GetConfigForClient(&qtls.ClientHelloInfo{ServerName: "foo.bar"})
 		Expect(err).ToNot(HaveOccurred())
 		Expect(cconf.ServerName).To(Equal("foo.bar"))
 		Expect(cconf.AlternativeRecordLayer).ToNot(BeNil())
@@ -126,8 +131,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 			&TransportParameters{},
 			runner,
 			testdata.GetTLSConfig(),
+			false,
+			nil,
+			0,
+			0,
 			&congestion.RTTStats{},
 			utils.DefaultLogger.WithPrefix("server"),
+			protocol.VersionTLS,
 		)
 
 		done := make(chan struct{})
@@ -163,8 +173,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 			&TransportParameters{},
 			runner,
 			testdata.GetTLSConfig(),
+			false,
+			nil,
+			0,
+			0,
 			&congestion.RTTStats{},
 			utils.DefaultLogger.WithPrefix("server"),
+			protocol.VersionTLS,
 		)
 
 		done := make(chan struct{})
@@ -203,8 +218,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 			&TransportParameters{},
 			runner,
 			serverConf,
+			false,
+			nil,
+			0,
+			0,
 			&congestion.RTTStats{},
 			utils.DefaultLogger.WithPrefix("server"),
+			protocol.VersionTLS,
 		)
 
 		done := make(chan struct{})
@@ -236,8 +256,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 			&TransportParameters{},
 			NewMockHandshakeRunner(mockCtrl),
 			serverConf,
+			false,
+			nil,
+			0,
+			0,
 			&congestion.RTTStats{},
 			utils.DefaultLogger.WithPrefix("server"),
+			protocol.VersionTLS,
 		)
 
 		done := make(chan struct{})
@@ -307,7 +332,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			Eventually(done).Should(BeClosed())
 		}
 
-		handshakeWithTLSConf := func(clientConf, serverConf *tls.Config) (CryptoSetup /* client */, error /* client error */, CryptoSetup /* server */, error /* server error */) {
+		handshakeWithTLSConf := func(clientConf, serverConf *tls.Config, enable0RTT bool) (CryptoSetup /* client */, error /* client error */, CryptoSetup /* server */, error /* server error */) {
 			var cHandshakeComplete bool
 			cChunkChan, cInitialStream, cHandshakeStream, cOneRTTStream := initStreams()
 			cErrChan := make(chan error, 1)
@@ -324,8 +349,12 @@ var _ = Describe("Crypto Setup TLS", func() {
 				&TransportParameters{},
 				cRunner,
 				clientConf,
+				enable0RTT,
+				0,
+				0,
 				&congestion.RTTStats{},
 				utils.DefaultLogger.WithPrefix("client"),
+				protocol.VersionTLS,
 			)
 
 			var sHandshakeComplete bool
@@ -345,8 +374,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 				&TransportParameters{StatelessResetToken: &token},
 				sRunner,
 				serverConf,
+				enable0RTT,
+				nil,
+				0,
+				0,
 				&congestion.RTTStats{},
 				utils.DefaultLogger.WithPrefix("server"),
+				protocol.VersionTLS,
 			)
 
 			handshake(client, cChunkChan, server, sChunkChan)
@@ -365,22 +399,37 @@ var _ = Describe("Crypto Setup TLS", func() {
 		}
 
 		It("handshakes", func() {
-			_, clientErr, _, serverErr := handshakeWithTLSConf(clientConf, serverConf)
+			_, clientErr, _, serverErr := handshakeWithTLSConf(clientConf, serverConf, false)
 			Expect(clientErr).ToNot(HaveOccurred())
 			Expect(serverErr).ToNot(HaveOccurred())
 		})
 
 		It("performs a HelloRetryRequst", func() {
 			serverConf.CurvePreferences = []tls.CurveID{tls.CurveP384}
-			_, clientErr, _, serverErr := handshakeWithTLSConf(clientConf, serverConf)
+			_, clientErr, _, serverErr := handshakeWithTLSConf(clientConf, serverConf, false)
 			Expect(clientErr).ToNot(HaveOccurred())
 			Expect(serverErr).ToNot(HaveOccurred())
 		})
 
+		It("passes the QUIC version to GetConfigForClient", func() {
+			versionChan := make(chan protocol.VersionNumber, 1)
+			conf := serverConf
+			serverConf = &tls.Config{
+				GetConfigForClient: func(ch *tls.ClientHelloInfo) (*tls.Config, error) {
+					versionChan <- ch.Conn.(ConnWithVersion).GetQUICVersion()
+					return conf, nil
+				},
+			}
+			_, clientErr, _, serverErr := handshakeWithTLSConf(clientConf, serverConf, false)
+			Expect(clientErr).ToNot(HaveOccurred())
+			Expect(serverErr).ToNot(HaveOccurred())
+			Expect(versionChan).To(Receive(Equal(protocol.VersionTLS)))
+		})
+
 		It("handshakes with client auth", func() {
 			clientConf.Certificates = []tls.Certificate{generateCert()}
 			serverConf.ClientAuth = qtls.RequireAnyClientCert
-			_, clientErr, _, serverErr := handshakeWithTLSConf(clientConf, serverConf)
+			_, clientErr, _, serverErr := handshakeWithTLSConf(clientConf, serverConf, false)
 			Expect(clientErr).ToNot(HaveOccurred())
 			Expect(serverErr).ToNot(HaveOccurred())
 		})
@@ -397,8 +446,12 @@ var _ = Describe("Crypto Setup TLS", func() {
 				&TransportParameters{},
 				runner,
 				&tls.Config{InsecureSkipVerify: true},
+				false,
+				0,
+				0,
 				&congestion.RTTStats{},
 				utils.DefaultLogger.WithPrefix("client"),
+				protocol.VersionTLS,
 			)
 
 			done := make(chan struct{})
@@ -409,7 +462,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			}()
 			var ch chunk
 			Eventually(cChunkChan).Should(Receive(&ch))
-			Eventually(chChan).Should(BeClosed())
+			Eventually(chChan).Should(Receive(BeNil()))
 			// make sure the whole ClientHello was written
 			Expect(len(ch.data)).To(BeNumerically(">=", 4))
 			Expect(messageType(ch.data[0])).To(Equal(typeClientHello))
@@ -437,8 +490,12 @@ var _ = Describe("Crypto Setup TLS", func() {
 				cTransportParameters,
 				cRunner,
 				clientConf,
+				false,
+				0,
+				0,
 				&congestion.RTTStats{},
 				utils.DefaultLogger.WithPrefix("client"),
+				protocol.VersionTLS,
 			)
 
 			sChunkChan, sInitialStream, sHandshakeStream, _ := initStreams()
@@ -459,8 +516,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 				sTransportParameters,
 				sRunner,
 				serverConf,
+				false,
+				nil,
+				0,
+				0,
 				&congestion.RTTStats{},
 				utils.DefaultLogger.WithPrefix("server"),
+				protocol.VersionTLS,
 			)
 
 			done := make(chan struct{})
@@ -472,11 +534,11 @@ var _ = Describe("Crypto Setup TLS", func() {
 			Eventually(done).Should(BeClosed())
 			Expect(cTransportParametersRcvd).ToNot(BeNil())
 			clTP := &TransportParameters{}
-			Expect(clTP.Unmarshal(cTransportParametersRcvd, protocol.PerspectiveClient)).To(Succeed())
+			Expect(clTP.Unmarshal(cTransportParametersRcvd, protocol.PerspectiveClient, protocol.VersionTLS)).To(Succeed())
 			Expect(clTP.IdleTimeout).To(Equal(cTransportParameters.IdleTimeout))
 			Expect(sTransportParametersRcvd).ToNot(BeNil())
 			srvTP := &TransportParameters{}
-			Expect(srvTP.Unmarshal(sTransportParametersRcvd, protocol.PerspectiveServer)).To(Succeed())
+			Expect(srvTP.Unmarshal(sTransportParametersRcvd, protocol.PerspectiveServer, protocol.VersionTLS)).To(Succeed())
 			Expect(srvTP.IdleTimeout).To(Equal(sTransportParameters.IdleTimeout))
 		})
 
@@ -495,8 +557,12 @@ var _ = Describe("Crypto Setup TLS", func() {
 					&TransportParameters{},
 					cRunner,
 					clientConf,
+					false,
+					0,
+					0,
 					&congestion.RTTStats{},
 					utils.DefaultLogger.WithPrefix("client"),
+					protocol.VersionTLS,
 				)
 
 				sChunkChan, sInitialStream, sHandshakeStream, _ := initStreams()
@@ -512,8 +578,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 					&TransportParameters{},
 					sRunner,
 					serverConf,
+					false,
+					nil,
+					0,
+					0,
 					&congestion.RTTStats{},
 					utils.DefaultLogger.WithPrefix("server"),
+					protocol.VersionTLS,
 				)
 
 				done := make(chan struct{})
@@ -550,8 +621,12 @@ var _ = Describe("Crypto Setup TLS", func() {
 					&TransportParameters{},
 					cRunner,
 					clientConf,
+					false,
+					0,
+					0,
 					&congestion.RTTStats{},
 					utils.DefaultLogger.WithPrefix("client"),
+					protocol.VersionTLS,
 				)
 
 				sChunkChan, sInitialStream, sHandshakeStream, _ := initStreams()
@@ -567,8 +642,13 @@ var _ = Describe("Crypto Setup TLS", func() {
 					&TransportParameters{},
 					sRunner,
 					serverConf,
+					false,
+					nil,
+					0,
+					0,
 					&congestion.RTTStats{},
 					utils.DefaultLogger.WithPrefix("server"),
+					protocol.VersionTLS,
 				)
 
 				done := make(chan struct{})
@@ -599,7 +679,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 					close(receivedSessionTicket)
 				})
 				clientConf.ClientSessionCache = csc
-				client, clientErr, server, serverErr := handshakeWithTLSConf(clientConf, serverConf)
+				client, clientErr, server, serverErr := handshakeWithTLSConf(clientConf, serverConf, false)
 				Expect(clientErr).ToNot(HaveOccurred())
 				Expect(serverErr).ToNot(HaveOccurred())
 				Eventually(receivedSessionTicket).Should(BeClosed())
@@ -608,7 +688,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 
 				csc.EXPECT().Get(gomock.Any()).Return(state, true)
 				csc.EXPECT().Put(gomock.Any(), gomock.Any()).MaxTimes(1)
-				client, clientErr, server, serverErr = handshakeWithTLSConf(clientConf, serverConf)
+				client, clientErr, server, serverErr = handshakeWithTLSConf(clientConf, serverConf, false)
 				Expect(clientErr).ToNot(HaveOccurred())
 				Expect(serverErr).ToNot(HaveOccurred())
 				Eventually(receivedSessionTicket).Should(BeClosed())
@@ -626,7 +706,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 					close(receivedSessionTicket)
 				})
 				clientConf.ClientSessionCache = csc
-				client, clientErr, server, serverErr := handshakeWithTLSConf(clientConf, serverConf)
+				client, clientErr, server, serverErr := handshakeWithTLSConf(clientConf, serverConf, false)
 				Expect(clientErr).ToNot(HaveOccurred())
 				Expect(serverErr).ToNot(HaveOccurred())
 				Eventually(receivedSessionTicket).Should(BeClosed())
@@ -635,13 +715,171 @@ var _ = Describe("Crypto Setup TLS", func() {
 
 				serverConf.SessionTicketsDisabled = true
 				csc.EXPECT().Get(gomock.Any()).Return(state, true)
-				client, clientErr, server, serverErr = handshakeWithTLSConf(clientConf, serverConf)
+				client, clientErr, server, serverErr = handshakeWithTLSConf(clientConf, serverConf, false)
 				Expect(clientErr).ToNot(HaveOccurred())
 				Expect(serverErr).ToNot(HaveOccurred())
 				Eventually(receivedSessionTicket).Should(BeClosed())
 				Expect(server.ConnectionState().DidResume).To(BeFalse())
 				Expect(client.ConnectionState().DidResume).To(BeFalse())
 			})
+
+			Context("using 0-RTT", func() {
+				var state *tls.ClientSessionState
+
+				// getSessionTicket does a first handshake and returns the session state saved by the client.
+				getSessionTicket := func(csc *MockClientSessionCache) *tls.ClientSessionState {
+					var state *tls.ClientSessionState
+					receivedSessionTicket := make(chan struct{})
+					csc.EXPECT().Get(gomock.Any())
+					csc.EXPECT().Put(gomock.Any(), gomock.Any()).Do(func(_ string, css *tls.ClientSessionState) {
+						state = css
+						close(receivedSessionTicket)
+					})
+					clientConf.ClientSessionCache = csc
+					client, clientErr, server, serverErr := handshakeWithTLSConf(clientConf, serverConf, true)
+					Expect(clientErr).ToNot(HaveOccurred())
+					Expect(serverErr).ToNot(HaveOccurred())
+					Eventually(receivedSessionTicket).Should(BeClosed())
+					Expect(server.ConnectionState().DidResume).To(BeFalse())
+					Expect(client.ConnectionState().DidResume).To(BeFalse())
+					return state
+				}
+
+				// resume does a second handshake, using the session state from the first handshake
+				resume := func(
+					csc *MockClientSessionCache,
+					cRunner, sRunner *MockHandshakeRunner,
+					serverTP *TransportParameters,
+					acceptTicket func([]byte) bool,
+				) (CryptoSetup /* client */, <-chan *TransportParameters, CryptoSetup /* server */) {
+					csc.EXPECT().Get(gomock.Any()).Return(state, true)
+					csc.EXPECT().Put(gomock.Any(), gomock.Any()).AnyTimes()
+					clientConf.ClientSessionCache = csc
+
+					cChunkChan, cInitialStream, cHandshakeStream, cOneRTTStream := initStreams()
+					client, clientHelloChan := NewCryptoSetupClient(
+						cInitialStream,
+						cHandshakeStream,
+						cOneRTTStream,
+						protocol.ConnectionID{},
+						nil,
+						&TransportParameters{},
+						cRunner,
+						clientConf,
+						true,
+						0,
+						0,
+						&congestion.RTTStats{},
+						utils.DefaultLogger.WithPrefix("client"),
+						protocol.VersionTLS,
+					)
+
+					sChunkChan, sInitialStream, sHandshakeStream, sOneRTTStream := initStreams()
+					server := NewCryptoSetupServer(
+						sInitialStream,
+						sHandshakeStream,
+						sOneRTTStream,
+						protocol.ConnectionID{},
+						nil,
+						serverTP,
+						sRunner,
+						serverConf,
+						true,
+						acceptTicket,
+						0,
+						0,
+						&congestion.RTTStats{},
+						utils.DefaultLogger.WithPrefix("server"),
+						protocol.VersionTLS,
+					)
+
+					done := make(chan struct{})
+					go func() {
+						defer GinkgoRecover()
+						handshake(client, cChunkChan, server, sChunkChan)
+						close(done)
+					}()
+					Eventually(done).Should(BeClosed())
+					return client, clientHelloChan, server
+				}
+
+				BeforeEach(func() {
+					state = getSessionTicket(NewMockClientSessionCache(mockCtrl))
+				})
+
+				It("uses 0-RTT", func() {
+					cRunner := NewMockHandshakeRunner(mockCtrl)
+					cRunner.EXPECT().OnReceivedParams(gomock.Any())
+					cRunner.EXPECT().OnHandshakeComplete()
+					sRunner := NewMockHandshakeRunner(mockCtrl)
+					sRunner.EXPECT().OnReceivedParams(gomock.Any())
+					sRunner.EXPECT().OnHandshakeComplete()
+					var token [16]byte
+					var ticketID []byte
+					client, clientHelloChan, server := resume(
+						NewMockClientSessionCache(mockCtrl),
+						cRunner,
+						sRunner,
+						&TransportParameters{StatelessResetToken: &token},
+						func(id []byte) bool { ticketID = id; return true },
+					)
+					Expect(clientHelloChan).To(Receive(Not(BeNil())))
+					Expect(ticketID).To(HaveLen(sessionTicketIDLen))
+					Expect(server.ConnectionState().DidResume).To(BeTrue())
+					Expect(client.ConnectionState().DidResume).To(BeTrue())
+					Expect(server.Get0RTTOpener()).ToNot(BeNil())
+				})
+
+				It("rejects 0-RTT if the anti-replay hook rejects the session ticket", func() {
+					cRunner := NewMockHandshakeRunner(mockCtrl)
+					cRunner.EXPECT().OnReceivedParams(gomock.Any())
+					cRunner.EXPECT().OnHandshakeComplete()
+					cRunner.EXPECT().DropKeys(protocol.Encryption0RTT)
+					sRunner := NewMockHandshakeRunner(mockCtrl)
+					sRunner.EXPECT().OnReceivedParams(gomock.Any())
+					sRunner.EXPECT().OnHandshakeComplete()
+					var token [16]byte
+					client, clientHelloChan, server := resume(
+						NewMockClientSessionCache(mockCtrl),
+						cRunner,
+						sRunner,
+						&TransportParameters{StatelessResetToken: &token},
+						func([]byte) bool { return false },
+					)
+					// the client optimistically sends 0-RTT data
+					Expect(clientHelloChan).To(Receive(Not(BeNil())))
+					Expect(server.ConnectionState().DidResume).To(BeTrue())
+					Expect(client.ConnectionState().DidResume).To(BeTrue())
+					_, err := client.Get0RTTSealer()
+					Expect(err).To(MatchError(ErrKeysDropped))
+					_, err = server.Get0RTTOpener()
+					Expect(err).To(HaveOccurred())
+				})
+
+				It("rejects 0-RTT if the transport parameters changed", func() {
+					cRunner := NewMockHandshakeRunner(mockCtrl)
+					cRunner.EXPECT().OnReceivedParams(gomock.Any())
+					cRunner.EXPECT().OnHandshakeComplete()
+					cRunner.EXPECT().DropKeys(protocol.Encryption0RTT)
+					sRunner := NewMockHandshakeRunner(mockCtrl)
+					sRunner.EXPECT().OnReceivedParams(gomock.Any())
+					sRunner.EXPECT().OnHandshakeComplete()
+					var token [16]byte
+					client, clientHelloChan, _ := resume(
+						NewMockClientSessionCache(mockCtrl),
+						cRunner,
+						sRunner,
+						&TransportParameters{
+							StatelessResetToken:     &token,
+							ActiveConnectionIDLimit: 42, // this value was 0 in the first connection
+						},
+						nil,
+					)
+					Expect(clientHelloChan).To(Receive(Not(BeNil())))
+					_, err := client.Get0RTTSealer()
+					Expect(err).To(MatchError(ErrKeysDropped))
+				})
+			})
 		})
 	})
 })
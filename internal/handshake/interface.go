@@ -20,6 +20,9 @@ var (
 	ErrKeysDropped = errors.New("CryptoSetup: keys were already dropped")
 	// ErrDecryptionFailed is returned when the AEAD fails to open the packet.
 	ErrDecryptionFailed = errors.New("decryption failed")
+	// ErrIntegrityLimitReached is returned when more packets failed authentication than the AEAD's integrity limit allows.
+	// The connection must be closed.
+	ErrIntegrityLimitReached = errors.New("integrity limit of the AEAD reached")
 )
 
 type headerDecryptor interface {
@@ -73,13 +76,21 @@ type CryptoSetup interface {
 
 	HandleMessage([]byte, protocol.EncryptionLevel) bool
 	SetLargest1RTTAcked(protocol.PacketNumber)
+	SetHandshakeConfirmed()
+	InitiateKeyUpdate() error
 	ConnectionState() tls.ConnectionState
 
 	GetInitialOpener() (LongHeaderOpener, error)
 	GetHandshakeOpener() (LongHeaderOpener, error)
+	Get0RTTOpener() (LongHeaderOpener, error)
 	Get1RTTOpener() (ShortHeaderOpener, error)
 
 	GetInitialSealer() (LongHeaderSealer, error)
 	GetHandshakeSealer() (LongHeaderSealer, error)
+	Get0RTTSealer() (LongHeaderSealer, error)
 	Get1RTTSealer() (ShortHeaderSealer, error)
+
+	// Used by the multipath extension, for paths other than the initial path.
+	Get1RTTOpenerForPath(pathID uint64) (ShortHeaderOpener, error)
+	Get1RTTSealerForPath(pathID uint64) (ShortHeaderSealer, error)
 }
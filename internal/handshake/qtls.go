@@ -6,18 +6,32 @@ import (
 	"time"
 	"unsafe"
 
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/marten-seemann/qtls"
 )
 
+// ConnWithVersion is the net.Conn passed to the GetConfigForClient callback of the tls.Config,
+// as the Conn of the tls.ClientHelloInfo.
+// It allows servers to select the application protocol based on the QUIC version.
+type ConnWithVersion interface {
+	net.Conn
+	GetQUICVersion() protocol.VersionNumber
+}
+
 type conn struct {
 	remoteAddr net.Addr
+	version    protocol.VersionNumber
 }
 
-func newConn(remote net.Addr) net.Conn {
-	return &conn{remoteAddr: remote}
+func newConn(remote net.Addr, version protocol.VersionNumber) ConnWithVersion {
+	return &conn{
+		remoteAddr: remote,
+		version:    version,
+	}
 }
 
-var _ net.Conn = &conn{}
+var _ ConnWithVersion = &conn{}
 
 func (c *conn) Read([]byte) (int, error)         { return 0, nil }
 func (c *conn) Write([]byte) (int, error)        { return 0, nil }
@@ -28,48 +42,18 @@ func (c *conn) SetReadDeadline(time.Time) error  { return nil }
 func (c *conn) SetWriteDeadline(time.Time) error { return nil }
 func (c *conn) SetDeadline(time.Time) error      { return nil }
 
-type clientSessionCache struct {
-	tls.ClientSessionCache
-}
-
-var _ qtls.ClientSessionCache = &clientSessionCache{}
-
-func (c *clientSessionCache) Get(sessionKey string) (*qtls.ClientSessionState, bool) {
-	sess, ok := c.ClientSessionCache.Get(sessionKey)
-	if sess == nil {
-		return nil, ok
-	}
-	// qtls.ClientSessionState is identical to the tls.ClientSessionState.
-	// In order to allow users of quic-go to use a tls.Config,
-	// we need this workaround to use the ClientSessionCache.
-	// In unsafe.go we check that the two structs are actually identical.
-	usess := (*[unsafe.Sizeof(*sess)]byte)(unsafe.Pointer(sess))[:]
-	var session qtls.ClientSessionState
-	usession := (*[unsafe.Sizeof(session)]byte)(unsafe.Pointer(&session))[:]
-	copy(usession, usess)
-	return &session, ok
-}
-
-func (c *clientSessionCache) Put(sessionKey string, cs *qtls.ClientSessionState) {
-	if cs == nil {
-		c.ClientSessionCache.Put(sessionKey, nil)
-		return
-	}
-	// qtls.ClientSessionState is identical to the tls.ClientSessionState.
-	// In order to allow users of quic-go to use a tls.Config,
-	// we need this workaround to use the ClientSessionCache.
-	// In unsafe.go we check that the two structs are actually identical.
-	usess := (*[unsafe.Sizeof(*cs)]byte)(unsafe.Pointer(cs))[:]
-	var session tls.ClientSessionState
-	usession := (*[unsafe.Sizeof(session)]byte)(unsafe.Pointer(&session))[:]
-	copy(usession, usess)
-	c.ClientSessionCache.Put(sessionKey, &session)
-}
+func (c *conn) GetQUICVersion() protocol.VersionNumber { return c.version }
 
 func tlsConfigToQtlsConfig(
 	c *tls.Config,
 	recordLayer qtls.RecordLayer,
 	extHandler tlsExtensionHandler,
+	rttStats *congestion.RTTStats,
+	getDataForSessionState func() []byte,
+	setDataFromSessionState func([]byte),
+	accept0RTT func([]byte) bool,
+	rejected0RTT func(),
+	enable0RTT bool,
 ) *qtls.Config {
 	if c == nil {
 		c = &tls.Config{}
@@ -86,30 +70,45 @@ func tlsConfigToQtlsConfig(
 	if maxVersion < qtls.VersionTLS13 {
 		maxVersion = qtls.VersionTLS13
 	}
-	var getConfigForClient func(ch *tls.ClientHelloInfo) (*qtls.Config, error)
+	var getConfigForClient func(ch *qtls.ClientHelloInfo) (*qtls.Config, error)
 	if c.GetConfigForClient != nil {
-		getConfigForClient = func(ch *tls.ClientHelloInfo) (*qtls.Config, error) {
-			tlsConf, err := c.GetConfigForClient(ch)
+		getConfigForClient = func(ch *qtls.ClientHelloInfo) (*qtls.Config, error) {
+			tlsConf, err := c.GetConfigForClient(toTLSClientHelloInfo(ch))
 			if err != nil {
 				return nil, err
 			}
 			if tlsConf == nil {
 				return nil, nil
 			}
-			return tlsConfigToQtlsConfig(tlsConf, recordLayer, extHandler), nil
+			return tlsConfigToQtlsConfig(tlsConf, recordLayer, extHandler, rttStats, getDataForSessionState, setDataFromSessionState, accept0RTT, rejected0RTT, enable0RTT), nil
+		}
+	}
+	var getCertificate func(ch *qtls.ClientHelloInfo) (*qtls.Certificate, error)
+	if c.GetCertificate != nil {
+		getCertificate = func(ch *qtls.ClientHelloInfo) (*qtls.Certificate, error) {
+			cert, err := c.GetCertificate(toTLSClientHelloInfo(ch))
+			if err != nil {
+				return nil, err
+			}
+			if cert == nil {
+				return nil, nil
+			}
+			return (*qtls.Certificate)(unsafe.Pointer(cert)), nil
 		}
 	}
 	var csc qtls.ClientSessionCache
 	if c.ClientSessionCache != nil {
-		csc = &clientSessionCache{c.ClientSessionCache}
+		csc = newClientSessionCache(c.ClientSessionCache, rttStats, getDataForSessionState, setDataFromSessionState)
 	}
-	return &qtls.Config{
+	// qtls.Certificate and qtls.CertificateRequestInfo are identical to their crypto/tls counterparts.
+	// In unsafe.go we check that the structs are actually identical.
+	conf := &qtls.Config{
 		Rand:                        c.Rand,
 		Time:                        c.Time,
-		Certificates:                c.Certificates,
-		NameToCertificate:           c.NameToCertificate,
-		GetCertificate:              c.GetCertificate,
-		GetClientCertificate:        c.GetClientCertificate,
+		Certificates:                *(*[]qtls.Certificate)(unsafe.Pointer(&c.Certificates)),
+		NameToCertificate:           *(*map[string]*qtls.Certificate)(unsafe.Pointer(&c.NameToCertificate)),
+		GetCertificate:              getCertificate,
+		GetClientCertificate:        *(*func(*qtls.CertificateRequestInfo) (*qtls.Certificate, error))(unsafe.Pointer(&c.GetClientCertificate)),
 		GetConfigForClient:          getConfigForClient,
 		VerifyPeerCertificate:       c.VerifyPeerCertificate,
 		RootCAs:                     c.RootCAs,
@@ -133,18 +132,12 @@ func tlsConfigToQtlsConfig(
 		AlternativeRecordLayer: recordLayer,
 		GetExtensions:          extHandler.GetExtensions,
 		ReceivedExtensions:     extHandler.ReceivedExtensions,
+		Accept0RTT:             accept0RTT,
+		Rejected0RTT:           rejected0RTT,
 	}
-}
-
-func cipherSuiteName(id uint16) string {
-	switch id {
-	case qtls.TLS_AES_128_GCM_SHA256:
-		return "TLS_AES_128_GCM_SHA256"
-	case qtls.TLS_CHACHA20_POLY1305_SHA256:
-		return "TLS_CHACHA20_POLY1305_SHA256"
-	case qtls.TLS_AES_256_GCM_SHA384:
-		return "TLS_AES_256_GCM_SHA384"
-	default:
-		return "unknown cipher suite"
+	if enable0RTT {
+		conf.Enable0RTT = true
+		conf.MaxEarlyData = 0xffffffff
 	}
+	return conf
 }
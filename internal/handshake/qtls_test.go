@@ -3,8 +3,10 @@ package handshake
 import (
 	"crypto/tls"
 	"errors"
+	"time"
 
 	gomock "github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/marten-seemann/qtls"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -28,19 +30,19 @@ func (*mockExtensionHandler) TransportParameters() <-chan []byte { panic("not im
 var _ = Describe("qtls.Config generation", func() {
 	It("sets MinVersion and MaxVersion", func() {
 		tlsConf := &tls.Config{MinVersion: tls.VersionTLS11, MaxVersion: tls.VersionTLS12}
-		qtlsConf := tlsConfigToQtlsConfig(tlsConf, nil, &mockExtensionHandler{})
+		qtlsConf := tlsConfigToQtlsConfig(tlsConf, nil, &mockExtensionHandler{}, &congestion.RTTStats{}, nil, nil, nil, nil, false)
 		Expect(qtlsConf.MinVersion).To(BeEquivalentTo(tls.VersionTLS13))
 		Expect(qtlsConf.MaxVersion).To(BeEquivalentTo(tls.VersionTLS13))
 	})
 
 	It("works when called with a nil config", func() {
-		qtlsConf := tlsConfigToQtlsConfig(nil, nil, &mockExtensionHandler{})
+		qtlsConf := tlsConfigToQtlsConfig(nil, nil, &mockExtensionHandler{}, &congestion.RTTStats{}, nil, nil, nil, nil, false)
 		Expect(qtlsConf).ToNot(BeNil())
 	})
 
 	It("sets the setter and getter function for TLS extensions", func() {
 		extHandler := &mockExtensionHandler{}
-		qtlsConf := tlsConfigToQtlsConfig(&tls.Config{}, nil, extHandler)
+		qtlsConf := tlsConfigToQtlsConfig(&tls.Config{}, nil, extHandler, &congestion.RTTStats{}, nil, nil, nil, nil, false)
 		Expect(extHandler.get).To(BeFalse())
 		qtlsConf.GetExtensions(10)
 		Expect(extHandler.get).To(BeTrue())
@@ -51,15 +53,15 @@ var _ = Describe("qtls.Config generation", func() {
 
 	It("initializes such that the session ticket key remains constant", func() {
 		tlsConf := &tls.Config{}
-		qtlsConf1 := tlsConfigToQtlsConfig(tlsConf, nil, &mockExtensionHandler{})
-		qtlsConf2 := tlsConfigToQtlsConfig(tlsConf, nil, &mockExtensionHandler{})
+		qtlsConf1 := tlsConfigToQtlsConfig(tlsConf, nil, &mockExtensionHandler{}, &congestion.RTTStats{}, nil, nil, nil, nil, false)
+		qtlsConf2 := tlsConfigToQtlsConfig(tlsConf, nil, &mockExtensionHandler{}, &congestion.RTTStats{}, nil, nil, nil, nil, false)
 		Expect(qtlsConf1.SessionTicketKey).ToNot(BeZero()) // should now contain a random value
 		Expect(qtlsConf1.SessionTicketKey).To(Equal(qtlsConf2.SessionTicketKey))
 	})
 
 	Context("GetConfigForClient callback", func() {
 		It("doesn't set it if absent", func() {
-			qtlsConf := tlsConfigToQtlsConfig(&tls.Config{}, nil, &mockExtensionHandler{})
+			qtlsConf := tlsConfigToQtlsConfig(&tls.Config{}, nil, &mockExtensionHandler{}, &congestion.RTTStats{}, nil, nil, nil, nil, false)
 			Expect(qtlsConf.GetConfigForClient).To(BeNil())
 		})
 
@@ -70,7 +72,7 @@ var _ = Describe("qtls.Config generation", func() {
 				},
 			}
 			extHandler := &mockExtensionHandler{}
-			qtlsConf := tlsConfigToQtlsConfig(tlsConf, nil, extHandler)
+			qtlsConf := tlsConfigToQtlsConfig(tlsConf, nil, extHandler, &congestion.RTTStats{}, nil, nil, nil, nil, false)
 			Expect(qtlsConf.GetConfigForClient).ToNot(BeNil())
 			confForClient, err := qtlsConf.GetConfigForClient(nil)
 			Expect(err).ToNot(HaveOccurred())
@@ -90,7 +92,7 @@ var _ = Describe("qtls.Config generation", func() {
 					return nil, testErr
 				},
 			}
-			qtlsConf := tlsConfigToQtlsConfig(tlsConf, nil, &mockExtensionHandler{})
+			qtlsConf := tlsConfigToQtlsConfig(tlsConf, nil, &mockExtensionHandler{}, &congestion.RTTStats{}, nil, nil, nil, nil, false)
 			_, err := qtlsConf.GetConfigForClient(nil)
 			Expect(err).To(MatchError(testErr))
 		})
@@ -101,35 +103,85 @@ var _ = Describe("qtls.Config generation", func() {
 					return nil, nil
 				},
 			}
-			qtlsConf := tlsConfigToQtlsConfig(tlsConf, nil, &mockExtensionHandler{})
+			qtlsConf := tlsConfigToQtlsConfig(tlsConf, nil, &mockExtensionHandler{}, &congestion.RTTStats{}, nil, nil, nil, nil, false)
 			Expect(qtlsConf.GetConfigForClient(nil)).To(BeNil())
 		})
 	})
 
+	It("enables 0-RTT", func() {
+		qtlsConf := tlsConfigToQtlsConfig(&tls.Config{}, nil, &mockExtensionHandler{}, &congestion.RTTStats{}, nil, nil, nil, nil, false)
+		Expect(qtlsConf.Enable0RTT).To(BeFalse())
+		Expect(qtlsConf.MaxEarlyData).To(BeZero())
+		qtlsConf = tlsConfigToQtlsConfig(&tls.Config{}, nil, &mockExtensionHandler{}, &congestion.RTTStats{}, nil, nil, nil, nil, true)
+		Expect(qtlsConf.Enable0RTT).To(BeTrue())
+		Expect(qtlsConf.MaxEarlyData).To(Equal(uint32(0xffffffff)))
+	})
+
 	Context("ClientSessionCache", func() {
 		It("doesn't set if absent", func() {
-			qtlsConf := tlsConfigToQtlsConfig(&tls.Config{}, nil, &mockExtensionHandler{})
+			qtlsConf := tlsConfigToQtlsConfig(&tls.Config{}, nil, &mockExtensionHandler{}, &congestion.RTTStats{}, nil, nil, nil, nil, false)
 			Expect(qtlsConf.ClientSessionCache).To(BeNil())
 		})
 
 		It("sets it, and puts and gets session states", func() {
 			csc := NewMockClientSessionCache(mockCtrl)
 			tlsConf := &tls.Config{ClientSessionCache: csc}
-			qtlsConf := tlsConfigToQtlsConfig(tlsConf, nil, &mockExtensionHandler{})
+			var appData []byte
+			qtlsConf := tlsConfigToQtlsConfig(
+				tlsConf,
+				nil,
+				&mockExtensionHandler{},
+				&congestion.RTTStats{},
+				func() []byte { return []byte("foobar") },
+				func(p []byte) { appData = p },
+				nil,
+				nil,
+				false,
+			)
 			Expect(qtlsConf.ClientSessionCache).ToNot(BeNil())
 			// put something
-			csc.EXPECT().Put("foobar", gomock.Any())
-			qtlsConf.ClientSessionCache.Put("foobar", &qtls.ClientSessionState{})
+			var state *tls.ClientSessionState
+			csc.EXPECT().Put("localhost", gomock.Any()).Do(func(_ string, css *tls.ClientSessionState) { state = css })
+			qtlsConf.ClientSessionCache.Put("localhost", &qtls.ClientSessionState{})
+			Expect(state).ToNot(BeNil())
 			// get something
-			csc.EXPECT().Get("raboof").Return(nil, true)
-			_, ok := qtlsConf.ClientSessionCache.Get("raboof")
+			csc.EXPECT().Get("localhost").Return(state, true)
+			_, ok := qtlsConf.ClientSessionCache.Get("localhost")
 			Expect(ok).To(BeTrue())
+			Expect(appData).To(Equal([]byte("foobar")))
+		})
+
+		It("restores the RTT", func() {
+			csc := NewMockClientSessionCache(mockCtrl)
+			tlsConf := &tls.Config{ClientSessionCache: csc}
+			rttStats := &congestion.RTTStats{}
+			rttStats.UpdateRTT(123*time.Millisecond, 0, time.Now())
+			qtlsConf := tlsConfigToQtlsConfig(tlsConf, nil, &mockExtensionHandler{}, rttStats, func() []byte { return nil }, func([]byte) {}, nil, nil, false)
+			var state *tls.ClientSessionState
+			csc.EXPECT().Put("localhost", gomock.Any()).Do(func(_ string, css *tls.ClientSessionState) { state = css })
+			qtlsConf.ClientSessionCache.Put("localhost", &qtls.ClientSessionState{})
+			// use a new RTTStats for the new connection
+			rttStats2 := &congestion.RTTStats{}
+			qtlsConf2 := tlsConfigToQtlsConfig(tlsConf, nil, &mockExtensionHandler{}, rttStats2, func() []byte { return nil }, func([]byte) {}, nil, nil, false)
+			csc.EXPECT().Get("localhost").Return(state, true)
+			_, ok := qtlsConf2.ClientSessionCache.Get("localhost")
+			Expect(ok).To(BeTrue())
+			Expect(rttStats2.SmoothedRTT()).To(Equal(123 * time.Millisecond))
+		})
+
+		It("rejects session states that weren't saved by quic-go", func() {
+			csc := NewMockClientSessionCache(mockCtrl)
+			tlsConf := &tls.Config{ClientSessionCache: csc}
+			qtlsConf := tlsConfigToQtlsConfig(tlsConf, nil, &mockExtensionHandler{}, &congestion.RTTStats{}, func() []byte { return nil }, func([]byte) {}, nil, nil, false)
+			csc.EXPECT().Get("localhost").Return(&tls.ClientSessionState{}, true)
+			_, ok := qtlsConf.ClientSessionCache.Get("localhost")
+			Expect(ok).To(BeFalse())
 		})
 
 		It("puts a nil session state", func() {
 			csc := NewMockClientSessionCache(mockCtrl)
 			tlsConf := &tls.Config{ClientSessionCache: csc}
-			qtlsConf := tlsConfigToQtlsConfig(tlsConf, nil, &mockExtensionHandler{})
+			qtlsConf := tlsConfigToQtlsConfig(tlsConf, nil, &mockExtensionHandler{}, &congestion.RTTStats{}, nil, nil, nil, nil, false)
 			// put something
 			csc.EXPECT().Put("foobar", nil)
 			qtlsConf.ClientSessionCache.Put("foobar", nil)
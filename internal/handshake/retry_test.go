@@ -0,0 +1,39 @@
+package handshake
+
+import (
+	"encoding/hex"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Retry Integrity Check", func() {
+	decode := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		Expect(err).ToNot(HaveOccurred())
+		return b
+	}
+
+	origDestConnID := protocol.ConnectionID{0x83, 0x94, 0xc8, 0xf0, 0x3e, 0x51, 0x57, 0x08}
+
+	// values taken from the Appendix of the drafts and RFC 9001
+	It("calculates the integrity tag for draft-29", func() {
+		packet := decode("ffff00001d0008f067a5502a4262b5746f6b656ed16926d81f6f9ca2953a8aa4575e1e49")
+		tag := GetRetryIntegrityTag(packet[:len(packet)-16], origDestConnID, protocol.VersionDraft29)
+		Expect(tag[:]).To(Equal(packet[len(packet)-16:]))
+	})
+
+	It("calculates the integrity tag for QUIC v1", func() {
+		packet := decode("ff000000010008f067a5502a4262b5746f6b656e04a265ba2eff4d829058fb3f0f2496ba")
+		tag := GetRetryIntegrityTag(packet[:len(packet)-16], origDestConnID, protocol.Version1)
+		Expect(tag[:]).To(Equal(packet[len(packet)-16:]))
+	})
+
+	It("uses the original destination connection ID", func() {
+		packet := decode("ff000000010008f067a5502a4262b5746f6b656e04a265ba2eff4d829058fb3f0f2496ba")
+		tag := GetRetryIntegrityTag(packet[:len(packet)-16], protocol.ConnectionID{1, 2, 3, 4}, protocol.Version1)
+		Expect(tag[:]).ToNot(Equal(packet[len(packet)-16:]))
+	})
+})
@@ -0,0 +1,113 @@
+package handshake
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"time"
+	"unsafe"
+)
+
+// clientSessionState has the same memory layout as qtls.ClientSessionState and tls.ClientSessionState.
+// It is used to access the fields of a tls.ClientSessionState, which are not exported.
+// In unsafe.go we check that the structs are actually identical.
+type clientSessionState struct {
+	sessionTicket      []uint8
+	vers               uint16
+	cipherSuite        uint16
+	masterSecret       []byte
+	serverCertificates []*x509.Certificate
+	verifiedChains     [][]*x509.Certificate
+	receivedAt         time.Time
+	nonce              []byte
+	useBy              time.Time
+	ageAdd             uint32
+}
+
+// sessionState is the struct that is used for ASN1 serialization and deserialization
+type sessionState struct {
+	SessionTicket      []byte
+	Version            int
+	CipherSuite        int
+	MasterSecret       []byte
+	ServerCertificates [][]byte
+	VerifiedChains     [][][]byte
+	ReceivedAt         int64
+	Nonce              []byte
+	UseBy              int64
+	AgeAdd             int64
+}
+
+// MarshalClientSessionState serializes a tls.ClientSessionState.
+// It also returns the time when the session ticket expires.
+// The serialized session state contains the resumption secret, and must be stored securely.
+func MarshalClientSessionState(state *tls.ClientSessionState) ([]byte, time.Time, error) {
+	s := (*clientSessionState)(unsafe.Pointer(state))
+	ss := sessionState{
+		SessionTicket: s.sessionTicket,
+		Version:       int(s.vers),
+		CipherSuite:   int(s.cipherSuite),
+		MasterSecret:  s.masterSecret,
+		ReceivedAt:    s.receivedAt.UnixNano(),
+		Nonce:         s.nonce,
+		UseBy:         s.useBy.UnixNano(),
+		AgeAdd:        int64(s.ageAdd),
+	}
+	for _, cert := range s.serverCertificates {
+		ss.ServerCertificates = append(ss.ServerCertificates, cert.Raw)
+	}
+	for _, chain := range s.verifiedChains {
+		rawChain := make([][]byte, 0, len(chain))
+		for _, cert := range chain {
+			rawChain = append(rawChain, cert.Raw)
+		}
+		ss.VerifiedChains = append(ss.VerifiedChains, rawChain)
+	}
+	data, err := asn1.Marshal(ss)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return data, s.useBy, nil
+}
+
+// UnmarshalClientSessionState parses a tls.ClientSessionState that was serialized using MarshalClientSessionState.
+func UnmarshalClientSessionState(data []byte) (*tls.ClientSessionState, error) {
+	var ss sessionState
+	rest, err := asn1.Unmarshal(data, &ss)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("session state has trailing data")
+	}
+	s := &clientSessionState{
+		sessionTicket: ss.SessionTicket,
+		vers:          uint16(ss.Version),
+		cipherSuite:   uint16(ss.CipherSuite),
+		masterSecret:  ss.MasterSecret,
+		receivedAt:    time.Unix(0, ss.ReceivedAt),
+		nonce:         ss.Nonce,
+		useBy:         time.Unix(0, ss.UseBy),
+		ageAdd:        uint32(ss.AgeAdd),
+	}
+	for _, raw := range ss.ServerCertificates {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, err
+		}
+		s.serverCertificates = append(s.serverCertificates, cert)
+	}
+	for _, rawChain := range ss.VerifiedChains {
+		chain := make([]*x509.Certificate, 0, len(rawChain))
+		for _, raw := range rawChain {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return nil, err
+			}
+			chain = append(chain, cert)
+		}
+		s.verifiedChains = append(s.verifiedChains, chain)
+	}
+	return (*tls.ClientSessionState)(unsafe.Pointer(s)), nil
+}
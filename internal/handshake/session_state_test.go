@@ -0,0 +1,62 @@
+package handshake
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"time"
+	"unsafe"
+
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+	"github.com/marten-seemann/qtls"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Session State", func() {
+	It("has the same memory layout as the qtls.ClientSessionState", func() {
+		Expect(structsEqual(&clientSessionState{}, &qtls.ClientSessionState{})).To(BeTrue())
+	})
+
+	It("marshals and unmarshals session states", func() {
+		cert, err := x509.ParseCertificate(testdata.GetTLSConfig().Certificates[0].Certificate[0])
+		Expect(err).ToNot(HaveOccurred())
+		useBy := time.Now().Add(time.Hour)
+		s := &clientSessionState{
+			sessionTicket:      []byte("session ticket"),
+			vers:               tls.VersionTLS13,
+			cipherSuite:        tls.TLS_AES_128_GCM_SHA256,
+			masterSecret:       []byte("master secret"),
+			serverCertificates: []*x509.Certificate{cert},
+			verifiedChains:     [][]*x509.Certificate{{cert, cert}},
+			receivedAt:         time.Now(),
+			nonce:              []byte("nonce"),
+			useBy:              useBy,
+			ageAdd:             0xdeadbeef,
+		}
+		data, expiry, err := MarshalClientSessionState((*tls.ClientSessionState)(unsafe.Pointer(s)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(expiry).To(Equal(useBy))
+		state, err := UnmarshalClientSessionState(data)
+		Expect(err).ToNot(HaveOccurred())
+		restored := (*clientSessionState)(unsafe.Pointer(state))
+		Expect(restored.sessionTicket).To(Equal(s.sessionTicket))
+		Expect(restored.vers).To(Equal(s.vers))
+		Expect(restored.cipherSuite).To(Equal(s.cipherSuite))
+		Expect(restored.masterSecret).To(Equal(s.masterSecret))
+		Expect(restored.serverCertificates).To(HaveLen(1))
+		Expect(restored.serverCertificates[0].Equal(cert)).To(BeTrue())
+		Expect(restored.verifiedChains).To(HaveLen(1))
+		Expect(restored.verifiedChains[0]).To(HaveLen(2))
+		Expect(restored.verifiedChains[0][1].Equal(cert)).To(BeTrue())
+		Expect(restored.receivedAt.Equal(s.receivedAt)).To(BeTrue())
+		Expect(restored.nonce).To(Equal(s.nonce))
+		Expect(restored.useBy.Equal(useBy)).To(BeTrue())
+		Expect(restored.ageAdd).To(Equal(s.ageAdd))
+	})
+
+	It("errors on invalid data", func() {
+		_, err := UnmarshalClientSessionState([]byte("foobar"))
+		Expect(err).To(HaveOccurred())
+	})
+})
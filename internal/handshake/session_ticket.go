@@ -0,0 +1,74 @@
+package handshake
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+const sessionTicketRevision = 1
+
+// sessionTicketIDLen is the length of the ID of a session ticket
+const sessionTicketIDLen = 16
+
+// A sessionTicket is the application data that the server saves in 0-RTT enabled session tickets.
+type sessionTicket struct {
+	// The ID is a random value that is unique for every session ticket.
+	// It is used to detect replayed 0-RTT data.
+	ID         []byte
+	Version    protocol.VersionNumber
+	Parameters *TransportParameters
+	RTT        time.Duration // to be encoded in µs
+}
+
+func (t *sessionTicket) Marshal() []byte {
+	b := &bytes.Buffer{}
+	utils.WriteVarInt(b, sessionTicketRevision)
+	utils.WriteVarInt(b, uint64(len(t.ID)))
+	b.Write(t.ID)
+	utils.BigEndian.WriteUint32(b, uint32(t.Version))
+	utils.WriteVarInt(b, uint64(t.RTT/time.Microsecond))
+	t.Parameters.MarshalForSessionTicket(b)
+	return b.Bytes()
+}
+
+func (t *sessionTicket) Unmarshal(b []byte) error {
+	r := bytes.NewReader(b)
+	rev, err := utils.ReadVarInt(r)
+	if err != nil {
+		return errors.New("failed to read session ticket revision")
+	}
+	if rev != sessionTicketRevision {
+		return fmt.Errorf("unknown session ticket revision: %d", rev)
+	}
+	idLen, err := utils.ReadVarInt(r)
+	if err != nil || idLen > uint64(r.Len()) {
+		return errors.New("failed to read session ticket ID")
+	}
+	id := make([]byte, idLen)
+	if _, err := io.ReadFull(r, id); err != nil {
+		return errors.New("failed to read session ticket ID")
+	}
+	version, err := utils.BigEndian.ReadUint32(r)
+	if err != nil {
+		return errors.New("failed to read QUIC version")
+	}
+	rtt, err := utils.ReadVarInt(r)
+	if err != nil {
+		return errors.New("failed to read RTT")
+	}
+	var tp TransportParameters
+	if err := tp.UnmarshalFromSessionTicket(b[len(b)-r.Len():]); err != nil {
+		return fmt.Errorf("unmarshaling transport parameters from session ticket failed: %s", err.Error())
+	}
+	t.ID = id
+	t.Version = protocol.VersionNumber(version)
+	t.Parameters = &tp
+	t.RTT = time.Duration(rtt) * time.Microsecond
+	return nil
+}
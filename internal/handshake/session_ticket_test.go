@@ -0,0 +1,75 @@
+package handshake
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Session Ticket", func() {
+	It("marshals and unmarshals a session ticket", func() {
+		ticket := &sessionTicket{
+			ID:         []byte("foobar"),
+			Version:    protocol.VersionTLS,
+			Parameters: &TransportParameters{InitialMaxStreamDataBidiLocal: 1, InitialMaxStreamDataBidiRemote: 2},
+			RTT:        1337 * time.Microsecond,
+		}
+		var t sessionTicket
+		Expect(t.Unmarshal(ticket.Marshal())).To(Succeed())
+		Expect(t.ID).To(Equal([]byte("foobar")))
+		Expect(t.Version).To(Equal(protocol.VersionTLS))
+		Expect(t.Parameters.InitialMaxStreamDataBidiLocal).To(BeEquivalentTo(1))
+		Expect(t.Parameters.InitialMaxStreamDataBidiRemote).To(BeEquivalentTo(2))
+		Expect(t.RTT).To(Equal(1337 * time.Microsecond))
+	})
+
+	It("refuses to unmarshal if the ticket is too short for the revision", func() {
+		Expect((&sessionTicket{}).Unmarshal([]byte{})).To(MatchError("failed to read session ticket revision"))
+	})
+
+	It("refuses to unmarshal if the revision doesn't match", func() {
+		b := &bytes.Buffer{}
+		utils.WriteVarInt(b, 1337)
+		Expect((&sessionTicket{}).Unmarshal(b.Bytes())).To(MatchError("unknown session ticket revision: 1337"))
+	})
+
+	It("refuses to unmarshal if the ID can't be read", func() {
+		b := &bytes.Buffer{}
+		utils.WriteVarInt(b, sessionTicketRevision)
+		utils.WriteVarInt(b, 10)
+		b.Write([]byte("foo"))
+		Expect((&sessionTicket{}).Unmarshal(b.Bytes())).To(MatchError("failed to read session ticket ID"))
+	})
+
+	It("refuses to unmarshal if the version can't be read", func() {
+		b := &bytes.Buffer{}
+		utils.WriteVarInt(b, sessionTicketRevision)
+		utils.WriteVarInt(b, 0)
+		Expect((&sessionTicket{}).Unmarshal(b.Bytes())).To(MatchError("failed to read QUIC version"))
+	})
+
+	It("refuses to unmarshal if the RTT can't be read", func() {
+		b := &bytes.Buffer{}
+		utils.WriteVarInt(b, sessionTicketRevision)
+		utils.WriteVarInt(b, 0)
+		utils.BigEndian.WriteUint32(b, uint32(protocol.VersionTLS))
+		Expect((&sessionTicket{}).Unmarshal(b.Bytes())).To(MatchError("failed to read RTT"))
+	})
+
+	It("refuses to unmarshal if unmarshaling the transport parameters fails", func() {
+		b := &bytes.Buffer{}
+		utils.WriteVarInt(b, sessionTicketRevision)
+		utils.WriteVarInt(b, 0)
+		utils.BigEndian.WriteUint32(b, uint32(protocol.VersionTLS))
+		utils.WriteVarInt(b, 1337)
+		b.Write([]byte("foobar"))
+		err := (&sessionTicket{}).Unmarshal(b.Bytes())
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("unmarshaling transport parameters from session ticket failed"))
+	})
+})
@@ -18,10 +18,12 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 		handlerServer = newExtensionHandler(
 			[]byte("foobar"),
 			protocol.PerspectiveServer,
+			protocol.VersionTLS,
 		)
 		handlerClient = newExtensionHandler(
 			[]byte("raboof"),
 			protocol.PerspectiveClient,
+			protocol.VersionTLS,
 		)
 	})
 
@@ -36,7 +38,7 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 			It("adds TransportParameters to the EncryptedExtensions message", func() {
 				exts := handlerServer.GetExtensions(uint8(typeEncryptedExtensions))
 				Expect(exts).To(HaveLen(1))
-				Expect(exts[0].Type).To(BeEquivalentTo(quicTLSExtensionType))
+				Expect(exts[0].Type).To(BeEquivalentTo(quicTLSExtensionTypeOldDrafts))
 				Expect(exts[0].Data).To(Equal([]byte("foobar")))
 			})
 		})
@@ -105,7 +107,7 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 			It("adds TransportParameters to the ClientHello message", func() {
 				exts := handlerClient.GetExtensions(uint8(typeClientHello))
 				Expect(exts).To(HaveLen(1))
-				Expect(exts[0].Type).To(BeEquivalentTo(quicTLSExtensionType))
+				Expect(exts[0].Type).To(BeEquivalentTo(quicTLSExtensionTypeOldDrafts))
 				Expect(exts[0].Data).To(Equal([]byte("raboof")))
 			})
 		})
@@ -162,4 +164,32 @@ var _ = Describe("TLS Extension Handler, for the server", func() {
 			})
 		})
 	})
+
+	Context("for QUIC v1", func() {
+		BeforeEach(func() {
+			handlerServer = newExtensionHandler([]byte("foobar"), protocol.PerspectiveServer, protocol.Version1)
+			handlerClient = newExtensionHandler([]byte("raboof"), protocol.PerspectiveClient, protocol.Version1)
+		})
+
+		It("uses the code point from the RFC", func() {
+			exts := handlerClient.GetExtensions(uint8(typeClientHello))
+			Expect(exts).To(HaveLen(1))
+			Expect(exts[0].Type).To(BeEquivalentTo(quicTLSExtensionType))
+			exts = handlerServer.GetExtensions(uint8(typeEncryptedExtensions))
+			Expect(exts).To(HaveLen(1))
+			Expect(exts[0].Type).To(BeEquivalentTo(quicTLSExtensionType))
+		})
+
+		It("ignores the code point used by the drafts", func() {
+			go func() {
+				defer GinkgoRecover()
+				exts := []qtls.Extension{{Type: quicTLSExtensionTypeOldDrafts, Data: []byte("raboof")}}
+				handlerServer.ReceivedExtensions(uint8(typeClientHello), exts)
+			}()
+
+			var data []byte
+			Eventually(handlerServer.TransportParameters()).Should(Receive(&data))
+			Expect(data).To(BeEmpty())
+		})
+	})
 })
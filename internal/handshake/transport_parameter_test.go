@@ -3,6 +3,7 @@ package handshake
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"math"
 	"math/rand"
 	"net"
@@ -21,6 +22,15 @@ var _ = Describe("Transport Parameters", func() {
 		return append(data, tp...)
 	}
 
+	getRandomValue := func() uint64 {
+		maxVals := []int64{math.MaxUint8 / 4, math.MaxUint16 / 4, math.MaxUint32 / 4, math.MaxUint64 / 4}
+		return uint64(rand.Int63n(maxVals[int(rand.Int31n(4))]))
+	}
+
+	BeforeEach(func() {
+		rand.Seed(GinkgoRandomSeed())
+	})
+
 	It("has a string representation", func() {
 		p := &TransportParameters{
 			InitialMaxStreamDataBidiLocal:  0x1234,
@@ -57,12 +67,6 @@ var _ = Describe("Transport Parameters", func() {
 	})
 
 	It("marshals and unmarshals", func() {
-		rand.Seed(GinkgoRandomSeed())
-		getRandomValue := func() uint64 {
-			maxVals := []int64{math.MaxUint8 / 4, math.MaxUint16 / 4, math.MaxUint32 / 4, math.MaxUint64 / 4}
-			return uint64(rand.Int63n(maxVals[int(rand.Int31n(4))]))
-		}
-
 		var token [16]byte
 		rand.Read(token[:])
 		params := &TransportParameters{
@@ -80,10 +84,10 @@ var _ = Describe("Transport Parameters", func() {
 			MaxAckDelay:                    42 * time.Millisecond,
 			ActiveConnectionIDLimit:        getRandomValue(),
 		}
-		data := params.Marshal()
+		data := params.Marshal(protocol.VersionTLS)
 
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer, protocol.VersionTLS)).To(Succeed())
 		Expect(p.InitialMaxStreamDataBidiLocal).To(Equal(params.InitialMaxStreamDataBidiLocal))
 		Expect(p.InitialMaxStreamDataBidiRemote).To(Equal(params.InitialMaxStreamDataBidiRemote))
 		Expect(p.InitialMaxStreamDataUni).To(Equal(params.InitialMaxStreamDataUni))
@@ -100,14 +104,14 @@ var _ = Describe("Transport Parameters", func() {
 	})
 
 	It("errors if the transport parameters are too short to contain the length", func() {
-		Expect((&TransportParameters{}).Unmarshal([]byte{0}, protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: transport parameter data too short"))
+		Expect((&TransportParameters{}).Unmarshal([]byte{0}, protocol.PerspectiveClient, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: transport parameter data too short"))
 	})
 
 	It("errors if the transport parameters are too short to contain the length", func() {
 		data := make([]byte, 2)
 		binary.BigEndian.PutUint16(data, 42)
 		data = append(data, make([]byte, 41)...)
-		Expect((&TransportParameters{}).Unmarshal(data, protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: expected transport parameters to be 42 bytes long, have 41"))
+		Expect((&TransportParameters{}).Unmarshal(data, protocol.PerspectiveClient, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: expected transport parameters to be 42 bytes long, have 41"))
 	})
 
 	It("errors when the stateless_reset_token has the wrong length", func() {
@@ -116,7 +120,7 @@ var _ = Describe("Transport Parameters", func() {
 		utils.BigEndian.WriteUint16(b, 15)
 		b.Write(make([]byte, 15))
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: wrong length for stateless_reset_token: 15 (expected 16)"))
+		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: wrong length for stateless_reset_token: 15 (expected 16)"))
 	})
 
 	It("errors when the max_packet_size is too small", func() {
@@ -125,7 +129,7 @@ var _ = Describe("Transport Parameters", func() {
 		utils.BigEndian.WriteUint16(b, uint16(utils.VarIntLen(1199)))
 		utils.WriteVarInt(b, 1199)
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: invalid value for max_packet_size: 1199 (minimum 1200)"))
+		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: invalid value for max_packet_size: 1199 (minimum 1200)"))
 	})
 
 	It("errors when disable_migration has content", func() {
@@ -134,13 +138,34 @@ var _ = Describe("Transport Parameters", func() {
 		utils.BigEndian.WriteUint16(b, 6)
 		b.Write([]byte("foobar"))
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: wrong length for disable_migration: 6 (expected empty)"))
+		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: wrong length for disable_migration: 6 (expected empty)"))
+	})
+
+	It("marshals and unmarshals enable_multipath", func() {
+		data := (&TransportParameters{EnableMultipath: true}).Marshal(protocol.VersionTLS)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveClient, protocol.VersionTLS)).To(Succeed())
+		Expect(p.EnableMultipath).To(BeTrue())
+		Expect(p.String()).To(ContainSubstring("EnableMultipath: true"))
+		data = (&TransportParameters{}).Marshal(protocol.VersionTLS)
+		p = &TransportParameters{}
+		Expect(p.Unmarshal(data, protocol.PerspectiveClient, protocol.VersionTLS)).To(Succeed())
+		Expect(p.EnableMultipath).To(BeFalse())
+	})
+
+	It("errors when enable_multipath has content", func() {
+		b := &bytes.Buffer{}
+		utils.BigEndian.WriteUint16(b, uint16(enableMultipathParameterID))
+		utils.BigEndian.WriteUint16(b, 6)
+		b.Write([]byte("foobar"))
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: wrong length for enable_multipath: 6 (expected empty)"))
 	})
 
 	It("errors when the max_ack_delay is too large", func() {
-		data := (&TransportParameters{MaxAckDelay: 1 << 14 * time.Millisecond}).Marshal()
+		data := (&TransportParameters{MaxAckDelay: 1 << 14 * time.Millisecond}).Marshal(protocol.VersionTLS)
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: invalid value for max_ack_delay: 16384ms (maximum 16383ms)"))
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: invalid value for max_ack_delay: 16384ms (maximum 16383ms)"))
 	})
 
 	It("doesn't send the max_ack_delay, if it has the default value", func() {
@@ -148,18 +173,18 @@ var _ = Describe("Transport Parameters", func() {
 		var defaultLen, dataLen int
 		// marshal 1000 times to average out the greasing transport parameter
 		for i := 0; i < num; i++ {
-			dataDefault := (&TransportParameters{MaxAckDelay: protocol.DefaultMaxAckDelay}).Marshal()
+			dataDefault := (&TransportParameters{MaxAckDelay: protocol.DefaultMaxAckDelay}).Marshal(protocol.VersionTLS)
 			defaultLen += len(dataDefault)
-			data := (&TransportParameters{MaxAckDelay: protocol.DefaultMaxAckDelay + time.Millisecond}).Marshal()
+			data := (&TransportParameters{MaxAckDelay: protocol.DefaultMaxAckDelay + time.Millisecond}).Marshal(protocol.VersionTLS)
 			dataLen += len(data)
 		}
 		Expect(float32(dataLen) / num).To(BeNumerically("~", float32(defaultLen)/num+2 /* parameter ID */ +2 /* length field */ +1 /* value */, 1))
 	})
 
 	It("errors when the ack_delay_exponenent is too large", func() {
-		data := (&TransportParameters{AckDelayExponent: 21}).Marshal()
+		data := (&TransportParameters{AckDelayExponent: 21}).Marshal(protocol.VersionTLS)
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: invalid value for ack_delay_exponent: 21 (maximum 20)"))
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: invalid value for ack_delay_exponent: 21 (maximum 20)"))
 	})
 
 	It("doesn't send the ack_delay_exponent, if it has the default value", func() {
@@ -167,18 +192,18 @@ var _ = Describe("Transport Parameters", func() {
 		var defaultLen, dataLen int
 		// marshal 1000 times to average out the greasing transport parameter
 		for i := 0; i < num; i++ {
-			dataDefault := (&TransportParameters{AckDelayExponent: protocol.DefaultAckDelayExponent}).Marshal()
+			dataDefault := (&TransportParameters{AckDelayExponent: protocol.DefaultAckDelayExponent}).Marshal(protocol.VersionTLS)
 			defaultLen += len(dataDefault)
-			data := (&TransportParameters{AckDelayExponent: protocol.DefaultAckDelayExponent + 1}).Marshal()
+			data := (&TransportParameters{AckDelayExponent: protocol.DefaultAckDelayExponent + 1}).Marshal(protocol.VersionTLS)
 			dataLen += len(data)
 		}
 		Expect(float32(dataLen) / num).To(BeNumerically("~", float32(defaultLen)/num+2 /* parameter ID */ +2 /* length field */ +1 /* value */, 1))
 	})
 
 	It("sets the default value for the ack_delay_exponent, when no value was sent", func() {
-		data := (&TransportParameters{AckDelayExponent: protocol.DefaultAckDelayExponent}).Marshal()
+		data := (&TransportParameters{AckDelayExponent: protocol.DefaultAckDelayExponent}).Marshal(protocol.VersionTLS)
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.Unmarshal(data, protocol.PerspectiveServer, protocol.VersionTLS)).To(Succeed())
 		Expect(p.AckDelayExponent).To(BeEquivalentTo(protocol.DefaultAckDelayExponent))
 	})
 
@@ -190,7 +215,7 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(utils.VarIntLen(val)).ToNot(BeEquivalentTo(2))
 		utils.WriteVarInt(b, val)
 		p := &TransportParameters{}
-		err := p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)
+		err := p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("TRANSPORT_PARAMETER_ERROR: inconsistent transport parameter length"))
 	})
@@ -202,7 +227,7 @@ var _ = Describe("Transport Parameters", func() {
 		utils.BigEndian.WriteUint16(b, uint16(utils.VarIntLen(val)))
 		utils.WriteVarInt(b, val)
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)).To(Succeed())
 		Expect(p.MaxAckDelay).To(BeNumerically(">", 290*365*24*time.Hour))
 	})
 
@@ -221,7 +246,7 @@ var _ = Describe("Transport Parameters", func() {
 		utils.BigEndian.WriteUint16(b, uint16(utils.VarIntLen(0x42)))
 		utils.WriteVarInt(b, 0x42)
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)).To(Succeed())
+		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)).To(Succeed())
 		Expect(p.InitialMaxStreamDataBidiLocal).To(Equal(protocol.ByteCount(0x1337)))
 		Expect(p.InitialMaxStreamDataBidiRemote).To(Equal(protocol.ByteCount(0x42)))
 	})
@@ -241,7 +266,7 @@ var _ = Describe("Transport Parameters", func() {
 		utils.BigEndian.WriteUint16(b, uint16(utils.VarIntLen(0x1337)))
 		utils.WriteVarInt(b, 0x1337)
 		p := &TransportParameters{}
-		err := p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)
+		err := p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("received duplicate transport parameter"))
 	})
@@ -252,7 +277,7 @@ var _ = Describe("Transport Parameters", func() {
 		utils.BigEndian.WriteUint16(b, 7)
 		b.Write([]byte("foobar"))
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: remaining length (6) smaller than parameter length (7)"))
+		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: remaining length (6) smaller than parameter length (7)"))
 	})
 
 	It("errors if there's unprocessed data after reading", func() {
@@ -262,22 +287,22 @@ var _ = Describe("Transport Parameters", func() {
 		utils.WriteVarInt(b, 0x1337)
 		b.Write([]byte("foo"))
 		p := &TransportParameters{}
-		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer)).To(MatchError("TRANSPORT_PARAMETER_ERROR: should have read all data. Still have 3 bytes"))
+		Expect(p.Unmarshal(prependLength(b.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: should have read all data. Still have 3 bytes"))
 	})
 
 	It("errors if the client sent a stateless_reset_token", func() {
 		var token [16]byte
 		params := &TransportParameters{StatelessResetToken: &token}
-		data := params.Marshal()
-		Expect((&TransportParameters{}).Unmarshal(data, protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: client sent a stateless_reset_token"))
+		data := params.Marshal(protocol.VersionTLS)
+		Expect((&TransportParameters{}).Unmarshal(data, protocol.PerspectiveClient, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: client sent a stateless_reset_token"))
 	})
 
 	It("errors if the client sent a stateless_reset_token", func() {
 		params := &TransportParameters{
 			OriginalConnectionID: protocol.ConnectionID{0xca, 0xfe},
 		}
-		data := params.Marshal()
-		Expect((&TransportParameters{}).Unmarshal(data, protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: client sent an original_connection_id"))
+		data := params.Marshal(protocol.VersionTLS)
+		Expect((&TransportParameters{}).Unmarshal(data, protocol.PerspectiveClient, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: client sent an original_connection_id"))
 	})
 
 	Context("preferred address", func() {
@@ -291,9 +316,9 @@ var _ = Describe("Transport Parameters", func() {
 		}
 
 		It("marshals and unmarshals", func() {
-			data := (&TransportParameters{PreferredAddress: pa}).Marshal()
+			data := (&TransportParameters{PreferredAddress: pa}).Marshal(protocol.VersionTLS)
 			p := &TransportParameters{}
-			Expect(p.Unmarshal(data, protocol.PerspectiveServer)).To(Succeed())
+			Expect(p.Unmarshal(data, protocol.PerspectiveServer, protocol.VersionTLS)).To(Succeed())
 			Expect(p.PreferredAddress.IPv4.String()).To(Equal(pa.IPv4.String()))
 			Expect(p.PreferredAddress.IPv4Port).To(Equal(pa.IPv4Port))
 			Expect(p.PreferredAddress.IPv6.String()).To(Equal(pa.IPv6.String()))
@@ -303,9 +328,16 @@ var _ = Describe("Transport Parameters", func() {
 		})
 
 		It("errors if the client sent a preferred_address", func() {
-			data := (&TransportParameters{PreferredAddress: pa}).Marshal()
+			data := (&TransportParameters{PreferredAddress: pa}).Marshal(protocol.VersionTLS)
 			p := &TransportParameters{}
-			Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(MatchError("TRANSPORT_PARAMETER_ERROR: client sent a preferred_address"))
+			Expect(p.Unmarshal(data, protocol.PerspectiveClient, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: client sent a preferred_address"))
+		})
+
+		It("errors if the connection ID is empty", func() {
+			p := *pa
+			p.ConnectionID = protocol.ConnectionID{}
+			data := (&TransportParameters{PreferredAddress: &p}).Marshal(protocol.VersionTLS)
+			Expect((&TransportParameters{}).Unmarshal(data, protocol.PerspectiveServer, protocol.VersionTLS)).To(MatchError("TRANSPORT_PARAMETER_ERROR: preferred_address with a zero-length connection ID"))
 		})
 
 		It("errors on EOF", func() {
@@ -323,8 +355,224 @@ var _ = Describe("Transport Parameters", func() {
 				utils.BigEndian.WriteUint16(buf, uint16(preferredAddressParamaterID))
 				buf.Write(prependLength(raw[:i]))
 				p := &TransportParameters{}
-				Expect(p.Unmarshal(prependLength(buf.Bytes()), protocol.PerspectiveServer)).ToNot(Succeed())
+				Expect(p.Unmarshal(prependLength(buf.Bytes()), protocol.PerspectiveServer, protocol.VersionTLS)).ToNot(Succeed())
+			}
+		})
+	})
+
+	Context("saving and retrieving from a session ticket", func() {
+		It("saves and retrieves the parameters", func() {
+			params := &TransportParameters{
+				InitialMaxStreamDataBidiLocal:  protocol.ByteCount(getRandomValue()),
+				InitialMaxStreamDataBidiRemote: protocol.ByteCount(getRandomValue()),
+				InitialMaxStreamDataUni:        protocol.ByteCount(getRandomValue()),
+				InitialMaxData:                 protocol.ByteCount(getRandomValue()),
+				MaxBidiStreamNum:               protocol.StreamNum(getRandomValue() % uint64(protocol.MaxStreamCount)),
+				MaxUniStreamNum:                protocol.StreamNum(getRandomValue() % uint64(protocol.MaxStreamCount)),
+				ActiveConnectionIDLimit:        getRandomValue(),
+			}
+			b := &bytes.Buffer{}
+			params.MarshalForSessionTicket(b)
+			var tp TransportParameters
+			Expect(tp.UnmarshalFromSessionTicket(b.Bytes())).To(Succeed())
+			Expect(tp.InitialMaxStreamDataBidiLocal).To(Equal(params.InitialMaxStreamDataBidiLocal))
+			Expect(tp.InitialMaxStreamDataBidiRemote).To(Equal(params.InitialMaxStreamDataBidiRemote))
+			Expect(tp.InitialMaxStreamDataUni).To(Equal(params.InitialMaxStreamDataUni))
+			Expect(tp.InitialMaxData).To(Equal(params.InitialMaxData))
+			Expect(tp.MaxBidiStreamNum).To(Equal(params.MaxBidiStreamNum))
+			Expect(tp.MaxUniStreamNum).To(Equal(params.MaxUniStreamNum))
+			Expect(tp.ActiveConnectionIDLimit).To(Equal(params.ActiveConnectionIDLimit))
+		})
+
+		It("rejects the parameters if it can't parse them", func() {
+			var p TransportParameters
+			Expect(p.UnmarshalFromSessionTicket([]byte("foobar"))).ToNot(Succeed())
+		})
+
+		It("rejects the parameters if the version changed", func() {
+			var p TransportParameters
+			b := &bytes.Buffer{}
+			p.MarshalForSessionTicket(b)
+			data := b.Bytes()
+			b = &bytes.Buffer{}
+			utils.WriteVarInt(b, transportParameterMarshalingVersion+1)
+			b.Write(data[utils.VarIntLen(transportParameterMarshalingVersion):])
+			Expect(p.UnmarshalFromSessionTicket(b.Bytes())).To(MatchError(fmt.Sprintf("unknown transport parameter marshaling version: %d", transportParameterMarshalingVersion+1)))
+		})
+
+		It("rejects the parameters if there's unprocessed data", func() {
+			var p TransportParameters
+			b := &bytes.Buffer{}
+			p.MarshalForSessionTicket(b)
+			b.Write([]byte("foo"))
+			Expect(p.UnmarshalFromSessionTicket(b.Bytes())).To(MatchError("should have read all data. Still have 3 bytes"))
+		})
+
+		Context("rejects the parameters if they changed", func() {
+			var p TransportParameters
+
+			BeforeEach(func() {
+				p = TransportParameters{
+					InitialMaxStreamDataBidiLocal:  1,
+					InitialMaxStreamDataBidiRemote: 2,
+					InitialMaxStreamDataUni:        3,
+					InitialMaxData:                 4,
+					MaxBidiStreamNum:               5,
+					MaxUniStreamNum:                6,
+					ActiveConnectionIDLimit:        7,
+				}
+			})
+
+			It("accepts the same parameters", func() {
+				Expect(p.ValidFor0RTT(&p)).To(BeTrue())
+			})
+
+			It("accepts increased limits", func() {
+				tp := p
+				tp.InitialMaxStreamDataBidiLocal++
+				tp.InitialMaxStreamDataBidiRemote++
+				tp.InitialMaxStreamDataUni++
+				tp.InitialMaxData++
+				tp.MaxBidiStreamNum++
+				tp.MaxUniStreamNum++
+				Expect(tp.ValidFor0RTT(&p)).To(BeTrue())
+			})
+
+			It("rejects the parameters if the InitialMaxStreamDataBidiLocal was reduced", func() {
+				tp := p
+				tp.InitialMaxStreamDataBidiLocal--
+				Expect(tp.ValidFor0RTT(&p)).To(BeFalse())
+			})
+
+			It("rejects the parameters if the InitialMaxStreamDataBidiRemote was reduced", func() {
+				tp := p
+				tp.InitialMaxStreamDataBidiRemote--
+				Expect(tp.ValidFor0RTT(&p)).To(BeFalse())
+			})
+
+			It("rejects the parameters if the InitialMaxStreamDataUni was reduced", func() {
+				tp := p
+				tp.InitialMaxStreamDataUni--
+				Expect(tp.ValidFor0RTT(&p)).To(BeFalse())
+			})
+
+			It("rejects the parameters if the InitialMaxData was reduced", func() {
+				tp := p
+				tp.InitialMaxData--
+				Expect(tp.ValidFor0RTT(&p)).To(BeFalse())
+			})
+
+			It("rejects the parameters if the MaxBidiStreamNum was reduced", func() {
+				tp := p
+				tp.MaxBidiStreamNum--
+				Expect(tp.ValidFor0RTT(&p)).To(BeFalse())
+			})
+
+			It("rejects the parameters if the MaxUniStreamNum was reduced", func() {
+				tp := p
+				tp.MaxUniStreamNum--
+				Expect(tp.ValidFor0RTT(&p)).To(BeFalse())
+			})
+
+			It("rejects the parameters if the ActiveConnectionIDLimit changed", func() {
+				tp := p
+				tp.ActiveConnectionIDLimit++
+				Expect(tp.ValidFor0RTT(&p)).To(BeFalse())
+			})
+		})
+	})
+
+	Context("for QUIC v1", func() {
+		It("marshals and unmarshals", func() {
+			retrySrcConnID := protocol.ConnectionID{0xca, 0xfe}
+			params := &TransportParameters{
+				InitialMaxStreamDataBidiLocal: 0x1337,
+				InitialMaxData:                0x42,
+				IdleTimeout:                   0xcafe * time.Second,
+				MaxAckDelay:                   42 * time.Millisecond,
+				AckDelayExponent:              13,
+				DisableMigration:              true,
+				StatelessResetToken:           &[16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+				OriginalConnectionID:          protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef},
+				InitialSourceConnectionID:     protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad},
+				RetrySourceConnectionID:       &retrySrcConnID,
+				ActiveConnectionIDLimit:       4,
 			}
+			data := params.Marshal(protocol.Version1)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveServer, protocol.Version1)).To(Succeed())
+			Expect(p.InitialMaxStreamDataBidiLocal).To(Equal(params.InitialMaxStreamDataBidiLocal))
+			Expect(p.InitialMaxData).To(Equal(params.InitialMaxData))
+			Expect(p.IdleTimeout).To(Equal(params.IdleTimeout))
+			Expect(p.MaxAckDelay).To(Equal(params.MaxAckDelay))
+			Expect(p.AckDelayExponent).To(Equal(params.AckDelayExponent))
+			Expect(p.DisableMigration).To(BeTrue())
+			Expect(p.StatelessResetToken).To(Equal(params.StatelessResetToken))
+			Expect(p.OriginalConnectionID).To(Equal(params.OriginalConnectionID))
+			Expect(p.InitialSourceConnectionID).To(Equal(params.InitialSourceConnectionID))
+			Expect(p.RetrySourceConnectionID).To(Equal(&retrySrcConnID))
+			Expect(p.ActiveConnectionIDLimit).To(BeEquivalentTo(4))
+		})
+
+		It("doesn't prefix the transport parameters with their length", func() {
+			params := &TransportParameters{InitialSourceConnectionID: protocol.ConnectionID{1, 2, 3, 4}}
+			Expect((&TransportParameters{}).Unmarshal(params.Marshal(protocol.VersionTLS), protocol.PerspectiveClient, protocol.Version1)).ToNot(Succeed())
+			Expect((&TransportParameters{}).Unmarshal(params.Marshal(protocol.Version1), protocol.PerspectiveClient, protocol.Version1)).To(Succeed())
+		})
+
+		It("uses varint parameter IDs and lengths", func() {
+			b := &bytes.Buffer{}
+			utils.WriteVarInt(b, uint64(initialMaxDataParameterID))
+			utils.WriteVarInt(b, uint64(utils.VarIntLen(0x1337)))
+			utils.WriteVarInt(b, 0x1337)
+			// write an unknown parameter with a large ID
+			utils.WriteVarInt(b, 0x1337000)
+			utils.WriteVarInt(b, 6)
+			b.Write([]byte("foobar"))
+			utils.WriteVarInt(b, uint64(initialSourceConnectionIDParameterID))
+			utils.WriteVarInt(b, 0)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(b.Bytes(), protocol.PerspectiveClient, protocol.Version1)).To(Succeed())
+			Expect(p.InitialMaxData).To(Equal(protocol.ByteCount(0x1337)))
+			Expect(p.InitialSourceConnectionID).To(BeEmpty())
+		})
+
+		It("sends a zero-length initial_source_connection_id", func() {
+			data := (&TransportParameters{}).Marshal(protocol.Version1)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveClient, protocol.Version1)).To(Succeed())
+			Expect(p.InitialSourceConnectionID).To(BeEmpty())
+		})
+
+		It("errors if the initial_source_connection_id is missing", func() {
+			b := &bytes.Buffer{}
+			utils.WriteVarInt(b, uint64(initialMaxDataParameterID))
+			utils.WriteVarInt(b, uint64(utils.VarIntLen(0x1337)))
+			utils.WriteVarInt(b, 0x1337)
+			Expect((&TransportParameters{}).Unmarshal(b.Bytes(), protocol.PerspectiveClient, protocol.Version1)).To(MatchError("TRANSPORT_PARAMETER_ERROR: missing initial_source_connection_id"))
+		})
+
+		It("errors if the server didn't send the original_destination_connection_id", func() {
+			data := (&TransportParameters{InitialSourceConnectionID: protocol.ConnectionID{1, 2, 3, 4}}).Marshal(protocol.Version1)
+			Expect((&TransportParameters{}).Unmarshal(data, protocol.PerspectiveServer, protocol.Version1)).To(MatchError("TRANSPORT_PARAMETER_ERROR: missing original_destination_connection_id"))
+		})
+
+		It("errors if the client sent a retry_source_connection_id", func() {
+			connID := protocol.ConnectionID{1, 2, 3, 4}
+			data := (&TransportParameters{RetrySourceConnectionID: &connID}).Marshal(protocol.Version1)
+			Expect((&TransportParameters{}).Unmarshal(data, protocol.PerspectiveClient, protocol.Version1)).To(MatchError("TRANSPORT_PARAMETER_ERROR: client sent a retry_source_connection_id"))
+		})
+
+		It("doesn't send the connection ID parameters for older drafts", func() {
+			connID := protocol.ConnectionID{1, 2, 3, 4}
+			data := (&TransportParameters{
+				InitialSourceConnectionID: connID,
+				RetrySourceConnectionID:   &connID,
+			}).Marshal(protocol.VersionTLS)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveServer, protocol.VersionTLS)).To(Succeed())
+			Expect(p.InitialSourceConnectionID).To(BeNil())
+			Expect(p.RetrySourceConnectionID).To(BeNil())
 		})
 	})
 })
@@ -21,7 +21,10 @@ func init() {
 	rand.Seed(time.Now().UTC().UnixNano())
 }
 
-type transportParameterID uint16
+// transportParameterMarshalingVersion is the version of the format used to save transport parameters in session tickets
+const transportParameterMarshalingVersion = 1
+
+type transportParameterID uint64
 
 const (
 	originalConnectionIDParameterID           transportParameterID = 0x0
@@ -39,6 +42,11 @@ const (
 	disableMigrationParameterID               transportParameterID = 0xc
 	preferredAddressParamaterID               transportParameterID = 0xd
 	activeConnectionIDLimitParameterID        transportParameterID = 0xe
+	// only used for draft-28 and later
+	initialSourceConnectionIDParameterID transportParameterID = 0xf
+	retrySourceConnectionIDParameterID   transportParameterID = 0x10
+	// experimental multipath extension
+	enableMultipathParameterID transportParameterID = 0xbabf
 )
 
 // PreferredAddress is the value encoding in the preferred_address transport parameter
@@ -75,23 +83,38 @@ type TransportParameters struct {
 	StatelessResetToken     *[16]byte
 	OriginalConnectionID    protocol.ConnectionID
 	ActiveConnectionIDLimit uint64
+
+	// The connection IDs used during the handshake.
+	// They are only sent for draft-28 and later.
+	InitialSourceConnectionID protocol.ConnectionID
+	RetrySourceConnectionID   *protocol.ConnectionID
+
+	// EnableMultipath is set if the endpoint supports the (experimental) multipath extension.
+	EnableMultipath bool
 }
 
 // Unmarshal the transport parameters
-func (p *TransportParameters) Unmarshal(data []byte, sentBy protocol.Perspective) error {
-	if err := p.unmarshal(data, sentBy); err != nil {
+func (p *TransportParameters) Unmarshal(data []byte, sentBy protocol.Perspective, v protocol.VersionNumber) error {
+	if err := p.unmarshal(data, sentBy, v); err != nil {
 		return qerr.Error(qerr.TransportParameterError, err.Error())
 	}
 	return nil
 }
 
-func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective) error {
-	if len(data) < 2 {
-		return errors.New("transport parameter data too short")
-	}
-	length := binary.BigEndian.Uint16(data[:2])
-	if len(data)-2 < int(length) {
-		return fmt.Errorf("expected transport parameters to be %d bytes long, have %d", length, len(data)-2)
+func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective, v protocol.VersionNumber) error {
+	// Before draft-27, the transport parameters were prefixed with their total length,
+	// and parameter IDs and lengths were encoded as uint16s.
+	minParamHeaderLen := 2
+	if !v.UsesVarIntTransportParameters() {
+		if len(data) < 2 {
+			return errors.New("transport parameter data too short")
+		}
+		length := binary.BigEndian.Uint16(data[:2])
+		if len(data)-2 < int(length) {
+			return fmt.Errorf("expected transport parameters to be %d bytes long, have %d", length, len(data)-2)
+		}
+		data = data[2:]
+		minParamHeaderLen = 4
 	}
 
 	// needed to check that every parameter is only sent at most once
@@ -99,12 +122,15 @@ func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective
 
 	var readAckDelayExponent bool
 	var readMaxAckDelay bool
-
-	r := bytes.NewReader(data[2:])
-	for r.Len() >= 4 {
-		paramIDInt, _ := utils.BigEndian.ReadUint16(r)
-		paramID := transportParameterID(paramIDInt)
-		paramLen, _ := utils.BigEndian.ReadUint16(r)
+	var readOriginalConnectionID bool
+	var readInitialSourceConnectionID bool
+
+	r := bytes.NewReader(data)
+	for r.Len() >= minParamHeaderLen {
+		paramID, paramLen, err := readParameterHeader(r, v)
+		if err != nil {
+			return err
+		}
 		parameterIDs = append(parameterIDs, paramID)
 		switch paramID {
 		case ackDelayExponentParameterID:
@@ -130,7 +156,7 @@ func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective
 				return err
 			}
 		default:
-			if r.Len() < int(paramLen) {
+			if uint64(r.Len()) < paramLen {
 				return fmt.Errorf("remaining length (%d) smaller than parameter length (%d)", r.Len(), paramLen)
 			}
 			switch paramID {
@@ -146,6 +172,11 @@ func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective
 					return fmt.Errorf("wrong length for disable_migration: %d (expected empty)", paramLen)
 				}
 				p.DisableMigration = true
+			case enableMultipathParameterID:
+				if paramLen != 0 {
+					return fmt.Errorf("wrong length for enable_multipath: %d (expected empty)", paramLen)
+				}
+				p.EnableMultipath = true
 			case statelessResetTokenParameterID:
 				if sentBy == protocol.PerspectiveClient {
 					return errors.New("client sent a stateless_reset_token")
@@ -161,6 +192,19 @@ func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective
 					return errors.New("client sent an original_connection_id")
 				}
 				p.OriginalConnectionID, _ = protocol.ReadConnectionID(r, int(paramLen))
+				readOriginalConnectionID = true
+			case initialSourceConnectionIDParameterID, retrySourceConnectionIDParameterID:
+				if !v.UsesConnectionIDTransportParameters() {
+					// not defined for this version
+					r.Seek(int64(paramLen), io.SeekCurrent)
+					break
+				}
+				if err := p.readHandshakeConnectionID(r, paramID, sentBy, int(paramLen)); err != nil {
+					return err
+				}
+				if paramID == initialSourceConnectionIDParameterID {
+					readInitialSourceConnectionID = true
+				}
 			default:
 				r.Seek(int64(paramLen), io.SeekCurrent)
 			}
@@ -176,6 +220,14 @@ func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective
 	if p.MaxPacketSize == 0 {
 		p.MaxPacketSize = protocol.MaxByteCount
 	}
+	if v.UsesConnectionIDTransportParameters() {
+		if sentBy == protocol.PerspectiveServer && !readOriginalConnectionID {
+			return errors.New("missing original_destination_connection_id")
+		}
+		if !readInitialSourceConnectionID {
+			return errors.New("missing initial_source_connection_id")
+		}
+	}
 
 	// check that every transport parameter was sent at most once
 	sort.Slice(parameterIDs, func(i, j int) bool { return parameterIDs[i] < parameterIDs[j] })
@@ -191,6 +243,23 @@ func (p *TransportParameters) unmarshal(data []byte, sentBy protocol.Perspective
 	return nil
 }
 
+func (p *TransportParameters) readHandshakeConnectionID(r *bytes.Reader, paramID transportParameterID, sentBy protocol.Perspective, l int) error {
+	connID, err := protocol.ReadConnectionID(r, l)
+	if err != nil {
+		return err
+	}
+	switch paramID {
+	case initialSourceConnectionIDParameterID:
+		p.InitialSourceConnectionID = connID
+	case retrySourceConnectionIDParameterID:
+		if sentBy == protocol.PerspectiveClient {
+			return errors.New("client sent a retry_source_connection_id")
+		}
+		p.RetrySourceConnectionID = &connID
+	}
+	return nil
+}
+
 func (p *TransportParameters) readPreferredAddress(r *bytes.Reader, expectedLen int) error {
 	remainingLen := r.Len()
 	pa := &PreferredAddress{}
@@ -218,6 +287,9 @@ func (p *TransportParameters) readPreferredAddress(r *bytes.Reader, expectedLen
 	if err != nil {
 		return err
 	}
+	if connIDLen == 0 {
+		return errors.New("preferred_address with a zero-length connection ID")
+	}
 	connID, err := protocol.ReadConnectionID(r, int(connIDLen))
 	if err != nil {
 		return err
@@ -289,57 +361,59 @@ func (p *TransportParameters) readNumericTransportParameter(
 }
 
 // Marshal the transport parameters
-func (p *TransportParameters) Marshal() []byte {
+func (p *TransportParameters) Marshal(v protocol.VersionNumber) []byte {
 	b := &bytes.Buffer{}
-	b.Write([]byte{0, 0}) // length. Will be replaced later
+	if !v.UsesVarIntTransportParameters() {
+		b.Write([]byte{0, 0}) // length. Will be replaced later
+	}
 
 	//add a greased value
-	utils.BigEndian.WriteUint16(b, uint16(27+31*rand.Intn(100)))
 	length := rand.Intn(16)
 	randomData := make([]byte, length)
 	rand.Read(randomData)
-	utils.BigEndian.WriteUint16(b, uint16(length))
+	writeParameterHeader(b, transportParameterID(27+31*rand.Intn(100)), uint64(length), v)
 	b.Write(randomData)
 
 	// initial_max_stream_data_bidi_local
-	p.marshalVarintParam(b, initialMaxStreamDataBidiLocalParameterID, uint64(p.InitialMaxStreamDataBidiLocal))
+	p.marshalVarintParam(b, initialMaxStreamDataBidiLocalParameterID, uint64(p.InitialMaxStreamDataBidiLocal), v)
 	// initial_max_stream_data_bidi_remote
-	p.marshalVarintParam(b, initialMaxStreamDataBidiRemoteParameterID, uint64(p.InitialMaxStreamDataBidiRemote))
+	p.marshalVarintParam(b, initialMaxStreamDataBidiRemoteParameterID, uint64(p.InitialMaxStreamDataBidiRemote), v)
 	// initial_max_stream_data_uni
-	p.marshalVarintParam(b, initialMaxStreamDataUniParameterID, uint64(p.InitialMaxStreamDataUni))
+	p.marshalVarintParam(b, initialMaxStreamDataUniParameterID, uint64(p.InitialMaxStreamDataUni), v)
 	// initial_max_data
-	p.marshalVarintParam(b, initialMaxDataParameterID, uint64(p.InitialMaxData))
+	p.marshalVarintParam(b, initialMaxDataParameterID, uint64(p.InitialMaxData), v)
 	// initial_max_bidi_streams
-	p.marshalVarintParam(b, initialMaxStreamsBidiParameterID, uint64(p.MaxBidiStreamNum))
+	p.marshalVarintParam(b, initialMaxStreamsBidiParameterID, uint64(p.MaxBidiStreamNum), v)
 	// initial_max_uni_streams
-	p.marshalVarintParam(b, initialMaxStreamsUniParameterID, uint64(p.MaxUniStreamNum))
+	p.marshalVarintParam(b, initialMaxStreamsUniParameterID, uint64(p.MaxUniStreamNum), v)
 	// idle_timeout
-	p.marshalVarintParam(b, idleTimeoutParameterID, uint64(p.IdleTimeout/time.Millisecond))
+	p.marshalVarintParam(b, idleTimeoutParameterID, uint64(p.IdleTimeout/time.Millisecond), v)
 	// max_packet_size
-	p.marshalVarintParam(b, maxPacketSizeParameterID, uint64(protocol.MaxReceivePacketSize))
+	p.marshalVarintParam(b, maxPacketSizeParameterID, uint64(protocol.MaxReceivePacketSize), v)
 	// max_ack_delay
 	// Only send it if is different from the default value.
 	if p.MaxAckDelay != protocol.DefaultMaxAckDelay {
-		p.marshalVarintParam(b, maxAckDelayParameterID, uint64(p.MaxAckDelay/time.Millisecond))
+		p.marshalVarintParam(b, maxAckDelayParameterID, uint64(p.MaxAckDelay/time.Millisecond), v)
 	}
 	// ack_delay_exponent
 	// Only send it if is different from the default value.
 	if p.AckDelayExponent != protocol.DefaultAckDelayExponent {
-		p.marshalVarintParam(b, ackDelayExponentParameterID, uint64(p.AckDelayExponent))
+		p.marshalVarintParam(b, ackDelayExponentParameterID, uint64(p.AckDelayExponent), v)
 	}
 	// disable_migration
 	if p.DisableMigration {
-		utils.BigEndian.WriteUint16(b, uint16(disableMigrationParameterID))
-		utils.BigEndian.WriteUint16(b, 0)
+		writeParameterHeader(b, disableMigrationParameterID, 0, v)
+	}
+	// enable_multipath
+	if p.EnableMultipath {
+		writeParameterHeader(b, enableMultipathParameterID, 0, v)
 	}
 	if p.StatelessResetToken != nil {
-		utils.BigEndian.WriteUint16(b, uint16(statelessResetTokenParameterID))
-		utils.BigEndian.WriteUint16(b, 16)
+		writeParameterHeader(b, statelessResetTokenParameterID, 16, v)
 		b.Write(p.StatelessResetToken[:])
 	}
 	if p.PreferredAddress != nil {
-		utils.BigEndian.WriteUint16(b, uint16(preferredAddressParamaterID))
-		utils.BigEndian.WriteUint16(b, 4+2+16+2+1+uint16(p.PreferredAddress.ConnectionID.Len())+16)
+		writeParameterHeader(b, preferredAddressParamaterID, uint64(4+2+16+2+1+p.PreferredAddress.ConnectionID.Len()+16), v)
 		ipv4 := p.PreferredAddress.IPv4
 		b.Write(ipv4[len(ipv4)-4:])
 		utils.BigEndian.WriteUint16(b, p.PreferredAddress.IPv4Port)
@@ -350,33 +424,151 @@ func (p *TransportParameters) Marshal() []byte {
 		b.Write(p.PreferredAddress.StatelessResetToken[:])
 	}
 	if p.OriginalConnectionID.Len() > 0 {
-		utils.BigEndian.WriteUint16(b, uint16(originalConnectionIDParameterID))
-		utils.BigEndian.WriteUint16(b, uint16(p.OriginalConnectionID.Len()))
+		writeParameterHeader(b, originalConnectionIDParameterID, uint64(p.OriginalConnectionID.Len()), v)
 		b.Write(p.OriginalConnectionID.Bytes())
 	}
 
 	// active_connection_id_limit
-	p.marshalVarintParam(b, activeConnectionIDLimitParameterID, p.ActiveConnectionIDLimit)
+	p.marshalVarintParam(b, activeConnectionIDLimitParameterID, p.ActiveConnectionIDLimit, v)
+
+	if v.UsesConnectionIDTransportParameters() {
+		// initial_source_connection_id
+		// This parameter is mandatory, even if the connection ID is zero-length.
+		writeParameterHeader(b, initialSourceConnectionIDParameterID, uint64(p.InitialSourceConnectionID.Len()), v)
+		b.Write(p.InitialSourceConnectionID.Bytes())
+		// retry_source_connection_id
+		if p.RetrySourceConnectionID != nil {
+			writeParameterHeader(b, retrySourceConnectionIDParameterID, uint64(p.RetrySourceConnectionID.Len()), v)
+			b.Write(p.RetrySourceConnectionID.Bytes())
+		}
+	}
 
 	data := b.Bytes()
-	binary.BigEndian.PutUint16(data[:2], uint16(b.Len()-2))
+	if !v.UsesVarIntTransportParameters() {
+		binary.BigEndian.PutUint16(data[:2], uint16(b.Len()-2))
+	}
 	return data
 }
 
-func (p *TransportParameters) marshalVarintParam(b *bytes.Buffer, id transportParameterID, val uint64) {
-	utils.BigEndian.WriteUint16(b, uint16(id))
-	utils.BigEndian.WriteUint16(b, uint16(utils.VarIntLen(val)))
+func (p *TransportParameters) marshalVarintParam(b *bytes.Buffer, id transportParameterID, val uint64, v protocol.VersionNumber) {
+	writeParameterHeader(b, id, uint64(utils.VarIntLen(val)), v)
 	utils.WriteVarInt(b, val)
 }
 
+func writeParameterHeader(b *bytes.Buffer, id transportParameterID, length uint64, v protocol.VersionNumber) {
+	if v.UsesVarIntTransportParameters() {
+		utils.WriteVarInt(b, uint64(id))
+		utils.WriteVarInt(b, length)
+		return
+	}
+	utils.BigEndian.WriteUint16(b, uint16(id))
+	utils.BigEndian.WriteUint16(b, uint16(length))
+}
+
+func readParameterHeader(r *bytes.Reader, v protocol.VersionNumber) (transportParameterID, uint64, error) {
+	if v.UsesVarIntTransportParameters() {
+		id, err := utils.ReadVarInt(r)
+		if err != nil {
+			return 0, 0, err
+		}
+		length, err := utils.ReadVarInt(r)
+		if err != nil {
+			return 0, 0, err
+		}
+		return transportParameterID(id), length, nil
+	}
+	id, err := utils.BigEndian.ReadUint16(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	length, err := utils.BigEndian.ReadUint16(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	return transportParameterID(id), uint64(length), nil
+}
+
+// MarshalForSessionTicket marshals the transport parameters we save in the session ticket.
+// When sending a 0-RTT enabled TLS session ticket, we need to save the transport parameters.
+// The client will remember the transport parameters used in the last session,
+// and apply those to the 0-RTT data it sends.
+// Saving the transport parameters in the ticket gives the server the option to reject 0-RTT
+// if the transport parameters changed.
+// Since the session ticket is encrypted, the serialization format is defined by the server.
+// The format doesn't depend on the QUIC version, since only the values relevant for 0-RTT are saved.
+func (p *TransportParameters) MarshalForSessionTicket(b *bytes.Buffer) {
+	utils.WriteVarInt(b, transportParameterMarshalingVersion)
+	utils.WriteVarInt(b, uint64(p.InitialMaxStreamDataBidiLocal))
+	utils.WriteVarInt(b, uint64(p.InitialMaxStreamDataBidiRemote))
+	utils.WriteVarInt(b, uint64(p.InitialMaxStreamDataUni))
+	utils.WriteVarInt(b, uint64(p.InitialMaxData))
+	utils.WriteVarInt(b, uint64(p.MaxBidiStreamNum))
+	utils.WriteVarInt(b, uint64(p.MaxUniStreamNum))
+	utils.WriteVarInt(b, p.ActiveConnectionIDLimit)
+}
+
+// UnmarshalFromSessionTicket unmarshals transport parameters from a session ticket.
+func (p *TransportParameters) UnmarshalFromSessionTicket(data []byte) error {
+	r := bytes.NewReader(data)
+	version, err := utils.ReadVarInt(r)
+	if err != nil {
+		return err
+	}
+	if version != transportParameterMarshalingVersion {
+		return fmt.Errorf("unknown transport parameter marshaling version: %d", version)
+	}
+	var vals [7]uint64
+	for i := range vals {
+		vals[i], err = utils.ReadVarInt(r)
+		if err != nil {
+			return err
+		}
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("should have read all data. Still have %d bytes", r.Len())
+	}
+	p.InitialMaxStreamDataBidiLocal = protocol.ByteCount(vals[0])
+	p.InitialMaxStreamDataBidiRemote = protocol.ByteCount(vals[1])
+	p.InitialMaxStreamDataUni = protocol.ByteCount(vals[2])
+	p.InitialMaxData = protocol.ByteCount(vals[3])
+	p.MaxBidiStreamNum = protocol.StreamNum(vals[4])
+	p.MaxUniStreamNum = protocol.StreamNum(vals[5])
+	p.ActiveConnectionIDLimit = vals[6]
+	return nil
+}
+
+// ValidFor0RTT checks if the transport parameters match those saved in the session ticket.
+// The client uses the saved values for the 0-RTT data it sends,
+// so the server must not reduce any of the limits.
+func (p *TransportParameters) ValidFor0RTT(tp *TransportParameters) bool {
+	return p.InitialMaxStreamDataBidiLocal >= tp.InitialMaxStreamDataBidiLocal &&
+		p.InitialMaxStreamDataBidiRemote >= tp.InitialMaxStreamDataBidiRemote &&
+		p.InitialMaxStreamDataUni >= tp.InitialMaxStreamDataUni &&
+		p.InitialMaxData >= tp.InitialMaxData &&
+		p.MaxBidiStreamNum >= tp.MaxBidiStreamNum &&
+		p.MaxUniStreamNum >= tp.MaxUniStreamNum &&
+		p.ActiveConnectionIDLimit == tp.ActiveConnectionIDLimit
+}
+
 // String returns a string representation, intended for logging.
 func (p *TransportParameters) String() string {
 	logString := "&handshake.TransportParameters{OriginalConnectionID: %s, InitialMaxStreamDataBidiLocal: %#x, InitialMaxStreamDataBidiRemote: %#x, InitialMaxStreamDataUni: %#x, InitialMaxData: %#x, MaxBidiStreamNum: %d, MaxUniStreamNum: %d, IdleTimeout: %s, AckDelayExponent: %d, MaxAckDelay: %s, ActiveConnectionIDLimit: %d"
 	logParams := []interface{}{p.OriginalConnectionID, p.InitialMaxStreamDataBidiLocal, p.InitialMaxStreamDataBidiRemote, p.InitialMaxStreamDataUni, p.InitialMaxData, p.MaxBidiStreamNum, p.MaxUniStreamNum, p.IdleTimeout, p.AckDelayExponent, p.MaxAckDelay, p.ActiveConnectionIDLimit}
+	if p.InitialSourceConnectionID != nil {
+		logString += ", InitialSourceConnectionID: %s"
+		logParams = append(logParams, p.InitialSourceConnectionID)
+	}
+	if p.RetrySourceConnectionID != nil {
+		logString += ", RetrySourceConnectionID: %s"
+		logParams = append(logParams, *p.RetrySourceConnectionID)
+	}
 	if p.StatelessResetToken != nil { // the client never sends a stateless reset token
 		logString += ", StatelessResetToken: %#x"
 		logParams = append(logParams, *p.StatelessResetToken)
 	}
+	if p.EnableMultipath {
+		logString += ", EnableMultipath: true"
+	}
 	logString += "}"
 	return fmt.Sprintf(logString, logParams...)
 }
@@ -1,8 +1,11 @@
 package handshake
 
 // This package uses unsafe to convert between:
-// * qtls.ConnectionState and tls.ConnectionState
+// * qtls.ConnectionState and tls.ConnectionState (using qtlsConnectionState and tlsConnectionState)
 // * qtls.ClientSessionState and tls.ClientSessionState
+// * tls.ClientSessionState and clientSessionState (to serialize session states)
+// * qtls.Certificate and tls.Certificate
+// * qtls.CertificateRequestInfo and tls.CertificateRequestInfo
 // We check in init() that this conversion actually is safe.
 
 import (
@@ -13,12 +16,24 @@ import (
 )
 
 func init() {
-	if !structsEqual(&tls.ConnectionState{}, &qtls.ConnectionState{}) {
-		panic("qtls.ConnectionState not compatible with tls.ConnectionState")
+	if !structsEqual(&tls.ConnectionState{}, &tlsConnectionState{}) {
+		panic("tlsConnectionState not compatible with tls.ConnectionState")
+	}
+	if !structsEqual(&qtls.ConnectionState{}, &qtlsConnectionState{}) {
+		panic("qtlsConnectionState not compatible with qtls.ConnectionState")
+	}
+	if !structsEqual(&tls.Certificate{}, &qtls.Certificate{}) {
+		panic("qtls.Certificate not compatible with tls.Certificate")
+	}
+	if !structsEqual(&tls.CertificateRequestInfo{}, &qtls.CertificateRequestInfo{}) {
+		panic("qtls.CertificateRequestInfo not compatible with tls.CertificateRequestInfo")
 	}
 	if !structsEqual(&tls.ClientSessionState{}, &qtls.ClientSessionState{}) {
 		panic("qtls.ClientSessionState not compatible with tls.ClientSessionState")
 	}
+	if !structsEqual(&clientSessionState{}, &qtls.ClientSessionState{}) {
+		panic("clientSessionState not compatible with qtls.ClientSessionState")
+	}
 }
 
 func structsEqual(a, b interface{}) bool {
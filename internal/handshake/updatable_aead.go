@@ -5,8 +5,10 @@ import (
 	"crypto/cipher"
 	"encoding/binary"
 	"fmt"
+	"math"
 	"os"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
@@ -42,12 +44,42 @@ func setKeyUpdateInterval() {
 	keyUpdateInterval = interval
 }
 
+// The confidentiality and integrity limits of the AEADs, as defined by the QUIC-TLS specification.
+const (
+	// the number of packets that can be encrypted using a single AES-GCM key
+	aesGCMConfidentialityLimit = 1 << 23
+	// the number of packets that fail authentication, after which AES-GCM can't be considered secure any more
+	aesGCMIntegrityLimit = 1 << 52
+	// the number of packets that fail authentication, after which ChaCha20-Poly1305 can't be considered secure any more
+	chaCha20IntegrityLimit = 1 << 36
+)
+
+// We close the connection a few packets before reaching the confidentiality limit,
+// so that the packets that are currently being packed and the CONNECTION_CLOSE can still be sent.
+const confidentialityLimitMargin = 1000
+
+func getAEADLimits(suite *qtls.CipherSuiteTLS13) (confidentialityLimit, integrityLimit uint64) {
+	if suite.ID == qtls.TLS_CHACHA20_POLY1305_SHA256 {
+		// ChaCha20-Poly1305 can be used to encrypt more packets than can be sent on a connection.
+		return math.MaxUint64, chaCha20IntegrityLimit
+	}
+	return aesGCMConfidentialityLimit, aesGCMIntegrityLimit
+}
+
 type updatableAEAD struct {
 	suite *qtls.CipherSuiteTLS13
 
-	keyPhase          protocol.KeyPhase
-	largestAcked      protocol.PacketNumber
-	keyUpdateInterval uint64
+	keyPhase               protocol.KeyPhase
+	largestAcked           protocol.PacketNumber
+	keyUpdateInterval      uint64
+	keyUpdateIntervalBytes uint64
+	// set to 1 (atomically) when a key update was requested by the application
+	keyUpdateRequested int32
+
+	confidentialityLimit uint64
+	integrityLimit       uint64
+	// the number of packets that failed authentication, across all key phases
+	numInvalidPackets uint64
 
 	// Time when the keys should be dropped. Keys are dropped on the next call to Open().
 	prevRcvAEADExpiry time.Time
@@ -57,6 +89,8 @@ type updatableAEAD struct {
 	firstSentWithCurrentKey protocol.PacketNumber
 	numRcvdWithCurrentKey   uint64
 	numSentWithCurrentKey   uint64
+	bytesRcvdWithCurrentKey uint64
+	bytesSentWithCurrentKey uint64
 	rcvAEAD                 cipher.AEAD
 	sendAEAD                cipher.AEAD
 	// caches cipher.AEAD.Overhead(). This speeds up calls to Overhead().
@@ -76,17 +110,28 @@ type updatableAEAD struct {
 
 	// use a single slice to avoid allocations
 	nonceBuf []byte
+
+	// the AEADs used for the paths of a multipath session, see forPath
+	pathAEADs map[uint64]*pathAEAD
 }
 
 var _ ShortHeaderOpener = &updatableAEAD{}
 var _ ShortHeaderSealer = &updatableAEAD{}
 
-func newUpdatableAEAD(rttStats *congestion.RTTStats, logger utils.Logger) *updatableAEAD {
+// newUpdatableAEAD creates a new updatableAEAD.
+// If interval is 0, the key update interval is read from the QUIC_GO_KEY_UPDATE_INTERVAL environment variable,
+// or protocol.KeyUpdateInterval is used.
+// If intervalBytes is 0, keys are not updated based on the number of bytes sent and received.
+func newUpdatableAEAD(rttStats *congestion.RTTStats, interval, intervalBytes uint64, logger utils.Logger) *updatableAEAD {
+	if interval == 0 {
+		interval = keyUpdateInterval
+	}
 	return &updatableAEAD{
 		largestAcked:            protocol.InvalidPacketNumber,
 		firstRcvdWithCurrentKey: protocol.InvalidPacketNumber,
 		firstSentWithCurrentKey: protocol.InvalidPacketNumber,
-		keyUpdateInterval:       keyUpdateInterval,
+		keyUpdateInterval:       interval,
+		keyUpdateIntervalBytes:  intervalBytes,
 		rttStats:                rttStats,
 		logger:                  logger,
 	}
@@ -98,6 +143,9 @@ func (a *updatableAEAD) rollKeys(now time.Time) {
 	a.firstSentWithCurrentKey = protocol.InvalidPacketNumber
 	a.numRcvdWithCurrentKey = 0
 	a.numSentWithCurrentKey = 0
+	a.bytesRcvdWithCurrentKey = 0
+	a.bytesSentWithCurrentKey = 0
+	atomic.StoreInt32(&a.keyUpdateRequested, 0)
 	a.prevRcvAEAD = a.rcvAEAD
 	a.prevRcvAEADExpiry = now.Add(3 * a.rttStats.PTO(true))
 	a.rcvAEAD = a.nextRcvAEAD
@@ -121,6 +169,7 @@ func (a *updatableAEAD) SetReadKey(suite *qtls.CipherSuiteTLS13, trafficSecret [
 	if a.suite == nil {
 		a.nonceBuf = make([]byte, a.rcvAEAD.NonceSize())
 		a.aeadOverhead = a.rcvAEAD.Overhead()
+		a.confidentialityLimit, a.integrityLimit = getAEADLimits(suite)
 		a.suite = suite
 	}
 
@@ -136,6 +185,7 @@ func (a *updatableAEAD) SetWriteKey(suite *qtls.CipherSuiteTLS13, trafficSecret
 	if a.suite == nil {
 		a.nonceBuf = make([]byte, a.sendAEAD.NonceSize())
 		a.aeadOverhead = a.sendAEAD.Overhead()
+		a.confidentialityLimit, a.integrityLimit = getAEADLimits(suite)
 		a.suite = suite
 	}
 
@@ -144,6 +194,17 @@ func (a *updatableAEAD) SetWriteKey(suite *qtls.CipherSuiteTLS13, trafficSecret
 }
 
 func (a *updatableAEAD) Open(dst, src []byte, rcvTime time.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
+	dec, err := a.open(dst, src, rcvTime, pn, kp, ad)
+	if err == ErrDecryptionFailed {
+		a.numInvalidPackets++
+		if a.numInvalidPackets > a.integrityLimit {
+			return nil, ErrIntegrityLimitReached
+		}
+	}
+	return dec, err
+}
+
+func (a *updatableAEAD) open(dst, src []byte, rcvTime time.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
 	if a.prevRcvAEAD != nil && rcvTime.After(a.prevRcvAEADExpiry) {
 		a.prevRcvAEAD = nil
 		a.prevRcvAEADExpiry = time.Time{}
@@ -188,6 +249,7 @@ func (a *updatableAEAD) Open(dst, src []byte, rcvTime time.Time, pn protocol.Pac
 		err = ErrDecryptionFailed
 	} else {
 		a.numRcvdWithCurrentKey++
+		a.bytesRcvdWithCurrentKey += uint64(len(dec))
 		if a.firstRcvdWithCurrentKey == protocol.InvalidPacketNumber {
 			a.firstRcvdWithCurrentKey = pn
 		}
@@ -200,6 +262,7 @@ func (a *updatableAEAD) Seal(dst, src []byte, pn protocol.PacketNumber, ad []byt
 		a.firstSentWithCurrentKey = pn
 	}
 	a.numSentWithCurrentKey++
+	a.bytesSentWithCurrentKey += uint64(len(src))
 	binary.BigEndian.PutUint64(a.nonceBuf[len(a.nonceBuf)-8:], uint64(pn))
 	// The AEAD we're using here will be the qtls.aeadAESGCM13.
 	// It uses the nonce provided here and XOR it with the IV.
@@ -216,10 +279,30 @@ func (a *updatableAEAD) updateAllowed() bool {
 		a.largestAcked >= a.firstSentWithCurrentKey
 }
 
+// InitiateKeyUpdate requests a key update.
+// The keys are updated when the next packet is sent, as soon as a key update is permitted.
+// It is safe to call this function from a different go routine.
+func (a *updatableAEAD) InitiateKeyUpdate() {
+	atomic.StoreInt32(&a.keyUpdateRequested, 1)
+}
+
+// confidentialityLimitReached says if (almost) the maximum number of packets were encrypted with the current key.
+func (a *updatableAEAD) confidentialityLimitReached() bool {
+	return a.numSentWithCurrentKey+confidentialityLimitMargin >= a.confidentialityLimit
+}
+
 func (a *updatableAEAD) shouldInitiateKeyUpdate() bool {
 	if !a.updateAllowed() {
 		return false
 	}
+	if atomic.LoadInt32(&a.keyUpdateRequested) == 1 {
+		a.logger.Debugf("Initiating requested key update to the next key phase: %s", a.keyPhase+1)
+		return true
+	}
+	if a.numSentWithCurrentKey >= a.confidentialityLimit/2 {
+		a.logger.Debugf("Sent %d packets with current key phase, approaching the confidentiality limit. Initiating key update to the next key phase: %s", a.numSentWithCurrentKey, a.keyPhase+1)
+		return true
+	}
 	if a.numRcvdWithCurrentKey >= a.keyUpdateInterval {
 		a.logger.Debugf("Received %d packets with current key phase. Initiating key update to the next key phase: %s", a.numRcvdWithCurrentKey, a.keyPhase+1)
 		return true
@@ -228,6 +311,16 @@ func (a *updatableAEAD) shouldInitiateKeyUpdate() bool {
 		a.logger.Debugf("Sent %d packets with current key phase. Initiating key update to the next key phase: %s", a.numSentWithCurrentKey, a.keyPhase+1)
 		return true
 	}
+	if a.keyUpdateIntervalBytes > 0 {
+		if a.bytesRcvdWithCurrentKey >= a.keyUpdateIntervalBytes {
+			a.logger.Debugf("Received %d bytes with current key phase. Initiating key update to the next key phase: %s", a.bytesRcvdWithCurrentKey, a.keyPhase+1)
+			return true
+		}
+		if a.bytesSentWithCurrentKey >= a.keyUpdateIntervalBytes {
+			a.logger.Debugf("Sent %d bytes with current key phase. Initiating key update to the next key phase: %s", a.bytesSentWithCurrentKey, a.keyPhase+1)
+			return true
+		}
+	}
 	return false
 }
 
@@ -249,3 +342,95 @@ func (a *updatableAEAD) EncryptHeader(sample []byte, firstByte *byte, hdrBytes [
 func (a *updatableAEAD) DecryptHeader(sample []byte, firstByte *byte, hdrBytes []byte) {
 	a.headerDecrypter.DecryptHeader(sample, firstByte, hdrBytes)
 }
+
+// forPath returns the AEAD used for the path with the given path identifier.
+// It must not be called for the initial path (path identifier 0), which uses the updatableAEAD itself.
+func (a *updatableAEAD) forPath(pathID uint64) *pathAEAD {
+	if a.pathAEADs == nil {
+		a.pathAEADs = make(map[uint64]*pathAEAD)
+	}
+	pa, ok := a.pathAEADs[pathID]
+	if !ok {
+		pa = &pathAEAD{updatableAEAD: a, pathID: pathID}
+		a.pathAEADs[pathID] = pa
+	}
+	return pa
+}
+
+// A pathAEAD seals and opens 1-RTT packets sent on a path other than the initial path of a multipath session.
+// Every path uses its own packet number space, so the path identifier is encoded into the nonce.
+// The pathAEAD uses the keys of the updatableAEAD.
+// Key updates are only initiated on the initial path, but the peer's key update can be detected on any path.
+type pathAEAD struct {
+	*updatableAEAD
+	pathID uint64
+}
+
+var _ ShortHeaderOpener = &pathAEAD{}
+var _ ShortHeaderSealer = &pathAEAD{}
+
+func (a *pathAEAD) setNonce(pn protocol.PacketNumber) {
+	binary.BigEndian.PutUint64(a.nonceBuf[len(a.nonceBuf)-8:], a.pathID<<protocol.PathIDNonceShift|uint64(pn))
+}
+
+func (a *pathAEAD) Open(dst, src []byte, rcvTime time.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
+	dec, err := a.open(dst, src, rcvTime, pn, kp, ad)
+	if err == ErrDecryptionFailed {
+		a.numInvalidPackets++
+		if a.numInvalidPackets > a.integrityLimit {
+			return nil, ErrIntegrityLimitReached
+		}
+	}
+	return dec, err
+}
+
+func (a *pathAEAD) open(dst, src []byte, rcvTime time.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
+	if pn > protocol.MaxPathPacketNumber {
+		return nil, ErrDecryptionFailed
+	}
+	if a.prevRcvAEAD != nil && rcvTime.After(a.prevRcvAEADExpiry) {
+		a.prevRcvAEAD = nil
+		a.prevRcvAEADExpiry = time.Time{}
+	}
+	a.setNonce(pn)
+	if kp == a.keyPhase.Bit() {
+		dec, err := a.rcvAEAD.Open(dst, a.nonceBuf, src, ad)
+		if err != nil {
+			return nil, ErrDecryptionFailed
+		}
+		a.numRcvdWithCurrentKey++
+		a.bytesRcvdWithCurrentKey += uint64(len(dec))
+		return dec, nil
+	}
+	// Packet numbers can't be compared to the packet numbers on the initial path,
+	// so we don't know if this packet was sent with the previous or with the next key phase.
+	if a.prevRcvAEAD != nil {
+		if dec, err := a.prevRcvAEAD.Open(dst, a.nonceBuf, src, ad); err == nil {
+			return dec, nil
+		}
+	}
+	dec, err := a.nextRcvAEAD.Open(dst, a.nonceBuf, src, ad)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	// Opening succeeded. Check if the peer was allowed to update.
+	if a.firstSentWithCurrentKey == protocol.InvalidPacketNumber {
+		return nil, qerr.Error(qerr.ProtocolViolation, "keys updated too quickly")
+	}
+	a.rollKeys(rcvTime)
+	a.logger.Debugf("Peer updated keys to %s (on path %d)", a.keyPhase, a.pathID)
+	return dec, nil
+}
+
+func (a *pathAEAD) Seal(dst, src []byte, pn protocol.PacketNumber, ad []byte) []byte {
+	a.numSentWithCurrentKey++
+	a.bytesSentWithCurrentKey += uint64(len(src))
+	a.setNonce(pn)
+	return a.sendAEAD.Seal(dst, a.nonceBuf, src, ad)
+}
+
+// KeyPhase returns the current key phase.
+// Key updates are only initiated when sending on the initial path.
+func (a *pathAEAD) KeyPhase() protocol.KeyPhaseBit {
+	return a.keyPhase.Bit()
+}
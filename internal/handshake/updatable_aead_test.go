@@ -3,12 +3,14 @@ package handshake
 import (
 	"crypto/rand"
 	"fmt"
+	"math"
 	"os"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/marten-seemann/qtls"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -25,8 +27,8 @@ var _ = Describe("Updatable AEAD", func() {
 				rand.Read(trafficSecret1)
 				rand.Read(trafficSecret2)
 
-				client = newUpdatableAEAD(rttStats, utils.DefaultLogger)
-				server = newUpdatableAEAD(rttStats, utils.DefaultLogger)
+				client = newUpdatableAEAD(rttStats, 0, 0, utils.DefaultLogger)
+				server = newUpdatableAEAD(rttStats, 0, 0, utils.DefaultLogger)
 				client.SetReadKey(cs, trafficSecret2)
 				client.SetWriteKey(cs, trafficSecret1)
 				server.SetReadKey(cs, trafficSecret1)
@@ -225,6 +227,88 @@ var _ = Describe("Updatable AEAD", func() {
 							server.SetLargestAcked(1)
 							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
 						})
+
+						It("initiates a key update after sealing the maximum number of bytes", func() {
+							server.keyUpdateIntervalBytes = 3 * uint64(len(msg))
+							for i := 0; i < 3; i++ {
+								pn := protocol.PacketNumber(i)
+								Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseZero))
+								server.Seal(nil, msg, pn, ad)
+							}
+							server.SetLargestAcked(0)
+							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+						})
+
+						It("initiates a key update after opening the maximum number of bytes", func() {
+							server.keyUpdateIntervalBytes = 3 * uint64(len(msg))
+							for i := 0; i < 3; i++ {
+								pn := protocol.PacketNumber(i)
+								Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseZero))
+								encrypted := client.Seal(nil, msg, pn, ad)
+								_, err := server.Open(nil, encrypted, time.Now(), pn, protocol.KeyPhaseZero, ad)
+								Expect(err).ToNot(HaveOccurred())
+							}
+							server.Seal(nil, msg, 1, ad)
+							server.SetLargestAcked(1)
+							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+						})
+
+						It("initiates a key update when requested", func() {
+							server.InitiateKeyUpdate()
+							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseZero))
+							server.Seal(nil, msg, 1, ad)
+							// no update allowed before receiving an acknowledgement for the current key phase
+							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseZero))
+							server.SetLargestAcked(1)
+							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+							// the request was fulfilled by this key update
+							server.Seal(nil, msg, 2, ad)
+							server.SetLargestAcked(2)
+							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+						})
+
+						It("initiates a key update when approaching the confidentiality limit", func() {
+							server.confidentialityLimit = 2 * keyUpdateInterval
+							server.keyUpdateInterval = 1000 * keyUpdateInterval
+							for i := 0; i < keyUpdateInterval; i++ {
+								pn := protocol.PacketNumber(i)
+								Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseZero))
+								server.Seal(nil, msg, pn, ad)
+							}
+							server.SetLargestAcked(0)
+							Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+						})
+					})
+
+					Context("AEAD limits", func() {
+						It("uses the limits of the cipher suite", func() {
+							if cs.ID == qtls.TLS_CHACHA20_POLY1305_SHA256 {
+								Expect(server.confidentialityLimit).To(BeEquivalentTo(uint64(math.MaxUint64)))
+								Expect(server.integrityLimit).To(BeEquivalentTo(chaCha20IntegrityLimit))
+							} else {
+								Expect(server.confidentialityLimit).To(BeEquivalentTo(aesGCMConfidentialityLimit))
+								Expect(server.integrityLimit).To(BeEquivalentTo(aesGCMIntegrityLimit))
+							}
+						})
+
+						It("reports when the confidentiality limit is reached", func() {
+							server.confidentialityLimit = confidentialityLimitMargin + 2
+							server.Seal(nil, msg, 1, ad)
+							Expect(server.confidentialityLimitReached()).To(BeFalse())
+							server.Seal(nil, msg, 2, ad)
+							Expect(server.confidentialityLimitReached()).To(BeTrue())
+						})
+
+						It("errors when the integrity limit is reached", func() {
+							server.integrityLimit = 2
+							encrypted := client.Seal(nil, msg, 0x1337, ad)
+							for i := 0; i < 2; i++ {
+								_, err := server.Open(nil, encrypted, time.Now(), 0x1337, protocol.KeyPhaseZero, []byte("wrong ad"))
+								Expect(err).To(MatchError(ErrDecryptionFailed))
+							}
+							_, err := server.Open(nil, encrypted, time.Now(), 0x1337, protocol.KeyPhaseZero, []byte("wrong ad"))
+							Expect(err).To(MatchError(ErrIntegrityLimitReached))
+						})
 					})
 
 					Context("reading the key update env", func() {
@@ -250,6 +334,78 @@ var _ = Describe("Updatable AEAD", func() {
 						})
 					})
 				})
+
+				Context("multipath", func() {
+					It("encrypts and decrypts a message on a path", func() {
+						encrypted := server.forPath(3).Seal(nil, msg, 0x1337, ad)
+						opened, err := client.forPath(3).Open(nil, encrypted, time.Now(), 0x1337, protocol.KeyPhaseZero, ad)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(opened).To(Equal(msg))
+					})
+
+					It("uses a different nonce on every path", func() {
+						encrypted0 := server.Seal(nil, msg, 0x1337, ad)
+						encrypted1 := server.forPath(1).Seal(nil, msg, 0x1337, ad)
+						encrypted2 := server.forPath(2).Seal(nil, msg, 0x1337, ad)
+						Expect(encrypted0).ToNot(Equal(encrypted1))
+						Expect(encrypted0).ToNot(Equal(encrypted2))
+						Expect(encrypted1).ToNot(Equal(encrypted2))
+						_, err := client.forPath(2).Open(nil, encrypted1, time.Now(), 0x1337, protocol.KeyPhaseZero, ad)
+						Expect(err).To(MatchError(ErrDecryptionFailed))
+						_, err = client.Open(nil, encrypted1, time.Now(), 0x1337, protocol.KeyPhaseZero, ad)
+						Expect(err).To(MatchError(ErrDecryptionFailed))
+					})
+
+					It("returns the same AEAD for a path", func() {
+						Expect(server.forPath(1)).To(BeIdenticalTo(server.forPath(1)))
+						Expect(server.forPath(1)).ToNot(BeIdenticalTo(server.forPath(2)))
+					})
+
+					It("rejects packet numbers that can't be encoded in the nonce", func() {
+						encrypted := server.forPath(1).Seal(nil, msg, protocol.MaxPathPacketNumber+1, ad)
+						_, err := client.forPath(1).Open(nil, encrypted, time.Now(), protocol.MaxPathPacketNumber+1, protocol.KeyPhaseZero, ad)
+						Expect(err).To(MatchError(ErrDecryptionFailed))
+					})
+
+					It("doesn't initiate key updates on a path", func() {
+						server.InitiateKeyUpdate()
+						server.SetLargestAcked(0)
+						_ = server.Seal(nil, msg, 0, ad)
+						Expect(server.forPath(1).KeyPhase()).To(Equal(protocol.KeyPhaseZero))
+						Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+						Expect(server.forPath(1).KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+					})
+
+					It("detects a key update of the peer on a path", func() {
+						now := time.Now()
+						_ = server.Seal(nil, msg, 0x1, ad)
+						client.rollKeys(now)
+						encrypted := client.forPath(1).Seal(nil, msg, 0x42, ad)
+						opened, err := server.forPath(1).Open(nil, encrypted, now, 0x42, protocol.KeyPhaseOne, ad)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(opened).To(Equal(msg))
+						Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+					})
+
+					It("opens a packet sent with the previous key phase on a path", func() {
+						now := time.Now()
+						encrypted := client.forPath(1).Seal(nil, msg, 0x42, ad)
+						_ = server.Seal(nil, msg, 0x1, ad)
+						client.rollKeys(now)
+						server.rollKeys(now)
+						opened, err := server.forPath(1).Open(nil, encrypted, now, 0x42, protocol.KeyPhaseZero, ad)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(opened).To(Equal(msg))
+						Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+					})
+
+					It("errors when the peer updates keys too quickly on a path", func() {
+						client.rollKeys(time.Now())
+						encrypted := client.forPath(1).Seal(nil, msg, 0x42, ad)
+						_, err := server.forPath(1).Open(nil, encrypted, time.Now(), 0x42, protocol.KeyPhaseOne, ad)
+						Expect(err).To(MatchError("PROTOCOL_VIOLATION: keys updated too quickly"))
+					})
+				})
 			})
 		})
 	}
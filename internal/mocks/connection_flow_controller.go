@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	flowcontrol "github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
@@ -58,6 +59,20 @@ func (mr *MockConnectionFlowControllerMockRecorder) AddBytesSent(arg0 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddBytesSent", reflect.TypeOf((*MockConnectionFlowController)(nil).AddBytesSent), arg0)
 }
 
+// GetStats mocks base method
+func (m *MockConnectionFlowController) GetStats() flowcontrol.Stats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStats")
+	ret0, _ := ret[0].(flowcontrol.Stats)
+	return ret0
+}
+
+// GetStats indicates an expected call of GetStats
+func (mr *MockConnectionFlowControllerMockRecorder) GetStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStats", reflect.TypeOf((*MockConnectionFlowController)(nil).GetStats))
+}
+
 // GetWindowUpdate mocks base method
 func (m *MockConnectionFlowController) GetWindowUpdate() protocol.ByteCount {
 	m.ctrl.T.Helper()
@@ -87,6 +102,18 @@ func (mr *MockConnectionFlowControllerMockRecorder) IsNewlyBlocked() *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsNewlyBlocked", reflect.TypeOf((*MockConnectionFlowController)(nil).IsNewlyBlocked))
 }
 
+// ReceivedBlocked mocks base method
+func (m *MockConnectionFlowController) ReceivedBlocked() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReceivedBlocked")
+}
+
+// ReceivedBlocked indicates an expected call of ReceivedBlocked
+func (mr *MockConnectionFlowControllerMockRecorder) ReceivedBlocked() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceivedBlocked", reflect.TypeOf((*MockConnectionFlowController)(nil).ReceivedBlocked))
+}
+
 // SendWindowSize mocks base method
 func (m *MockConnectionFlowController) SendWindowSize() protocol.ByteCount {
 	m.ctrl.T.Helper()
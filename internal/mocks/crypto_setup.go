@@ -76,6 +76,36 @@ func (mr *MockCryptoSetupMockRecorder) ConnectionState() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectionState", reflect.TypeOf((*MockCryptoSetup)(nil).ConnectionState))
 }
 
+// Get0RTTOpener mocks base method
+func (m *MockCryptoSetup) Get0RTTOpener() (handshake.LongHeaderOpener, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get0RTTOpener")
+	ret0, _ := ret[0].(handshake.LongHeaderOpener)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get0RTTOpener indicates an expected call of Get0RTTOpener
+func (mr *MockCryptoSetupMockRecorder) Get0RTTOpener() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get0RTTOpener", reflect.TypeOf((*MockCryptoSetup)(nil).Get0RTTOpener))
+}
+
+// Get0RTTSealer mocks base method
+func (m *MockCryptoSetup) Get0RTTSealer() (handshake.LongHeaderSealer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get0RTTSealer")
+	ret0, _ := ret[0].(handshake.LongHeaderSealer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get0RTTSealer indicates an expected call of Get0RTTSealer
+func (mr *MockCryptoSetupMockRecorder) Get0RTTSealer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get0RTTSealer", reflect.TypeOf((*MockCryptoSetup)(nil).Get0RTTSealer))
+}
+
 // Get1RTTOpener mocks base method
 func (m *MockCryptoSetup) Get1RTTOpener() (handshake.ShortHeaderOpener, error) {
 	m.ctrl.T.Helper()
@@ -91,6 +121,21 @@ func (mr *MockCryptoSetupMockRecorder) Get1RTTOpener() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get1RTTOpener", reflect.TypeOf((*MockCryptoSetup)(nil).Get1RTTOpener))
 }
 
+// Get1RTTOpenerForPath mocks base method
+func (m *MockCryptoSetup) Get1RTTOpenerForPath(arg0 uint64) (handshake.ShortHeaderOpener, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get1RTTOpenerForPath", arg0)
+	ret0, _ := ret[0].(handshake.ShortHeaderOpener)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get1RTTOpenerForPath indicates an expected call of Get1RTTOpenerForPath
+func (mr *MockCryptoSetupMockRecorder) Get1RTTOpenerForPath(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get1RTTOpenerForPath", reflect.TypeOf((*MockCryptoSetup)(nil).Get1RTTOpenerForPath), arg0)
+}
+
 // Get1RTTSealer mocks base method
 func (m *MockCryptoSetup) Get1RTTSealer() (handshake.ShortHeaderSealer, error) {
 	m.ctrl.T.Helper()
@@ -106,6 +151,21 @@ func (mr *MockCryptoSetupMockRecorder) Get1RTTSealer() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get1RTTSealer", reflect.TypeOf((*MockCryptoSetup)(nil).Get1RTTSealer))
 }
 
+// Get1RTTSealerForPath mocks base method
+func (m *MockCryptoSetup) Get1RTTSealerForPath(arg0 uint64) (handshake.ShortHeaderSealer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get1RTTSealerForPath", arg0)
+	ret0, _ := ret[0].(handshake.ShortHeaderSealer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get1RTTSealerForPath indicates an expected call of Get1RTTSealerForPath
+func (mr *MockCryptoSetupMockRecorder) Get1RTTSealerForPath(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get1RTTSealerForPath", reflect.TypeOf((*MockCryptoSetup)(nil).Get1RTTSealerForPath), arg0)
+}
+
 // GetHandshakeOpener mocks base method
 func (m *MockCryptoSetup) GetHandshakeOpener() (handshake.LongHeaderOpener, error) {
 	m.ctrl.T.Helper()
@@ -180,6 +240,20 @@ func (mr *MockCryptoSetupMockRecorder) HandleMessage(arg0, arg1 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleMessage", reflect.TypeOf((*MockCryptoSetup)(nil).HandleMessage), arg0, arg1)
 }
 
+// InitiateKeyUpdate mocks base method
+func (m *MockCryptoSetup) InitiateKeyUpdate() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InitiateKeyUpdate")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InitiateKeyUpdate indicates an expected call of InitiateKeyUpdate
+func (mr *MockCryptoSetupMockRecorder) InitiateKeyUpdate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitiateKeyUpdate", reflect.TypeOf((*MockCryptoSetup)(nil).InitiateKeyUpdate))
+}
+
 // RunHandshake mocks base method
 func (m *MockCryptoSetup) RunHandshake() {
 	m.ctrl.T.Helper()
@@ -192,6 +266,18 @@ func (mr *MockCryptoSetupMockRecorder) RunHandshake() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunHandshake", reflect.TypeOf((*MockCryptoSetup)(nil).RunHandshake))
 }
 
+// SetHandshakeConfirmed mocks base method
+func (m *MockCryptoSetup) SetHandshakeConfirmed() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetHandshakeConfirmed")
+}
+
+// SetHandshakeConfirmed indicates an expected call of SetHandshakeConfirmed
+func (mr *MockCryptoSetupMockRecorder) SetHandshakeConfirmed() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHandshakeConfirmed", reflect.TypeOf((*MockCryptoSetup)(nil).SetHandshakeConfirmed))
+}
+
 // SetLargest1RTTAcked mocks base method
 func (m *MockCryptoSetup) SetLargest1RTTAcked(arg0 protocol.PacketNumber) {
 	m.ctrl.T.Helper()
@@ -124,6 +124,48 @@ func (mr *MockSessionMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockSession)(nil).Context))
 }
 
+// FlowControlStats mocks base method
+func (m *MockSession) FlowControlStats() quic_go.FlowControlStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlowControlStats")
+	ret0, _ := ret[0].(quic_go.FlowControlStats)
+	return ret0
+}
+
+// FlowControlStats indicates an expected call of FlowControlStats
+func (mr *MockSessionMockRecorder) FlowControlStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlStats", reflect.TypeOf((*MockSession)(nil).FlowControlStats))
+}
+
+// GetConnectionRTT mocks base method
+func (m *MockSession) GetConnectionRTT() float64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConnectionRTT")
+	ret0, _ := ret[0].(float64)
+	return ret0
+}
+
+// GetConnectionRTT indicates an expected call of GetConnectionRTT
+func (mr *MockSessionMockRecorder) GetConnectionRTT() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConnectionRTT", reflect.TypeOf((*MockSession)(nil).GetConnectionRTT))
+}
+
+// InitiateKeyUpdate mocks base method
+func (m *MockSession) InitiateKeyUpdate() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InitiateKeyUpdate")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InitiateKeyUpdate indicates an expected call of InitiateKeyUpdate
+func (mr *MockSessionMockRecorder) InitiateKeyUpdate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitiateKeyUpdate", reflect.TypeOf((*MockSession)(nil).InitiateKeyUpdate))
+}
+
 // LocalAddr mocks base method
 func (m *MockSession) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -168,6 +210,36 @@ func (mr *MockSessionMockRecorder) OpenStreamSync(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamSync", reflect.TypeOf((*MockSession)(nil).OpenStreamSync), arg0)
 }
 
+// OpenStreamSyncWithOptions mocks base method
+func (m *MockSession) OpenStreamSyncWithOptions(arg0 context.Context, arg1 *quic_go.StreamOptions) (quic_go.Stream, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenStreamSyncWithOptions", arg0, arg1)
+	ret0, _ := ret[0].(quic_go.Stream)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenStreamSyncWithOptions indicates an expected call of OpenStreamSyncWithOptions
+func (mr *MockSessionMockRecorder) OpenStreamSyncWithOptions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamSyncWithOptions", reflect.TypeOf((*MockSession)(nil).OpenStreamSyncWithOptions), arg0, arg1)
+}
+
+// OpenStreamWithOptions mocks base method
+func (m *MockSession) OpenStreamWithOptions(arg0 *quic_go.StreamOptions) (quic_go.Stream, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenStreamWithOptions", arg0)
+	ret0, _ := ret[0].(quic_go.Stream)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenStreamWithOptions indicates an expected call of OpenStreamWithOptions
+func (mr *MockSessionMockRecorder) OpenStreamWithOptions(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamWithOptions", reflect.TypeOf((*MockSession)(nil).OpenStreamWithOptions), arg0)
+}
+
 // OpenUniStream mocks base method
 func (m *MockSession) OpenUniStream() (quic_go.SendStream, error) {
 	m.ctrl.T.Helper()
@@ -198,6 +270,20 @@ func (mr *MockSessionMockRecorder) OpenUniStreamSync(arg0 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockSession)(nil).OpenUniStreamSync), arg0)
 }
 
+// Scheduler mocks base method
+func (m *MockSession) Scheduler() quic_go.ResponseWriterScheduler {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Scheduler")
+	ret0, _ := ret[0].(quic_go.ResponseWriterScheduler)
+	return ret0
+}
+
+// Scheduler indicates an expected call of Scheduler
+func (mr *MockSessionMockRecorder) Scheduler() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scheduler", reflect.TypeOf((*MockSession)(nil).Scheduler))
+}
+
 // RemoteAddr mocks base method
 func (m *MockSession) RemoteAddr() net.Addr {
 	m.ctrl.T.Helper()
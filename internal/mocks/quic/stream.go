@@ -10,6 +10,7 @@ import (
 	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	quic_go "github.com/lucas-clemente/quic-go"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
@@ -88,6 +89,20 @@ func (mr *MockStreamMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockStream)(nil).Context))
 }
 
+// FlowControlStats mocks base method
+func (m *MockStream) FlowControlStats() quic_go.FlowControlStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlowControlStats")
+	ret0, _ := ret[0].(quic_go.FlowControlStats)
+	return ret0
+}
+
+// FlowControlStats indicates an expected call of FlowControlStats
+func (mr *MockStreamMockRecorder) FlowControlStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlStats", reflect.TypeOf((*MockStream)(nil).FlowControlStats))
+}
+
 // Read mocks base method
 func (m *MockStream) Read(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	flowcontrol "github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
@@ -70,6 +71,20 @@ func (mr *MockStreamFlowControllerMockRecorder) AddBytesSent(arg0 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddBytesSent", reflect.TypeOf((*MockStreamFlowController)(nil).AddBytesSent), arg0)
 }
 
+// GetStats mocks base method
+func (m *MockStreamFlowController) GetStats() flowcontrol.Stats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStats")
+	ret0, _ := ret[0].(flowcontrol.Stats)
+	return ret0
+}
+
+// GetStats indicates an expected call of GetStats
+func (mr *MockStreamFlowControllerMockRecorder) GetStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStats", reflect.TypeOf((*MockStreamFlowController)(nil).GetStats))
+}
+
 // GetWindowUpdate mocks base method
 func (m *MockStreamFlowController) GetWindowUpdate() protocol.ByteCount {
 	m.ctrl.T.Helper()
@@ -99,6 +114,18 @@ func (mr *MockStreamFlowControllerMockRecorder) IsNewlyBlocked() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsNewlyBlocked", reflect.TypeOf((*MockStreamFlowController)(nil).IsNewlyBlocked))
 }
 
+// ReceivedBlocked mocks base method
+func (m *MockStreamFlowController) ReceivedBlocked() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReceivedBlocked")
+}
+
+// ReceivedBlocked indicates an expected call of ReceivedBlocked
+func (mr *MockStreamFlowControllerMockRecorder) ReceivedBlocked() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceivedBlocked", reflect.TypeOf((*MockStreamFlowController)(nil).ReceivedBlocked))
+}
+
 // SendWindowSize mocks base method
 func (m *MockStreamFlowController) SendWindowSize() protocol.ByteCount {
 	m.ctrl.T.Helper()
@@ -113,6 +140,18 @@ func (mr *MockStreamFlowControllerMockRecorder) SendWindowSize() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendWindowSize", reflect.TypeOf((*MockStreamFlowController)(nil).SendWindowSize))
 }
 
+// SetReceiveWindowSize mocks base method
+func (m *MockStreamFlowController) SetReceiveWindowSize(arg0 protocol.ByteCount, arg1 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReceiveWindowSize", arg0, arg1)
+}
+
+// SetReceiveWindowSize indicates an expected call of SetReceiveWindowSize
+func (mr *MockStreamFlowControllerMockRecorder) SetReceiveWindowSize(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReceiveWindowSize", reflect.TypeOf((*MockStreamFlowController)(nil).SetReceiveWindowSize), arg0, arg1)
+}
+
 // UpdateHighestReceived mocks base method
 func (m *MockStreamFlowController) UpdateHighestReceived(arg0 protocol.ByteCount, arg1 bool) error {
 	m.ctrl.T.Helper()
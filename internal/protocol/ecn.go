@@ -0,0 +1,33 @@
+package protocol
+
+// ECN is the ECN value of an IP packet, as defined in RFC 3168
+type ECN uint8
+
+// the ECN codepoints
+const (
+	ECNNon ECN = 0 // 00: not ECN-capable transport
+	ECT1   ECN = 1 // 01: ECN-capable transport, ECT(1)
+	ECT0   ECN = 2 // 10: ECN-capable transport, ECT(0)
+	ECNCE  ECN = 3 // 11: congestion experienced
+)
+
+// ECNFromTOS returns the ECN value encoded in the lowest two bits of
+// the IPv4 TOS field (or the IPv6 traffic class)
+func ECNFromTOS(tos byte) ECN {
+	return ECN(tos & 0x3)
+}
+
+func (e ECN) String() string {
+	switch e {
+	case ECNNon:
+		return "Not-ECT"
+	case ECT1:
+		return "ECT(1)"
+	case ECT0:
+		return "ECT(0)"
+	case ECNCE:
+		return "CE"
+	default:
+		return "invalid ECN value"
+	}
+}
@@ -0,0 +1,23 @@
+package protocol
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ECN", func() {
+	It("has a string representation", func() {
+		Expect(ECNNon.String()).To(Equal("Not-ECT"))
+		Expect(ECT0.String()).To(Equal("ECT(0)"))
+		Expect(ECT1.String()).To(Equal("ECT(1)"))
+		Expect(ECNCE.String()).To(Equal("CE"))
+		Expect(ECN(42).String()).To(Equal("invalid ECN value"))
+	})
+
+	It("extracts the ECN bits from the TOS byte", func() {
+		Expect(ECNFromTOS(0)).To(Equal(ECNNon))
+		Expect(ECNFromTOS(0xb8 | 0x2)).To(Equal(ECT0))
+		Expect(ECNFromTOS(0xb8 | 0x1)).To(Equal(ECT1))
+		Expect(ECNFromTOS(0xff)).To(Equal(ECNCE))
+	})
+})
@@ -11,6 +11,8 @@ const (
 	EncryptionInitial
 	// EncryptionHandshake is the Handshake encryption level
 	EncryptionHandshake
+	// Encryption0RTT is the 0-RTT encryption level
+	Encryption0RTT
 	// Encryption1RTT is the 1-RTT encryption level
 	Encryption1RTT
 )
@@ -21,6 +23,8 @@ func (e EncryptionLevel) String() string {
 		return "Initial"
 	case EncryptionHandshake:
 		return "Handshake"
+	case Encryption0RTT:
+		return "0-RTT"
 	case Encryption1RTT:
 		return "1-RTT"
 	}
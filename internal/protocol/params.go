@@ -16,6 +16,13 @@ const DefaultMaxCongestionWindow ByteCount = defaultMaxCongestionWindowPackets *
 // InitialCongestionWindow is the initial congestion window in QUIC packets
 const InitialCongestionWindow ByteCount = 32 * DefaultTCPMSS
 
+// DefaultInitialPacingBurst is the number of bytes that can be sent at the beginning of a connection without pacing.
+const DefaultInitialPacingBurst ByteCount = InitialCongestionWindow
+
+// DefaultMaxPacingBurst is the maximum number of bytes that are sent in a single burst,
+// once the initial burst was used up.
+const DefaultMaxPacingBurst ByteCount = 10 * MaxPacketSizeIPv4
+
 // MaxUndecryptablePackets limits the number of undecryptable packets that are queued in the session.
 const MaxUndecryptablePackets = 10
 
@@ -146,6 +153,18 @@ const MaxActiveConnectionIDs = 4
 // MaxIssuedConnectionIDs is the maximum number of connection IDs that we're issuing at the same time.
 const MaxIssuedConnectionIDs = 6
 
+// MaxPathChallenges is the number of PATH_CHALLENGE frames sent when validating a path.
+// Path validation fails if no PATH_RESPONSE is received within one PTO after the last one.
+const MaxPathChallenges = 3
+
+// PathIDNonceShift is the number of bits that the path identifier is shifted by when it is encoded into the AEAD nonce.
+// When using the multipath extension, the nonce of a packet sent on a path other than the initial path
+// contains the path identifier in the upper 24 bits, and the packet number in the lower 40 bits.
+const PathIDNonceShift = 40
+
+// MaxPathPacketNumber is the maximum packet number that can be used on a path other than the initial path.
+const MaxPathPacketNumber = 1<<PathIDNonceShift - 1
+
 // PacketsPerConnectionID is the number of packets we send using one connection ID.
 // If the peer provices us with enough new connection IDs, we switch to a new connection ID.
 const PacketsPerConnectionID = 10000
@@ -166,3 +185,11 @@ const MaxAckDelayInclGranularity = MaxAckDelay + TimerGranularity
 
 // KeyUpdateInterval is the maximum number of packets we send or receive before initiating a key udpate.
 const KeyUpdateInterval = 100 * 1000
+
+// BatchSize is the maximum number of packets read or written in a single system call,
+// if batched I/O is enabled.
+const BatchSize = 16
+
+// MaxGSOSegments is the maximum number of packets sent in a single UDP GSO send operation.
+// This is the limit imposed by the Linux kernel.
+const MaxGSOSegments = 64
@@ -19,15 +19,17 @@ const (
 // The version numbers, making grepping easier
 const (
 	VersionTLS      VersionNumber = VersionMilestone0_14
-	VersionWhatever VersionNumber = 1 // for when the version doesn't matter
+	VersionWhatever VersionNumber = math.MaxUint32 - 1 // for when the version doesn't matter
 	VersionUnknown  VersionNumber = math.MaxUint32
 
 	VersionMilestone0_14 VersionNumber = 0xff000018 // QUIC WG draft-24
+	VersionDraft29       VersionNumber = 0xff00001d // QUIC WG draft-29
+	Version1             VersionNumber = 0x1        // RFC 9000
 )
 
 // SupportedVersions lists the versions that the server supports
-// must be in sorted descending order
-var SupportedVersions = []VersionNumber{VersionMilestone0_14}
+// must be sorted by preference, in descending order
+var SupportedVersions = []VersionNumber{Version1, VersionDraft29, VersionMilestone0_14}
 
 // IsValidVersion says if the version is known to quic-go
 func IsValidVersion(v VersionNumber) bool {
@@ -42,6 +44,10 @@ func (vn VersionNumber) String() string {
 		return "unknown"
 	case VersionMilestone0_14:
 		return "QUIC WG draft-24"
+	case VersionDraft29:
+		return "QUIC WG draft-29"
+	case Version1:
+		return "v1"
 	default:
 		if vn.isGQUIC() {
 			return fmt.Sprintf("gQUIC %d", vn.toGQUICVersion())
@@ -50,6 +56,40 @@ func (vn VersionNumber) String() string {
 	}
 }
 
+// draftNumber returns the number of the QUIC WG draft that this version implements.
+// QUIC version 1 is treated as the newest draft.
+func (vn VersionNumber) draftNumber() int {
+	if vn == Version1 {
+		return math.MaxInt32
+	}
+	if vn&0xffffff00 == 0xff000000 {
+		return int(vn & 0xff)
+	}
+	return 0
+}
+
+// UsesRetryIntegrityTag says if Retry packets carry an integrity tag (draft-25 and later),
+// instead of the Original Destination Connection ID.
+func (vn VersionNumber) UsesRetryIntegrityTag() bool {
+	return vn.draftNumber() >= 25
+}
+
+// UsesHandshakeDoneFrame says if the server confirms the handshake using a HANDSHAKE_DONE frame (draft-25 and later).
+func (vn VersionNumber) UsesHandshakeDoneFrame() bool {
+	return vn.draftNumber() >= 25
+}
+
+// UsesVarIntTransportParameters says if transport parameter IDs and lengths are encoded as varints (draft-27 and later).
+func (vn VersionNumber) UsesVarIntTransportParameters() bool {
+	return vn.draftNumber() >= 27
+}
+
+// UsesConnectionIDTransportParameters says if the connection IDs used during the handshake
+// are authenticated using transport parameters (draft-28 and later).
+func (vn VersionNumber) UsesConnectionIDTransportParameters() bool {
+	return vn.draftNumber() >= 28
+}
+
 func (vn VersionNumber) isGQUIC() bool {
 	return vn > gquicVersion0 && vn <= maxGquicVersion
 }
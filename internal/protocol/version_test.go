@@ -13,17 +13,23 @@ var _ = Describe("Version", func() {
 	It("says if a version is valid", func() {
 		Expect(IsValidVersion(VersionTLS)).To(BeTrue())
 		Expect(IsValidVersion(VersionMilestone0_14)).To(BeTrue())
+		Expect(IsValidVersion(VersionDraft29)).To(BeTrue())
+		Expect(IsValidVersion(Version1)).To(BeTrue())
 		Expect(IsValidVersion(VersionWhatever)).To(BeFalse())
 		Expect(IsValidVersion(VersionUnknown)).To(BeFalse())
 		Expect(IsValidVersion(1234)).To(BeFalse())
 	})
 
 	It("versions don't have reserved version numbers", func() {
-		Expect(isReservedVersion(VersionTLS)).To(BeFalse())
+		for _, v := range SupportedVersions {
+			Expect(isReservedVersion(v)).To(BeFalse())
+		}
 	})
 
 	It("has the right string representation", func() {
 		Expect(VersionMilestone0_14.String()).To(ContainSubstring("QUIC WG draft-24"))
+		Expect(VersionDraft29.String()).To(ContainSubstring("QUIC WG draft-29"))
+		Expect(Version1.String()).To(Equal("v1"))
 		Expect(VersionWhatever.String()).To(Equal("whatever"))
 		Expect(VersionUnknown.String()).To(Equal("unknown"))
 		// check with unsupported version numbers from the wiki
@@ -40,12 +46,28 @@ var _ = Describe("Version", func() {
 		Expect(IsSupportedVersion(SupportedVersions, SupportedVersions[len(SupportedVersions)-1])).To(BeTrue())
 	})
 
-	It("has supported versions in sorted order", func() {
-		for i := 0; i < len(SupportedVersions)-1; i++ {
+	It("prefers newer versions", func() {
+		Expect(SupportedVersions[0]).To(Equal(Version1))
+		for i := 1; i < len(SupportedVersions)-1; i++ {
 			Expect(SupportedVersions[i]).To(BeNumerically(">", SupportedVersions[i+1]))
 		}
 	})
 
+	It("says which features a version uses", func() {
+		Expect(VersionMilestone0_14.UsesRetryIntegrityTag()).To(BeFalse())
+		Expect(VersionMilestone0_14.UsesHandshakeDoneFrame()).To(BeFalse())
+		Expect(VersionMilestone0_14.UsesVarIntTransportParameters()).To(BeFalse())
+		Expect(VersionMilestone0_14.UsesConnectionIDTransportParameters()).To(BeFalse())
+		for _, v := range []VersionNumber{VersionDraft29, Version1} {
+			Expect(v.UsesRetryIntegrityTag()).To(BeTrue())
+			Expect(v.UsesHandshakeDoneFrame()).To(BeTrue())
+			Expect(v.UsesVarIntTransportParameters()).To(BeTrue())
+			Expect(v.UsesConnectionIDTransportParameters()).To(BeTrue())
+		}
+		Expect(VersionNumber(0xff00001b).UsesVarIntTransportParameters()).To(BeTrue())
+		Expect(VersionNumber(0xff00001b).UsesConnectionIDTransportParameters()).To(BeFalse())
+	})
+
 	Context("highest supported version", func() {
 		It("finds the supported version", func() {
 			supportedVersions := []VersionNumber{1, 2, 3}
@@ -22,6 +22,7 @@ const (
 	TransportParameterError ErrorCode = 0x8
 	ProtocolViolation       ErrorCode = 0xa
 	CryptoBufferExceeded    ErrorCode = 0xd
+	AEADLimitReached        ErrorCode = 0xf
 )
 
 func (e ErrorCode) isCryptoError() bool {
@@ -68,6 +69,8 @@ func (e ErrorCode) String() string {
 		return "PROTOCOL_VIOLATION"
 	case CryptoBufferExceeded:
 		return "CRYPTO_BUFFER_EXCEEDED"
+	case AEADLimitReached:
+		return "AEAD_LIMIT_REACHED"
 	default:
 		if e.isCryptoError() {
 			return "CRYPTO_ERROR"
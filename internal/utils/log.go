@@ -6,6 +6,8 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
 // LogLevel of quic-go
@@ -29,6 +31,10 @@ type Logger interface {
 	SetLogLevel(LogLevel)
 	SetLogTimeFormat(format string)
 	WithPrefix(prefix string) Logger
+	// WithConnectionID returns a Logger that annotates all messages with the connection ID.
+	WithConnectionID(protocol.ConnectionID) Logger
+	// WithStreamID returns a Logger that annotates all messages with the stream ID.
+	WithStreamID(protocol.StreamID) Logger
 	Debug() bool
 
 	Errorf(format string, args ...interface{})
@@ -104,6 +110,14 @@ func (l *defaultLogger) WithPrefix(prefix string) Logger {
 	}
 }
 
+func (l *defaultLogger) WithConnectionID(connID protocol.ConnectionID) Logger {
+	return l.WithPrefix(connID.String())
+}
+
+func (l *defaultLogger) WithStreamID(id protocol.StreamID) Logger {
+	return l.WithPrefix(fmt.Sprintf("stream %d", id))
+}
+
 // Debug returns true if the log level is LogLevelDebug
 func (l *defaultLogger) Debug() bool {
 	return l.logLevel == LogLevelDebug
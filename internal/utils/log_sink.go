@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// A LogEntry is a single structured log message.
+type LogEntry struct {
+	Time  time.Time
+	Level LogLevel
+	// Component is the part of quic-go that logged the message, e.g. "server" or "h3 client".
+	// Nested components are separated by a "/".
+	Component string
+	// ConnectionID is nil if the message doesn't belong to a connection.
+	ConnectionID protocol.ConnectionID
+	// StreamID is only valid if HasStreamID is set.
+	StreamID    protocol.StreamID
+	HasStreamID bool
+	Message     string
+}
+
+// A LogSink receives structured log messages.
+type LogSink interface {
+	// Enabled says if messages of the given level should be logged for a component.
+	// It is called before the message is formatted.
+	Enabled(component string, level LogLevel) bool
+	Log(LogEntry)
+}
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelNothing:
+		return "nothing"
+	case LogLevelError:
+		return "error"
+	case LogLevelInfo:
+		return "info"
+	case LogLevelDebug:
+		return "debug"
+	default:
+		return fmt.Sprintf("unknown log level (%d)", uint8(l))
+	}
+}
+
+// NewLogger returns a Logger for the component that writes to the sink.
+// If sink is nil, the DefaultLogger is used.
+func NewLogger(sink LogSink, component string) Logger {
+	if sink == nil {
+		return DefaultLogger.WithPrefix(component)
+	}
+	return &sinkLogger{sink: sink, component: component}
+}
+
+type sinkLogger struct {
+	sink LogSink
+
+	component   string
+	connID      protocol.ConnectionID
+	streamID    protocol.StreamID
+	hasStreamID bool
+}
+
+var _ Logger = &sinkLogger{}
+
+// SetLogLevel is a no-op. The log level is controlled by the LogSink.
+func (l *sinkLogger) SetLogLevel(LogLevel) {}
+
+// SetLogTimeFormat is a no-op. Formatting is done by the LogSink.
+func (l *sinkLogger) SetLogTimeFormat(string) {}
+
+func (l *sinkLogger) WithPrefix(prefix string) Logger {
+	c := *l
+	if len(c.component) > 0 {
+		c.component += "/" + prefix
+	} else {
+		c.component = prefix
+	}
+	return &c
+}
+
+func (l *sinkLogger) WithConnectionID(connID protocol.ConnectionID) Logger {
+	c := *l
+	c.connID = connID
+	return &c
+}
+
+func (l *sinkLogger) WithStreamID(id protocol.StreamID) Logger {
+	c := *l
+	c.streamID = id
+	c.hasStreamID = true
+	return &c
+}
+
+func (l *sinkLogger) Debug() bool {
+	return l.sink.Enabled(l.component, LogLevelDebug)
+}
+
+func (l *sinkLogger) Debugf(format string, args ...interface{}) {
+	l.logMessage(LogLevelDebug, format, args...)
+}
+
+func (l *sinkLogger) Infof(format string, args ...interface{}) {
+	l.logMessage(LogLevelInfo, format, args...)
+}
+
+func (l *sinkLogger) Errorf(format string, args ...interface{}) {
+	l.logMessage(LogLevelError, format, args...)
+}
+
+func (l *sinkLogger) logMessage(level LogLevel, format string, args ...interface{}) {
+	if !l.sink.Enabled(l.component, level) {
+		return
+	}
+	l.sink.Log(LogEntry{
+		Time:         time.Now(),
+		Level:        level,
+		Component:    l.component,
+		ConnectionID: l.connID,
+		StreamID:     l.streamID,
+		HasStreamID:  l.hasStreamID,
+		Message:      fmt.Sprintf(format, args...),
+	})
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type recordingSink struct {
+	level   LogLevel
+	checked []string
+	entries []LogEntry
+}
+
+func (s *recordingSink) Enabled(component string, level LogLevel) bool {
+	s.checked = append(s.checked, component)
+	return level <= s.level
+}
+
+func (s *recordingSink) Log(e LogEntry) { s.entries = append(s.entries, e) }
+
+var _ = Describe("Log Sink", func() {
+	var sink *recordingSink
+
+	BeforeEach(func() {
+		sink = &recordingSink{level: LogLevelInfo}
+	})
+
+	It("uses the DefaultLogger if no sink is set", func() {
+		logger := NewLogger(nil, "client")
+		Expect(logger).To(BeAssignableToTypeOf(&defaultLogger{}))
+		Expect(logger.(*defaultLogger).prefix).To(Equal("client"))
+	})
+
+	It("logs the messages that the sink enables", func() {
+		logger := NewLogger(sink, "server")
+		logger.Debugf("debug")
+		logger.Infof("info %d", 42)
+		logger.Errorf("err")
+		Expect(sink.entries).To(HaveLen(2))
+		Expect(sink.entries[0].Level).To(Equal(LogLevelInfo))
+		Expect(sink.entries[0].Component).To(Equal("server"))
+		Expect(sink.entries[0].Message).To(Equal("info 42"))
+		Expect(sink.entries[0].Time).To(BeTemporally("~", time.Now(), time.Second))
+		Expect(sink.entries[0].ConnectionID).To(BeNil())
+		Expect(sink.entries[0].HasStreamID).To(BeFalse())
+		Expect(sink.entries[1].Level).To(Equal(LogLevelError))
+		Expect(sink.entries[1].Message).To(Equal("err"))
+	})
+
+	It("says whether debug is enabled", func() {
+		logger := NewLogger(sink, "server")
+		Expect(logger.Debug()).To(BeFalse())
+		sink.level = LogLevelDebug
+		Expect(logger.Debug()).To(BeTrue())
+	})
+
+	It("nests components", func() {
+		logger := NewLogger(sink, "h3 client").WithPrefix("parallel scheduler")
+		logger.Infof("info")
+		Expect(sink.checked).To(Equal([]string{"h3 client/parallel scheduler"}))
+		Expect(sink.entries[0].Component).To(Equal("h3 client/parallel scheduler"))
+	})
+
+	It("adds the connection ID and stream ID", func() {
+		connID := protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}
+		logger := NewLogger(sink, "server")
+		logger.WithConnectionID(connID).WithStreamID(8).Infof("info")
+		logger.Infof("without IDs")
+		Expect(sink.entries).To(HaveLen(2))
+		Expect(sink.entries[0].ConnectionID).To(Equal(connID))
+		Expect(sink.entries[0].StreamID).To(Equal(protocol.StreamID(8)))
+		Expect(sink.entries[0].HasStreamID).To(BeTrue())
+		Expect(sink.entries[1].ConnectionID).To(BeNil())
+		Expect(sink.entries[1].HasStreamID).To(BeFalse())
+	})
+
+	It("has a string representation of the log levels", func() {
+		Expect(LogLevelNothing.String()).To(Equal("nothing"))
+		Expect(LogLevelError.String()).To(Equal("error"))
+		Expect(LogLevelInfo.String()).To(Equal("info"))
+		Expect(LogLevelDebug.String()).To(Equal("debug"))
+	})
+})
@@ -6,6 +6,8 @@ import (
 	"os"
 	"time"
 
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -108,6 +110,12 @@ var _ = Describe("Log", func() {
 		Expect(b.String()).To(ContainSubstring("debug"))
 	})
 
+	It("adds the connection ID and stream ID", func() {
+		DefaultLogger.SetLogLevel(LogLevelDebug)
+		DefaultLogger.WithPrefix("prefix").WithConnectionID(protocol.ConnectionID{0xde, 0xad}).WithStreamID(4).Debugf("debug")
+		Expect(b.String()).To(ContainSubstring("prefix 0xdead stream 4 debug"))
+	})
+
 	Context("reading from env", func() {
 		BeforeEach(func() {
 			Expect(DefaultLogger.(*defaultLogger).logLevel).To(Equal(LogLevelNothing))
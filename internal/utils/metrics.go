@@ -0,0 +1,49 @@
+package utils
+
+// A Counter is a metric that only increases.
+type Counter interface {
+	Add(float64)
+}
+
+// A Gauge is a metric that can increase and decrease.
+type Gauge interface {
+	Set(float64)
+	Add(float64)
+}
+
+// A Histogram counts observations in buckets.
+type Histogram interface {
+	Observe(float64)
+}
+
+// Metrics creates the metrics that quic-go reports.
+// Calling one of the methods again with the same name returns the same metric.
+type Metrics interface {
+	Counter(name, help string) Counter
+	Gauge(name, help string) Gauge
+	// Histogram creates a histogram. The buckets are the upper bounds of the buckets, in increasing order.
+	Histogram(name, help string, buckets []float64) Histogram
+}
+
+// NopMetrics discards all values.
+var NopMetrics Metrics = nopMetrics{}
+
+// MetricsOrNop returns m, or NopMetrics if m is nil.
+func MetricsOrNop(m Metrics) Metrics {
+	if m == nil {
+		return NopMetrics
+	}
+	return m
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) Counter(string, string) Counter                { return nopMetric{} }
+func (nopMetrics) Gauge(string, string) Gauge                    { return nopMetric{} }
+func (nopMetrics) Histogram(string, string, []float64) Histogram { return nopMetric{} }
+
+type nopMetric struct{}
+
+func (nopMetric) Add(float64)     {}
+func (nopMetric) Set(float64)     {}
+func (nopMetric) Observe(float64) {}
@@ -16,6 +16,8 @@ var errInvalidAckRanges = errors.New("AckFrame: ACK frame contains invalid ACK r
 type AckFrame struct {
 	AckRanges []AckRange // has to be ordered. The highest ACK range goes first, the lowest ACK range goes last
 	DelayTime time.Duration
+
+	ECT0, ECT1, ECNCE uint64
 }
 
 // parseAckFrame reads an ACK frame
@@ -24,8 +26,11 @@ func parseAckFrame(r *bytes.Reader, ackDelayExponent uint8, _ protocol.VersionNu
 	if err != nil {
 		return nil, err
 	}
-	ecn := typeByte&0x1 > 0
+	return parseAckFrameBody(r, typeByte&0x1 > 0, ackDelayExponent)
+}
 
+// parseAckFrameBody parses the ACK frame, starting after the frame type
+func parseAckFrameBody(r *bytes.Reader, ecn bool, ackDelayExponent uint8) (*AckFrame, error) {
 	frame := &AckFrame{}
 
 	la, err := utils.ReadVarInt(r)
@@ -91,13 +96,23 @@ func parseAckFrame(r *bytes.Reader, ackDelayExponent uint8, _ protocol.VersionNu
 		return nil, errInvalidAckRanges
 	}
 
-	// parse (and skip) the ECN section
+	// parse the ECN section
 	if ecn {
-		for i := 0; i < 3; i++ {
-			if _, err := utils.ReadVarInt(r); err != nil {
-				return nil, err
-			}
+		ect0, err := utils.ReadVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		frame.ECT0 = ect0
+		ect1, err := utils.ReadVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		frame.ECT1 = ect1
+		ecnce, err := utils.ReadVarInt(r)
+		if err != nil {
+			return nil, err
 		}
+		frame.ECNCE = ecnce
 	}
 
 	return frame, nil
@@ -105,7 +120,18 @@ func parseAckFrame(r *bytes.Reader, ackDelayExponent uint8, _ protocol.VersionNu
 
 // Write writes an ACK frame.
 func (f *AckFrame) Write(b *bytes.Buffer, version protocol.VersionNumber) error {
-	b.WriteByte(0x2)
+	if f.HasECN() {
+		b.WriteByte(0x3)
+	} else {
+		b.WriteByte(0x2)
+	}
+	f.writeBody(b)
+	return nil
+}
+
+// writeBody writes the ACK frame, without the frame type
+func (f *AckFrame) writeBody(b *bytes.Buffer) {
+	hasECN := f.HasECN()
 	utils.WriteVarInt(b, uint64(f.LargestAcked()))
 	utils.WriteVarInt(b, encodeAckDelay(f.DelayTime))
 
@@ -122,7 +148,12 @@ func (f *AckFrame) Write(b *bytes.Buffer, version protocol.VersionNumber) error
 		utils.WriteVarInt(b, gap)
 		utils.WriteVarInt(b, len)
 	}
-	return nil
+
+	if hasECN {
+		utils.WriteVarInt(b, f.ECT0)
+		utils.WriteVarInt(b, f.ECT1)
+		utils.WriteVarInt(b, f.ECNCE)
+	}
 }
 
 // Length of a written frame
@@ -141,6 +172,9 @@ func (f *AckFrame) Length(version protocol.VersionNumber) protocol.ByteCount {
 		length += utils.VarIntLen(gap)
 		length += utils.VarIntLen(len)
 	}
+	if f.HasECN() {
+		length += utils.VarIntLen(f.ECT0) + utils.VarIntLen(f.ECT1) + utils.VarIntLen(f.ECNCE)
+	}
 	return length
 }
 
@@ -170,6 +204,12 @@ func (f *AckFrame) encodeAckRange(i int) (uint64 /* gap */, uint64 /* length */)
 		uint64(f.AckRanges[i].Largest - f.AckRanges[i].Smallest)
 }
 
+// HasECN says if this frame contains ECN counts.
+// If so, it is sent as an ACK_ECN frame.
+func (f *AckFrame) HasECN() bool {
+	return f.ECT0 > 0 || f.ECT1 > 0 || f.ECNCE > 0
+}
+
 // HasMissingRanges returns if this frame reports any missing packets
 func (f *AckFrame) HasMissingRanges() bool {
 	return len(f.AckRanges) > 1
@@ -0,0 +1,56 @@
+package wire
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// The frame types of the ACK_MP frame of the (experimental) multipath extension.
+const (
+	ackMPFrameType    = 0xbaba00
+	ackMPECNFrameType = 0xbaba01
+)
+
+// An AckMPFrame is an ACK_MP frame.
+// It is used by the multipath extension to acknowledge packets sent on the path with the path identifier PathID.
+type AckMPFrame struct {
+	PathID uint64
+	AckFrame
+}
+
+// parseAckMPFrame reads an ACK_MP frame
+func parseAckMPFrame(r *bytes.Reader, ackDelayExponent uint8, _ protocol.VersionNumber) (*AckMPFrame, error) {
+	frameType, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	pathID, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	ack, err := parseAckFrameBody(r, frameType == ackMPECNFrameType, ackDelayExponent)
+	if err != nil {
+		return nil, err
+	}
+	return &AckMPFrame{PathID: pathID, AckFrame: *ack}, nil
+}
+
+// Write writes an ACK_MP frame.
+func (f *AckMPFrame) Write(b *bytes.Buffer, _ protocol.VersionNumber) error {
+	if f.HasECN() {
+		utils.WriteVarInt(b, ackMPECNFrameType)
+	} else {
+		utils.WriteVarInt(b, ackMPFrameType)
+	}
+	utils.WriteVarInt(b, f.PathID)
+	f.writeBody(b)
+	return nil
+}
+
+// Length of a written frame
+func (f *AckMPFrame) Length(version protocol.VersionNumber) protocol.ByteCount {
+	// The ACK frame uses a single byte for the frame type.
+	return utils.VarIntLen(ackMPFrameType) + utils.VarIntLen(f.PathID) + f.AckFrame.Length(version) - 1
+}
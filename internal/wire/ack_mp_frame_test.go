@@ -0,0 +1,108 @@
+package wire
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ACK_MP frame", func() {
+	Context("parsing", func() {
+		It("parses an ACK_MP frame", func() {
+			data := encodeVarInt(0xbaba00)
+			data = append(data, encodeVarInt(3)...)   // path ID
+			data = append(data, encodeVarInt(100)...) // largest acked
+			data = append(data, encodeVarInt(0)...)   // delay
+			data = append(data, encodeVarInt(0)...)   // num blocks
+			data = append(data, encodeVarInt(10)...)  // first ack block
+			b := bytes.NewReader(data)
+			frame, err := parseAckMPFrame(b, protocol.AckDelayExponent, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.PathID).To(BeEquivalentTo(3))
+			Expect(frame.LargestAcked()).To(Equal(protocol.PacketNumber(100)))
+			Expect(frame.LowestAcked()).To(Equal(protocol.PacketNumber(90)))
+			Expect(frame.HasECN()).To(BeFalse())
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("parses an ACK_MP frame with ECN counts", func() {
+			data := encodeVarInt(0xbaba01)
+			data = append(data, encodeVarInt(1)...)   // path ID
+			data = append(data, encodeVarInt(100)...) // largest acked
+			data = append(data, encodeVarInt(0)...)   // delay
+			data = append(data, encodeVarInt(0)...)   // num blocks
+			data = append(data, encodeVarInt(10)...)  // first ack block
+			data = append(data, encodeVarInt(0x42)...)
+			data = append(data, encodeVarInt(0x12345)...)
+			data = append(data, encodeVarInt(0x12345678)...)
+			b := bytes.NewReader(data)
+			frame, err := parseAckMPFrame(b, protocol.AckDelayExponent, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.PathID).To(BeEquivalentTo(1))
+			Expect(frame.LargestAcked()).To(Equal(protocol.PacketNumber(100)))
+			Expect(frame.ECT0).To(BeEquivalentTo(0x42))
+			Expect(frame.ECT1).To(BeEquivalentTo(0x12345))
+			Expect(frame.ECNCE).To(BeEquivalentTo(0x12345678))
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("errors on EOF", func() {
+			data := encodeVarInt(0xbaba00)
+			data = append(data, encodeVarInt(3)...)    // path ID
+			data = append(data, encodeVarInt(1000)...) // largest acked
+			data = append(data, encodeVarInt(0)...)    // delay
+			data = append(data, encodeVarInt(1)...)    // num blocks
+			data = append(data, encodeVarInt(100)...)  // first ack block
+			data = append(data, encodeVarInt(98)...)   // gap
+			data = append(data, encodeVarInt(50)...)   // ack block
+			_, err := parseAckMPFrame(bytes.NewReader(data), protocol.AckDelayExponent, versionIETFFrames)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := parseAckMPFrame(bytes.NewReader(data[0:i]), protocol.AckDelayExponent, versionIETFFrames)
+				Expect(err).To(MatchError(io.EOF))
+			}
+		})
+	})
+
+	Context("writing", func() {
+		It("writes an ACK_MP frame", func() {
+			f := &AckMPFrame{
+				PathID: 2,
+				AckFrame: AckFrame{
+					AckRanges: []AckRange{{Smallest: 100, Largest: 1000}},
+					DelayTime: 18 * time.Millisecond,
+				},
+			}
+			b := &bytes.Buffer{}
+			Expect(f.Write(b, versionIETFFrames)).To(Succeed())
+			Expect(f.Length(versionIETFFrames)).To(BeEquivalentTo(b.Len()))
+			Expect(b.Bytes()[:4]).To(Equal(encodeVarInt(0xbaba00)))
+			frame, err := parseAckMPFrame(bytes.NewReader(b.Bytes()), protocol.AckDelayExponent, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(f))
+		})
+
+		It("writes an ACK_MP frame with ECN counts", func() {
+			f := &AckMPFrame{
+				PathID: 1,
+				AckFrame: AckFrame{
+					AckRanges: []AckRange{{Smallest: 10, Largest: 20}, {Smallest: 1, Largest: 5}},
+					ECT0:      13,
+					ECT1:      37,
+					ECNCE:     12345,
+				},
+			}
+			b := &bytes.Buffer{}
+			Expect(f.Write(b, versionIETFFrames)).To(Succeed())
+			Expect(f.Length(versionIETFFrames)).To(BeEquivalentTo(b.Len()))
+			Expect(b.Bytes()[:4]).To(Equal(encodeVarInt(0xbaba01)))
+			frame, err := parseAckMPFrame(bytes.NewReader(b.Bytes()), protocol.AckDelayExponent, versionIETFFrames)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(f))
+		})
+	})
+})
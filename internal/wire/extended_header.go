@@ -148,8 +148,11 @@ func (h *ExtendedHeader) writeLongHeader(b *bytes.Buffer, _ protocol.VersionNumb
 
 	switch h.Type {
 	case protocol.PacketTypeRetry:
-		b.WriteByte(uint8(h.OrigDestConnectionID.Len()))
-		b.Write(h.OrigDestConnectionID.Bytes())
+		// For newer versions, the caller appends the Retry Integrity Tag.
+		if !h.Version.UsesRetryIntegrityTag() {
+			b.WriteByte(uint8(h.OrigDestConnectionID.Len()))
+			b.Write(h.OrigDestConnectionID.Bytes())
+		}
 		b.Write(h.Token)
 		return nil
 	case protocol.PacketTypeInitial:
@@ -118,6 +118,24 @@ var _ = Describe("Header", func() {
 				Expect(buf.Bytes()).To(Equal(expected))
 			})
 
+			It("writes a Retry packet without the Orig Destination Connection ID, for QUIC v1", func() {
+				Expect((&ExtendedHeader{Header: Header{
+					IsLongHeader:         true,
+					Version:              protocol.Version1,
+					Type:                 protocol.PacketTypeRetry,
+					Token:                []byte("foobar"),
+					OrigDestConnectionID: protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9},
+				}}).Write(buf, protocol.Version1)).To(Succeed())
+				expected := []byte{
+					0xc0 | 0x3<<4,
+					0x0, 0x0, 0x0, 0x1, // version number
+					0x0, // dest connection ID length
+					0x0, // src connection ID length
+				}
+				expected = append(expected, []byte("foobar")...)
+				Expect(buf.Bytes()).To(Equal(expected))
+			})
+
 			It("refuses to write a Retry packet with an invalid Orig Destination Connection ID length", func() {
 				err := (&ExtendedHeader{Header: Header{
 					IsLongHeader:         true,
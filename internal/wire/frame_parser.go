@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 type frameParser struct {
@@ -31,6 +33,14 @@ func (p *frameParser) ParseNext(r *bytes.Reader, encLevel protocol.EncryptionLev
 		}
 		r.UnreadByte()
 
+		// Frame types that are encoded in more than one byte are only used by extensions.
+		if typeByte&0xc0 != 0 {
+			frameType, f, err := p.parseExtensionFrame(r, typeByte, encLevel)
+			if err != nil {
+				return nil, qerr.ErrorWithFrameType(qerr.FrameEncodingError, frameType, err.Error())
+			}
+			return f, nil
+		}
 		f, err := p.parseFrame(r, typeByte, encLevel)
 		if err != nil {
 			return nil, qerr.ErrorWithFrameType(qerr.FrameEncodingError, uint64(typeByte), err.Error())
@@ -85,6 +95,12 @@ func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protoc
 			frame, err = parsePathResponseFrame(r, p.version)
 		case 0x1c, 0x1d:
 			frame, err = parseConnectionCloseFrame(r, p.version)
+		case 0x1e:
+			if !p.version.UsesHandshakeDoneFrame() {
+				err = errors.New("unknown frame type")
+				break
+			}
+			frame, err = parseHandshakeDoneFrame(r, p.version)
 		default:
 			err = errors.New("unknown frame type")
 		}
@@ -98,6 +114,30 @@ func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protoc
 	return frame, nil
 }
 
+func (p *frameParser) parseExtensionFrame(r *bytes.Reader, typeByte byte, encLevel protocol.EncryptionLevel) (uint64 /* frame type */, Frame, error) {
+	startLen := r.Len()
+	frameType, err := utils.ReadVarInt(r)
+	if err != nil {
+		return uint64(typeByte), nil, errors.New("unknown frame type")
+	}
+	r.Seek(int64(r.Len()-startLen), io.SeekCurrent)
+
+	var frame Frame
+	switch frameType {
+	case ackMPFrameType, ackMPECNFrameType:
+		frame, err = parseAckMPFrame(r, p.ackDelayExponent, p.version)
+	default:
+		err = errors.New("unknown frame type")
+	}
+	if err != nil {
+		return frameType, nil, err
+	}
+	if !p.isAllowedAtEncLevel(frame, encLevel) {
+		return frameType, nil, fmt.Errorf("%s not allowed at encryption level %s", reflect.TypeOf(frame).Elem().Name(), encLevel)
+	}
+	return frameType, frame, nil
+}
+
 func (p *frameParser) isAllowedAtEncLevel(f Frame, encLevel protocol.EncryptionLevel) bool {
 	switch encLevel {
 	case protocol.EncryptionInitial, protocol.EncryptionHandshake:
@@ -105,6 +145,13 @@ func (p *frameParser) isAllowedAtEncLevel(f Frame, encLevel protocol.EncryptionL
 		case *CryptoFrame, *AckFrame, *ConnectionCloseFrame, *PingFrame:
 			return true
 		}
+	case protocol.Encryption0RTT:
+		switch f.(type) {
+		case *CryptoFrame, *AckFrame, *AckMPFrame, *NewTokenFrame, *HandshakeDoneFrame, *PathResponseFrame, *RetireConnectionIDFrame:
+			return false
+		default:
+			return true
+		}
 	case protocol.Encryption1RTT:
 		return true
 	}
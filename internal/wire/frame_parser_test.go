@@ -80,6 +80,27 @@ var _ = Describe("Frame parsing", func() {
 		Expect(frame.(*AckFrame).DelayTime).To(Equal(time.Second))
 	})
 
+	It("unpacks ACK_MP frames", func() {
+		f := &AckMPFrame{
+			PathID:   2,
+			AckFrame: AckFrame{AckRanges: []AckRange{{Smallest: 1, Largest: 0x13}}},
+		}
+		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
+		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(f))
+	})
+
+	It("rejects ACK_MP frames in Handshake packets", func() {
+		f := &AckMPFrame{
+			PathID:   2,
+			AckFrame: AckFrame{AckRanges: []AckRange{{Smallest: 1, Largest: 0x13}}},
+		}
+		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
+		_, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.EncryptionHandshake)
+		Expect(err).To(MatchError("FRAME_ENCODING_ERROR (frame type: 0xbaba00): AckMPFrame not allowed at encryption level Handshake"))
+	})
+
 	It("unpacks RESET_STREAM frames", func() {
 		f := &ResetStreamFrame{
 			StreamID:   0xdeadbeef,
@@ -271,11 +292,37 @@ var _ = Describe("Frame parsing", func() {
 		Expect(frame).To(Equal(f))
 	})
 
+	It("unpacks HANDSHAKE_DONE frames", func() {
+		parser = NewFrameParser(protocol.Version1)
+		b := &bytes.Buffer{}
+		Expect((&HandshakeDoneFrame{}).Write(b, protocol.Version1)).To(Succeed())
+		frame, err := parser.ParseNext(bytes.NewReader(b.Bytes()), protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(&HandshakeDoneFrame{}))
+	})
+
+	It("rejects HANDSHAKE_DONE frames for versions that don't use them", func() {
+		parser = NewFrameParser(protocol.VersionMilestone0_14)
+		_, err := parser.ParseNext(bytes.NewReader([]byte{0x1e}), protocol.Encryption1RTT)
+		Expect(err).To(MatchError("FRAME_ENCODING_ERROR (frame type: 0x1e): unknown frame type"))
+	})
+
+	It("rejects HANDSHAKE_DONE frames in Handshake packets", func() {
+		parser = NewFrameParser(protocol.Version1)
+		_, err := parser.ParseNext(bytes.NewReader([]byte{0x1e}), protocol.EncryptionHandshake)
+		Expect(err).To(HaveOccurred())
+	})
+
 	It("errors on invalid type", func() {
 		_, err := parser.ParseNext(bytes.NewReader([]byte{0x42}), protocol.Encryption1RTT)
 		Expect(err).To(MatchError("FRAME_ENCODING_ERROR (frame type: 0x42): unknown frame type"))
 	})
 
+	It("errors on invalid multi-byte types", func() {
+		_, err := parser.ParseNext(bytes.NewReader(encodeVarInt(0x4242)), protocol.Encryption1RTT)
+		Expect(err).To(MatchError("FRAME_ENCODING_ERROR (frame type: 0x4242): unknown frame type"))
+	})
+
 	It("errors on invalid frames", func() {
 		f := &MaxStreamDataFrame{
 			StreamID:   0x1337,
@@ -347,6 +394,19 @@ var _ = Describe("Frame parsing", func() {
 			}
 		})
 
+		It("rejects ACK, CRYPTO, NEW_TOKEN, PATH_RESPONSE and RETIRE_CONNECTION_ID frames in 0-RTT packets", func() {
+			for i, b := range framesSerialized {
+				_, err := parser.ParseNext(bytes.NewReader(b), protocol.Encryption0RTT)
+				switch frames[i].(type) {
+				case *AckFrame, *CryptoFrame, *NewTokenFrame, *PathResponseFrame, *RetireConnectionIDFrame:
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("not allowed at encryption level 0-RTT"))
+				default:
+					Expect(err).ToNot(HaveOccurred())
+				}
+			}
+		})
+
 		It("accepts all frame types in 1-RTT packets", func() {
 			for _, b := range framesSerialized {
 				_, err := parser.ParseNext(bytes.NewReader(b), protocol.Encryption1RTT)
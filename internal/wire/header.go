@@ -44,6 +44,10 @@ func IsVersionNegotiationPacket(b []byte) bool {
 
 var errUnsupportedVersion = errors.New("unsupported version")
 
+// RetryIntegrityTagLen is the length of the integrity tag appended to Retry packets
+// (for draft-25 and later).
+const RetryIntegrityTagLen = 16
+
 // The Header is the version independent part of the header
 type Header struct {
 	IsLongHeader bool
@@ -176,19 +180,33 @@ func (h *Header) parseLongHeader(b *bytes.Reader) error {
 	}
 
 	if h.Type == protocol.PacketTypeRetry {
-		origDestConnIDLen, err := b.ReadByte()
-		if err != nil {
-			return err
+		if !h.Version.UsesRetryIntegrityTag() {
+			origDestConnIDLen, err := b.ReadByte()
+			if err != nil {
+				return err
+			}
+			h.OrigDestConnectionID, err = protocol.ReadConnectionID(b, int(origDestConnIDLen))
+			if err != nil {
+				return err
+			}
+			h.Token = make([]byte, b.Len())
+			if _, err := io.ReadFull(b, h.Token); err != nil {
+				return err
+			}
+			return nil
 		}
-		h.OrigDestConnectionID, err = protocol.ReadConnectionID(b, int(origDestConnIDLen))
-		if err != nil {
-			return err
+		// The token is followed by the Retry Integrity Tag.
+		// The tag is verified by the session, using the raw packet.
+		tokenLen := b.Len() - RetryIntegrityTagLen
+		if tokenLen < 0 {
+			return io.EOF
 		}
-		h.Token = make([]byte, b.Len())
+		h.Token = make([]byte, tokenLen)
 		if _, err := io.ReadFull(b, h.Token); err != nil {
 			return err
 		}
-		return nil
+		_, err := b.Seek(RetryIntegrityTagLen, io.SeekCurrent)
+		return err
 	}
 
 	if h.Type == protocol.PacketTypeInitial {
@@ -283,6 +283,31 @@ var _ = Describe("Header Parsing", func() {
 			Expect(rest).To(BeEmpty())
 		})
 
+		It("parses a Retry packet with an integrity tag", func() {
+			tag := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+			data := []byte{0xc0 | 0x3<<4 | (10 - 3) /* connection ID length */}
+			data = appendVersion(data, protocol.Version1)
+			data = append(data, []byte{0x0, 0x0}...)                     // dest and src conn ID lengths
+			data = append(data, []byte{'f', 'o', 'o', 'b', 'a', 'r'}...) // token
+			data = append(data, tag...)
+			hdr, pdata, rest, err := ParsePacket(data, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hdr.Type).To(Equal(protocol.PacketTypeRetry))
+			Expect(hdr.OrigDestConnectionID).To(BeEmpty())
+			Expect(hdr.Token).To(Equal([]byte("foobar")))
+			Expect(pdata).To(Equal(data))
+			Expect(rest).To(BeEmpty())
+		})
+
+		It("errors if a Retry packet is too short to contain the integrity tag", func() {
+			data := []byte{0xc0 | 0x3<<4}
+			data = appendVersion(data, protocol.Version1)
+			data = append(data, []byte{0x0, 0x0}...) // dest and src conn ID lengths
+			data = append(data, make([]byte, 15)...)
+			_, _, _, err := ParsePacket(data, 0)
+			Expect(err).To(MatchError(io.EOF))
+		})
+
 		It("errors if the token length is too large", func() {
 			data := []byte{0xc0 ^ 0x1}
 			data = appendVersion(data, versionIETFFrames)
@@ -29,10 +29,12 @@ func LogFrame(logger utils.Logger, frame Frame, sent bool) {
 			for i, r := range f.AckRanges {
 				ackRanges[i] = fmt.Sprintf("{Largest: %#x, Smallest: %#x}", r.Largest, r.Smallest)
 			}
-			logger.Debugf("\t%s &wire.AckFrame{LargestAcked: %#x, LowestAcked: %#x, AckRanges: {%s}, DelayTime: %s}", dir, f.LargestAcked(), f.LowestAcked(), strings.Join(ackRanges, ", "), f.DelayTime.String())
+			logger.Debugf("\t%s &wire.AckFrame{LargestAcked: %#x, LowestAcked: %#x, AckRanges: {%s}, DelayTime: %s%s}", dir, f.LargestAcked(), f.LowestAcked(), strings.Join(ackRanges, ", "), f.DelayTime.String(), ecnString(f))
 		} else {
-			logger.Debugf("\t%s &wire.AckFrame{LargestAcked: %#x, LowestAcked: %#x, DelayTime: %s}", dir, f.LargestAcked(), f.LowestAcked(), f.DelayTime.String())
+			logger.Debugf("\t%s &wire.AckFrame{LargestAcked: %#x, LowestAcked: %#x, DelayTime: %s%s}", dir, f.LargestAcked(), f.LowestAcked(), f.DelayTime.String(), ecnString(f))
 		}
+	case *AckMPFrame:
+		logger.Debugf("\t%s &wire.AckMPFrame{PathID: %d, LargestAcked: %#x, LowestAcked: %#x, DelayTime: %s%s}", dir, f.PathID, f.LargestAcked(), f.LowestAcked(), f.DelayTime.String(), ecnString(&f.AckFrame))
 	case *MaxStreamsFrame:
 		switch f.Type {
 		case protocol.StreamTypeUni:
@@ -55,3 +57,10 @@ func LogFrame(logger utils.Logger, frame Frame, sent bool) {
 		logger.Debugf("\t%s %#v", dir, frame)
 	}
 }
+
+func ecnString(f *AckFrame) string {
+	if !f.HasECN() {
+		return ""
+	}
+	return fmt.Sprintf(", ECT0: %d, ECT1: %d, CE: %d", f.ECT0, f.ECT1, f.ECNCE)
+}
@@ -87,6 +87,15 @@ var _ = Describe("Frame logging", func() {
 		Expect(buf.String()).To(ContainSubstring("\t<- &wire.AckFrame{LargestAcked: 0x8, LowestAcked: 0x2, AckRanges: {{Largest: 0x8, Smallest: 0x5}, {Largest: 0x3, Smallest: 0x2}}, DelayTime: 12ms}\n"))
 	})
 
+	It("logs ACK_MP frames", func() {
+		frame := &AckMPFrame{
+			PathID:   3,
+			AckFrame: AckFrame{AckRanges: []AckRange{{Smallest: 0x42, Largest: 0x1337}}},
+		}
+		LogFrame(logger, frame, true)
+		Expect(buf.String()).To(ContainSubstring("\t-> &wire.AckMPFrame{PathID: 3, LargestAcked: 0x1337, LowestAcked: 0x42, DelayTime: 0s}\n"))
+	})
+
 	It("logs MAX_STREAMS frames", func() {
 		frame := &MaxStreamsFrame{
 			Type:         protocol.StreamTypeBidi,
@@ -0,0 +1,132 @@
+package quic
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// A LogLevel is the severity of a log message.
+type LogLevel = utils.LogLevel
+
+const (
+	// LogLevelNothing disables logging.
+	LogLevelNothing = utils.LogLevelNothing
+	// LogLevelError enables error logs.
+	LogLevelError = utils.LogLevelError
+	// LogLevelInfo enables info logs (e.g. packets).
+	LogLevelInfo = utils.LogLevelInfo
+	// LogLevelDebug enables debug logs (e.g. packet contents).
+	LogLevelDebug = utils.LogLevelDebug
+)
+
+// A LogEntry is a single log message.
+// It carries the component that logged it, and the connection and stream it belongs to (if any).
+type LogEntry = utils.LogEntry
+
+// A Logger receives the log messages of quic-go and of the http3 package.
+// Messages are tagged with the component that logged them:
+// "client", "server", "h3 client", "h3 server" and "memory storage".
+// Nested components are separated by a "/", e.g. "server/scheduler" for the response scheduler of a connection,
+// or "h3 client/parallel scheduler" for the request scheduler of an HTTP/3 client.
+// A Logger must be safe for concurrent use.
+type Logger = utils.LogSink
+
+// A WriterLogger is a Logger that writes log messages to an io.Writer, one message per line.
+// The log level can be set for every component.
+type WriterLogger struct {
+	mutex sync.Mutex
+
+	w          io.Writer
+	level      LogLevel
+	levels     map[string]LogLevel
+	timeFormat string
+}
+
+var _ Logger = &WriterLogger{}
+
+// NewWriterLogger creates a new WriterLogger.
+// Components that don't have their own log level use the given level.
+func NewWriterLogger(w io.Writer, level LogLevel) *WriterLogger {
+	return &WriterLogger{
+		w:          w,
+		level:      level,
+		levels:     make(map[string]LogLevel),
+		timeFormat: time.RFC3339Nano,
+	}
+}
+
+// SetLevel sets the log level used for components that don't have their own log level.
+func (l *WriterLogger) SetLevel(level LogLevel) {
+	l.mutex.Lock()
+	l.level = level
+	l.mutex.Unlock()
+}
+
+// SetComponentLevel sets the log level of a component.
+// It also applies to all nested components, unless they have their own log level.
+func (l *WriterLogger) SetComponentLevel(component string, level LogLevel) {
+	l.mutex.Lock()
+	l.levels[component] = level
+	l.mutex.Unlock()
+}
+
+// SetTimeFormat sets the format of the timestamp.
+// An empty string disables the logging of timestamps.
+func (l *WriterLogger) SetTimeFormat(format string) {
+	l.mutex.Lock()
+	l.timeFormat = format
+	l.mutex.Unlock()
+}
+
+// Enabled says if messages of the given level are logged for a component.
+func (l *WriterLogger) Enabled(component string, level LogLevel) bool {
+	if level == LogLevelNothing {
+		return false
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return level <= l.levelForComponent(component)
+}
+
+func (l *WriterLogger) levelForComponent(component string) LogLevel {
+	for {
+		if level, ok := l.levels[component]; ok {
+			return level
+		}
+		i := strings.LastIndex(component, "/")
+		if i < 0 {
+			return l.level
+		}
+		component = component[:i]
+	}
+}
+
+// Log writes a log message.
+func (l *WriterLogger) Log(e LogEntry) {
+	var b strings.Builder
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.timeFormat) > 0 {
+		b.WriteString(e.Time.Format(l.timeFormat))
+		b.WriteByte(' ')
+	}
+	b.WriteString(strings.ToUpper(e.Level.String()))
+	if len(e.Component) > 0 {
+		fmt.Fprintf(&b, " [%s]", e.Component)
+	}
+	if e.ConnectionID != nil {
+		fmt.Fprintf(&b, " conn=%s", e.ConnectionID)
+	}
+	if e.HasStreamID {
+		fmt.Fprintf(&b, " stream=%d", e.StreamID)
+	}
+	b.WriteByte(' ')
+	b.WriteString(strings.TrimSuffix(e.Message, "\n"))
+	b.WriteByte('\n')
+	io.WriteString(l.w, b.String()) //nolint:errcheck
+}
@@ -0,0 +1,81 @@
+package quic
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Writer Logger", func() {
+	var (
+		b      *bytes.Buffer
+		logger *WriterLogger
+	)
+
+	BeforeEach(func() {
+		b = &bytes.Buffer{}
+		logger = NewWriterLogger(b, LogLevelInfo)
+		logger.SetTimeFormat("")
+	})
+
+	It("uses the default log level", func() {
+		Expect(logger.Enabled("server", LogLevelError)).To(BeTrue())
+		Expect(logger.Enabled("server", LogLevelInfo)).To(BeTrue())
+		Expect(logger.Enabled("server", LogLevelDebug)).To(BeFalse())
+		Expect(logger.Enabled("server", LogLevelNothing)).To(BeFalse())
+		logger.SetLevel(LogLevelDebug)
+		Expect(logger.Enabled("server", LogLevelDebug)).To(BeTrue())
+	})
+
+	It("sets the log level per component", func() {
+		logger.SetComponentLevel("h3 client", LogLevelDebug)
+		logger.SetComponentLevel("server", LogLevelNothing)
+		Expect(logger.Enabled("h3 client", LogLevelDebug)).To(BeTrue())
+		Expect(logger.Enabled("server", LogLevelError)).To(BeFalse())
+		Expect(logger.Enabled("client", LogLevelInfo)).To(BeTrue())
+		Expect(logger.Enabled("client", LogLevelDebug)).To(BeFalse())
+	})
+
+	It("applies the log level of a component to nested components", func() {
+		logger.SetComponentLevel("h3 client", LogLevelDebug)
+		logger.SetComponentLevel("h3 client/parallel scheduler", LogLevelError)
+		Expect(logger.Enabled("h3 client/single connection scheduler", LogLevelDebug)).To(BeTrue())
+		Expect(logger.Enabled("h3 client/parallel scheduler", LogLevelInfo)).To(BeFalse())
+		Expect(logger.Enabled("h3 client/parallel scheduler", LogLevelError)).To(BeTrue())
+		Expect(logger.Enabled("h3 clients", LogLevelDebug)).To(BeFalse())
+	})
+
+	It("writes log messages", func() {
+		logger.Log(LogEntry{
+			Level:        LogLevelInfo,
+			Component:    "server",
+			ConnectionID: protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad},
+			StreamID:     4,
+			HasStreamID:  true,
+			Message:      "foo\n",
+		})
+		logger.Log(LogEntry{Level: LogLevelError, Component: "client", Message: "bar"})
+		Expect(b.String()).To(Equal("INFO [server] conn=0xdecafbad stream=4 foo\nERROR [client] bar\n"))
+	})
+
+	It("adds a timestamp", func() {
+		logger.SetTimeFormat(time.RFC3339)
+		now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		logger.Log(LogEntry{Time: now, Level: LogLevelDebug, Message: "foo"})
+		Expect(b.String()).To(Equal("2020-01-02T03:04:05Z DEBUG foo\n"))
+	})
+
+	It("is used for the log messages of a server", func() {
+		config := populateServerConfig(&Config{Logger: logger})
+		Expect(config.Logger).To(Equal(logger))
+	})
+
+	It("is used for the log messages of a client", func() {
+		config := populateClientConfig(&Config{Logger: logger}, false)
+		Expect(config.Logger).To(Equal(logger))
+	})
+})
@@ -6,7 +6,7 @@ import (
   "strings"
   "sync"
 
-  "github.com/google/logger"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/order"
 )
 
@@ -52,7 +52,7 @@ func GetMimeType(fileName string) string {
   if mtype == "" {
     // 无法从系统库中查找到该资源对应的 MIME 类型
     mtype = "unknown"
-    logger.Infof("warning: unknown mimetype for url = %v\n", fileName)
+    storage.logger.Infof("unknown mimetype for url = %v", fileName)
   } else {
     // 针对可以从系统库中获取到 MIME 类型的资源，检查系统库给的 MIME 类型中是否包含
     // 类似于 “text/css; charset=utf-8” 的字符集信息
@@ -80,10 +80,13 @@ type MemoryStorage struct {
 
   // 没有指定顺序的 stream 采用 RR 算法交替发送数据
 	UnmanagedStreams []StreamID
+
+  logger utils.Logger
 }
 
 // 全局唯一的 memory storage 实例
 var storage = MemoryStorage{
+  logger:                  utils.DefaultLogger.WithPrefix("memory storage"),
   ManagedStreams:          make([]*StreamControlBlock, 0),
   URLToManagedStreamIndex: make(map[string]int),
   IDToManagedStreamIndex:  make(map[StreamID]int),
@@ -96,15 +99,22 @@ func InitMemoryStorage() {
   storage.ManagedStreams = append(storage.ManagedStreams,
     NewStreamControlBlock(-1, "", false, nil, false)) // 先把控制 stream 压入队列
   storage.URLToManagedStreamIndex[""] = 0 // 此 stream 位于队伍首位，以最高优先级传输
-  logger.Infof("url = <%v> added to index <%v>", "", 0)
+  storage.logger.Debugf("url = <%v> added to index <%v>", "", 0)
   // 加载其余的 url 队列中
   for index, url := range order.YoutubeNetworkList {
     storage.ManagedStreams =
       append(storage.ManagedStreams, NewStreamControlBlock(-1, url, false, nil, false))
     storage.URLToManagedStreamIndex[url] = index + 1
-    logger.Infof("url = <%v> added to index <%v>", url, index+1)
+    storage.logger.Debugf("url = <%v> added to index <%v>", url, index+1)
   }
-  logger.Infof("memory storage inited")
+  storage.logger.Infof("memory storage inited")
+}
+
+// SetLogger 设置 memory storage 输出日志所使用的 Logger，为 nil 时使用默认日志
+func (ms *MemoryStorage) SetLogger(l Logger) {
+  ms.mutex.Lock()
+  ms.logger = utils.NewLogger(l, "memory storage")
+  ms.mutex.Unlock()
 }
 
 // GetMemoryStorage 返回指向 memory storage 全局变量的指针
@@ -185,16 +195,16 @@ func (ms *MemoryStorage) BindURLAndStreamID(url string, id StreamID) {
   index, ok := ms.URLToManagedStreamIndex[url]
   if !ok {
 		// 不会向 IDToManagedStreamIndex 中添加 unmanaged stream 的条目
-    logger.Infof("binding an unmanaged stream with url <%v> to id <%v>", url, id)
+    ms.logger.WithStreamID(id).Debugf("binding an unmanaged stream with url <%v>", url)
     return
   }
   _, ok = ms.IDToManagedStreamIndex[id]
   if ok {
-    logger.Errorf("given id already exists, id = <%v>, url = <%v>", id, url)
+    ms.logger.WithStreamID(id).Errorf("given id already exists, url = <%v>", url)
     return
   }
 	ms.IDToManagedStreamIndex[id] = index
 	// 绑定 StreamID 和 URL 的关系
 	ms.ManagedStreams[index].StreamID = id
-  logger.Infof("binding stream id <%v> to url <%v>", id, url)
+  ms.logger.WithStreamID(id).Debugf("binding stream to url <%v>", url)
 }
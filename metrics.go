@@ -0,0 +1,264 @@
+package quic
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// A Counter is a metric that only increases.
+type Counter = utils.Counter
+
+// A Gauge is a metric that can increase and decrease.
+type Gauge = utils.Gauge
+
+// A Histogram counts observations in buckets.
+type Histogram = utils.Histogram
+
+// Metrics receives the metrics of quic-go and of the http3 package.
+// Calling one of the methods again with the same name must return the same metric,
+// since metrics are shared between all sessions of a client or server.
+// Metrics must be safe for concurrent use.
+type Metrics = utils.Metrics
+
+// A MetricsRegistry is a Metrics that keeps the metrics in memory.
+// It is an http.Handler that serves the metrics in the Prometheus text format.
+type MetricsRegistry struct {
+	mutex   sync.Mutex
+	metrics map[string]registeredMetric
+}
+
+var _ Metrics = &MetricsRegistry{}
+var _ http.Handler = &MetricsRegistry{}
+
+type registeredMetric interface {
+	metricType() string
+	metricHelp() string
+	write(w io.Writer, name string)
+}
+
+// NewMetricsRegistry creates a new MetricsRegistry.
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{metrics: make(map[string]registeredMetric)}
+}
+
+// Counter returns the counter with the given name, creating it if necessary.
+// It panics if a different kind of metric was registered with that name.
+func (r *MetricsRegistry) Counter(name, help string) Counter {
+	return r.getOrRegister(name, func() registeredMetric { return &counter{help: help} }).(*counter)
+}
+
+// Gauge returns the gauge with the given name, creating it if necessary.
+// It panics if a different kind of metric was registered with that name.
+func (r *MetricsRegistry) Gauge(name, help string) Gauge {
+	return r.getOrRegister(name, func() registeredMetric { return &gauge{help: help} }).(*gauge)
+}
+
+// Histogram returns the histogram with the given name, creating it if necessary.
+// The buckets are the upper bounds of the buckets, in increasing order.
+// It panics if a different kind of metric was registered with that name.
+func (r *MetricsRegistry) Histogram(name, help string, buckets []float64) Histogram {
+	return r.getOrRegister(name, func() registeredMetric { return newHistogram(help, buckets) }).(*histogram)
+}
+
+func (r *MetricsRegistry) getOrRegister(name string, create func() registeredMetric) registeredMetric {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	m := create()
+	if existing, ok := r.metrics[name]; ok {
+		if existing.metricType() != m.metricType() {
+			panic(fmt.Sprintf("quic: metric %s is already registered as a %s", name, existing.metricType()))
+		}
+		return existing
+	}
+	r.metrics[name] = m
+	return m
+}
+
+// WritePrometheus writes all metrics in the Prometheus text format, sorted by name.
+func (r *MetricsRegistry) WritePrometheus(w io.Writer) error {
+	r.mutex.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	metrics := make([]registeredMetric, 0, len(r.metrics))
+	sort.Strings(names)
+	for _, name := range names {
+		metrics = append(metrics, r.metrics[name])
+	}
+	r.mutex.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for i, m := range metrics {
+		if len(m.metricHelp()) > 0 {
+			fmt.Fprintf(bw, "# HELP %s %s\n", names[i], m.metricHelp())
+		}
+		fmt.Fprintf(bw, "# TYPE %s %s\n", names[i], m.metricType())
+		m.write(bw, names[i])
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (r *MetricsRegistry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WritePrometheus(w) //nolint:errcheck
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+type counter struct {
+	mutex sync.Mutex
+	help  string
+	value float64
+}
+
+// Add increases the counter. Negative values are ignored.
+func (c *counter) Add(v float64) {
+	if v <= 0 {
+		return
+	}
+	c.mutex.Lock()
+	c.value += v
+	c.mutex.Unlock()
+}
+
+func (c *counter) metricType() string { return "counter" }
+func (c *counter) metricHelp() string { return c.help }
+
+func (c *counter) write(w io.Writer, name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	fmt.Fprintf(w, "%s %s\n", name, formatFloat(c.value))
+}
+
+type gauge struct {
+	mutex sync.Mutex
+	help  string
+	value float64
+}
+
+func (g *gauge) Set(v float64) {
+	g.mutex.Lock()
+	g.value = v
+	g.mutex.Unlock()
+}
+
+func (g *gauge) Add(v float64) {
+	g.mutex.Lock()
+	g.value += v
+	g.mutex.Unlock()
+}
+
+func (g *gauge) metricType() string { return "gauge" }
+func (g *gauge) metricHelp() string { return g.help }
+
+func (g *gauge) write(w io.Writer, name string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	fmt.Fprintf(w, "%s %s\n", name, formatFloat(g.value))
+}
+
+type histogram struct {
+	mutex   sync.Mutex
+	help    string
+	buckets []float64
+	counts  []uint64 // counts[i] is the number of observations in bucket i, the last element counts the +Inf bucket
+	sum     float64
+	count   uint64
+}
+
+func newHistogram(help string, buckets []float64) *histogram {
+	if !sort.Float64sAreSorted(buckets) {
+		panic("quic: histogram buckets must be sorted")
+	}
+	return &histogram{
+		help:    help,
+		buckets: append([]float64(nil), buckets...),
+		counts:  make([]uint64, len(buckets)+1),
+	}
+}
+
+func (h *histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v) // the first bucket with an upper bound >= v
+	h.mutex.Lock()
+	h.counts[i]++
+	h.sum += v
+	h.count++
+	h.mutex.Unlock()
+}
+
+func (h *histogram) metricType() string { return "histogram" }
+func (h *histogram) metricHelp() string { return h.help }
+
+func (h *histogram) write(w io.Writer, name string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	var cumulative uint64
+	for i, upper := range h.buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", name, formatFloat(upper), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(w, "%s_sum %s\n", name, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count %d\n", name, h.count)
+}
+
+// handshakeDurationBuckets are the buckets (in seconds) of the handshake duration histogram.
+var handshakeDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// sessionMetrics are the metrics reported by a session.
+type sessionMetrics struct {
+	sessionsOpen        Gauge
+	streamsOpen         Gauge
+	handshakesCompleted Counter
+	handshakesFailed    Counter
+	handshakeDuration   Histogram
+	retriesReceived     Counter
+}
+
+func newSessionMetrics(m Metrics) *sessionMetrics {
+	return &sessionMetrics{
+		sessionsOpen:        m.Gauge("quic_sessions_open", "Number of open sessions."),
+		streamsOpen:         m.Gauge("quic_streams_open", "Number of open streams."),
+		handshakesCompleted: m.Counter("quic_handshakes_completed_total", "Number of completed handshakes."),
+		handshakesFailed:    m.Counter("quic_handshakes_failed_total", "Number of sessions that were closed before completing the handshake."),
+		handshakeDuration:   m.Histogram("quic_handshake_duration_seconds", "Time from creating a session until the handshake completed, in seconds.", handshakeDurationBuckets),
+		retriesReceived:     m.Counter("quic_retry_packets_received_total", "Number of Retry packets accepted by clients."),
+	}
+}
+
+// serverMetrics are the metrics reported by a server.
+type serverMetrics struct {
+	sessionsCreated     Counter
+	sessionsRejected    Counter
+	retriesSent         Counter
+	versionNegotiations Counter
+}
+
+func newServerMetrics(m Metrics) *serverMetrics {
+	return &serverMetrics{
+		sessionsCreated:     m.Counter("quic_server_sessions_created_total", "Number of sessions created by the server."),
+		sessionsRejected:    m.Counter("quic_server_sessions_rejected_total", "Number of connection attempts rejected because the server was busy."),
+		retriesSent:         m.Counter("quic_server_retry_packets_sent_total", "Number of Retry packets sent by the server."),
+		versionNegotiations: m.Counter("quic_server_version_negotiation_packets_sent_total", "Number of Version Negotiation packets sent by the server."),
+	}
+}
@@ -0,0 +1,96 @@
+package quic
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metrics Registry", func() {
+	var r *MetricsRegistry
+
+	BeforeEach(func() {
+		r = NewMetricsRegistry()
+	})
+
+	prometheus := func() string {
+		b := &bytes.Buffer{}
+		Expect(r.WritePrometheus(b)).To(Succeed())
+		return b.String()
+	}
+
+	It("returns the same metric for the same name", func() {
+		c := r.Counter("foo_total", "foo")
+		Expect(r.Counter("foo_total", "foo")).To(BeIdenticalTo(c))
+		g := r.Gauge("bar", "bar")
+		Expect(r.Gauge("bar", "bar")).To(BeIdenticalTo(g))
+		h := r.Histogram("baz", "baz", []float64{1, 2})
+		Expect(r.Histogram("baz", "baz", []float64{1, 2})).To(BeIdenticalTo(h))
+	})
+
+	It("panics if a metric is registered with a different type", func() {
+		r.Counter("foo", "foo")
+		Expect(func() { r.Gauge("foo", "foo") }).To(Panic())
+	})
+
+	It("panics if the histogram buckets are not sorted", func() {
+		Expect(func() { r.Histogram("foo", "foo", []float64{2, 1}) }).To(Panic())
+	})
+
+	It("writes counters and gauges", func() {
+		c := r.Counter("foo_total", "Number of foos.")
+		c.Add(2)
+		c.Add(-1) // ignored
+		c.Add(1.5)
+		g := r.Gauge("bar", "")
+		g.Set(10)
+		g.Add(-3)
+		Expect(prometheus()).To(Equal(
+			"# TYPE bar gauge\n" +
+				"bar 7\n" +
+				"# HELP foo_total Number of foos.\n" +
+				"# TYPE foo_total counter\n" +
+				"foo_total 3.5\n",
+		))
+	})
+
+	It("writes histograms", func() {
+		h := r.Histogram("rtt_seconds", "RTT.", []float64{0.01, 0.1, 1})
+		h.Observe(0.005)
+		h.Observe(0.01)
+		h.Observe(0.5)
+		h.Observe(3)
+		Expect(prometheus()).To(Equal(
+			"# HELP rtt_seconds RTT.\n" +
+				"# TYPE rtt_seconds histogram\n" +
+				"rtt_seconds_bucket{le=\"0.01\"} 2\n" +
+				"rtt_seconds_bucket{le=\"0.1\"} 2\n" +
+				"rtt_seconds_bucket{le=\"1\"} 3\n" +
+				"rtt_seconds_bucket{le=\"+Inf\"} 4\n" +
+				"rtt_seconds_sum 3.515\n" +
+				"rtt_seconds_count 4\n",
+		))
+	})
+
+	It("serves the metrics via HTTP", func() {
+		r.Counter("foo_total", "").Add(1)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Content-Type")).To(HavePrefix("text/plain; version=0.0.4"))
+		Expect(w.Body.String()).To(ContainSubstring("foo_total 1\n"))
+	})
+
+	It("creates the session metrics", func() {
+		m := newSessionMetrics(r)
+		m.sessionsOpen.Add(1)
+		m.handshakeDuration.Observe(0.02)
+		out := prometheus()
+		Expect(out).To(ContainSubstring("quic_sessions_open 1\n"))
+		Expect(out).To(ContainSubstring("quic_handshake_duration_seconds_count 1\n"))
+		Expect(newSessionMetrics(r).sessionsOpen).To(BeIdenticalTo(m.sessionsOpen))
+	})
+})
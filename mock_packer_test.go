@@ -93,6 +93,36 @@ func (mr *MockPackerMockRecorder) PackConnectionClose(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PackConnectionClose", reflect.TypeOf((*MockPacker)(nil).PackConnectionClose), arg0)
 }
 
+// PackPathChallenge mocks base method
+func (m *MockPacker) PackPathChallenge(arg0 *wire.PathChallengeFrame, arg1 protocol.ConnectionID) (*packedPacket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PackPathChallenge", arg0, arg1)
+	ret0, _ := ret[0].(*packedPacket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PackPathChallenge indicates an expected call of PackPathChallenge
+func (mr *MockPackerMockRecorder) PackPathChallenge(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PackPathChallenge", reflect.TypeOf((*MockPacker)(nil).PackPathChallenge), arg0, arg1)
+}
+
+// PackPathResponse mocks base method
+func (m *MockPacker) PackPathResponse(arg0 *wire.PathResponseFrame, arg1 protocol.ConnectionID) (*packedPacket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PackPathResponse", arg0, arg1)
+	ret0, _ := ret[0].(*packedPacket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PackPathResponse indicates an expected call of PackPathResponse
+func (mr *MockPackerMockRecorder) PackPathResponse(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PackPathResponse", reflect.TypeOf((*MockPacker)(nil).PackPathResponse), arg0, arg1)
+}
+
 // PackPacket mocks base method
 func (m *MockPacker) PackPacket() (*packedPacket, error) {
 	m.ctrl.T.Helper()
@@ -123,6 +123,20 @@ func (mr *MockQuicSessionMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockQuicSession)(nil).Context))
 }
 
+// FlowControlStats mocks base method
+func (m *MockQuicSession) FlowControlStats() FlowControlStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlowControlStats")
+	ret0, _ := ret[0].(FlowControlStats)
+	return ret0
+}
+
+// FlowControlStats indicates an expected call of FlowControlStats
+func (mr *MockQuicSessionMockRecorder) FlowControlStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlStats", reflect.TypeOf((*MockQuicSession)(nil).FlowControlStats))
+}
+
 // GetVersion mocks base method
 func (m *MockQuicSession) GetVersion() protocol.VersionNumber {
 	m.ctrl.T.Helper()
@@ -151,6 +165,20 @@ func (mr *MockQuicSessionMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockQuicSession)(nil).HandshakeComplete))
 }
 
+// InitiateKeyUpdate mocks base method
+func (m *MockQuicSession) InitiateKeyUpdate() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InitiateKeyUpdate")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InitiateKeyUpdate indicates an expected call of InitiateKeyUpdate
+func (mr *MockQuicSessionMockRecorder) InitiateKeyUpdate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitiateKeyUpdate", reflect.TypeOf((*MockQuicSession)(nil).InitiateKeyUpdate))
+}
+
 // LocalAddr mocks base method
 func (m *MockQuicSession) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -195,6 +223,36 @@ func (mr *MockQuicSessionMockRecorder) OpenStreamSync(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamSync", reflect.TypeOf((*MockQuicSession)(nil).OpenStreamSync), arg0)
 }
 
+// OpenStreamSyncWithOptions mocks base method
+func (m *MockQuicSession) OpenStreamSyncWithOptions(arg0 context.Context, arg1 *StreamOptions) (Stream, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenStreamSyncWithOptions", arg0, arg1)
+	ret0, _ := ret[0].(Stream)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenStreamSyncWithOptions indicates an expected call of OpenStreamSyncWithOptions
+func (mr *MockQuicSessionMockRecorder) OpenStreamSyncWithOptions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamSyncWithOptions", reflect.TypeOf((*MockQuicSession)(nil).OpenStreamSyncWithOptions), arg0, arg1)
+}
+
+// OpenStreamWithOptions mocks base method
+func (m *MockQuicSession) OpenStreamWithOptions(arg0 *StreamOptions) (Stream, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenStreamWithOptions", arg0)
+	ret0, _ := ret[0].(Stream)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenStreamWithOptions indicates an expected call of OpenStreamWithOptions
+func (mr *MockQuicSessionMockRecorder) OpenStreamWithOptions(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamWithOptions", reflect.TypeOf((*MockQuicSession)(nil).OpenStreamWithOptions), arg0)
+}
+
 // OpenUniStream mocks base method
 func (m *MockQuicSession) OpenUniStream() (SendStream, error) {
 	m.ctrl.T.Helper()
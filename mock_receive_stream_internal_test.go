@@ -48,6 +48,20 @@ func (mr *MockReceiveStreamIMockRecorder) CancelRead(arg0 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelRead", reflect.TypeOf((*MockReceiveStreamI)(nil).CancelRead), arg0)
 }
 
+// FlowControlStats mocks base method
+func (m *MockReceiveStreamI) FlowControlStats() FlowControlStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlowControlStats")
+	ret0, _ := ret[0].(FlowControlStats)
+	return ret0
+}
+
+// FlowControlStats indicates an expected call of FlowControlStats
+func (mr *MockReceiveStreamIMockRecorder) FlowControlStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlStats", reflect.TypeOf((*MockReceiveStreamI)(nil).FlowControlStats))
+}
+
 // Read mocks base method
 func (m *MockReceiveStreamI) Read(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -131,6 +145,18 @@ func (mr *MockReceiveStreamIMockRecorder) handleResetStreamFrame(arg0 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleResetStreamFrame", reflect.TypeOf((*MockReceiveStreamI)(nil).handleResetStreamFrame), arg0)
 }
 
+// handleStreamDataBlockedFrame mocks base method
+func (m *MockReceiveStreamI) handleStreamDataBlockedFrame(arg0 *wire.StreamDataBlockedFrame) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "handleStreamDataBlockedFrame", arg0)
+}
+
+// handleStreamDataBlockedFrame indicates an expected call of handleStreamDataBlockedFrame
+func (mr *MockReceiveStreamIMockRecorder) handleStreamDataBlockedFrame(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleStreamDataBlockedFrame", reflect.TypeOf((*MockReceiveStreamI)(nil).handleStreamDataBlockedFrame), arg0)
+}
+
 // handleStreamFrame mocks base method
 func (m *MockReceiveStreamI) handleStreamFrame(arg0 *wire.StreamFrame) error {
 	m.ctrl.T.Helper()
@@ -144,3 +170,15 @@ func (mr *MockReceiveStreamIMockRecorder) handleStreamFrame(arg0 interface{}) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleStreamFrame", reflect.TypeOf((*MockReceiveStreamI)(nil).handleStreamFrame), arg0)
 }
+
+// setReceiveWindowSize mocks base method
+func (m *MockReceiveStreamI) setReceiveWindowSize(arg0 protocol.ByteCount, arg1 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "setReceiveWindowSize", arg0, arg1)
+}
+
+// setReceiveWindowSize indicates an expected call of setReceiveWindowSize
+func (mr *MockReceiveStreamIMockRecorder) setReceiveWindowSize(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setReceiveWindowSize", reflect.TypeOf((*MockReceiveStreamI)(nil).setReceiveWindowSize), arg0, arg1)
+}
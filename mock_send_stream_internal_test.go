@@ -78,6 +78,20 @@ func (mr *MockSendStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockSendStreamI)(nil).Context))
 }
 
+// FlowControlStats mocks base method
+func (m *MockSendStreamI) FlowControlStats() FlowControlStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlowControlStats")
+	ret0, _ := ret[0].(FlowControlStats)
+	return ret0
+}
+
+// FlowControlStats indicates an expected call of FlowControlStats
+func (mr *MockSendStreamIMockRecorder) FlowControlStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlStats", reflect.TypeOf((*MockSendStreamI)(nil).FlowControlStats))
+}
+
 // SetWriteDeadline mocks base method
 func (m *MockSendStreamI) SetWriteDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
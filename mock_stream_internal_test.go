@@ -90,6 +90,20 @@ func (mr *MockStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockStreamI)(nil).Context))
 }
 
+// FlowControlStats mocks base method
+func (m *MockStreamI) FlowControlStats() FlowControlStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlowControlStats")
+	ret0, _ := ret[0].(FlowControlStats)
+	return ret0
+}
+
+// FlowControlStats indicates an expected call of FlowControlStats
+func (mr *MockStreamIMockRecorder) FlowControlStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlStats", reflect.TypeOf((*MockStreamI)(nil).FlowControlStats))
+}
+
 // Read mocks base method
 func (m *MockStreamI) Read(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -240,6 +254,18 @@ func (mr *MockStreamIMockRecorder) handleStopSendingFrame(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleStopSendingFrame", reflect.TypeOf((*MockStreamI)(nil).handleStopSendingFrame), arg0)
 }
 
+// handleStreamDataBlockedFrame mocks base method
+func (m *MockStreamI) handleStreamDataBlockedFrame(arg0 *wire.StreamDataBlockedFrame) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "handleStreamDataBlockedFrame", arg0)
+}
+
+// handleStreamDataBlockedFrame indicates an expected call of handleStreamDataBlockedFrame
+func (mr *MockStreamIMockRecorder) handleStreamDataBlockedFrame(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleStreamDataBlockedFrame", reflect.TypeOf((*MockStreamI)(nil).handleStreamDataBlockedFrame), arg0)
+}
+
 // handleStreamFrame mocks base method
 func (m *MockStreamI) handleStreamFrame(arg0 *wire.StreamFrame) error {
 	m.ctrl.T.Helper()
@@ -282,3 +308,15 @@ func (mr *MockStreamIMockRecorder) popStreamFrame(arg0 interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "popStreamFrame", reflect.TypeOf((*MockStreamI)(nil).popStreamFrame), arg0)
 }
+
+// setReceiveWindowSize mocks base method
+func (m *MockStreamI) setReceiveWindowSize(arg0 protocol.ByteCount, arg1 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "setReceiveWindowSize", arg0, arg1)
+}
+
+// setReceiveWindowSize indicates an expected call of setReceiveWindowSize
+func (mr *MockStreamIMockRecorder) setReceiveWindowSize(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setReceiveWindowSize", reflect.TypeOf((*MockStreamI)(nil).setReceiveWindowSize), arg0, arg1)
+}
@@ -0,0 +1,179 @@
+package quic
+
+import (
+	"net"
+	"sort"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+)
+
+// The multipath extension allows using multiple paths within one session.
+// The client opens a new path by sending packets with an unused connection ID from a different local address.
+// The path is identified by the sequence number of that connection ID:
+// On the path with ID n, both endpoints use the connection ID with sequence number n issued by the peer.
+// The initial path has the ID 0, and uses the connection IDs of the handshake.
+//
+// Every path has its own packet number space and congestion controller.
+// In order to avoid nonce reuse, the path ID is encoded into the AEAD nonce.
+// Packets on a path are acknowledged using ACK_MP frames, which can be sent on any path.
+
+// pathCryptoSetup is the handshake.CryptoSetup used on a path other than the initial path.
+// Paths are only opened after the handshake completed, so only 1-RTT keys are available.
+type pathCryptoSetup struct {
+	handshake.CryptoSetup
+
+	pathID uint64
+}
+
+var _ handshake.CryptoSetup = &pathCryptoSetup{}
+
+func (c *pathCryptoSetup) GetInitialOpener() (handshake.LongHeaderOpener, error) {
+	return nil, handshake.ErrKeysDropped
+}
+
+func (c *pathCryptoSetup) GetHandshakeOpener() (handshake.LongHeaderOpener, error) {
+	return nil, handshake.ErrKeysDropped
+}
+
+func (c *pathCryptoSetup) Get1RTTOpener() (handshake.ShortHeaderOpener, error) {
+	return c.CryptoSetup.Get1RTTOpenerForPath(c.pathID)
+}
+
+func (c *pathCryptoSetup) GetInitialSealer() (handshake.LongHeaderSealer, error) {
+	return nil, handshake.ErrKeysDropped
+}
+
+func (c *pathCryptoSetup) GetHandshakeSealer() (handshake.LongHeaderSealer, error) {
+	return nil, handshake.ErrKeysDropped
+}
+
+func (c *pathCryptoSetup) Get1RTTSealer() (handshake.ShortHeaderSealer, error) {
+	return c.CryptoSetup.Get1RTTSealerForPath(c.pathID)
+}
+
+// A path is a path used by the multipath extension.
+type path struct {
+	pathValidator
+
+	id         uint64
+	localAddr  net.Addr
+	remoteAddr net.Addr
+	destConnID protocol.ConnectionID
+	// pconn is only set for paths added by the client using AddPath.
+	// It is closed when the path is removed.
+	pconn net.PacketConn
+	// validated is set when we received a PATH_RESPONSE on this path.
+	// Packets other than path validation packets and ACKs are only sent on validated paths.
+	validated bool
+
+	rttStats              *congestion.RTTStats
+	sentPacketHandler     ackhandler.SentPacketHandler
+	receivedPacketHandler ackhandler.ReceivedPacketHandler
+	packer                packer
+	unpacker              unpacker
+
+	send func(*packedPacket)
+}
+
+func (p *path) Info() PathInfo {
+	stats := p.sentPacketHandler.GetStats()
+	return PathInfo{
+		ID:               p.id,
+		LocalAddr:        p.localAddr,
+		RemoteAddr:       p.remoteAddr,
+		Validated:        p.validated,
+		SmoothedRTT:      p.rttStats.SmoothedRTT(),
+		CongestionWindow: uint64(stats.CongestionWindow),
+		BytesInFlight:    uint64(stats.BytesInFlight),
+	}
+}
+
+// CanSend says if a packet can be sent on this path right now.
+// It also returns the time when the pacer allows sending the next packet.
+func (p *path) CanSend(now time.Time) (bool, time.Time) {
+	if !p.validated || p.sentPacketHandler.SendMode() != ackhandler.SendAny {
+		return false, time.Time{}
+	}
+	if p.id != 0 {
+		// The path ID is encoded into the nonce, which limits the packet number.
+		if pn, _ := p.sentPacketHandler.PeekPacketNumber(protocol.Encryption1RTT); pn > protocol.MaxPathPacketNumber {
+			return false, time.Time{}
+		}
+	}
+	if t := p.sentPacketHandler.TimeUntilSend(); t.After(now) {
+		return false, t
+	}
+	return true, time.Time{}
+}
+
+type multipathManager struct {
+	// the crypto setup of the session, used for deriving the keys of the paths
+	cryptoSetup handshake.CryptoSetup
+	scheduler   PathScheduler
+
+	// all paths except for the initial path
+	paths map[uint64]*path
+}
+
+func newMultipathManager(cs handshake.CryptoSetup, newScheduler func() PathScheduler) *multipathManager {
+	var scheduler PathScheduler
+	if newScheduler != nil {
+		scheduler = newScheduler()
+	} else {
+		scheduler = NewMinRTTPathScheduler()
+	}
+	return &multipathManager{
+		cryptoSetup: cs,
+		scheduler:   scheduler,
+		paths:       make(map[uint64]*path),
+	}
+}
+
+// SortedPaths returns the paths, ordered by their ID.
+// The initial path is not included.
+func (m *multipathManager) SortedPaths() []*path {
+	paths := make([]*path, 0, len(m.paths))
+	for _, p := range m.paths {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool { return paths[i].id < paths[j].id })
+	return paths
+}
+
+// IsInUse says if a path with this ID exists.
+func (m *multipathManager) IsInUse(id uint64) bool {
+	_, ok := m.paths[id]
+	return ok
+}
+
+// Timeout returns the earliest time when a timer of a path expires.
+// The timers of the initial path are not included.
+func (m *multipathManager) Timeout() time.Time {
+	var timeout time.Time
+	for _, p := range m.paths {
+		timeout = utils.MinNonZeroTime(timeout, p.receivedPacketHandler.GetAlarmTimeout())
+		timeout = utils.MinNonZeroTime(timeout, p.sentPacketHandler.GetLossDetectionTimeout())
+		timeout = utils.MinNonZeroTime(timeout, p.Deadline())
+	}
+	return timeout
+}
+
+// Remove removes a path, and closes its packet conn.
+func (m *multipathManager) Remove(p *path) {
+	delete(m.paths, p.id)
+	if p.pconn != nil {
+		p.pconn.Close()
+	}
+}
+
+// Close closes the packet conns of all paths.
+func (m *multipathManager) Close() {
+	for _, p := range m.paths {
+		m.Remove(p)
+	}
+}
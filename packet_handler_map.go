@@ -221,23 +221,32 @@ func (h *packetHandlerMap) close(e error) error {
 
 func (h *packetHandlerMap) listen() {
 	defer close(h.listening)
+	if bc, ok := h.conn.(batchPacketConn); ok {
+		for {
+			if err := bc.ReadPackets(h.handlePacket); err != nil {
+				h.close(err)
+				return
+			}
+		}
+	}
 	for {
 		buffer := getPacketBuffer()
 		data := buffer.Slice
 		// The packet size should not exceed protocol.MaxReceivePacketSize bytes
 		// If it does, we only read a truncated packet, which will then end up undecryptable
-		n, addr, err := h.conn.ReadFrom(data)
+		n, addr, ecn, err := readPacket(h.conn, data)
 		if err != nil {
 			h.close(err)
 			return
 		}
-		h.handlePacket(addr, buffer, data[:n])
+		h.handlePacket(addr, buffer, ecn, data[:n])
 	}
 }
 
 func (h *packetHandlerMap) handlePacket(
 	addr net.Addr,
 	buffer *packetBuffer,
+	ecn protocol.ECN,
 	data []byte,
 ) {
 	connID, err := wire.ParseConnectionID(data, h.connIDLen)
@@ -261,6 +270,7 @@ func (h *packetHandlerMap) handlePacket(
 		rcvTime:    rcvTime,
 		buffer:     buffer,
 		data:       data,
+		ecn:        ecn,
 	}
 	if handlerFound { // existing session
 		handler.handlePacket(p)
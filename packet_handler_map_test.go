@@ -122,7 +122,7 @@ var _ = Describe("Packet Handler Map", func() {
 		})
 
 		It("drops unparseable packets", func() {
-			handler.handlePacket(nil, nil, []byte{0, 1, 2, 3})
+			handler.handlePacket(nil, nil, protocol.ECNNon, []byte{0, 1, 2, 3})
 		})
 
 		It("deletes removed sessions immediately", func() {
@@ -130,7 +130,7 @@ var _ = Describe("Packet Handler Map", func() {
 			connID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
 			handler.Add(connID, NewMockPacketHandler(mockCtrl))
 			handler.Remove(connID)
-			handler.handlePacket(nil, nil, getPacket(connID))
+			handler.handlePacket(nil, nil, protocol.ECNNon, getPacket(connID))
 			// don't EXPECT any calls to handlePacket of the MockPacketHandler
 		})
 
@@ -141,7 +141,7 @@ var _ = Describe("Packet Handler Map", func() {
 			handler.Add(connID, sess)
 			handler.Retire(connID)
 			time.Sleep(scaleDuration(30 * time.Millisecond))
-			handler.handlePacket(nil, nil, getPacket(connID))
+			handler.handlePacket(nil, nil, protocol.ECNNon, getPacket(connID))
 			// don't EXPECT any calls to handlePacket of the MockPacketHandler
 		})
 
@@ -155,13 +155,13 @@ var _ = Describe("Packet Handler Map", func() {
 			})
 			handler.Add(connID, packetHandler)
 			handler.Retire(connID)
-			handler.handlePacket(nil, nil, getPacket(connID))
+			handler.handlePacket(nil, nil, protocol.ECNNon, getPacket(connID))
 			Eventually(handled).Should(BeClosed())
 		})
 
 		It("drops packets for unknown receivers", func() {
 			connID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8}
-			handler.handlePacket(nil, nil, getPacket(connID))
+			handler.handlePacket(nil, nil, protocol.ECNNon, getPacket(connID))
 		})
 
 		It("closes the packet handlers when reading from the conn fails", func() {
@@ -194,7 +194,7 @@ var _ = Describe("Packet Handler Map", func() {
 				Expect(cid).To(Equal(connID))
 			})
 			handler.SetServer(server)
-			handler.handlePacket(nil, nil, p)
+			handler.handlePacket(nil, nil, protocol.ECNNon, p)
 		})
 
 		It("closes all server sessions", func() {
@@ -216,7 +216,7 @@ var _ = Describe("Packet Handler Map", func() {
 			// don't EXPECT any calls to server.handlePacket
 			handler.SetServer(server)
 			handler.CloseServer()
-			handler.handlePacket(nil, nil, p)
+			handler.handlePacket(nil, nil, protocol.ECNNon, p)
 		})
 	})
 
@@ -269,7 +269,7 @@ var _ = Describe("Packet Handler Map", func() {
 				p = append(p, token[:]...)
 
 				time.Sleep(scaleDuration(30 * time.Millisecond))
-				handler.handlePacket(nil, nil, p)
+				handler.handlePacket(nil, nil, protocol.ECNNon, p)
 			})
 
 			It("ignores packets too small to contain a stateless reset", func() {
@@ -312,7 +312,7 @@ var _ = Describe("Packet Handler Map", func() {
 			It("sends stateless resets", func() {
 				addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
 				p := append([]byte{40}, make([]byte, 100)...)
-				handler.handlePacket(addr, getPacketBuffer(), p)
+				handler.handlePacket(addr, getPacketBuffer(), protocol.ECNNon, p)
 				var reset mockPacketConnWrite
 				Eventually(conn.dataWritten).Should(Receive(&reset))
 				Expect(reset.to).To(Equal(addr))
@@ -323,7 +323,7 @@ var _ = Describe("Packet Handler Map", func() {
 			It("doesn't send stateless resets for small packets", func() {
 				addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
 				p := append([]byte{40}, make([]byte, protocol.MinStatelessResetSize-2)...)
-				handler.handlePacket(addr, getPacketBuffer(), p)
+				handler.handlePacket(addr, getPacketBuffer(), protocol.ECNNon, p)
 				Consistently(conn.dataWritten).ShouldNot(Receive())
 			})
 		})
@@ -332,7 +332,7 @@ var _ = Describe("Packet Handler Map", func() {
 			It("doesn't send stateless resets", func() {
 				addr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
 				p := append([]byte{40}, make([]byte, 100)...)
-				handler.handlePacket(addr, getPacketBuffer(), p)
+				handler.handlePacket(addr, getPacketBuffer(), protocol.ECNNon, p)
 				Consistently(conn.dataWritten).ShouldNot(Receive())
 			})
 		})
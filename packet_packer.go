@@ -19,6 +19,8 @@ type packer interface {
 	MaybePackProbePacket(protocol.EncryptionLevel) (*packedPacket, error)
 	MaybePackAckPacket() (*packedPacket, error)
 	PackConnectionClose(*wire.ConnectionCloseFrame) (*packedPacket, error)
+	PackPathChallenge(*wire.PathChallengeFrame, protocol.ConnectionID) (*packedPacket, error)
+	PackPathResponse(*wire.PathResponseFrame, protocol.ConnectionID) (*packedPacket, error)
 
 	HandleTransportParameters(*handshake.TransportParameters)
 	SetToken([]byte)
@@ -52,6 +54,8 @@ func (p *packedPacket) EncryptionLevel() protocol.EncryptionLevel {
 		return protocol.EncryptionInitial
 	case protocol.PacketTypeHandshake:
 		return protocol.EncryptionHandshake
+	case protocol.PacketType0RTT:
+		return protocol.Encryption0RTT
 	default:
 		return protocol.EncryptionUnspecified
 	}
@@ -76,7 +80,7 @@ func (p *packedPacket) ToAckHandlerPacket(q *retransmissionQueue) *ackhandler.Pa
 			p.frames[i].OnLost = q.AddInitial
 		case protocol.EncryptionHandshake:
 			p.frames[i].OnLost = q.AddHandshake
-		case protocol.Encryption1RTT:
+		case protocol.Encryption0RTT, protocol.Encryption1RTT:
 			p.frames[i].OnLost = q.AddAppData
 		}
 	}
@@ -115,6 +119,7 @@ type packetNumberManager interface {
 type sealingManager interface {
 	GetInitialSealer() (handshake.LongHeaderSealer, error)
 	GetHandshakeSealer() (handshake.LongHeaderSealer, error)
+	Get0RTTSealer() (handshake.LongHeaderSealer, error)
 	Get1RTTSealer() (handshake.ShortHeaderSealer, error)
 }
 
@@ -153,7 +158,12 @@ type packetPacker struct {
 	numNonAckElicitingAcks int
 
 	// session 级下发的 ptm 指针，用于在不同层次之间共享该变量
+	// 多路径扩展中，除初始路径外的路径不参与 ping 测量，此时为 nil
 	ptm *pingTestManager
+
+	// The path that this packer packs packets for, when using the multipath extension.
+	// On paths other than the initial path, ACKs are sent in ACK_MP frames.
+	pathID uint64
 }
 
 var _ packer = &packetPacker{}
@@ -227,6 +237,36 @@ func (p *packetPacker) PackConnectionClose(ccf *wire.ConnectionCloseFrame) (*pac
 	return p.writeAndSealPacket(hdr, payload, encLevel, sealer)
 }
 
+// PackPathChallenge packs a 1-RTT packet that ONLY contains a PATH_CHALLENGE frame.
+// The packet is sent on a new path, using the connection ID destConnID.
+// It is padded to the minimum size of an Initial packet, in order to verify that the path supports this packet size.
+func (p *packetPacker) PackPathChallenge(frame *wire.PathChallengeFrame, destConnID protocol.ConnectionID) (*packedPacket, error) {
+	return p.packPathValidationPacket(frame, destConnID)
+}
+
+// PackPathResponse packs a 1-RTT packet that ONLY contains a PATH_RESPONSE frame.
+// The packet is sent on the path that the PATH_CHALLENGE was received on, using the connection ID destConnID.
+// Like the PATH_CHALLENGE, it is padded to the minimum size of an Initial packet.
+func (p *packetPacker) PackPathResponse(frame *wire.PathResponseFrame, destConnID protocol.ConnectionID) (*packedPacket, error) {
+	return p.packPathValidationPacket(frame, destConnID)
+}
+
+func (p *packetPacker) packPathValidationPacket(frame wire.Frame, destConnID protocol.ConnectionID) (*packedPacket, error) {
+	sealer, err := p.cryptoSetup.Get1RTTSealer()
+	if err != nil {
+		return nil, err
+	}
+	hdr := p.getShortHeader(sealer.KeyPhase())
+	hdr.DestConnectionID = destConnID
+	payload := payload{
+		// Path validation frames are not retransmitted on the original path.
+		frames: []ackhandler.Frame{{Frame: frame, OnLost: func(wire.Frame) {}}},
+		length: frame.Length(p.version),
+	}
+	paddingLen := protocol.ByteCount(protocol.MinInitialPacketSize-sealer.Overhead()) - hdr.GetLength(p.version) - payload.length
+	return p.writeAndSealPacketWithPadding(hdr, payload, paddingLen, protocol.Encryption1RTT, sealer)
+}
+
 func (p *packetPacker) MaybePackAckPacket() (*packedPacket, error) {
 	var encLevel protocol.EncryptionLevel
 	var ack *wire.AckFrame
@@ -253,7 +293,7 @@ func (p *packetPacker) MaybePackAckPacket() (*packedPacket, error) {
 	}
 	payload := payload{
 		ack:    ack,
-		length: ack.Length(p.version),
+		length: p.ackLength(ack),
 	}
 
 	sealer, hdr, err := p.getSealerAndHeader(encLevel)
@@ -375,16 +415,31 @@ func (p *packetPacker) packCryptoPacket(
 }
 
 func (p *packetPacker) maybePackAppDataPacket() (*packedPacket, error) {
-	sealer, err := p.cryptoSetup.Get1RTTSealer()
-	if err != nil {
-		// sealer not yet available
-		return nil, nil
+	var sealer sealer
+	var header *wire.ExtendedHeader
+	encLevel := protocol.Encryption1RTT
+	oneRTTSealer, err := p.cryptoSetup.Get1RTTSealer()
+	if err == nil {
+		sealer = oneRTTSealer
+		header = p.getShortHeader(oneRTTSealer.KeyPhase())
+	} else {
+		// The 1-RTT sealer is not yet available.
+		// Only clients send 0-RTT packets, if 0-RTT keys are available.
+		if p.perspective != protocol.PerspectiveClient {
+			return nil, nil
+		}
+		sealer, err = p.cryptoSetup.Get0RTTSealer()
+		if err != nil {
+			return nil, nil
+		}
+		encLevel = protocol.Encryption0RTT
+		header = p.getLongHeader(protocol.Encryption0RTT)
 	}
-	header := p.getShortHeader(sealer.KeyPhase())
 	headerLen := header.GetLength(p.version)
 
 	maxSize := p.maxPacketSize - protocol.ByteCount(sealer.Overhead()) - headerLen
-	payload := p.composeNextPacket(maxSize)
+	// 0-RTT packets can't contain ACK frames.
+	payload := p.composeNextPacket(maxSize, encLevel == protocol.Encryption1RTT)
 
 	// check if we have anything to send
 	if len(payload.frames) == 0 && payload.ack == nil {
@@ -404,7 +459,7 @@ func (p *packetPacker) maybePackAppDataPacket() (*packedPacket, error) {
 	}
 
 	// 检查 payload 中是否包含 ping 帧
-	if p.ptm.PingPacketAvailable() {
+	if p.ptm != nil && encLevel == protocol.Encryption1RTT && p.ptm.PingPacketAvailable() {
 		// 取走这一个信号
 		p.ptm.Mutex.Lock()
 		p.ptm.ConsumePingPacketSignal()
@@ -413,15 +468,17 @@ func (p *packetPacker) maybePackAppDataPacket() (*packedPacket, error) {
 		p.ptm.Mutex.Unlock()
 	}
 
-	return p.writeAndSealPacket(header, payload, protocol.Encryption1RTT, sealer)
+	return p.writeAndSealPacket(header, payload, encLevel, sealer)
 }
 
-func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount) payload {
+func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, ackAllowed bool) payload {
 	var payload payload
 
-	if ack := p.acks.GetAckFrame(protocol.Encryption1RTT); ack != nil {
-		payload.ack = ack
-		payload.length += ack.Length(p.version)
+	if ackAllowed {
+		if ack := p.acks.GetAckFrame(protocol.Encryption1RTT); ack != nil {
+			payload.ack = ack
+			payload.length += p.ackLength(ack)
+		}
 	}
 
 	for {
@@ -475,6 +532,13 @@ func (p *packetPacker) getSealerAndHeader(encLevel protocol.EncryptionLevel) (se
 		}
 		hdr := p.getLongHeader(protocol.EncryptionHandshake)
 		return sealer, hdr, nil
+	case protocol.Encryption0RTT:
+		sealer, err := p.cryptoSetup.Get0RTTSealer()
+		if err != nil {
+			return nil, nil, err
+		}
+		hdr := p.getLongHeader(protocol.Encryption0RTT)
+		return sealer, hdr, nil
 	case protocol.Encryption1RTT:
 		sealer, err := p.cryptoSetup.Get1RTTSealer()
 		if err != nil {
@@ -510,11 +574,13 @@ func (p *packetPacker) getLongHeader(encLevel protocol.EncryptionLevel) *wire.Ex
 		hdr.Token = p.token
 	case protocol.EncryptionHandshake:
 		hdr.Type = protocol.PacketTypeHandshake
+	case protocol.Encryption0RTT:
+		hdr.Type = protocol.PacketType0RTT
 	}
 
 	hdr.Version = p.version
 	hdr.IsLongHeader = true
-	// Always send Initial and Handshake packets with the maximum packet number length.
+	// Always send long header packets with the maximum packet number length.
 	// This simplifies retransmissions: Since the header can't get any larger,
 	// we don't need to split CRYPTO frames.
 	hdr.PacketNumberLen = protocol.PacketNumberLen4
@@ -565,7 +631,7 @@ func (p *packetPacker) writeAndSealPacketWithPadding(
 	payloadOffset := buffer.Len()
 
 	if payload.ack != nil {
-		if err := payload.ack.Write(buffer, p.version); err != nil {
+		if err := p.ackFrame(payload.ack).Write(buffer, p.version); err != nil {
 			return nil, err
 		}
 	}
@@ -579,7 +645,6 @@ func (p *packetPacker) writeAndSealPacketWithPadding(
 	}
 
 	if payloadSize := protocol.ByteCount(buffer.Len()-payloadOffset) - paddingLen; payloadSize != payload.length {
-		fmt.Printf("%#v\n", payload)
 		return nil, fmt.Errorf("PacketPacker BUG: payload size inconsistent (expected %d, got %d bytes)", payload.length, payloadSize)
 	}
 	if size := protocol.ByteCount(buffer.Len() + sealer.Overhead()); size > p.maxPacketSize {
@@ -610,6 +675,19 @@ func (p *packetPacker) writeAndSealPacketWithPadding(
 	}, nil
 }
 
+// ackFrame returns the frame that is used to send the ACK.
+// On paths other than the initial path, this is an ACK_MP frame.
+func (p *packetPacker) ackFrame(ack *wire.AckFrame) wire.Frame {
+	if p.pathID == 0 {
+		return ack
+	}
+	return &wire.AckMPFrame{PathID: p.pathID, AckFrame: *ack}
+}
+
+func (p *packetPacker) ackLength(ack *wire.AckFrame) protocol.ByteCount {
+	return p.ackFrame(ack).Length(p.version)
+}
+
 func (p *packetPacker) SetToken(token []byte) {
 	p.token = token
 }
@@ -239,6 +239,32 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.EncryptionLevel()).To(Equal(protocol.Encryption1RTT))
 				Expect(p.ack).To(Equal(ack))
 			})
+
+			It("packs ACK_MP frames on paths other than the initial path", func() {
+				packer.pathID = 3
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(sealer, nil)
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 10}}}
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial)
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionHandshake)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT).Return(ack)
+				p, err := packer.MaybePackAckPacket()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(p).ToNot(BeNil())
+				Expect(p.ack).To(Equal(ack))
+				// cut off the tag that the mock sealer added
+				raw := p.raw[:len(p.raw)-sealer.Overhead()]
+				hdr, _, _, err := wire.ParsePacket(raw, len(packer.getDestConnID()))
+				Expect(err).ToNot(HaveOccurred())
+				r := bytes.NewReader(raw)
+				_, err = hdr.ParseExtended(r, packer.version)
+				Expect(err).ToNot(HaveOccurred())
+				frame, err := wire.NewFrameParser(packer.version).ParseNext(r, protocol.Encryption1RTT)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(&wire.AckMPFrame{PathID: 3, AckFrame: *ack}))
+				Expect(r.Len()).To(BeZero())
+			})
 		})
 
 		Context("packing normal packets", func() {
@@ -298,6 +324,46 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.EncryptionLevel()).To(Equal(protocol.Encryption1RTT))
 			})
 
+			It("packs 0-RTT packets", func() {
+				packer.perspective = protocol.PerspectiveClient
+				sealingManager.EXPECT().Get1RTTSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+				sealingManager.EXPECT().Get0RTTSealer().Return(sealer, nil)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption0RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption0RTT).Return(protocol.PacketNumber(0x42))
+				// don't expect a call to GetAckFrame, 0-RTT packets can't contain ACK frames
+				expectAppendControlFrames()
+				f := &wire.StreamFrame{
+					StreamID: 5,
+					Data:     []byte("foobar"),
+				}
+				expectAppendStreamFrames(ackhandler.Frame{Frame: f})
+				p, err := packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).ToNot(BeNil())
+				Expect(p.header.IsLongHeader).To(BeTrue())
+				Expect(p.header.Type).To(Equal(protocol.PacketType0RTT))
+				Expect(p.EncryptionLevel()).To(Equal(protocol.Encryption0RTT))
+				Expect(p.ack).To(BeNil())
+				Expect(p.frames).To(Equal([]ackhandler.Frame{{Frame: f}}))
+				checkLength(p.raw)
+			})
+
+			It("doesn't pack 0-RTT packets as a server", func() {
+				sealingManager.EXPECT().Get1RTTSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+				p, err := packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).To(BeNil())
+			})
+
+			It("doesn't pack packets if neither 1-RTT nor 0-RTT keys are available", func() {
+				packer.perspective = protocol.PerspectiveClient
+				sealingManager.EXPECT().Get1RTTSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+				sealingManager.EXPECT().Get0RTTSealer().Return(nil, handshake.ErrKeysDropped)
+				p, err := packer.PackPacket()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).To(BeNil())
+			})
+
 			It("packs a single ACK", func() {
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
 				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
@@ -327,6 +393,32 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.frames[0].Frame).To(Equal(&ccf))
 			})
 
+			It("packs a PATH_CHALLENGE", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(sealer, nil)
+				f := &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}
+				p, err := packer.PackPathChallenge(f, protocol.ConnectionID{1, 3, 3, 7})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.header.DestConnectionID).To(Equal(protocol.ConnectionID{1, 3, 3, 7}))
+				Expect(p.frames).To(HaveLen(1))
+				Expect(p.frames[0].Frame).To(Equal(f))
+				Expect(p.raw).To(HaveLen(protocol.MinInitialPacketSize))
+			})
+
+			It("packs a PATH_RESPONSE", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(sealer, nil)
+				f := &wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}
+				p, err := packer.PackPathResponse(f, protocol.ConnectionID{1, 3, 3, 7})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.header.DestConnectionID).To(Equal(protocol.ConnectionID{1, 3, 3, 7}))
+				Expect(p.frames).To(HaveLen(1))
+				Expect(p.frames[0].Frame).To(Equal(f))
+				Expect(p.raw).To(HaveLen(protocol.MinInitialPacketSize))
+			})
+
 			It("packs control frames", func() {
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
 				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
@@ -0,0 +1,94 @@
+package quic
+
+import (
+	"net"
+	"time"
+)
+
+// PathInfo contains information about a path of a session that uses the multipath extension.
+type PathInfo struct {
+	// ID is the path identifier. The initial path has the ID 0.
+	ID uint64
+	// LocalAddr is the local address of the path.
+	LocalAddr net.Addr
+	// RemoteAddr is the address of the peer on this path.
+	RemoteAddr net.Addr
+	// Validated says if path validation succeeded for this path.
+	// Only validated paths are used for sending data.
+	Validated bool
+	// SmoothedRTT is the smoothed RTT of the path.
+	// It is 0 if no RTT sample was obtained on this path yet.
+	SmoothedRTT time.Duration
+	// CongestionWindow is the congestion window of the path, in bytes.
+	CongestionWindow uint64
+	// BytesInFlight is the number of bytes sent on this path that were neither acknowledged nor declared lost.
+	BytesInFlight uint64
+}
+
+// A PathScheduler decides which path a packet is sent on, when using the multipath extension.
+// A PathScheduler is only used by a single session, from the session's run loop.
+type PathScheduler interface {
+	// SelectPath is called before a packet containing (among others) STREAM frames is sent.
+	// paths contains all validated paths that congestion control and pacing allow sending on,
+	// ordered by the path ID. It is never empty.
+	// SelectPath returns the index of the path that the packet is sent on.
+	SelectPath(paths []PathInfo) int
+}
+
+// The MinRTTPathScheduler sends packets on the path with the lowest smoothed RTT.
+// Paths without an RTT sample are preferred, such that an RTT sample is obtained quickly.
+// Since paths are only selected if the congestion controller allows sending,
+// packets are sent on other paths once the congestion window of the fastest path is full.
+type MinRTTPathScheduler struct{}
+
+var _ PathScheduler = &MinRTTPathScheduler{}
+
+// NewMinRTTPathScheduler creates a new MinRTTPathScheduler.
+// This is the default PathScheduler.
+func NewMinRTTPathScheduler() *MinRTTPathScheduler {
+	return &MinRTTPathScheduler{}
+}
+
+// SelectPath selects the path with the lowest smoothed RTT.
+func (s *MinRTTPathScheduler) SelectPath(paths []PathInfo) int {
+	var selected int
+	for i, p := range paths {
+		if p.SmoothedRTT == 0 {
+			return i
+		}
+		if p.SmoothedRTT < paths[selected].SmoothedRTT {
+			selected = i
+		}
+	}
+	return selected
+}
+
+// The RoundRobinPathScheduler sends packets on all available paths in turn.
+type RoundRobinPathScheduler struct {
+	lastPathID uint64
+	started    bool
+}
+
+var _ PathScheduler = &RoundRobinPathScheduler{}
+
+// NewRoundRobinPathScheduler creates a new RoundRobinPathScheduler.
+func NewRoundRobinPathScheduler() *RoundRobinPathScheduler {
+	return &RoundRobinPathScheduler{}
+}
+
+// SelectPath selects the path with the lowest ID higher than the ID of the path selected last time.
+// If there is no such path, it starts over with the first path.
+func (s *RoundRobinPathScheduler) SelectPath(paths []PathInfo) int {
+	selected := 0
+	if s.started {
+		for i, p := range paths {
+			if p.ID > s.lastPathID {
+				selected = i
+				break
+			}
+		}
+	}
+	s.started = true
+	s.lastPathID = paths[selected].ID
+	return selected
+}
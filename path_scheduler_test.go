@@ -0,0 +1,64 @@
+package quic
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path Scheduler", func() {
+	Context("min RTT", func() {
+		var s *MinRTTPathScheduler
+
+		BeforeEach(func() {
+			s = NewMinRTTPathScheduler()
+		})
+
+		It("selects the only path", func() {
+			Expect(s.SelectPath([]PathInfo{{ID: 3, SmoothedRTT: time.Second}})).To(BeZero())
+		})
+
+		It("selects the path with the lowest RTT", func() {
+			paths := []PathInfo{
+				{ID: 0, SmoothedRTT: 30 * time.Millisecond},
+				{ID: 1, SmoothedRTT: 10 * time.Millisecond},
+				{ID: 2, SmoothedRTT: 20 * time.Millisecond},
+			}
+			Expect(s.SelectPath(paths)).To(Equal(1))
+		})
+
+		It("prefers paths without an RTT sample", func() {
+			paths := []PathInfo{
+				{ID: 0, SmoothedRTT: 10 * time.Millisecond},
+				{ID: 1, SmoothedRTT: 30 * time.Millisecond},
+				{ID: 2},
+			}
+			Expect(s.SelectPath(paths)).To(Equal(2))
+		})
+	})
+
+	Context("round robin", func() {
+		var s *RoundRobinPathScheduler
+
+		BeforeEach(func() {
+			s = NewRoundRobinPathScheduler()
+		})
+
+		It("selects the paths in turn", func() {
+			paths := []PathInfo{{ID: 0}, {ID: 1}, {ID: 3}}
+			Expect(s.SelectPath(paths)).To(Equal(0))
+			Expect(s.SelectPath(paths)).To(Equal(1))
+			Expect(s.SelectPath(paths)).To(Equal(2))
+			Expect(s.SelectPath(paths)).To(Equal(0))
+		})
+
+		It("skips paths that are not available", func() {
+			Expect(s.SelectPath([]PathInfo{{ID: 0}, {ID: 1}, {ID: 2}})).To(Equal(0))
+			// path 1 is congestion limited
+			Expect(s.SelectPath([]PathInfo{{ID: 0}, {ID: 2}})).To(Equal(1))
+			Expect(s.SelectPath([]PathInfo{{ID: 0}, {ID: 1}, {ID: 2}})).To(Equal(0))
+			Expect(s.SelectPath([]PathInfo{{ID: 0}, {ID: 1}, {ID: 2}})).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,54 @@
+package quic
+
+import (
+	"crypto/rand"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// A pathValidator keeps track of the PATH_CHALLENGEs sent to validate a path.
+// It is used for the path to the server's preferred address, and for the paths of the multipath extension.
+type pathValidator struct {
+	challenges [][8]byte // the data of the PATH_CHALLENGE frames sent on this path
+	deadline   time.Time // when the next PATH_CHALLENGE is sent, or path validation fails
+	done       bool      // path validation either succeeded or failed
+}
+
+// NextChallenge returns a new PATH_CHALLENGE frame.
+// If no PATH_RESPONSE is received until pto has passed, the next PATH_CHALLENGE frame should be sent.
+func (p *pathValidator) NextChallenge(now time.Time, pto time.Duration) (*wire.PathChallengeFrame, error) {
+	f := &wire.PathChallengeFrame{}
+	if _, err := rand.Read(f.Data[:]); err != nil {
+		return nil, err
+	}
+	p.challenges = append(p.challenges, f.Data)
+	p.deadline = now.Add(pto)
+	return f, nil
+}
+
+// ShouldRetry says if another PATH_CHALLENGE should be sent after the deadline passed.
+// If it returns false, path validation failed.
+func (p *pathValidator) ShouldRetry() bool {
+	return len(p.challenges) < protocol.MaxPathChallenges
+}
+
+// Deadline returns the time when the next PATH_CHALLENGE should be sent, or when path validation fails.
+// It returns the zero value if path validation is not in progress.
+func (p *pathValidator) Deadline() time.Time {
+	if p.done {
+		return time.Time{}
+	}
+	return p.deadline
+}
+
+// HandleResponse checks if the data of the PATH_RESPONSE matches any of the PATH_CHALLENGEs that were sent.
+func (p *pathValidator) HandleResponse(data [8]byte) bool {
+	for _, c := range p.challenges {
+		if c == data {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path Validator", func() {
+	var path *pathValidator
+
+	BeforeEach(func() {
+		path = &pathValidator{}
+	})
+
+	It("sends PATH_CHALLENGEs and sets the deadline", func() {
+		now := time.Now()
+		f1, err := path.NextChallenge(now, time.Second)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(path.Deadline()).To(Equal(now.Add(time.Second)))
+		f2, err := path.NextChallenge(now.Add(time.Second), time.Second)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f1.Data).ToNot(Equal(f2.Data))
+		Expect(path.Deadline()).To(Equal(now.Add(2 * time.Second)))
+	})
+
+	It("accepts responses for any of the PATH_CHALLENGEs", func() {
+		f1, err := path.NextChallenge(time.Now(), time.Second)
+		Expect(err).ToNot(HaveOccurred())
+		f2, err := path.NextChallenge(time.Now(), time.Second)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(path.HandleResponse(f1.Data)).To(BeTrue())
+		Expect(path.HandleResponse(f2.Data)).To(BeTrue())
+		Expect(path.HandleResponse([8]byte{1, 2, 3, 4, 5, 6, 7, 8})).To(BeFalse())
+	})
+
+	It("gives up after sending the maximum number of PATH_CHALLENGEs", func() {
+		for i := 0; i < protocol.MaxPathChallenges; i++ {
+			Expect(path.ShouldRetry()).To(BeTrue())
+			_, err := path.NextChallenge(time.Now(), time.Second)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(path.ShouldRetry()).To(BeFalse())
+	})
+
+	It("doesn't have a deadline when path validation is done", func() {
+		_, err := path.NextChallenge(time.Now(), time.Second)
+		Expect(err).ToNot(HaveOccurred())
+		path.done = true
+		Expect(path.Deadline()).To(BeZero())
+	})
+})
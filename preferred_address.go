@@ -0,0 +1,63 @@
+package quic
+
+import (
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// newPreferredAddressParameter converts the PreferredAddress from the config to the preferred_address transport parameter.
+// Addresses that are not set are sent as the unspecified address with port 0.
+func newPreferredAddressParameter(pa *PreferredAddress, connID protocol.ConnectionID, token [16]byte) *handshake.PreferredAddress {
+	p := &handshake.PreferredAddress{
+		IPv4:                net.IPv4zero.To4(),
+		IPv6:                net.IPv6zero,
+		ConnectionID:        connID,
+		StatelessResetToken: token,
+	}
+	if pa.IPv4 != nil {
+		p.IPv4 = pa.IPv4.IP.To4()
+		p.IPv4Port = uint16(pa.IPv4.Port)
+	}
+	if pa.IPv6 != nil {
+		p.IPv6 = pa.IPv6.IP.To16()
+		p.IPv6Port = uint16(pa.IPv6.Port)
+	}
+	return p
+}
+
+// selectPreferredAddress selects the address from the preferred_address that has the same address family as remoteAddr.
+// It returns nil if the server didn't send an address of this family.
+func selectPreferredAddress(pa *handshake.PreferredAddress, remoteAddr net.Addr) *net.UDPAddr {
+	udpAddr, ok := remoteAddr.(*net.UDPAddr)
+	if !ok {
+		return nil
+	}
+	ip, port := pa.IPv6, pa.IPv6Port
+	if udpAddr.IP.To4() != nil {
+		ip, port = pa.IPv4, pa.IPv4Port
+	}
+	if len(ip) == 0 || ip.IsUnspecified() || port == 0 {
+		return nil
+	}
+	return &net.UDPAddr{IP: ip, Port: int(port)}
+}
+
+// A preferredAddressPath is the path to the server's preferred address.
+// The client validates this path after completing the handshake, and migrates to it if the validation succeeds.
+type preferredAddressPath struct {
+	pathValidator
+
+	addr       net.Addr
+	connID     protocol.ConnectionID
+	resetToken [16]byte
+}
+
+func newPreferredAddressPath(addr net.Addr, pa *handshake.PreferredAddress) *preferredAddressPath {
+	return &preferredAddressPath{
+		addr:       addr,
+		connID:     pa.ConnectionID,
+		resetToken: pa.StatelessResetToken,
+	}
+}
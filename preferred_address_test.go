@@ -0,0 +1,72 @@
+package quic
+
+import (
+	"net"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Preferred Address", func() {
+	Context("creating the transport parameter", func() {
+		It("uses both addresses", func() {
+			p := newPreferredAddressParameter(&PreferredAddress{
+				IPv4: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234},
+				IPv6: &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 4321},
+			}, protocol.ConnectionID{1, 2, 3, 4}, [16]byte{42})
+			Expect(p.IPv4).To(Equal(net.IPv4(127, 0, 0, 1).To4()))
+			Expect(p.IPv4Port).To(BeEquivalentTo(1234))
+			Expect(p.IPv6).To(Equal(net.ParseIP("2001:db8::1")))
+			Expect(p.IPv6Port).To(BeEquivalentTo(4321))
+			Expect(p.ConnectionID).To(Equal(protocol.ConnectionID{1, 2, 3, 4}))
+			Expect(p.StatelessResetToken).To(Equal([16]byte{42}))
+		})
+
+		It("uses the unspecified address if an address is not set", func() {
+			p := newPreferredAddressParameter(&PreferredAddress{
+				IPv4: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234},
+			}, protocol.ConnectionID{1, 2, 3, 4}, [16]byte{42})
+			Expect(p.IPv6.IsUnspecified()).To(BeTrue())
+			Expect(p.IPv6).To(HaveLen(16))
+			Expect(p.IPv6Port).To(BeZero())
+		})
+	})
+
+	Context("selecting the address", func() {
+		pa := &handshake.PreferredAddress{
+			IPv4:     net.IPv4(127, 0, 0, 1).To4(),
+			IPv4Port: 1234,
+			IPv6:     net.ParseIP("2001:db8::1"),
+			IPv6Port: 4321,
+		}
+
+		It("selects the IPv4 address", func() {
+			addr := selectPreferredAddress(pa, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 443})
+			Expect(addr).To(Equal(&net.UDPAddr{IP: pa.IPv4, Port: 1234}))
+		})
+
+		It("selects the IPv6 address", func() {
+			addr := selectPreferredAddress(pa, &net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 443})
+			Expect(addr).To(Equal(&net.UDPAddr{IP: pa.IPv6, Port: 4321}))
+		})
+
+		It("doesn't select an unspecified address", func() {
+			p := *pa
+			p.IPv4 = net.IPv4zero.To4()
+			Expect(selectPreferredAddress(&p, &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 443})).To(BeNil())
+		})
+
+		It("doesn't select an address with port 0", func() {
+			p := *pa
+			p.IPv6Port = 0
+			Expect(selectPreferredAddress(&p, &net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 443})).To(BeNil())
+		})
+
+		It("doesn't select an address if the remote address is not a UDP address", func() {
+			Expect(selectPreferredAddress(pa, &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 443})).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,84 @@
+package quiclb
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// ErrUnroutable is returned by the Decoder if a connection ID doesn't encode a server ID.
+// This is the case for the connection IDs that clients choose for their first Initial packets,
+// and for connection IDs of unknown configurations.
+// Load balancers should route these packets using a different mechanism, e.g. by hashing the connection ID.
+var ErrUnroutable = errors.New("quiclb: connection ID is not routable")
+
+type decoderConfig struct {
+	Config
+	block cipher.Block
+}
+
+// A Decoder extracts the server ID from connection IDs.
+// It is used by the load balancer.
+type Decoder struct {
+	configs [MaxConfigID + 1]*decoderConfig
+}
+
+// NewDecoder creates a new Decoder.
+// Multiple configurations can be used at the same time, as long as they use different config IDs.
+func NewDecoder(configs ...*Config) (*Decoder, error) {
+	d := &Decoder{}
+	for _, c := range configs {
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+		if d.configs[c.ConfigID] != nil {
+			return nil, fmt.Errorf("quiclb: duplicate config ID %d", c.ConfigID)
+		}
+		block, err := c.newBlock()
+		if err != nil {
+			return nil, err
+		}
+		d.configs[c.ConfigID] = &decoderConfig{Config: *c, block: block}
+	}
+	return d, nil
+}
+
+// ServerID returns the server ID encoded in a connection ID.
+func (d *Decoder) ServerID(connID []byte) ([]byte, error) {
+	if len(connID) == 0 {
+		return nil, ErrUnroutable
+	}
+	configID := connID[0] >> 5
+	if configID == unroutableConfigID {
+		return nil, ErrUnroutable
+	}
+	c := d.configs[configID]
+	if c == nil || len(connID) != c.ConnectionIDLen() {
+		return nil, ErrUnroutable
+	}
+	if c.block == nil {
+		return append([]byte{}, connID[1:1+c.ServerIDLen]...), nil
+	}
+	b := make([]byte, blockSize)
+	c.block.Decrypt(b, connID[1:])
+	return b[:c.ServerIDLen], nil
+}
+
+// ServerIDFromPacket returns the server ID encoded in the destination connection ID of a QUIC packet.
+// For packets with a short header, the length of the connection ID is taken from its first octet.
+func (d *Decoder) ServerIDFromPacket(data []byte) ([]byte, error) {
+	if len(data) < 2 {
+		return nil, errors.New("quiclb: packet too small")
+	}
+	var shortHeaderConnIDLen int
+	if data[0]&0x80 == 0 {
+		shortHeaderConnIDLen = int(data[1]&0x1f) + 1
+	}
+	connID, err := wire.ParseConnectionID(data, shortHeaderConnIDLen)
+	if err != nil {
+		return nil, err
+	}
+	return d.ServerID(connID)
+}
@@ -0,0 +1,122 @@
+package quiclb
+
+import (
+	"bytes"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Decoder", func() {
+	plaintextConf := &Config{ConfigID: 0, ServerIDLen: 2, NonceLen: 6}
+	encryptedConf := &Config{
+		ConfigID:    3,
+		ServerIDLen: 5,
+		NonceLen:    11,
+		Key:         []byte{15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0},
+	}
+
+	var decoder *Decoder
+
+	BeforeEach(func() {
+		var err error
+		decoder, err = NewDecoder(plaintextConf, encryptedConf)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	generate := func(conf *Config, serverID []byte) []byte {
+		g, err := NewGenerator(conf, serverID)
+		Expect(err).ToNot(HaveOccurred())
+		connID, err := g.GenerateConnectionID()
+		Expect(err).ToNot(HaveOccurred())
+		return connID
+	}
+
+	It("decodes plaintext connection IDs", func() {
+		serverID, err := decoder.ServerID(generate(plaintextConf, []byte{0x13, 0x37}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serverID).To(Equal([]byte{0x13, 0x37}))
+	})
+
+	It("decodes encrypted connection IDs", func() {
+		for i := 0; i < 10; i++ {
+			serverID, err := decoder.ServerID(generate(encryptedConf, []byte{1, 2, 3, 4, 5}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serverID).To(Equal([]byte{1, 2, 3, 4, 5}))
+		}
+	})
+
+	It("doesn't decode connection IDs encrypted with a different key", func() {
+		conf := *encryptedConf
+		conf.Key = bytes.Repeat([]byte{0x42}, 16)
+		serverID, err := decoder.ServerID(generate(&conf, []byte{1, 2, 3, 4, 5}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serverID).ToNot(Equal([]byte{1, 2, 3, 4, 5}))
+	})
+
+	It("rejects duplicate config IDs", func() {
+		_, err := NewDecoder(plaintextConf, &Config{ConfigID: 0, ServerIDLen: 3, NonceLen: 4})
+		Expect(err).To(MatchError("quiclb: duplicate config ID 0"))
+	})
+
+	It("doesn't route unroutable connection IDs", func() {
+		_, err := decoder.ServerID(nil)
+		Expect(err).To(MatchError(ErrUnroutable))
+		// config ID 0b111
+		_, err = decoder.ServerID([]byte{0xe7, 1, 2, 3, 4, 5, 6, 7})
+		Expect(err).To(MatchError(ErrUnroutable))
+		// unknown config ID
+		_, err = decoder.ServerID([]byte{0x27, 1, 2, 3, 4, 5, 6, 7})
+		Expect(err).To(MatchError(ErrUnroutable))
+		// wrong length
+		connID := generate(plaintextConf, []byte{0x13, 0x37})
+		_, err = decoder.ServerID(connID[:len(connID)-1])
+		Expect(err).To(MatchError(ErrUnroutable))
+	})
+
+	Context("parsing packets", func() {
+		It("decodes the connection ID of a short header packet", func() {
+			connID := generate(encryptedConf, []byte{1, 2, 3, 4, 5})
+			hdr := &wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: protocol.ConnectionID(connID)},
+				PacketNumber:    0x42,
+				PacketNumberLen: protocol.PacketNumberLen2,
+			}
+			buf := &bytes.Buffer{}
+			Expect(hdr.Write(buf, protocol.VersionTLS)).To(Succeed())
+			buf.Write([]byte("foobar"))
+			serverID, err := decoder.ServerIDFromPacket(buf.Bytes())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serverID).To(Equal([]byte{1, 2, 3, 4, 5}))
+		})
+
+		It("decodes the connection ID of a long header packet", func() {
+			connID := generate(plaintextConf, []byte{0x13, 0x37})
+			hdr := &wire.ExtendedHeader{
+				Header: wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeHandshake,
+					DestConnectionID: protocol.ConnectionID(connID),
+					SrcConnectionID:  protocol.ConnectionID{1, 2, 3, 4},
+					Version:          protocol.VersionTLS,
+					Length:           10,
+				},
+				PacketNumber:    0x42,
+				PacketNumberLen: protocol.PacketNumberLen2,
+			}
+			buf := &bytes.Buffer{}
+			Expect(hdr.Write(buf, protocol.VersionTLS)).To(Succeed())
+			serverID, err := decoder.ServerIDFromPacket(buf.Bytes())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serverID).To(Equal([]byte{0x13, 0x37}))
+		})
+
+		It("errors on packets that are too small", func() {
+			_, err := decoder.ServerIDFromPacket([]byte{0x40})
+			Expect(err).To(MatchError("quiclb: packet too small"))
+		})
+	})
+})
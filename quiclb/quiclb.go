@@ -0,0 +1,141 @@
+// Package quiclb implements load balancer routable connection IDs, following the encoding of the
+// QUIC-LB draft (draft-ietf-quic-load-balancers).
+//
+// Servers use a Generator as the quic.Config.ConnectionIDGenerator. It embeds the server ID into every
+// connection ID that the server issues, so that a stateless load balancer can use a Decoder to route all
+// packets of a connection to the same server, even after the client migrated to a new address or switched
+// to a new connection ID.
+//
+// The first octet of the connection ID encodes the config ID in its three most significant bits,
+// and the length of the connection ID (minus one) in the remaining five bits.
+// In plaintext mode, it is followed by the server ID and a random nonce.
+// In block cipher mode, server ID and nonce are encrypted using AES-128 as a single 16 byte block.
+// The four-pass encryption the draft uses for other lengths is not supported.
+package quiclb
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+const (
+	// MaxConfigID is the largest config ID.
+	// The config ID 0b111 is reserved for connection IDs that are not routable.
+	MaxConfigID = 6
+	// MaxServerIDLen is the maximum length of the server ID
+	MaxServerIDLen = 15
+	// MinNonceLen is the minimum length of the nonce
+	MinNonceLen = 4
+	// MaxNonceLen is the maximum length of the nonce
+	MaxNonceLen = 18
+
+	unroutableConfigID = 0x7
+	maxConnIDLen       = 20
+	blockSize          = aes.BlockSize
+)
+
+// A Config is a QUIC-LB configuration.
+// The same configuration must be used by the servers and the load balancer.
+type Config struct {
+	// ConfigID identifies the configuration. It allows rotating configurations without breaking existing connections.
+	// It must be a value between 0 and MaxConfigID.
+	ConfigID uint8
+	// ServerIDLen is the length of the server ID.
+	// It must be a value between 1 and MaxServerIDLen.
+	ServerIDLen int
+	// NonceLen is the length of the nonce.
+	// It must be a value between MinNonceLen and MaxNonceLen, and ServerIDLen + NonceLen must not exceed 19.
+	NonceLen int
+	// Key is a 16 byte AES-128 key.
+	// If set, block cipher mode is used, and ServerIDLen + NonceLen must be 16.
+	// If not set, the server ID is sent in plaintext.
+	Key []byte
+}
+
+// ConnectionIDLen returns the length of the connection IDs.
+func (c *Config) ConnectionIDLen() int {
+	return 1 + c.ServerIDLen + c.NonceLen
+}
+
+func (c *Config) validate() error {
+	if c.ConfigID > MaxConfigID {
+		return fmt.Errorf("quiclb: invalid config ID %d", c.ConfigID)
+	}
+	if c.ServerIDLen < 1 || c.ServerIDLen > MaxServerIDLen {
+		return fmt.Errorf("quiclb: invalid server ID length %d", c.ServerIDLen)
+	}
+	if c.NonceLen < MinNonceLen || c.NonceLen > MaxNonceLen {
+		return fmt.Errorf("quiclb: invalid nonce length %d", c.NonceLen)
+	}
+	if c.ConnectionIDLen() > maxConnIDLen {
+		return fmt.Errorf("quiclb: connection ID too long (%d bytes)", c.ConnectionIDLen())
+	}
+	if c.Key != nil && c.ServerIDLen+c.NonceLen != blockSize {
+		return fmt.Errorf("quiclb: block cipher mode requires server ID and nonce to be %d bytes long", blockSize)
+	}
+	return nil
+}
+
+func (c *Config) newBlock() (cipher.Block, error) {
+	if c.Key == nil {
+		return nil, nil
+	}
+	if len(c.Key) != 16 {
+		return nil, errors.New("quiclb: key must be 16 bytes long")
+	}
+	return aes.NewCipher(c.Key)
+}
+
+func (c *Config) firstOctet() byte {
+	return c.ConfigID<<5 | byte(c.ConnectionIDLen()-1)
+}
+
+// A Generator generates connection IDs that encode a server ID.
+// It implements the quic.ConnectionIDGenerator interface.
+type Generator struct {
+	config   Config
+	serverID []byte
+	block    cipher.Block // nil in plaintext mode
+}
+
+// NewGenerator creates a new Generator for a server.
+// The serverID must be config.ServerIDLen bytes long.
+func NewGenerator(config *Config, serverID []byte) (*Generator, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if len(serverID) != config.ServerIDLen {
+		return nil, fmt.Errorf("quiclb: expected a %d byte server ID, got %d bytes", config.ServerIDLen, len(serverID))
+	}
+	block, err := config.newBlock()
+	if err != nil {
+		return nil, err
+	}
+	return &Generator{
+		config:   *config,
+		serverID: append([]byte{}, serverID...),
+		block:    block,
+	}, nil
+}
+
+// GenerateConnectionID generates a new connection ID.
+func (g *Generator) GenerateConnectionID() ([]byte, error) {
+	b := make([]byte, g.config.ConnectionIDLen())
+	b[0] = g.config.firstOctet()
+	copy(b[1:], g.serverID)
+	if _, err := rand.Read(b[1+g.config.ServerIDLen:]); err != nil {
+		return nil, err
+	}
+	if g.block != nil {
+		g.block.Encrypt(b[1:], b[1:])
+	}
+	return b, nil
+}
+
+// ConnectionIDLen returns the length of the connection IDs.
+func (g *Generator) ConnectionIDLen() int {
+	return g.config.ConnectionIDLen()
+}
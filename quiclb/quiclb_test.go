@@ -0,0 +1,64 @@
+package quiclb
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Generator", func() {
+	key := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+	It("generates plaintext connection IDs", func() {
+		g, err := NewGenerator(&Config{ConfigID: 2, ServerIDLen: 3, NonceLen: 5}, []byte{0xde, 0xad, 0xbe})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(g.ConnectionIDLen()).To(Equal(9))
+		c1, err := g.GenerateConnectionID()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c1).To(HaveLen(9))
+		Expect(c1[0]).To(Equal(byte(2<<5 | 8)))
+		Expect(c1[1:4]).To(Equal([]byte{0xde, 0xad, 0xbe}))
+		c2, err := g.GenerateConnectionID()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c2[:4]).To(Equal(c1[:4]))
+		Expect(c2[4:]).ToNot(Equal(c1[4:]))
+	})
+
+	It("generates encrypted connection IDs", func() {
+		g, err := NewGenerator(&Config{ConfigID: 1, ServerIDLen: 4, NonceLen: 12, Key: key}, []byte{0xde, 0xad, 0xbe, 0xef})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(g.ConnectionIDLen()).To(Equal(17))
+		connID, err := g.GenerateConnectionID()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(connID).To(HaveLen(17))
+		Expect(connID[0]).To(Equal(byte(1<<5 | 16)))
+		Expect(bytes.Contains(connID, []byte{0xde, 0xad, 0xbe, 0xef})).To(BeFalse())
+	})
+
+	It("rejects invalid configurations", func() {
+		for _, c := range []struct {
+			config *Config
+			err    string
+		}{
+			{&Config{ConfigID: 7, ServerIDLen: 2, NonceLen: 4}, "quiclb: invalid config ID 7"},
+			{&Config{ServerIDLen: 0, NonceLen: 4}, "quiclb: invalid server ID length 0"},
+			{&Config{ServerIDLen: 16, NonceLen: 4}, "quiclb: invalid server ID length 16"},
+			{&Config{ServerIDLen: 2, NonceLen: 3}, "quiclb: invalid nonce length 3"},
+			{&Config{ServerIDLen: 2, NonceLen: 19}, "quiclb: invalid nonce length 19"},
+			{&Config{ServerIDLen: 15, NonceLen: 5}, "quiclb: connection ID too long (21 bytes)"},
+			{&Config{ServerIDLen: 2, NonceLen: 4, Key: key}, "quiclb: block cipher mode requires server ID and nonce to be 16 bytes long"},
+			{&Config{ServerIDLen: 2, NonceLen: 14, Key: key[:8]}, "quiclb: key must be 16 bytes long"},
+		} {
+			_, err := NewGenerator(c.config, make([]byte, c.config.ServerIDLen))
+			Expect(err).To(MatchError(c.err))
+			_, err = NewDecoder(c.config)
+			Expect(err).To(MatchError(c.err))
+		}
+	})
+
+	It("rejects server IDs of the wrong length", func() {
+		_, err := NewGenerator(&Config{ServerIDLen: 2, NonceLen: 4}, []byte{1, 2, 3})
+		Expect(err).To(MatchError("quiclb: expected a 2 byte server ID, got 3 bytes"))
+	})
+})
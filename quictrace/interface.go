@@ -13,6 +13,15 @@ type Tracer interface {
 	GetAllTraces() map[string][]byte
 }
 
+// A ConnectionCloseTracer is a Tracer that is notified when a connection is closed.
+// Tracers that implement this interface can release the resources used for a connection.
+type ConnectionCloseTracer interface {
+	Tracer
+	// ConnectionClosed is called when a connection is closed.
+	// No more events will be traced for this connection ID.
+	ConnectionClosed(protocol.ConnectionID) error
+}
+
 // EventType is the type of an event
 type EventType uint8
 
@@ -23,6 +32,11 @@ const (
 	PacketReceived
 	// PacketLost means that a packet was lost
 	PacketLost
+	// CongestionWindowUpdated means that the congestion window changed
+	CongestionWindowUpdated
+	// FramesRetransmitted means that frames were queued for retransmission,
+	// either because the packet carrying them was lost, or because it was used as a probe packet
+	FramesRetransmitted
 )
 
 // Event is a quic-traceable event
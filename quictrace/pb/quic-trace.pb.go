@@ -204,6 +204,10 @@ const (
 	// (available bandwidth, RTT, congestion window, etc) is supplied to the
 	// sender.
 	EventType_EXTERNAL_PARAMETERS EventType = 5
+	// Frames were queued for retransmission, either because the packet
+	// carrying them was lost, or because it was used as a probe packet.
+	// This event type is a quic-go extension.
+	EventType_FRAMES_RETRANSMITTED EventType = 6
 )
 
 var EventType_name = map[int32]string{
@@ -213,15 +217,17 @@ var EventType_name = map[int32]string{
 	3: "PACKET_LOST",
 	4: "APPLICATION_LIMITED",
 	5: "EXTERNAL_PARAMETERS",
+	6: "FRAMES_RETRANSMITTED",
 }
 
 var EventType_value = map[string]int32{
-	"UNKNOWN_EVENT":       0,
-	"PACKET_SENT":         1,
-	"PACKET_RECEIVED":     2,
-	"PACKET_LOST":         3,
-	"APPLICATION_LIMITED": 4,
-	"EXTERNAL_PARAMETERS": 5,
+	"UNKNOWN_EVENT":        0,
+	"PACKET_SENT":          1,
+	"PACKET_RECEIVED":      2,
+	"PACKET_LOST":          3,
+	"APPLICATION_LIMITED":  4,
+	"EXTERNAL_PARAMETERS":  5,
+	"FRAMES_RETRANSMITTED": 6,
 }
 
 func (x EventType) Enum() *EventType {
@@ -1121,95 +1127,96 @@ func init() {
 func init() { proto.RegisterFile("quic-trace.proto", fileDescriptor_79ecf15e0416742d) }
 
 var fileDescriptor_79ecf15e0416742d = []byte{
-	// 1432 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x56, 0xdd, 0x6e, 0xe3, 0x44,
-	0x14, 0xae, 0xf3, 0x9f, 0x93, 0xa4, 0x76, 0xa7, 0xa5, 0x4d, 0xd9, 0x5d, 0xe8, 0x06, 0x54, 0x95,
-	0x0a, 0x56, 0xbb, 0x15, 0x17, 0x68, 0x57, 0x62, 0xe5, 0x26, 0x6e, 0xd7, 0x6a, 0x6a, 0x87, 0x89,
-	0xbb, 0x2c, 0x12, 0x30, 0x72, 0x9d, 0x49, 0x6b, 0xd5, 0xb1, 0x8d, 0x3d, 0x6d, 0xb7, 0x7b, 0x8b,
-	0xd0, 0x5e, 0xf2, 0x08, 0xbc, 0x07, 0x97, 0xf0, 0x44, 0xbc, 0x01, 0x9a, 0x19, 0x3b, 0x7f, 0x65,
-	0xc5, 0x9d, 0xe7, 0x3b, 0xdf, 0xf9, 0x99, 0xef, 0x9c, 0x33, 0x09, 0x68, 0xbf, 0x5c, 0xfb, 0xde,
-	0x57, 0x2c, 0x71, 0x3d, 0xfa, 0x24, 0x4e, 0x22, 0x16, 0xa1, 0x42, 0x7c, 0xde, 0x89, 0x41, 0x1d,
-	0xb2, 0x84, 0xba, 0x93, 0xa3, 0xc4, 0x9d, 0x50, 0x33, 0x1c, 0x47, 0xe8, 0x01, 0xd4, 0x53, 0x01,
-	0x11, 0x7f, 0xd4, 0x56, 0x76, 0x94, 0xbd, 0x12, 0xae, 0x49, 0xc0, 0x1c, 0x21, 0x0d, 0x8a, 0x63,
-	0x3f, 0x6c, 0x17, 0x76, 0x94, 0xbd, 0x1a, 0xe6, 0x9f, 0x68, 0x13, 0x2a, 0x01, 0x0d, 0x2f, 0xd8,
-	0x65, 0xbb, 0x28, 0xb8, 0xd9, 0x89, 0xe3, 0xd1, 0x78, 0x9c, 0x52, 0xd6, 0x2e, 0x49, 0x5c, 0x9e,
-	0x3a, 0x3a, 0xa8, 0xdd, 0xe4, 0x2e, 0x66, 0xd1, 0x2c, 0xe3, 0x2c, 0x84, 0xf2, 0x81, 0x10, 0x85,
-	0x85, 0x10, 0x16, 0xd4, 0x74, 0xef, 0xea, 0x30, 0x88, 0xbc, 0x2b, 0xf4, 0x18, 0x9a, 0x63, 0x3f,
-	0x49, 0x19, 0x89, 0x5d, 0xef, 0x8a, 0xb2, 0x2c, 0x42, 0x43, 0x60, 0x03, 0x01, 0xa1, 0x4f, 0xa1,
-	0x11, 0xb8, 0x33, 0x86, 0x8c, 0x05, 0x1c, 0x92, 0x84, 0xce, 0xcf, 0x50, 0xd5, 0xbd, 0x2b, 0x51,
-	0xca, 0x33, 0x68, 0x71, 0x6c, 0x94, 0x91, 0xd3, 0xb6, 0xb2, 0x53, 0xdc, 0x6b, 0x1c, 0x34, 0x9f,
-	0xc4, 0xe7, 0x4f, 0xf2, 0x9c, 0xb8, 0x29, 0x28, 0xd2, 0x39, 0x45, 0x3b, 0xc0, 0xcf, 0x64, 0x44,
-	0x03, 0xf7, 0x8e, 0x5c, 0xa7, 0x79, 0x7c, 0xd7, 0xbb, 0xea, 0x71, 0xe8, 0x2c, 0xed, 0xbc, 0x57,
-	0x40, 0xc5, 0x34, 0xa5, 0x4c, 0x4a, 0xfd, 0xff, 0x2a, 0x7f, 0x0d, 0x9b, 0x6e, 0x1c, 0x07, 0xbe,
-	0xe7, 0x32, 0x3f, 0x0a, 0x09, 0x4d, 0x92, 0x28, 0x21, 0x5e, 0x34, 0xa2, 0x22, 0x78, 0x0b, 0x6f,
-	0xcc, 0x59, 0x0d, 0x6e, 0xec, 0x46, 0x23, 0x2a, 0xa5, 0x08, 0xdd, 0x80, 0x64, 0xa2, 0x15, 0x73,
-	0x29, 0x42, 0x37, 0xb0, 0xa5, 0x72, 0x7f, 0x2a, 0x50, 0xef, 0x06, 0x51, 0x2a, 0x75, 0x7f, 0x04,
-	0x30, 0x17, 0x5a, 0x11, 0xa1, 0xeb, 0x74, 0x1a, 0xef, 0x33, 0x68, 0x25, 0xd4, 0x4d, 0xa3, 0x90,
-	0xc4, 0x97, 0x89, 0x9b, 0xca, 0xe4, 0x75, 0xdc, 0x94, 0xe0, 0x40, 0x60, 0xe8, 0x4b, 0x00, 0x8f,
-	0x07, 0x24, 0xec, 0x2e, 0xa6, 0x22, 0xe5, 0xea, 0x41, 0x8b, 0xab, 0x25, 0xd2, 0x38, 0x77, 0x31,
-	0xc5, 0x75, 0x2f, 0xff, 0x44, 0x2f, 0xe0, 0x63, 0x96, 0xb8, 0x61, 0x1a, 0x47, 0x09, 0x23, 0xd2,
-	0x6f, 0xcc, 0xc7, 0x40, 0x7a, 0xcb, 0x41, 0xd9, 0x9a, 0x32, 0x44, 0x08, 0x31, 0x26, 0xdc, 0xb9,
-	0x63, 0x82, 0x7a, 0x14, 0x44, 0xb7, 0xdd, 0x28, 0x64, 0x49, 0x14, 0x88, 0x1b, 0x6c, 0x43, 0x6d,
-	0xe2, 0xbe, 0x25, 0x23, 0x97, 0xb9, 0x99, 0x88, 0xd5, 0x89, 0xfb, 0xb6, 0xe7, 0x32, 0x77, 0x51,
-	0xe0, 0xc2, 0xa2, 0xc0, 0x9d, 0xdf, 0x8b, 0x50, 0x16, 0x81, 0x79, 0xfd, 0x73, 0x15, 0x28, 0xb3,
-	0xfa, 0xa7, 0x79, 0x71, 0x7d, 0x9c, 0x7f, 0xa2, 0x97, 0xb0, 0x96, 0x05, 0x95, 0x4e, 0x7e, 0x38,
-	0x8e, 0x44, 0xf0, 0xc6, 0xc1, 0x3a, 0x77, 0x5a, 0xda, 0x25, 0xac, 0xa6, 0x4b, 0xcb, 0xb5, 0x0b,
-	0x35, 0x3e, 0x2c, 0xc2, 0xaf, 0x28, 0xfc, 0x1a, 0xd9, 0x68, 0x09, 0x7e, 0xd5, 0xcd, 0xe6, 0xf0,
-	0x25, 0xac, 0x25, 0x7c, 0x62, 0x48, 0x7e, 0x07, 0xee, 0x50, 0x9a, 0x25, 0x5a, 0x1a, 0x27, 0xac,
-	0x26, 0x4b, 0xf3, 0x35, 0xed, 0x8b, 0xf0, 0x2c, 0x0b, 0xcf, 0x59, 0x5f, 0x84, 0x8f, 0xec, 0x4b,
-	0x9e, 0x6e, 0x1c, 0x44, 0xb7, 0xc4, 0x93, 0xda, 0x4a, 0xa7, 0xca, 0x2c, 0xdd, 0x92, 0xee, 0x58,
-	0x1d, 0x2f, 0x35, 0xe2, 0x25, 0xac, 0x79, 0x62, 0xab, 0xe7, 0x85, 0xa9, 0xce, 0x02, 0x2c, 0xad,
-	0x3c, 0x56, 0xbd, 0x45, 0xa0, 0xf3, 0x47, 0x01, 0x56, 0x9d, 0xbc, 0xf1, 0x43, 0xe6, 0x32, 0x8a,
-	0x1e, 0x02, 0x4c, 0xfc, 0x90, 0x24, 0x8c, 0xf1, 0xb5, 0xca, 0x76, 0x64, 0xe2, 0x87, 0x98, 0xb1,
-	0xb3, 0x14, 0xed, 0x82, 0x9a, 0x4e, 0xa2, 0x88, 0x5d, 0xd2, 0x51, 0x4e, 0x91, 0x5d, 0x6e, 0xe5,
-	0xb0, 0xe4, 0x7d, 0x92, 0x6d, 0x7f, 0xc6, 0x91, 0x4b, 0x51, 0xe7, 0xd0, 0x34, 0x8e, 0x1f, 0x92,
-	0x71, 0xe0, 0x5f, 0x5c, 0x32, 0x72, 0x7e, 0xc7, 0x68, 0x9a, 0xcd, 0x61, 0xcb, 0x0f, 0x8f, 0x04,
-	0x7a, 0xc8, 0x41, 0xbe, 0x2c, 0xde, 0x6d, 0x38, 0xca, 0x28, 0x65, 0x19, 0x86, 0x23, 0xd2, 0xbc,
-	0x0b, 0x6a, 0xec, 0x7a, 0x7e, 0x78, 0x41, 0x12, 0x97, 0x51, 0x72, 0x1e, 0xa7, 0x42, 0xbf, 0x12,
-	0x6e, 0x49, 0x18, 0xbb, 0x8c, 0x1e, 0xc6, 0x29, 0xfa, 0x06, 0xda, 0x5e, 0x14, 0x5e, 0xd0, 0x54,
-	0x6c, 0x76, 0xae, 0x77, 0xca, 0x2f, 0x2c, 0xf4, 0xaa, 0xe3, 0xcd, 0x99, 0x3d, 0x53, 0x58, 0xc8,
-	0xd1, 0xb9, 0x81, 0x6d, 0xe3, 0x2d, 0xa3, 0x49, 0xe8, 0x06, 0x16, 0x65, 0xb7, 0x51, 0x72, 0x35,
-	0x70, 0xb9, 0x7c, 0x8c, 0x26, 0x29, 0xdf, 0xd5, 0x73, 0x37, 0x1c, 0xdd, 0xfa, 0x23, 0x76, 0x29,
-	0x92, 0x4b, 0xb9, 0x9a, 0x53, 0x90, 0xe7, 0xfe, 0x08, 0x2a, 0x0b, 0x4a, 0x95, 0x13, 0xa1, 0xc0,
-	0xe2, 0xcd, 0x8a, 0x4b, 0x37, 0xeb, 0xfc, 0x53, 0x84, 0xb2, 0x71, 0x43, 0x43, 0x86, 0xb6, 0xa0,
-	0xca, 0xfc, 0x09, 0x9d, 0x75, 0xa3, 0xc2, 0x8f, 0x67, 0x29, 0x1f, 0x36, 0xca, 0x19, 0x72, 0x89,
-	0x0a, 0xb3, 0x25, 0x12, 0x7e, 0x72, 0x89, 0x68, 0xfe, 0xc9, 0x6b, 0x95, 0xaf, 0x2b, 0x09, 0xaf,
-	0x27, 0xe7, 0x34, 0xc9, 0x52, 0x36, 0x25, 0x68, 0x09, 0x0c, 0x3d, 0x86, 0x8a, 0x98, 0x24, 0xde,
-	0x0d, 0xfe, 0x02, 0xd7, 0xa7, 0x3b, 0x89, 0x33, 0x03, 0x7f, 0xd7, 0xb3, 0x38, 0xa9, 0xff, 0x8e,
-	0x66, 0x2d, 0x01, 0x09, 0x0d, 0xfd, 0x77, 0x14, 0x7d, 0x0b, 0x1a, 0x0d, 0xc5, 0xa0, 0x71, 0xad,
-	0x03, 0x7a, 0x43, 0x03, 0xd1, 0x94, 0x55, 0x39, 0x93, 0xc6, 0xd4, 0xd6, 0xe7, 0x26, 0xac, 0xd2,
-	0x45, 0x00, 0xbd, 0x00, 0x75, 0xf6, 0x5a, 0xcd, 0x5a, 0xd4, 0x38, 0x40, 0xdc, 0x7d, 0x71, 0x5a,
-	0xf1, 0x2a, 0x5b, 0x9c, 0xde, 0x9f, 0xe0, 0x01, 0xcd, 0xda, 0x45, 0x42, 0xd9, 0x2f, 0x12, 0x4f,
-	0x1b, 0xd6, 0xae, 0x89, 0x40, 0x8f, 0x44, 0x1d, 0x1f, 0xea, 0x2a, 0xde, 0xa6, 0x1f, 0x6c, 0xf8,
-	0x8f, 0xb0, 0x2e, 0x12, 0x4e, 0xfc, 0x34, 0xe5, 0xb7, 0x93, 0x8f, 0x72, 0xbb, 0x2e, 0xae, 0xb7,
-	0x39, 0xad, 0x2f, 0x33, 0x63, 0x61, 0x7d, 0xbe, 0x6e, 0xd9, 0xf8, 0x54, 0xef, 0x13, 0x07, 0xeb,
-	0xd6, 0xf0, 0xd4, 0x1c, 0x0e, 0x4d, 0xdb, 0xc2, 0x88, 0xdd, 0x23, 0x76, 0xfe, 0x56, 0xa0, 0xec,
-	0xf0, 0xbf, 0x0a, 0xe8, 0x0b, 0xd0, 0xc4, 0xbf, 0x05, 0x2f, 0x0a, 0xc8, 0x0d, 0x4d, 0x38, 0x47,
-	0x34, 0xbf, 0x89, 0xd5, 0x1c, 0x7f, 0x2d, 0x61, 0xf4, 0x14, 0x36, 0xd2, 0xe8, 0x3a, 0xf1, 0x28,
-	0x1f, 0xeb, 0x90, 0x7a, 0x42, 0xf5, 0xec, 0xf1, 0x6d, 0x62, 0x24, 0x6d, 0xdd, 0xa9, 0xc9, 0x1c,
-	0xa1, 0xe7, 0xb0, 0x3d, 0xe2, 0x93, 0x1e, 0xba, 0xf9, 0x36, 0xcc, 0xb9, 0x15, 0x85, 0xdb, 0xd6,
-	0x1c, 0x61, 0xc1, 0xf7, 0x31, 0x54, 0xc4, 0x48, 0x2d, 0x0c, 0x88, 0x98, 0x37, 0x9c, 0x19, 0xf6,
-	0xff, 0x52, 0xa0, 0x3e, 0x7d, 0xc6, 0xd1, 0x1a, 0xb4, 0xce, 0xac, 0x13, 0xcb, 0xfe, 0xde, 0x22,
-	0x47, 0x58, 0x3f, 0x35, 0xb4, 0x15, 0x04, 0x50, 0x19, 0x3a, 0xd8, 0xd0, 0x4f, 0x35, 0x05, 0x55,
-	0xa1, 0xa8, 0x77, 0x4f, 0xb4, 0x02, 0xd2, 0xa0, 0x89, 0x8d, 0xa1, 0xe1, 0x90, 0xcc, 0x54, 0x44,
-	0x1b, 0xa0, 0x75, 0x6d, 0xcb, 0x32, 0xba, 0x8e, 0x69, 0x5b, 0xa4, 0xdb, 0xb7, 0x87, 0x86, 0x56,
-	0x42, 0x4d, 0xa8, 0x9d, 0xea, 0x6f, 0x48, 0x4f, 0x77, 0x74, 0xad, 0x8c, 0xd6, 0x41, 0xe5, 0x27,
-	0xe9, 0x23, 0xc1, 0x0a, 0xaa, 0x41, 0x69, 0x60, 0x5a, 0xc7, 0x5a, 0x15, 0x35, 0xa0, 0x7a, 0xd8,
-	0xb7, 0xbb, 0x27, 0x46, 0x4f, 0xab, 0x21, 0x04, 0xab, 0x19, 0x2f, 0xc7, 0xea, 0x9c, 0x30, 0xd0,
-	0x7b, 0x3d, 0xce, 0x06, 0x5e, 0x57, 0x17, 0xff, 0x30, 0x70, 0x6c, 0xad, 0xb1, 0xff, 0x6b, 0xfe,
-	0x93, 0x2d, 0x2e, 0xb1, 0x03, 0x0f, 0x8f, 0x6d, 0xfb, 0xb8, 0x6f, 0x90, 0xef, 0xce, 0xcc, 0x2e,
-	0xb9, 0x57, 0xd6, 0x0a, 0xda, 0x83, 0xcf, 0x4d, 0xc3, 0x39, 0x92, 0x76, 0xd1, 0xe8, 0x81, 0x8d,
-	0x9d, 0xfb, 0x4c, 0x05, 0xed, 0xc3, 0xee, 0x8c, 0xa9, 0x0f, 0x06, 0x7d, 0xb3, 0xab, 0x4b, 0xc2,
-	0x32, 0xb7, 0xb0, 0xff, 0x9b, 0x02, 0xea, 0xd2, 0xbe, 0xa0, 0x4d, 0x40, 0x86, 0x25, 0xea, 0xe4,
-	0xcc, 0x4c, 0x5b, 0x6d, 0x65, 0x09, 0x37, 0x2d, 0xd3, 0x31, 0xf5, 0xbe, 0xa6, 0x70, 0x89, 0xe6,
-	0xf0, 0xa7, 0xd8, 0x71, 0xb4, 0xc2, 0x12, 0xf8, 0x8c, 0x83, 0x45, 0xd4, 0x86, 0x8d, 0x39, 0xf0,
-	0x95, 0x6e, 0xf5, 0x86, 0xaf, 0xf4, 0x13, 0x43, 0x2b, 0xed, 0xbf, 0x57, 0xa0, 0x3e, 0x7d, 0x54,
-	0xe6, 0x5b, 0x6a, 0xbc, 0x36, 0x2c, 0x47, 0x5b, 0x41, 0x2a, 0x34, 0x06, 0x7a, 0xf7, 0x84, 0xb7,
-	0x8f, 0x03, 0x22, 0x6b, 0x06, 0x60, 0xa3, 0x6b, 0x98, 0xaf, 0x8d, 0x9e, 0x56, 0x98, 0x63, 0xf5,
-	0xed, 0x21, 0xcf, 0xb8, 0x05, 0xeb, 0xf3, 0x0a, 0xf4, 0xcd, 0x53, 0xd3, 0x31, 0x7a, 0x5a, 0x89,
-	0x1b, 0x8c, 0x37, 0x8e, 0x81, 0x2d, 0xbd, 0x4f, 0x06, 0x3a, 0x9f, 0x1b, 0xc7, 0xc0, 0x43, 0xad,
-	0xbc, 0x9f, 0x00, 0xba, 0xbf, 0x61, 0x9c, 0xfe, 0x1f, 0x3b, 0xa6, 0xad, 0xf0, 0x32, 0x1c, 0xdd,
-	0xec, 0xf3, 0x7c, 0x43, 0x32, 0xc0, 0xf6, 0x21, 0xef, 0xc0, 0x06, 0x68, 0xd8, 0xb1, 0x17, 0xa9,
-	0x05, 0x7e, 0x7b, 0x4e, 0x30, 0xad, 0xe3, 0x45, 0x4b, 0xf1, 0xdf, 0x00, 0x00, 0x00, 0xff, 0xff,
-	0x4a, 0x0f, 0xb7, 0x51, 0xc4, 0x0b, 0x00, 0x00,
+	// 1444 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x56, 0xdd, 0x6e, 0xdb, 0x46,
+	0x16, 0x36, 0xf5, 0xaf, 0x23, 0xc9, 0x64, 0xc6, 0x5e, 0x5b, 0xde, 0x24, 0xbb, 0x8e, 0x76, 0x61,
+	0x78, 0x8d, 0xdd, 0x20, 0x31, 0xf6, 0x62, 0x91, 0x00, 0x1b, 0xd0, 0x12, 0xed, 0x10, 0x96, 0x29,
+	0x75, 0x44, 0xa7, 0x29, 0xd0, 0x76, 0x30, 0xa6, 0x46, 0x36, 0x61, 0x8a, 0x64, 0xc9, 0xb1, 0x1d,
+	0xe7, 0xb6, 0x28, 0x7a, 0xd9, 0x47, 0x28, 0xfa, 0x1a, 0xbd, 0x6c, 0x9f, 0xa8, 0x6f, 0x50, 0xcc,
+	0x0c, 0xa9, 0x3f, 0x37, 0xe8, 0x1d, 0xe7, 0x3b, 0xdf, 0xf9, 0x99, 0xef, 0x9c, 0x33, 0x12, 0x18,
+	0xdf, 0xdc, 0xf8, 0xde, 0x7f, 0x78, 0x42, 0x3d, 0xf6, 0x3c, 0x4e, 0x22, 0x1e, 0xa1, 0x42, 0x7c,
+	0xd1, 0x89, 0x41, 0x1f, 0xf1, 0x84, 0xd1, 0xe9, 0x71, 0x42, 0xa7, 0xcc, 0x0e, 0x27, 0x11, 0x7a,
+	0x0c, 0xf5, 0x54, 0x42, 0xc4, 0x1f, 0xb7, 0xb5, 0x5d, 0x6d, 0xbf, 0x84, 0x6b, 0x0a, 0xb0, 0xc7,
+	0xc8, 0x80, 0xe2, 0xc4, 0x0f, 0xdb, 0x85, 0x5d, 0x6d, 0xbf, 0x86, 0xc5, 0x27, 0xda, 0x82, 0x4a,
+	0xc0, 0xc2, 0x4b, 0x7e, 0xd5, 0x2e, 0x4a, 0x6e, 0x76, 0x12, 0x78, 0x34, 0x99, 0xa4, 0x8c, 0xb7,
+	0x4b, 0x0a, 0x57, 0xa7, 0x8e, 0x09, 0x7a, 0x37, 0xb9, 0x8f, 0x79, 0x34, 0xcf, 0x38, 0x0f, 0xa1,
+	0x7d, 0x22, 0x44, 0x61, 0x29, 0x84, 0x03, 0x35, 0xd3, 0xbb, 0x3e, 0x0a, 0x22, 0xef, 0x1a, 0x3d,
+	0x83, 0xe6, 0xc4, 0x4f, 0x52, 0x4e, 0x62, 0xea, 0x5d, 0x33, 0x9e, 0x45, 0x68, 0x48, 0x6c, 0x28,
+	0x21, 0xf4, 0x77, 0x68, 0x04, 0x74, 0xce, 0x50, 0xb1, 0x20, 0xa0, 0x39, 0xa1, 0xf3, 0x35, 0x54,
+	0x4d, 0xef, 0x5a, 0x96, 0xf2, 0x12, 0x5a, 0x02, 0x1b, 0x67, 0xe4, 0xb4, 0xad, 0xed, 0x16, 0xf7,
+	0x1b, 0x87, 0xcd, 0xe7, 0xf1, 0xc5, 0xf3, 0x3c, 0x27, 0x6e, 0x4a, 0x8a, 0x72, 0x4e, 0xd1, 0x2e,
+	0x88, 0x33, 0x19, 0xb3, 0x80, 0xde, 0x93, 0x9b, 0x34, 0x8f, 0x4f, 0xbd, 0xeb, 0x9e, 0x80, 0xce,
+	0xd3, 0xce, 0xf7, 0x1a, 0xe8, 0x98, 0xa5, 0x8c, 0x2b, 0xa9, 0xff, 0x5c, 0xe5, 0xff, 0xc2, 0x16,
+	0x8d, 0xe3, 0xc0, 0xf7, 0x28, 0xf7, 0xa3, 0x90, 0xb0, 0x24, 0x89, 0x12, 0xe2, 0x45, 0x63, 0x26,
+	0x83, 0xb7, 0xf0, 0xe6, 0x82, 0xd5, 0x12, 0xc6, 0x6e, 0x34, 0x66, 0x4a, 0x8a, 0x90, 0x06, 0x24,
+	0x13, 0xad, 0x98, 0x4b, 0x11, 0xd2, 0x60, 0xa0, 0x94, 0xfb, 0x59, 0x83, 0x7a, 0x37, 0x88, 0x52,
+	0xa5, 0xfb, 0x53, 0x80, 0x85, 0xd0, 0x9a, 0x0c, 0x5d, 0x67, 0xb3, 0x78, 0xff, 0x80, 0x56, 0xc2,
+	0x68, 0x1a, 0x85, 0x24, 0xbe, 0x4a, 0x68, 0xaa, 0x92, 0xd7, 0x71, 0x53, 0x81, 0x43, 0x89, 0xa1,
+	0x7f, 0x03, 0x78, 0x22, 0x20, 0xe1, 0xf7, 0x31, 0x93, 0x29, 0xd7, 0x0f, 0x5b, 0x42, 0x2d, 0x99,
+	0xc6, 0xbd, 0x8f, 0x19, 0xae, 0x7b, 0xf9, 0x27, 0x7a, 0x0d, 0x7f, 0xe5, 0x09, 0x0d, 0xd3, 0x38,
+	0x4a, 0x38, 0x51, 0x7e, 0x13, 0x31, 0x06, 0xca, 0x5b, 0x0d, 0xca, 0xf6, 0x8c, 0x21, 0x43, 0xc8,
+	0x31, 0x11, 0xce, 0x1d, 0x1b, 0xf4, 0xe3, 0x20, 0xba, 0xeb, 0x46, 0x21, 0x4f, 0xa2, 0x40, 0xde,
+	0x60, 0x07, 0x6a, 0x53, 0xfa, 0x81, 0x8c, 0x29, 0xa7, 0x99, 0x88, 0xd5, 0x29, 0xfd, 0xd0, 0xa3,
+	0x9c, 0x2e, 0x0b, 0x5c, 0x58, 0x16, 0xb8, 0xf3, 0x43, 0x11, 0xca, 0x32, 0xb0, 0xa8, 0x7f, 0xa1,
+	0x02, 0x6d, 0x5e, 0xff, 0x2c, 0x2f, 0xae, 0x4f, 0xf2, 0x4f, 0xf4, 0x06, 0x1e, 0x65, 0x41, 0x95,
+	0x93, 0x1f, 0x4e, 0x22, 0x19, 0xbc, 0x71, 0xb8, 0x21, 0x9c, 0x56, 0x76, 0x09, 0xeb, 0xe9, 0x32,
+	0x80, 0xf6, 0xa0, 0x26, 0x86, 0x45, 0xfa, 0x15, 0xa5, 0x5f, 0x23, 0x1b, 0x2d, 0xc9, 0xaf, 0x52,
+	0xf5, 0x21, 0x12, 0x25, 0x62, 0x62, 0x48, 0x7e, 0x07, 0xe1, 0x50, 0x9a, 0x27, 0x5a, 0x19, 0x27,
+	0xac, 0x27, 0xcb, 0xc0, 0xbc, 0x2f, 0xd2, 0xb3, 0x2c, 0x3d, 0xe7, 0x7d, 0x91, 0x3e, 0x75, 0x2f,
+	0xff, 0x14, 0xe9, 0x26, 0x41, 0x74, 0x47, 0x3c, 0xa5, 0xad, 0x72, 0xaa, 0xcc, 0xd3, 0xad, 0xe8,
+	0x8e, 0xf5, 0xc9, 0x4a, 0x23, 0xde, 0xc0, 0x23, 0x4f, 0x6e, 0xf5, 0xa2, 0x30, 0xd5, 0x79, 0x80,
+	0x95, 0x95, 0xc7, 0xba, 0xb7, 0x0c, 0x74, 0x7e, 0x2c, 0xc0, 0xba, 0x9b, 0x37, 0x7e, 0xc4, 0x29,
+	0x67, 0xe8, 0x09, 0xc0, 0xd4, 0x0f, 0x49, 0xc2, 0xb9, 0x58, 0xab, 0x6c, 0x47, 0xa6, 0x7e, 0x88,
+	0x39, 0x3f, 0x4f, 0xd1, 0x1e, 0xe8, 0xe9, 0x34, 0x8a, 0xf8, 0x15, 0x1b, 0xe7, 0x14, 0xd5, 0xe5,
+	0x56, 0x0e, 0x2b, 0xde, 0xdf, 0xb2, 0xed, 0xcf, 0x38, 0x6a, 0x29, 0xea, 0x02, 0x9a, 0xc5, 0xf1,
+	0x43, 0x32, 0x09, 0xfc, 0xcb, 0x2b, 0x4e, 0x2e, 0xee, 0x39, 0x4b, 0xb3, 0x39, 0x6c, 0xf9, 0xe1,
+	0xb1, 0x44, 0x8f, 0x04, 0x28, 0x96, 0xc5, 0xbb, 0x0b, 0xc7, 0x19, 0xa5, 0xac, 0xc2, 0x08, 0x44,
+	0x99, 0xf7, 0x40, 0x8f, 0xa9, 0xe7, 0x87, 0x97, 0x24, 0xa1, 0x9c, 0x91, 0x8b, 0x38, 0x95, 0xfa,
+	0x95, 0x70, 0x4b, 0xc1, 0x98, 0x72, 0x76, 0x14, 0xa7, 0xe8, 0x7f, 0xd0, 0xf6, 0xa2, 0xf0, 0x92,
+	0xa5, 0x72, 0xb3, 0x73, 0xbd, 0x53, 0x71, 0x61, 0xa9, 0x57, 0x1d, 0x6f, 0xcd, 0xed, 0x99, 0xc2,
+	0x52, 0x8e, 0xce, 0x2d, 0xec, 0x58, 0x1f, 0x38, 0x4b, 0x42, 0x1a, 0x38, 0x8c, 0xdf, 0x45, 0xc9,
+	0xf5, 0x90, 0x0a, 0xf9, 0x38, 0x4b, 0x52, 0xb1, 0xab, 0x17, 0x34, 0x1c, 0xdf, 0xf9, 0x63, 0x7e,
+	0x25, 0x93, 0x2b, 0xb9, 0x9a, 0x33, 0x50, 0xe4, 0xfe, 0x0b, 0x54, 0x96, 0x94, 0x2a, 0x27, 0x52,
+	0x81, 0xe5, 0x9b, 0x15, 0x57, 0x6e, 0xd6, 0xf9, 0xad, 0x08, 0x65, 0xeb, 0x96, 0x85, 0x1c, 0x6d,
+	0x43, 0x95, 0xfb, 0x53, 0x36, 0xef, 0x46, 0x45, 0x1c, 0xcf, 0x53, 0x31, 0x6c, 0x4c, 0x30, 0xd4,
+	0x12, 0x15, 0xe6, 0x4b, 0x24, 0xfd, 0xd4, 0x12, 0xb1, 0xfc, 0x53, 0xd4, 0xaa, 0x5e, 0x57, 0x12,
+	0xde, 0x4c, 0x2f, 0x58, 0x92, 0xa5, 0x6c, 0x2a, 0xd0, 0x91, 0x18, 0x7a, 0x06, 0x15, 0x39, 0x49,
+	0xa2, 0x1b, 0xe2, 0x05, 0xae, 0xcf, 0x76, 0x12, 0x67, 0x06, 0xf1, 0xae, 0x67, 0x71, 0x52, 0xff,
+	0x23, 0xcb, 0x5a, 0x02, 0x0a, 0x1a, 0xf9, 0x1f, 0x19, 0xfa, 0x3f, 0x18, 0x2c, 0x94, 0x83, 0x26,
+	0xb4, 0x0e, 0xd8, 0x2d, 0x0b, 0x64, 0x53, 0xd6, 0xd5, 0x4c, 0x5a, 0x33, 0x5b, 0x5f, 0x98, 0xb0,
+	0xce, 0x96, 0x01, 0xf4, 0x1a, 0xf4, 0xf9, 0x6b, 0x35, 0x6f, 0x51, 0xe3, 0x10, 0x09, 0xf7, 0xe5,
+	0x69, 0xc5, 0xeb, 0x7c, 0xe9, 0x8c, 0xbe, 0x82, 0xc7, 0x2c, 0x6b, 0x17, 0x09, 0x55, 0xbf, 0x48,
+	0x3c, 0x6b, 0x58, 0xbb, 0x26, 0x03, 0x3d, 0x95, 0x75, 0x7c, 0xaa, 0xab, 0x78, 0x87, 0x7d, 0xb2,
+	0xe1, 0x5f, 0xc2, 0x86, 0x4c, 0x38, 0xf5, 0xd3, 0x54, 0xdc, 0x4e, 0x3d, 0xca, 0xed, 0xba, 0xbc,
+	0xde, 0xd6, 0xac, 0xbe, 0xcc, 0x8c, 0xa5, 0xf5, 0xd5, 0x86, 0x33, 0xc0, 0x67, 0x66, 0x9f, 0xb8,
+	0xd8, 0x74, 0x46, 0x67, 0xf6, 0x68, 0x64, 0x0f, 0x1c, 0x8c, 0xf8, 0x03, 0x62, 0xe7, 0x57, 0x0d,
+	0xca, 0xae, 0xf8, 0xab, 0x80, 0xfe, 0x05, 0x86, 0xfc, 0xb7, 0xe0, 0x45, 0x01, 0xb9, 0x65, 0x89,
+	0xe0, 0xc8, 0xe6, 0x37, 0xb1, 0x9e, 0xe3, 0xef, 0x14, 0x8c, 0x5e, 0xc0, 0x66, 0x1a, 0xdd, 0x24,
+	0x1e, 0x13, 0x63, 0x1d, 0x32, 0x4f, 0xaa, 0x9e, 0x3d, 0xbe, 0x4d, 0x8c, 0x94, 0xad, 0x3b, 0x33,
+	0xd9, 0x63, 0xf4, 0x0a, 0x76, 0xc6, 0x62, 0xd2, 0x43, 0x9a, 0x6f, 0xc3, 0x82, 0x5b, 0x51, 0xba,
+	0x6d, 0x2f, 0x10, 0x96, 0x7c, 0x9f, 0x41, 0x45, 0x8e, 0xd4, 0xd2, 0x80, 0xc8, 0x79, 0xc3, 0x99,
+	0xe1, 0xe0, 0x17, 0x0d, 0xea, 0xb3, 0x67, 0x1c, 0x3d, 0x82, 0xd6, 0xb9, 0x73, 0xea, 0x0c, 0x3e,
+	0x77, 0xc8, 0x31, 0x36, 0xcf, 0x2c, 0x63, 0x0d, 0x01, 0x54, 0x46, 0x2e, 0xb6, 0xcc, 0x33, 0x43,
+	0x43, 0x55, 0x28, 0x9a, 0xdd, 0x53, 0xa3, 0x80, 0x0c, 0x68, 0x62, 0x6b, 0x64, 0xb9, 0x24, 0x33,
+	0x15, 0xd1, 0x26, 0x18, 0xdd, 0x81, 0xe3, 0x58, 0x5d, 0xd7, 0x1e, 0x38, 0xa4, 0xdb, 0x1f, 0x8c,
+	0x2c, 0xa3, 0x84, 0x9a, 0x50, 0x3b, 0x33, 0xdf, 0x93, 0x9e, 0xe9, 0x9a, 0x46, 0x19, 0x6d, 0x80,
+	0x2e, 0x4e, 0xca, 0x47, 0x81, 0x15, 0x54, 0x83, 0xd2, 0xd0, 0x76, 0x4e, 0x8c, 0x2a, 0x6a, 0x40,
+	0xf5, 0xa8, 0x3f, 0xe8, 0x9e, 0x5a, 0x3d, 0xa3, 0x86, 0x10, 0xac, 0x67, 0xbc, 0x1c, 0xab, 0x0b,
+	0xc2, 0xd0, 0xec, 0xf5, 0x04, 0x1b, 0x44, 0x5d, 0x5d, 0xfc, 0xc5, 0xd0, 0x1d, 0x18, 0x8d, 0x83,
+	0x6f, 0xf3, 0x9f, 0x6c, 0x79, 0x89, 0x5d, 0x78, 0x72, 0x32, 0x18, 0x9c, 0xf4, 0x2d, 0xf2, 0xd9,
+	0xb9, 0xdd, 0x25, 0x0f, 0xca, 0x5a, 0x43, 0xfb, 0xf0, 0x4f, 0xdb, 0x72, 0x8f, 0x95, 0x5d, 0x36,
+	0x7a, 0x38, 0xc0, 0xee, 0x43, 0xa6, 0x86, 0x0e, 0x60, 0x6f, 0xce, 0x34, 0x87, 0xc3, 0xbe, 0xdd,
+	0x35, 0x15, 0x61, 0x95, 0x5b, 0x38, 0xf8, 0x4e, 0x03, 0x7d, 0x65, 0x5f, 0xd0, 0x16, 0x20, 0xcb,
+	0x91, 0x75, 0x0a, 0x66, 0xa6, 0xad, 0xb1, 0xb6, 0x82, 0xdb, 0x8e, 0xed, 0xda, 0x66, 0xdf, 0xd0,
+	0x84, 0x44, 0x0b, 0xf8, 0x0b, 0xec, 0xba, 0x46, 0x61, 0x05, 0x7c, 0x29, 0xc0, 0x22, 0x6a, 0xc3,
+	0xe6, 0x02, 0xf8, 0xd6, 0x74, 0x7a, 0xa3, 0xb7, 0xe6, 0xa9, 0x65, 0x94, 0x0e, 0x7e, 0xd2, 0xa0,
+	0x3e, 0x7b, 0x54, 0x16, 0x5b, 0x6a, 0xbd, 0xb3, 0x1c, 0xd7, 0x58, 0x43, 0x3a, 0x34, 0x86, 0x66,
+	0xf7, 0x54, 0xb4, 0x4f, 0x00, 0x32, 0x6b, 0x06, 0x60, 0xab, 0x6b, 0xd9, 0xef, 0xac, 0x9e, 0x51,
+	0x58, 0x60, 0xf5, 0x07, 0x23, 0x91, 0x71, 0x1b, 0x36, 0x16, 0x15, 0xe8, 0xdb, 0x67, 0xb6, 0x6b,
+	0xf5, 0x8c, 0x92, 0x30, 0x58, 0xef, 0x5d, 0x0b, 0x3b, 0x66, 0x9f, 0x0c, 0x4d, 0x31, 0x37, 0xae,
+	0x85, 0x47, 0x46, 0x59, 0xd4, 0x28, 0xc7, 0x68, 0x44, 0xb0, 0x95, 0xed, 0x93, 0x2b, 0x5c, 0x2a,
+	0x07, 0x09, 0xa0, 0x87, 0xbb, 0x27, 0x02, 0xfd, 0xc1, 0xf6, 0x19, 0x6b, 0xa2, 0x40, 0xd7, 0xb4,
+	0xfb, 0xa2, 0x92, 0x11, 0x19, 0xe2, 0xc1, 0x91, 0xe8, 0xcd, 0x26, 0x18, 0xd8, 0x1d, 0x2c, 0x53,
+	0x0b, 0x22, 0xa7, 0x20, 0xd8, 0xce, 0xc9, 0xb2, 0xa5, 0xf8, 0xfb, 0x00, 0x24, 0x83, 0x22, 0xd5,
+	0xde, 0x0b, 0x00, 0x00,
 }
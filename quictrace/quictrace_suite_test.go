@@ -0,0 +1,13 @@
+package quictrace
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestQuicTrace(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "quic-trace Suite")
+}
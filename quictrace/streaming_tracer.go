@@ -0,0 +1,217 @@
+package quictrace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// DefaultMaxBufferedEvents is the number of events buffered per connection,
+// if StreamingTracerConfig.MaxBufferedEvents is not set.
+const DefaultMaxBufferedEvents = 1 << 10
+
+// TraceFileSuffix is the suffix of the files written by the streaming tracer.
+const TraceFileSuffix = ".qtr"
+
+// StreamingTracerConfig configures a tracer that writes traces to disk.
+type StreamingTracerConfig struct {
+	// Dir is the directory that the trace files are written to.
+	// It is created if it doesn't exist yet.
+	Dir string
+	// MaxBufferedEvents is the maximum number of events buffered for a connection.
+	// When this number is reached, the events are appended to the connection's trace file.
+	// If zero, DefaultMaxBufferedEvents is used.
+	MaxBufferedEvents int
+	// MaxFileSize is the size (in bytes) at which a trace file is rotated.
+	// Every file is a valid quic-trace protobuf on its own.
+	// If zero, trace files are never rotated.
+	MaxFileSize int64
+}
+
+// A StreamingTracer is a Tracer that writes the trace of every connection to a file.
+// Events are buffered in memory, and written to disk when the buffer is full,
+// when Flush is called, and when the connection is closed.
+type StreamingTracer interface {
+	ConnectionCloseTracer
+	// Flush writes all buffered events to disk.
+	Flush() error
+	// Close flushes all buffered events and closes all trace files.
+	// No more events may be traced after calling Close.
+	Close() error
+}
+
+type connectionTrace struct {
+	connID    protocol.ConnectionID
+	startTime time.Time
+	events    []Event
+
+	file        *os.File
+	fileSize    int64
+	numRotation int
+}
+
+type streamingTracer struct {
+	mutex sync.Mutex
+
+	config StreamingTracerConfig
+	traces map[string] /* conn ID */ *connectionTrace
+	closed bool
+}
+
+var _ StreamingTracer = &streamingTracer{}
+
+// NewStreamingTracer creates a new StreamingTracer
+func NewStreamingTracer(config *StreamingTracerConfig) (StreamingTracer, error) {
+	if config == nil || config.Dir == "" {
+		return nil, fmt.Errorf("quictrace: no trace directory configured")
+	}
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, err
+	}
+	conf := *config
+	if conf.MaxBufferedEvents <= 0 {
+		conf.MaxBufferedEvents = DefaultMaxBufferedEvents
+	}
+	return &streamingTracer{
+		config: conf,
+		traces: make(map[string]*connectionTrace),
+	}, nil
+}
+
+// Trace traces an event
+func (t *streamingTracer) Trace(connID protocol.ConnectionID, ev Event) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.closed {
+		return
+	}
+	ct, ok := t.traces[string(connID)]
+	if !ok {
+		ct = &connectionTrace{
+			connID:    connID,
+			startTime: ev.Time,
+			events:    make([]Event, 0, t.config.MaxBufferedEvents),
+		}
+		t.traces[string(connID)] = ct
+	}
+	ct.events = append(ct.events, ev)
+	if len(ct.events) >= t.config.MaxBufferedEvents {
+		// There's no way to report the error to the caller.
+		// Drop the buffered events, to make sure we don't exceed the memory limit.
+		if err := t.flush(ct); err != nil {
+			ct.events = ct.events[:0]
+		}
+	}
+}
+
+// ConnectionClosed writes all buffered events of this connection and closes the trace file.
+func (t *streamingTracer) ConnectionClosed(connID protocol.ConnectionID) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	ct, ok := t.traces[string(connID)]
+	if !ok {
+		return nil
+	}
+	delete(t.traces, string(connID))
+	flushErr := t.flush(ct)
+	if err := ct.close(); err != nil && flushErr == nil {
+		return err
+	}
+	return flushErr
+}
+
+// GetAllTraces returns an empty map.
+// The traces are written to the trace directory.
+func (t *streamingTracer) GetAllTraces() map[string][]byte {
+	return map[string][]byte{}
+}
+
+func (t *streamingTracer) Flush() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var firstErr error
+	for _, ct := range t.traces {
+		if err := t.flush(ct); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (t *streamingTracer) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var firstErr error
+	for key, ct := range t.traces {
+		if err := t.flush(ct); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if err := ct.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(t.traces, key)
+	}
+	t.closed = true
+	return firstErr
+}
+
+// flush appends the buffered events to the trace file.
+// Since protobuf messages can be concatenated, and repeated fields are merged when parsing,
+// the resulting file is a valid trace.
+func (t *streamingTracer) flush(ct *connectionTrace) error {
+	if len(ct.events) == 0 {
+		return nil
+	}
+	if ct.file != nil && t.config.MaxFileSize > 0 && ct.fileSize >= t.config.MaxFileSize {
+		if err := ct.close(); err != nil {
+			return err
+		}
+		ct.numRotation++
+	}
+	if ct.file == nil {
+		f, err := os.OpenFile(t.filename(ct), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		ct.file = f
+		ct.fileSize = 0
+	}
+	data, err := proto.Marshal(newTrace(ct.connID, ct.startTime, ct.events))
+	if err != nil {
+		return err
+	}
+	n, err := ct.file.Write(data)
+	ct.fileSize += int64(n)
+	if err != nil {
+		return err
+	}
+	ct.events = ct.events[:0]
+	return nil
+}
+
+func (t *streamingTracer) filename(ct *connectionTrace) string {
+	name := fmt.Sprintf("%x", []byte(ct.connID))
+	if ct.numRotation > 0 {
+		name = fmt.Sprintf("%s.%d", name, ct.numRotation)
+	}
+	return filepath.Join(t.config.Dir, name+TraceFileSuffix)
+}
+
+func (ct *connectionTrace) close() error {
+	if ct.file == nil {
+		return nil
+	}
+	err := ct.file.Close()
+	ct.file = nil
+	return err
+}
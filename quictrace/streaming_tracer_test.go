@@ -0,0 +1,165 @@
+package quictrace
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/quictrace/pb"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Streaming Tracer", func() {
+	var (
+		dir    string
+		tracer StreamingTracer
+		connID protocol.ConnectionID
+	)
+
+	newEvent := func(pn protocol.PacketNumber, t time.Time) Event {
+		return Event{
+			Time:            t,
+			EventType:       PacketSent,
+			EncryptionLevel: protocol.Encryption1RTT,
+			PacketNumber:    pn,
+			PacketSize:      1234,
+			Frames:          []wire.Frame{&wire.PingFrame{}},
+			TransportState:  &TransportState{CongestionWindow: 4321},
+		}
+	}
+
+	readTrace := func(filename string) *pb.Trace {
+		data, err := ioutil.ReadFile(filepath.Join(dir, filename))
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		trace := &pb.Trace{}
+		ExpectWithOffset(1, proto.Unmarshal(data, trace)).To(Succeed())
+		return trace
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "quictrace")
+		Expect(err).ToNot(HaveOccurred())
+		connID = protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("errors when no directory is configured", func() {
+		_, err := NewStreamingTracer(&StreamingTracerConfig{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("buffers events until the connection is closed", func() {
+		var err error
+		tracer, err = NewStreamingTracer(&StreamingTracerConfig{Dir: dir})
+		Expect(err).ToNot(HaveOccurred())
+		start := time.Now()
+		tracer.Trace(connID, newEvent(1, start))
+		tracer.Trace(connID, newEvent(2, start.Add(time.Millisecond)))
+		_, err = os.Stat(filepath.Join(dir, "deadbeef.qtr"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+		Expect(tracer.ConnectionClosed(connID)).To(Succeed())
+		trace := readTrace("deadbeef.qtr")
+		Expect(trace.DestinationConnectionId).To(Equal([]byte(connID)))
+		Expect(trace.Events).To(HaveLen(2))
+		Expect(trace.Events[0].GetPacketNumber()).To(BeEquivalentTo(1))
+		Expect(trace.Events[1].GetPacketNumber()).To(BeEquivalentTo(2))
+		Expect(trace.Events[1].GetTimeUs()).To(BeEquivalentTo(1000))
+		Expect(trace.Events[1].GetTransportState().GetCwndBytes()).To(BeEquivalentTo(4321))
+	})
+
+	It("writes events to disk when the buffer is full", func() {
+		var err error
+		tracer, err = NewStreamingTracer(&StreamingTracerConfig{Dir: dir, MaxBufferedEvents: 3})
+		Expect(err).ToNot(HaveOccurred())
+		start := time.Now()
+		for i := 0; i < 7; i++ {
+			tracer.Trace(connID, newEvent(protocol.PacketNumber(i), start.Add(time.Duration(i)*time.Second)))
+		}
+		// only the first 6 events were written so far
+		trace := readTrace("deadbeef.qtr")
+		Expect(trace.Events).To(HaveLen(6))
+		for i, ev := range trace.Events {
+			Expect(ev.GetPacketNumber()).To(BeEquivalentTo(i))
+			Expect(ev.GetTimeUs()).To(BeEquivalentTo(i * 1e6))
+		}
+		Expect(tracer.Flush()).To(Succeed())
+		Expect(readTrace("deadbeef.qtr").Events).To(HaveLen(7))
+	})
+
+	It("rotates trace files", func() {
+		var err error
+		tracer, err = NewStreamingTracer(&StreamingTracerConfig{
+			Dir:               dir,
+			MaxBufferedEvents: 1,
+			MaxFileSize:       1,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		start := time.Now()
+		tracer.Trace(connID, newEvent(1, start))
+		tracer.Trace(connID, newEvent(2, start.Add(time.Second)))
+		tracer.Trace(connID, newEvent(3, start.Add(2*time.Second)))
+		Expect(tracer.Close()).To(Succeed())
+		Expect(readTrace("deadbeef.qtr").Events).To(HaveLen(1))
+		Expect(readTrace("deadbeef.1.qtr").Events).To(HaveLen(1))
+		trace := readTrace("deadbeef.2.qtr")
+		Expect(trace.Events).To(HaveLen(1))
+		Expect(trace.Events[0].GetPacketNumber()).To(BeEquivalentTo(3))
+		// times are relative to the start of the connection, not to the start of the file
+		Expect(trace.Events[0].GetTimeUs()).To(BeEquivalentTo(2e6))
+	})
+
+	It("records congestion window updates", func() {
+		var err error
+		tracer, err = NewStreamingTracer(&StreamingTracerConfig{Dir: dir})
+		Expect(err).ToNot(HaveOccurred())
+		tracer.Trace(connID, Event{
+			Time:           time.Now(),
+			EventType:      CongestionWindowUpdated,
+			TransportState: &TransportState{CongestionWindow: 1337, SmoothedRTT: time.Millisecond},
+		})
+		Expect(tracer.ConnectionClosed(connID)).To(Succeed())
+		trace := readTrace("deadbeef.qtr")
+		Expect(trace.Events).To(HaveLen(1))
+		Expect(trace.Events[0].GetEventType()).To(Equal(pb.EventType_EXTERNAL_PARAMETERS))
+		Expect(trace.Events[0].GetExternalNetworkParameters().GetCwndBytes()).To(BeEquivalentTo(1337))
+		Expect(trace.Events[0].GetExternalNetworkParameters().GetRttUs()).To(BeEquivalentTo(1000))
+	})
+
+	It("records retransmissions", func() {
+		var err error
+		tracer, err = NewStreamingTracer(&StreamingTracerConfig{Dir: dir})
+		Expect(err).ToNot(HaveOccurred())
+		tracer.Trace(connID, Event{
+			Time:      time.Now(),
+			EventType: FramesRetransmitted,
+			Frames:    []wire.Frame{&wire.PingFrame{}},
+		})
+		Expect(tracer.ConnectionClosed(connID)).To(Succeed())
+		trace := readTrace("deadbeef.qtr")
+		Expect(trace.Events).To(HaveLen(1))
+		Expect(trace.Events[0].GetEventType()).To(Equal(pb.EventType_FRAMES_RETRANSMITTED))
+	})
+
+	It("doesn't trace events after closing", func() {
+		var err error
+		tracer, err = NewStreamingTracer(&StreamingTracerConfig{Dir: dir})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tracer.Close()).To(Succeed())
+		tracer.Trace(connID, newEvent(1, time.Now()))
+		Expect(tracer.ConnectionClosed(connID)).To(Succeed())
+		files, err := ioutil.ReadDir(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(BeEmpty())
+	})
+})
@@ -72,34 +72,47 @@ func (t *tracer) emitByConnIDAsString(connID string) ([]byte, error) {
 	if !ok {
 		return nil, fmt.Errorf("no trace found for connection ID %s", connID)
 	}
+	evs := make([]Event, len(events))
+	for i, ev := range events {
+		evs[i] = ev.ev
+	}
+	delete(t.events, connID)
+	if len(evs) == 0 {
+		return proto.Marshal(newTrace(protocol.ConnectionID(connID), time.Time{}, nil))
+	}
+	return proto.Marshal(newTrace(protocol.ConnectionID(connID), evs[0].Time, evs))
+}
+
+// newTrace creates the protobuf for a list of events.
+// The time of each event is encoded relative to startTime.
+func newTrace(connID protocol.ConnectionID, startTime time.Time, events []Event) *pb.Trace {
 	trace := &pb.Trace{
-		DestinationConnectionId: []byte{1, 2, 3, 4, 5, 6, 7, 8},
-		SourceConnectionId:      []byte{1, 2, 3, 4, 5, 6, 7, 8},
+		DestinationConnectionId: connID,
+		SourceConnectionId:      connID,
 		ProtocolVersion:         []byte{0xff, 0, 0, 19},
 		Events:                  make([]*pb.Event, len(events)),
 	}
-	var startTime time.Time
-	for i, ev := range events {
-		event := ev.ev
-		if i == 0 {
-			startTime = event.Time
-		}
-
+	for i, event := range events {
 		packetNumber := uint64(event.PacketNumber)
 		packetSize := uint64(event.PacketSize)
 
-		trace.Events[i] = &pb.Event{
+		ev := &pb.Event{
 			TimeUs:          durationToUs(event.Time.Sub(startTime)),
 			EventType:       getEventType(event.EventType),
-			PacketSize:      &packetSize,
-			PacketNumber:    &packetNumber,
 			TransportState:  getTransportState(event.TransportState),
 			EncryptionLevel: getEncryptionLevel(event.EncryptionLevel),
 			Frames:          getFrames(event.Frames),
 		}
+		switch event.EventType {
+		case PacketSent, PacketReceived, PacketLost:
+			ev.PacketSize = &packetSize
+			ev.PacketNumber = &packetNumber
+		case CongestionWindowUpdated:
+			ev.ExternalNetworkParameters = getExternalNetworkParameters(event.TransportState)
+		}
+		trace.Events[i] = ev
 	}
-	delete(t.events, connID)
-	return proto.Marshal(trace)
+	return trace
 }
 
 func getEventType(evType EventType) *pb.EventType {
@@ -111,6 +124,13 @@ func getEventType(evType EventType) *pb.EventType {
 		t = pb.EventType_PACKET_RECEIVED
 	case PacketLost:
 		t = pb.EventType_PACKET_LOST
+	case CongestionWindowUpdated:
+		// quic-trace doesn't have a dedicated event type for congestion window updates.
+		// The new congestion window is recorded in the external network parameters.
+		t = pb.EventType_EXTERNAL_PARAMETERS
+	case FramesRetransmitted:
+		// quic-trace doesn't have an event type for retransmissions, this is a quic-go extension.
+		t = pb.EventType_FRAMES_RETRANSMITTED
 	default:
 		panic("unknown event type")
 	}
@@ -182,6 +202,9 @@ func getFrames(wframes []wire.Frame) []*pb.Frame {
 }
 
 func getTransportState(state *TransportState) *pb.TransportState {
+	if state == nil {
+		return nil
+	}
 	bytesInFlight := uint64(state.BytesInFlight)
 	congestionWindow := uint64(state.CongestionWindow)
 	ccs := fmt.Sprintf("InSlowStart: %t, InRecovery: %t", state.InSlowStart, state.InRecovery)
@@ -195,6 +218,17 @@ func getTransportState(state *TransportState) *pb.TransportState {
 	}
 }
 
+func getExternalNetworkParameters(state *TransportState) *pb.ExternalNetworkParameters {
+	if state == nil {
+		return nil
+	}
+	congestionWindow := uint64(state.CongestionWindow)
+	return &pb.ExternalNetworkParameters{
+		RttUs:     durationToUs(state.SmoothedRTT),
+		CwndBytes: &congestionWindow,
+	}
+}
+
 func durationToUs(d time.Duration) *uint64 {
 	dur := uint64(d / 1000)
 	return &dur
@@ -17,8 +17,10 @@ type receiveStreamI interface {
 
 	handleStreamFrame(*wire.StreamFrame) error
 	handleResetStreamFrame(*wire.ResetStreamFrame) error
+	handleStreamDataBlockedFrame(*wire.StreamDataBlockedFrame)
 	closeForShutdown(error)
 	getWindowUpdate() protocol.ByteCount
+	setReceiveWindowSize(size, maxSize protocol.ByteCount)
 }
 
 type receiveStream struct {
@@ -315,6 +317,18 @@ func (s *receiveStream) getWindowUpdate() protocol.ByteCount {
 	return s.flowController.GetWindowUpdate()
 }
 
+func (s *receiveStream) handleStreamDataBlockedFrame(*wire.StreamDataBlockedFrame) {
+	s.flowController.ReceivedBlocked()
+}
+
+func (s *receiveStream) setReceiveWindowSize(size, maxSize protocol.ByteCount) {
+	s.flowController.SetReceiveWindowSize(size, maxSize)
+}
+
+func (s *receiveStream) FlowControlStats() FlowControlStats {
+	return toFlowControlStats(s.flowController.GetStats())
+}
+
 // signalRead performs a non-blocking send on the readChan
 func (s *receiveStream) signalRead() {
 	select {
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
@@ -654,5 +655,27 @@ var _ = Describe("Receive Stream", func() {
 			mockFC.EXPECT().GetWindowUpdate().Return(protocol.ByteCount(0x100))
 			Expect(str.getWindowUpdate()).To(Equal(protocol.ByteCount(0x100)))
 		})
+
+		It("tells the flow controller about STREAM_DATA_BLOCKED frames", func() {
+			mockFC.EXPECT().ReceivedBlocked()
+			str.handleStreamDataBlockedFrame(&wire.StreamDataBlockedFrame{StreamID: streamID, DataLimit: 0x1000})
+		})
+
+		It("sets the receive window size", func() {
+			mockFC.EXPECT().SetReceiveWindowSize(protocol.ByteCount(0x1000), protocol.ByteCount(0x2000))
+			str.setReceiveWindowSize(0x1000, 0x2000)
+		})
+
+		It("returns the flow control statistics", func() {
+			mockFC.EXPECT().GetStats().Return(flowcontrol.Stats{
+				ReceiveWindow:    0x1000,
+				BytesRead:        0x100,
+				PeerBlockedCount: 2,
+			})
+			stats := str.FlowControlStats()
+			Expect(stats.ReceiveWindow).To(BeEquivalentTo(0x1000))
+			Expect(stats.BytesRead).To(BeEquivalentTo(0x100))
+			Expect(stats.PeerBlockedCount).To(BeEquivalentTo(2))
+		})
 	})
 })
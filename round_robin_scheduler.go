@@ -1,9 +1,10 @@
 package quic
 
 import (
-	"fmt"
 	"net/http"
 	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 const roundRobinSchedulerName = "round-robin-scheduler"
@@ -17,14 +18,17 @@ type RoundRobinScheduler struct {
 	blockArriveChan chan *ResponseWriterControlBlock
 	// 并行 go 程数目
 	concurrentStreamNumber uint16
+
+	logger utils.Logger
 }
 
-// NewRoundRobinScheduler 初始化一个 roundRobinScheduler 实例并返回其指针
-func NewRoundRobinScheduler() *RoundRobinScheduler {
+// NewRoundRobinScheduler 初始化一个 roundRobinScheduler 实例并返回其指针, 调度器通过 logger 输出日志
+func NewRoundRobinScheduler(logger utils.Logger) *RoundRobinScheduler {
 	return &RoundRobinScheduler{
 		mutex:           sync.Mutex{},
 		name:            roundRobinSchedulerName,
 		blockArriveChan: make(chan *ResponseWriterControlBlock, 300),
+		logger:          logger,
 	}
 }
 
@@ -80,7 +84,7 @@ func (schd *RoundRobinScheduler) executeResponseWriter(block *ResponseWriterCont
 func (schd *RoundRobinScheduler) onStart() {
 	schd.mutex.Lock()
 	schd.concurrentStreamNumber++
-	fmt.Printf("%d\n", schd.concurrentStreamNumber)
+	schd.logger.Debugf("concurrent streams: %d", schd.concurrentStreamNumber)
 	schd.mutex.Unlock()
 }
 
@@ -1,10 +1,10 @@
 package quic
 
 import (
-	"fmt"
 	"net/http"
 	"sync"
 
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/order"
 )
 
@@ -13,10 +13,11 @@ type concurrentStreamCounter struct {
 	counter int
 }
 
-func (c *concurrentStreamCounter) OnStart() {
+// OnStart 在开始处理一个 stream 时调用, 并通过 logger 输出当前并发的 stream 数目
+func (c *concurrentStreamCounter) OnStart(logger utils.Logger) {
 	c.mutex.Lock()
 	c.counter++
-	fmt.Printf("%d\n", c.counter)
+	logger.Debugf("concurrent streams: %d", c.counter)
 	c.mutex.Unlock()
 }
 
@@ -63,21 +64,23 @@ var (
 	currentOrderList = order.YoutubeNetworkList
 )
 
-// InitResponseWriterScheduler 根据配置的调度器类型初始化对应的调度器实例
-func InitResponseWriterScheduler() ResponseWriterScheduler {
+// InitResponseWriterScheduler 根据配置的调度器类型初始化对应的调度器实例，调度器通过 logger 输出日志,
+// 通过 metrics 上报统计数据
+func InitResponseWriterScheduler(logger utils.Logger, metrics utils.Metrics) ResponseWriterScheduler {
 	var scheduler ResponseWriterScheduler
 	switch currentScheduler {
 	case roundRobinSchedulerName:
 		{
-			// fmt.Printf("using %v\n", roundRobinSchedulerName)
-			scheduler = NewRoundRobinScheduler()
+			scheduler = NewRoundRobinScheduler(logger)
 		}
 	case staticOrderSchedulerName:
 		{
-			// fmt.Printf("using %v\n", staticOrderSchedulerName)
-			scheduler = NewStaticOrderScheduler()
+			schd := NewStaticOrderScheduler()
+			schd.setMetrics(metrics)
+			scheduler = schd
 		}
 	}
+	logger.Debugf("using %v", scheduler.Name())
 	return scheduler
 }
 
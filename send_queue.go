@@ -4,14 +4,26 @@ type sendQueue struct {
 	queue     chan *packedPacket
 	closeChan chan struct{}
 	conn      connection
+
+	// only set if the connection supports batched writes
+	batchConn batchConnection
+	batchSize int
+	// only used by the Run go routine
+	batch []*packedPacket
+	raws  [][]byte
 }
 
 func newSendQueue(conn connection) *sendQueue {
 	s := &sendQueue{
 		conn:      conn,
 		closeChan: make(chan struct{}),
-		queue:     make(chan *packedPacket, 1),
+		batchSize: 1,
+	}
+	if bc, ok := conn.(batchConnection); ok && bc.BatchSize() > 1 {
+		s.batchConn = bc
+		s.batchSize = bc.BatchSize()
 	}
+	s.queue = make(chan *packedPacket, s.batchSize)
 	return s
 }
 
@@ -27,6 +39,12 @@ func (h *sendQueue) Run() error {
 			return nil
 		case p = <-h.queue:
 		}
+		if h.batchConn != nil {
+			if err := h.sendBatch(p); err != nil {
+				return err
+			}
+			continue
+		}
 		if err := h.conn.Write(p.raw); err != nil {
 			return err
 		}
@@ -34,6 +52,31 @@ func (h *sendQueue) Run() error {
 	}
 }
 
+// sendBatch sends p, together with all packets that are already queued.
+func (h *sendQueue) sendBatch(p *packedPacket) error {
+	h.batch = append(h.batch[:0], p)
+loop:
+	for len(h.batch) < h.batchSize {
+		select {
+		case p := <-h.queue:
+			h.batch = append(h.batch, p)
+		default:
+			break loop
+		}
+	}
+	h.raws = h.raws[:0]
+	for _, p := range h.batch {
+		h.raws = append(h.raws, p.raw)
+	}
+	if err := h.batchConn.WriteBatch(h.raws); err != nil {
+		return err
+	}
+	for _, p := range h.batch {
+		p.buffer.Release()
+	}
+	return nil
+}
+
 func (h *sendQueue) Close() {
 	close(h.closeChan)
 }
@@ -1,10 +1,40 @@
 package quic
 
 import (
+	"sync"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
+type mockBatchConnection struct {
+	*mockConnection
+
+	mutex   sync.Mutex
+	batches [][][]byte
+}
+
+var _ batchConnection = &mockBatchConnection{}
+
+func (m *mockBatchConnection) WriteBatch(packets [][]byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	batch := make([][]byte, len(packets))
+	for i, p := range packets {
+		batch[i] = append([]byte{}, p...)
+	}
+	m.batches = append(m.batches, batch)
+	return nil
+}
+
+func (m *mockBatchConnection) BatchSize() int { return 4 }
+
+func (m *mockBatchConnection) getBatches() [][][]byte {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.batches
+}
+
 var _ = Describe("Send Queue", func() {
 	var q *sendQueue
 	var c *mockConnection
@@ -63,4 +93,28 @@ var _ = Describe("Send Queue", func() {
 		q.Close()
 		Eventually(done).Should(BeClosed())
 	})
+
+	It("writes queued packets in batches", func() {
+		bc := &mockBatchConnection{mockConnection: newMockConnection()}
+		q = newSendQueue(bc)
+		for _, p := range []string{"foo", "bar", "baz", "qux"} {
+			q.Send(getPacket([]byte(p)))
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			q.Run()
+			close(done)
+		}()
+
+		Eventually(bc.getBatches).Should(HaveLen(1))
+		q.Send(getPacket([]byte("quux")))
+		Eventually(bc.getBatches).Should(HaveLen(2))
+		Expect(bc.getBatches()[0]).To(Equal([][]byte{[]byte("foo"), []byte("bar"), []byte("baz"), []byte("qux")}))
+		Expect(bc.getBatches()[1]).To(Equal([][]byte{[]byte("quux")}))
+		Expect(bc.written).To(BeEmpty())
+		q.Close()
+		Eventually(done).Should(BeClosed())
+	})
 })
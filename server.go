@@ -72,7 +72,7 @@ type baseServer struct {
 	sessionHandler packetHandlerManager
 
 	// set as a member, so they can be set in the tests
-	newSession func(connection, sessionRunner, protocol.ConnectionID /* original connection ID */, protocol.ConnectionID /* client dest connection ID */, protocol.ConnectionID /* destination connection ID */, protocol.ConnectionID /* source connection ID */, [16]byte, *Config, *tls.Config, *handshake.TokenGenerator, utils.Logger, protocol.VersionNumber) quicSession
+	newSession func(connection, sessionRunner, protocol.ConnectionID /* original connection ID */, protocol.ConnectionID /* client dest connection ID */, protocol.ConnectionID /* destination connection ID */, protocol.ConnectionID /* source connection ID */, [16]byte, *Config, *tls.Config, *handshake.TokenGenerator, bool /* enable 0-RTT */, utils.Logger, protocol.VersionNumber) quicSession
 
 	serverError error
 	errorChan   chan struct{}
@@ -81,7 +81,8 @@ type baseServer struct {
 	sessionQueue    chan quicSession
 	sessionQueueLen int32 // to be used as an atomic
 
-	logger utils.Logger
+	logger  utils.Logger
+	metrics *serverMetrics
 }
 
 var _ Listener = &baseServer{}
@@ -120,12 +121,7 @@ func listenAddr(addr string, tlsConf *tls.Config, config *Config, acceptEarly bo
 	if err != nil {
 		return nil, err
 	}
-	serv, err := listen(conn, tlsConf, config, acceptEarly)
-	if err != nil {
-		return nil, err
-	}
-	serv.createdPacketConn = true
-	return serv, nil
+	return listen(conn, tlsConf, config, acceptEarly, true)
 }
 
 // Listen listens for QUIC connections on a given net.PacketConn.
@@ -136,12 +132,13 @@ func listenAddr(addr string, tlsConf *tls.Config, config *Config, acceptEarly bo
 // Furthermore, it must define an application control (using NextProtos).
 // The quic.Config may be nil, in that case the default values will be used.
 func Listen(conn net.PacketConn, tlsConf *tls.Config, config *Config) (Listener, error) {
-	return listen(conn, tlsConf, config, false)
+	return listen(conn, tlsConf, config, false, false)
 }
 
 // ListenEarly works like Listen, but it returns sessions before the handshake completes.
+// It also enables 0-RTT. Config.Accept0RTT can be used to reject replayed 0-RTT data.
 func ListenEarly(conn net.PacketConn, tlsConf *tls.Config, config *Config) (EarlyListener, error) {
-	s, err := listen(conn, tlsConf, config, true)
+	s, err := listen(conn, tlsConf, config, true, false)
 	if err != nil {
 		return nil, err
 	}
@@ -149,17 +146,44 @@ func ListenEarly(conn net.PacketConn, tlsConf *tls.Config, config *Config) (Earl
 	return &earlyServer{s}, nil
 }
 
-func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarly bool) (*baseServer, error) {
+func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarly, createdPacketConn bool) (*baseServer, error) {
 	if tlsConf == nil {
 		return nil, errors.New("quic: tls.Config not set")
 	}
+	if config != nil && config.ConnectionIDGenerator != nil {
+		connIDLen := config.ConnectionIDGenerator.ConnectionIDLen()
+		if connIDLen < 4 || connIDLen > protocol.MaxConnIDLen {
+			return nil, fmt.Errorf("quic: invalid connection ID length for the ConnectionIDGenerator: %d", connIDLen)
+		}
+		if config.ConnectionIDLength != 0 && config.ConnectionIDLength != connIDLen {
+			return nil, fmt.Errorf("quic: ConnectionIDLength (%d) doesn't match the ConnectionIDGenerator (%d)", config.ConnectionIDLength, connIDLen)
+		}
+	}
 	config = populateServerConfig(config)
 	for _, v := range config.Versions {
 		if !protocol.IsValidVersion(v) {
 			return nil, fmt.Errorf("%s is not a valid QUIC version", v)
 		}
 	}
+	if pa := config.PreferredAddress; pa != nil {
+		if pa.IPv4 == nil && pa.IPv6 == nil {
+			return nil, errors.New("quic: PreferredAddress doesn't contain an address")
+		}
+		if pa.IPv4 != nil && pa.IPv4.IP.To4() == nil {
+			return nil, fmt.Errorf("quic: %s is not an IPv4 address", pa.IPv4)
+		}
+		if pa.IPv6 != nil && (pa.IPv6.IP.To16() == nil || pa.IPv6.IP.To4() != nil) {
+			return nil, fmt.Errorf("quic: %s is not an IPv6 address", pa.IPv6)
+		}
+	}
 
+	// Only set the ECN socket options on connections passed in by the application if it asked for it.
+	if createdPacketConn || config.EnableECN {
+		conn = newECNConn(conn)
+	}
+	if config.BatchedIO {
+		conn = newBatchConn(conn)
+	}
 	sessionHandler, err := getMultiplexer().AddConn(conn, config.ConnectionIDLength, config.StatelessResetKey)
 	if err != nil {
 		return nil, err
@@ -177,8 +201,10 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarl
 		sessionQueue:        make(chan quicSession),
 		errorChan:           make(chan struct{}),
 		newSession:          newSession,
-		logger:              utils.DefaultLogger.WithPrefix("server"),
+		logger:              utils.NewLogger(config.Logger, "server"),
+		metrics:             newServerMetrics(utils.MetricsOrNop(config.Metrics)),
 		acceptEarlySessions: acceptEarly,
+		createdPacketConn:   createdPacketConn,
 	}
 	sessionHandler.SetServer(s)
 	s.logger.Debugf("Listening for %s connections on %s", conn.LocalAddr().Network(), conn.LocalAddr().String())
@@ -250,8 +276,18 @@ func populateServerConfig(config *Config) *Config {
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	initialPacingBurst := config.InitialPacingBurst
+	if initialPacingBurst == 0 {
+		initialPacingBurst = uint64(protocol.DefaultInitialPacingBurst)
+	}
+	maxPacingBurst := config.MaxPacingBurst
+	if maxPacingBurst == 0 {
+		maxPacingBurst = uint64(protocol.DefaultMaxPacingBurst)
+	}
 	connIDLen := config.ConnectionIDLength
-	if connIDLen == 0 {
+	if config.ConnectionIDGenerator != nil {
+		connIDLen = config.ConnectionIDGenerator.ConnectionIDLen()
+	} else if connIDLen == 0 {
 		connIDLen = protocol.DefaultConnectionIDLength
 	}
 
@@ -260,13 +296,28 @@ func populateServerConfig(config *Config) *Config {
 		HandshakeTimeout:                      handshakeTimeout,
 		IdleTimeout:                           idleTimeout,
 		AcceptToken:                           verifyToken,
+		Accept0RTT:                            config.Accept0RTT,
 		KeepAlive:                             config.KeepAlive,
+		BatchedIO:                             config.BatchedIO,
+		EnableECN:                             config.EnableECN,
+		DisablePacing:                         config.DisablePacing,
+		InitialPacingBurst:                    initialPacingBurst,
+		MaxPacingBurst:                        maxPacingBurst,
 		MaxReceiveStreamFlowControlWindow:     maxReceiveStreamFlowControlWindow,
 		MaxReceiveConnectionFlowControlWindow: maxReceiveConnectionFlowControlWindow,
 		MaxIncomingStreams:                    maxIncomingStreams,
 		MaxIncomingUniStreams:                 maxIncomingUniStreams,
 		ConnectionIDLength:                    connIDLen,
+		ConnectionIDGenerator:                 config.ConnectionIDGenerator,
 		StatelessResetKey:                     config.StatelessResetKey,
+		KeyUpdateInterval:                     config.KeyUpdateInterval,
+		KeyUpdateIntervalBytes:                config.KeyUpdateIntervalBytes,
+		PreferredAddress:                      config.PreferredAddress,
+		AdmissionController:                   config.AdmissionController,
+		EnableMultipath:                       config.EnableMultipath,
+		NewPathScheduler:                      config.NewPathScheduler,
+		Logger:                                config.Logger,
+		Metrics:                               config.Metrics,
 		QuicTracer:                            config.QuicTracer,
 	}
 }
@@ -410,8 +461,25 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) (qui
 		return nil, s.sendServerBusy(p.remoteAddr, hdr)
 	}
 
-	connID, err := protocol.GenerateConnectionID(s.config.ConnectionIDLength)
+	if ac := s.config.AdmissionController; ac != nil {
+		// The client's address was validated if it presented a valid token.
+		switch ac.admit(p.remoteAddr, token != nil, time.Now()) {
+		case admissionRetry:
+			s.logger.Debugf("Server under load. Requesting address validation from %s.", p.remoteAddr)
+			(&wire.ExtendedHeader{Header: *hdr}).Log(s.logger)
+			return nil, s.sendRetry(p.remoteAddr, hdr)
+		case admissionReject:
+			s.logger.Debugf("Rejecting new connection from %s. Admission control limit reached.", p.remoteAddr)
+			return nil, s.sendServerBusy(p.remoteAddr, hdr)
+		}
+	}
+
+	connID, err := generateConnID(s.config.ConnectionIDGenerator, s.config.ConnectionIDLength)
 	if err != nil {
+		// The session was already admitted.
+		if ac := s.config.AdmissionController; ac != nil {
+			ac.sessionClosed(p.remoteAddr, false)
+		}
 		return nil, err
 	}
 	s.logger.Debugf("Changing connection ID to %s.", connID)
@@ -448,21 +516,42 @@ func (s *baseServer) createNewSession(
 		s.config,
 		s.tlsConf,
 		s.tokenGenerator,
-		s.logger,
+		s.acceptEarlySessions,
+		s.logger.WithConnectionID(clientDestConnID),
 		version,
 	)
 	added := s.sessionHandler.AddIfNotTaken(clientDestConnID, sess)
 	// We're already keeping track of this connection ID.
 	// This might happen if we receive two copies of the Initial at the same time.
 	if !added {
+		if ac := s.config.AdmissionController; ac != nil {
+			ac.sessionClosed(remoteAddr, false)
+		}
 		return nil
 	}
 	s.sessionHandler.Add(srcConnID, sess)
+	s.metrics.sessionsCreated.Add(1)
 	go sess.run()
 	go s.handleNewSession(sess)
+	if ac := s.config.AdmissionController; ac != nil {
+		go trackAdmittedSession(ac, sess, remoteAddr)
+	}
 	return sess
 }
 
+// trackAdmittedSession reports to the AdmissionController when the session completes the handshake, and when it is closed.
+func trackAdmittedSession(ac *AdmissionController, sess quicSession, remoteAddr net.Addr) {
+	sessCtx := sess.Context()
+	select {
+	case <-sess.HandshakeComplete().Done():
+		ac.handshakeCompleted()
+		<-sessCtx.Done()
+		ac.sessionClosed(remoteAddr, true)
+	case <-sessCtx.Done():
+		ac.sessionClosed(remoteAddr, false)
+	}
+}
+
 func (s *baseServer) handleNewSession(sess quicSession) {
 	sessCtx := sess.Context()
 	if s.acceptEarlySessions {
@@ -496,7 +585,7 @@ func (s *baseServer) sendRetry(remoteAddr net.Addr, hdr *wire.Header) error {
 	if err != nil {
 		return err
 	}
-	connID, err := protocol.GenerateConnectionID(s.config.ConnectionIDLength)
+	connID, err := generateConnID(s.config.ConnectionIDGenerator, s.config.ConnectionIDLength)
 	if err != nil {
 		return err
 	}
@@ -510,11 +599,16 @@ func (s *baseServer) sendRetry(remoteAddr net.Addr, hdr *wire.Header) error {
 	replyHdr.Token = token
 	s.logger.Debugf("Changing connection ID to %s.", connID)
 	s.logger.Debugf("-> Sending Retry")
+	s.metrics.retriesSent.Add(1)
 	replyHdr.Log(s.logger)
 	buf := &bytes.Buffer{}
 	if err := replyHdr.Write(buf, hdr.Version); err != nil {
 		return err
 	}
+	if hdr.Version.UsesRetryIntegrityTag() {
+		tag := handshake.GetRetryIntegrityTag(buf.Bytes(), hdr.DestConnectionID, hdr.Version)
+		buf.Write(tag[:])
+	}
 	if _, err := s.conn.WriteTo(buf.Bytes(), remoteAddr); err != nil {
 		s.logger.Debugf("Error sending Retry: %s", err)
 	}
@@ -522,7 +616,8 @@ func (s *baseServer) sendRetry(remoteAddr net.Addr, hdr *wire.Header) error {
 }
 
 func (s *baseServer) sendServerBusy(remoteAddr net.Addr, hdr *wire.Header) error {
-	sealer, _ := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer)
+	s.metrics.sessionsRejected.Add(1)
+	sealer, _ := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer, hdr.Version)
 	packetBuffer := getPacketBuffer()
 	defer packetBuffer.Release()
 	buf := bytes.NewBuffer(packetBuffer.Slice[:0])
@@ -567,6 +662,7 @@ func (s *baseServer) sendServerBusy(remoteAddr net.Addr, hdr *wire.Header) error
 
 func (s *baseServer) sendVersionNegotiationPacket(p *receivedPacket, hdr *wire.Header) {
 	s.logger.Debugf("Client offered version %s, sending Version Negotiation", hdr.Version)
+	s.metrics.versionNegotiations.Add(1)
 	data, err := wire.ComposeVersionNegotiation(hdr.SrcConnectionID, hdr.DestConnectionID, s.config.Versions)
 	if err != nil {
 		s.logger.Debugf("Error composing Version Negotiation: %s", err)
@@ -89,6 +89,44 @@ var _ = Describe("Server", func() {
 		Expect(err).To(MatchError("0x1234 is not a valid QUIC version"))
 	})
 
+	It("errors when the ConnectionIDGenerator uses an invalid connection ID length", func() {
+		_, err := Listen(nil, tlsConf, &Config{ConnectionIDGenerator: &sequentialConnIDGenerator{connIDLen: 3}})
+		Expect(err).To(MatchError("quic: invalid connection ID length for the ConnectionIDGenerator: 3"))
+	})
+
+	It("errors when the ConnectionIDLength doesn't match the ConnectionIDGenerator", func() {
+		_, err := Listen(nil, tlsConf, &Config{
+			ConnectionIDLength:    8,
+			ConnectionIDGenerator: &sequentialConnIDGenerator{connIDLen: 10},
+		})
+		Expect(err).To(MatchError("quic: ConnectionIDLength (8) doesn't match the ConnectionIDGenerator (10)"))
+	})
+
+	It("uses the connection ID length of the ConnectionIDGenerator", func() {
+		ln, err := Listen(conn, tlsConf, &Config{ConnectionIDGenerator: &sequentialConnIDGenerator{connIDLen: 10}})
+		Expect(err).ToNot(HaveOccurred())
+		server := ln.(*baseServer)
+		Expect(server.config.ConnectionIDLength).To(Equal(10))
+		Expect(server.config.ConnectionIDGenerator).ToNot(BeNil())
+		Expect(ln.Close()).To(Succeed())
+	})
+
+	It("errors when the PreferredAddress doesn't contain an address", func() {
+		_, err := Listen(nil, tlsConf, &Config{PreferredAddress: &PreferredAddress{}})
+		Expect(err).To(MatchError("quic: PreferredAddress doesn't contain an address"))
+	})
+
+	It("errors when the PreferredAddress contains addresses of the wrong family", func() {
+		_, err := Listen(nil, tlsConf, &Config{PreferredAddress: &PreferredAddress{
+			IPv4: &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 443},
+		}})
+		Expect(err).To(MatchError("quic: [2001:db8::1]:443 is not an IPv4 address"))
+		_, err = Listen(nil, tlsConf, &Config{PreferredAddress: &PreferredAddress{
+			IPv6: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 443},
+		}})
+		Expect(err).To(MatchError("quic: 127.0.0.1:443 is not an IPv6 address"))
+	})
+
 	It("fills in default values if options are not set in the Config", func() {
 		ln, err := Listen(conn, tlsConf, &Config{})
 		Expect(err).ToNot(HaveOccurred())
@@ -105,13 +143,16 @@ var _ = Describe("Server", func() {
 	It("setups with the right values", func() {
 		supportedVersions := []protocol.VersionNumber{protocol.VersionTLS}
 		acceptToken := func(_ net.Addr, _ *Token) bool { return true }
+		accept0RTT := func(_ net.Addr, _ []byte) bool { return true }
 		tracer := quictrace.NewTracer()
 		config := Config{
 			Versions:          supportedVersions,
 			AcceptToken:       acceptToken,
+			Accept0RTT:        accept0RTT,
 			HandshakeTimeout:  1337 * time.Hour,
 			IdleTimeout:       42 * time.Minute,
 			KeepAlive:         true,
+			EnableECN:         true,
 			StatelessResetKey: []byte("foobar"),
 			QuicTracer:        tracer,
 		}
@@ -123,7 +164,9 @@ var _ = Describe("Server", func() {
 		Expect(server.config.HandshakeTimeout).To(Equal(1337 * time.Hour))
 		Expect(server.config.IdleTimeout).To(Equal(42 * time.Minute))
 		Expect(reflect.ValueOf(server.config.AcceptToken)).To(Equal(reflect.ValueOf(acceptToken)))
+		Expect(reflect.ValueOf(server.config.Accept0RTT)).To(Equal(reflect.ValueOf(accept0RTT)))
 		Expect(server.config.KeepAlive).To(BeTrue())
+		Expect(server.config.EnableECN).To(BeTrue())
 		Expect(server.config.StatelessResetKey).To(Equal([]byte("foobar")))
 		Expect(server.config.QuicTracer).To(Equal(tracer))
 		// stop the listener
@@ -280,6 +323,8 @@ var _ = Describe("Server", func() {
 
 			It("replies with a Retry packet, if a Token is required", func() {
 				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return false }
+				metrics := NewMetricsRegistry()
+				serv.metrics = newServerMetrics(metrics)
 				hdr := &wire.Header{
 					IsLongHeader:     true,
 					Type:             protocol.PacketTypeInitial,
@@ -299,6 +344,9 @@ var _ = Describe("Server", func() {
 				Expect(replyHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
 				Expect(replyHdr.OrigDestConnectionID).To(Equal(hdr.DestConnectionID))
 				Expect(replyHdr.Token).ToNot(BeEmpty())
+				out := &bytes.Buffer{}
+				Expect(metrics.WritePrometheus(out)).To(Succeed())
+				Expect(out.String()).To(ContainSubstring("quic_server_retry_packets_sent_total 1\n"))
 			})
 
 			It("creates a session, if no Token is required", func() {
@@ -331,9 +379,11 @@ var _ = Describe("Server", func() {
 					_ *Config,
 					_ *tls.Config,
 					_ *handshake.TokenGenerator,
+					enable0RTT bool,
 					_ utils.Logger,
 					_ protocol.VersionNumber,
 				) quicSession {
+					Expect(enable0RTT).To(BeFalse())
 					Expect(origConnID).To(Equal(hdr.DestConnectionID))
 					Expect(destConnID).To(Equal(hdr.SrcConnectionID))
 					// make sure we're using a server-generated connection ID
@@ -381,6 +431,7 @@ var _ = Describe("Server", func() {
 					_ *Config,
 					_ *tls.Config,
 					_ *handshake.TokenGenerator,
+					_ bool,
 					_ utils.Logger,
 					_ protocol.VersionNumber,
 				) quicSession {
@@ -409,6 +460,7 @@ var _ = Describe("Server", func() {
 					_ *Config,
 					_ *tls.Config,
 					_ *handshake.TokenGenerator,
+					_ bool,
 					_ utils.Logger,
 					_ protocol.VersionNumber,
 				) quicSession {
@@ -451,6 +503,150 @@ var _ = Describe("Server", func() {
 				Expect(rejectHdr.SrcConnectionID).To(Equal(hdr.DestConnectionID))
 			})
 
+			Context("admission control", func() {
+				var (
+					sessCtx      context.Context
+					closeSession context.CancelFunc
+					run          chan struct{}
+				)
+
+				BeforeEach(func() {
+					serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return true }
+					sessCtx, closeSession = context.WithCancel(context.Background())
+					run = make(chan struct{})
+					serv.newSession = func(
+						_ connection,
+						_ sessionRunner,
+						_ protocol.ConnectionID,
+						_ protocol.ConnectionID,
+						_ protocol.ConnectionID,
+						_ protocol.ConnectionID,
+						_ [16]byte,
+						_ *Config,
+						_ *tls.Config,
+						_ *handshake.TokenGenerator,
+						_ bool,
+						_ utils.Logger,
+						_ protocol.VersionNumber,
+					) quicSession {
+						sess := NewMockQuicSession(mockCtrl)
+						sess.EXPECT().handlePacket(gomock.Any())
+						sess.EXPECT().run().Do(func() { close(run) })
+						sess.EXPECT().Context().Return(sessCtx).AnyTimes()
+						sess.EXPECT().HandshakeComplete().Return(context.Background()).AnyTimes()
+						return sess
+					}
+				})
+
+				AfterEach(func() {
+					closeSession()
+				})
+
+				It("rejects new connection attempts if the limit is reached", func() {
+					ac := NewAdmissionController(&AdmissionControlConfig{MaxSessions: 1})
+					serv.config.AdmissionController = ac
+					phm.EXPECT().GetStatelessResetToken(gomock.Any())
+					phm.EXPECT().AddIfNotTaken(gomock.Any(), gomock.Any()).Return(true)
+					phm.EXPECT().Add(gomock.Any(), gomock.Any())
+					Expect(serv.handlePacketImpl(getInitialWithRandomDestConnID())).To(BeTrue())
+					Eventually(run).Should(BeClosed())
+					Expect(ac.Stats().Sessions).To(Equal(1))
+
+					p := getInitialWithRandomDestConnID()
+					hdr := parseHeader(p.data)
+					Expect(serv.handlePacketImpl(p)).To(BeFalse())
+					var reject mockPacketConnWrite
+					Eventually(conn.dataWritten).Should(Receive(&reject))
+					Expect(reject.to).To(Equal(p.remoteAddr))
+					rejectHdr := parseHeader(reject.data)
+					Expect(rejectHdr.Type).To(Equal(protocol.PacketTypeInitial))
+					Expect(rejectHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
+					Expect(ac.Stats().RejectedMaxSessions).To(BeEquivalentTo(1))
+
+					// the session is removed from the admission controller when it is closed
+					closeSession()
+					Eventually(func() int { return ac.Stats().Sessions }).Should(BeZero())
+				})
+
+				It("sends a Retry if too many handshakes are in progress", func() {
+					ac := NewAdmissionController(&AdmissionControlConfig{RetryThreshold: 1})
+					serv.config.AdmissionController = ac
+					serv.newSession = func(
+						_ connection,
+						_ sessionRunner,
+						_ protocol.ConnectionID,
+						_ protocol.ConnectionID,
+						_ protocol.ConnectionID,
+						_ protocol.ConnectionID,
+						_ [16]byte,
+						_ *Config,
+						_ *tls.Config,
+						_ *handshake.TokenGenerator,
+						_ bool,
+						_ utils.Logger,
+						_ protocol.VersionNumber,
+					) quicSession {
+						sess := NewMockQuicSession(mockCtrl)
+						sess.EXPECT().handlePacket(gomock.Any())
+						sess.EXPECT().run().Do(func() { close(run) })
+						sess.EXPECT().Context().Return(sessCtx).AnyTimes()
+						// the handshake never completes
+						sess.EXPECT().HandshakeComplete().Return(sessCtx).AnyTimes()
+						return sess
+					}
+					phm.EXPECT().GetStatelessResetToken(gomock.Any())
+					phm.EXPECT().AddIfNotTaken(gomock.Any(), gomock.Any()).Return(true)
+					phm.EXPECT().Add(gomock.Any(), gomock.Any())
+					Expect(serv.handlePacketImpl(getInitialWithRandomDestConnID())).To(BeTrue())
+					Eventually(run).Should(BeClosed())
+
+					p := getInitialWithRandomDestConnID()
+					hdr := parseHeader(p.data)
+					Expect(serv.handlePacketImpl(p)).To(BeFalse())
+					var write mockPacketConnWrite
+					Eventually(conn.dataWritten).Should(Receive(&write))
+					replyHdr := parseHeader(write.data)
+					Expect(replyHdr.Type).To(Equal(protocol.PacketTypeRetry))
+					Expect(replyHdr.OrigDestConnectionID).To(Equal(hdr.DestConnectionID))
+					Expect(ac.Stats().Retried).To(BeEquivalentTo(1))
+				})
+
+				It("releases the session if the Initial was a duplicate", func() {
+					ac := NewAdmissionController(&AdmissionControlConfig{MaxSessions: 1})
+					serv.config.AdmissionController = ac
+					serv.newSession = func(
+						_ connection,
+						_ sessionRunner,
+						_ protocol.ConnectionID,
+						_ protocol.ConnectionID,
+						_ protocol.ConnectionID,
+						_ protocol.ConnectionID,
+						_ [16]byte,
+						_ *Config,
+						_ *tls.Config,
+						_ *handshake.TokenGenerator,
+						_ bool,
+						_ utils.Logger,
+						_ protocol.VersionNumber,
+					) quicSession {
+						return NewMockQuicSession(mockCtrl)
+					}
+					phm.EXPECT().GetStatelessResetToken(gomock.Any())
+					phm.EXPECT().AddIfNotTaken(gomock.Any(), gomock.Any()).Return(false)
+					Expect(serv.handlePacketImpl(getInitialWithRandomDestConnID())).To(BeFalse())
+					Expect(ac.Stats().Sessions).To(BeZero())
+				})
+
+				It("releases the session if generating the connection ID fails", func() {
+					ac := NewAdmissionController(&AdmissionControlConfig{MaxSessions: 1})
+					serv.config.AdmissionController = ac
+					serv.config.ConnectionIDGenerator = &sequentialConnIDGenerator{connIDLen: 8, err: errors.New("no connection IDs left")}
+					Expect(serv.handlePacketImpl(getInitialWithRandomDestConnID())).To(BeFalse())
+					Expect(ac.Stats().Sessions).To(BeZero())
+					Expect(ac.Stats().HandshakingSessions).To(BeZero())
+				})
+			})
+
 			It("doesn't accept new sessions if they were closed in the mean time", func() {
 				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return true }
 
@@ -469,6 +665,7 @@ var _ = Describe("Server", func() {
 					_ *Config,
 					_ *tls.Config,
 					_ *handshake.TokenGenerator,
+					_ bool,
 					_ utils.Logger,
 					_ protocol.VersionNumber,
 				) quicSession {
@@ -572,6 +769,7 @@ var _ = Describe("Server", func() {
 					_ *Config,
 					_ *tls.Config,
 					_ *handshake.TokenGenerator,
+					_ bool,
 					_ utils.Logger,
 					_ protocol.VersionNumber,
 				) quicSession {
@@ -624,9 +822,11 @@ var _ = Describe("Server", func() {
 				_ *Config,
 				_ *tls.Config,
 				_ *handshake.TokenGenerator,
+				enable0RTT bool,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
 			) quicSession {
+				Expect(enable0RTT).To(BeTrue())
 				sess.EXPECT().run().Do(func() {})
 				sess.EXPECT().earlySessionReady().Return(ready)
 				sess.EXPECT().Context().Return(context.Background())
@@ -653,6 +853,7 @@ var _ = Describe("Server", func() {
 				_ *Config,
 				_ *tls.Config,
 				_ *handshake.TokenGenerator,
+				_ bool,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
 			) quicSession {
@@ -709,6 +910,7 @@ var _ = Describe("Server", func() {
 				_ *Config,
 				_ *tls.Config,
 				_ *handshake.TokenGenerator,
+				_ bool,
 				_ utils.Logger,
 				_ protocol.VersionNumber,
 			) quicSession {
@@ -51,6 +51,8 @@ type cryptoStreamHandler interface {
 	RunHandshake()
 	ChangeConnectionID(protocol.ConnectionID)
 	SetLargest1RTTAcked(protocol.PacketNumber)
+	SetHandshakeConfirmed()
+	InitiateKeyUpdate() error
 	io.Closer
 	ConnectionState() tls.ConnectionState
 }
@@ -59,6 +61,7 @@ type receivedPacket struct {
 	remoteAddr net.Addr
 	rcvTime    time.Time
 	data       []byte
+	ecn        protocol.ECN
 
 	buffer *packetBuffer
 }
@@ -68,6 +71,7 @@ func (p *receivedPacket) Clone() *receivedPacket {
 		remoteAddr: p.remoteAddr,
 		rcvTime:    p.rcvTime,
 		data:       p.data,
+		ecn:        p.ecn,
 		buffer:     p.buffer,
 	}
 }
@@ -109,7 +113,11 @@ type session struct {
 	handshakeDestConnID protocol.ConnectionID
 	// if the server sends a Retry, this is the connection ID we used initially
 	origDestConnID protocol.ConnectionID
-	srcConnIDLen   int
+	// The client's destination connection ID of the first Initial, and the source connection ID of the Retry.
+	// For draft-28 and later, they are authenticated using the transport parameters.
+	initialDestConnID protocol.ConnectionID
+	retrySrcConnID    protocol.ConnectionID
+	srcConnIDLen      int
 
 	perspective    protocol.Perspective
 	initialVersion protocol.VersionNumber // if version negotiation is performed, this is the version we initially tried
@@ -122,6 +130,13 @@ type session struct {
 	streamsMap      streamManager
 	connIDManager   *connIDManager
 	connIDGenerator *connIDGenerator
+	// only used by the client, if the server sent a preferred_address
+	preferredAddr *preferredAddressPath
+	// only set if the multipath extension is enabled, and (after processing the transport parameters) used
+	multipath *multipathManager
+	// the path that the packet that is currently being processed was received on
+	// nil for the initial path, and if the multipath extension is not used
+	rcvPath *path
 
 	rttStats *congestion.RTTStats
 
@@ -143,6 +158,8 @@ type session struct {
 
 	receivedPackets  chan *receivedPacket
 	sendingScheduled chan struct{}
+	// functions that need to be executed on the run loop, e.g. to add a new path
+	pathRequests chan func()
 
 	closeOnce sync.Once
 	// closeChan is used to notify the run loop that it should terminate
@@ -155,7 +172,7 @@ type session struct {
 
 	undecryptablePackets []*receivedPacket
 
-	clientHelloWritten    <-chan struct{}
+	clientHelloWritten    <-chan *handshake.TransportParameters
 	earlySessionReadyChan chan struct{}
 	handshakeCompleteChan chan struct{} // is closed when the handshake completes
 	handshakeComplete     bool
@@ -180,10 +197,15 @@ type session struct {
 	keepAliveInterval time.Duration
 
 	traceCallback func(quictrace.Event)
+	// the connection ID used to identify this connection in the quic-trace output
+	traceConnID protocol.ConnectionID
 
 	logID  string
 	logger utils.Logger
 
+	metrics        utils.Metrics
+	sessionMetrics *sessionMetrics
+
 	Schd ResponseWriterScheduler
 
 	ptm *pingTestManager
@@ -195,6 +217,7 @@ func (s *session) Scheduler() ResponseWriterScheduler {
 
 var _ Session = &session{}
 var _ EarlySession = &session{}
+var _ MultipathSession = &session{}
 var _ streamSender = &session{}
 
 var newSession = func(
@@ -208,6 +231,7 @@ var newSession = func(
 	conf *Config,
 	tlsConf *tls.Config,
 	tokenGenerator *handshake.TokenGenerator,
+	enable0RTT bool,
 	logger utils.Logger,
 	v protocol.VersionNumber,
 ) quicSession {
@@ -224,8 +248,10 @@ var newSession = func(
 	}
 	if origDestConnID != nil {
 		s.logID = origDestConnID.String()
+		s.traceConnID = origDestConnID
 	} else {
 		s.logID = destConnID.String()
+		s.traceConnID = clientDestConnID
 	}
 	s.connIDManager = newConnIDManager(
 		destConnID,
@@ -237,6 +263,7 @@ var newSession = func(
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
 		clientDestConnID,
+		s.config.ConnectionIDGenerator,
 		func(connID protocol.ConnectionID) [16]byte { return runner.Add(connID, s) },
 		runner.Remove,
 		runner.Retire,
@@ -244,7 +271,7 @@ var newSession = func(
 		s.queueControlFrame,
 	)
 	s.preSetup()
-	s.sentPacketHandler = ackhandler.NewSentPacketHandler(0, s.rttStats, s.traceCallback, s.logger)
+	s.sentPacketHandler = ackhandler.NewSentPacketHandler(0, s.rttStats, s.conn.ECN(), s.conn.DisableECN, s.pacingConfig(), s.traceCallback, s.metrics, s.logger)
 	initialStream := newCryptoStream()
 	handshakeStream := newCryptoStream()
 	oneRTTStream := newPostHandshakeCryptoStream(s.framer)
@@ -259,9 +286,33 @@ var newSession = func(
 		MaxAckDelay:                    protocol.MaxAckDelayInclGranularity,
 		AckDelayExponent:               protocol.AckDelayExponent,
 		DisableMigration:               true,
+		EnableMultipath:                s.config.EnableMultipath,
 		StatelessResetToken:            &statelessResetToken,
 		OriginalConnectionID:           origDestConnID,
 		ActiveConnectionIDLimit:        protocol.MaxActiveConnectionIDs,
+		InitialSourceConnectionID:      srcConnID,
+	}
+	if s.config.PreferredAddress != nil {
+		connID, token, err := s.connIDGenerator.GeneratePreferredAddressConnID()
+		if err != nil {
+			// The close error is handled as soon as the session is run.
+			s.closeLocal(fmt.Errorf("generating the preferred_address connection ID failed: %s", err))
+		} else {
+			params.PreferredAddress = newPreferredAddressParameter(s.config.PreferredAddress, connID, token)
+		}
+	}
+	if s.version.UsesConnectionIDTransportParameters() {
+		// The original_destination_connection_id is always sent.
+		// If we sent a Retry, the connection ID of the Retry is sent as well.
+		if origDestConnID != nil {
+			params.RetrySourceConnectionID = &clientDestConnID
+		} else {
+			params.OriginalConnectionID = clientDestConnID
+		}
+	}
+	var acceptTicket func(ticketID []byte) bool
+	if s.config.Accept0RTT != nil {
+		acceptTicket = func(ticketID []byte) bool { return s.config.Accept0RTT(conn.RemoteAddr(), ticketID) }
 	}
 	cs := handshake.NewCryptoSetupServer(
 		initialStream,
@@ -280,10 +331,18 @@ var newSession = func(
 			},
 		},
 		tlsConf,
+		enable0RTT,
+		acceptTicket,
+		s.config.KeyUpdateInterval,
+		s.config.KeyUpdateIntervalBytes,
 		s.rttStats,
 		logger,
+		s.version,
 	)
 	s.cryptoStreamHandler = cs
+	if s.config.EnableMultipath {
+		s.multipath = newMultipathManager(cs, s.config.NewPathScheduler)
+	}
 
 	if s.ptm == nil {
 		s.ptm = newPingTestManager(s)
@@ -307,7 +366,7 @@ var newSession = func(
 	s.unpacker = newPacketUnpacker(cs, s.version)
 	s.cryptoStreamManager = newCryptoStreamManager(cs, initialStream, handshakeStream, oneRTTStream)
 
-	s.conn.Init()
+	s.conn.Init(s.logger, s.metrics)
 
 	return s
 }
@@ -322,6 +381,7 @@ var newClientSession = func(
 	tlsConf *tls.Config,
 	initialPacketNumber protocol.PacketNumber,
 	initialVersion protocol.VersionNumber,
+	enable0RTT bool,
 	logger utils.Logger,
 	v protocol.VersionNumber,
 ) quicSession {
@@ -329,10 +389,12 @@ var newClientSession = func(
 		conn:                  conn,
 		config:                conf,
 		handshakeDestConnID:   destConnID,
+		initialDestConnID:     destConnID,
 		srcConnIDLen:          srcConnID.Len(),
 		perspective:           protocol.PerspectiveClient,
 		handshakeCompleteChan: make(chan struct{}),
 		logID:                 destConnID.String(),
+		traceConnID:           destConnID,
 		logger:                logger,
 		initialVersion:        initialVersion,
 		version:               v,
@@ -347,6 +409,7 @@ var newClientSession = func(
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
 		nil,
+		nil,
 		func(connID protocol.ConnectionID) [16]byte { return runner.Add(connID, s) },
 		runner.Remove,
 		runner.Retire,
@@ -354,7 +417,7 @@ var newClientSession = func(
 		s.queueControlFrame,
 	)
 	s.preSetup()
-	s.sentPacketHandler = ackhandler.NewSentPacketHandler(initialPacketNumber, s.rttStats, s.traceCallback, s.logger)
+	s.sentPacketHandler = ackhandler.NewSentPacketHandler(initialPacketNumber, s.rttStats, s.conn.ECN(), s.conn.DisableECN, s.pacingConfig(), s.traceCallback, s.metrics, s.logger)
 	initialStream := newCryptoStream()
 	handshakeStream := newCryptoStream()
 	oneRTTStream := newPostHandshakeCryptoStream(s.framer)
@@ -369,7 +432,9 @@ var newClientSession = func(
 		MaxAckDelay:                    protocol.MaxAckDelayInclGranularity,
 		AckDelayExponent:               protocol.AckDelayExponent,
 		DisableMigration:               true,
+		EnableMultipath:                s.config.EnableMultipath,
 		ActiveConnectionIDLimit:        protocol.MaxActiveConnectionIDs,
+		InitialSourceConnectionID:      srcConnID,
 	}
 	cs, clientHelloWritten := handshake.NewCryptoSetupClient(
 		initialStream,
@@ -385,11 +450,18 @@ var newClientSession = func(
 			onHandshakeComplete: func() { close(s.handshakeCompleteChan) },
 		},
 		tlsConf,
+		enable0RTT,
+		s.config.KeyUpdateInterval,
+		s.config.KeyUpdateIntervalBytes,
 		s.rttStats,
 		logger,
+		s.version,
 	)
 	s.clientHelloWritten = clientHelloWritten
 	s.cryptoStreamHandler = cs
+	if s.config.EnableMultipath {
+		s.multipath = newMultipathManager(cs, s.config.NewPathScheduler)
+	}
 	s.cryptoStreamManager = newCryptoStreamManager(cs, initialStream, handshakeStream, oneRTTStream)
 
 	if s.ptm == nil {
@@ -425,7 +497,17 @@ var newClientSession = func(
 	return s
 }
 
+func (s *session) pacingConfig() ackhandler.PacingConfig {
+	return ackhandler.PacingConfig{
+		Disabled:     s.config.DisablePacing,
+		InitialBurst: protocol.ByteCount(s.config.InitialPacingBurst),
+		MaxBurst:     protocol.ByteCount(s.config.MaxPacingBurst),
+	}
+}
+
 func (s *session) preSetup() {
+	s.metrics = utils.MetricsOrNop(s.config.Metrics)
+	s.sessionMetrics = newSessionMetrics(s.metrics)
 	s.sendQueue = newSendQueue(s.conn)
 	s.retransmissionQueue = newRetransmissionQueue(s.version)
 	s.frameParser = wire.NewFrameParser(s.version)
@@ -446,6 +528,7 @@ func (s *session) preSetup() {
 		uint64(s.config.MaxIncomingUniStreams),
 		s.perspective,
 		s.version,
+		s.sessionMetrics.streamsOpen,
 	)
 	if s.ptm == nil {
 		// 尚未初始化
@@ -455,6 +538,7 @@ func (s *session) preSetup() {
 	s.receivedPackets = make(chan *receivedPacket, protocol.MaxSessionUnprocessedPackets)
 	s.closeChan = make(chan closeError, 1)
 	s.sendingScheduled = make(chan struct{}, 1)
+	s.pathRequests = make(chan func())
 	s.undecryptablePackets = make([]*receivedPacket, 0, protocol.MaxUndecryptablePackets)
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
 	s.handshakeCtx, s.handshakeCtxCancel = context.WithCancel(context.Background())
@@ -468,7 +552,7 @@ func (s *session) preSetup() {
 
 	if s.config.QuicTracer != nil {
 		s.traceCallback = func(ev quictrace.Event) {
-			s.config.QuicTracer.Trace(s.origDestConnID, ev)
+			s.config.QuicTracer.Trace(s.traceConnID, ev)
 		}
 	}
 }
@@ -476,10 +560,12 @@ func (s *session) preSetup() {
 // run the session main loop
 func (s *session) run() error {
 	defer s.ctxCancel()
+	s.sessionMetrics.sessionsOpen.Add(1)
+	defer s.sessionMetrics.sessionsOpen.Add(-1)
 
 	if s.ptm == nil {
 		// ptm 实例还没有被初始化
-		fmt.Println("ptm has not been inited, init a new one")
+		s.logger.Debugf("ptm has not been inited, init a new one")
 		s.ptm = newPingTestManager(s)
 	} else {
 		// 在其他 go 程运行 ptm 主线程
@@ -495,8 +581,12 @@ func (s *session) run() error {
 
 	if s.perspective == protocol.PerspectiveClient {
 		select {
-		case <-s.clientHelloWritten:
+		case zeroRTTParams := <-s.clientHelloWritten:
 			s.scheduleSending()
+			if zeroRTTParams != nil {
+				s.restoreTransportParameters(zeroRTTParams)
+				close(s.earlySessionReadyChan)
+			}
 		case closeErr := <-s.closeChan:
 			// put the close error back into the channel, so that the run loop can receive it
 			s.closeChan <- closeErr
@@ -530,6 +620,8 @@ runLoop:
 		case <-s.sendingScheduled:
 			// We do all the interesting stuff after the switch statement, so
 			// nothing to see here.
+		case f := <-s.pathRequests:
+			f()
 		case p := <-s.receivedPackets:
 			// Only reset the timers if this packet was actually processed.
 			// This avoids modifying any state when handling undecryptable packets,
@@ -549,9 +641,18 @@ runLoop:
 				s.closeLocal(err)
 			}
 		}
+		if s.preferredAddr != nil {
+			if deadline := s.preferredAddr.Deadline(); !deadline.IsZero() && !deadline.After(now) {
+				s.onPathValidationTimeout(now)
+			}
+		}
+		if s.multipath != nil {
+			s.handlePathTimers(now)
+		}
 
 		var pacingDeadline time.Time
-		if s.pacingDeadline.IsZero() { // the timer didn't have a pacing deadline set
+		// When using multiple paths, pacing is applied per path when sending packets.
+		if s.pacingDeadline.IsZero() && !s.usesMultiplePaths() { // the timer didn't have a pacing deadline set
 			pacingDeadline = s.sentPacketHandler.TimeUntilSend()
 		}
 		if s.config.KeepAlive && !s.keepAlivePingSent && s.handshakeComplete && s.firstAckElicitingPacketAfterIdleSentTime.IsZero() && time.Since(s.lastPacketReceivedTime) >= s.keepAliveInterval/2 {
@@ -582,9 +683,20 @@ runLoop:
 	}
 
 	s.handleCloseError(closeErr)
+	if !s.handshakeComplete && closeErr.err != errCloseForRecreating {
+		s.sessionMetrics.handshakesFailed.Add(1)
+	}
+	if tracer, ok := s.config.QuicTracer.(quictrace.ConnectionCloseTracer); ok && closeErr.err != errCloseForRecreating {
+		if err := tracer.ConnectionClosed(s.traceConnID); err != nil {
+			s.logger.Errorf("Writing the trace failed: %s", err)
+		}
+	}
 	s.logger.Infof("Connection %s closed.", s.logID)
 	s.cryptoStreamHandler.Close()
 	s.sendQueue.Close()
+	if s.multipath != nil {
+		s.multipath.Close()
+	}
 	return closeErr.err
 }
 
@@ -609,6 +721,19 @@ func (s *session) ConnectionState() tls.ConnectionState {
 	return s.cryptoStreamHandler.ConnectionState()
 }
 
+func (s *session) InitiateKeyUpdate() error {
+	select {
+	case <-s.handshakeCompleteChan:
+	default:
+		return errors.New("cannot initiate a key update before the handshake completes")
+	}
+	return s.cryptoStreamHandler.InitiateKeyUpdate()
+}
+
+func (s *session) FlowControlStats() FlowControlStats {
+	return toFlowControlStats(s.connFlowController.GetStats())
+}
+
 func (s *session) maybeResetTimer() {
 	var deadline time.Time
 	if s.config.KeepAlive && s.handshakeComplete && !s.keepAlivePingSent {
@@ -630,6 +755,16 @@ func (s *session) maybeResetTimer() {
 	if !s.pacingDeadline.IsZero() {
 		deadline = utils.MinTime(deadline, s.pacingDeadline)
 	}
+	if s.preferredAddr != nil {
+		if pathDeadline := s.preferredAddr.Deadline(); !pathDeadline.IsZero() {
+			deadline = utils.MinTime(deadline, pathDeadline)
+		}
+	}
+	if s.multipath != nil {
+		if pathTimeout := s.multipath.Timeout(); !pathTimeout.IsZero() {
+			deadline = utils.MinTime(deadline, pathTimeout)
+		}
+	}
 
 	s.timer.Reset(deadline)
 }
@@ -640,11 +775,16 @@ func (s *session) idleTimeoutStartTime() time.Time {
 
 func (s *session) handleHandshakeComplete() {
 	s.handshakeComplete = true
+	s.sessionMetrics.handshakesCompleted.Add(1)
+	s.sessionMetrics.handshakeDuration.Observe(time.Since(s.sessionCreationTime).Seconds())
 	s.handshakeCompleteChan = nil // prevent this case from ever being selected again
 	s.handshakeCtxCancel()
 
 	s.connIDGenerator.SetHandshakeComplete()
 	s.sentPacketHandler.SetHandshakeComplete()
+	if s.preferredAddr != nil {
+		s.sendPathChallenge(time.Now())
+	}
 	// The client completes the handshake first (after sending the CFIN).
 	// We need to make sure it learns about the server completing the handshake,
 	// in order to stop retransmitting handshake packets.
@@ -655,6 +795,11 @@ func (s *session) handleHandshakeComplete() {
 			s.closeLocal(err)
 		}
 		s.queueControlFrame(&wire.NewTokenFrame{Token: token})
+		// For newer versions, the server confirms the handshake explicitly.
+		if s.version.UsesHandshakeDoneFrame() {
+			s.cryptoStreamHandler.SetHandshakeConfirmed()
+			s.queueControlFrame(&wire.HandshakeDoneFrame{})
+		}
 	}
 }
 
@@ -712,7 +857,7 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 	}()
 
 	if hdr.Type == protocol.PacketTypeRetry {
-		return s.handleRetryPacket(hdr)
+		return s.handleRetryPacket(hdr, p.data)
 	}
 
 	// The server can change the source connection ID with the first Handshake packet.
@@ -721,12 +866,30 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 		s.logger.Debugf("Dropping %s packet with unexpected source connection ID: %s (expected %s)", hdr.PacketType(), hdr.SrcConnectionID, s.handshakeDestConnID)
 		return false
 	}
-	// drop 0-RTT packets
-	if hdr.Type == protocol.PacketType0RTT {
+	// Only clients send 0-RTT packets.
+	if s.perspective == protocol.PerspectiveClient && hdr.Type == protocol.PacketType0RTT {
+		s.logger.Debugf("Dropping 0-RTT packet (%d bytes), since clients don't accept 0-RTT packets.", len(p.data))
 		return false
 	}
 
-	packet, err := s.unpacker.Unpack(hdr, p.rcvTime, p.data)
+	unpacker := s.unpacker
+	var newPath *path
+	if s.multipath != nil && !hdr.IsLongHeader {
+		path, isNew, ok := s.getPathForPacket(hdr.DestConnectionID, p.remoteAddr)
+		if !ok {
+			return false
+		}
+		if path != nil {
+			unpacker = path.unpacker
+			if isNew {
+				newPath = path
+			}
+			s.rcvPath = path
+			defer func() { s.rcvPath = nil }()
+		}
+	}
+
+	packet, err := unpacker.Unpack(hdr, p.rcvTime, p.data)
 	if err != nil {
 		switch err {
 		case handshake.ErrKeysDropped:
@@ -738,6 +901,8 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 			s.tryQueueingUndecryptablePacket(p)
 		case wire.ErrInvalidReservedBits:
 			s.closeLocal(qerr.Error(qerr.ProtocolViolation, err.Error()))
+		case handshake.ErrIntegrityLimitReached:
+			s.closeLocal(qerr.Error(qerr.AEADLimitReached, err.Error()))
 		default:
 			// This might be a packet injected by an attacker.
 			// Drop it.
@@ -746,19 +911,25 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 		return false
 	}
 
+	// Only start using a new path after successfully decrypting a packet received on it.
+	// Otherwise, an attacker could make us send PATH_CHALLENGEs to arbitrary addresses.
+	if newPath != nil {
+		s.addPeerPath(newPath)
+	}
+
 	if s.logger.Debug() {
 		s.logger.Debugf("<- Reading packet %#x (%d bytes) for connection %s, %s", packet.packetNumber, len(p.data), hdr.DestConnectionID, packet.encryptionLevel)
 		packet.hdr.Log(s.logger)
 	}
 
-	if err := s.handleUnpackedPacket(packet, p.rcvTime); err != nil {
+	if err := s.handleUnpackedPacket(packet, p.ecn, p.rcvTime); err != nil {
 		s.closeLocal(err)
 		return false
 	}
 	return true
 }
 
-func (s *session) handleRetryPacket(hdr *wire.Header) bool /* was this a valid Retry */ {
+func (s *session) handleRetryPacket(hdr *wire.Header, data []byte) bool /* was this a valid Retry */ {
 	if s.perspective == protocol.PerspectiveServer {
 		s.logger.Debugf("Ignoring Retry.")
 		return false
@@ -768,7 +939,18 @@ func (s *session) handleRetryPacket(hdr *wire.Header) bool /* was this a valid R
 		return false
 	}
 	(&wire.ExtendedHeader{Header: *hdr}).Log(s.logger)
-	if !hdr.OrigDestConnectionID.Equal(s.handshakeDestConnID) {
+	if s.version.UsesRetryIntegrityTag() {
+		if len(hdr.Token) == 0 {
+			s.logger.Debugf("Ignoring Retry, since it doesn't contain a token.")
+			return false
+		}
+		tagOffset := len(data) - wire.RetryIntegrityTagLen
+		tag := handshake.GetRetryIntegrityTag(data[:tagOffset], s.handshakeDestConnID, s.version)
+		if !bytes.Equal(tag[:], data[tagOffset:]) {
+			s.logger.Debugf("Ignoring spoofed Retry. Integrity Tag doesn't match.")
+			return false
+		}
+	} else if !hdr.OrigDestConnectionID.Equal(s.handshakeDestConnID) {
 		s.logger.Debugf("Ignoring spoofed Retry. Original Destination Connection ID: %s, expected: %s", hdr.OrigDestConnectionID, s.handshakeDestConnID)
 		return false
 	}
@@ -783,9 +965,11 @@ func (s *session) handleRetryPacket(hdr *wire.Header) bool /* was this a valid R
 		return false
 	}
 	s.logger.Debugf("<- Received Retry")
+	s.sessionMetrics.retriesReceived.Add(1)
 	s.logger.Debugf("Switching destination connection ID to: %s", hdr.SrcConnectionID)
 	s.origDestConnID = s.handshakeDestConnID
 	newDestConnID := hdr.SrcConnectionID
+	s.retrySrcConnID = newDestConnID
 	s.receivedRetry = true
 	if err := s.sentPacketHandler.ResetForRetry(); err != nil {
 		s.closeLocal(err)
@@ -799,7 +983,7 @@ func (s *session) handleRetryPacket(hdr *wire.Header) bool /* was this a valid R
 	return true
 }
 
-func (s *session) handleUnpackedPacket(packet *unpackedPacket, rcvTime time.Time) error {
+func (s *session) handleUnpackedPacket(packet *unpackedPacket, ecn protocol.ECN, rcvTime time.Time) error {
 	if len(packet.data) == 0 {
 		return qerr.Error(qerr.ProtocolViolation, "empty packet")
 	}
@@ -856,7 +1040,11 @@ func (s *session) handleUnpackedPacket(packet *unpackedPacket, rcvTime time.Time
 		})
 	}
 
-	s.receivedPacketHandler.ReceivedPacket(packet.packetNumber, packet.encryptionLevel, rcvTime, isAckEliciting)
+	receivedPacketHandler := s.receivedPacketHandler
+	if s.rcvPath != nil {
+		receivedPacketHandler = s.rcvPath.receivedPacketHandler
+	}
+	receivedPacketHandler.ReceivedPacket(packet.packetNumber, ecn, packet.encryptionLevel, rcvTime, isAckEliciting)
 	return nil
 }
 
@@ -870,6 +1058,8 @@ func (s *session) handleFrame(f wire.Frame, pn protocol.PacketNumber, encLevel p
 		err = s.handleStreamFrame(frame)
 	case *wire.AckFrame:
 		err = s.handleAckFrame(frame, pn, encLevel)
+	case *wire.AckMPFrame:
+		err = s.handleAckMPFrame(frame, pn, encLevel)
 	case *wire.ConnectionCloseFrame:
 		s.handleConnectionCloseFrame(frame)
 	case *wire.ResetStreamFrame:
@@ -881,7 +1071,9 @@ func (s *session) handleFrame(f wire.Frame, pn protocol.PacketNumber, encLevel p
 	case *wire.MaxStreamsFrame:
 		err = s.handleMaxStreamsFrame(frame)
 	case *wire.DataBlockedFrame:
+		s.connFlowController.ReceivedBlocked()
 	case *wire.StreamDataBlockedFrame:
+		err = s.handleStreamDataBlockedFrame(frame)
 	case *wire.StreamsBlockedFrame:
 	case *wire.StopSendingFrame:
 		err = s.handleStopSendingFrame(frame)
@@ -889,14 +1081,15 @@ func (s *session) handleFrame(f wire.Frame, pn protocol.PacketNumber, encLevel p
 	case *wire.PathChallengeFrame:
 		s.handlePathChallengeFrame(frame)
 	case *wire.PathResponseFrame:
-		// since we don't send PATH_CHALLENGEs, we don't expect PATH_RESPONSEs
-		err = errors.New("unexpected PATH_RESPONSE frame")
+		err = s.handlePathResponseFrame(frame)
 	case *wire.NewTokenFrame:
 		err = s.handleNewTokenFrame(frame)
 	case *wire.NewConnectionIDFrame:
 		err = s.handleNewConnectionIDFrame(frame)
 	case *wire.RetireConnectionIDFrame:
 		err = s.handleRetireConnectionIDFrame(frame)
+	case *wire.HandshakeDoneFrame:
+		err = s.handleHandshakeDoneFrame()
 	default:
 		err = fmt.Errorf("unexpected frame type: %s", reflect.ValueOf(&frame).Elem().Type().Name())
 	}
@@ -913,6 +1106,14 @@ func (s *session) handlePacket(p *receivedPacket) {
 	}
 }
 
+func (s *session) handleHandshakeDoneFrame() error {
+	if s.perspective == protocol.PerspectiveServer {
+		return qerr.Error(qerr.ProtocolViolation, "received a HANDSHAKE_DONE frame")
+	}
+	s.cryptoStreamHandler.SetHandshakeConfirmed()
+	return nil
+}
+
 func (s *session) handleConnectionCloseFrame(frame *wire.ConnectionCloseFrame) {
 	var e error
 	if frame.IsApplicationError {
@@ -951,6 +1152,19 @@ func (s *session) handleMaxDataFrame(frame *wire.MaxDataFrame) {
 	s.connFlowController.UpdateSendWindow(frame.ByteOffset)
 }
 
+func (s *session) handleStreamDataBlockedFrame(frame *wire.StreamDataBlockedFrame) error {
+	str, err := s.streamsMap.GetOrOpenReceiveStream(frame.StreamID)
+	if err != nil {
+		return err
+	}
+	if str == nil {
+		// stream is closed and already garbage collected
+		return nil
+	}
+	str.handleStreamDataBlockedFrame(frame)
+	return nil
+}
+
 func (s *session) handleMaxStreamDataFrame(frame *wire.MaxStreamDataFrame) error {
 	str, err := s.streamsMap.GetOrOpenSendStream(frame.StreamID)
 	if err != nil {
@@ -994,9 +1208,38 @@ func (s *session) handleStopSendingFrame(frame *wire.StopSendingFrame) error {
 }
 
 func (s *session) handlePathChallengeFrame(frame *wire.PathChallengeFrame) {
+	// The PATH_RESPONSE has to be sent on the path that the PATH_CHALLENGE was received on.
+	if s.rcvPath != nil {
+		s.sendPathResponse(s.rcvPath, frame)
+		return
+	}
 	s.queueControlFrame(&wire.PathResponseFrame{Data: frame.Data})
 }
 
+func (s *session) handlePathResponseFrame(frame *wire.PathResponseFrame) error {
+	if s.multipath != nil {
+		s.handlePathResponseForMultipath(frame)
+		return nil
+	}
+	// we only send PATH_CHALLENGEs when validating the path to the server's preferred address
+	if s.preferredAddr == nil {
+		return errors.New("unexpected PATH_RESPONSE frame")
+	}
+	if !s.preferredAddr.HandleResponse(frame.Data) {
+		s.logger.Debugf("Ignoring PATH_RESPONSE frame that doesn't match any PATH_CHALLENGE.")
+		return nil
+	}
+	if s.preferredAddr.done { // duplicate PATH_RESPONSE, or a PATH_RESPONSE to a retransmitted PATH_CHALLENGE
+		return nil
+	}
+	s.preferredAddr.done = true
+	s.logger.Infof("Validated the path to the server's preferred address. Migrating to %s.", s.preferredAddr.addr)
+	s.conn.SetCurrentRemoteAddr(s.preferredAddr.addr)
+	s.connIDManager.UsePreferredAddressConnID(s.preferredAddr.connID, s.preferredAddr.resetToken)
+	s.rttStats.OnConnectionMigration()
+	return nil
+}
+
 func (s *session) handleNewTokenFrame(frame *wire.NewTokenFrame) error {
 	if s.perspective == protocol.PerspectiveServer {
 		return qerr.Error(qerr.ProtocolViolation, "Received NEW_TOKEN frame from the client.")
@@ -1023,7 +1266,7 @@ func (s *session) handleAckFrame(frame *wire.AckFrame, pn protocol.PacketNumber,
 			if frame.AcksPacket(packetNumber) {
 				sendTime, ok := s.ptm.packetNumberMap[packetNumber]
 				if !ok {
-					fmt.Println("map missed")
+					s.logger.Debugf("ptm: no send time for ping packet %d", packetNumber)
 					continue
 				}
 				// 从队列中移除该 ping 包序号
@@ -1166,42 +1409,105 @@ func (s *session) processTransportParameters(data []byte) {
 	s.connFlowController.UpdateSendWindow(params.InitialMaxData)
 	s.rttStats.SetMaxAckDelay(params.MaxAckDelay)
 	s.connIDGenerator.SetMaxActiveConnIDs(params.ActiveConnectionIDLimit)
+	if s.multipath != nil {
+		if params.EnableMultipath {
+			s.logger.Debugf("Using the multipath extension.")
+			s.connIDManager.EnableMultipath()
+		} else {
+			s.multipath = nil
+		}
+	}
 	if params.StatelessResetToken != nil {
 		s.connIDManager.SetStatelessResetToken(*params.StatelessResetToken)
 	}
 	// On the server side, the early session is ready as soon as we processed
 	// the client's transport parameters.
-	close(s.earlySessionReadyChan)
+	// When using 0-RTT, the client's early session is ready as soon as the ClientHello was sent.
+	select {
+	case <-s.earlySessionReadyChan:
+	default:
+		close(s.earlySessionReadyChan)
+	}
+}
+
+// restoreTransportParameters applies the server's transport parameters remembered from the last connection.
+// It is used by the client when sending 0-RTT data.
+// When the server's transport parameters are received, they replace the remembered values.
+func (s *session) restoreTransportParameters(params *handshake.TransportParameters) {
+	s.logger.Debugf("Restoring Transport Parameters: %s", params)
+	s.peerParams = params
+	s.connIDGenerator.SetMaxActiveConnIDs(params.ActiveConnectionIDLimit)
+	s.connFlowController.UpdateSendWindow(params.InitialMaxData)
+	if err := s.streamsMap.UpdateLimits(params); err != nil {
+		s.closeLocal(err)
+	}
 }
 
 func (s *session) processTransportParametersForClient(data []byte) (*handshake.TransportParameters, error) {
 	params := &handshake.TransportParameters{}
-	if err := params.Unmarshal(data, s.perspective.Opposite()); err != nil {
+	if err := params.Unmarshal(data, s.perspective.Opposite(), s.version); err != nil {
 		return nil, err
 	}
 
-	// check the Retry token
-	if !params.OriginalConnectionID.Equal(s.origDestConnID) {
+	if s.version.UsesConnectionIDTransportParameters() {
+		if err := s.checkHandshakeConnectionIDs(params); err != nil {
+			return nil, err
+		}
+	} else if !params.OriginalConnectionID.Equal(s.origDestConnID) { // check the Retry token
 		return nil, qerr.Error(qerr.TransportParameterError, fmt.Sprintf("expected original_connection_id to equal %s, is %s", s.origDestConnID, params.OriginalConnectionID))
 	}
-	// We don't support connection migration yet, so we don't have any use for the preferred_address.
 	if params.PreferredAddress != nil {
-		s.logger.Debugf("Server sent preferred_address. Retiring the preferred_address connection ID.")
-		// Retire the connection ID.
-		s.framer.QueueControlFrame(&wire.RetireConnectionIDFrame{SequenceNumber: 1})
+		if s.multipath != nil && params.EnableMultipath {
+			s.logger.Debugf("Server sent preferred_address, but the multipath extension is used. Retiring the preferred_address connection ID.")
+			s.framer.QueueControlFrame(&wire.RetireConnectionIDFrame{SequenceNumber: 1})
+		} else if addr := selectPreferredAddress(params.PreferredAddress, s.conn.RemoteAddr()); addr != nil {
+			s.logger.Debugf("Server sent preferred_address %s. Validating the path after the handshake completes.", addr)
+			s.preferredAddr = newPreferredAddressPath(addr, params.PreferredAddress)
+		} else {
+			s.logger.Debugf("Server sent preferred_address without an address for our address family. Retiring the preferred_address connection ID.")
+			s.framer.QueueControlFrame(&wire.RetireConnectionIDFrame{SequenceNumber: 1})
+		}
 	}
 	return params, nil
 }
 
+// checkHandshakeConnectionIDs checks the connection IDs that the server sent in its transport parameters.
+func (s *session) checkHandshakeConnectionIDs(params *handshake.TransportParameters) error {
+	expectedOrigDestConnID := s.initialDestConnID
+	if s.receivedRetry {
+		expectedOrigDestConnID = s.origDestConnID
+	}
+	if !params.OriginalConnectionID.Equal(expectedOrigDestConnID) {
+		return qerr.Error(qerr.TransportParameterError, fmt.Sprintf("expected original_destination_connection_id to equal %s, is %s", expectedOrigDestConnID, params.OriginalConnectionID))
+	}
+	if !params.InitialSourceConnectionID.Equal(s.handshakeDestConnID) {
+		return qerr.Error(qerr.TransportParameterError, fmt.Sprintf("expected initial_source_connection_id to equal %s, is %s", s.handshakeDestConnID, params.InitialSourceConnectionID))
+	}
+	if s.receivedRetry {
+		if params.RetrySourceConnectionID == nil || !params.RetrySourceConnectionID.Equal(s.retrySrcConnID) {
+			return qerr.Error(qerr.TransportParameterError, fmt.Sprintf("expected retry_source_connection_id to equal %s", s.retrySrcConnID))
+		}
+	} else if params.RetrySourceConnectionID != nil {
+		return qerr.Error(qerr.TransportParameterError, "received retry_source_connection_id, although no Retry was performed")
+	}
+	return nil
+}
+
 func (s *session) processTransportParametersForServer(data []byte) (*handshake.TransportParameters, error) {
 	params := &handshake.TransportParameters{}
-	if err := params.Unmarshal(data, s.perspective.Opposite()); err != nil {
+	if err := params.Unmarshal(data, s.perspective.Opposite(), s.version); err != nil {
 		return nil, err
 	}
+	if s.version.UsesConnectionIDTransportParameters() && !params.InitialSourceConnectionID.Equal(s.handshakeDestConnID) {
+		return nil, qerr.Error(qerr.TransportParameterError, fmt.Sprintf("expected initial_source_connection_id to equal %s, is %s", s.handshakeDestConnID, params.InitialSourceConnectionID))
+	}
 	return params, nil
 }
 
 func (s *session) sendPackets() error {
+	if s.usesMultiplePaths() {
+		return s.sendPacketsMultipath()
+	}
 	s.pacingDeadline = time.Time{}
 
 	sendMode := s.sentPacketHandler.SendMode()
@@ -1281,15 +1587,19 @@ func (s *session) maybeSendAckOnlyPacket() error {
 }
 
 func (s *session) sendProbePacket(encLevel protocol.EncryptionLevel) error {
+	return s.sendProbePacketOnPath(s.initialPath(), encLevel)
+}
+
+func (s *session) sendProbePacketOnPath(p *path, encLevel protocol.EncryptionLevel) error {
 	// Queue probe packets until we actually send out a packet,
 	// or until there are no more packets to queue.
 	var packet *packedPacket
 	for {
-		if wasQueued := s.sentPacketHandler.QueueProbePacket(encLevel); !wasQueued {
+		if wasQueued := p.sentPacketHandler.QueueProbePacket(encLevel); !wasQueued {
 			break
 		}
 		var err error
-		packet, err = s.packer.MaybePackProbePacket(encLevel)
+		packet, err = p.packer.MaybePackProbePacket(encLevel)
 		if err != nil {
 			return err
 		}
@@ -1309,7 +1619,7 @@ func (s *session) sendProbePacket(encLevel protocol.EncryptionLevel) error {
 			panic("unexpected encryption level")
 		}
 		var err error
-		packet, err = s.packer.MaybePackProbePacket(encLevel)
+		packet, err = p.packer.MaybePackProbePacket(encLevel)
 		if err != nil {
 			return err
 		}
@@ -1317,8 +1627,8 @@ func (s *session) sendProbePacket(encLevel protocol.EncryptionLevel) error {
 	if packet == nil {
 		return fmt.Errorf("session BUG: couldn't pack %s probe packet", encLevel)
 	}
-	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(s.retransmissionQueue))
-	s.sendPackedPacket(packet)
+	p.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(s.retransmissionQueue))
+	p.send(packet)
 	return nil
 }
 
@@ -1337,6 +1647,401 @@ func (s *session) sendPacket() (bool, error) {
 	return true, nil
 }
 
+// sendPathChallenge sends a PATH_CHALLENGE to the server's preferred address
+func (s *session) sendPathChallenge(now time.Time) {
+	frame, err := s.preferredAddr.NextChallenge(now, s.rttStats.PTO(true))
+	if err != nil {
+		s.closeLocal(err)
+		return
+	}
+	packet, err := s.packer.PackPathChallenge(frame, s.preferredAddr.connID)
+	if err != nil {
+		s.closeLocal(err)
+		return
+	}
+	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(s.retransmissionQueue))
+	s.logPacket(packet)
+	// If the packet can't be sent, path validation will time out.
+	if err := s.conn.WriteTo(packet.raw, s.preferredAddr.addr); err != nil {
+		s.logger.Debugf("Sending PATH_CHALLENGE to %s failed: %s", s.preferredAddr.addr, err)
+	}
+	packet.buffer.Release()
+}
+
+func (s *session) onPathValidationTimeout(now time.Time) {
+	if s.preferredAddr.ShouldRetry() {
+		s.sendPathChallenge(now)
+		return
+	}
+	s.preferredAddr.done = true
+	s.logger.Debugf("Validating the path to the server's preferred address %s failed. Retiring the preferred_address connection ID.", s.preferredAddr.addr)
+	s.framer.QueueControlFrame(&wire.RetireConnectionIDFrame{SequenceNumber: 1})
+}
+
+// usesMultiplePaths says if packets are sent on more than the initial path.
+func (s *session) usesMultiplePaths() bool {
+	return s.multipath != nil && len(s.multipath.paths) > 0
+}
+
+// initialPath returns a path for the initial path, which uses the session's packet handlers and packer.
+func (s *session) initialPath() *path {
+	return &path{
+		id:                    0,
+		localAddr:             s.conn.LocalAddr(),
+		remoteAddr:            s.conn.RemoteAddr(),
+		validated:             true,
+		rttStats:              s.rttStats,
+		sentPacketHandler:     s.sentPacketHandler,
+		receivedPacketHandler: s.receivedPacketHandler,
+		packer:                s.packer,
+		unpacker:              s.unpacker,
+		send:                  s.sendPackedPacket,
+	}
+}
+
+// newPath creates a new path for the multipath extension.
+// On this path, packets are sent to the peer's connection ID destConnID, using the write function.
+func (s *session) newPath(id uint64, destConnID protocol.ConnectionID, localAddr, remoteAddr net.Addr, write func([]byte) error) *path {
+	rttStats := &congestion.RTTStats{}
+	rttStats.SetMaxAckDelay(s.peerParams.MaxAckDelay)
+	// Paths are only used after the handshake completed.
+	sentPacketHandler := ackhandler.NewSentPacketHandler(0, rttStats, protocol.ECNNon, nil, s.pacingConfig(), nil, s.metrics, s.logger)
+	sentPacketHandler.DropPackets(protocol.EncryptionInitial)
+	sentPacketHandler.DropPackets(protocol.EncryptionHandshake)
+	sentPacketHandler.SetHandshakeComplete()
+	receivedPacketHandler := ackhandler.NewReceivedPacketHandler(rttStats, s.logger, s.version)
+	receivedPacketHandler.DropPackets(protocol.EncryptionInitial)
+	receivedPacketHandler.DropPackets(protocol.EncryptionHandshake)
+
+	cs := &pathCryptoSetup{CryptoSetup: s.multipath.cryptoSetup, pathID: id}
+	packer := newPacketPacker(
+		nil,
+		func() protocol.ConnectionID { return destConnID },
+		nil,
+		nil,
+		sentPacketHandler,
+		s.retransmissionQueue,
+		remoteAddr,
+		cs,
+		s.framer,
+		receivedPacketHandler,
+		s.perspective,
+		s.version,
+		nil,
+	)
+	packer.pathID = id
+	packer.HandleTransportParameters(s.peerParams)
+
+	p := &path{
+		id:                    id,
+		localAddr:             localAddr,
+		remoteAddr:            remoteAddr,
+		destConnID:            destConnID,
+		rttStats:              rttStats,
+		sentPacketHandler:     sentPacketHandler,
+		receivedPacketHandler: receivedPacketHandler,
+		packer:                packer,
+		unpacker:              newPacketUnpacker(cs, s.version),
+	}
+	p.send = func(packet *packedPacket) {
+		if s.firstAckElicitingPacketAfterIdleSentTime.IsZero() && packet.IsAckEliciting() {
+			s.firstAckElicitingPacketAfterIdleSentTime = time.Now()
+		}
+		s.logPacket(packet)
+		// If the packet can't be sent, it will be declared lost and retransmitted.
+		if err := write(packet.raw); err != nil {
+			s.logger.Debugf("Sending packet on path %d failed: %s", p.id, err)
+		}
+		packet.buffer.Release()
+	}
+	return p
+}
+
+// getPathForPacket returns the path that a short header packet with the connection ID destConnID was received on.
+// For the initial path, it returns nil.
+// If the peer started using a new path, a new path is returned, and isNew is set.
+// ok is false if the packet should be dropped.
+func (s *session) getPathForPacket(destConnID protocol.ConnectionID, remoteAddr net.Addr) (p *path, isNew bool, ok bool) {
+	seq, found := s.connIDGenerator.SequenceNumber(destConnID)
+	if !found || seq == 0 {
+		return nil, false, true
+	}
+	if p, ok := s.multipath.paths[seq]; ok {
+		return p, false, true
+	}
+	// Only the client opens new paths.
+	if s.perspective == protocol.PerspectiveClient || !s.handshakeComplete {
+		s.logger.Debugf("Dropping packet for unknown path %d.", seq)
+		return nil, false, false
+	}
+	connID, found := s.connIDManager.GetForPath(seq)
+	if !found {
+		s.logger.Debugf("Dropping packet for path %d. No connection ID with that sequence number available.", seq)
+		return nil, false, false
+	}
+	p = s.newPath(seq, connID, s.conn.LocalAddr(), remoteAddr, func(b []byte) error {
+		return s.conn.WriteTo(b, remoteAddr)
+	})
+	return p, true, true
+}
+
+// addPeerPath starts using a path opened by the peer.
+// The path is validated before it is used for sending data.
+func (s *session) addPeerPath(p *path) {
+	s.logger.Debugf("Peer opened path %d from %s. Validating the path.", p.id, p.remoteAddr)
+	s.multipath.paths[p.id] = p
+	s.sendPathChallengeOnPath(p, time.Now())
+}
+
+func (s *session) handleAckMPFrame(frame *wire.AckMPFrame, pn protocol.PacketNumber, encLevel protocol.EncryptionLevel) error {
+	if s.multipath == nil {
+		return qerr.Error(qerr.ProtocolViolation, "received an ACK_MP frame, but multipath was not negotiated")
+	}
+	if frame.PathID == 0 {
+		return s.handleAckFrame(&frame.AckFrame, pn, encLevel)
+	}
+	p, ok := s.multipath.paths[frame.PathID]
+	if !ok {
+		s.logger.Debugf("Ignoring ACK_MP frame for unknown path %d.", frame.PathID)
+		return nil
+	}
+	if err := p.sentPacketHandler.ReceivedAck(&frame.AckFrame, pn, encLevel, s.lastPacketReceivedTime); err != nil {
+		return err
+	}
+	p.receivedPacketHandler.IgnoreBelow(p.sentPacketHandler.GetLowestPacketNotConfirmedAcked())
+	return nil
+}
+
+// sendPathResponse sends a PATH_RESPONSE on the path that the PATH_CHALLENGE was received on.
+func (s *session) sendPathResponse(p *path, frame *wire.PathChallengeFrame) {
+	packet, err := p.packer.PackPathResponse(&wire.PathResponseFrame{Data: frame.Data}, p.destConnID)
+	if err != nil {
+		s.closeLocal(err)
+		return
+	}
+	p.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(s.retransmissionQueue))
+	p.send(packet)
+}
+
+func (s *session) handlePathResponseForMultipath(frame *wire.PathResponseFrame) {
+	for _, p := range s.multipath.paths {
+		if p.HandleResponse(frame.Data) {
+			p.done = true
+			p.validated = true
+			s.logger.Debugf("Path %d (%s) validated.", p.id, p.remoteAddr)
+			return
+		}
+	}
+}
+
+// sendPathChallengeOnPath sends a PATH_CHALLENGE on a path of the multipath extension.
+func (s *session) sendPathChallengeOnPath(p *path, now time.Time) {
+	frame, err := p.NextChallenge(now, s.rttStats.PTO(true))
+	if err != nil {
+		s.closeLocal(err)
+		return
+	}
+	packet, err := p.packer.PackPathChallenge(frame, p.destConnID)
+	if err != nil {
+		s.closeLocal(err)
+		return
+	}
+	p.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(s.retransmissionQueue))
+	p.send(packet)
+}
+
+// handlePathTimers runs the loss detection and path validation timers of the paths of the multipath extension.
+func (s *session) handlePathTimers(now time.Time) {
+	for _, p := range s.multipath.SortedPaths() {
+		if timeout := p.sentPacketHandler.GetLossDetectionTimeout(); !timeout.IsZero() && timeout.Before(now) {
+			if err := p.sentPacketHandler.OnLossDetectionTimeout(); err != nil {
+				s.closeLocal(err)
+				return
+			}
+		}
+		if p.done {
+			continue
+		}
+		if deadline := p.Deadline(); !deadline.IsZero() && !deadline.After(now) {
+			if p.ShouldRetry() {
+				s.sendPathChallengeOnPath(p, now)
+				continue
+			}
+			p.done = true
+			s.logger.Debugf("Validating path %d (%s) failed. Removing the path.", p.id, p.remoteAddr)
+			s.multipath.Remove(p)
+		}
+	}
+}
+
+// sendPacketsMultipath sends packets when the multipath extension is used.
+// The PathScheduler decides which path a packet is sent on.
+func (s *session) sendPacketsMultipath() error {
+	s.pacingDeadline = time.Time{}
+	now := time.Now()
+	paths := append([]*path{s.initialPath()}, s.multipath.SortedPaths()...)
+
+	for _, p := range paths {
+		for {
+			var encLevel protocol.EncryptionLevel
+			switch p.sentPacketHandler.SendMode() {
+			case ackhandler.SendPTOInitial:
+				encLevel = protocol.EncryptionInitial
+			case ackhandler.SendPTOHandshake:
+				encLevel = protocol.EncryptionHandshake
+			case ackhandler.SendPTOAppData:
+				encLevel = protocol.Encryption1RTT
+			default:
+				encLevel = protocol.EncryptionUnspecified
+			}
+			if encLevel == protocol.EncryptionUnspecified {
+				break
+			}
+			if err := s.sendProbePacketOnPath(p, encLevel); err != nil {
+				return err
+			}
+		}
+	}
+
+	if isBlocked, offset := s.connFlowController.IsNewlyBlocked(); isBlocked {
+		s.framer.QueueControlFrame(&wire.DataBlockedFrame{DataLimit: offset})
+	}
+	s.windowUpdateQueue.QueueAll()
+
+	candidates := make([]*path, 0, len(paths))
+	infos := make([]PathInfo, 0, len(paths))
+	for {
+		candidates = candidates[:0]
+		infos = infos[:0]
+		for _, p := range paths {
+			canSend, pacingDeadline := p.CanSend(now)
+			if !canSend {
+				if !pacingDeadline.IsZero() {
+					s.pacingDeadline = utils.MinNonZeroTime(s.pacingDeadline, pacingDeadline)
+				}
+				continue
+			}
+			candidates = append(candidates, p)
+			infos = append(infos, p.Info())
+		}
+		if len(candidates) == 0 {
+			break
+		}
+		idx := s.multipath.scheduler.SelectPath(infos)
+		if idx < 0 || idx >= len(candidates) {
+			idx = 0
+		}
+		p := candidates[idx]
+		packet, err := p.packer.PackPacket()
+		if err != nil {
+			return err
+		}
+		if packet == nil {
+			break
+		}
+		p.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(s.retransmissionQueue))
+		p.send(packet)
+	}
+
+	// Send ACKs on all paths, including paths that are not yet validated.
+	for _, p := range paths {
+		packet, err := p.packer.MaybePackAckPacket()
+		if err != nil {
+			return err
+		}
+		if packet == nil {
+			continue
+		}
+		p.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(s.retransmissionQueue))
+		p.send(packet)
+	}
+	return nil
+}
+
+// AddPath opens a new path, using the local packet conn pconn.
+// It is only available for clients, and only after the handshake completed.
+func (s *session) AddPath(pconn net.PacketConn) error {
+	if s.perspective == protocol.PerspectiveServer {
+		return errors.New("only clients can add paths")
+	}
+	select {
+	case <-s.handshakeCtx.Done():
+	default:
+		return errors.New("cannot add a path before the handshake completed")
+	}
+	errChan := make(chan error, 1)
+	f := func() { errChan <- s.addPath(pconn) }
+	select {
+	case s.pathRequests <- f:
+	case <-s.ctx.Done():
+		return errors.New("session closed")
+	}
+	return <-errChan
+}
+
+func (s *session) addPath(pconn net.PacketConn) error {
+	if s.multipath == nil {
+		return errors.New("multipath was not negotiated")
+	}
+	id, connID, ok := s.connIDManager.NextPathConnID(func(seq uint64) bool {
+		return s.multipath.IsInUse(seq) || !s.connIDGenerator.IsActive(seq)
+	})
+	if !ok {
+		return errors.New("no connection ID available for a new path")
+	}
+	remoteAddr := s.conn.RemoteAddr()
+	p := s.newPath(id, connID, pconn.LocalAddr(), remoteAddr, func(b []byte) error {
+		_, err := pconn.WriteTo(b, remoteAddr)
+		return err
+	})
+	p.pconn = pconn
+	s.multipath.paths[id] = p
+	s.logger.Debugf("Adding path %d from %s.", id, p.localAddr)
+	go s.readPath(pconn)
+	s.sendPathChallengeOnPath(p, time.Now())
+	return nil
+}
+
+// readPath reads packets received on a path added using AddPath.
+// It returns when the packet conn is closed.
+func (s *session) readPath(pconn net.PacketConn) {
+	for {
+		buffer := getPacketBuffer()
+		data := buffer.Slice[:protocol.MaxReceivePacketSize]
+		n, addr, err := pconn.ReadFrom(data)
+		if err != nil {
+			buffer.Release()
+			return
+		}
+		s.handlePacket(&receivedPacket{
+			remoteAddr: addr,
+			rcvTime:    time.Now(),
+			data:       data[:n],
+			buffer:     buffer,
+		})
+	}
+}
+
+// Paths returns information about the paths used by the session.
+func (s *session) Paths() []PathInfo {
+	infoChan := make(chan []PathInfo, 1)
+	f := func() {
+		infos := []PathInfo{s.initialPath().Info()}
+		if s.multipath != nil {
+			for _, p := range s.multipath.SortedPaths() {
+				infos = append(infos, p.Info())
+			}
+		}
+		infoChan <- infos
+	}
+	select {
+	case s.pathRequests <- f:
+	case <-s.ctx.Done():
+		return nil
+	}
+	return <-infoChan
+}
+
 func (s *session) sendPackedPacket(packet *packedPacket) {
 	if s.firstAckElicitingPacketAfterIdleSentTime.IsZero() && packet.IsAckEliciting() {
 		s.firstAckElicitingPacketAfterIdleSentTime = time.Now()
@@ -1417,6 +2122,31 @@ func (s *session) OpenStreamSync(ctx context.Context) (Stream, error) {
 	return s.streamsMap.OpenStreamSync(ctx)
 }
 
+func (s *session) OpenStreamWithOptions(opts *StreamOptions) (Stream, error) {
+	str, err := s.streamsMap.OpenStream()
+	if err != nil {
+		return nil, err
+	}
+	applyStreamOptions(str, opts)
+	return str, nil
+}
+
+func (s *session) OpenStreamSyncWithOptions(ctx context.Context, opts *StreamOptions) (Stream, error) {
+	str, err := s.streamsMap.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applyStreamOptions(str, opts)
+	return str, nil
+}
+
+func applyStreamOptions(str Stream, opts *StreamOptions) {
+	if opts == nil {
+		return
+	}
+	str.(receiveStreamI).setReceiveWindowSize(protocol.ByteCount(opts.ReceiveWindow), protocol.ByteCount(opts.MaxReceiveWindow))
+}
+
 func (s *session) OpenUniStream() (SendStream, error) {
 	return s.streamsMap.OpenUniStream()
 }
@@ -31,12 +31,14 @@ type mockConnection struct {
 	remoteAddr net.Addr
 	localAddr  net.Addr
 	written    chan []byte
+	writtenTo  chan net.Addr
 }
 
 func newMockConnection() *mockConnection {
 	return &mockConnection{
 		remoteAddr: &net.UDPAddr{},
 		written:    make(chan []byte, 100),
+		writtenTo:  make(chan net.Addr, 100),
 	}
 }
 
@@ -50,15 +52,30 @@ func (m *mockConnection) Write(p []byte) error {
 	}
 	return nil
 }
+
+func (m *mockConnection) WriteTo(p []byte, addr net.Addr) error {
+	select {
+	case m.writtenTo <- addr:
+	default:
+		panic("mockConnection channel full")
+	}
+	return m.Write(p)
+}
+
 func (m *mockConnection) Read([]byte) (int, net.Addr, error) { panic("not implemented") }
 
 func (m *mockConnection) SetCurrentRemoteAddr(addr net.Addr) {
 	m.remoteAddr = addr
 }
+func (m *mockConnection) ECN() protocol.ECN    { return protocol.ECNNon }
+func (m *mockConnection) DisableECN()          {}
 func (m *mockConnection) LocalAddr() net.Addr  { return m.localAddr }
 func (m *mockConnection) RemoteAddr() net.Addr { return m.remoteAddr }
 func (*mockConnection) Close() error           { panic("not implemented") }
 
+func (*mockConnection) Init(utils.Logger, utils.Metrics)   {}
+func (*mockConnection) Scheduler() ResponseWriterScheduler { return nil }
+
 func areSessionsRunning() bool {
 	var b bytes.Buffer
 	pprof.Lookup("goroutine").WriteTo(&b, 1)
@@ -122,6 +139,7 @@ var _ = Describe("Session", func() {
 			populateServerConfig(&Config{}),
 			nil, // tls.Config
 			tokenGenerator,
+			false,
 			utils.DefaultLogger,
 			protocol.VersionTLS,
 		).(*session)
@@ -195,6 +213,41 @@ var _ = Describe("Session", func() {
 			})
 		})
 
+		Context("handling ACK_MP frames", func() {
+			It("rejects ACK_MP frames if multipath wasn't negotiated", func() {
+				f := &wire.AckMPFrame{PathID: 1, AckFrame: wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}}}
+				err := sess.handleFrame(f, 42, protocol.Encryption1RTT)
+				Expect(err).To(MatchError("PROTOCOL_VIOLATION: received an ACK_MP frame, but multipath was not negotiated"))
+			})
+
+			It("handles ACK_MP frames for the initial path", func() {
+				sess.multipath = newMultipathManager(cryptoSetup, nil)
+				f := &wire.AckMPFrame{AckFrame: wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}}}
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().ReceivedAck(&f.AckFrame, protocol.PacketNumber(42), protocol.EncryptionHandshake, gomock.Any())
+				sess.sentPacketHandler = sph
+				Expect(sess.handleFrame(f, 42, protocol.EncryptionHandshake)).To(Succeed())
+			})
+
+			It("handles ACK_MP frames for other paths", func() {
+				sess.multipath = newMultipathManager(cryptoSetup, nil)
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+				sess.multipath.paths[2] = &path{id: 2, sentPacketHandler: sph, receivedPacketHandler: rph}
+				f := &wire.AckMPFrame{PathID: 2, AckFrame: wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}}}
+				sph.EXPECT().ReceivedAck(&f.AckFrame, protocol.PacketNumber(42), protocol.Encryption1RTT, gomock.Any())
+				sph.EXPECT().GetLowestPacketNotConfirmedAcked().Return(protocol.PacketNumber(0x42))
+				rph.EXPECT().IgnoreBelow(protocol.PacketNumber(0x42))
+				Expect(sess.handleFrame(f, 42, protocol.Encryption1RTT)).To(Succeed())
+			})
+
+			It("ignores ACK_MP frames for unknown paths", func() {
+				sess.multipath = newMultipathManager(cryptoSetup, nil)
+				f := &wire.AckMPFrame{PathID: 3, AckFrame: wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}}}
+				Expect(sess.handleFrame(f, 42, protocol.Encryption1RTT)).To(Succeed())
+			})
+		})
+
 		Context("handling RESET_STREAM frames", func() {
 			It("closes the streams for writing", func() {
 				f := &wire.ResetStreamFrame{
@@ -341,16 +394,41 @@ var _ = Describe("Session", func() {
 			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.ProtocolViolation))
 		})
 
+		It("rejects HANDSHAKE_DONE frames", func() {
+			err := sess.handleFrame(&wire.HandshakeDoneFrame{}, 0, protocol.Encryption1RTT)
+			Expect(err).To(HaveOccurred())
+			Expect(err).To(BeAssignableToTypeOf(&qerr.QuicError{}))
+			Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.ProtocolViolation))
+		})
+
 		It("handles BLOCKED frames", func() {
 			err := sess.handleFrame(&wire.DataBlockedFrame{}, 0, protocol.EncryptionUnspecified)
 			Expect(err).NotTo(HaveOccurred())
+			Expect(sess.FlowControlStats().PeerBlockedCount).To(BeEquivalentTo(1))
 		})
 
 		It("handles STREAM_BLOCKED frames", func() {
-			err := sess.handleFrame(&wire.StreamDataBlockedFrame{}, 0, protocol.EncryptionUnspecified)
+			f := &wire.StreamDataBlockedFrame{StreamID: 5, DataLimit: 0x1337}
+			str := NewMockReceiveStreamI(mockCtrl)
+			streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(5)).Return(str, nil)
+			str.EXPECT().handleStreamDataBlockedFrame(f)
+			err := sess.handleFrame(f, 0, protocol.EncryptionUnspecified)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("ignores STREAM_BLOCKED frames for closed streams", func() {
+			streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(5)).Return(nil, nil)
+			err := sess.handleFrame(&wire.StreamDataBlockedFrame{StreamID: 5}, 0, protocol.EncryptionUnspecified)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("errors when receiving a STREAM_BLOCKED frame for an invalid stream", func() {
+			testErr := errors.New("invalid stream")
+			streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(5)).Return(nil, testErr)
+			err := sess.handleFrame(&wire.StreamDataBlockedFrame{StreamID: 5}, 0, protocol.EncryptionUnspecified)
+			Expect(err).To(MatchError(testErr))
+		})
+
 		It("handles STREAM_ID_BLOCKED frames", func() {
 			err := sess.handleFrame(&wire.StreamsBlockedFrame{}, 0, protocol.EncryptionUnspecified)
 			Expect(err).NotTo(HaveOccurred())
@@ -411,6 +489,39 @@ var _ = Describe("Session", func() {
 		Expect(sess.GetVersion()).To(Equal(protocol.VersionNumber(4242)))
 	})
 
+	It("closes the session if generating the preferred_address connection ID fails", func() {
+		tokenGenerator, err := handshake.NewTokenGenerator()
+		Expect(err).ToNot(HaveOccurred())
+		sess = newSession(
+			mconn,
+			sessionRunner,
+			nil,
+			clientDestConnID,
+			destConnID,
+			srcConnID,
+			[16]byte{},
+			populateServerConfig(&Config{
+				PreferredAddress:      &PreferredAddress{IPv4: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 443}},
+				ConnectionIDGenerator: &sequentialConnIDGenerator{connIDLen: srcConnID.Len(), err: errors.New("generation failed")},
+			}),
+			nil, // tls.Config
+			tokenGenerator,
+			false,
+			utils.DefaultLogger,
+			protocol.VersionTLS,
+		).(*session)
+		sess.streamsMap = streamManager
+		sess.packer = packer
+		sess.cryptoStreamHandler = cryptoSetup
+		streamManager.EXPECT().CloseWithError(gomock.Any())
+		expectReplaceWithClosed()
+		cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+		cryptoSetup.EXPECT().Close()
+		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+		Expect(sess.run()).To(MatchError("generating the preferred_address connection ID failed: generation failed"))
+		Expect(sess.Context().Done()).To(BeClosed())
+	})
+
 	Context("closing", func() {
 		var (
 			runErr         error
@@ -593,7 +704,7 @@ var _ = Describe("Session", func() {
 				data:            []byte{0}, // one PADDING frame
 			}, nil)
 			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
-			rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.EncryptionInitial, rcvTime, false)
+			rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.ECNNon, protocol.EncryptionInitial, rcvTime, false)
 			sess.receivedPacketHandler = rph
 			packet := getPacket(hdr, nil)
 			packet.rcvTime = rcvTime
@@ -616,7 +727,7 @@ var _ = Describe("Session", func() {
 				data:            buf.Bytes(),
 			}, nil)
 			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
-			rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.Encryption1RTT, rcvTime, true)
+			rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), protocol.ECNNon, protocol.Encryption1RTT, rcvTime, true)
 			sess.receivedPacketHandler = rph
 			packet := getPacket(hdr, nil)
 			packet.rcvTime = rcvTime
@@ -666,6 +777,28 @@ var _ = Describe("Session", func() {
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
 
+		It("closes the session when the integrity limit of the AEAD is reached", func() {
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrIntegrityLimitReached)
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				err := sess.run()
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*qerr.QuicError).ErrorCode).To(Equal(qerr.AEADLimitReached))
+				close(done)
+			}()
+			expectReplaceWithClosed()
+			sess.handlePacket(getPacket(&wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}, nil))
+			Eventually(sess.Context().Done()).Should(BeClosed())
+		})
+
 		It("ignores packets when unpacking fails for any other reason", func() {
 			testErr := errors.New("test err")
 			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, testErr)
@@ -713,16 +846,33 @@ var _ = Describe("Session", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
-		It("ignores 0-RTT packets", func() {
+		It("processes 0-RTT packets", func() {
 			hdr := &wire.ExtendedHeader{
 				Header: wire.Header{
 					IsLongHeader:     true,
 					Type:             protocol.PacketType0RTT,
 					DestConnectionID: srcConnID,
+					Length:           2 + 6,
+					Version:          sess.version,
 				},
+				PacketNumber:    0x37,
 				PacketNumberLen: protocol.PacketNumberLen2,
 			}
-			Expect(sess.handlePacketImpl(getPacket(hdr, nil))).To(BeFalse())
+			rcvTime := time.Now().Add(-10 * time.Second)
+			buf := &bytes.Buffer{}
+			Expect((&wire.PingFrame{}).Write(buf, sess.version)).To(Succeed())
+			unpacker.EXPECT().Unpack(gomock.Any(), rcvTime, gomock.Any()).Return(&unpackedPacket{
+				packetNumber:    0x37,
+				encryptionLevel: protocol.Encryption0RTT,
+				hdr:             hdr,
+				data:            buf.Bytes(),
+			}, nil)
+			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+			rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x37), protocol.ECNNon, protocol.Encryption0RTT, rcvTime, true)
+			sess.receivedPacketHandler = rph
+			packet := getPacket(hdr, []byte("foobar"))
+			packet.rcvTime = rcvTime
+			Expect(sess.handlePacketImpl(packet)).To(BeTrue())
 		})
 
 		It("ignores packets with a different source connection ID", func() {
@@ -914,7 +1064,7 @@ var _ = Describe("Session", func() {
 
 		It("sends packets", func() {
 			packer.EXPECT().PackPacket().Return(getPacket(1), nil)
-			sess.receivedPacketHandler.ReceivedPacket(0x035e, protocol.Encryption1RTT, time.Now(), true)
+			sess.receivedPacketHandler.ReceivedPacket(0x035e, protocol.ECNNon, protocol.Encryption1RTT, time.Now(), true)
 			sent, err := sess.sendPacket()
 			Expect(err).NotTo(HaveOccurred())
 			Expect(sent).To(BeTrue())
@@ -923,7 +1073,7 @@ var _ = Describe("Session", func() {
 
 		It("doesn't send packets if there's nothing to send", func() {
 			packer.EXPECT().PackPacket().Return(getPacket(2), nil)
-			sess.receivedPacketHandler.ReceivedPacket(0x035e, protocol.Encryption1RTT, time.Now(), true)
+			sess.receivedPacketHandler.ReceivedPacket(0x035e, protocol.ECNNon, protocol.Encryption1RTT, time.Now(), true)
 			sent, err := sess.sendPacket()
 			Expect(err).NotTo(HaveOccurred())
 			Expect(sent).To(BeTrue())
@@ -1336,7 +1486,7 @@ var _ = Describe("Session", func() {
 			packer.EXPECT().PackPacket().MaxTimes(3)
 			Expect(sess.earlySessionReady()).ToNot(BeClosed())
 			sessionRunner.EXPECT().Add(gomock.Any(), sess).Times(3)
-			sess.processTransportParameters(params.Marshal())
+			sess.processTransportParameters(params.Marshal(sess.version))
 			Expect(sess.earlySessionReady()).To(BeClosed())
 
 			// make the go routine return
@@ -1358,7 +1508,7 @@ var _ = Describe("Session", func() {
 			tp := &handshake.TransportParameters{IdleTimeout: t}
 			streamManager.EXPECT().UpdateLimits(gomock.Any())
 			packer.EXPECT().HandleTransportParameters(gomock.Any())
-			sess.processTransportParameters(tp.Marshal())
+			sess.processTransportParameters(tp.Marshal(sess.version))
 		}
 
 		runSession := func() {
@@ -1559,6 +1709,31 @@ var _ = Describe("Session", func() {
 			Expect(str).To(Equal(mstr))
 		})
 
+		It("opens streams with options", func() {
+			mstr := NewMockStreamI(mockCtrl)
+			streamManager.EXPECT().OpenStream().Return(mstr, nil)
+			mstr.EXPECT().setReceiveWindowSize(protocol.ByteCount(1000), protocol.ByteCount(2000))
+			str, err := sess.OpenStreamWithOptions(&StreamOptions{ReceiveWindow: 1000, MaxReceiveWindow: 2000})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(Equal(mstr))
+		})
+
+		It("opens streams synchronously with options", func() {
+			mstr := NewMockStreamI(mockCtrl)
+			streamManager.EXPECT().OpenStreamSync(context.Background()).Return(mstr, nil)
+			mstr.EXPECT().setReceiveWindowSize(protocol.ByteCount(0), protocol.ByteCount(2000))
+			str, err := sess.OpenStreamSyncWithOptions(context.Background(), &StreamOptions{MaxReceiveWindow: 2000})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(Equal(mstr))
+		})
+
+		It("returns errors when opening streams with options", func() {
+			testErr := errors.New("too many streams")
+			streamManager.EXPECT().OpenStream().Return(nil, testErr)
+			_, err := sess.OpenStreamWithOptions(&StreamOptions{ReceiveWindow: 1000})
+			Expect(err).To(MatchError(testErr))
+		})
+
 		It("opens unidirectional streams", func() {
 			mstr := NewMockSendStreamI(mockCtrl)
 			streamManager.EXPECT().OpenUniStream().Return(mstr, nil)
@@ -1607,6 +1782,18 @@ var _ = Describe("Session", func() {
 		mconn.remoteAddr = addr
 		Expect(sess.RemoteAddr()).To(Equal(addr))
 	})
+
+	Context("key updates", func() {
+		It("doesn't initiate a key update before the handshake completes", func() {
+			Expect(sess.InitiateKeyUpdate()).To(MatchError("cannot initiate a key update before the handshake completes"))
+		})
+
+		It("initiates a key update", func() {
+			close(sess.handshakeCompleteChan)
+			cryptoSetup.EXPECT().InitiateKeyUpdate()
+			Expect(sess.InitiateKeyUpdate()).To(Succeed())
+		})
+	})
 })
 
 var _ = Describe("Client Session", func() {
@@ -1659,6 +1846,7 @@ var _ = Describe("Client Session", func() {
 			tlsConf,
 			42, // initial packet number
 			protocol.VersionTLS,
+			false,
 			utils.DefaultLogger,
 			protocol.VersionTLS,
 		).(*session)
@@ -1668,6 +1856,51 @@ var _ = Describe("Client Session", func() {
 		sess.cryptoStreamHandler = cryptoSetup
 	})
 
+	It("ignores 0-RTT packets", func() {
+		hdr := &wire.ExtendedHeader{
+			Header: wire.Header{
+				IsLongHeader:     true,
+				Type:             protocol.PacketType0RTT,
+				DestConnectionID: srcConnID,
+				Length:           2 + 6,
+				Version:          sess.version,
+			},
+			PacketNumber:    0x42,
+			PacketNumberLen: protocol.PacketNumberLen2,
+		}
+		Expect(sess.handlePacketImpl(getPacket(hdr, []byte("foobar")))).To(BeFalse())
+	})
+
+	It("restores the server's transport parameters when using 0-RTT", func() {
+		params := &handshake.TransportParameters{
+			InitialMaxData:   1337,
+			MaxBidiStreamNum: 10,
+			MaxUniStreamNum:  20,
+		}
+		chWritten := make(chan *handshake.TransportParameters, 1)
+		chWritten <- params
+		sess.clientHelloWritten = chWritten
+		streamManager := NewMockStreamManager(mockCtrl)
+		streamManager.EXPECT().UpdateLimits(params)
+		sess.streamsMap = streamManager
+		packer.EXPECT().PackPacket().AnyTimes()
+		go func() {
+			defer GinkgoRecover()
+			cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+			sess.run()
+		}()
+		Eventually(sess.earlySessionReady()).Should(BeClosed())
+		Expect(sess.connFlowController.SendWindowSize()).To(Equal(protocol.ByteCount(1337)))
+		Expect(sess.HandshakeComplete().Done()).ToNot(BeClosed())
+		// make sure the go routine returns
+		streamManager.EXPECT().CloseWithError(gomock.Any())
+		packer.EXPECT().PackConnectionClose(gomock.Any()).Return(&packedPacket{}, nil)
+		expectReplaceWithClosed()
+		cryptoSetup.EXPECT().Close()
+		Expect(sess.Close()).To(Succeed())
+		Eventually(sess.Context().Done()).Should(BeClosed())
+	})
+
 	It("changes the connection ID when receiving the first packet from the server", func() {
 		unpacker := NewMockUnpacker(mockCtrl)
 		unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(hdr *wire.Header, _ time.Time, data []byte) (*unpackedPacket, error) {
@@ -1792,6 +2025,116 @@ var _ = Describe("Client Session", func() {
 			}
 			Expect(sess.handlePacketImpl(getPacket(hdr, nil))).To(BeFalse())
 		})
+
+		Context("using the Retry Integrity Tag", func() {
+			getRetryPacket := func(hdr *wire.ExtendedHeader, origDestConnID protocol.ConnectionID) *receivedPacket {
+				p := getPacket(hdr, nil)
+				tag := handshake.GetRetryIntegrityTag(p.data, origDestConnID, hdr.Version)
+				p.data = append(p.data, tag[:]...)
+				return p
+			}
+
+			JustBeforeEach(func() {
+				sess.version = protocol.VersionDraft29
+				validRetryHdr.Version = protocol.VersionDraft29
+			})
+
+			It("handles Retry packets with a valid tag", func() {
+				cryptoSetup.EXPECT().ChangeConnectionID(protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef})
+				packer.EXPECT().SetToken([]byte("foobar"))
+				Expect(sess.handlePacketImpl(getRetryPacket(validRetryHdr, destConnID))).To(BeTrue())
+				Expect(sess.retrySrcConnID).To(Equal(protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}))
+			})
+
+			It("ignores Retry packets with an invalid tag", func() {
+				Expect(sess.handlePacketImpl(getRetryPacket(validRetryHdr, protocol.ConnectionID{1, 2, 3, 4}))).To(BeFalse())
+			})
+		})
+	})
+
+	Context("handling HANDSHAKE_DONE frames", func() {
+		It("confirms the handshake", func() {
+			cryptoSetup.EXPECT().SetHandshakeConfirmed()
+			Expect(sess.handleFrame(&wire.HandshakeDoneFrame{}, 0, protocol.Encryption1RTT)).To(Succeed())
+		})
+	})
+
+	Context("migrating to the preferred address", func() {
+		preferredAddr := &handshake.PreferredAddress{
+			IPv4:                net.IPv4(127, 0, 0, 1).To4(),
+			IPv4Port:            1234,
+			IPv6:                net.IPv6zero,
+			ConnectionID:        protocol.ConnectionID{1, 3, 3, 7},
+			StatelessResetToken: [16]byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1},
+		}
+
+		JustBeforeEach(func() {
+			mconn.remoteAddr = &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 443}
+		})
+
+		It("validates the path to the preferred address of the same address family", func() {
+			params := &handshake.TransportParameters{PreferredAddress: preferredAddr}
+			_, err := sess.processTransportParametersForClient(params.Marshal(sess.version))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.preferredAddr).ToNot(BeNil())
+			Expect(sess.preferredAddr.addr).To(Equal(&net.UDPAddr{IP: preferredAddr.IPv4, Port: 1234}))
+			Expect(sess.preferredAddr.connID).To(Equal(protocol.ConnectionID{1, 3, 3, 7}))
+			cf, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+			Expect(cf).To(BeEmpty())
+		})
+
+		It("retires the preferred_address connection ID if there's no address of the same address family", func() {
+			mconn.remoteAddr = &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 443}
+			params := &handshake.TransportParameters{PreferredAddress: preferredAddr}
+			_, err := sess.processTransportParametersForClient(params.Marshal(sess.version))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.preferredAddr).To(BeNil())
+			cf, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+			Expect(cf).To(HaveLen(1))
+			Expect(cf[0].Frame).To(Equal(&wire.RetireConnectionIDFrame{SequenceNumber: 1}))
+		})
+
+		Context("handling PATH_RESPONSE frames", func() {
+			var challenge *wire.PathChallengeFrame
+
+			JustBeforeEach(func() {
+				addr := &net.UDPAddr{IP: preferredAddr.IPv4, Port: 1234}
+				sess.preferredAddr = newPreferredAddressPath(addr, preferredAddr)
+				var err error
+				challenge, err = sess.preferredAddr.NextChallenge(time.Now(), time.Second)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("migrates when receiving the PATH_RESPONSE", func() {
+				sessionRunner.EXPECT().AddResetToken(preferredAddr.StatelessResetToken, gomock.Any())
+				Expect(sess.handleFrame(&wire.PathResponseFrame{Data: challenge.Data}, 0, protocol.Encryption1RTT)).To(Succeed())
+				Expect(mconn.RemoteAddr()).To(Equal(&net.UDPAddr{IP: preferredAddr.IPv4, Port: 1234}))
+				Expect(sess.connIDManager.Get()).To(Equal(protocol.ConnectionID{1, 3, 3, 7}))
+				Expect(sess.preferredAddr.Deadline()).To(BeZero())
+				cf, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+				Expect(cf).To(HaveLen(1))
+				Expect(cf[0].Frame).To(Equal(&wire.RetireConnectionIDFrame{SequenceNumber: 0}))
+			})
+
+			It("ignores PATH_RESPONSE frames that don't match a PATH_CHALLENGE", func() {
+				Expect(sess.handleFrame(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, 0, protocol.Encryption1RTT)).To(Succeed())
+				Expect(mconn.RemoteAddr()).To(Equal(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 443}))
+				Expect(sess.preferredAddr.Deadline()).ToNot(BeZero())
+			})
+
+			It("retires the preferred_address connection ID when path validation fails", func() {
+				for sess.preferredAddr.ShouldRetry() {
+					_, err := sess.preferredAddr.NextChallenge(time.Now(), time.Second)
+					Expect(err).ToNot(HaveOccurred())
+				}
+				sess.onPathValidationTimeout(time.Now())
+				Expect(sess.preferredAddr.Deadline()).To(BeZero())
+				Expect(mconn.RemoteAddr()).To(Equal(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 443}))
+				cf, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+				Expect(cf).To(HaveLen(1))
+				Expect(cf[0].Frame).To(Equal(&wire.RetireConnectionIDFrame{SequenceNumber: 1}))
+			})
+		})
 	})
 
 	Context("transport parameters", func() {
@@ -1825,7 +2168,7 @@ var _ = Describe("Client Session", func() {
 			}
 			packer.EXPECT().HandleTransportParameters(gomock.Any())
 			packer.EXPECT().PackPacket().MaxTimes(1)
-			sess.processTransportParameters(params.Marshal())
+			sess.processTransportParameters(params.Marshal(sess.version))
 			cf, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
 			Expect(cf).To(HaveLen(1))
 			Expect(cf[0].Frame).To(Equal(&wire.RetireConnectionIDFrame{SequenceNumber: 1}))
@@ -1846,7 +2189,7 @@ var _ = Describe("Client Session", func() {
 				OriginalConnectionID: protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad},
 				StatelessResetToken:  &[16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
 			}
-			_, err := sess.processTransportParametersForClient(params.Marshal())
+			_, err := sess.processTransportParametersForClient(params.Marshal(sess.version))
 			Expect(err).To(MatchError("TRANSPORT_PARAMETER_ERROR: expected original_connection_id to equal (empty), is 0xdecafbad"))
 		})
 
@@ -1856,7 +2199,7 @@ var _ = Describe("Client Session", func() {
 				OriginalConnectionID: protocol.ConnectionID{0xde, 0xca, 0xfb, 0xad},
 				StatelessResetToken:  &[16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
 			}
-			_, err := sess.processTransportParametersForClient(params.Marshal())
+			_, err := sess.processTransportParametersForClient(params.Marshal(sess.version))
 			Expect(err).To(MatchError("TRANSPORT_PARAMETER_ERROR: expected original_connection_id to equal 0xdeadbeef, is 0xdecafbad"))
 		})
 	})
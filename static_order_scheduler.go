@@ -3,6 +3,8 @@ package quic
 import (
 	"net/http"
 	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
 const (
@@ -34,6 +36,11 @@ type StaticOrderScheduler struct {
 	// 当有 block 就绪或者执行完毕的时候，调度器都需要向此 chan 发送消息，以便寻找并执行
 	// 下一就选的 ResponseWriter
 	tryRunChan chan struct{}
+
+	// 调度器的统计数据, 所有连接的调度器共用同一组 gauge, 因此只能通过 Add 修改
+	managedQueueLen   utils.Gauge
+	unmanagedQueueLen utils.Gauge
+	running           utils.Gauge
 }
 
 // NewStaticOrderScheduler 根据给定的传输顺序初始化调度器实例并返回其指针
@@ -45,6 +52,7 @@ func NewStaticOrderScheduler() *StaticOrderScheduler {
 		UnmanagedResponseWriter: make([]*ResponseWriterControlBlock, 0),
 		tryRunChan:              make(chan struct{}, 100),
 	}
+	scheduler.setMetrics(utils.NopMetrics)
 	// 写入传输顺序
 	for index, url := range currentOrderList {
 		scheduler.ManagedURLMap[url] = index
@@ -53,6 +61,13 @@ func NewStaticOrderScheduler() *StaticOrderScheduler {
 	return &scheduler
 }
 
+// setMetrics 设置调度器上报统计数据所使用的 Metrics
+func (schd *StaticOrderScheduler) setMetrics(m utils.Metrics) {
+	schd.managedQueueLen = m.Gauge("quic_scheduler_managed_queue_length", "Number of managed response writers waiting to be executed.")
+	schd.unmanagedQueueLen = m.Gauge("quic_scheduler_unmanaged_queue_length", "Number of unmanaged response writers waiting to be executed.")
+	schd.running = m.Gauge("quic_scheduler_running_response_writers", "Number of response writers being executed.")
+}
+
 // Name 返回该调度器的名字
 func (schd *StaticOrderScheduler) Name() string {
 	return schd.name
@@ -87,11 +102,13 @@ func (schd *StaticOrderScheduler) AddNewResponseWriter(writer http.ResponseWrite
 		schd.ManagedResponseWriter[index] = newResponseWriterControlBlock(
 			writer, request, quicStr, handler)
 		schd.QueuedManagedResponseWriter++
+		schd.managedQueueLen.Add(1)
 	} else {
 		// 这个是尚未安排好顺序的请求
 		schd.UnmanagedResponseWriter = append(schd.UnmanagedResponseWriter,
 			newResponseWriterControlBlock(writer, request, quicStr, handler))
 		schd.QueuedUnmanagedResponseWriter++
+		schd.unmanagedQueueLen.Add(1)
 	}
 
 	// 发送信号
@@ -133,6 +150,8 @@ func (schd *StaticOrderScheduler) popNextResponseWriter() *ResponseWriterControl
 		if next != nil {
 			schd.QueuedManagedResponseWriter--
 			schd.ConcurrentManagedResponseWriter++
+			schd.managedQueueLen.Add(-1)
+			schd.running.Add(1)
 			return next
 		}
 	}
@@ -148,10 +167,12 @@ func (schd *StaticOrderScheduler) popNextResponseWriter() *ResponseWriterControl
 		schd.UnmanagedResponseWriter = schd.UnmanagedResponseWriter[1:]
 		schd.QueuedUnmanagedResponseWriter--
 		schd.ConcurrentUnmanagedResponseWriter++
+		schd.unmanagedQueueLen.Add(-1)
 	}
 
 	if next != nil {
 		schd.ConcurrentResponseWriter++
+		schd.running.Add(1)
 	}
 
 	return next
@@ -167,6 +188,7 @@ func (schd *StaticOrderScheduler) executeResponseWriter(block *ResponseWriterCon
 	} else {
 		schd.ConcurrentUnmanagedResponseWriter--
 	}
+	schd.running.Add(-1)
 	schd.mutex.Unlock()
 	block.str.Close()
 	schd.tryRunChan <- struct{}{}
@@ -46,7 +46,9 @@ type streamI interface {
 	// for receiving
 	handleStreamFrame(*wire.StreamFrame) error
 	handleResetStreamFrame(*wire.ResetStreamFrame) error
+	handleStreamDataBlockedFrame(*wire.StreamDataBlockedFrame)
 	getWindowUpdate() protocol.ByteCount
+	setReceiveWindowSize(size, maxSize protocol.ByteCount)
 	// for sending
 	hasData() bool
 	handleStopSendingFrame(*wire.StopSendingFrame)
@@ -135,6 +137,12 @@ func (s *stream) Close() error {
 	return nil
 }
 
+// need to define FlowControlStats() here, since both receiveStream and sendStream have a FlowControlStats()
+func (s *stream) FlowControlStats() FlowControlStats {
+	// both stream halves use the same flow controller
+	return s.receiveStream.FlowControlStats()
+}
+
 func (s *stream) SetDeadline(t time.Time) error {
 	_ = s.SetReadDeadline(t)  // SetReadDeadline never errors
 	_ = s.SetWriteDeadline(t) // SetWriteDeadline never errors
@@ -153,6 +161,21 @@ func (s *stream) handleResetStreamFrame(frame *wire.ResetStreamFrame) error {
 	return s.receiveStream.handleResetStreamFrame(frame)
 }
 
+func toFlowControlStats(s flowcontrol.Stats) FlowControlStats {
+	return FlowControlStats{
+		SendWindow:           uint64(s.SendWindow),
+		BytesSent:            uint64(s.BytesSent),
+		BlockedCount:         s.BlockedCount,
+		ReceiveWindow:        uint64(s.ReceiveWindow),
+		ReceiveWindowSize:    uint64(s.ReceiveWindowSize),
+		MaxReceiveWindowSize: uint64(s.MaxReceiveWindowSize),
+		BytesRead:            uint64(s.BytesRead),
+		HighestReceived:      uint64(s.HighestReceived),
+		PeerBlockedCount:     s.PeerBlockedCount,
+		WindowIncreaseCount:  s.WindowIncreaseCount,
+	}
+}
+
 // checkIfCompleted is called from the uniStreamSender, when one of the stream halves is completed.
 // It makes sure that the onStreamCompleted callback is only called if both receive and send side have completed.
 func (s *stream) checkIfCompleted() {
@@ -5,11 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sync/atomic"
 
 	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
 
@@ -54,6 +56,10 @@ type streamsMap struct {
 	outgoingUniStreams  *outgoingUniStreamsMap
 	incomingBidiStreams *incomingBidiStreamsMap
 	incomingUniStreams  *incomingUniStreamsMap
+
+	// the number of open streams, reported to the openStreams gauge
+	numOpenStreams int64 // to be used as an atomic
+	openStreams    utils.Gauge
 }
 
 var _ streamManager = &streamsMap{}
@@ -65,15 +71,18 @@ func newStreamsMap(
 	maxIncomingUniStreams uint64,
 	perspective protocol.Perspective,
 	version protocol.VersionNumber,
+	openStreams utils.Gauge,
 ) streamManager {
 	m := &streamsMap{
 		perspective:       perspective,
 		newFlowController: newFlowController,
 		sender:            sender,
+		openStreams:       openStreams,
 	}
 	m.outgoingBidiStreams = newOutgoingBidiStreamsMap(
 		func(num protocol.StreamNum) streamI {
 			id := num.StreamID(protocol.StreamTypeBidi, perspective)
+			m.streamOpened()
 			return newStream(id, m.sender, m.newFlowController(id), version)
 		},
 		sender.queueControlFrame,
@@ -81,6 +90,7 @@ func newStreamsMap(
 	m.incomingBidiStreams = newIncomingBidiStreamsMap(
 		func(num protocol.StreamNum) streamI {
 			id := num.StreamID(protocol.StreamTypeBidi, perspective.Opposite())
+			m.streamOpened()
 			return newStream(id, m.sender, m.newFlowController(id), version)
 		},
 		maxIncomingBidiStreams,
@@ -89,6 +99,7 @@ func newStreamsMap(
 	m.outgoingUniStreams = newOutgoingUniStreamsMap(
 		func(num protocol.StreamNum) sendStreamI {
 			id := num.StreamID(protocol.StreamTypeUni, perspective)
+			m.streamOpened()
 			return newSendStream(id, m.sender, m.newFlowController(id), version)
 		},
 		sender.queueControlFrame,
@@ -96,6 +107,7 @@ func newStreamsMap(
 	m.incomingUniStreams = newIncomingUniStreamsMap(
 		func(num protocol.StreamNum) receiveStreamI {
 			id := num.StreamID(protocol.StreamTypeUni, perspective.Opposite())
+			m.streamOpened()
 			return newReceiveStream(id, m.sender, m.newFlowController(id), version)
 		},
 		maxIncomingUniStreams,
@@ -135,6 +147,14 @@ func (m *streamsMap) AcceptUniStream(ctx context.Context) (ReceiveStream, error)
 }
 
 func (m *streamsMap) DeleteStream(id protocol.StreamID) error {
+	err := m.deleteStream(id)
+	if err == nil {
+		m.streamClosed()
+	}
+	return err
+}
+
+func (m *streamsMap) deleteStream(id protocol.StreamID) error {
 	num := id.StreamNum()
 	switch id.Type() {
 	case protocol.StreamTypeUni:
@@ -239,4 +259,25 @@ func (m *streamsMap) CloseWithError(err error) {
 	m.outgoingUniStreams.CloseWithError(err)
 	m.incomingBidiStreams.CloseWithError(err)
 	m.incomingUniStreams.CloseWithError(err)
+	// All streams are closed now.
+	// Streams that are deleted later were already accounted for.
+	m.openStreams.Add(-float64(atomic.SwapInt64(&m.numOpenStreams, 0)))
+}
+
+func (m *streamsMap) streamOpened() {
+	atomic.AddInt64(&m.numOpenStreams, 1)
+	m.openStreams.Add(1)
+}
+
+func (m *streamsMap) streamClosed() {
+	for {
+		n := atomic.LoadInt64(&m.numOpenStreams)
+		if n == 0 { // the map was already closed
+			return
+		}
+		if atomic.CompareAndSwapInt64(&m.numOpenStreams, n, n-1) {
+			m.openStreams.Add(-1)
+			return
+		}
+	}
 }
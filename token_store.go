@@ -1,9 +1,13 @@
 package quic
 
 import (
+	"bytes"
 	"container/list"
+	"encoding/binary"
 	"sync"
+	"time"
 
+	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 )
 
@@ -115,3 +119,106 @@ func (s *lruTokenStore) Pop(key string) *ClientToken {
 	}
 	return token
 }
+
+type persistentTokenStore struct {
+	store           KeyValueStore
+	tokensPerOrigin int
+
+	logger utils.Logger
+}
+
+var _ TokenStore = &persistentTokenStore{}
+
+// NewPersistentTokenStore creates a TokenStore that saves tokens in a KeyValueStore.
+// This allows clients to skip address validation when they connect to a server again, after they were restarted.
+// tokensPerOrigin specifies the maximum number of tokens per origin. It is at least 1.
+// Tokens expire after 24 hours.
+// When the KeyValueStore is shared between processes, a token is only used by a single process.
+// Errors of the KeyValueStore are logged to logger. If logger is nil, the default logger is used.
+func NewPersistentTokenStore(store KeyValueStore, tokensPerOrigin int, logger Logger) TokenStore {
+	return &persistentTokenStore{
+		store:           store,
+		tokensPerOrigin: utils.Max(tokensPerOrigin, 1),
+		logger:          utils.NewLogger(logger, "token store"),
+	}
+}
+
+func (s *persistentTokenStore) Put(key string, token *ClientToken) {
+	if err := s.store.Update(s.storeKey(key), func(data []byte) ([]byte, time.Time) {
+		tokens := append(decodeTokens(data), persistedToken{
+			data:   token.data,
+			expiry: time.Now().Add(protocol.TokenValidity),
+		})
+		if len(tokens) > s.tokensPerOrigin {
+			tokens = tokens[len(tokens)-s.tokensPerOrigin:]
+		}
+		return encodeTokens(tokens), tokens[len(tokens)-1].expiry
+	}); err != nil {
+		s.logger.Debugf("Saving token for %s failed: %s", key, err)
+	}
+}
+
+func (s *persistentTokenStore) Pop(key string) *ClientToken {
+	var token *ClientToken
+	// The token is removed in the same update, so that no other process uses the same token.
+	if err := s.store.Update(s.storeKey(key), func(data []byte) ([]byte, time.Time) {
+		tokens := decodeTokens(data)
+		if len(tokens) == 0 {
+			return nil, time.Time{}
+		}
+		token = &ClientToken{data: tokens[len(tokens)-1].data}
+		if tokens = tokens[:len(tokens)-1]; len(tokens) > 0 {
+			return encodeTokens(tokens), tokens[len(tokens)-1].expiry
+		}
+		return nil, time.Time{}
+	}); err != nil {
+		s.logger.Debugf("Reading tokens for %s failed: %s", key, err)
+		return nil
+	}
+	return token
+}
+
+func (s *persistentTokenStore) storeKey(key string) string {
+	return "token " + key
+}
+
+type persistedToken struct {
+	data   []byte
+	expiry time.Time
+}
+
+// encodeTokens encodes tokens, ordered from the oldest to the newest token.
+// Every token is encoded as the expiry time (unix nanoseconds), followed by the length of the token and the token.
+func encodeTokens(tokens []persistedToken) []byte {
+	b := &bytes.Buffer{}
+	for _, t := range tokens {
+		binary.Write(b, binary.BigEndian, t.expiry.UnixNano())
+		utils.WriteVarInt(b, uint64(len(t.data)))
+		b.Write(t.data)
+	}
+	return b.Bytes()
+}
+
+// decodeTokens decodes tokens encoded by encodeTokens.
+// It skips tokens that already expired.
+func decodeTokens(data []byte) []persistedToken {
+	var tokens []persistedToken
+	now := time.Now()
+	r := bytes.NewReader(data)
+	for r.Len() > 0 {
+		var expiry int64
+		if err := binary.Read(r, binary.BigEndian, &expiry); err != nil {
+			return tokens
+		}
+		l, err := utils.ReadVarInt(r)
+		if err != nil || l > uint64(r.Len()) {
+			return tokens
+		}
+		t := persistedToken{data: make([]byte, l), expiry: time.Unix(0, expiry)}
+		r.Read(t.data)
+		if t.expiry.After(now) {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
@@ -1,7 +1,14 @@
 package quic
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -106,3 +113,109 @@ var _ = Describe("Token Cache", func() {
 		})
 	})
 })
+
+var _ = Describe("Persistent Token Store", func() {
+	var (
+		dir   string
+		store KeyValueStore
+		s     TokenStore
+	)
+
+	mockToken := func(num int) *ClientToken {
+		return &ClientToken{data: []byte(fmt.Sprintf("%d", num))}
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "quic-go-token-store")
+		Expect(err).ToNot(HaveOccurred())
+		store, err = NewFileStore(dir, bytes.Repeat([]byte{0x42}, 32))
+		Expect(err).ToNot(HaveOccurred())
+		s = NewPersistentTokenStore(store, 3, nil)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("adds and gets tokens", func() {
+		s.Put("localhost", mockToken(1))
+		s.Put("localhost", mockToken(2))
+		s.Put("quic.clemente.io", mockToken(3))
+		Expect(s.Pop("localhost")).To(Equal(mockToken(2)))
+		Expect(s.Pop("localhost")).To(Equal(mockToken(1)))
+		Expect(s.Pop("localhost")).To(BeNil())
+		Expect(s.Pop("quic.clemente.io")).To(Equal(mockToken(3)))
+	})
+
+	It("limits the number of tokens per origin", func() {
+		for i := 1; i <= 5; i++ {
+			s.Put("localhost", mockToken(i))
+		}
+		Expect(s.Pop("localhost")).To(Equal(mockToken(5)))
+		Expect(s.Pop("localhost")).To(Equal(mockToken(4)))
+		Expect(s.Pop("localhost")).To(Equal(mockToken(3)))
+		Expect(s.Pop("localhost")).To(BeNil())
+	})
+
+	It("saves at least one token per origin", func() {
+		for _, n := range []int{0, -1} {
+			s = NewPersistentTokenStore(store, n, nil)
+			s.Put("localhost", mockToken(1))
+			s.Put("localhost", mockToken(2))
+			Expect(s.Pop("localhost")).To(Equal(mockToken(2)))
+			Expect(s.Pop("localhost")).To(BeNil())
+		}
+	})
+
+	It("shares tokens between token stores", func() {
+		s.Put("localhost", mockToken(1))
+		s.Put("localhost", mockToken(2))
+		s2 := NewPersistentTokenStore(store, 3, nil)
+		Expect(s2.Pop("localhost")).To(Equal(mockToken(2)))
+		Expect(s.Pop("localhost")).To(Equal(mockToken(1)))
+		Expect(s2.Pop("localhost")).To(BeNil())
+	})
+
+	It("doesn't lose tokens added by multiple processes at the same time", func() {
+		var wg sync.WaitGroup
+		for i := 1; i <= 3; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				// use different stores, to simulate different processes
+				store, err := NewFileStore(dir, bytes.Repeat([]byte{0x42}, 32))
+				Expect(err).ToNot(HaveOccurred())
+				NewPersistentTokenStore(store, 3, nil).Put("localhost", mockToken(i))
+			}(i)
+		}
+		wg.Wait()
+		var tokens []*ClientToken
+		for i := 0; i < 3; i++ {
+			tokens = append(tokens, s.Pop("localhost"))
+		}
+		Expect(tokens).To(ConsistOf(mockToken(1), mockToken(2), mockToken(3)))
+		Expect(s.Pop("localhost")).To(BeNil())
+	})
+
+	It("drops expired tokens", func() {
+		data := encodeTokens([]persistedToken{
+			{data: []byte("1"), expiry: time.Now().Add(-time.Second)},
+			{data: []byte("2"), expiry: time.Now().Add(time.Hour)},
+			{data: []byte("3"), expiry: time.Now().Add(-time.Second)},
+		})
+		Expect(store.Put("token localhost", data, time.Now().Add(time.Hour))).To(Succeed())
+		Expect(s.Pop("localhost")).To(Equal(mockToken(2)))
+		Expect(s.Pop("localhost")).To(BeNil())
+	})
+
+	It("sets the expiry time of tokens", func() {
+		s.Put("localhost", mockToken(1))
+		data, err := store.Get("token localhost")
+		Expect(err).ToNot(HaveOccurred())
+		tokens := decodeTokens(data)
+		Expect(tokens).To(HaveLen(1))
+		Expect(tokens[0].expiry).To(BeTemporally("~", time.Now().Add(protocol.TokenValidity), time.Second))
+	})
+})